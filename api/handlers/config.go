@@ -66,10 +66,12 @@ func (h *BaseHandler) HandleGetModelConfigs(c *gin.Context) {
 
 type UpdateModelConfigRequest struct {
 	Models map[string]struct {
-		Enabled         bool   `json:"enabled"`
-		APIKey          string `json:"api_key"`
-		CustomAPIURL    string `json:"custom_api_url"`
-		CustomModelName string `json:"custom_model_name"`
+		Enabled         bool     `json:"enabled"`
+		APIKey          string   `json:"api_key"`
+		CustomAPIURL    string   `json:"custom_api_url"`
+		CustomModelName string   `json:"custom_model_name"`
+		Temperature     *float64 `json:"temperature,omitempty"` // 采样温度（不传表示保持不变）
+		MaxTokens       *int     `json:"max_tokens,omitempty"`  // 最大输出token数（不传表示保持不变）
 	} `json:"models"`
 }
 
@@ -89,6 +91,10 @@ func (h *BaseHandler) HandleUpdateModelConfigs(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新模型 %s 失败: %v", modelID, err)})
 			return
 		}
+		if err := h.Database.UpdateAIModelParams(userID, modelID, modelData.Temperature, modelData.MaxTokens); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("更新模型 %s 参数失败: %v", modelID, err)})
+			return
+		}
 	}
 
 	// 重新加载该用户的所有交易员，使新配置立即生效
@@ -3,15 +3,18 @@ package api
 import (
         "database/sql"
         "encoding/json"
+        "errors"
         "fmt"
         "log"
         "net/http"
         "nofx/api/credits"
         "nofx/auth"
+        "nofx/backtest"
         "nofx/config"
         "nofx/decision"
         "nofx/email"
         "nofx/manager"
+        "nofx/mcp"
         "nofx/middleware"
         creditsService "nofx/service/credits"
         "os"
@@ -31,6 +34,7 @@ type Server struct {
         emailClient   *email.ResendClient
         creditService creditsService.Service
         creditHandler *credits.Handler
+        backtests     *backtest.JobManager
         port          int
 }
 
@@ -68,6 +72,7 @@ func NewServer(traderManager *manager.TraderManager, database *config.Database,
                 emailClient:   email.NewResendClient(),
                 creditService: creditService,
                 creditHandler: creditHandler,
+                backtests:     backtest.NewJobManager(),
                 port:          port,
         }
 
@@ -257,6 +262,8 @@ func (s *Server) setupRoutes() {
                         protected.POST("/traders/:id/start", s.handleStartTrader)
                         protected.POST("/traders/:id/stop", s.handleStopTrader)
                         protected.PUT("/traders/:id/prompt", s.handleUpdateTraderPrompt)
+                        protected.POST("/traders/:id/guardian/reset", s.handleResetGuardianHalt)
+                        protected.POST("/traders/:id/circuit-breaker/reset", s.handleResetCircuitBreaker)
 
                         // AI模型配置
                         protected.GET("/models", s.handleGetModelConfigs)
@@ -266,6 +273,12 @@ func (s *Server) setupRoutes() {
                         protected.GET("/exchanges", s.handleGetExchangeConfigs)
                         protected.PUT("/exchanges", s.handleUpdateExchangeConfigs)
 
+                        // 交易所命名凭证（同一交易所多套凭证/子账户）
+                        protected.GET("/exchange-accounts", s.handleGetExchangeAccounts)
+                        protected.POST("/exchange-accounts", s.handleCreateExchangeAccount)
+                        protected.PUT("/exchange-accounts/:id", s.handleUpdateExchangeAccount)
+                        protected.DELETE("/exchange-accounts/:id", s.handleDeleteExchangeAccount)
+
                         // 用户信号源配置
                         protected.GET("/user/signal-sources", s.handleGetUserSignalSource)
                         protected.POST("/user/signal-sources", s.handleSaveUserSignalSource)
@@ -279,6 +292,14 @@ func (s *Server) setupRoutes() {
                         protected.GET("/statistics", s.handleStatistics)
                         protected.GET("/performance", s.handlePerformance)
 
+                        // 历史回测（异步任务）
+                        protected.POST("/backtests", s.handleCreateBacktest)
+                        protected.GET("/backtests", s.handleListBacktests)
+                        protected.GET("/backtests/:id", s.handleGetBacktest)
+
+                        // AI用量与费用（?period=day|month&days=N&trader_id=xxx）
+                        protected.GET("/ai-usage", s.handleAIUsage)
+
                         // 用户管理
                         protected.GET("/users", s.handleGetUsers)
                         protected.GET("/user/me", s.handleGetMe)
@@ -311,10 +332,35 @@ func (s *Server) setupRoutes() {
                                 creditAdmin.GET("/users/:id/credits", s.creditHandler.HandleGetUserCreditsByAdmin)
                                 creditAdmin.GET("/users/:id/credits/transactions", s.creditHandler.HandleGetUserTransactionsByAdmin)
                         }
+
+                        // AI提供商健康状态（故障转移）
+                        admin.GET("/ai-providers/health", s.handleAIProviderHealth)
+                        admin.POST("/ai-providers/health/reset", s.handleResetAIProviderHealth)
                 }
         }
 }
 
+// handleAIProviderHealth AI提供商健康状态（错误率、延迟、冷却）
+func (s *Server) handleAIProviderHealth(c *gin.Context) {
+        c.JSON(http.StatusOK, gin.H{"providers": mcp.DefaultHealthTracker.Snapshot()})
+}
+
+// handleResetAIProviderHealth 清除AI提供商的冷却状态（如充值后手动恢复）
+func (s *Server) handleResetAIProviderHealth(c *gin.Context) {
+        var req struct {
+                Key string `json:"key" binding:"required"`
+        }
+        if err := c.ShouldBindJSON(&req); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+        if !mcp.DefaultHealthTracker.Reset(req.Key) {
+                c.JSON(http.StatusNotFound, gin.H{"error": "AI提供商不存在"})
+                return
+        }
+        c.JSON(http.StatusOK, gin.H{"message": "已清除冷却状态"})
+}
+
 // handleHealth 健康检查
 func (s *Server) handleHealth(c *gin.Context) {
         c.JSON(http.StatusOK, gin.H{
@@ -467,6 +513,13 @@ type CreateTraderRequest struct {
         IsCrossMargin        *bool   `json:"is_cross_margin"`        // 指针类型，nil表示使用默认值true
         UseCoinPool          bool    `json:"use_coin_pool"`
         UseOITop             bool    `json:"use_oi_top"`
+        ExchangeAccountID    string  `json:"exchange_account_id"`  // 使用的交易所凭证ID，为空使用交易所默认配置
+        AllowSharedAccount   bool    `json:"allow_shared_account"` // 是否允许与其他交易员共用同一账户
+        SizingMode           string  `json:"sizing_mode"`           // 开仓金额计算方式: ai（默认）/ volatility
+        RiskPerTradePct      float64 `json:"risk_per_trade_pct"`    // 波动率模式每笔风险预算（占净值百分比，默认1）
+        TargetVolatilityPct  float64 `json:"target_volatility_pct"` // 波动率模式目标波动率（ATR占价格百分比，0表示不缩放）
+        EnsembleConfig       string  `json:"ensemble_config"`       // 集成决策配置（JSON，空表示单模型决策）
+        FallbackModelIDs     string  `json:"fallback_model_ids"`    // 故障转移备用AI模型ID（逗号分隔，按顺序使用）
 }
 
 type ModelConfig struct {
@@ -490,10 +543,12 @@ type ExchangeConfig struct {
 
 type UpdateModelConfigRequest struct {
         Models map[string]struct {
-                Enabled         bool   `json:"enabled"`
-                APIKey          string `json:"api_key"`
-                CustomAPIURL    string `json:"custom_api_url"`
-                CustomModelName string `json:"custom_model_name"`
+                Enabled         bool     `json:"enabled"`
+                APIKey          string   `json:"api_key"`
+                CustomAPIURL    string   `json:"custom_api_url"`
+                CustomModelName string   `json:"custom_model_name"`
+                Temperature     *float64 `json:"temperature,omitempty"` // 采样温度（不传表示保持不变）
+                MaxTokens       *int     `json:"max_tokens,omitempty"`  // 最大输出token数（不传表示保持不变）
         } `json:"models"`
 }
 
@@ -542,6 +597,47 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
                 }
         }
 
+        // 模拟盘交易员：初始资金即模拟账户资金，必须大于0；无需API密钥，自动启用模拟盘交易所
+        if req.ExchangeID == "paper" {
+                if req.InitialBalance <= 0 {
+                        c.JSON(http.StatusBadRequest, gin.H{"error": "模拟盘交易员必须设置大于0的初始资金"})
+                        return
+                }
+                if err := s.database.UpdateExchange(userID, "paper", true, "", "", false, "", "", "", "", ""); err != nil {
+                        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("启用模拟盘失败: %v", err)})
+                        return
+                }
+        }
+
+        // 校验交易所凭证
+        if err := s.validateExchangeAccount(userID, req.ExchangeID, req.ExchangeAccountID); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+
+        // 仓位计算配置默认值：使用AI给出的金额，波动率模式每笔风险1%
+        sizingMode := req.SizingMode
+        if sizingMode == "" {
+                sizingMode = "ai"
+        }
+        riskPerTradePct := req.RiskPerTradePct
+        if riskPerTradePct == 0 {
+                riskPerTradePct = 1
+        }
+        if err := validateSizingConfig(sizingMode, riskPerTradePct, req.TargetVolatilityPct); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+        if err := s.validateEnsembleConfig(userID, req.AIModelID, req.EnsembleConfig); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+        fallbackModelIDs, err := s.validateFallbackModels(userID, req.AIModelID, req.FallbackModelIDs)
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+
         // 生成交易员ID
         traderID := fmt.Sprintf("%s_%s_%d", req.ExchangeID, req.AIModelID, time.Now().Unix())
 
@@ -606,10 +702,17 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
                 IsCrossMargin:        isCrossMargin,
                 ScanIntervalMinutes:  scanIntervalMinutes,
                 IsRunning:            false,
+                ExchangeAccountID:    req.ExchangeAccountID,
+                AllowSharedAccount:   req.AllowSharedAccount,
+                SizingMode:           sizingMode,
+                RiskPerTradePct:      riskPerTradePct,
+                TargetVolatilityPct:  req.TargetVolatilityPct,
+                EnsembleConfig:       req.EnsembleConfig,
+                FallbackModelIDs:     fallbackModelIDs,
         }
 
         // 保存到数据库
-        err := s.database.CreateTrader(trader)
+        err = s.database.CreateTrader(trader)
         if err != nil {
                 c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("创建交易员失败: %v", err)})
                 return
@@ -645,6 +748,86 @@ type UpdateTraderRequest struct {
         CustomPrompt        string  `json:"custom_prompt"`
         OverrideBasePrompt  bool    `json:"override_base_prompt"`
         IsCrossMargin       *bool   `json:"is_cross_margin"`
+        ExchangeAccountID   *string `json:"exchange_account_id"`  // 指针类型，nil表示保持原值
+        AllowSharedAccount  *bool   `json:"allow_shared_account"` // 指针类型，nil表示保持原值
+        SizingMode          *string  `json:"sizing_mode"`           // 指针类型，nil表示保持原值
+        RiskPerTradePct     *float64 `json:"risk_per_trade_pct"`    // 指针类型，nil表示保持原值
+        TargetVolatilityPct *float64 `json:"target_volatility_pct"` // 指针类型，nil表示保持原值
+        EnsembleConfig      *string  `json:"ensemble_config"`       // 指针类型，nil表示保持原值，空字符串表示关闭集成决策
+        FallbackModelIDs    *string  `json:"fallback_model_ids"`    // 指针类型，nil表示保持原值，空字符串表示不使用备用模型
+}
+
+// validateSizingConfig 校验仓位计算配置
+func validateSizingConfig(mode string, riskPerTradePct, targetVolatilityPct float64) error {
+        if mode != "ai" && mode != "volatility" {
+                return fmt.Errorf("无效的仓位计算方式: %s（可选 ai / volatility）", mode)
+        }
+        if riskPerTradePct <= 0 || riskPerTradePct > 10 {
+                return fmt.Errorf("每笔风险预算必须在0-10%%之间: %.2f", riskPerTradePct)
+        }
+        if targetVolatilityPct < 0 || targetVolatilityPct > 100 {
+                return fmt.Errorf("目标波动率必须在0-100%%之间: %.2f", targetVolatilityPct)
+        }
+        return nil
+}
+
+// validateEnsembleConfig 校验集成决策配置：投票规则有效，参与投票的模型属于当前用户、已启用且不是交易员的主模型
+func (s *Server) validateEnsembleConfig(userID, aiModelID, raw string) error {
+        ensembleConfig, err := decision.ParseEnsembleConfig(raw)
+        if err != nil || ensembleConfig == nil {
+                return err
+        }
+        if len(ensembleConfig.ModelIDs) == 0 {
+                return fmt.Errorf("集成决策至少需要配置一个其他AI模型")
+        }
+        return s.validateExtraModels(userID, aiModelID, ensembleConfig.ModelIDs, "集成决策模型")
+}
+
+// validateFallbackModels 校验故障转移备用模型（逗号分隔），返回规范化后的ID列表
+func (s *Server) validateFallbackModels(userID, aiModelID, raw string) (string, error) {
+        var modelIDs []string
+        for _, modelID := range strings.Split(raw, ",") {
+                if modelID = strings.TrimSpace(modelID); modelID != "" {
+                        modelIDs = append(modelIDs, modelID)
+                }
+        }
+        if err := s.validateExtraModels(userID, aiModelID, modelIDs, "备用模型"); err != nil {
+                return "", err
+        }
+        return strings.Join(modelIDs, ","), nil
+}
+
+// validateExtraModels 校验主模型之外的AI模型：属于当前用户、已启用、不重复且不是交易员的主模型
+func (s *Server) validateExtraModels(userID, aiModelID string, modelIDs []string, label string) error {
+        if len(modelIDs) == 0 {
+                return nil
+        }
+        models, err := s.database.GetAIModels(userID)
+        if err != nil {
+                return fmt.Errorf("获取AI模型配置失败: %w", err)
+        }
+        enabled := make(map[string]bool, len(models))
+        for _, model := range models {
+                enabled[model.ID] = model.Enabled
+        }
+        seen := make(map[string]bool, len(modelIDs))
+        for _, modelID := range modelIDs {
+                if modelID == aiModelID {
+                        return fmt.Errorf("%s不能包含交易员的主模型: %s", label, modelID)
+                }
+                if seen[modelID] {
+                        return fmt.Errorf("%s重复: %s", label, modelID)
+                }
+                seen[modelID] = true
+                isEnabled, exists := enabled[modelID]
+                if !exists {
+                        return fmt.Errorf("%s不存在: %s", label, modelID)
+                }
+                if !isEnabled {
+                        return fmt.Errorf("%s未启用: %s", label, modelID)
+                }
+        }
+        return nil
 }
 
 // handleUpdateTrader 更新交易员配置
@@ -700,6 +883,61 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
                 scanIntervalMinutes = existingTrader.ScanIntervalMinutes // 保持原值
         }
 
+        // 交易所凭证，未传时保持原值（切换交易所时原凭证失效，需要重新选择）
+        exchangeAccountID := existingTrader.ExchangeAccountID
+        if req.ExchangeAccountID != nil {
+                exchangeAccountID = *req.ExchangeAccountID
+        } else if req.ExchangeID != existingTrader.ExchangeID {
+                exchangeAccountID = ""
+        }
+        if err := s.validateExchangeAccount(userID, req.ExchangeID, exchangeAccountID); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+        allowSharedAccount := existingTrader.AllowSharedAccount
+        if req.AllowSharedAccount != nil {
+                allowSharedAccount = *req.AllowSharedAccount
+        }
+
+        // 仓位计算配置，未传时保持原值
+        sizingMode := existingTrader.SizingMode
+        if req.SizingMode != nil {
+                sizingMode = *req.SizingMode
+        }
+        riskPerTradePct := existingTrader.RiskPerTradePct
+        if req.RiskPerTradePct != nil {
+                riskPerTradePct = *req.RiskPerTradePct
+        }
+        targetVolatilityPct := existingTrader.TargetVolatilityPct
+        if req.TargetVolatilityPct != nil {
+                targetVolatilityPct = *req.TargetVolatilityPct
+        }
+        if err := validateSizingConfig(sizingMode, riskPerTradePct, targetVolatilityPct); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+
+        // 集成决策配置，未传时保持原值（更换主模型时重新校验）
+        ensembleConfig := existingTrader.EnsembleConfig
+        if req.EnsembleConfig != nil {
+                ensembleConfig = *req.EnsembleConfig
+        }
+        if err := s.validateEnsembleConfig(userID, req.AIModelID, ensembleConfig); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+
+        // 备用模型，未传时保持原值
+        fallbackModelIDs := existingTrader.FallbackModelIDs
+        if req.FallbackModelIDs != nil {
+                fallbackModelIDs = *req.FallbackModelIDs
+        }
+        fallbackModelIDs, err = s.validateFallbackModels(userID, req.AIModelID, fallbackModelIDs)
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+
         // 更新交易员配置
         trader := &config.TraderRecord{
                 ID:                   traderID,
@@ -717,6 +955,13 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
                 IsCrossMargin:        isCrossMargin,
                 ScanIntervalMinutes:  scanIntervalMinutes,
                 IsRunning:            existingTrader.IsRunning, // 保持原值
+                ExchangeAccountID:    exchangeAccountID,
+                AllowSharedAccount:   allowSharedAccount,
+                SizingMode:           sizingMode,
+                RiskPerTradePct:      riskPerTradePct,
+                TargetVolatilityPct:  targetVolatilityPct,
+                EnsembleConfig:       ensembleConfig,
+                FallbackModelIDs:     fallbackModelIDs,
         }
 
         // 更新数据库
@@ -821,13 +1066,15 @@ func (s *Server) handleStartTrader(c *gin.Context) {
                 return
         }
 
-        // 启动交易员
-        go func() {
-                log.Printf("▶️  启动交易员 %s (%s)", traderID, trader.GetName())
-                if err := trader.Run(); err != nil {
-                        log.Printf("❌ 交易员 %s 运行错误: %v", trader.GetName(), err)
+        // 启动交易员（与其他运行中的交易员共用同一账户时拒绝启动）
+        if err := s.traderManager.StartTrader(traderID); err != nil {
+                if errors.Is(err, manager.ErrAccountInUse) {
+                        c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+                        return
                 }
-        }()
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
 
         // 更新数据库中的运行状态
         err = s.database.UpdateTraderStatus(userID, traderID, true)
@@ -939,6 +1186,82 @@ func (s *Server) handleUpdateTraderPrompt(c *gin.Context) {
         c.JSON(http.StatusOK, gin.H{"message": "自定义prompt已更新"})
 }
 
+// handleResetGuardianHalt 人工解除实时风控净值下限触发的交易暂停
+func (s *Server) handleResetGuardianHalt(c *gin.Context) {
+        userID := c.GetString("user_id")
+        traderID := c.Param("id")
+
+        // 校验交易员是否属于当前用户
+        traders, err := s.database.GetTraders(userID)
+        if err != nil {
+                log.Printf("❌ 获取用户 %s 的交易员列表失败: %v", userID, err)
+                c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易员列表失败"})
+                return
+        }
+        owned := false
+        for _, trader := range traders {
+                if trader.ID == traderID {
+                        owned = true
+                        break
+                }
+        }
+        if !owned {
+                c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+                return
+        }
+
+        trader, err := s.traderManager.GetTrader(traderID)
+        if err != nil {
+                c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载"})
+                return
+        }
+        if err := trader.ResetGuardianHalt(); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+
+        log.Printf("▶️  交易员 %s 的实时风控暂停已解除", trader.GetName())
+        c.JSON(http.StatusOK, gin.H{"message": "实时风控暂停已解除"})
+}
+
+// handleResetCircuitBreaker 人工重置账户熔断（解除暂停，以当前净值作为新的峰值和当日起点）
+func (s *Server) handleResetCircuitBreaker(c *gin.Context) {
+        userID := c.GetString("user_id")
+        traderID := c.Param("id")
+
+        // 校验交易员是否属于当前用户
+        traders, err := s.database.GetTraders(userID)
+        if err != nil {
+                log.Printf("❌ 获取用户 %s 的交易员列表失败: %v", userID, err)
+                c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易员列表失败"})
+                return
+        }
+        owned := false
+        for _, trader := range traders {
+                if trader.ID == traderID {
+                        owned = true
+                        break
+                }
+        }
+        if !owned {
+                c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+                return
+        }
+
+        trader, err := s.traderManager.GetTrader(traderID)
+        if err != nil {
+                c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载"})
+                return
+        }
+        if err := trader.ResetCircuitBreaker(); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+
+        log.Printf("▶️  交易员 %s 的账户熔断已重置", trader.GetName())
+        c.JSON(http.StatusOK, gin.H{"message": "账户熔断已重置"})
+}
+
 // handleGetModelConfigs 获取AI模型配置
 func (s *Server) handleGetModelConfigs(c *gin.Context) {
         userID := c.GetString("user_id")
@@ -970,6 +1293,10 @@ func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
                         c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新模型 %s 失败: %v", modelID, err)})
                         return
                 }
+                if err := s.database.UpdateAIModelParams(userID, modelID, modelData.Temperature, modelData.MaxTokens); err != nil {
+                        c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("更新模型 %s 参数失败: %v", modelID, err)})
+                        return
+                }
         }
 
         // 重新加载该用户的所有交易员，使新配置立即生效
@@ -1027,6 +1354,188 @@ func (s *Server) handleUpdateExchangeConfigs(c *gin.Context) {
         c.JSON(http.StatusOK, gin.H{"message": "交易所配置已更新"})
 }
 
+// ExchangeAccountRequest 创建/更新交易所命名凭证请求
+type ExchangeAccountRequest struct {
+        ExchangeID            string `json:"exchange_id"`
+        Name                  string `json:"name" binding:"required"`
+        APIKey                string `json:"api_key"`    // 更新时为空表示保持原值
+        SecretKey             string `json:"secret_key"` // 更新时为空表示保持原值
+        Passphrase            string `json:"passphrase"` // OKX/Bitget，更新时为空表示保持原值
+        Testnet               bool   `json:"testnet"`
+        SubAccount            string `json:"sub_account"` // 币安子账户邮箱 / OKX子账户名
+        HyperliquidWalletAddr string `json:"hyperliquid_wallet_addr"`
+        AsterUser             string `json:"aster_user"`
+        AsterSigner           string `json:"aster_signer"`
+        AsterPrivateKey       string `json:"aster_private_key"` // 更新时为空表示保持原值
+}
+
+// subAccountExchanges 支持子账户标识的交易所
+var subAccountExchanges = map[string]bool{"binance": true, "okx": true}
+
+// exchangeAccountView 凭证的对外展示形式，不返回任何密钥
+func exchangeAccountView(account *config.ExchangeAccount) gin.H {
+        return gin.H{
+                "id":                      account.ID,
+                "exchange_id":             account.ExchangeID,
+                "name":                    account.Name,
+                "testnet":                 account.Testnet,
+                "sub_account":             account.SubAccount,
+                "hyperliquid_wallet_addr": account.HyperliquidWalletAddr,
+                "aster_user":              account.AsterUser,
+                "aster_signer":            account.AsterSigner,
+                "has_api_key":             account.APIKey != "",
+                "has_secret_key":          account.SecretKey != "",
+                "has_passphrase":          account.Passphrase != "",
+                "has_aster_private_key":   account.AsterPrivateKey != "",
+                "created_at":              account.CreatedAt,
+                "updated_at":              account.UpdatedAt,
+        }
+}
+
+// validateExchangeAccount 校验交易员选择的凭证属于当前用户且与交易所一致，accountID为空表示使用交易所默认配置
+func (s *Server) validateExchangeAccount(userID, exchangeID, accountID string) error {
+        if accountID == "" {
+                return nil
+        }
+        account, err := s.database.GetExchangeAccount(userID, accountID)
+        if err != nil {
+                return fmt.Errorf("交易所凭证不存在: %s", accountID)
+        }
+        if account.ExchangeID != exchangeID {
+                return fmt.Errorf("交易所凭证 %s 属于 %s，与所选交易所 %s 不一致", account.Name, account.ExchangeID, exchangeID)
+        }
+        return nil
+}
+
+// handleGetExchangeAccounts 获取交易所命名凭证
+func (s *Server) handleGetExchangeAccounts(c *gin.Context) {
+        userID := c.GetString("user_id")
+        accounts, err := s.database.GetExchangeAccounts(userID)
+        if err != nil {
+                c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取交易所凭证失败: %v", err)})
+                return
+        }
+
+        result := make([]gin.H, 0, len(accounts))
+        for _, account := range accounts {
+                result = append(result, exchangeAccountView(account))
+        }
+        c.JSON(http.StatusOK, result)
+}
+
+// handleCreateExchangeAccount 添加交易所命名凭证
+func (s *Server) handleCreateExchangeAccount(c *gin.Context) {
+        userID := c.GetString("user_id")
+        var req ExchangeAccountRequest
+        if err := c.ShouldBindJSON(&req); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+
+        if req.ExchangeID == "" || req.ExchangeID == "paper" {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "请选择需要凭证的交易所"})
+                return
+        }
+        if req.SubAccount != "" && !subAccountExchanges[req.ExchangeID] {
+                c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("交易所 %s 不支持子账户标识", req.ExchangeID)})
+                return
+        }
+        if req.APIKey == "" && req.AsterPrivateKey == "" {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "API密钥不能为空"})
+                return
+        }
+
+        account := &config.ExchangeAccount{
+                UserID:                userID,
+                ExchangeID:            req.ExchangeID,
+                Name:                  strings.TrimSpace(req.Name),
+                APIKey:                req.APIKey,
+                SecretKey:             req.SecretKey,
+                Passphrase:            req.Passphrase,
+                Testnet:               req.Testnet,
+                SubAccount:            strings.TrimSpace(req.SubAccount),
+                HyperliquidWalletAddr: req.HyperliquidWalletAddr,
+                AsterUser:             req.AsterUser,
+                AsterSigner:           req.AsterSigner,
+                AsterPrivateKey:       req.AsterPrivateKey,
+        }
+        if err := s.database.CreateExchangeAccount(account); err != nil {
+                c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("添加交易所凭证失败（同一交易所下名称不能重复）: %v", err)})
+                return
+        }
+
+        log.Printf("✓ 已添加交易所凭证: %s/%s", account.ExchangeID, account.Name)
+        c.JSON(http.StatusCreated, exchangeAccountView(account))
+}
+
+// handleUpdateExchangeAccount 更新交易所命名凭证
+func (s *Server) handleUpdateExchangeAccount(c *gin.Context) {
+        userID := c.GetString("user_id")
+        accountID := c.Param("id")
+        var req ExchangeAccountRequest
+        if err := c.ShouldBindJSON(&req); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+
+        existing, err := s.database.GetExchangeAccount(userID, accountID)
+        if err != nil {
+                c.JSON(http.StatusNotFound, gin.H{"error": "交易所凭证不存在"})
+                return
+        }
+        if req.SubAccount != "" && !subAccountExchanges[existing.ExchangeID] {
+                c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("交易所 %s 不支持子账户标识", existing.ExchangeID)})
+                return
+        }
+
+        // 交易所不可修改；密钥为空时保留原值
+        account := &config.ExchangeAccount{
+                ID:                    accountID,
+                UserID:                userID,
+                ExchangeID:            existing.ExchangeID,
+                Name:                  strings.TrimSpace(req.Name),
+                APIKey:                req.APIKey,
+                SecretKey:             req.SecretKey,
+                Passphrase:            req.Passphrase,
+                Testnet:               req.Testnet,
+                SubAccount:            strings.TrimSpace(req.SubAccount),
+                HyperliquidWalletAddr: req.HyperliquidWalletAddr,
+                AsterUser:             req.AsterUser,
+                AsterSigner:           req.AsterSigner,
+                AsterPrivateKey:       req.AsterPrivateKey,
+        }
+        if err := s.database.UpdateExchangeAccount(account); err != nil {
+                c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新交易所凭证失败: %v", err)})
+                return
+        }
+
+        // 重新加载该用户的交易员，使新凭证对尚未加载的交易员生效
+        if err := s.traderManager.LoadUserTraders(s.database, userID); err != nil {
+                log.Printf("⚠️ 重新加载用户交易员到内存失败: %v", err)
+        }
+
+        log.Printf("✓ 已更新交易所凭证: %s/%s", account.ExchangeID, account.Name)
+        c.JSON(http.StatusOK, gin.H{"message": "交易所凭证已更新"})
+}
+
+// handleDeleteExchangeAccount 删除交易所命名凭证（仍有交易员使用时拒绝删除）
+func (s *Server) handleDeleteExchangeAccount(c *gin.Context) {
+        userID := c.GetString("user_id")
+        accountID := c.Param("id")
+
+        if err := s.database.DeleteExchangeAccount(userID, accountID); err != nil {
+                if errors.Is(err, sql.ErrNoRows) {
+                        c.JSON(http.StatusNotFound, gin.H{"error": "交易所凭证不存在"})
+                        return
+                }
+                c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("删除交易所凭证失败: %v", err)})
+                return
+        }
+
+        log.Printf("✓ 已删除交易所凭证: %s", accountID)
+        c.JSON(http.StatusOK, gin.H{"message": "交易所凭证已删除"})
+}
+
 // handleGetUserSignalSource 获取用户信号源配置
 func (s *Server) handleGetUserSignalSource(c *gin.Context) {
         userID := c.GetString("user_id")
@@ -1154,6 +1663,13 @@ func (s *Server) handleGetTraderConfig(c *gin.Context) {
                 "is_cross_margin":       traderConfig.IsCrossMargin,
                 "use_coin_pool":         traderConfig.UseCoinPool,
                 "use_oi_top":            traderConfig.UseOITop,
+                "exchange_account_id":   traderConfig.ExchangeAccountID,
+                "allow_shared_account":  traderConfig.AllowSharedAccount,
+                "sizing_mode":           traderConfig.SizingMode,
+                "risk_per_trade_pct":    traderConfig.RiskPerTradePct,
+                "target_volatility_pct": traderConfig.TargetVolatilityPct,
+                "ensemble_config":       traderConfig.EnsembleConfig,
+                "fallback_model_ids":    traderConfig.FallbackModelIDs,
                 "is_running":            isRunning,
         }
 
@@ -1452,6 +1968,216 @@ func (s *Server) handlePerformance(c *gin.Context) {
         c.JSON(http.StatusOK, performance)
 }
 
+// CreateBacktestRequest 创建回测任务请求
+type CreateBacktestRequest struct {
+        Name                 string   `json:"name"`
+        Symbols              []string `json:"symbols" binding:"required"`
+        StartTime            string   `json:"start_time" binding:"required"`
+        EndTime              string   `json:"end_time" binding:"required"`
+        IntervalMinutes      int      `json:"interval_minutes"`
+        InitialBalance       float64  `json:"initial_balance"`
+        BTCETHLeverage       int      `json:"btc_eth_leverage"`
+        AltcoinLeverage      int      `json:"altcoin_leverage"`
+        IsCrossMargin        *bool    `json:"is_cross_margin"`
+        FeeRate              *float64 `json:"fee_rate"`      // 手续费率（不传使用模拟盘默认值，0表示免手续费）
+        SlippageRate         *float64 `json:"slippage_rate"` // 滑点比例（不传使用模拟盘默认值，0表示无滑点）
+        SystemPromptTemplate string   `json:"system_prompt_template"`
+        CustomPrompt         string   `json:"custom_prompt"`
+        OverrideBasePrompt   bool     `json:"override_base_prompt"`
+        Source               string   `json:"source"`      // stub / recorded / live
+        TraderID             string   `json:"trader_id"`   // recorded: 回放该交易员的决策日志
+        AIModelID            string   `json:"ai_model_id"` // live: 使用该AI模型实时决策
+}
+
+// handleCreateBacktest 提交回测任务（后台执行，通过GET /api/backtests/:id 查询结果）
+func (s *Server) handleCreateBacktest(c *gin.Context) {
+        userID := c.GetString("user_id")
+
+        var req CreateBacktestRequest
+        if err := c.ShouldBindJSON(&req); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+
+        // 币种名称会拼接为K线文件路径，只允许大写字母和数字
+        for i, symbol := range req.Symbols {
+                req.Symbols[i] = strings.ToUpper(strings.TrimSpace(symbol))
+                if err := backtest.ValidateSymbol(req.Symbols[i]); err != nil {
+                        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                        return
+                }
+        }
+
+        start, err := backtest.ParseTime(req.StartTime)
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+        end, err := backtest.ParseTime(req.EndTime)
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+
+        if req.InitialBalance <= 0 {
+                req.InitialBalance = 1000
+        }
+        if (req.FeeRate != nil && *req.FeeRate < 0) || (req.SlippageRate != nil && *req.SlippageRate < 0) {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "手续费率和滑点不能为负数"})
+                return
+        }
+        isCrossMargin := true
+        if req.IsCrossMargin != nil {
+                isCrossMargin = *req.IsCrossMargin
+        }
+        if req.Source == "" {
+                req.Source = backtest.SourceStub
+        }
+
+        // 根据决策来源构建AI客户端
+        var source mcp.AIClient
+        switch req.Source {
+        case backtest.SourceStub:
+                source = backtest.NewWaitStub()
+        case backtest.SourceRecorded:
+                if req.TraderID == "" {
+                        c.JSON(http.StatusBadRequest, gin.H{"error": "回放模式需要指定trader_id"})
+                        return
+                }
+                if _, _, _, err := s.database.GetTraderConfig(userID, req.TraderID); err != nil {
+                        c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+                        return
+                }
+                recorded, err := backtest.LoadRecordedSource(fmt.Sprintf("decision_logs/%s", req.TraderID))
+                if err != nil {
+                        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                        return
+                }
+                source = recorded
+        case backtest.SourceLive:
+                models, err := s.database.GetAIModels(userID)
+                if err != nil {
+                        c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取AI模型配置失败: %v", err)})
+                        return
+                }
+                var model *config.AIModelConfig
+                for _, m := range models {
+                        if m.ID == req.AIModelID {
+                                model = m
+                                break
+                        }
+                }
+                if model == nil {
+                        c.JSON(http.StatusNotFound, gin.H{"error": "AI模型不存在"})
+                        return
+                }
+                live, err := backtest.NewLiveSource(model.Provider, model.APIKey, model.CustomAPIURL, model.CustomModelName)
+                if err != nil {
+                        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                        return
+                }
+                source = live
+        default:
+                c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("不支持的决策来源: %s", req.Source)})
+                return
+        }
+
+        dataDir, _ := s.database.GetSystemConfig("backtest_data_dir")
+        if dataDir == "" {
+                dataDir = "backtest_data"
+        }
+
+        job, err := s.backtests.Submit(userID, req.Source, backtest.Config{
+                Name:                 req.Name,
+                Symbols:              req.Symbols,
+                Start:                start,
+                End:                  end,
+                Interval:             time.Duration(req.IntervalMinutes) * time.Minute,
+                InitialBalance:       req.InitialBalance,
+                BTCETHLeverage:       req.BTCETHLeverage,
+                AltcoinLeverage:      req.AltcoinLeverage,
+                IsCrossMargin:        isCrossMargin,
+                FeeRate:              req.FeeRate,
+                SlippageRate:         req.SlippageRate,
+                SystemPromptTemplate: req.SystemPromptTemplate,
+                CustomPrompt:         req.CustomPrompt,
+                OverrideBasePrompt:   req.OverrideBasePrompt,
+                Store:                backtest.NewFileKlineStore(dataDir),
+                Source:               source,
+        })
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+
+        log.Printf("🧪 用户 %s 提交回测任务: %s (模板: %s, 来源: %s)", userID, job.ID, job.SystemPromptTemplate, job.Source)
+        c.JSON(http.StatusAccepted, job)
+}
+
+// handleListBacktests 当前用户的回测任务列表
+func (s *Server) handleListBacktests(c *gin.Context) {
+        userID := c.GetString("user_id")
+        c.JSON(http.StatusOK, s.backtests.List(userID))
+}
+
+// handleGetBacktest 回测任务详情（完成后包含报告）
+func (s *Server) handleGetBacktest(c *gin.Context) {
+        userID := c.GetString("user_id")
+        job, err := s.backtests.Get(userID, c.Param("id"))
+        if err != nil {
+                c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+                return
+        }
+        c.JSON(http.StatusOK, job)
+}
+
+// handleAIUsage 当前用户的AI用量和费用汇总（按日或按月，可按交易员筛选）
+func (s *Server) handleAIUsage(c *gin.Context) {
+        userID := c.GetString("user_id")
+        period := c.DefaultQuery("period", "day")
+        if period != "day" && period != "month" {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "period必须为day或month"})
+                return
+        }
+        defaultDays := "30"
+        if period == "month" {
+                defaultDays = "365"
+        }
+        days, err := strconv.Atoi(c.DefaultQuery("days", defaultDays))
+        if err != nil || days < 1 || days > 730 {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "days必须为1-730之间的整数"})
+                return
+        }
+
+        since := time.Now().AddDate(0, 0, -days)
+        summaries, err := s.database.GetAIUsageSummary(userID, c.Query("trader_id"), period, since)
+        if err != nil {
+                c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取AI用量失败: %v", err)})
+                return
+        }
+
+        var total config.AIUsageSummary
+        for _, summary := range summaries {
+                total.Cycles += summary.Cycles
+                total.Calls += summary.Calls
+                total.PromptTokens += summary.PromptTokens
+                total.CompletionTokens += summary.CompletionTokens
+                total.CostUSD += summary.CostUSD
+        }
+        c.JSON(http.StatusOK, gin.H{
+                "period":    period,
+                "since":     since,
+                "summaries": summaries,
+                "total": gin.H{
+                        "cycles":            total.Cycles,
+                        "calls":             total.Calls,
+                        "prompt_tokens":     total.PromptTokens,
+                        "completion_tokens": total.CompletionTokens,
+                        "cost_usd":          total.CostUSD,
+                },
+        })
+}
+
 // authMiddleware JWT认证中间件
 func (s *Server) authMiddleware() gin.HandlerFunc {
         return func(c *gin.Context) {
@@ -1950,6 +2676,8 @@ func (s *Server) Start() error {
         log.Printf("  • POST /api/traders/:id/stop  - 停止AI交易员")
         log.Printf("  • GET  /api/models           - 获取AI模型配置")
         log.Printf("  • PUT  /api/models           - 更新AI模型配置")
+        log.Printf("  • GET  /api/exchange-accounts - 获取交易所命名凭证（不含密钥）")
+        log.Printf("  • POST /api/exchange-accounts - 添加交易所命名凭证（支持子账户）")
         log.Printf("  • GET  /api/exchanges        - 获取交易所配置")
         log.Printf("  • PUT  /api/exchanges        - 更新交易所配置")
         log.Printf("  • GET  /api/status?trader_id=xxx     - 指定trader的系统状态")
@@ -1959,6 +2687,9 @@ func (s *Server) Start() error {
         log.Printf("  • GET  /api/decisions/latest?trader_id=xxx - 指定trader的最新决策")
         log.Printf("  • GET  /api/statistics?trader_id=xxx - 指定trader的统计信息")
         log.Printf("  • GET  /api/performance?trader_id=xxx - 指定trader的AI学习表现分析")
+        log.Printf("  • POST /api/backtests        - 提交历史回测任务")
+        log.Printf("  • GET  /api/backtests        - 回测任务列表")
+        log.Printf("  • GET  /api/backtests/:id    - 回测任务详情与报告")
         log.Println()
         log.Printf("✅ API服务器就绪，等待请求...")
 
@@ -0,0 +1,215 @@
+package backtest
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/mcp"
+	"nofx/trader"
+)
+
+// 回测默认参数
+const (
+	defaultInterval        = 3 * time.Minute
+	defaultBTCETHLeverage  = 5
+	defaultAltcoinLeverage = 5
+	defaultPromptTemplate  = "default"
+)
+
+// Config 回测配置
+type Config struct {
+	Name            string        // 回测名称（用于日志）
+	Symbols         []string      // 交易币种
+	Start           time.Time     // 开始时间（需有足够的前置K线计算指标）
+	End             time.Time     // 结束时间
+	Interval        time.Duration // 决策周期（默认3分钟）
+	InitialBalance  float64       // 初始资金
+	BTCETHLeverage  int           // BTC/ETH杠杆上限
+	AltcoinLeverage int           // 山寨币杠杆上限
+	IsCrossMargin   bool          // 全仓模式
+	FeeRate         *float64      // 手续费率（nil使用模拟盘默认值，0表示免手续费）
+	SlippageRate    *float64      // 滑点比例（nil使用模拟盘默认值，0表示无滑点）
+
+	// 提示词配置（对比不同模板的核心参数）
+	SystemPromptTemplate string
+	CustomPrompt         string
+	OverrideBasePrompt   bool
+
+	Store  KlineStore   // 历史K线来源
+	Source mcp.AIClient // 决策来源（StubSource / RecordedSource / *mcp.Client）
+	LogDir string       // 决策日志目录（为空时使用临时目录，回测结束后删除）
+}
+
+// normalize 校验配置并填充默认值
+func (c *Config) normalize() error {
+	if len(c.Symbols) == 0 {
+		return fmt.Errorf("至少需要一个交易币种")
+	}
+	symbols := make([]string, 0, len(c.Symbols))
+	for _, symbol := range c.Symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if err := ValidateSymbol(symbol); err != nil {
+			return err
+		}
+		symbols = append(symbols, symbol)
+	}
+	c.Symbols = symbols
+	if c.Start.IsZero() || c.End.IsZero() || !c.End.After(c.Start) {
+		return fmt.Errorf("回测时间范围无效: %s ~ %s", c.Start.Format(time.RFC3339), c.End.Format(time.RFC3339))
+	}
+	if c.InitialBalance <= 0 {
+		return fmt.Errorf("初始资金必须大于0")
+	}
+	if c.Store == nil {
+		return fmt.Errorf("未配置K线数据来源")
+	}
+	if c.Source == nil {
+		return fmt.Errorf("未配置决策来源")
+	}
+	if c.Interval <= 0 {
+		c.Interval = defaultInterval
+	}
+	if c.BTCETHLeverage <= 0 {
+		c.BTCETHLeverage = defaultBTCETHLeverage
+	}
+	if c.AltcoinLeverage <= 0 {
+		c.AltcoinLeverage = defaultAltcoinLeverage
+	}
+	if c.SystemPromptTemplate == "" {
+		c.SystemPromptTemplate = defaultPromptTemplate
+	}
+	if c.Name == "" {
+		c.Name = "Backtest"
+	}
+	return nil
+}
+
+// ParseTime 解析回测时间参数（支持RFC3339、"2006-01-02 15:04"和"2006-01-02"，默认UTC）
+func ParseTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(value), time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间: %s", value)
+}
+
+// fillCollector 收集模拟盘成交（模拟盘只保留最近500笔，回测需要全部）
+type fillCollector struct {
+	mu    sync.Mutex
+	fills []trader.PaperFill
+}
+
+func (c *fillCollector) add(fill trader.PaperFill) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fills = append(c.fills, fill)
+}
+
+func (c *fillCollector) all() []trader.PaperFill {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]trader.PaperFill(nil), c.fills...)
+}
+
+// Run 执行回测：按决策周期推进模拟时间，用历史K线驱动AutoTrader和模拟盘
+func Run(cfg Config) (*Report, error) {
+	if err := cfg.normalize(); err != nil {
+		return nil, err
+	}
+
+	feed, err := newHistoricalFeed(cfg.Store, cfg.Symbols)
+	if err != nil {
+		return nil, err
+	}
+	feed.Advance(cfg.Start, nil)
+
+	logDir := cfg.LogDir
+	if logDir == "" {
+		logDir, err = os.MkdirTemp("", "nofx_backtest_")
+		if err != nil {
+			return nil, fmt.Errorf("创建回测日志目录失败: %w", err)
+		}
+		defer os.RemoveAll(logDir)
+	}
+
+	collector := &fillCollector{}
+	paper, err := trader.NewPaperTrader(trader.PaperTraderConfig{
+		TraderID:       "backtest",
+		InitialBalance: cfg.InitialBalance,
+		FeeRate:        cfg.FeeRate,
+		SlippageRate:   cfg.SlippageRate,
+		PriceSource:    feed.Price,
+		Clock:          feed.now,
+		FillHandler:    collector.add,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("初始化模拟盘失败: %w", err)
+	}
+
+	autoTrader, err := trader.NewAutoTrader(trader.AutoTraderConfig{
+		ID:                   "backtest",
+		Name:                 cfg.Name,
+		AIModel:              "backtest",
+		Exchange:             "paper",
+		ScanInterval:         cfg.Interval,
+		InitialBalance:       cfg.InitialBalance,
+		BTCETHLeverage:       cfg.BTCETHLeverage,
+		AltcoinLeverage:      cfg.AltcoinLeverage,
+		IsCrossMargin:        cfg.IsCrossMargin,
+		TradingCoins:         cfg.Symbols,
+		SystemPromptTemplate: cfg.SystemPromptTemplate,
+		Trader:               paper,
+		AIClient:             cfg.Source,
+		MarketData:           feed.Data,
+		Clock:                feed.now,
+		DecisionLogDir:       logDir,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("初始化回测交易员失败: %w", err)
+	}
+	autoTrader.SetCustomPrompt(cfg.CustomPrompt)
+	autoTrader.SetOverrideBasePrompt(cfg.OverrideBasePrompt)
+
+	log.Printf("🧪 [%s] 开始回测: %v %s ~ %s, 周期 %v, 模板 %s", cfg.Name, cfg.Symbols,
+		cfg.Start.Format("2006-01-02 15:04"), cfg.End.Format("2006-01-02 15:04"), cfg.Interval, cfg.SystemPromptTemplate)
+
+	builder := newReportBuilder(cfg)
+	for t := cfg.Start; !t.After(cfg.End); t = t.Add(cfg.Interval) {
+		// 回放上一周期到当前时刻的价格路径，触发止损止盈和强平
+		feed.Advance(t, func(symbol string, price float64) {
+			paper.UpdateMarkPrice(symbol, price)
+		})
+
+		cycleErr := autoTrader.RunOnce()
+		if errors.Is(cycleErr, ErrDecisionsExhausted) {
+			log.Printf("🧪 [%s] 录制决策已回放完毕，回测提前结束于 %s", cfg.Name, t.Format("2006-01-02 15:04"))
+			break
+		}
+
+		balance, err := paper.GetBalance()
+		if err != nil {
+			return nil, fmt.Errorf("获取模拟盘余额失败: %w", err)
+		}
+		builder.addCycle(t, balance.TotalEquity(), cycleErr)
+	}
+
+	balance, err := paper.GetBalance()
+	if err != nil {
+		return nil, fmt.Errorf("获取模拟盘余额失败: %w", err)
+	}
+	positions, err := paper.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取模拟盘持仓失败: %w", err)
+	}
+
+	report := builder.build(paper, balance, len(positions), collector.all())
+	log.Printf("🧪 [%s] 回测完成: %d 个周期, 收益 %.2f%%, 最大回撤 %.2f%%, 夏普 %.3f, 交易 %d 笔",
+		cfg.Name, report.Cycles, report.TotalReturnPct, report.MaxDrawdownPct, report.SharpeRatio, len(report.Trades))
+	return report, nil
+}
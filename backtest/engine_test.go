@@ -0,0 +1,262 @@
+package backtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/logger"
+	"nofx/market"
+)
+
+// makeTrendKlines 生成单边上涨的3分钟K线
+func makeTrendKlines(start time.Time, count int, basePrice, step float64) []market.Kline {
+	klines := make([]market.Kline, 0, count)
+	for i := 0; i < count; i++ {
+		open := basePrice + float64(i)*step
+		close := open + step
+		openTime := start.Add(time.Duration(i) * 3 * time.Minute)
+		klines = append(klines, market.Kline{
+			OpenTime:  openTime.UnixMilli(),
+			Open:      open,
+			High:      close + step*0.2,
+			Low:       open - step*0.2,
+			Close:     close,
+			Volume:    1000,
+			CloseTime: openTime.Add(3*time.Minute).UnixMilli() - 1,
+		})
+	}
+	return klines
+}
+
+// openOnceStub 第一次调用开多（带止损止盈），之后一直观望
+func openOnceStub(symbol string) *StubSource {
+	opened := false
+	return &StubSource{Respond: func(systemPrompt, userPrompt string) string {
+		if opened {
+			return "继续持有\n\n[{\"symbol\":\"" + symbol + "\",\"action\":\"hold\",\"reasoning\":\"trend\"}]"
+		}
+		opened = true
+		// 按prompt中的当前价格设置止损止盈（风险回报比满足3:1）
+		price := currentPriceFromPrompt(userPrompt)
+		return fmt.Sprintf("趋势向上，开多\n\n[{\"symbol\":\"%s\",\"action\":\"open_long\",\"leverage\":5,\"position_size_usd\":1000,\"stop_loss\":%.4f,\"take_profit\":%.4f,\"confidence\":80,\"reasoning\":\"trend\"}]",
+			symbol, price*0.9, price*1.05)
+	}}
+}
+
+// currentPriceFromPrompt 提取prompt中的 "current_price = X"
+func currentPriceFromPrompt(prompt string) float64 {
+	idx := strings.Index(prompt, "current_price = ")
+	if idx < 0 {
+		return 0
+	}
+	var price float64
+	fmt.Sscanf(prompt[idx+len("current_price = "):], "%f", &price)
+	return price
+}
+
+func TestRunTakesProfitOnTrend(t *testing.T) {
+	dataStart := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryKlineStore()
+	store.Add("BTCUSDT", Interval3m, makeTrendKlines(dataStart, 240, 100, 0.5))
+
+	start := dataStart.Add(150 * 3 * time.Minute)
+	report, err := Run(Config{
+		Symbols:        []string{"btcusdt"},
+		Start:          start,
+		End:            start.Add(40 * 3 * time.Minute),
+		InitialBalance: 1000,
+		Store:          store,
+		Source:         openOnceStub("BTCUSDT"),
+		LogDir:         t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("回测失败: %v", err)
+	}
+
+	if report.Cycles != 41 || len(report.EquityCurve) != 41 {
+		t.Fatalf("期望41个周期, got %d (曲线 %d)", report.Cycles, len(report.EquityCurve))
+	}
+	if report.FailedCycles != 0 {
+		t.Errorf("不应有失败周期, got %d", report.FailedCycles)
+	}
+	if len(report.Trades) != 1 {
+		t.Fatalf("期望1笔止盈交易, got %d", len(report.Trades))
+	}
+	trade := report.Trades[0]
+	if trade.PnL <= 0 || trade.WasStopLoss || trade.Leverage != 5 {
+		t.Errorf("止盈交易不符合预期: %+v", trade)
+	}
+	if report.FinalEquity <= report.InitialBalance || report.TotalReturnPct <= 0 {
+		t.Errorf("上涨趋势中应盈利: final=%.4f return=%.4f%%", report.FinalEquity, report.TotalReturnPct)
+	}
+	if report.TotalFees <= 0 {
+		t.Errorf("应计入手续费, got %.6f", report.TotalFees)
+	}
+	stats := report.Performance.SymbolStats["BTCUSDT"]
+	if stats == nil || stats.WinningTrades != 1 || report.Performance.WinRate != 100 {
+		t.Errorf("币种统计不正确: %+v", stats)
+	}
+	if report.OpenPositions != 0 {
+		t.Errorf("止盈后不应有持仓, got %d", report.OpenPositions)
+	}
+}
+
+func TestRunWithZeroFeeRate(t *testing.T) {
+	dataStart := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryKlineStore()
+	store.Add("BTCUSDT", Interval3m, makeTrendKlines(dataStart, 240, 100, 0.5))
+
+	// 显式设置为0：免手续费回测（不回退到模拟盘默认费率）
+	zero := 0.0
+	start := dataStart.Add(150 * 3 * time.Minute)
+	report, err := Run(Config{
+		Symbols:        []string{"BTCUSDT"},
+		Start:          start,
+		End:            start.Add(40 * 3 * time.Minute),
+		InitialBalance: 1000,
+		FeeRate:        &zero,
+		SlippageRate:   &zero,
+		Store:          store,
+		Source:         openOnceStub("BTCUSDT"),
+		LogDir:         t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("回测失败: %v", err)
+	}
+	if len(report.Trades) != 1 || report.TotalFees != 0 {
+		t.Errorf("手续费率为0时不应产生手续费: trades=%d fees=%.6f", len(report.Trades), report.TotalFees)
+	}
+}
+
+func TestRunWithRecordedSourceStopsWhenExhausted(t *testing.T) {
+	dataStart := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryKlineStore()
+	store.Add("ETHUSDT", Interval3m, makeTrendKlines(dataStart, 200, 2000, -1))
+
+	source := NewRecordedSource([]*logger.DecisionRecord{
+		{Timestamp: dataStart.Add(time.Minute), CoTTrace: "观望", DecisionJSON: `[{"symbol":"ETHUSDT","action":"wait","reasoning":"r"}]`},
+		{Timestamp: dataStart, CoTTrace: "观望"},
+		{Timestamp: dataStart.Add(2 * time.Minute)}, // 没有AI输出，不参与回放
+	})
+	if source.Len() != 2 {
+		t.Fatalf("期望2条可回放响应, got %d", source.Len())
+	}
+
+	start := dataStart.Add(120 * 3 * time.Minute)
+	report, err := Run(Config{
+		Symbols:        []string{"ETHUSDT"},
+		Start:          start,
+		End:            start.Add(10 * 3 * time.Minute),
+		InitialBalance: 500,
+		Store:          store,
+		Source:         source,
+	})
+	if err != nil {
+		t.Fatalf("回测失败: %v", err)
+	}
+	if report.Cycles != 2 {
+		t.Errorf("录制决策用完后应提前结束, got %d 个周期", report.Cycles)
+	}
+	if report.FinalEquity != 500 || report.MaxDrawdownPct != 0 {
+		t.Errorf("只观望时净值不应变化: %+v", report)
+	}
+}
+
+func TestRecordedSourceReplaysRepairAttempts(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := NewRecordedSource([]*logger.DecisionRecord{
+		{Timestamp: start, RawResponse: "r1"},
+		{Timestamp: start.Add(time.Minute), RawResponse: "fixed", ParseAttempts: []logger.ParseAttempt{
+			{Attempt: 0, Response: "broken", Error: "JSON解析失败"},
+			{Attempt: 1, Response: "fixed"},
+		}},
+	})
+
+	// 修复过的周期按原顺序回放首次响应和修复响应
+	for _, want := range []string{"r1", "broken", "fixed"} {
+		got, err := source.CallWithMessages("", "")
+		if err != nil || got != want {
+			t.Fatalf("回放顺序错误: got %q, want %q (%v)", got, want, err)
+		}
+	}
+	if _, err := source.CallWithMessages("", ""); err != ErrDecisionsExhausted {
+		t.Errorf("回放完毕应返回ErrDecisionsExhausted: %v", err)
+	}
+}
+
+func TestFeedHidesFutureKlines(t *testing.T) {
+	dataStart := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryKlineStore()
+	store.Add("SOLUSDT", Interval3m, makeTrendKlines(dataStart, 50, 10, 1))
+
+	feed, err := newHistoricalFeed(store, []string{"SOLUSDT"})
+	if err != nil {
+		t.Fatalf("创建行情回放失败: %v", err)
+	}
+
+	feed.Advance(dataStart.Add(time.Minute), nil)
+	if _, err := feed.Price("SOLUSDT"); err == nil {
+		t.Error("第一根K线收盘前不应有价格")
+	}
+
+	// 第10根K线收盘后，价格应为其收盘价 10 + 10*1
+	var pushed []float64
+	feed.Advance(dataStart.Add(10*3*time.Minute), nil)
+	feed.Advance(dataStart.Add(11*3*time.Minute), func(symbol string, price float64) {
+		pushed = append(pushed, price)
+	})
+	price, err := feed.Price("SOLUSDT")
+	if err != nil || price != 21 {
+		t.Errorf("期望价格 21, got %.4f (%v)", price, err)
+	}
+	if len(pushed) != 4 || pushed[len(pushed)-1] != 21 {
+		t.Errorf("应推送一根K线的价格路径, got %v", pushed)
+	}
+
+	data, err := feed.Data("SOLUSDT")
+	if err != nil || data.CurrentPrice != 21 {
+		t.Errorf("市场数据应截止到当前时间: %+v (%v)", data, err)
+	}
+}
+
+func TestFileKlineStore(t *testing.T) {
+	dir := t.TempDir()
+	content := `[{"openTime":2,"close":2,"closeTime":3},{"openTime":0,"close":1,"closeTime":1}]`
+	if err := os.WriteFile(filepath.Join(dir, "BTCUSDT_3m.json"), []byte(content), 0644); err != nil {
+		t.Fatalf("写入K线文件失败: %v", err)
+	}
+
+	store := NewFileKlineStore(dir)
+	klines, err := store.Klines("btcusdt", Interval3m)
+	if err != nil {
+		t.Fatalf("读取K线失败: %v", err)
+	}
+	if len(klines) != 2 || klines[0].OpenTime != 0 {
+		t.Errorf("K线应按时间排序: %+v", klines)
+	}
+	if _, err := store.Klines("ETHUSDT", Interval3m); err == nil {
+		t.Error("缺失文件应返回错误")
+	}
+
+	// 币种名称拼接为文件路径，不能读取缓存目录之外的文件
+	outside := filepath.Join(filepath.Dir(dir), "secret_3m.json")
+	if err := os.WriteFile(outside, []byte(content), 0644); err != nil {
+		t.Fatalf("写入K线文件失败: %v", err)
+	}
+	defer os.Remove(outside)
+	for _, symbol := range []string{"../secret", "../../x", "BTC/USDT", ""} {
+		if _, err := store.Klines(symbol, Interval3m); err == nil {
+			t.Errorf("非法币种 %q 应返回错误", symbol)
+		}
+	}
+	if _, err := store.Klines("BTCUSDT", "../3m"); err == nil {
+		t.Error("不支持的K线周期应返回错误")
+	}
+	if err := (&Config{Symbols: []string{"../../x"}}).normalize(); err == nil {
+		t.Error("回测配置中的非法币种应被拒绝")
+	}
+}
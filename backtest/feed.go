@@ -0,0 +1,140 @@
+package backtest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/market"
+)
+
+// 构建市场数据时使用的K线数量（与market.Get一致）
+const feedKlineLimit = 100
+
+// historicalFeed 历史行情回放器
+// 只暴露收盘时间不晚于当前模拟时间的K线，避免使用未来数据
+type historicalFeed struct {
+	mu       sync.RWMutex
+	cursor   time.Time
+	klines3m map[string][]market.Kline
+	klines4h map[string][]market.Kline
+}
+
+// newHistoricalFeed 加载所有币种的K线
+func newHistoricalFeed(store KlineStore, symbols []string) (*historicalFeed, error) {
+	feed := &historicalFeed{
+		klines3m: make(map[string][]market.Kline),
+		klines4h: make(map[string][]market.Kline),
+	}
+	for _, symbol := range symbols {
+		klines3m, err := store.Klines(symbol, Interval3m)
+		if err != nil {
+			return nil, fmt.Errorf("加载 %s 3分钟K线失败: %w", symbol, err)
+		}
+		if len(klines3m) == 0 {
+			return nil, fmt.Errorf("%s 3分钟K线为空", symbol)
+		}
+		feed.klines3m[symbol] = klines3m
+
+		// 4小时K线可选，缺失时长期指标为空
+		if klines4h, err := store.Klines(symbol, Interval4h); err == nil {
+			feed.klines4h[symbol] = klines4h
+		}
+	}
+	return feed, nil
+}
+
+// now 当前模拟时间
+func (f *historicalFeed) now() time.Time {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.cursor
+}
+
+// closedCount 返回收盘时间不晚于t的K线数量
+func closedCount(klines []market.Kline, t time.Time) int {
+	ms := t.UnixMilli()
+	return sort.Search(len(klines), func(i int) bool {
+		return klines[i].CloseTime > ms
+	})
+}
+
+// lastN 截取末尾最多n根K线
+func lastN(klines []market.Kline, n int) []market.Kline {
+	if len(klines) > n {
+		return klines[len(klines)-n:]
+	}
+	return klines
+}
+
+// Data 返回当前模拟时间的市场数据（供决策引擎和执行路径使用）
+func (f *historicalFeed) Data(symbol string) (*market.Data, error) {
+	symbol = strings.ToUpper(symbol)
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	all3m, ok := f.klines3m[symbol]
+	if !ok {
+		return nil, fmt.Errorf("回测数据中没有 %s", symbol)
+	}
+	klines3m := lastN(all3m[:closedCount(all3m, f.cursor)], feedKlineLimit)
+	all4h := f.klines4h[symbol]
+	klines4h := lastN(all4h[:closedCount(all4h, f.cursor)], feedKlineLimit)
+
+	return market.BuildData(symbol, klines3m, klines4h)
+}
+
+// Price 返回当前模拟时间的最新价格（最近一根已收盘3分钟K线的收盘价）
+func (f *historicalFeed) Price(symbol string) (float64, error) {
+	symbol = strings.ToUpper(symbol)
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	klines, ok := f.klines3m[symbol]
+	if !ok {
+		return 0, fmt.Errorf("回测数据中没有 %s", symbol)
+	}
+	n := closedCount(klines, f.cursor)
+	if n == 0 {
+		return 0, fmt.Errorf("%s 在 %s 之前没有K线数据", symbol, f.cursor.Format("2006-01-02 15:04"))
+	}
+	return klines[n-1].Close, nil
+}
+
+// Advance 将模拟时间推进到to，并按K线内的价格路径推送给onPrice
+// 每根新收盘的3分钟K线依次推送 开盘→(最低/最高)→收盘，用于触发止损止盈和强平
+func (f *historicalFeed) Advance(to time.Time, onPrice func(symbol string, price float64)) {
+	f.mu.Lock()
+	from := f.cursor
+	f.cursor = to
+	f.mu.Unlock()
+
+	if onPrice == nil || from.IsZero() {
+		return
+	}
+
+	symbols := make([]string, 0, len(f.klines3m))
+	for symbol := range f.klines3m {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	for _, symbol := range symbols {
+		klines := f.klines3m[symbol]
+		for _, k := range klines[closedCount(klines, from):closedCount(klines, to)] {
+			for _, price := range pricePath(k) {
+				onPrice(symbol, price)
+			}
+		}
+	}
+}
+
+// pricePath 估算K线内的价格路径：阳线先到最低再到最高，阴线相反
+func pricePath(k market.Kline) []float64 {
+	if k.Close >= k.Open {
+		return []float64{k.Open, k.Low, k.High, k.Close}
+	}
+	return []float64{k.Open, k.High, k.Low, k.Close}
+}
@@ -0,0 +1,135 @@
+package backtest
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 回测任务状态
+const (
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+)
+
+// 每个用户同时运行的回测任务上限
+const maxRunningJobsPerUser = 2
+
+// Job 异步回测任务
+type Job struct {
+	ID                   string     `json:"id"`
+	UserID               string     `json:"-"`
+	Name                 string     `json:"name"`
+	Symbols              []string   `json:"symbols"`
+	SystemPromptTemplate string     `json:"system_prompt_template"`
+	Source               string     `json:"source"`
+	Status               string     `json:"status"`
+	Error                string     `json:"error,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+	FinishedAt           *time.Time `json:"finished_at,omitempty"`
+	Report               *Report    `json:"report,omitempty"`
+}
+
+// JobManager 回测任务管理器（内存保存，服务重启后清空）
+type JobManager struct {
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+// NewJobManager 创建回测任务管理器
+func NewJobManager() *JobManager {
+	return &JobManager{jobs: make(map[string]*Job)}
+}
+
+// Submit 提交回测任务，在后台执行
+// source 为决策来源类型（stub/recorded/live），仅用于展示
+func (m *JobManager) Submit(userID, source string, cfg Config) (*Job, error) {
+	if err := cfg.normalize(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	running := 0
+	for _, job := range m.jobs {
+		if job.UserID == userID && job.Status == JobStatusRunning {
+			running++
+		}
+	}
+	if running >= maxRunningJobsPerUser {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("最多同时运行 %d 个回测任务", maxRunningJobsPerUser)
+	}
+
+	job := &Job{
+		ID:                   uuid.New().String(),
+		UserID:               userID,
+		Name:                 cfg.Name,
+		Symbols:              cfg.Symbols,
+		SystemPromptTemplate: cfg.SystemPromptTemplate,
+		Source:               source,
+		Status:               JobStatusRunning,
+		CreatedAt:            time.Now(),
+	}
+	m.jobs[job.ID] = job
+	m.mu.Unlock()
+
+	go func() {
+		report, err := Run(cfg)
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		finished := time.Now()
+		job.FinishedAt = &finished
+		if err != nil {
+			log.Printf("❌ 回测任务 %s 失败: %v", job.ID, err)
+			job.Status = JobStatusFailed
+			job.Error = err.Error()
+			return
+		}
+		job.Status = JobStatusCompleted
+		job.Report = report
+	}()
+
+	return m.snapshot(job), nil
+}
+
+// Get 获取用户的回测任务（包含报告）
+func (m *JobManager) Get(userID, jobID string) (*Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	job, ok := m.jobs[jobID]
+	if !ok || job.UserID != userID {
+		return nil, fmt.Errorf("回测任务不存在")
+	}
+	return m.snapshot(job), nil
+}
+
+// List 列出用户的回测任务（不含报告，按创建时间倒序）
+func (m *JobManager) List(userID string) []*Job {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	jobs := []*Job{}
+	for _, job := range m.jobs {
+		if job.UserID != userID {
+			continue
+		}
+		summary := m.snapshot(job)
+		summary.Report = nil
+		jobs = append(jobs, summary)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// snapshot 复制任务，避免调用方与后台goroutine竞争（调用方需持有锁）
+func (m *JobManager) snapshot(job *Job) *Job {
+	copied := *job
+	return &copied
+}
@@ -0,0 +1,120 @@
+package backtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"nofx/market"
+)
+
+// 回测使用的K线周期（与market.Get保持一致）
+const (
+	Interval3m = "3m"
+	Interval4h = "4h"
+)
+
+// symbolPattern 合法的币种名称（如BTCUSDT），币种名称会拼接为K线文件路径
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9]+$`)
+
+// ValidateSymbol 校验币种名称只包含大写字母和数字（防止 ../ 等路径穿越）
+func ValidateSymbol(symbol string) error {
+	if !symbolPattern.MatchString(symbol) {
+		return fmt.Errorf("无效的币种: %q", symbol)
+	}
+	return nil
+}
+
+// KlineStore 历史K线存储
+type KlineStore interface {
+	// Klines 返回某币种某周期的全部历史K线（按开盘时间正序）
+	Klines(symbol, interval string) ([]market.Kline, error)
+}
+
+// FileKlineStore 从目录读取K线文件
+// 文件路径: <Dir>/<SYMBOL>_<interval>.json，内容为market.Kline数组
+type FileKlineStore struct {
+	Dir string
+
+	mu    sync.Mutex
+	cache map[string][]market.Kline
+}
+
+// NewFileKlineStore 创建文件K线存储
+func NewFileKlineStore(dir string) *FileKlineStore {
+	return &FileKlineStore{Dir: dir, cache: make(map[string][]market.Kline)}
+}
+
+// Klines 读取K线文件（带缓存）
+func (s *FileKlineStore) Klines(symbol, interval string) ([]market.Kline, error) {
+	symbol = strings.ToUpper(symbol)
+	if err := ValidateSymbol(symbol); err != nil {
+		return nil, err
+	}
+	if interval != Interval3m && interval != Interval4h {
+		return nil, fmt.Errorf("不支持的K线周期: %q", interval)
+	}
+	key := symbol + "_" + interval
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if klines, ok := s.cache[key]; ok {
+		return klines, nil
+	}
+
+	path := filepath.Join(s.Dir, key+".json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取K线文件失败: %w", err)
+	}
+
+	var klines []market.Kline
+	if err := json.Unmarshal(data, &klines); err != nil {
+		return nil, fmt.Errorf("解析K线文件 %s 失败: %w", path, err)
+	}
+	sortKlines(klines)
+
+	if s.cache == nil {
+		s.cache = make(map[string][]market.Kline)
+	}
+	s.cache[key] = klines
+	return klines, nil
+}
+
+// MemoryKlineStore 内存K线存储（测试或调用方自行加载数据时使用）
+type MemoryKlineStore struct {
+	data map[string][]market.Kline
+}
+
+// NewMemoryKlineStore 创建内存K线存储
+func NewMemoryKlineStore() *MemoryKlineStore {
+	return &MemoryKlineStore{data: make(map[string][]market.Kline)}
+}
+
+// Add 添加某币种某周期的K线
+func (s *MemoryKlineStore) Add(symbol, interval string, klines []market.Kline) {
+	sorted := append([]market.Kline(nil), klines...)
+	sortKlines(sorted)
+	s.data[strings.ToUpper(symbol)+"_"+interval] = sorted
+}
+
+// Klines 返回内存中的K线
+func (s *MemoryKlineStore) Klines(symbol, interval string) ([]market.Kline, error) {
+	klines, ok := s.data[strings.ToUpper(symbol)+"_"+interval]
+	if !ok {
+		return nil, fmt.Errorf("%s 没有 %s K线数据", symbol, interval)
+	}
+	return klines, nil
+}
+
+// sortKlines 按开盘时间正序排列
+func sortKlines(klines []market.Kline) {
+	sort.Slice(klines, func(i, j int) bool {
+		return klines[i].OpenTime < klines[j].OpenTime
+	})
+}
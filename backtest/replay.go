@@ -0,0 +1,119 @@
+package backtest
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// ReplayOptions 决策日志回放配置
+type ReplayOptions struct {
+	// 系统提示词中无法识别杠杆配置时使用的默认值
+	BTCETHLeverage  int
+	AltcoinLeverage int
+	// 是否在报告中包含没有差异的周期
+	IncludeUnchanged bool
+}
+
+// ReplayCycle 单个周期的回放结果
+type ReplayCycle struct {
+	Timestamp   time.Time `json:"timestamp"`
+	CycleNumber int       `json:"cycle_number"`
+	// Reconstructed 为true表示日志中没有原始响应，使用思维链+决策JSON重建
+	// （此时无法发现JSON修复逻辑带来的解析差异）
+	Reconstructed bool `json:"reconstructed"`
+	*decision.ReplayResult
+}
+
+// ReplayReport 决策日志回放报告
+type ReplayReport struct {
+	Records           int            `json:"records"`            // 日志记录总数
+	Replayed          int            `json:"replayed"`           // 有AI输出、参与回放的记录数
+	Unchanged         int            `json:"unchanged"`          // 结果完全一致的周期数
+	NewlyRejected     int            `json:"newly_rejected"`     // 现在会被拒绝的决策数
+	NewlyAccepted     int            `json:"newly_accepted"`     // 现在会通过的决策数
+	Resized           int            `json:"resized"`            // 仓位或杠杆不同的决策数
+	ParsedDifferently int            `json:"parsed_differently"` // 解析结果不同的决策数（新增/缺失/字段变化）
+	Cycles            []*ReplayCycle `json:"cycles"`
+}
+
+// Changed 是否存在任何差异
+func (r *ReplayReport) Changed() bool {
+	return r.Replayed > r.Unchanged
+}
+
+// ReplayRecords 使用当前的解析和验证代码重放决策日志，找出行为变化
+func ReplayRecords(records []*logger.DecisionRecord, opts ReplayOptions) *ReplayReport {
+	if opts.BTCETHLeverage <= 0 {
+		opts.BTCETHLeverage = defaultBTCETHLeverage
+	}
+	if opts.AltcoinLeverage <= 0 {
+		opts.AltcoinLeverage = defaultAltcoinLeverage
+	}
+
+	report := &ReplayReport{Records: len(records), Cycles: []*ReplayCycle{}}
+	for _, record := range records {
+		// 没有AI输出的周期（如AI调用失败、风控暂停）无法回放
+		if record.RawResponse == "" && record.CoTTrace == "" && record.DecisionJSON == "" {
+			continue
+		}
+		report.Replayed++
+
+		input := decision.ReplayInput{
+			Response:        record.RawResponse,
+			AccountEquity:   record.AccountState.TotalBalance,
+			BTCETHLeverage:  opts.BTCETHLeverage,
+			AltcoinLeverage: opts.AltcoinLeverage,
+			OriginalError:   replayOriginalError(record),
+		}
+		reconstructed := input.Response == ""
+		if reconstructed {
+			input.Response = record.CoTTrace + "\n\n" + record.DecisionJSON
+		}
+		if btcEth, altcoin, ok := decision.ParseLeverageFromSystemPrompt(record.SystemPrompt); ok {
+			input.BTCETHLeverage = btcEth
+			input.AltcoinLeverage = altcoin
+		}
+		if record.DecisionJSON != "" {
+			json.Unmarshal([]byte(record.DecisionJSON), &input.Original)
+		}
+
+		result := decision.ReplayDecisions(input)
+		if !result.Changed() {
+			report.Unchanged++
+		}
+		for _, change := range result.Changes {
+			switch change.Kind {
+			case decision.ReplayChangeRejected:
+				report.NewlyRejected++
+			case decision.ReplayChangeAccepted:
+				report.NewlyAccepted++
+			case decision.ReplayChangeResized:
+				report.Resized++
+			default:
+				report.ParsedDifferently++
+			}
+		}
+
+		if result.Changed() || opts.IncludeUnchanged {
+			report.Cycles = append(report.Cycles, &ReplayCycle{
+				Timestamp:     record.Timestamp,
+				CycleNumber:   record.CycleNumber,
+				Reconstructed: reconstructed,
+				ReplayResult:  result,
+			})
+		}
+	}
+	return report
+}
+
+// replayOriginalError 提取记录中与AI响应解析相关的错误（其他错误视为解析通过）
+func replayOriginalError(record *logger.DecisionRecord) string {
+	if record.Success || !strings.Contains(record.ErrorMessage, "解析AI响应失败") {
+		return ""
+	}
+	return record.ErrorMessage
+}
@@ -0,0 +1,46 @@
+package backtest
+
+import (
+	"testing"
+	"time"
+
+	"nofx/logger"
+)
+
+func TestReplayRecordsSummarizesChanges(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	openJSON := `[{"symbol":"BTCUSDT","action":"open_long","leverage":8,"position_size_usd":2000,"stop_loss":90000,"take_profit":110000,"confidence":80,"reasoning":"trend"}]`
+
+	records := []*logger.DecisionRecord{
+		// 没有AI输出，不参与回放
+		{Timestamp: now, Success: false, ErrorMessage: "获取市场数据失败"},
+		// 系统提示词中杠杆上限为10倍：当时通过，现在仍通过
+		{
+			Timestamp:    now.Add(time.Minute),
+			SystemPrompt: "3. 单币仓位: 山寨800-1500 U(10x杠杆) | BTC/ETH 5000-10000 U(10x杠杆)",
+			CoTTrace:     "看多",
+			DecisionJSON: openJSON,
+			AccountState: logger.AccountSnapshot{TotalBalance: 1000},
+			Success:      true,
+		},
+		// 无法识别杠杆，使用默认5倍：8倍杠杆现在会被拒绝
+		{
+			Timestamp:    now.Add(2 * time.Minute),
+			RawResponse:  "看多\n" + openJSON,
+			DecisionJSON: openJSON,
+			AccountState: logger.AccountSnapshot{TotalBalance: 1000},
+			Success:      true,
+		},
+	}
+
+	report := ReplayRecords(records, ReplayOptions{})
+	if report.Records != 3 || report.Replayed != 2 || report.Unchanged != 1 {
+		t.Fatalf("统计不正确: %+v", report)
+	}
+	if report.NewlyRejected != 1 || !report.Changed() {
+		t.Errorf("应报告1个新拒绝的决策: %+v", report)
+	}
+	if len(report.Cycles) != 1 || report.Cycles[0].Reconstructed {
+		t.Errorf("只应包含有变化的周期，且使用原始响应: %+v", report.Cycles)
+	}
+}
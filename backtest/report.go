@@ -0,0 +1,170 @@
+package backtest
+
+import (
+	"time"
+
+	"nofx/logger"
+	"nofx/trader"
+)
+
+// EquityPoint 净值曲线上的一个点
+type EquityPoint struct {
+	Time        time.Time `json:"time"`
+	Equity      float64   `json:"equity"`
+	DrawdownPct float64   `json:"drawdown_pct"` // 相对历史最高净值的回撤百分比
+}
+
+// Report 回测报告
+type Report struct {
+	Name                 string                      `json:"name"`
+	Symbols              []string                    `json:"symbols"`
+	SystemPromptTemplate string                      `json:"system_prompt_template"`
+	StartTime            time.Time                   `json:"start_time"`
+	EndTime              time.Time                   `json:"end_time"`
+	Interval             string                      `json:"interval"`
+	Cycles               int                         `json:"cycles"`        // 执行的决策周期数
+	FailedCycles         int                         `json:"failed_cycles"` // 失败的周期数（AI解析失败等）
+	InitialBalance       float64                     `json:"initial_balance"`
+	FinalEquity          float64                     `json:"final_equity"`
+	TotalReturnPct       float64                     `json:"total_return_pct"`
+	MaxDrawdownPct       float64                     `json:"max_drawdown_pct"`
+	SharpeRatio          float64                     `json:"sharpe_ratio"` // 周期级夏普比率（与实盘统计口径一致）
+	RealizedPnL          float64                     `json:"realized_pnl"`
+	TotalFees            float64                     `json:"total_fees"`
+	OpenPositions        int                         `json:"open_positions"` // 回测结束时仍未平仓的持仓数
+	EquityCurve          []EquityPoint               `json:"equity_curve"`
+	Trades               []logger.TradeOutcome       `json:"trades"`      // 全部已完成交易（按时间正序）
+	Performance          *logger.PerformanceAnalysis `json:"performance"` // 交易统计（含各币种表现）
+}
+
+// reportBuilder 逐周期累积回测数据
+type reportBuilder struct {
+	report *Report
+	peak   float64
+}
+
+func newReportBuilder(cfg Config) *reportBuilder {
+	return &reportBuilder{
+		report: &Report{
+			Name:                 cfg.Name,
+			Symbols:              cfg.Symbols,
+			SystemPromptTemplate: cfg.SystemPromptTemplate,
+			StartTime:            cfg.Start,
+			EndTime:              cfg.Start,
+			Interval:             cfg.Interval.String(),
+			InitialBalance:       cfg.InitialBalance,
+			EquityCurve:          []EquityPoint{},
+		},
+		peak: cfg.InitialBalance,
+	}
+}
+
+// addCycle 记录一个周期结束时的净值
+func (b *reportBuilder) addCycle(t time.Time, equity float64, cycleErr error) {
+	r := b.report
+	r.Cycles++
+	if cycleErr != nil {
+		r.FailedCycles++
+	}
+	r.EndTime = t
+
+	if equity > b.peak {
+		b.peak = equity
+	}
+	drawdown := 0.0
+	if b.peak > 0 {
+		drawdown = (b.peak - equity) / b.peak * 100
+	}
+	if drawdown > r.MaxDrawdownPct {
+		r.MaxDrawdownPct = drawdown
+	}
+	r.EquityCurve = append(r.EquityCurve, EquityPoint{Time: t, Equity: equity, DrawdownPct: drawdown})
+}
+
+// build 生成最终报告
+func (b *reportBuilder) build(paper *trader.PaperTrader, balance *trader.Balance, openPositions int, fills []trader.PaperFill) *Report {
+	r := b.report
+	r.FinalEquity = balance.TotalEquity()
+	r.RealizedPnL = paper.RealizedPnL()
+	r.TotalFees = paper.TotalFees()
+	r.OpenPositions = openPositions
+	if r.InitialBalance > 0 {
+		r.TotalReturnPct = (r.FinalEquity - r.InitialBalance) / r.InitialBalance * 100
+	}
+
+	equities := make([]float64, 0, len(r.EquityCurve)+1)
+	equities = append(equities, r.InitialBalance)
+	for _, point := range r.EquityCurve {
+		equities = append(equities, point.Equity)
+	}
+	r.SharpeRatio = logger.CalculateSharpeRatio(equities)
+
+	r.Trades = tradesFromFills(fills)
+	r.Performance = logger.NewPerformanceAnalysis(r.Trades)
+	r.Performance.SharpeRatio = r.SharpeRatio
+	return r
+}
+
+// tradesFromFills 将模拟盘成交转换为交易结果（每笔平仓成交对应一笔交易）
+// 盈亏口径与logger.AnalyzePerformance一致：按价差计算，不扣手续费
+func tradesFromFills(fills []trader.PaperFill) []logger.TradeOutcome {
+	type openState struct {
+		quantity float64
+		openTime time.Time
+	}
+	open := make(map[string]*openState)
+	trades := []logger.TradeOutcome{}
+
+	for _, fill := range fills {
+		switch fill.Action {
+		case "open_long", "open_short":
+			side := fill.Action[len("open_"):]
+			key := fill.Symbol + "_" + side
+			if state, ok := open[key]; ok {
+				state.quantity += fill.Quantity
+			} else {
+				open[key] = &openState{quantity: fill.Quantity, openTime: fill.Time}
+			}
+
+		case "close_long", "close_short":
+			side := fill.Action[len("close_"):]
+			key := fill.Symbol + "_" + side
+			openTime := fill.Time
+			if state, ok := open[key]; ok {
+				openTime = state.openTime
+				state.quantity -= fill.Quantity
+				if state.quantity <= 1e-12 {
+					delete(open, key)
+				}
+			}
+
+			positionValue := fill.Quantity * fill.EntryPrice
+			marginUsed := positionValue
+			if fill.Leverage > 0 {
+				marginUsed = positionValue / float64(fill.Leverage)
+			}
+			pnlPct := 0.0
+			if marginUsed > 0 {
+				pnlPct = fill.RealizedPnL / marginUsed * 100
+			}
+
+			trades = append(trades, logger.TradeOutcome{
+				Symbol:        fill.Symbol,
+				Side:          side,
+				Quantity:      fill.Quantity,
+				Leverage:      fill.Leverage,
+				OpenPrice:     fill.EntryPrice,
+				ClosePrice:    fill.Price,
+				PositionValue: positionValue,
+				MarginUsed:    marginUsed,
+				PnL:           fill.RealizedPnL,
+				PnLPct:        pnlPct,
+				Duration:      fill.Time.Sub(openTime).String(),
+				OpenTime:      openTime,
+				CloseTime:     fill.Time,
+				WasStopLoss:   fill.Reason == trader.PaperFillReasonStopLoss || fill.Reason == trader.PaperFillReasonLiquidation,
+			})
+		}
+	}
+	return trades
+}
@@ -0,0 +1,144 @@
+package backtest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"nofx/logger"
+	"nofx/mcp"
+)
+
+// ErrDecisionsExhausted 录制的决策已全部回放完毕
+var ErrDecisionsExhausted = errors.New("录制的决策已全部回放")
+
+// 决策来源类型
+const (
+	SourceStub     = "stub"     // 固定响应（默认全部观望）
+	SourceRecorded = "recorded" // 回放决策日志中的AI响应
+	SourceLive     = "live"     // 实时调用AI模型
+)
+
+// StubSource 固定响应的决策来源，用于验证引擎或测试提示词以外的逻辑
+type StubSource struct {
+	// Respond 根据prompt生成响应，为nil时始终返回观望
+	Respond func(systemPrompt, userPrompt string) string
+}
+
+// NewWaitStub 创建始终观望的决策来源
+func NewWaitStub() *StubSource {
+	return &StubSource{}
+}
+
+// CallWithMessages 实现mcp.AIClient
+func (s *StubSource) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	if s.Respond != nil {
+		return s.Respond(systemPrompt, userPrompt), nil
+	}
+	return "回测桩：保持观望\n\n[{\"symbol\":\"ALL\",\"action\":\"wait\",\"reasoning\":\"stub\"}]", nil
+}
+
+// RecordedSource 按顺序回放决策日志中的AI响应（思维链 + 决策JSON）
+type RecordedSource struct {
+	mu        sync.Mutex
+	responses []string
+	next      int
+}
+
+// NewRecordedSource 从决策记录创建回放来源（按记录时间排序）
+func NewRecordedSource(records []*logger.DecisionRecord) *RecordedSource {
+	sorted := append([]*logger.DecisionRecord(nil), records...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	responses := make([]string, 0, len(sorted))
+	for _, record := range sorted {
+		// 没有AI输出的周期（如风控暂停）不参与回放
+		if record.RawResponse == "" && record.CoTTrace == "" && record.DecisionJSON == "" {
+			continue
+		}
+		// 经过修复的周期按原顺序回放每次响应，使修复循环得到相同的结果
+		if len(record.ParseAttempts) > 0 {
+			for _, attempt := range record.ParseAttempts {
+				responses = append(responses, attempt.Response)
+			}
+			continue
+		}
+		if record.RawResponse != "" {
+			responses = append(responses, record.RawResponse)
+			continue
+		}
+		decisionJSON := record.DecisionJSON
+		if decisionJSON == "" {
+			decisionJSON = "[]"
+		}
+		responses = append(responses, record.CoTTrace+"\n\n"+decisionJSON)
+	}
+	return &RecordedSource{responses: responses}
+}
+
+// LoadRecordedSource 读取决策日志目录，创建回放来源
+func LoadRecordedSource(logDir string) (*RecordedSource, error) {
+	records, err := logger.LoadDecisionRecords(logDir)
+	if err != nil {
+		return nil, err
+	}
+	source := NewRecordedSource(records)
+	if source.Len() == 0 {
+		return nil, fmt.Errorf("决策日志目录 %s 中没有可回放的AI响应", logDir)
+	}
+	return source, nil
+}
+
+// Len 可回放的响应数量
+func (s *RecordedSource) Len() int {
+	return len(s.responses)
+}
+
+// CallWithMessages 实现mcp.AIClient，依次返回录制的响应
+func (s *RecordedSource) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.next >= len(s.responses) {
+		return "", ErrDecisionsExhausted
+	}
+	response := s.responses[s.next]
+	s.next++
+	return response, nil
+}
+
+// NewLiveSource 创建实时调用AI模型的决策来源
+// provider: deepseek / qwen / anthropic / openai / gemini / custom（与AutoTrader的AI模型配置一致）
+func NewLiveSource(provider, apiKey, apiURL, modelName string) (*mcp.Client, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("实时决策来源需要API Key")
+	}
+	client := mcp.New()
+	switch provider {
+	case "deepseek", "":
+		client.SetDeepSeekAPIKey(apiKey, apiURL, modelName)
+	case "qwen":
+		client.SetQwenAPIKey(apiKey, apiURL, modelName)
+	case "anthropic", "openai", "gemini":
+		if err := client.SetProviderAPIKey(mcp.Provider(provider), apiKey, apiURL, modelName); err != nil {
+			return nil, err
+		}
+	case "custom":
+		if apiURL == "" || modelName == "" {
+			return nil, fmt.Errorf("自定义AI需要提供API地址和模型名称")
+		}
+		client.SetCustomAPI(apiURL, apiKey, modelName)
+	default:
+		return nil, fmt.Errorf("不支持的AI提供商: %s", provider)
+	}
+	return client, nil
+}
+
+// 确保各来源实现mcp.AIClient
+var (
+	_ mcp.AIClient = (*StubSource)(nil)
+	_ mcp.AIClient = (*RecordedSource)(nil)
+	_ mcp.AIClient = (*mcp.Client)(nil)
+)
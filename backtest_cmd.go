@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"nofx/backtest"
+	"nofx/mcp"
+)
+
+// runBacktestCommand 执行离线回测子命令（不连接数据库）
+// 用法: nofx backtest -symbols BTCUSDT,ETHUSDT -start 2025-01-01 -end 2025-01-07 -templates default,nof1
+func runBacktestCommand(args []string) error {
+	fs := flag.NewFlagSet("backtest", flag.ContinueOnError)
+	dataDir := fs.String("data", "backtest_data", "K线数据目录（<SYMBOL>_3m.json / <SYMBOL>_4h.json）")
+	symbolsFlag := fs.String("symbols", "BTCUSDT", "交易币种，逗号分隔")
+	startFlag := fs.String("start", "", "开始时间（2006-01-02 或 2006-01-02 15:04 或 RFC3339，UTC）")
+	endFlag := fs.String("end", "", "结束时间")
+	interval := fs.Duration("interval", 3*time.Minute, "决策周期")
+	balance := fs.Float64("balance", 1000, "初始资金（USDT）")
+	btcEthLeverage := fs.Int("btc-eth-leverage", 5, "BTC/ETH杠杆上限")
+	altcoinLeverage := fs.Int("altcoin-leverage", 5, "山寨币杠杆上限")
+	crossMargin := fs.Bool("cross", true, "全仓模式")
+	feeRate := fs.Float64("fee", 0, "手续费率（未指定时使用默认0.04%，0表示免手续费）")
+	slippageRate := fs.Float64("slippage", 0, "滑点比例（未指定时使用默认0.05%，0表示无滑点）")
+	templates := fs.String("templates", "default", "系统提示词模板，逗号分隔（多个模板时逐一回测并对比）")
+	promptFile := fs.String("prompt-file", "", "自定义策略prompt文件")
+	overridePrompt := fs.Bool("override-prompt", false, "自定义prompt覆盖基础prompt")
+	source := fs.String("source", backtest.SourceStub, "决策来源: stub / recorded / live")
+	recordedDir := fs.String("recorded-dir", "", "recorded模式下的决策日志目录（如 decision_logs/<trader_id>）")
+	provider := fs.String("provider", "deepseek", "live模式的AI提供商: deepseek / qwen / custom")
+	apiKey := fs.String("api-key", "", "live模式的AI API Key")
+	apiURL := fs.String("api-url", "", "live模式的自定义API地址")
+	modelName := fs.String("model", "", "live模式的自定义模型名称")
+	logDir := fs.String("log-dir", "", "保存回测决策日志的目录（为空则不保留）")
+	output := fs.String("out", "", "报告输出文件（为空则输出到标准输出）")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// 只有显式指定的费率才传给模拟盘（包括0），未指定时使用默认值
+	var feeRateSet, slippageRateSet *float64
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "fee":
+			feeRateSet = feeRate
+		case "slippage":
+			slippageRateSet = slippageRate
+		}
+	})
+
+	start, err := backtest.ParseTime(*startFlag)
+	if err != nil {
+		return fmt.Errorf("开始时间无效: %w", err)
+	}
+	end, err := backtest.ParseTime(*endFlag)
+	if err != nil {
+		return fmt.Errorf("结束时间无效: %w", err)
+	}
+
+	customPrompt := ""
+	if *promptFile != "" {
+		data, err := os.ReadFile(*promptFile)
+		if err != nil {
+			return fmt.Errorf("读取自定义prompt失败: %w", err)
+		}
+		customPrompt = string(data)
+	}
+
+	// 每个模板使用独立的决策来源（录制回放需要从头开始）
+	newSource := func() (mcp.AIClient, error) {
+		switch *source {
+		case backtest.SourceStub:
+			return backtest.NewWaitStub(), nil
+		case backtest.SourceRecorded:
+			if *recordedDir == "" {
+				return nil, fmt.Errorf("recorded模式需要指定 -recorded-dir")
+			}
+			return backtest.LoadRecordedSource(*recordedDir)
+		case backtest.SourceLive:
+			return backtest.NewLiveSource(*provider, *apiKey, *apiURL, *modelName)
+		default:
+			return nil, fmt.Errorf("不支持的决策来源: %s", *source)
+		}
+	}
+
+	store := backtest.NewFileKlineStore(*dataDir)
+	var reports []*backtest.Report
+	for _, template := range splitList(*templates) {
+		decisionSource, err := newSource()
+		if err != nil {
+			return err
+		}
+
+		runLogDir := ""
+		if *logDir != "" {
+			runLogDir = fmt.Sprintf("%s/%s", *logDir, template)
+		}
+
+		report, err := backtest.Run(backtest.Config{
+			Name:                 "backtest_" + template,
+			Symbols:              splitList(*symbolsFlag),
+			Start:                start,
+			End:                  end,
+			Interval:             *interval,
+			InitialBalance:       *balance,
+			BTCETHLeverage:       *btcEthLeverage,
+			AltcoinLeverage:      *altcoinLeverage,
+			IsCrossMargin:        *crossMargin,
+			FeeRate:              feeRateSet,
+			SlippageRate:         slippageRateSet,
+			SystemPromptTemplate: template,
+			CustomPrompt:         customPrompt,
+			OverrideBasePrompt:   *overridePrompt,
+			Store:                store,
+			Source:               decisionSource,
+			LogDir:               runLogDir,
+		})
+		if err != nil {
+			return fmt.Errorf("模板 %s 回测失败: %w", template, err)
+		}
+		reports = append(reports, report)
+	}
+
+	// 打印模板对比摘要
+	log.Println(strings.Repeat("=", 70))
+	log.Printf("%-20s %10s %10s %10s %8s %8s", "模板", "收益%", "最大回撤%", "夏普", "交易数", "胜率%")
+	for _, r := range reports {
+		log.Printf("%-20s %10.2f %10.2f %10.3f %8d %8.1f", r.SystemPromptTemplate,
+			r.TotalReturnPct, r.MaxDrawdownPct, r.SharpeRatio, r.Performance.TotalTrades, r.Performance.WinRate)
+	}
+	log.Println(strings.Repeat("=", 70))
+
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化回测报告失败: %w", err)
+	}
+	if *output == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		return fmt.Errorf("写入回测报告失败: %w", err)
+	}
+	log.Printf("✓ 回测报告已保存: %s", *output)
+	return nil
+}
+
+// splitList 拆分逗号分隔的参数并去除空白
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// AIUsageRecord 一个决策周期中某个模型的token用量和费用
+type AIUsageRecord struct {
+	UserID           string    `json:"user_id"`
+	TraderID         string    `json:"trader_id"`
+	CycleNumber      int       `json:"cycle_number"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	Calls            int       `json:"calls"` // 调用次数（含修复请求）
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	CostUSD          float64   `json:"cost_usd"`
+	CreatedAt        time.Time `json:"created_at"` // 决策周期时间（同一周期的记录相同）
+}
+
+// AIUsageSummary 按周期（日/月）和交易员汇总的AI用量
+type AIUsageSummary struct {
+	Period           time.Time `json:"period"` // 日或月的起始时间
+	TraderID         string    `json:"trader_id"`
+	Cycles           int       `json:"cycles"` // 决策周期数
+	Calls            int       `json:"calls"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	CostUSD          float64   `json:"cost_usd"`
+}
+
+// SaveAIUsage 保存一个决策周期的AI用量
+func (d *Database) SaveAIUsage(records []AIUsageRecord) error {
+	for _, r := range records {
+		_, err := d.exec(`
+			INSERT INTO ai_usage (user_id, trader_id, cycle_number, provider, model, calls, prompt_tokens, completion_tokens, cost_usd, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`, r.UserID, r.TraderID, r.CycleNumber, r.Provider, r.Model, r.Calls, r.PromptTokens, r.CompletionTokens, r.CostUSD, r.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("保存AI用量失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetAIUsageSummary 按日（period=day）或月（period=month）汇总用户的AI用量，traderID为空时汇总所有交易员
+func (d *Database) GetAIUsageSummary(userID, traderID, period string, since time.Time) ([]AIUsageSummary, error) {
+	if period != "day" && period != "month" {
+		return nil, fmt.Errorf("无效的汇总周期: %s", period)
+	}
+
+	// 同一周期的记录created_at相同，按created_at去重统计决策周期数
+	rows, err := d.query(`
+		SELECT date_trunc($1, created_at) AS period, trader_id,
+			COUNT(DISTINCT created_at), COALESCE(SUM(calls), 0),
+			COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0), COALESCE(SUM(cost_usd), 0)
+		FROM ai_usage
+		WHERE user_id = $2 AND ($3 = '' OR trader_id = $3) AND created_at >= $4
+		GROUP BY period, trader_id
+		ORDER BY period DESC, trader_id
+	`, period, userID, traderID, since)
+	if err != nil {
+		return nil, fmt.Errorf("查询AI用量失败: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := []AIUsageSummary{}
+	for rows.Next() {
+		var s AIUsageSummary
+		if err := rows.Scan(&s.Period, &s.TraderID, &s.Cycles, &s.Calls, &s.PromptTokens, &s.CompletionTokens, &s.CostUSD); err != nil {
+			return nil, fmt.Errorf("读取AI用量失败: %w", err)
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+// GetAverageCycleAICost 交易员自since以来平均每个决策周期的AI费用（美元）和周期数
+func (d *Database) GetAverageCycleAICost(traderID string, since time.Time) (float64, int, error) {
+	var total float64
+	var cycles int
+	err := d.queryRow(`
+		SELECT COALESCE(SUM(cost_usd), 0), COUNT(DISTINCT created_at)
+		FROM ai_usage
+		WHERE trader_id = $1 AND created_at >= $2
+	`, traderID, since).Scan(&total, &cycles)
+	if err != nil {
+		return 0, 0, fmt.Errorf("查询AI平均费用失败: %w", err)
+	}
+	if cycles == 0 {
+		return 0, 0, nil
+	}
+	return total / float64(cycles), cycles, nil
+}
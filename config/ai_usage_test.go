@@ -0,0 +1,65 @@
+package config
+
+import (
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestAIUsageSummary 测试AI用量保存和按日/月汇总
+func TestAIUsageSummary(t *testing.T) {
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("跳过测试：未设置 DATABASE_URL 环境变量")
+	}
+
+	db, err := NewDatabase("")
+	if err != nil {
+		t.Fatalf("连接数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	userID := "test_user_" + GenerateUUID()[:8]
+	traderID := "test_trader_" + GenerateUUID()[:8]
+	defer db.exec(`DELETE FROM ai_usage WHERE user_id = $1`, userID)
+
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	cycles := [][]AIUsageRecord{
+		{
+			{UserID: userID, TraderID: traderID, CycleNumber: 1, Provider: "deepseek", Model: "deepseek-chat", Calls: 2, PromptTokens: 3000, CompletionTokens: 300, CostUSD: 0.01, CreatedAt: day.Add(time.Hour)},
+			{UserID: userID, TraderID: traderID, CycleNumber: 1, Provider: "openai", Model: "gpt-4o", Calls: 1, PromptTokens: 1000, CompletionTokens: 100, CostUSD: 0.02, CreatedAt: day.Add(time.Hour)},
+		},
+		{
+			{UserID: userID, TraderID: traderID, CycleNumber: 2, Provider: "deepseek", Model: "deepseek-chat", Calls: 1, PromptTokens: 1000, CompletionTokens: 100, CostUSD: 0.03, CreatedAt: day.Add(2 * time.Hour)},
+		},
+	}
+	for _, records := range cycles {
+		if err := db.SaveAIUsage(records); err != nil {
+			t.Fatalf("保存AI用量失败: %v", err)
+		}
+	}
+
+	summaries, err := db.GetAIUsageSummary(userID, "", "day", day.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("汇总AI用量失败: %v", err)
+	}
+	if len(summaries) != 1 {
+		t.Fatalf("应汇总为1天1个交易员: %+v", summaries)
+	}
+	s := summaries[0]
+	if s.Cycles != 2 || s.Calls != 4 || s.PromptTokens != 5000 || s.CompletionTokens != 500 || math.Abs(s.CostUSD-0.06) > 1e-9 {
+		t.Errorf("汇总结果错误: %+v", s)
+	}
+
+	if summaries, _ := db.GetAIUsageSummary(userID, "other_trader", "month", day.Add(-24*time.Hour)); len(summaries) != 0 {
+		t.Errorf("按交易员筛选结果错误: %+v", summaries)
+	}
+	if _, err := db.GetAIUsageSummary(userID, "", "week", day); err == nil {
+		t.Error("无效的汇总周期应返回错误")
+	}
+
+	avg, n, err := db.GetAverageCycleAICost(traderID, day.Add(-24*time.Hour))
+	if err != nil || n != 2 || math.Abs(avg-0.03) > 1e-9 {
+		t.Errorf("平均周期费用错误: %v %d %v", avg, n, err)
+	}
+}
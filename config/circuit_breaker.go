@@ -0,0 +1,38 @@
+package config
+
+import (
+	"database/sql"
+)
+
+// GetCircuitBreakerState 获取交易员的账户熔断状态（JSON快照），不存在时返回空字符串
+func (d *Database) GetCircuitBreakerState(traderID string) (string, error) {
+	var state string
+	err := d.queryRow(`
+		SELECT state FROM circuit_breaker_states WHERE trader_id = $1
+	`, traderID).Scan(&state)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return state, nil
+}
+
+// SaveCircuitBreakerState 保存交易员的账户熔断状态（JSON快照），重启后恢复暂停状态
+func (d *Database) SaveCircuitBreakerState(traderID string, state string) error {
+	_, err := d.exec(`
+		INSERT INTO circuit_breaker_states (trader_id, state, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (trader_id) DO UPDATE SET
+			state = EXCLUDED.state,
+			updated_at = CURRENT_TIMESTAMP
+	`, traderID, state)
+	return err
+}
+
+// DeleteCircuitBreakerState 删除交易员的账户熔断状态（删除交易员时调用）
+func (d *Database) DeleteCircuitBreakerState(traderID string) error {
+	_, err := d.exec(`DELETE FROM circuit_breaker_states WHERE trader_id = $1`, traderID)
+	return err
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"database/sql"
+)
+
+// GetConstraintsState 获取交易员的学习阶段约束状态（JSON快照），不存在时返回空字符串
+func (d *Database) GetConstraintsState(traderID string) (string, error) {
+	var state string
+	err := d.queryRow(`
+		SELECT state FROM constraints_states WHERE trader_id = $1
+	`, traderID).Scan(&state)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return state, nil
+}
+
+// SaveConstraintsState 保存交易员的学习阶段约束状态（JSON快照），重启后恢复学习阶段
+func (d *Database) SaveConstraintsState(traderID string, state string) error {
+	_, err := d.exec(`
+		INSERT INTO constraints_states (trader_id, state, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (trader_id) DO UPDATE SET
+			state = EXCLUDED.state,
+			updated_at = CURRENT_TIMESTAMP
+	`, traderID, state)
+	return err
+}
+
+// DeleteConstraintsState 删除交易员的学习阶段约束状态（删除交易员时调用）
+func (d *Database) DeleteConstraintsState(traderID string) error {
+	_, err := d.exec(`DELETE FROM constraints_states WHERE trader_id = $1`, traderID)
+	return err
+}
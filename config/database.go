@@ -420,6 +420,90 @@ func (d *Database) createTablesPostgres() error {
                         updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
                         UNIQUE(trader_id, symbol)
                 )`,
+
+                // 模拟盘账户表 (保存PaperTrader的账户快照,重启后恢复)
+                `CREATE TABLE IF NOT EXISTS paper_accounts (
+                        trader_id TEXT PRIMARY KEY,
+                        state TEXT NOT NULL,
+                        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+                )`,
+
+                // 账户熔断状态表 (保存日盈亏基准、净值峰值和暂停状态,重启后恢复)
+                `CREATE TABLE IF NOT EXISTS circuit_breaker_states (
+                        trader_id TEXT PRIMARY KEY,
+                        state TEXT NOT NULL,
+                        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+                )`,
+
+                // 学习阶段约束状态表 (保存交易数、当日亏损和拒绝次数,重启后恢复学习阶段)
+                `CREATE TABLE IF NOT EXISTS constraints_states (
+                        trader_id TEXT PRIMARY KEY,
+                        state TEXT NOT NULL,
+                        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+                )`,
+
+                // 实时风控状态表 (保存净值下限触发的暂停状态,需人工重置)
+                `CREATE TABLE IF NOT EXISTS guardian_states (
+                        trader_id TEXT PRIMARY KEY,
+                        state TEXT NOT NULL,
+                        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+                )`,
+
+                // 限价入场单状态表 (保存未成交限价单的止盈止损和挂单时间,重启后继续跟踪)
+                `CREATE TABLE IF NOT EXISTS limit_order_states (
+                        trader_id TEXT PRIMARY KEY,
+                        state TEXT NOT NULL,
+                        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+                )`,
+
+                // 本地跟踪止损状态表 (保存交易所不支持跟踪止损时本地模拟的跟踪止损,重启后继续跟踪)
+                `CREATE TABLE IF NOT EXISTS trailing_stop_states (
+                        trader_id TEXT PRIMARY KEY,
+                        state TEXT NOT NULL,
+                        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+                )`,
+
+                // 持仓资金费状态表 (保存各持仓的统计起点和已计入交易结果的资金费,重启后继续统计)
+                `CREATE TABLE IF NOT EXISTS funding_states (
+                        trader_id TEXT PRIMARY KEY,
+                        state TEXT NOT NULL,
+                        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+                )`,
+
+                // 交易所凭证表 (同一交易所可登记多套命名凭证，如币安/OKX子账户)
+                `CREATE TABLE IF NOT EXISTS exchange_accounts (
+                        id TEXT PRIMARY KEY,
+                        user_id TEXT NOT NULL,
+                        exchange_id TEXT NOT NULL,
+                        name TEXT NOT NULL,
+                        api_key TEXT DEFAULT '',
+                        secret_key TEXT DEFAULT '',
+                        passphrase TEXT DEFAULT '',
+                        testnet BOOLEAN DEFAULT false,
+                        sub_account TEXT DEFAULT '',
+                        hyperliquid_wallet_addr TEXT DEFAULT '',
+                        aster_user TEXT DEFAULT '',
+                        aster_signer TEXT DEFAULT '',
+                        aster_private_key TEXT DEFAULT '',
+                        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                        UNIQUE(user_id, exchange_id, name)
+                )`,
+
+                // AI用量表 (每个决策周期按模型汇总的token用量和费用)
+                `CREATE TABLE IF NOT EXISTS ai_usage (
+                        id BIGSERIAL PRIMARY KEY,
+                        user_id TEXT NOT NULL,
+                        trader_id TEXT NOT NULL,
+                        cycle_number INT DEFAULT 0,
+                        provider TEXT DEFAULT '',
+                        model TEXT DEFAULT '',
+                        calls INT DEFAULT 0,
+                        prompt_tokens BIGINT DEFAULT 0,
+                        completion_tokens BIGINT DEFAULT 0,
+                        cost_usd DECIMAL(18,8) DEFAULT 0,
+                        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+                )`,
         }
 
         for _, query := range queries {
@@ -471,13 +555,24 @@ func (d *Database) alterTables() error {
                 `ALTER TABLE traders ADD COLUMN use_coin_pool BOOLEAN DEFAULT 0`,               // 是否使用COIN POOL信号源
                 `ALTER TABLE traders ADD COLUMN use_oi_top BOOLEAN DEFAULT 0`,                  // 是否使用OI TOP信号源
                 `ALTER TABLE traders ADD COLUMN system_prompt_template TEXT DEFAULT 'default'`, // 系统提示词模板名称
+                `ALTER TABLE traders ADD COLUMN exchange_account_id TEXT DEFAULT ''`,           // 使用的交易所凭证ID，空表示交易所默认配置
+                `ALTER TABLE traders ADD COLUMN allow_shared_account BOOLEAN DEFAULT false`,    // 是否允许与其他交易员共用同一账户
+                `ALTER TABLE traders ADD COLUMN sizing_mode TEXT DEFAULT 'ai'`,                 // 开仓金额计算方式: ai / volatility
+                `ALTER TABLE traders ADD COLUMN risk_per_trade_pct REAL DEFAULT 1`,             // 波动率模式下每笔交易风险预算（占净值百分比）
+                `ALTER TABLE traders ADD COLUMN target_volatility_pct REAL DEFAULT 0`,          // 波动率模式下的目标波动率（ATR占价格百分比，0表示不缩放）
+                `ALTER TABLE traders ADD COLUMN ensemble_config TEXT DEFAULT ''`,               // 集成决策配置（JSON，空表示单模型决策）
+                `ALTER TABLE traders ADD COLUMN fallback_model_ids TEXT DEFAULT ''`,            // 故障转移备用AI模型ID（逗号分隔，按顺序使用）
                 // 添加ai_models表字段
                 `ALTER TABLE ai_models ADD COLUMN custom_api_url TEXT DEFAULT ''`,              // 自定义API地址
                 `ALTER TABLE ai_models ADD COLUMN custom_model_name TEXT DEFAULT ''`,           // 自定义模型名称
+                `ALTER TABLE ai_models ADD COLUMN temperature REAL DEFAULT 0.5`,                 // 采样温度
+                `ALTER TABLE ai_models ADD COLUMN max_tokens INTEGER DEFAULT 2000`,              // 单次输出的最大token数
 
                 // 为新的交易记录表创建索引
                 `CREATE INDEX IF NOT EXISTS idx_trade_records_trader_time ON trade_records(trader_id, created_at DESC)`,
                 `CREATE INDEX IF NOT EXISTS idx_trade_records_symbol ON trade_records(symbol)`,
+                `CREATE INDEX IF NOT EXISTS idx_ai_usage_user_time ON ai_usage(user_id, created_at DESC)`,
+                `CREATE INDEX IF NOT EXISTS idx_ai_usage_trader_time ON ai_usage(trader_id, created_at DESC)`,
         }
 
         for _, query := range alterQueries {
@@ -518,6 +613,9 @@ func (d *Database) initDefaultData() error {
         }{
                 {"deepseek", "DeepSeek", "deepseek"},
                 {"qwen", "Qwen", "qwen"},
+                {"anthropic", "Anthropic Claude", "anthropic"},
+                {"openai", "OpenAI", "openai"},
+                {"gemini", "Google Gemini", "gemini"},
         }
 
         // 需要初始化模型的用户列表
@@ -544,6 +642,10 @@ func (d *Database) initDefaultData() error {
                 {"hyperliquid", "Hyperliquid", "dex"},
                 {"aster", "Aster DEX", "dex"},
                 {"okx", "OKX Futures", "cex"},
+                {"bybit", "Bybit Futures", "cex"},
+                {"gate", "Gate.io Futures", "cex"},
+                {"bitget", "Bitget Futures", "cex"},
+                {"paper", "Paper Trading", "cex"},
         }
 
         for _, exchange := range exchanges {
@@ -601,6 +703,34 @@ func (d *Database) initDefaultData() error {
 
         			"trading_decision_points_cost": "1",
 
+        			"trading_decision_points_per_usd": "0", // 大于0时按实际AI费用折算每次决策消耗的积分（每美元积分数）
+
+        			"ai_model_pricing":           "", // 模型单价覆盖（JSON，美元/百万token，空表示使用默认单价）
+
+        			"paper_fee_rate":             "0.0004",
+
+        			"paper_slippage_rate":        "0.0005",
+
+        			"backtest_data_dir":          "backtest_data",
+
+        			"limit_order_ttl_minutes":    "30",
+
+        			"limit_order_time_in_force":  "GTC",
+
+        			"guardian_max_loss_pct":      "50",
+
+        			"guardian_equity_floor_pct":  "0",
+
+        			"circuit_breaker_flatten":    "false",
+
+        			"stage_constraints_enabled":  "true",
+
+        			"liquidation_atr_multiple":   "2",
+
+        			"portfolio_risk_limits":      `{"max_symbol_notional":0,"max_total_notional":0,"max_bucket_notional":0,"max_gross_leverage":0,"buckets":{"majors":["BTCUSDT","ETHUSDT"]}}`,
+
+        			"funding_cost_guard":         `{"max_tp_fraction":0,"hold_hours":24,"auto_close":false}`,
+
         		}
 
         for key, value := range systemConfigs {
@@ -746,6 +876,8 @@ func (d *Database) migrateAIModelsTable() error {
                                 api_key TEXT DEFAULT '',
                                 custom_api_url TEXT DEFAULT '',
                                 custom_model_name TEXT DEFAULT '',
+                                temperature REAL DEFAULT 0.5,
+                                max_tokens INTEGER DEFAULT 2000,
                                 created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
                                 updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
                                 PRIMARY KEY (id, user_id)
@@ -759,8 +891,9 @@ func (d *Database) migrateAIModelsTable() error {
 
                 // 3. 迁移数据
                 _, err = d.exec(`
-                        INSERT INTO ai_models (id, user_id, name, provider, enabled, api_key, custom_api_url, custom_model_name, created_at, updated_at)
-                        SELECT id, user_id, name, provider, enabled, COALESCE(api_key, ''), COALESCE(custom_api_url, ''), COALESCE(custom_model_name, ''), created_at, updated_at
+                        INSERT INTO ai_models (id, user_id, name, provider, enabled, api_key, custom_api_url, custom_model_name, temperature, max_tokens, created_at, updated_at)
+                        SELECT id, user_id, name, provider, enabled, COALESCE(api_key, ''), COALESCE(custom_api_url, ''), COALESCE(custom_model_name, ''),
+                               COALESCE(temperature, 0.5), COALESCE(max_tokens, 2000), created_at, updated_at
                         FROM ai_models_old
                 `)
                 if err != nil {
@@ -812,6 +945,8 @@ type AIModelConfig struct {
         APIKey          string    `json:"apiKey"`
         CustomAPIURL    string    `json:"customApiUrl"`
         CustomModelName string    `json:"customModelName"`
+        Temperature     float64   `json:"temperature"` // 采样温度（0-2，Anthropic最大为1）
+        MaxTokens       int       `json:"maxTokens"`   // 单次输出的最大token数
         CreatedAt       time.Time `json:"created_at"`
         UpdatedAt       time.Time `json:"updated_at"`
 }
@@ -834,6 +969,13 @@ type ExchangeConfig struct {
         AsterPrivateKey string    `json:"asterPrivateKey"`
         // OKX 特定字段
         OKXPassphrase   string    `json:"okxPassphrase"`
+        // Bybit 使用通用的 APIKey/SecretKey/Testnet 字段
+        // Gate.io 使用通用的 APIKey/SecretKey/Testnet 字段
+        // Bitget 的 Passphrase 复用 OKXPassphrase 字段
+        // 命名凭证（exchange_accounts），使用交易所默认配置时为空
+        AccountID       string    `json:"accountId,omitempty"`
+        AccountName     string    `json:"accountName,omitempty"`
+        SubAccount      string    `json:"subAccount,omitempty"` // 币安/OKX子账户标识
         CreatedAt       time.Time `json:"created_at"`
         UpdatedAt       time.Time `json:"updated_at"`
 }
@@ -857,6 +999,13 @@ type TraderRecord struct {
         OverrideBasePrompt   bool      `json:"override_base_prompt"`   // 是否覆盖基础prompt
         SystemPromptTemplate string    `json:"system_prompt_template"` // 系统提示词模板名称
         IsCrossMargin        bool      `json:"is_cross_margin"`        // 是否为全仓模式（true=全仓，false=逐仓）
+        ExchangeAccountID    string    `json:"exchange_account_id"`    // 使用的交易所凭证ID，空表示交易所默认配置
+        AllowSharedAccount   bool      `json:"allow_shared_account"`   // 是否允许与其他运行中的交易员共用同一账户
+        SizingMode           string    `json:"sizing_mode"`            // 开仓金额计算方式: ai（使用AI给出的金额）/ volatility（按ATR和风险预算计算）
+        RiskPerTradePct      float64   `json:"risk_per_trade_pct"`     // 每笔交易风险预算（占净值百分比）
+        TargetVolatilityPct  float64   `json:"target_volatility_pct"`  // 目标波动率（ATR占价格百分比，0表示不按币种波动率缩放）
+        EnsembleConfig       string    `json:"ensemble_config"`        // 集成决策配置（JSON: model_ids / open_rule / close_rule / min_confidence / min_voters，空表示单模型）
+        FallbackModelIDs     string    `json:"fallback_model_ids"`     // 故障转移备用AI模型ID（逗号分隔，主模型失败或冷却时按顺序使用）
         CreatedAt            time.Time `json:"created_at"`
         UpdatedAt            time.Time `json:"updated_at"`
 }
@@ -1331,6 +1480,8 @@ func (d *Database) GetAIModels(userID string) ([]*AIModelConfig, error) {
                         SELECT id, user_id, name, provider, enabled, api_key,
                                COALESCE(custom_api_url, '') as custom_api_url,
                                COALESCE(custom_model_name, '') as custom_model_name,
+                               COALESCE(temperature, 0.5) as temperature,
+                               COALESCE(max_tokens, 2000) as max_tokens,
                                created_at, updated_at
                         FROM ai_models WHERE user_id = ? ORDER BY id
                 `, userID)
@@ -1346,6 +1497,7 @@ func (d *Database) GetAIModels(userID string) ([]*AIModelConfig, error) {
                         err := rows.Scan(
                                 &model.ID, &model.UserID, &model.Name, &model.Provider,
                                 &model.Enabled, &model.APIKey, &model.CustomAPIURL, &model.CustomModelName,
+                                &model.Temperature, &model.MaxTokens,
                                 &model.CreatedAt, &model.UpdatedAt,
                         )
                         if err != nil {
@@ -1393,7 +1545,7 @@ func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, custom
 
         // 没有找到任何现有配置，创建新的
         // 推断 provider（从 id 中提取，或者直接使用 id）
-        if provider == id && (provider == "deepseek" || provider == "qwen") {
+        if provider == id && (provider == "deepseek" || provider == "qwen" || provider == "anthropic" || provider == "openai" || provider == "gemini") {
                 // id 本身就是 provider
                 provider = id
         } else {
@@ -1439,6 +1591,39 @@ func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, custom
         return err
 }
 
+// UpdateAIModelParams 更新AI模型的采样温度和最大输出token数（在UpdateAIModel之后调用，nil表示保持不变）
+func (d *Database) UpdateAIModelParams(userID, id string, temperature *float64, maxTokens *int) error {
+        if temperature == nil && maxTokens == nil {
+                return nil
+        }
+        if temperature != nil && (*temperature < 0 || *temperature > 2) {
+                return fmt.Errorf("temperature必须在0-2之间: %v", *temperature)
+        }
+        if maxTokens != nil && *maxTokens <= 0 {
+                return fmt.Errorf("max_tokens必须大于0: %d", *maxTokens)
+        }
+
+        // 与UpdateAIModel一致：先精确匹配ID，再兼容旧版按provider匹配
+        var existingID string
+        err := d.queryRow(`
+                SELECT id FROM ai_models WHERE user_id = $1 AND id = $2 LIMIT 1
+        `, userID, id).Scan(&existingID)
+        if err != nil {
+                err = d.queryRow(`
+                        SELECT id FROM ai_models WHERE user_id = $1 AND provider = $2 LIMIT 1
+                `, userID, id).Scan(&existingID)
+                if err != nil {
+                        return fmt.Errorf("AI模型不存在: %s", id)
+                }
+        }
+
+        _, err = d.exec(`
+                UPDATE ai_models SET temperature = COALESCE($1, temperature), max_tokens = COALESCE($2, max_tokens), updated_at = CURRENT_TIMESTAMP
+                WHERE id = $3 AND user_id = $4
+        `, temperature, maxTokens, existingID, userID)
+        return err
+}
+
 // GetExchanges 获取用户的交易所配置
 func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
         return withRetry(func() ([]*ExchangeConfig, error) {
@@ -1521,6 +1706,18 @@ func (d *Database) UpdateExchange(userID, id string, enabled bool, apiKey, secre
                 } else if id == "okx" {
                         name = "OKX Futures"
                         typ = "cex"
+                } else if id == "bybit" {
+                        name = "Bybit Futures"
+                        typ = "cex"
+                } else if id == "gate" {
+                        name = "Gate.io Futures"
+                        typ = "cex"
+                } else if id == "bitget" {
+                        name = "Bitget Futures"
+                        typ = "cex"
+                } else if id == "paper" {
+                        name = "Paper Trading"
+                        typ = "cex"
                 } else {
                         name = id + " Exchange"
                         typ = "cex"
@@ -1581,9 +1778,9 @@ func (d *Database) CreateExchange(userID, id, name, typ string, enabled bool, ap
 // CreateTrader 创建交易员
 func (d *Database) CreateTrader(trader *TraderRecord) error {
         _, err := d.exec(`
-                INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes, is_running, btc_eth_leverage, altcoin_leverage, trading_symbols, use_coin_pool, use_oi_top, custom_prompt, override_base_prompt, system_prompt_template, is_cross_margin)
-                VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
-        `, trader.ID, trader.UserID, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance, trader.ScanIntervalMinutes, trader.IsRunning, trader.BTCETHLeverage, trader.AltcoinLeverage, trader.TradingSymbols, trader.UseCoinPool, trader.UseOITop, trader.CustomPrompt, trader.OverrideBasePrompt, trader.SystemPromptTemplate, trader.IsCrossMargin)
+                INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes, is_running, btc_eth_leverage, altcoin_leverage, trading_symbols, use_coin_pool, use_oi_top, custom_prompt, override_base_prompt, system_prompt_template, is_cross_margin, exchange_account_id, allow_shared_account, sizing_mode, risk_per_trade_pct, target_volatility_pct, ensemble_config, fallback_model_ids)
+                VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)
+        `, trader.ID, trader.UserID, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance, trader.ScanIntervalMinutes, trader.IsRunning, trader.BTCETHLeverage, trader.AltcoinLeverage, trader.TradingSymbols, trader.UseCoinPool, trader.UseOITop, trader.CustomPrompt, trader.OverrideBasePrompt, trader.SystemPromptTemplate, trader.IsCrossMargin, trader.ExchangeAccountID, trader.AllowSharedAccount, trader.SizingMode, trader.RiskPerTradePct, trader.TargetVolatilityPct, trader.EnsembleConfig, trader.FallbackModelIDs)
         return err
 }
 
@@ -1597,7 +1794,12 @@ func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
                                COALESCE(use_coin_pool, false) as use_coin_pool, COALESCE(use_oi_top, false) as use_oi_top,
                                COALESCE(custom_prompt, '') as custom_prompt, COALESCE(override_base_prompt, false) as override_base_prompt,
                                COALESCE(system_prompt_template, 'default') as system_prompt_template,
-                               COALESCE(is_cross_margin, true) as is_cross_margin, created_at, updated_at
+                               COALESCE(is_cross_margin, true) as is_cross_margin,
+                               COALESCE(exchange_account_id, '') as exchange_account_id, COALESCE(allow_shared_account, false) as allow_shared_account,
+                               COALESCE(sizing_mode, 'ai') as sizing_mode, COALESCE(risk_per_trade_pct, 1) as risk_per_trade_pct,
+                               COALESCE(target_volatility_pct, 0) as target_volatility_pct,
+                               COALESCE(ensemble_config, '') as ensemble_config, COALESCE(fallback_model_ids, '') as fallback_model_ids,
+                               created_at, updated_at
                         FROM traders WHERE user_id = $1 ORDER BY created_at DESC
                 `, userID)
                 if err != nil {
@@ -1615,6 +1817,9 @@ func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
                                 &trader.UseCoinPool, &trader.UseOITop,
                                 &trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
                                 &trader.IsCrossMargin,
+                                &trader.ExchangeAccountID, &trader.AllowSharedAccount,
+                                &trader.SizingMode, &trader.RiskPerTradePct, &trader.TargetVolatilityPct,
+                                &trader.EnsembleConfig, &trader.FallbackModelIDs,
                                 &trader.CreatedAt, &trader.UpdatedAt,
                         )
                         if err != nil {
@@ -1640,12 +1845,16 @@ func (d *Database) UpdateTrader(trader *TraderRecord) error {
                         name = ?, ai_model_id = ?, exchange_id = ?, initial_balance = ?,
                         scan_interval_minutes = ?, btc_eth_leverage = ?, altcoin_leverage = ?,
                         trading_symbols = ?, custom_prompt = ?, override_base_prompt = ?,
-                        system_prompt_template = ?, is_cross_margin = ?, updated_at = CURRENT_TIMESTAMP
+                        system_prompt_template = ?, is_cross_margin = ?,
+                        exchange_account_id = ?, allow_shared_account = ?,
+                        sizing_mode = ?, risk_per_trade_pct = ?, target_volatility_pct = ?, ensemble_config = ?, fallback_model_ids = ?, updated_at = CURRENT_TIMESTAMP
                 WHERE id = ? AND user_id = ?
         `, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance,
                 trader.ScanIntervalMinutes, trader.BTCETHLeverage, trader.AltcoinLeverage,
                 trader.TradingSymbols, trader.CustomPrompt, trader.OverrideBasePrompt,
-                trader.SystemPromptTemplate, trader.IsCrossMargin, trader.ID, trader.UserID)
+                trader.SystemPromptTemplate, trader.IsCrossMargin,
+                trader.ExchangeAccountID, trader.AllowSharedAccount,
+                trader.SizingMode, trader.RiskPerTradePct, trader.TargetVolatilityPct, trader.EnsembleConfig, trader.FallbackModelIDs, trader.ID, trader.UserID)
         return err
 }
 
@@ -1657,8 +1866,36 @@ func (d *Database) UpdateTraderCustomPrompt(userID, id string, customPrompt stri
 
 // DeleteTrader 删除交易员
 func (d *Database) DeleteTrader(userID, id string) error {
-        _, err := d.exec(`DELETE FROM traders WHERE id = ? AND user_id = ?`, id, userID)
-        return err
+        result, err := d.exec(`DELETE FROM traders WHERE id = ? AND user_id = ?`, id, userID)
+        if err != nil {
+                return err
+        }
+
+        // 同时清理模拟盘账户（非模拟盘交易员没有记录）和熔断状态
+        if rows, err := result.RowsAffected(); err == nil && rows > 0 {
+                if err := d.DeletePaperAccountState(id); err != nil {
+                        log.Printf("⚠️ 清理模拟盘账户失败: %v", err)
+                }
+                if err := d.DeleteCircuitBreakerState(id); err != nil {
+                        log.Printf("⚠️ 清理熔断状态失败: %v", err)
+                }
+                if err := d.DeleteConstraintsState(id); err != nil {
+                        log.Printf("⚠️ 清理学习阶段约束状态失败: %v", err)
+                }
+                if err := d.DeleteGuardianState(id); err != nil {
+                        log.Printf("⚠️ 清理实时风控状态失败: %v", err)
+                }
+                if err := d.DeleteLimitOrderState(id); err != nil {
+                        log.Printf("⚠️ 清理限价入场单状态失败: %v", err)
+                }
+                if err := d.DeleteTrailingStopState(id); err != nil {
+                        log.Printf("⚠️ 清理本地跟踪止损状态失败: %v", err)
+                }
+                if err := d.DeleteFundingState(id); err != nil {
+                        log.Printf("⚠️ 清理持仓资金费状态失败: %v", err)
+                }
+        }
+        return nil
 }
 
 // traderConfigResult 用于 withRetry 的返回结构
@@ -1678,7 +1915,13 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
                 err := d.queryRow(`
                         SELECT 
                                 t.id, t.user_id, t.name, t.ai_model_id, t.exchange_id, t.initial_balance, t.scan_interval_minutes, t.is_running, t.created_at, t.updated_at,
-                                a.id, a.user_id, a.name, a.provider, a.enabled, a.api_key, a.created_at, a.updated_at,
+                                COALESCE(t.exchange_account_id, '') as exchange_account_id, COALESCE(t.allow_shared_account, false) as allow_shared_account,
+                                COALESCE(t.sizing_mode, 'ai') as sizing_mode, COALESCE(t.risk_per_trade_pct, 1) as risk_per_trade_pct,
+                                COALESCE(t.target_volatility_pct, 0) as target_volatility_pct,
+                                COALESCE(t.ensemble_config, '') as ensemble_config, COALESCE(t.fallback_model_ids, '') as fallback_model_ids,
+                                a.id, a.user_id, a.name, a.provider, a.enabled, a.api_key,
+                                COALESCE(a.temperature, 0.5) as temperature, COALESCE(a.max_tokens, 2000) as max_tokens,
+                                a.created_at, a.updated_at,
                                 e.id, e.user_id, e.name, e.type, e.enabled, e.api_key, e.secret_key, e.testnet,
                                 COALESCE(e.hyperliquid_wallet_addr, '') as hyperliquid_wallet_addr,
                                 COALESCE(e.aster_user, '') as aster_user,
@@ -1693,7 +1936,11 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
                         &trader.ID, &trader.UserID, &trader.Name, &trader.AIModelID, &trader.ExchangeID,
                         &trader.InitialBalance, &trader.ScanIntervalMinutes, &trader.IsRunning,
                         &trader.CreatedAt, &trader.UpdatedAt,
+                        &trader.ExchangeAccountID, &trader.AllowSharedAccount,
+                        &trader.SizingMode, &trader.RiskPerTradePct, &trader.TargetVolatilityPct,
+                        &trader.EnsembleConfig, &trader.FallbackModelIDs,
                         &aiModel.ID, &aiModel.UserID, &aiModel.Name, &aiModel.Provider, &aiModel.Enabled, &aiModel.APIKey,
+                        &aiModel.Temperature, &aiModel.MaxTokens,
                         &aiModel.CreatedAt, &aiModel.UpdatedAt,
                         &exchange.ID, &exchange.UserID, &exchange.Name, &exchange.Type, &exchange.Enabled,
                         &exchange.APIKey, &exchange.SecretKey, &exchange.Testnet,
@@ -95,6 +95,43 @@ func TestUpdateAIModelExisting(t *testing.T) {
 	assert.Equal(t, "key2", models[0].APIKey, "API key should be updated")
 }
 
+// TestUpdateAIModelParams 测试更新AI模型的生成参数
+func TestUpdateAIModelParams(t *testing.T) {
+	tdb := setupTestDB(t)
+	defer tdb.teardown(t)
+
+	now := time.Now()
+	user := &User{
+		ID:           "test_user_ai3",
+		Email:        "testai3@example.com",
+		PasswordHash: "hash",
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	require.NoError(t, tdb.db.CreateUser(user))
+	require.NoError(t, tdb.db.UpdateAIModel("test_user_ai3", "anthropic", true, "key", "", ""))
+
+	// 新建模型使用默认参数
+	models, err := tdb.db.GetAIModels("test_user_ai3")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(models))
+	assert.Equal(t, "anthropic", models[0].Provider)
+	assert.Equal(t, 0.5, models[0].Temperature)
+	assert.Equal(t, 2000, models[0].MaxTokens)
+
+	// 只更新temperature，max_tokens保持不变
+	temperature := 0.0
+	require.NoError(t, tdb.db.UpdateAIModelParams("test_user_ai3", "anthropic", &temperature, nil))
+	models, err = tdb.db.GetAIModels("test_user_ai3")
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, models[0].Temperature)
+	assert.Equal(t, 2000, models[0].MaxTokens)
+
+	// 超出范围的参数被拒绝
+	invalid := 3.0
+	assert.Error(t, tdb.db.UpdateAIModelParams("test_user_ai3", "anthropic", &invalid, nil))
+}
+
 // TestGetExchanges 测试获取交易所配置
 func TestGetExchanges(t *testing.T) {
 	tdb := setupTestDB(t)
@@ -52,6 +52,7 @@ func cleanupTestData(t *testing.T, db *Database) {
 		"DELETE FROM login_attempts WHERE email LIKE 'test_%'",
 		"DELETE FROM audit_logs WHERE user_id LIKE 'test_%'",
 		"DELETE FROM traders WHERE user_id LIKE 'test_%'",
+		"DELETE FROM exchange_accounts WHERE user_id LIKE 'test_%'",
 		"DELETE FROM user_signal_sources WHERE user_id LIKE 'test_%'",
 		"DELETE FROM exchanges WHERE user_id LIKE 'test_%'",
 		"DELETE FROM ai_models WHERE user_id LIKE 'test_%'",
@@ -175,6 +176,10 @@ func TestDefaultExchangesExist(t *testing.T) {
 	assert.NotNil(t, exchangeMap["hyperliquid"], "Hyperliquid should exist")
 	assert.NotNil(t, exchangeMap["aster"], "Aster should exist")
 	assert.NotNil(t, exchangeMap["okx"], "OKX should exist")
+	assert.NotNil(t, exchangeMap["bybit"], "Bybit should exist")
+	assert.NotNil(t, exchangeMap["gate"], "Gate.io should exist")
+	assert.NotNil(t, exchangeMap["bitget"], "Bitget should exist")
+	assert.NotNil(t, exchangeMap["paper"], "Paper trading should exist")
 
 	// 验证OKX类型正确
 	okx := exchangeMap["okx"]
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExchangeAccount 交易所命名凭证
+// 同一交易所可登记多套凭证（如币安/OKX子账户），交易员通过 exchange_account_id 选择其中一套
+type ExchangeAccount struct {
+	ID         string `json:"id"`
+	UserID     string `json:"user_id"`
+	ExchangeID string `json:"exchange_id"`
+	Name       string `json:"name"`
+	APIKey     string `json:"apiKey"`
+	SecretKey  string `json:"secretKey"`
+	Passphrase string `json:"passphrase"` // OKX/Bitget 的 API Passphrase
+	Testnet    bool   `json:"testnet"`
+	// 子账户标识（币安子账户邮箱、OKX子账户名），子账户使用自己的API Key下单，此字段用于标识和共用检测
+	SubAccount string `json:"subAccount"`
+	// Hyperliquid 特定字段
+	HyperliquidWalletAddr string `json:"hyperliquidWalletAddr"`
+	// Aster 特定字段
+	AsterUser       string    `json:"asterUser"`
+	AsterSigner     string    `json:"asterSigner"`
+	AsterPrivateKey string    `json:"asterPrivateKey"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ExchangeConfig 用凭证覆盖交易所默认配置，得到交易员实际使用的配置（base为nil时只使用凭证）
+func (a *ExchangeAccount) ExchangeConfig(base *ExchangeConfig) *ExchangeConfig {
+	cfg := ExchangeConfig{ID: a.ExchangeID, UserID: a.UserID, Name: a.ExchangeID}
+	if base != nil {
+		cfg = *base
+	}
+	cfg.Enabled = true
+	cfg.APIKey = a.APIKey
+	cfg.SecretKey = a.SecretKey
+	cfg.OKXPassphrase = a.Passphrase
+	cfg.Testnet = a.Testnet
+	cfg.HyperliquidWalletAddr = a.HyperliquidWalletAddr
+	cfg.AsterUser = a.AsterUser
+	cfg.AsterSigner = a.AsterSigner
+	cfg.AsterPrivateKey = a.AsterPrivateKey
+	cfg.AccountID = a.ID
+	cfg.AccountName = a.Name
+	cfg.SubAccount = a.SubAccount
+	return &cfg
+}
+
+// AccountKey 配置实际操作的交易所账户标识，用于检测多个交易员共用同一账户
+// 模拟盘和没有凭证的配置返回空字符串（不参与检测）；API Key只保存哈希，避免出现在日志中
+func (e *ExchangeConfig) AccountKey() string {
+	var identity string
+	switch {
+	case e.ID == "paper":
+		return ""
+	case e.SubAccount != "":
+		identity = "sub:" + strings.ToLower(strings.TrimSpace(e.SubAccount))
+	case e.ID == "hyperliquid" && e.HyperliquidWalletAddr != "":
+		identity = "wallet:" + strings.ToLower(e.HyperliquidWalletAddr)
+	case e.ID == "aster" && e.AsterUser != "":
+		identity = "user:" + strings.ToLower(e.AsterUser)
+	case e.APIKey != "":
+		sum := sha256.Sum256([]byte(e.APIKey))
+		identity = "key:" + hex.EncodeToString(sum[:8])
+	default:
+		return ""
+	}
+
+	network := "mainnet"
+	if e.Testnet {
+		network = "testnet"
+	}
+	return e.ID + ":" + network + ":" + identity
+}
+
+// CreateExchangeAccount 创建交易所命名凭证
+func (d *Database) CreateExchangeAccount(account *ExchangeAccount) error {
+	if account.ID == "" {
+		account.ID = uuid.New().String()
+	}
+	_, err := d.exec(`
+		INSERT INTO exchange_accounts (id, user_id, exchange_id, name, api_key, secret_key, passphrase, testnet, sub_account,
+			hyperliquid_wallet_addr, aster_user, aster_signer, aster_private_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`, account.ID, account.UserID, account.ExchangeID, account.Name, account.APIKey, account.SecretKey, account.Passphrase,
+		account.Testnet, account.SubAccount, account.HyperliquidWalletAddr, account.AsterUser, account.AsterSigner, account.AsterPrivateKey)
+	return err
+}
+
+// UpdateExchangeAccount 更新交易所命名凭证，敏感字段为空时保留原值
+func (d *Database) UpdateExchangeAccount(account *ExchangeAccount) error {
+	result, err := d.exec(`
+		UPDATE exchange_accounts SET
+			name = $1, testnet = $2, sub_account = $3, hyperliquid_wallet_addr = $4, aster_user = $5, aster_signer = $6,
+			api_key = CASE WHEN $7 = '' THEN api_key ELSE $7 END,
+			secret_key = CASE WHEN $8 = '' THEN secret_key ELSE $8 END,
+			passphrase = CASE WHEN $9 = '' THEN passphrase ELSE $9 END,
+			aster_private_key = CASE WHEN $10 = '' THEN aster_private_key ELSE $10 END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $11 AND user_id = $12
+	`, account.Name, account.Testnet, account.SubAccount, account.HyperliquidWalletAddr, account.AsterUser, account.AsterSigner,
+		account.APIKey, account.SecretKey, account.Passphrase, account.AsterPrivateKey, account.ID, account.UserID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+const exchangeAccountColumns = `id, user_id, exchange_id, name, api_key, secret_key, passphrase, testnet, sub_account,
+	hyperliquid_wallet_addr, aster_user, aster_signer, aster_private_key, created_at, updated_at`
+
+func scanExchangeAccount(row interface{ Scan(...interface{}) error }) (*ExchangeAccount, error) {
+	var account ExchangeAccount
+	err := row.Scan(
+		&account.ID, &account.UserID, &account.ExchangeID, &account.Name,
+		&account.APIKey, &account.SecretKey, &account.Passphrase, &account.Testnet, &account.SubAccount,
+		&account.HyperliquidWalletAddr, &account.AsterUser, &account.AsterSigner, &account.AsterPrivateKey,
+		&account.CreatedAt, &account.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// GetExchangeAccounts 获取用户的交易所命名凭证
+func (d *Database) GetExchangeAccounts(userID string) ([]*ExchangeAccount, error) {
+	return withRetry(func() ([]*ExchangeAccount, error) {
+		rows, err := d.query(`
+			SELECT `+exchangeAccountColumns+`
+			FROM exchange_accounts WHERE user_id = $1 ORDER BY exchange_id, name
+		`, userID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var accounts []*ExchangeAccount
+		for rows.Next() {
+			account, err := scanExchangeAccount(rows)
+			if err != nil {
+				return nil, err
+			}
+			accounts = append(accounts, account)
+		}
+		return accounts, rows.Err()
+	})
+}
+
+// GetExchangeAccount 获取单个交易所命名凭证
+func (d *Database) GetExchangeAccount(userID, id string) (*ExchangeAccount, error) {
+	return withRetry(func() (*ExchangeAccount, error) {
+		return scanExchangeAccount(d.queryRow(`
+			SELECT `+exchangeAccountColumns+`
+			FROM exchange_accounts WHERE id = $1 AND user_id = $2
+		`, id, userID))
+	})
+}
+
+// DeleteExchangeAccount 删除交易所命名凭证，仍有交易员使用时拒绝删除
+func (d *Database) DeleteExchangeAccount(userID, id string) error {
+	var inUse int
+	if err := d.queryRow(`
+		SELECT COUNT(*) FROM traders WHERE user_id = $1 AND exchange_account_id = $2
+	`, userID, id).Scan(&inUse); err != nil {
+		return err
+	}
+	if inUse > 0 {
+		return fmt.Errorf("仍有 %d 个交易员使用该凭证，请先修改交易员配置", inUse)
+	}
+
+	result, err := d.exec(`DELETE FROM exchange_accounts WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
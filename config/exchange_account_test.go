@@ -0,0 +1,88 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExchangeAccountOverridesDefaultConfig(t *testing.T) {
+	base := &ExchangeConfig{ID: "okx", UserID: "u1", Name: "OKX Futures", Type: "cex", APIKey: "default-key", SecretKey: "default-secret", OKXPassphrase: "default-pass"}
+	account := &ExchangeAccount{ID: "acc1", UserID: "u1", ExchangeID: "okx", Name: "sub-a", APIKey: "sub-key", SecretKey: "sub-secret", Passphrase: "sub-pass", SubAccount: "SubA"}
+
+	cfg := account.ExchangeConfig(base)
+	assert.Equal(t, "OKX Futures", cfg.Name)
+	assert.True(t, cfg.Enabled, "使用命名凭证时视为已启用")
+	assert.Equal(t, "sub-key", cfg.APIKey)
+	assert.Equal(t, "sub-pass", cfg.OKXPassphrase)
+	assert.Equal(t, "acc1", cfg.AccountID)
+	assert.Equal(t, "SubA", cfg.SubAccount)
+	assert.Equal(t, "default-key", base.APIKey, "不应修改交易所默认配置")
+
+	// 交易所默认配置不存在时只使用凭证
+	cfg = account.ExchangeConfig(nil)
+	assert.Equal(t, "okx", cfg.ID)
+	assert.Equal(t, "u1", cfg.UserID)
+}
+
+func TestExchangeConfigAccountKey(t *testing.T) {
+	binance := func(apiKey, sub string, testnet bool) string {
+		return (&ExchangeConfig{ID: "binance", APIKey: apiKey, SubAccount: sub, Testnet: testnet}).AccountKey()
+	}
+
+	assert.Equal(t, binance("key-a", "", false), binance("key-a", "", false))
+	assert.NotEqual(t, binance("key-a", "", false), binance("key-b", "", false))
+	assert.NotEqual(t, binance("key-a", "", false), binance("key-a", "", true), "测试网与主网是不同账户")
+	assert.NotContains(t, binance("key-a", "", false), "key-a", "不应包含原始API Key")
+
+	// 子账户以标识区分，大小写不敏感
+	assert.Equal(t, binance("key-a", "Trader1@Example.com", false), binance("key-b", "trader1@example.com", false))
+	assert.NotEqual(t, binance("key-a", "sub1", false), binance("key-a", "sub2", false))
+
+	// Hyperliquid按钱包地址识别
+	hlA := (&ExchangeConfig{ID: "hyperliquid", APIKey: "pk1", HyperliquidWalletAddr: "0xABC"}).AccountKey()
+	hlB := (&ExchangeConfig{ID: "hyperliquid", APIKey: "pk2", HyperliquidWalletAddr: "0xabc"}).AccountKey()
+	assert.Equal(t, hlA, hlB)
+	require.True(t, strings.HasPrefix(hlA, "hyperliquid:"))
+
+	assert.Empty(t, (&ExchangeConfig{ID: "paper"}).AccountKey(), "模拟盘不参与共用检测")
+	assert.Empty(t, (&ExchangeConfig{ID: "binance"}).AccountKey(), "没有凭证时不参与共用检测")
+}
+
+func TestExchangeAccountCRUD(t *testing.T) {
+	tdb := setupTestDB(t)
+	defer tdb.teardown(t)
+	db := tdb.db
+
+	account := &ExchangeAccount{UserID: "test_accounts", ExchangeID: "binance", Name: "sub-1", APIKey: "k1", SecretKey: "s1", SubAccount: "sub1@example.com"}
+	require.NoError(t, db.CreateExchangeAccount(account))
+	require.NotEmpty(t, account.ID)
+
+	// 同一交易所下名称不能重复
+	assert.Error(t, db.CreateExchangeAccount(&ExchangeAccount{UserID: "test_accounts", ExchangeID: "binance", Name: "sub-1"}))
+
+	// 敏感字段为空时保留原值
+	account.Name = "sub-1-renamed"
+	account.APIKey, account.SecretKey = "", ""
+	require.NoError(t, db.UpdateExchangeAccount(account))
+	stored, err := db.GetExchangeAccount("test_accounts", account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "sub-1-renamed", stored.Name)
+	assert.Equal(t, "k1", stored.APIKey)
+	assert.Equal(t, "s1", stored.SecretKey)
+
+	accounts, err := db.GetExchangeAccounts("test_accounts")
+	require.NoError(t, err)
+	assert.Len(t, accounts, 1)
+
+	// 有交易员使用时拒绝删除
+	require.NoError(t, db.CreateTrader(&TraderRecord{ID: "test_accounts_trader", UserID: "test_accounts", Name: "t", AIModelID: "deepseek", ExchangeID: "binance", InitialBalance: 1000, ExchangeAccountID: account.ID}))
+	assert.Error(t, db.DeleteExchangeAccount("test_accounts", account.ID))
+
+	require.NoError(t, db.DeleteTrader("test_accounts", "test_accounts_trader"))
+	require.NoError(t, db.DeleteExchangeAccount("test_accounts", account.ID))
+	_, err = db.GetExchangeAccount("test_accounts", account.ID)
+	assert.Error(t, err)
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"database/sql"
+)
+
+// GetFundingState 获取交易员的持仓资金费统计（JSON快照），不存在时返回空字符串
+func (d *Database) GetFundingState(traderID string) (string, error) {
+	var state string
+	err := d.queryRow(`
+		SELECT state FROM funding_states WHERE trader_id = $1
+	`, traderID).Scan(&state)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return state, nil
+}
+
+// SaveFundingState 保存交易员的持仓资金费统计（JSON快照），重启后从原开仓时间继续统计
+func (d *Database) SaveFundingState(traderID string, state string) error {
+	_, err := d.exec(`
+		INSERT INTO funding_states (trader_id, state, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (trader_id) DO UPDATE SET
+			state = EXCLUDED.state,
+			updated_at = CURRENT_TIMESTAMP
+	`, traderID, state)
+	return err
+}
+
+// DeleteFundingState 删除交易员的持仓资金费统计（删除交易员时调用）
+func (d *Database) DeleteFundingState(traderID string) error {
+	_, err := d.exec(`DELETE FROM funding_states WHERE trader_id = $1`, traderID)
+	return err
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"database/sql"
+)
+
+// GetGuardianState 获取交易员的实时风控状态（JSON快照），不存在时返回空字符串
+func (d *Database) GetGuardianState(traderID string) (string, error) {
+	var state string
+	err := d.queryRow(`
+		SELECT state FROM guardian_states WHERE trader_id = $1
+	`, traderID).Scan(&state)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return state, nil
+}
+
+// SaveGuardianState 保存交易员的实时风控状态（JSON快照），重启后恢复净值下限触发的暂停
+func (d *Database) SaveGuardianState(traderID string, state string) error {
+	_, err := d.exec(`
+		INSERT INTO guardian_states (trader_id, state, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (trader_id) DO UPDATE SET
+			state = EXCLUDED.state,
+			updated_at = CURRENT_TIMESTAMP
+	`, traderID, state)
+	return err
+}
+
+// DeleteGuardianState 删除交易员的实时风控状态（删除交易员时调用）
+func (d *Database) DeleteGuardianState(traderID string) error {
+	_, err := d.exec(`DELETE FROM guardian_states WHERE trader_id = $1`, traderID)
+	return err
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"database/sql"
+)
+
+// GetLimitOrderState 获取交易员的未成交限价入场单（JSON快照），不存在时返回空字符串
+func (d *Database) GetLimitOrderState(traderID string) (string, error) {
+	var state string
+	err := d.queryRow(`
+		SELECT state FROM limit_order_states WHERE trader_id = $1
+	`, traderID).Scan(&state)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return state, nil
+}
+
+// SaveLimitOrderState 保存交易员的未成交限价入场单（JSON快照），重启后继续跟踪成交和超时
+func (d *Database) SaveLimitOrderState(traderID string, state string) error {
+	_, err := d.exec(`
+		INSERT INTO limit_order_states (trader_id, state, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (trader_id) DO UPDATE SET
+			state = EXCLUDED.state,
+			updated_at = CURRENT_TIMESTAMP
+	`, traderID, state)
+	return err
+}
+
+// DeleteLimitOrderState 删除交易员的未成交限价入场单（删除交易员时调用）
+func (d *Database) DeleteLimitOrderState(traderID string) error {
+	_, err := d.exec(`DELETE FROM limit_order_states WHERE trader_id = $1`, traderID)
+	return err
+}
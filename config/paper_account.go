@@ -0,0 +1,38 @@
+package config
+
+import (
+	"database/sql"
+)
+
+// GetPaperAccountState 获取模拟盘账户状态（JSON快照），不存在时返回空字符串
+func (d *Database) GetPaperAccountState(traderID string) (string, error) {
+	var state string
+	err := d.queryRow(`
+		SELECT state FROM paper_accounts WHERE trader_id = $1
+	`, traderID).Scan(&state)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return state, nil
+}
+
+// SavePaperAccountState 保存模拟盘账户状态（JSON快照）
+func (d *Database) SavePaperAccountState(traderID string, state string) error {
+	_, err := d.exec(`
+		INSERT INTO paper_accounts (trader_id, state, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (trader_id) DO UPDATE SET
+			state = EXCLUDED.state,
+			updated_at = CURRENT_TIMESTAMP
+	`, traderID, state)
+	return err
+}
+
+// DeletePaperAccountState 删除模拟盘账户状态（删除交易员时调用）
+func (d *Database) DeletePaperAccountState(traderID string) error {
+	_, err := d.exec(`DELETE FROM paper_accounts WHERE trader_id = $1`, traderID)
+	return err
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"database/sql"
+)
+
+// GetTrailingStopState 获取交易员的本地模拟跟踪止损（JSON快照），不存在时返回空字符串
+func (d *Database) GetTrailingStopState(traderID string) (string, error) {
+	var state string
+	err := d.queryRow(`
+		SELECT state FROM trailing_stop_states WHERE trader_id = $1
+	`, traderID).Scan(&state)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return state, nil
+}
+
+// SaveTrailingStopState 保存交易员的本地模拟跟踪止损（JSON快照），重启后继续跟踪
+func (d *Database) SaveTrailingStopState(traderID string, state string) error {
+	_, err := d.exec(`
+		INSERT INTO trailing_stop_states (trader_id, state, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (trader_id) DO UPDATE SET
+			state = EXCLUDED.state,
+			updated_at = CURRENT_TIMESTAMP
+	`, traderID, state)
+	return err
+}
+
+// DeleteTrailingStopState 删除交易员的本地模拟跟踪止损（删除交易员时调用）
+func (d *Database) DeleteTrailingStopState(traderID string) error {
+	_, err := d.exec(`DELETE FROM trailing_stop_states WHERE trader_id = $1`, traderID)
+	return err
+}
@@ -23,7 +23,8 @@ type PositionInfo struct {
 	UnrealizedPnLPct float64 `json:"unrealized_pnl_pct"`
 	LiquidationPrice float64 `json:"liquidation_price"`
 	MarginUsed       float64 `json:"margin_used"`
-	UpdateTime       int64   `json:"update_time"` // 持仓更新时间戳（毫秒）
+	AccruedFunding   float64 `json:"accrued_funding"` // 开仓以来已结算的资金费（正数为收到，负数为支付）
+	UpdateTime       int64   `json:"update_time"`     // 持仓更新时间戳（毫秒）
 }
 
 // AccountInfo 账户信息
@@ -66,18 +67,34 @@ type Context struct {
 	Performance     interface{}             `json:"-"` // 历史表现分析（logger.PerformanceAnalysis）
 	BTCETHLeverage  int                     `json:"-"` // BTC/ETH杠杆倍数（从配置读取）
 	AltcoinLeverage int                     `json:"-"` // 山寨币杠杆倍数（从配置读取）
+
+	// 回测注入（为空时使用实时数据）
+	MarketDataProvider func(symbol string) (*market.Data, error) `json:"-"` // 行情数据源（设置后不再拉取实时OI Top数据）
+	Clock              func() time.Time                          `json:"-"` // 时钟（模拟时间）
+
+	Ensemble *Ensemble `json:"-"` // 集成决策（为空或少于2个模型时使用单模型）
+}
+
+// now 返回上下文时钟的当前时间
+func (ctx *Context) now() time.Time {
+	if ctx.Clock != nil {
+		return ctx.Clock()
+	}
+	return time.Now()
 }
 
 // Decision AI的交易决策
 type Decision struct {
 	Symbol          string  `json:"symbol"`
-	Action          string  `json:"action"` // "open_long", "open_short", "close_long", "close_short", "hold", "wait"
+	Action          string  `json:"action"` // "open_long", "open_short", "close_long", "close_short", "reduce_long", "reduce_short", "add_long", "add_short", "hold", "wait"
 	Leverage        int     `json:"leverage,omitempty"`
 	PositionSizeUSD float64 `json:"position_size_usd,omitempty"`
+	SizePercent     float64 `json:"size_percent,omitempty"` // 减仓/加仓数量占当前持仓的百分比（与position_size_usd二选一）
 	StopLoss        float64 `json:"stop_loss,omitempty"`
 	TakeProfit      float64 `json:"take_profit,omitempty"`
-	Confidence      int     `json:"confidence,omitempty"` // 信心度 (0-100)
-	RiskUSD         float64 `json:"risk_usd,omitempty"`   // 最大美元风险
+	Confidence      int     `json:"confidence,omitempty"`  // 信心度 (0-100)
+	RiskUSD         float64 `json:"risk_usd,omitempty"`    // 最大美元风险
+	LimitPrice      float64 `json:"limit_price,omitempty"` // 限价入场价（0表示市价开仓）
 	Reasoning       string  `json:"reasoning"`
 }
 
@@ -86,17 +103,26 @@ type FullDecision struct {
 	SystemPrompt string     `json:"system_prompt"` // 系统提示词（发送给AI的系统prompt）
 	UserPrompt   string     `json:"user_prompt"`   // 发送给AI的输入prompt
 	CoTTrace     string     `json:"cot_trace"`     // 思维链分析（AI输出）
+	RawResponse  string     `json:"raw_response"`  // AI原始响应（用于离线回放，修复后为最终解析的响应）
 	Decisions    []Decision `json:"decisions"`     // 具体决策列表
 	Timestamp    time.Time  `json:"timestamp"`
+
+	StructuredOutput bool            `json:"structured_output"`        // 是否使用JSON schema结构化输出
+	ParseOutcome     string          `json:"parse_outcome"`            // 解析结果: ok / repaired / failed
+	ParseAttempts    []ParseAttempt  `json:"parse_attempts,omitempty"` // 首次解析失败时的各次响应和错误
+	AIProvider       string          `json:"ai_provider,omitempty"`    // 给出决策的AI提供商（故障转移时为实际使用的备用模型）
+	Usage            []mcp.CallUsage `json:"usage,omitempty"`          // 本次决策每次AI调用的token用量（含修复请求和集成决策各模型）
+
+	Ensemble *EnsembleResult `json:"ensemble,omitempty"` // 集成决策时各模型的输出和投票结果
 }
 
 // GetFullDecision 获取AI的完整交易决策（批量分析所有币种和持仓）
-func GetFullDecision(ctx *Context, mcpClient *mcp.Client) (*FullDecision, error) {
+func GetFullDecision(ctx *Context, mcpClient mcp.AIClient) (*FullDecision, error) {
 	return GetFullDecisionWithCustomPrompt(ctx, mcpClient, "", false, "")
 }
 
 // GetFullDecisionWithCustomPrompt 获取AI的完整交易决策（支持自定义prompt和模板选择）
-func GetFullDecisionWithCustomPrompt(ctx *Context, mcpClient *mcp.Client, customPrompt string, overrideBase bool, templateName string) (*FullDecision, error) {
+func GetFullDecisionWithCustomPrompt(ctx *Context, mcpClient mcp.AIClient, customPrompt string, overrideBase bool, templateName string) (*FullDecision, error) {
 	// 1. 为所有币种获取市场数据
 	if err := fetchMarketDataForContext(ctx); err != nil {
 		return nil, fmt.Errorf("获取市场数据失败: %w", err)
@@ -111,29 +137,43 @@ func GetFullDecisionWithCustomPrompt(ctx *Context, mcpClient *mcp.Client, custom
 	systemPrompt := buildSystemPromptWithCustom(ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, customPrompt, overrideBase, templateName)
 	userPrompt := buildUserPrompt(ctx)
 
-	// 3. 调用AI API（使用 system + user prompt）
-	aiResponse, err := mcpClient.CallWithMessages(systemPrompt, userPrompt)
-	if err != nil {
+	// 集成决策：多个模型并行决策后投票合并
+	if ctx.Ensemble != nil && len(ctx.Ensemble.Members) >= 2 {
+		decision, err := getEnsembleDecision(ctx.Ensemble, systemPrompt, userPrompt, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
+		decision.Timestamp = ctx.now()
+		decision.SystemPrompt = systemPrompt
+		decision.UserPrompt = userPrompt
+		if err != nil {
+			return decision, fmt.Errorf("集成决策失败: %w", err)
+		}
+		return decision, nil
+	}
+
+	// 3. 调用AI API并解析（使用 system + user prompt，解析失败时把错误发回AI修复）
+	decision, err := requestDecisions(mcpClient, systemPrompt, userPrompt, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
+	if decision == nil {
 		// 检查是否为余额不足错误
 		if strings.Contains(err.Error(), "Insufficient Balance") || strings.Contains(err.Error(), "余额不足") {
-			log.Printf("\n" + strings.Repeat("!", 70))
+			log.Print("\n" + strings.Repeat("!", 70))
 			log.Printf("❌ 严重错误: AI API 余额不足！")
-			log.Printf("👉 请检查您的 AI 服务提供商 (%s) 账户余额", mcpClient.Provider)
+			if client, ok := mcpClient.(*mcp.Client); ok {
+				log.Printf("👉 请检查您的 AI 服务提供商 (%s) 账户余额", client.Provider)
+			}
 			log.Printf("👉 或者尝试切换到其他 AI 模型 (在配置中修改)")
-			log.Printf(strings.Repeat("!", 70) + "\n")
+			log.Print(strings.Repeat("!", 70) + "\n")
 		}
 		return nil, fmt.Errorf("调用AI API失败: %w", err)
 	}
 
-	// 4. 解析AI响应
-	decision, err := parseFullDecisionResponse(aiResponse, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
-	if err != nil {
-		return decision, fmt.Errorf("解析AI响应失败: %w", err)
+	if reporter, ok := mcpClient.(mcp.ProviderReporter); ok {
+		decision.AIProvider = reporter.LastProvider()
 	}
-
-	decision.Timestamp = time.Now()
+	decision.Timestamp = ctx.now()
 	decision.SystemPrompt = systemPrompt // 保存系统prompt
 	decision.UserPrompt = userPrompt     // 保存输入prompt
+	if err != nil {
+		return decision, fmt.Errorf("解析AI响应失败: %w", err)
+	}
 	return decision, nil
 }
 
@@ -166,8 +206,13 @@ func fetchMarketDataForContext(ctx *Context) error {
 		positionSymbols[pos.Symbol] = true
 	}
 
+	getData := market.Get
+	if ctx.MarketDataProvider != nil {
+		getData = ctx.MarketDataProvider
+	}
+
 	for symbol := range symbolSet {
-		data, err := market.Get(symbol)
+		data, err := getData(symbol)
 		if err != nil {
 			// 单个币种失败不影响整体，只记录错误
 			continue
@@ -191,6 +236,11 @@ func fetchMarketDataForContext(ctx *Context) error {
 		ctx.MarketDataMap[symbol] = data
 	}
 
+	// 使用注入的历史数据时不拉取实时OI Top数据（避免引入未来信息）
+	if ctx.MarketDataProvider != nil {
+		return nil
+	}
+
 	// 加载OI Top数据（不影响主流程）
 	oiPositions, err := pool.GetOITopPositions()
 	if err == nil {
@@ -288,12 +338,16 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 	sb.WriteString("第二步: JSON决策数组\n\n")
 	sb.WriteString("```json\n[\n")
 	sb.WriteString(fmt.Sprintf("  {\"symbol\": \"BTCUSDT\", \"action\": \"open_short\", \"leverage\": %d, \"position_size_usd\": %.0f, \"stop_loss\": 97000, \"take_profit\": 91000, \"confidence\": 85, \"risk_usd\": 300, \"reasoning\": \"下跌趋势+MACD死叉\"},\n", btcEthLeverage, accountEquity*5))
-	sb.WriteString("  {\"symbol\": \"ETHUSDT\", \"action\": \"close_long\", \"reasoning\": \"止盈离场\"}\n")
+	sb.WriteString("  {\"symbol\": \"ETHUSDT\", \"action\": \"close_long\", \"reasoning\": \"止盈离场\"},\n")
+	sb.WriteString("  {\"symbol\": \"SOLUSDT\", \"action\": \"reduce_long\", \"size_percent\": 50, \"reasoning\": \"到达第一目标，减仓一半\"}\n")
 	sb.WriteString("]\n```\n\n")
 	sb.WriteString("字段说明:\n")
-	sb.WriteString("- `action`: open_long | open_short | close_long | close_short | hold | wait\n")
+	sb.WriteString("- `action`: open_long | open_short | close_long | close_short | reduce_long | reduce_short | add_long | add_short | hold | wait\n")
 	sb.WriteString("- `confidence`: 0-100（开仓建议≥75）\n")
-	sb.WriteString("- 开仓时必填: leverage, position_size_usd, stop_loss, take_profit, confidence, risk_usd, reasoning\n\n")
+	sb.WriteString("- 开仓时必填: leverage, position_size_usd, stop_loss, take_profit, confidence, risk_usd, reasoning\n")
+	sb.WriteString("- `limit_price`: 可选，开仓时按该价格挂限价单入场（须在止损和止盈之间），不填则市价开仓；超时未成交的挂单会被自动撤销\n")
+	sb.WriteString("- 减仓(reduce_*)/加仓(add_*)只能用于已有持仓，必填 size_percent（占当前持仓的百分比）或 position_size_usd（美元价值）其中之一；减仓100%等同于平仓\n")
+	sb.WriteString("- 加仓时可选填 stop_loss, take_profit 作为整个持仓的新止损止盈，不填则沿用原止损止盈；止盈止损单会按新的持仓数量自动调整\n\n")
 
 	return sb.String()
 }
@@ -329,7 +383,7 @@ func buildUserPrompt(ctx *Context) string {
 			// 计算持仓时长
 			holdingDuration := ""
 			if pos.UpdateTime > 0 {
-				durationMs := time.Now().UnixMilli() - pos.UpdateTime
+				durationMs := ctx.now().UnixMilli() - pos.UpdateTime
 				durationMin := durationMs / (1000 * 60) // 转换为分钟
 				if durationMin < 60 {
 					holdingDuration = fmt.Sprintf(" | 持仓时长%d分钟", durationMin)
@@ -340,10 +394,15 @@ func buildUserPrompt(ctx *Context) string {
 				}
 			}
 
-			sb.WriteString(fmt.Sprintf("%d. %s %s | 入场价%.4f 当前价%.4f | 盈亏%+.2f%% | 杠杆%dx | 保证金%.0f | 强平价%.4f%s\n\n",
+			fundingInfo := ""
+			if pos.AccruedFunding != 0 {
+				fundingInfo = fmt.Sprintf(" | 已结算资金费%+.2f USDT", pos.AccruedFunding)
+			}
+
+			sb.WriteString(fmt.Sprintf("%d. %s %s | 入场价%.4f 当前价%.4f | 盈亏%+.2f%% | 杠杆%dx | 保证金%.0f | 强平价%.4f%s%s\n\n",
 				i+1, pos.Symbol, strings.ToUpper(pos.Side),
 				pos.EntryPrice, pos.MarkPrice, pos.UnrealizedPnLPct,
-				pos.Leverage, pos.MarginUsed, pos.LiquidationPrice, holdingDuration))
+				pos.Leverage, pos.MarginUsed, pos.LiquidationPrice, holdingDuration, fundingInfo))
 
 			// 使用FormatMarketData输出完整市场数据
 			if marketData, ok := ctx.MarketDataMap[pos.Symbol]; ok {
@@ -429,6 +488,11 @@ func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthL
 
 // extractCoTTrace 提取思维链分析
 func extractCoTTrace(response string) string {
+	// 结构化输出：思维链在analysis字段
+	if structured, ok := parseStructuredResponse(response); ok {
+		return strings.TrimSpace(structured.Analysis)
+	}
+
 	// 查找JSON数组的开始位置
 	jsonStart := strings.Index(response, "[")
 
@@ -443,6 +507,11 @@ func extractCoTTrace(response string) string {
 
 // extractDecisions 提取JSON决策列表
 func extractDecisions(response string) ([]Decision, error) {
+	// 结构化输出直接使用decisions字段
+	if structured, ok := parseStructuredResponse(response); ok {
+		return structured.Decisions, nil
+	}
+
 	// 直接查找JSON数组 - 找第一个完整的JSON数组
 	arrayStart := strings.Index(response, "[")
 	if arrayStart == -1 {
@@ -491,6 +560,68 @@ func validateDecisions(decisions []Decision, accountEquity float64, btcEthLevera
 	return nil
 }
 
+// isAdjustAction 是否为减仓/加仓操作
+func isAdjustAction(action string) bool {
+	switch action {
+	case "reduce_long", "reduce_short", "add_long", "add_short":
+		return true
+	}
+	return false
+}
+
+// MaxPositionValue 单币种持仓价值上限：BTC/ETH最多10倍账户净值，山寨币最多1.5倍（开仓和加仓后的合计持仓共用）
+func MaxPositionValue(symbol string, accountEquity float64) float64 {
+	if symbol == "BTCUSDT" || symbol == "ETHUSDT" {
+		return accountEquity * 10
+	}
+	return accountEquity * 1.5
+}
+
+// validateAdjustDecision 验证减仓/加仓决策
+// 减仓百分比不超过100%；加仓的美元价值受与开仓相同的单币种上限约束，杠杆可不填（沿用持仓杠杆）
+func validateAdjustDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int) error {
+	if d.SizePercent < 0 || d.PositionSizeUSD < 0 {
+		return fmt.Errorf("减仓/加仓数量不能为负数")
+	}
+	if (d.SizePercent > 0) == (d.PositionSizeUSD > 0) {
+		return fmt.Errorf("%s 必须且只能指定 size_percent 或 position_size_usd 其中之一", d.Action)
+	}
+
+	if strings.HasPrefix(d.Action, "reduce_") {
+		if d.SizePercent > 100 {
+			return fmt.Errorf("减仓比例不能超过100%%: %.2f%%", d.SizePercent)
+		}
+		return nil
+	}
+
+	// 这里只能检查加仓部分；加仓后的合计持仓价值在执行时按当时的持仓检查（按百分比加仓时数量也只有执行时才知道）
+	maxLeverage := altcoinLeverage
+	maxPositionValue := MaxPositionValue(d.Symbol, accountEquity)
+	if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
+		maxLeverage = btcEthLeverage
+	}
+	if d.Leverage < 0 || d.Leverage > maxLeverage {
+		return fmt.Errorf("杠杆必须在1-%d之间（%s，当前配置上限%d倍）: %d", maxLeverage, d.Symbol, maxLeverage, d.Leverage)
+	}
+	if d.PositionSizeUSD > maxPositionValue*1.01 {
+		return fmt.Errorf("加仓价值不能超过%.0f USDT，实际: %.0f", maxPositionValue, d.PositionSizeUSD)
+	}
+
+	// 新的止损止盈可选，填写时必须方向正确
+	if d.StopLoss < 0 || d.TakeProfit < 0 {
+		return fmt.Errorf("止损和止盈不能为负数")
+	}
+	if d.StopLoss > 0 && d.TakeProfit > 0 {
+		if d.Action == "add_long" && d.StopLoss >= d.TakeProfit {
+			return fmt.Errorf("做多时止损价必须小于止盈价")
+		}
+		if d.Action == "add_short" && d.StopLoss <= d.TakeProfit {
+			return fmt.Errorf("做空时止损价必须大于止盈价")
+		}
+	}
+	return nil
+}
+
 // findMatchingBracket 查找匹配的右括号
 func findMatchingBracket(s string, start int) int {
 	if start >= len(s) || s[start] != '[' {
@@ -517,26 +648,41 @@ func findMatchingBracket(s string, start int) int {
 func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int) error {
 	// 验证action
 	validActions := map[string]bool{
-		"open_long":   true,
-		"open_short":  true,
-		"close_long":  true,
-		"close_short": true,
-		"hold":        true,
-		"wait":        true,
+		"open_long":    true,
+		"open_short":   true,
+		"close_long":   true,
+		"close_short":  true,
+		"reduce_long":  true,
+		"reduce_short": true,
+		"add_long":     true,
+		"add_short":    true,
+		"hold":         true,
+		"wait":         true,
 	}
 
 	if !validActions[d.Action] {
 		return fmt.Errorf("无效的action: %s", d.Action)
 	}
 
+	if d.LimitPrice < 0 {
+		return fmt.Errorf("限价不能为负数: %.4f", d.LimitPrice)
+	}
+	if d.LimitPrice > 0 && d.Action != "open_long" && d.Action != "open_short" {
+		return fmt.Errorf("只有开仓操作可以指定limit_price: %s", d.Action)
+	}
+
+	// 减仓/加仓：数量按百分比或美元价值二选一
+	if isAdjustAction(d.Action) {
+		return validateAdjustDecision(d, accountEquity, btcEthLeverage, altcoinLeverage)
+	}
+
 	// 开仓操作必须提供完整参数
 	if d.Action == "open_long" || d.Action == "open_short" {
 		// 根据币种使用配置的杠杆上限
-		maxLeverage := altcoinLeverage          // 山寨币使用配置的杠杆
-		maxPositionValue := accountEquity * 1.5 // 山寨币最多1.5倍账户净值
+		maxLeverage := altcoinLeverage // 山寨币使用配置的杠杆
+		maxPositionValue := MaxPositionValue(d.Symbol, accountEquity)
 		if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
-			maxLeverage = btcEthLeverage          // BTC和ETH使用配置的杠杆
-			maxPositionValue = accountEquity * 10 // BTC/ETH最多10倍账户净值
+			maxLeverage = btcEthLeverage // BTC和ETH使用配置的杠杆
 		}
 
 		if d.Leverage <= 0 || d.Leverage > maxLeverage {
@@ -563,16 +709,24 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 			if d.StopLoss >= d.TakeProfit {
 				return fmt.Errorf("做多时止损价必须小于止盈价")
 			}
+			if d.LimitPrice > 0 && (d.LimitPrice <= d.StopLoss || d.LimitPrice >= d.TakeProfit) {
+				return fmt.Errorf("做多限价必须在止损价和止盈价之间: 限价 %.4f [止损:%.4f 止盈:%.4f]", d.LimitPrice, d.StopLoss, d.TakeProfit)
+			}
 		} else {
 			if d.StopLoss <= d.TakeProfit {
 				return fmt.Errorf("做空时止损价必须大于止盈价")
 			}
+			if d.LimitPrice > 0 && (d.LimitPrice >= d.StopLoss || d.LimitPrice <= d.TakeProfit) {
+				return fmt.Errorf("做空限价必须在止盈价和止损价之间: 限价 %.4f [止损:%.4f 止盈:%.4f]", d.LimitPrice, d.StopLoss, d.TakeProfit)
+			}
 		}
 
 		// 验证风险回报比（必须≥1:3）
-		// 计算入场价（假设当前市价）
+		// 计算入场价（限价单使用限价，市价单假设当前市价）
 		var entryPrice float64
-		if d.Action == "open_long" {
+		if d.LimitPrice > 0 {
+			entryPrice = d.LimitPrice
+		} else if d.Action == "open_long" {
 			// 做多：入场价在止损和止盈之间
 			entryPrice = d.StopLoss + (d.TakeProfit-d.StopLoss)*0.2 // 假设在20%位置入场
 		} else {
@@ -0,0 +1,354 @@
+package decision
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+
+	"nofx/mcp"
+)
+
+// 集成决策投票规则
+const (
+	EnsembleRuleUnanimous = "unanimous" // 所有给出有效决策的模型一致
+	EnsembleRuleMajority  = "majority"  // 超过半数
+	EnsembleRuleAny       = "any"       // 至少一个模型
+)
+
+// defaultEnsembleMinVoters 未配置时至少需要2个模型给出有效决策
+const defaultEnsembleMinVoters = 2
+
+// EnsembleConfig 集成决策配置（traders.ensemble_config，JSON）
+type EnsembleConfig struct {
+	ModelIDs      []string `json:"model_ids"`      // 参与投票的其他AI模型ID（交易员的主模型自动参与）
+	OpenRule      string   `json:"open_rule"`      // 开仓/加仓规则（默认unanimous）
+	CloseRule     string   `json:"close_rule"`     // 平仓/减仓规则（默认majority）
+	MinConfidence int      `json:"min_confidence"` // 开仓/加仓的平均信心度下限（0表示不限制）
+	MinVoters     int      `json:"min_voters"`     // 最少有效模型数（默认2，不足时本周期不执行）
+}
+
+// ParseEnsembleConfig 解析并校验集成决策配置，空字符串返回nil（单模型）
+func ParseEnsembleConfig(raw string) (*EnsembleConfig, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var cfg EnsembleConfig
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return nil, fmt.Errorf("解析集成决策配置失败: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate 校验投票规则和参数
+func (c EnsembleConfig) Validate() error {
+	for _, rule := range []string{c.OpenRule, c.CloseRule} {
+		if rule != "" && rule != EnsembleRuleUnanimous && rule != EnsembleRuleMajority && rule != EnsembleRuleAny {
+			return fmt.Errorf("无效的投票规则: %s（可选 unanimous / majority / any）", rule)
+		}
+	}
+	if c.MinConfidence < 0 || c.MinConfidence > 100 {
+		return fmt.Errorf("信心度下限必须在0-100之间: %d", c.MinConfidence)
+	}
+	if c.MinVoters < 0 {
+		return fmt.Errorf("最少有效模型数不能为负数: %d", c.MinVoters)
+	}
+	return nil
+}
+
+func (c EnsembleConfig) openRule() string {
+	if c.OpenRule == "" {
+		return EnsembleRuleUnanimous
+	}
+	return c.OpenRule
+}
+
+func (c EnsembleConfig) closeRule() string {
+	if c.CloseRule == "" {
+		return EnsembleRuleMajority
+	}
+	return c.CloseRule
+}
+
+func (c EnsembleConfig) minVoters() int {
+	if c.MinVoters > 0 {
+		return c.MinVoters
+	}
+	return defaultEnsembleMinVoters
+}
+
+// EnsembleMember 参与投票的模型
+type EnsembleMember struct {
+	Name   string
+	Client mcp.AIClient
+}
+
+// Ensemble 集成决策：Context.Ensemble不为nil时替代单模型调用，所有模型使用相同的prompt并行决策
+type Ensemble struct {
+	Members []EnsembleMember
+	Config  EnsembleConfig
+}
+
+// EnsembleMemberResult 单个模型的输出
+type EnsembleMemberResult struct {
+	Model        string     `json:"model"`
+	RawResponse  string     `json:"raw_response,omitempty"`
+	Decisions    []Decision `json:"decisions,omitempty"`
+	ParseOutcome string     `json:"parse_outcome,omitempty"`
+	Error        string     `json:"error,omitempty"` // 调用或解析失败（该模型不参与投票）
+
+	Usage []mcp.CallUsage `json:"-"` // token用量（汇总到FullDecision.Usage）
+}
+
+// EnsembleVote 单个（币种, 操作）的投票结果
+type EnsembleVote struct {
+	Symbol   string   `json:"symbol"`
+	Action   string   `json:"action"`
+	Voters   []string `json:"voters"`   // 提出该决策的模型
+	Required int      `json:"required"` // 按规则需要的票数
+	Accepted bool     `json:"accepted"`
+	Reason   string   `json:"reason,omitempty"` // 未通过原因
+}
+
+// EnsembleResult 集成决策结果（各模型输出和投票结果）
+type EnsembleResult struct {
+	Members []EnsembleMemberResult `json:"members"`
+	Votes   []EnsembleVote         `json:"votes"`
+}
+
+// Voters 通过的决策由哪些模型提出
+func (r *EnsembleResult) Voters(symbol, action string) []string {
+	if r == nil {
+		return nil
+	}
+	for _, vote := range r.Votes {
+		if vote.Accepted && vote.Symbol == symbol && vote.Action == action {
+			return vote.Voters
+		}
+	}
+	return nil
+}
+
+// ensembleProposal 某个模型提出的决策
+type ensembleProposal struct {
+	Model    string
+	Decision Decision
+}
+
+// getEnsembleDecision 并行调用所有模型，按投票规则合并决策
+func getEnsembleDecision(ensemble *Ensemble, systemPrompt, userPrompt string, accountEquity float64, btcEthLeverage, altcoinLeverage int) (*FullDecision, error) {
+	members := ensemble.Members
+	outputs := make([]*FullDecision, len(members))
+	result := &EnsembleResult{Members: make([]EnsembleMemberResult, len(members)), Votes: []EnsembleVote{}}
+
+	var wg sync.WaitGroup
+	for i, member := range members {
+		wg.Add(1)
+		go func(i int, member EnsembleMember) {
+			defer wg.Done()
+			output, err := requestDecisions(member.Client, systemPrompt, userPrompt, accountEquity, btcEthLeverage, altcoinLeverage)
+			memberResult := EnsembleMemberResult{Model: member.Name}
+			if output != nil {
+				memberResult.Usage = output.Usage
+				memberResult.RawResponse = output.RawResponse
+				memberResult.Decisions = output.Decisions
+				memberResult.ParseOutcome = output.ParseOutcome
+			}
+			if err != nil {
+				memberResult.Error = err.Error()
+			} else {
+				outputs[i] = output
+			}
+			result.Members[i] = memberResult
+		}(i, member)
+	}
+	wg.Wait()
+
+	var cot strings.Builder
+	var proposals [][]ensembleProposal
+	for i, output := range outputs {
+		name := members[i].Name
+		if output == nil {
+			log.Printf("⚠️ [集成决策] %s 未给出有效决策: %s", name, result.Members[i].Error)
+			fmt.Fprintf(&cot, "## %s（未参与投票）\n%s\n\n", name, result.Members[i].Error)
+			continue
+		}
+		fmt.Fprintf(&cot, "## %s\n%s\n\n", name, output.CoTTrace)
+		memberProposals := make([]ensembleProposal, 0, len(output.Decisions))
+		for _, d := range output.Decisions {
+			memberProposals = append(memberProposals, ensembleProposal{Model: name, Decision: d})
+		}
+		proposals = append(proposals, memberProposals)
+	}
+
+	fullDecision := &FullDecision{Decisions: []Decision{}, Ensemble: result, ParseOutcome: ParseOutcomeOK}
+	for _, member := range result.Members {
+		fullDecision.Usage = append(fullDecision.Usage, member.Usage...)
+	}
+	if len(proposals) < ensemble.Config.minVoters() {
+		fullDecision.CoTTrace = strings.TrimSpace(cot.String())
+		return fullDecision, fmt.Errorf("集成决策有效模型不足: %d/%d（至少需要%d个）", len(proposals), len(members), ensemble.Config.minVoters())
+	}
+
+	fullDecision.Decisions, result.Votes = combineDecisions(proposals, ensemble.Config, accountEquity, btcEthLeverage, altcoinLeverage)
+	cot.WriteString("## 投票结果\n")
+	for _, vote := range result.Votes {
+		status := "✅ 通过"
+		if !vote.Accepted {
+			status = "❌ " + vote.Reason
+		}
+		fmt.Fprintf(&cot, "- %s %s: %d/%d票 [%s] %s\n", vote.Symbol, vote.Action, len(vote.Voters), vote.Required, strings.Join(vote.Voters, ", "), status)
+	}
+	fullDecision.CoTTrace = strings.TrimSpace(cot.String())
+
+	// 原始响应保存为合并后的决策（各模型输出含方括号，不放入以免回放时误解析），回放时与单模型响应格式一致
+	decisionJSON, _ := json.Marshal(fullDecision.Decisions)
+	fullDecision.RawResponse = fmt.Sprintf("集成决策: %d个模型投票\n\n%s", len(proposals), decisionJSON)
+	return fullDecision, nil
+}
+
+// requiredVotes 规则要求的票数
+func requiredVotes(rule string, voters int) int {
+	switch rule {
+	case EnsembleRuleAny:
+		return 1
+	case EnsembleRuleMajority:
+		return voters/2 + 1
+	default:
+		return voters
+	}
+}
+
+// isEntryAction 开仓/加仓（增加风险敞口）
+func isEntryAction(action string) bool {
+	return strings.HasPrefix(action, "open_") || strings.HasPrefix(action, "add_")
+}
+
+// combineDecisions 按（币种, 操作）统计票数，达到规则要求的决策取平均参数后重新验证
+// 开仓/加仓使用OpenRule并检查平均信心度，平仓/减仓使用CloseRule；hold/wait不参与投票
+func combineDecisions(proposals [][]ensembleProposal, cfg EnsembleConfig, accountEquity float64, btcEthLeverage, altcoinLeverage int) ([]Decision, []EnsembleVote) {
+	type voteKey struct{ symbol, action string }
+	var order []voteKey
+	grouped := make(map[voteKey][]ensembleProposal)
+	for _, memberProposals := range proposals {
+		seen := make(map[voteKey]bool)
+		for _, p := range memberProposals {
+			key := voteKey{p.Decision.Symbol, p.Decision.Action}
+			if p.Decision.Action == "hold" || p.Decision.Action == "wait" || seen[key] {
+				continue // 同一模型对同一操作只计一票
+			}
+			seen[key] = true
+			if _, ok := grouped[key]; !ok {
+				order = append(order, key)
+			}
+			grouped[key] = append(grouped[key], p)
+		}
+	}
+
+	votes := make([]EnsembleVote, 0, len(order))
+	combined := make([]Decision, 0, len(order))
+	for _, key := range order {
+		group := grouped[key]
+		rule := cfg.closeRule()
+		if isEntryAction(key.action) {
+			rule = cfg.openRule()
+		}
+		vote := EnsembleVote{Symbol: key.symbol, Action: key.action, Required: requiredVotes(rule, len(proposals))}
+		for _, p := range group {
+			vote.Voters = append(vote.Voters, p.Model)
+		}
+
+		d := averageDecisions(group)
+		switch {
+		case len(group) < vote.Required:
+			vote.Reason = fmt.Sprintf("票数不足（规则%s）", rule)
+		case isEntryAction(key.action) && cfg.MinConfidence > 0 && d.Confidence < cfg.MinConfidence:
+			vote.Reason = fmt.Sprintf("平均信心度 %d 低于 %d", d.Confidence, cfg.MinConfidence)
+		default:
+			if err := validateDecision(&d, accountEquity, btcEthLeverage, altcoinLeverage); err != nil {
+				vote.Reason = fmt.Sprintf("合并后验证失败: %v", err)
+			} else {
+				vote.Accepted = true
+			}
+		}
+		votes = append(votes, vote)
+		if vote.Accepted {
+			combined = append(combined, d)
+		}
+	}
+
+	// 同一币种同时通过多空两个方向的开仓（如any规则）时都不执行
+	for i := range votes {
+		if !votes[i].Accepted || !isEntryAction(votes[i].Action) {
+			continue
+		}
+		for j := range votes {
+			if i != j && votes[j].Accepted && votes[j].Symbol == votes[i].Symbol && isEntryAction(votes[j].Action) &&
+				strings.HasSuffix(votes[j].Action, "_long") != strings.HasSuffix(votes[i].Action, "_long") {
+				votes[i].Accepted, votes[i].Reason = false, "多空方向冲突"
+				votes[j].Accepted, votes[j].Reason = false, "多空方向冲突"
+			}
+		}
+	}
+	result := combined[:0]
+	for _, d := range combined {
+		for _, vote := range votes {
+			if vote.Accepted && vote.Symbol == d.Symbol && vote.Action == d.Action {
+				result = append(result, d)
+				break
+			}
+		}
+	}
+	return result, votes
+}
+
+// averageDecisions 合并同一（币种, 操作）的多个决策：数值参数取提供了该参数的模型的平均值
+// 杠杆向下取整；只有所有模型都给出限价时才使用平均限价，否则市价；减仓/加仓有模型使用百分比时统一按百分比
+func averageDecisions(group []ensembleProposal) Decision {
+	first := group[0].Decision
+	d := Decision{Symbol: first.Symbol, Action: first.Action}
+
+	average := func(value func(Decision) float64) float64 {
+		sum, count := 0.0, 0
+		for _, p := range group {
+			if v := value(p.Decision); v > 0 {
+				sum += v
+				count++
+			}
+		}
+		if count == 0 {
+			return 0
+		}
+		return sum / float64(count)
+	}
+
+	d.Leverage = int(math.Floor(average(func(x Decision) float64 { return float64(x.Leverage) })))
+	d.StopLoss = average(func(x Decision) float64 { return x.StopLoss })
+	d.TakeProfit = average(func(x Decision) float64 { return x.TakeProfit })
+	d.RiskUSD = average(func(x Decision) float64 { return x.RiskUSD })
+	d.Confidence = int(math.Round(average(func(x Decision) float64 { return float64(x.Confidence) })))
+	d.SizePercent = average(func(x Decision) float64 { return x.SizePercent })
+	if d.SizePercent == 0 || !isAdjustAction(d.Action) {
+		d.SizePercent = 0
+		d.PositionSizeUSD = average(func(x Decision) float64 { return x.PositionSizeUSD })
+	}
+
+	allLimit := true
+	reasons := make([]string, 0, len(group))
+	for _, p := range group {
+		if p.Decision.LimitPrice <= 0 {
+			allLimit = false
+		}
+		reasons = append(reasons, fmt.Sprintf("[%s] %s", p.Model, p.Decision.Reasoning))
+	}
+	if allLimit {
+		d.LimitPrice = average(func(x Decision) float64 { return x.LimitPrice })
+	}
+	d.Reasoning = strings.Join(reasons, " | ")
+	return d
+}
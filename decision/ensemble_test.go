@@ -0,0 +1,151 @@
+package decision
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	ensembleOpenBTC  = `{"symbol":"BTCUSDT","action":"open_long","leverage":5,"position_size_usd":2000,"stop_loss":90000,"take_profit":110000,"confidence":80,"risk_usd":100,"reasoning":"trend"}`
+	ensembleOpenBTC3 = `{"symbol":"BTCUSDT","action":"open_long","leverage":2,"position_size_usd":1000,"stop_loss":92000,"take_profit":112000,"confidence":60,"risk_usd":50,"reasoning":"breakout"}`
+	ensembleShortBTC = `{"symbol":"BTCUSDT","action":"open_short","leverage":3,"position_size_usd":1000,"stop_loss":110000,"take_profit":90000,"confidence":70,"risk_usd":50,"reasoning":"top"}`
+	ensembleCloseETH = `{"symbol":"ETHUSDT","action":"close_long","reasoning":"weak"}`
+	ensembleWait     = `{"symbol":"SOLUSDT","action":"wait","reasoning":"no setup"}`
+)
+
+// newTestEnsemble 每个响应对应一个模型（a, b, c...），空响应表示调用失败
+func newTestEnsemble(cfg EnsembleConfig, responses ...string) *Ensemble {
+	ensemble := &Ensemble{Config: cfg}
+	for i, response := range responses {
+		client := &scriptedClient{}
+		if response != "" {
+			client.responses = []string{response}
+		}
+		ensemble.Members = append(ensemble.Members, EnsembleMember{Name: string(rune('a' + i)), Client: client})
+	}
+	return ensemble
+}
+
+func ensembleResponse(decisions ...string) string {
+	return "分析\n\n[" + strings.Join(decisions, ",") + "]"
+}
+
+func findVote(t *testing.T, result *EnsembleResult, symbol, action string) EnsembleVote {
+	t.Helper()
+	for _, vote := range result.Votes {
+		if vote.Symbol == symbol && vote.Action == action {
+			return vote
+		}
+	}
+	t.Fatalf("缺少投票结果: %s %s", symbol, action)
+	return EnsembleVote{}
+}
+
+func TestEnsembleVotingRules(t *testing.T) {
+	responses := []string{
+		ensembleResponse(ensembleOpenBTC, ensembleCloseETH),
+		ensembleResponse(ensembleOpenBTC, ensembleCloseETH),
+		ensembleResponse(ensembleWait),
+	}
+
+	// 默认规则：开仓需要全票，平仓过半即可
+	decision, err := getEnsembleDecision(newTestEnsemble(EnsembleConfig{}, responses...), "system", "user", 1000, 5, 5)
+	if err != nil {
+		t.Fatalf("集成决策失败: %v", err)
+	}
+	if len(decision.Decisions) != 1 || decision.Decisions[0].Action != "close_long" {
+		t.Fatalf("只有平仓应通过: %+v", decision.Decisions)
+	}
+	open := findVote(t, decision.Ensemble, "BTCUSDT", "open_long")
+	if open.Accepted || open.Required != 3 || !reflect.DeepEqual(open.Voters, []string{"a", "b"}) {
+		t.Errorf("开仓投票结果错误: %+v", open)
+	}
+	if close := findVote(t, decision.Ensemble, "ETHUSDT", "close_long"); !close.Accepted || close.Required != 2 {
+		t.Errorf("平仓投票结果错误: %+v", close)
+	}
+	if len(decision.Ensemble.Members) != 3 || decision.Ensemble.Members[2].RawResponse != responses[2] {
+		t.Errorf("应记录每个模型的原始输出: %+v", decision.Ensemble.Members)
+	}
+	if len(decision.Usage) != 3 {
+		t.Errorf("应汇总各模型的token用量: %+v", decision.Usage)
+	}
+
+	// 原始响应为合并后的决策，回放时得到相同结果
+	replayed, err := parseFullDecisionResponse(decision.RawResponse, 1000, 5, 5)
+	if err != nil || !reflect.DeepEqual(replayed.Decisions, decision.Decisions) {
+		t.Errorf("原始响应应可解析为合并后的决策: %+v %v", replayed, err)
+	}
+
+	// 开仓改为多数规则后通过
+	decision, err = getEnsembleDecision(newTestEnsemble(EnsembleConfig{OpenRule: EnsembleRuleMajority}, responses...), "system", "user", 1000, 5, 5)
+	if err != nil || len(decision.Decisions) != 2 {
+		t.Fatalf("多数规则下开仓应通过: %+v %v", decision.Decisions, err)
+	}
+	if voters := decision.Ensemble.Voters("BTCUSDT", "open_long"); !reflect.DeepEqual(voters, []string{"a", "b"}) {
+		t.Errorf("应记录提出决策的模型: %v", voters)
+	}
+}
+
+func TestEnsembleAveragesSizing(t *testing.T) {
+	ensemble := newTestEnsemble(EnsembleConfig{}, ensembleResponse(ensembleOpenBTC), ensembleResponse(ensembleOpenBTC3))
+	decision, err := getEnsembleDecision(ensemble, "system", "user", 1000, 5, 5)
+	if err != nil || len(decision.Decisions) != 1 {
+		t.Fatalf("全票开仓应通过: %+v %v", decision, err)
+	}
+	d := decision.Decisions[0]
+	if d.Leverage != 3 || d.PositionSizeUSD != 1500 || d.StopLoss != 91000 || d.TakeProfit != 111000 || d.Confidence != 70 || d.RiskUSD != 75 {
+		t.Errorf("参数应取平均值（杠杆向下取整）: %+v", d)
+	}
+	if d.Reasoning != "[a] trend | [b] breakout" {
+		t.Errorf("应合并各模型的理由: %s", d.Reasoning)
+	}
+}
+
+func TestEnsembleMinConfidenceAndConflicts(t *testing.T) {
+	ensemble := newTestEnsemble(EnsembleConfig{MinConfidence: 75}, ensembleResponse(ensembleOpenBTC), ensembleResponse(ensembleOpenBTC3))
+	decision, err := getEnsembleDecision(ensemble, "system", "user", 1000, 5, 5)
+	if err != nil || len(decision.Decisions) != 0 {
+		t.Fatalf("平均信心度不足时不应开仓: %+v %v", decision.Decisions, err)
+	}
+	if vote := findVote(t, decision.Ensemble, "BTCUSDT", "open_long"); vote.Accepted || !strings.Contains(vote.Reason, "信心度") {
+		t.Errorf("应记录信心度不足: %+v", vote)
+	}
+
+	// any规则下多空同时通过时都不执行
+	ensemble = newTestEnsemble(EnsembleConfig{OpenRule: EnsembleRuleAny}, ensembleResponse(ensembleOpenBTC), ensembleResponse(ensembleShortBTC))
+	decision, err = getEnsembleDecision(ensemble, "system", "user", 1000, 5, 5)
+	if err != nil || len(decision.Decisions) != 0 {
+		t.Fatalf("多空冲突时不应开仓: %+v %v", decision.Decisions, err)
+	}
+	if vote := findVote(t, decision.Ensemble, "BTCUSDT", "open_short"); vote.Accepted || vote.Reason != "多空方向冲突" {
+		t.Errorf("应记录方向冲突: %+v", vote)
+	}
+}
+
+func TestEnsembleRequiresMinVoters(t *testing.T) {
+	// 第二个模型调用失败，有效模型不足2个
+	ensemble := newTestEnsemble(EnsembleConfig{}, ensembleResponse(ensembleOpenBTC), "")
+	decision, err := getEnsembleDecision(ensemble, "system", "user", 1000, 5, 5)
+	if err == nil || !strings.Contains(err.Error(), "有效模型不足") {
+		t.Fatalf("有效模型不足时应返回错误: %v", err)
+	}
+	if len(decision.Decisions) != 0 || decision.Ensemble.Members[1].Error == "" {
+		t.Errorf("应记录失败的模型且不执行决策: %+v", decision)
+	}
+}
+
+func TestParseEnsembleConfig(t *testing.T) {
+	if cfg, err := ParseEnsembleConfig(""); cfg != nil || err != nil {
+		t.Errorf("空配置应返回nil: %+v %v", cfg, err)
+	}
+	cfg, err := ParseEnsembleConfig(`{"model_ids":["openai","gemini"],"close_rule":"any","min_confidence":70}`)
+	if err != nil || len(cfg.ModelIDs) != 2 || cfg.openRule() != EnsembleRuleUnanimous || cfg.closeRule() != EnsembleRuleAny || cfg.minVoters() != 2 {
+		t.Errorf("解析结果错误: %+v %v", cfg, err)
+	}
+	for _, raw := range []string{`{"open_rule":"most"}`, `{"min_confidence":101}`, `not json`} {
+		if _, err := ParseEnsembleConfig(raw); err == nil {
+			t.Errorf("无效配置应返回错误: %s", raw)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package decision
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"nofx/mcp"
+)
+
+// maxRepairAttempts AI响应未通过解析或验证时，最多请求AI修复的次数
+const maxRepairAttempts = 2
+
+// 解析结果
+const (
+	ParseOutcomeOK       = "ok"       // 首次响应即通过
+	ParseOutcomeRepaired = "repaired" // 经修复后通过
+	ParseOutcomeFailed   = "failed"   // 修复次数用尽仍未通过
+)
+
+// ParseAttempt 一次AI响应的解析结果（Attempt为0表示首次响应，1..N为修复）
+type ParseAttempt struct {
+	Attempt  int    `json:"attempt"`
+	Response string `json:"response"`
+	Error    string `json:"error,omitempty"` // 解析/验证错误（空表示通过）
+}
+
+// requestDecisions 调用AI并解析决策
+// 支持JSON schema的提供商请求结构化输出；响应未通过解析或验证时，把错误发回AI要求修正，最多maxRepairAttempts次
+// AI调用失败时返回nil；修复请求失败时返回最后一次解析结果和解析错误
+func requestDecisions(client mcp.AIClient, systemPrompt, userPrompt string, accountEquity float64, btcEthLeverage, altcoinLeverage int) (*FullDecision, error) {
+	structuredClient, structured := client.(mcp.StructuredClient)
+	structured = structured && structuredClient.SupportsResponseSchema()
+	var usage []mcp.CallUsage // 每次成功调用的token用量（含修复请求）
+	call := func(prompt string) (string, error) {
+		var response string
+		var err error
+		if structured {
+			response, err = structuredClient.CallWithSchema(systemPrompt, prompt, DecisionResponseSchema())
+		} else {
+			response, err = client.CallWithMessages(systemPrompt, prompt)
+		}
+		if reporter, ok := client.(mcp.UsageReporter); ok && err == nil {
+			usage = append(usage, reporter.LastCallUsage())
+		}
+		return response, err
+	}
+
+	response, err := call(userPrompt)
+	if err != nil {
+		return nil, err
+	}
+	decision, parseErr := parseFullDecisionResponse(response, accountEquity, btcEthLeverage, altcoinLeverage)
+	decision.RawResponse = response // 保存原始响应（即使解析失败也保存，便于回放）
+	decision.StructuredOutput = structured
+	decision.ParseOutcome = ParseOutcomeOK
+	decision.Usage = usage
+	if parseErr == nil {
+		return decision, nil
+	}
+
+	attempts := []ParseAttempt{{Attempt: 0, Response: response, Error: parseErr.Error()}}
+	for attempt := 1; attempt <= maxRepairAttempts; attempt++ {
+		log.Printf("🔧 AI响应未通过校验，请求修复 (%d/%d): %v", attempt, maxRepairAttempts, parseErr)
+		repaired, err := call(buildRepairPrompt(userPrompt, response, parseErr))
+		if err != nil {
+			log.Printf("⚠️ 修复请求失败: %v", err)
+			break
+		}
+		response = repaired
+		next, nextErr := parseFullDecisionResponse(response, accountEquity, btcEthLeverage, altcoinLeverage)
+		next.RawResponse = response
+		next.StructuredOutput = structured
+		decision, parseErr = next, nextErr
+
+		record := ParseAttempt{Attempt: attempt, Response: response}
+		if parseErr != nil {
+			record.Error = parseErr.Error()
+		}
+		attempts = append(attempts, record)
+		if parseErr == nil {
+			break
+		}
+	}
+
+	decision.ParseAttempts = attempts
+	decision.Usage = usage
+	if parseErr != nil {
+		decision.ParseOutcome = ParseOutcomeFailed
+		return decision, fmt.Errorf("修复%d次后仍未通过: %w", len(attempts)-1, parseErr)
+	}
+	decision.ParseOutcome = ParseOutcomeRepaired
+	log.Printf("✅ AI响应修复成功 (第%d次)", len(attempts)-1)
+	return decision, nil
+}
+
+// buildRepairPrompt 修复请求：原始输入 + 上一次的输出 + 校验错误
+func buildRepairPrompt(userPrompt, response string, parseErr error) string {
+	var sb strings.Builder
+	sb.WriteString(userPrompt)
+	sb.WriteString("\n\n---\n\n# ⚠️ 你上一次的输出未通过校验\n\n")
+	sb.WriteString("## 上一次的输出\n\n")
+	sb.WriteString(response)
+	sb.WriteString("\n\n## 校验错误\n\n")
+	sb.WriteString(parseErr.Error())
+	sb.WriteString("\n\n请根据校验错误修正后重新输出完整的回答：先给出简要的思维链分析，再输出完整的JSON决策数组（包含所有决策，而不仅是出错的那一条）。\n")
+	return sb.String()
+}
@@ -0,0 +1,122 @@
+package decision
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"nofx/mcp"
+)
+
+// scriptedClient 依次返回预设响应，并记录收到的prompt
+type scriptedClient struct {
+	responses  []string
+	prompts    []string
+	schemas    []*mcp.ResponseSchema
+	structured bool
+}
+
+func (c *scriptedClient) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	return c.next(userPrompt, nil)
+}
+
+func (c *scriptedClient) SupportsResponseSchema() bool {
+	return c.structured
+}
+
+func (c *scriptedClient) CallWithSchema(systemPrompt, userPrompt string, schema *mcp.ResponseSchema) (string, error) {
+	return c.next(userPrompt, schema)
+}
+
+// LastCallUsage 每次调用输入100个token、输出10个token
+func (c *scriptedClient) LastCallUsage() mcp.CallUsage {
+	return mcp.CallUsage{Provider: "test", Model: "scripted", Usage: mcp.Usage{PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110}}
+}
+
+func (c *scriptedClient) next(userPrompt string, schema *mcp.ResponseSchema) (string, error) {
+	c.prompts = append(c.prompts, userPrompt)
+	c.schemas = append(c.schemas, schema)
+	if len(c.prompts) > len(c.responses) {
+		return "", errors.New("没有更多响应")
+	}
+	return c.responses[len(c.prompts)-1], nil
+}
+
+func TestRequestDecisionsRepairsInvalidResponse(t *testing.T) {
+	client := &scriptedClient{responses: []string{
+		`分析: 趋势向上 [{"symbol":"BTCUSDT","action":"open_long","leverage":"5"}]`,                         // JSON类型错误
+		`分析: 趋势向上 [{"symbol":"BTCUSDT","action":"open_long","leverage":50,"position_size_usd":2000}]`, // 杠杆超限
+		replayOpenLong,
+	}}
+
+	decision, err := requestDecisions(client, "system", "user prompt", 1000, 5, 5)
+	if err != nil {
+		t.Fatalf("修复后应通过: %v", err)
+	}
+	if decision.ParseOutcome != ParseOutcomeRepaired || len(decision.ParseAttempts) != 3 {
+		t.Fatalf("应记录3次解析: %s %+v", decision.ParseOutcome, decision.ParseAttempts)
+	}
+	if decision.ParseAttempts[0].Error == "" || decision.ParseAttempts[1].Error == "" || decision.ParseAttempts[2].Error != "" {
+		t.Errorf("前两次应失败、最后一次通过: %+v", decision.ParseAttempts)
+	}
+	if decision.RawResponse != replayOpenLong || len(decision.Decisions) != 1 || decision.CoTTrace != "分析: 趋势向上" {
+		t.Errorf("应使用修复后的响应: %+v", decision)
+	}
+
+	// 修复请求包含原始输入、上一次输出和校验错误
+	repairPrompt := client.prompts[2]
+	if !strings.HasPrefix(repairPrompt, "user prompt") || !strings.Contains(repairPrompt, `"leverage":50`) || !strings.Contains(repairPrompt, "杠杆必须在1-5之间") {
+		t.Errorf("修复请求内容错误: %s", repairPrompt)
+	}
+	if client.schemas[0] != nil {
+		t.Error("不支持结构化输出的提供商不应使用schema")
+	}
+	if len(decision.Usage) != 3 || decision.Usage[2].PromptTokens != 100 {
+		t.Errorf("应记录每次调用（含修复请求）的token用量: %+v", decision.Usage)
+	}
+}
+
+func TestRequestDecisionsStopsAfterMaxRepairs(t *testing.T) {
+	bad := `没有JSON`
+	client := &scriptedClient{responses: []string{bad, bad, bad, replayOpenLong}}
+
+	decision, err := requestDecisions(client, "system", "user", 1000, 5, 5)
+	if err == nil || !strings.Contains(err.Error(), "修复2次后仍未通过") {
+		t.Fatalf("修复次数用尽应返回错误: %v", err)
+	}
+	if len(client.prompts) != 1+maxRepairAttempts {
+		t.Errorf("修复次数应有上限: %d", len(client.prompts))
+	}
+	if decision.ParseOutcome != ParseOutcomeFailed || len(decision.ParseAttempts) != 1+maxRepairAttempts {
+		t.Errorf("应记录失败结果: %s %d", decision.ParseOutcome, len(decision.ParseAttempts))
+	}
+	if len(decision.Usage) != 1+maxRepairAttempts {
+		t.Errorf("解析失败时同样应记录token用量: %d", len(decision.Usage))
+	}
+
+	// AI调用失败（非解析错误）不进入修复
+	if decision, err := requestDecisions(&scriptedClient{}, "system", "user", 1000, 5, 5); decision != nil || err == nil {
+		t.Errorf("AI调用失败应返回nil: %+v %v", decision, err)
+	}
+}
+
+func TestRequestDecisionsStructuredOutput(t *testing.T) {
+	client := &scriptedClient{structured: true, responses: []string{
+		`{"analysis":"趋势向上","decisions":[{"symbol":"BTCUSDT","action":"open_long","leverage":5,"position_size_usd":2000,"size_percent":null,"stop_loss":90000,"take_profit":110000,"confidence":80,"risk_usd":100,"limit_price":null,"reasoning":"trend"}]}`,
+	}}
+
+	decision, err := requestDecisions(client, "system", "user", 1000, 5, 5)
+	if err != nil {
+		t.Fatalf("结构化输出应解析成功: %v", err)
+	}
+	if client.schemas[0] == nil || client.schemas[0].Name != decisionSchemaName || !client.schemas[0].Strict || !json.Valid(client.schemas[0].Schema) {
+		t.Errorf("应请求结构化输出: %+v", client.schemas[0])
+	}
+	if !decision.StructuredOutput || decision.ParseOutcome != ParseOutcomeOK || len(decision.ParseAttempts) != 0 {
+		t.Errorf("解析记录错误: %+v", decision)
+	}
+	if decision.CoTTrace != "趋势向上" || len(decision.Decisions) != 1 || decision.Decisions[0] != replayOriginalOpenLong() {
+		t.Errorf("应从analysis和decisions字段解析: %+v", decision)
+	}
+}
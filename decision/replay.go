@@ -0,0 +1,192 @@
+package decision
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 回放差异类型
+const (
+	ReplayChangeRejected = "rejected" // 当时通过验证，现在会被拒绝
+	ReplayChangeAccepted = "accepted" // 当时被拒绝，现在会通过验证
+	ReplayChangeResized  = "resized"  // 仓位大小或杠杆不同
+	ReplayChangeChanged  = "changed"  // 止损/止盈/信心度等其他字段不同
+	ReplayChangeAdded    = "added"    // 现在解析出了当时没有的决策
+	ReplayChangeRemoved  = "removed"  // 当时的决策现在解析不出来
+)
+
+// ReplayInput 一次需要回放的AI响应
+type ReplayInput struct {
+	Response        string     // AI响应（原始响应，或由思维链+决策JSON重建）
+	AccountEquity   float64    // 当时的账户净值（验证仓位上限使用）
+	BTCETHLeverage  int        // 当时的BTC/ETH杠杆上限
+	AltcoinLeverage int        // 当时的山寨币杠杆上限
+	Original        []Decision // 当时解析出的决策
+	OriginalError   string     // 当时的解析/验证错误（为空表示通过）
+}
+
+// ReplayChange 单个决策的回放差异
+type ReplayChange struct {
+	Index  int    `json:"index"` // 在当前解析结果中的序号（从1开始，removed时为当时的序号）
+	Symbol string `json:"symbol"`
+	Action string `json:"action"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+}
+
+// ReplayResult 使用当前代码重新解析和验证的结果
+type ReplayResult struct {
+	OriginalValid bool           `json:"original_valid"`
+	OriginalError string         `json:"original_error,omitempty"`
+	CurrentValid  bool           `json:"current_valid"`
+	CurrentError  string         `json:"current_error,omitempty"`
+	Current       []Decision     `json:"current"`
+	Changes       []ReplayChange `json:"changes"`
+}
+
+// Changed 是否与当时的结果有差异
+func (r *ReplayResult) Changed() bool {
+	return r.OriginalValid != r.CurrentValid || len(r.Changes) > 0
+}
+
+var (
+	// 匹配验证错误中的决策序号，如 "决策 #2 验证失败"
+	replayRejectedIndexRe = regexp.MustCompile(`决策 #(\d+) 验证失败`)
+	// 匹配系统提示词硬约束中的杠杆配置
+	replayLeverageRe = regexp.MustCompile(`山寨[\d.]+-[\d.]+ U\((\d+)x杠杆\) \| BTC/ETH [\d.]+-[\d.]+ U\((\d+)x杠杆\)`)
+)
+
+// ParseLeverageFromSystemPrompt 从系统提示词的硬约束中提取当时的杠杆配置
+func ParseLeverageFromSystemPrompt(systemPrompt string) (btcEthLeverage, altcoinLeverage int, ok bool) {
+	match := replayLeverageRe.FindStringSubmatch(systemPrompt)
+	if match == nil {
+		return 0, 0, false
+	}
+	altcoinLeverage, _ = strconv.Atoi(match[1])
+	btcEthLeverage, _ = strconv.Atoi(match[2])
+	return btcEthLeverage, altcoinLeverage, btcEthLeverage > 0 && altcoinLeverage > 0
+}
+
+// ReplayDecisions 用当前的解析和验证逻辑重新处理一条AI响应，并与当时的结果对比
+// 与parseFullDecisionResponse一致：任何一个决策验证失败，整批决策都不会执行
+func ReplayDecisions(input ReplayInput) *ReplayResult {
+	result := &ReplayResult{
+		OriginalValid: input.OriginalError == "",
+		OriginalError: input.OriginalError,
+		Changes:       []ReplayChange{},
+	}
+
+	// 当时验证失败的决策序号（只有第一个失败的决策会出现在错误中）
+	originalRejected := 0
+	if match := replayRejectedIndexRe.FindStringSubmatch(input.OriginalError); match != nil {
+		originalRejected, _ = strconv.Atoi(match[1])
+	}
+
+	current, err := extractDecisions(input.Response)
+	if err != nil {
+		result.CurrentError = fmt.Sprintf("提取决策失败: %v", err)
+		for i, d := range input.Original {
+			result.Changes = append(result.Changes, ReplayChange{
+				Index: i + 1, Symbol: d.Symbol, Action: d.Action, Kind: ReplayChangeRemoved,
+				Detail: "当前代码无法提取决策",
+			})
+		}
+		return result
+	}
+	result.Current = current
+	result.CurrentValid = true
+
+	matched := make([]bool, len(input.Original))
+	for i := range current {
+		d := &current[i]
+
+		// 逐个验证（validateDecisions在第一个失败时停止，这里需要知道每个决策的结果）
+		validateErr := validateDecision(d, input.AccountEquity, input.BTCETHLeverage, input.AltcoinLeverage)
+		if validateErr != nil && result.CurrentValid {
+			result.CurrentValid = false
+			result.CurrentError = fmt.Sprintf("决策验证失败: 决策 #%d 验证失败: %v", i+1, validateErr)
+		}
+
+		// 按 symbol+action 匹配当时的决策
+		origIndex := -1
+		for j, orig := range input.Original {
+			if !matched[j] && orig.Symbol == d.Symbol && orig.Action == d.Action {
+				origIndex = j
+				matched[j] = true
+				break
+			}
+		}
+		if origIndex < 0 {
+			result.Changes = append(result.Changes, ReplayChange{
+				Index: i + 1, Symbol: d.Symbol, Action: d.Action, Kind: ReplayChangeAdded,
+				Detail: "当时没有解析出该决策",
+			})
+			continue
+		}
+		orig := input.Original[origIndex]
+
+		wasRejected := originalRejected == origIndex+1
+		switch {
+		case validateErr != nil && !wasRejected:
+			result.Changes = append(result.Changes, ReplayChange{
+				Index: i + 1, Symbol: d.Symbol, Action: d.Action, Kind: ReplayChangeRejected,
+				Detail: validateErr.Error(),
+			})
+		case validateErr == nil && wasRejected:
+			result.Changes = append(result.Changes, ReplayChange{
+				Index: i + 1, Symbol: d.Symbol, Action: d.Action, Kind: ReplayChangeAccepted,
+				Detail: input.OriginalError,
+			})
+		}
+
+		if orig.PositionSizeUSD != d.PositionSizeUSD || orig.Leverage != d.Leverage {
+			result.Changes = append(result.Changes, ReplayChange{
+				Index: i + 1, Symbol: d.Symbol, Action: d.Action, Kind: ReplayChangeResized,
+				Detail: fmt.Sprintf("仓位 %.2f → %.2f USD, 杠杆 %dx → %dx", orig.PositionSizeUSD, d.PositionSizeUSD, orig.Leverage, d.Leverage),
+			})
+		}
+		if diff := diffDecisionFields(orig, *d); diff != "" {
+			result.Changes = append(result.Changes, ReplayChange{
+				Index: i + 1, Symbol: d.Symbol, Action: d.Action, Kind: ReplayChangeChanged,
+				Detail: diff,
+			})
+		}
+	}
+
+	for j, orig := range input.Original {
+		if !matched[j] {
+			result.Changes = append(result.Changes, ReplayChange{
+				Index: j + 1, Symbol: orig.Symbol, Action: orig.Action, Kind: ReplayChangeRemoved,
+				Detail: "当前代码没有解析出该决策",
+			})
+		}
+	}
+
+	return result
+}
+
+// diffDecisionFields 对比仓位以外的决策字段
+func diffDecisionFields(orig, current Decision) string {
+	var diffs []string
+	if orig.StopLoss != current.StopLoss {
+		diffs = append(diffs, fmt.Sprintf("止损 %.4f → %.4f", orig.StopLoss, current.StopLoss))
+	}
+	if orig.TakeProfit != current.TakeProfit {
+		diffs = append(diffs, fmt.Sprintf("止盈 %.4f → %.4f", orig.TakeProfit, current.TakeProfit))
+	}
+	if orig.Confidence != current.Confidence {
+		diffs = append(diffs, fmt.Sprintf("信心度 %d → %d", orig.Confidence, current.Confidence))
+	}
+	if orig.LimitPrice != current.LimitPrice {
+		diffs = append(diffs, fmt.Sprintf("限价 %.4f → %.4f", orig.LimitPrice, current.LimitPrice))
+	}
+	if orig.RiskUSD != current.RiskUSD {
+		diffs = append(diffs, fmt.Sprintf("风险 %.2f → %.2f USD", orig.RiskUSD, current.RiskUSD))
+	}
+	if orig.Reasoning != current.Reasoning {
+		diffs = append(diffs, "理由文本不同")
+	}
+	return strings.Join(diffs, ", ")
+}
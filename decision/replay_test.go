@@ -0,0 +1,173 @@
+package decision
+
+import (
+	"testing"
+)
+
+const replayOpenLong = `分析: 趋势向上
+
+[{"symbol":"BTCUSDT","action":"open_long","leverage":5,"position_size_usd":2000,"stop_loss":90000,"take_profit":110000,"confidence":80,"risk_usd":100,"reasoning":"trend"}]`
+
+func replayOriginalOpenLong() Decision {
+	return Decision{
+		Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 2000,
+		StopLoss: 90000, TakeProfit: 110000, Confidence: 80, RiskUSD: 100, Reasoning: "trend",
+	}
+}
+
+func countReplayChanges(result *ReplayResult, kind string) int {
+	count := 0
+	for _, change := range result.Changes {
+		if change.Kind == kind {
+			count++
+		}
+	}
+	return count
+}
+
+func TestReplayDecisionsUnchanged(t *testing.T) {
+	result := ReplayDecisions(ReplayInput{
+		Response:        replayOpenLong,
+		AccountEquity:   1000,
+		BTCETHLeverage:  5,
+		AltcoinLeverage: 5,
+		Original:        []Decision{replayOriginalOpenLong()},
+	})
+	if result.Changed() {
+		t.Fatalf("相同输入不应有差异: %+v", result)
+	}
+	if !result.CurrentValid || len(result.Current) != 1 {
+		t.Errorf("应解析出1个有效决策: %+v", result)
+	}
+}
+
+func TestReplayDecisionsDetectsNewRejectionAndAcceptance(t *testing.T) {
+	// 当时通过验证，杠杆上限降为3倍后应被拒绝
+	rejected := ReplayDecisions(ReplayInput{
+		Response:        replayOpenLong,
+		AccountEquity:   1000,
+		BTCETHLeverage:  3,
+		AltcoinLeverage: 3,
+		Original:        []Decision{replayOriginalOpenLong()},
+	})
+	if rejected.CurrentValid || countReplayChanges(rejected, ReplayChangeRejected) != 1 {
+		t.Errorf("应报告新拒绝: %+v", rejected)
+	}
+
+	// 当时第1个决策被拒绝，现在通过
+	accepted := ReplayDecisions(ReplayInput{
+		Response:        replayOpenLong,
+		AccountEquity:   1000,
+		BTCETHLeverage:  5,
+		AltcoinLeverage: 5,
+		Original:        []Decision{replayOriginalOpenLong()},
+		OriginalError:   "获取AI决策失败: 解析AI响应失败: 决策验证失败: 决策 #1 验证失败: 风险回报比过低",
+	})
+	if !accepted.CurrentValid || countReplayChanges(accepted, ReplayChangeAccepted) != 1 {
+		t.Errorf("应报告新通过: %+v", accepted)
+	}
+}
+
+func TestReplayDecisionsDetectsParsingDifferences(t *testing.T) {
+	original := replayOriginalOpenLong()
+	original.PositionSizeUSD = 1500
+	original.StopLoss = 91000
+
+	result := ReplayDecisions(ReplayInput{
+		Response:        replayOpenLong,
+		AccountEquity:   1000,
+		BTCETHLeverage:  5,
+		AltcoinLeverage: 5,
+		Original: []Decision{
+			original,
+			{Symbol: "ETHUSDT", Action: "close_long", Reasoning: "exit"},
+		},
+	})
+	if countReplayChanges(result, ReplayChangeResized) != 1 {
+		t.Errorf("应报告仓位变化: %+v", result.Changes)
+	}
+	if countReplayChanges(result, ReplayChangeChanged) != 1 {
+		t.Errorf("应报告止损变化: %+v", result.Changes)
+	}
+	if countReplayChanges(result, ReplayChangeRemoved) != 1 {
+		t.Errorf("应报告缺失的ETH决策: %+v", result.Changes)
+	}
+
+	broken := ReplayDecisions(ReplayInput{
+		Response: "没有JSON",
+		Original: []Decision{replayOriginalOpenLong()},
+	})
+	if broken.CurrentValid || broken.CurrentError == "" || countReplayChanges(broken, ReplayChangeRemoved) != 1 {
+		t.Errorf("无法提取时应报告缺失: %+v", broken)
+	}
+}
+
+func TestParseLeverageFromSystemPrompt(t *testing.T) {
+	prompt := buildSystemPrompt(1000, 10, 3, "default")
+	btcEth, altcoin, ok := ParseLeverageFromSystemPrompt(prompt)
+	if !ok || btcEth != 10 || altcoin != 3 {
+		t.Errorf("期望 BTC/ETH 10x 山寨 3x, got %d/%d (%v)", btcEth, altcoin, ok)
+	}
+	if _, _, ok := ParseLeverageFromSystemPrompt("自定义prompt"); ok {
+		t.Error("没有硬约束时不应识别出杠杆")
+	}
+}
+
+func TestValidateDecisionLimitPrice(t *testing.T) {
+	valid := replayOriginalOpenLong()
+	valid.LimitPrice = 95000
+	if err := validateDecision(&valid, 1000, 5, 5); err != nil {
+		t.Fatalf("止损止盈之间的限价应通过: %v", err)
+	}
+
+	outside := replayOriginalOpenLong()
+	outside.LimitPrice = 89000
+	if err := validateDecision(&outside, 1000, 5, 5); err == nil {
+		t.Error("限价低于止损的多单应被拒绝")
+	}
+
+	closing := Decision{Symbol: "BTCUSDT", Action: "close_long", LimitPrice: 95000}
+	if err := validateDecision(&closing, 1000, 5, 5); err == nil {
+		t.Error("平仓决策不应带限价")
+	}
+
+	result := ReplayDecisions(ReplayInput{
+		Response:        replayOpenLong,
+		AccountEquity:   1000,
+		BTCETHLeverage:  5,
+		AltcoinLeverage: 5,
+		Original:        []Decision{valid},
+	})
+	if countReplayChanges(result, ReplayChangeChanged) != 1 {
+		t.Errorf("应报告限价变化: %+v", result.Changes)
+	}
+}
+
+func TestValidateAdjustDecision(t *testing.T) {
+	tests := []struct {
+		name     string
+		decision Decision
+		wantErr  bool
+	}{
+		{"按比例减仓", Decision{Symbol: "SOLUSDT", Action: "reduce_long", SizePercent: 50}, false},
+		{"按金额减仓", Decision{Symbol: "SOLUSDT", Action: "reduce_short", PositionSizeUSD: 200}, false},
+		{"减仓超过100%", Decision{Symbol: "SOLUSDT", Action: "reduce_long", SizePercent: 120}, true},
+		{"未指定数量", Decision{Symbol: "SOLUSDT", Action: "reduce_long"}, true},
+		{"同时指定比例和金额", Decision{Symbol: "SOLUSDT", Action: "add_long", SizePercent: 50, PositionSizeUSD: 200}, true},
+		{"加仓沿用杠杆", Decision{Symbol: "BTCUSDT", Action: "add_long", PositionSizeUSD: 2000}, false},
+		{"加仓带新止损止盈", Decision{Symbol: "BTCUSDT", Action: "add_short", SizePercent: 30, StopLoss: 105000, TakeProfit: 90000}, false},
+		{"加仓止损方向错误", Decision{Symbol: "BTCUSDT", Action: "add_long", SizePercent: 30, StopLoss: 105000, TakeProfit: 90000}, true},
+		{"加仓超过上限", Decision{Symbol: "SOLUSDT", Action: "add_long", PositionSizeUSD: 5000}, true},
+		{"加仓杠杆超限", Decision{Symbol: "SOLUSDT", Action: "add_long", SizePercent: 20, Leverage: 20}, true},
+		{"减仓不能带限价", Decision{Symbol: "SOLUSDT", Action: "reduce_long", SizePercent: 50, LimitPrice: 100}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDecision(&tt.decision, 1000, 5, 5)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("wantErr=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package decision
+
+import (
+	"encoding/json"
+	"strings"
+
+	"nofx/mcp"
+)
+
+// decisionSchemaName 结构化输出schema名称
+const decisionSchemaName = "trading_decisions"
+
+// decisionResponseSchema AI决策输出的JSON schema
+// 结构化输出要求根节点为object，因此把[]Decision包装在decisions字段中，思维链放在analysis字段
+// strict模式下所有字段都必须列入required，可选字段用null表示未填写
+var decisionResponseSchema = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "analysis": {"type": "string", "description": "思维链分析"},
+    "decisions": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "symbol": {"type": "string"},
+          "action": {"type": "string", "enum": ["open_long", "open_short", "close_long", "close_short", "reduce_long", "reduce_short", "add_long", "add_short", "hold", "wait"]},
+          "leverage": {"type": ["integer", "null"]},
+          "position_size_usd": {"type": ["number", "null"]},
+          "size_percent": {"type": ["number", "null"], "description": "减仓/加仓数量占当前持仓的百分比，与position_size_usd二选一"},
+          "stop_loss": {"type": ["number", "null"]},
+          "take_profit": {"type": ["number", "null"]},
+          "confidence": {"type": ["integer", "null"], "description": "信心度 0-100"},
+          "risk_usd": {"type": ["number", "null"]},
+          "limit_price": {"type": ["number", "null"], "description": "限价入场价，null表示市价开仓"},
+          "reasoning": {"type": "string"}
+        },
+        "required": ["symbol", "action", "leverage", "position_size_usd", "size_percent", "stop_loss", "take_profit", "confidence", "risk_usd", "limit_price", "reasoning"],
+        "additionalProperties": false
+      }
+    }
+  },
+  "required": ["analysis", "decisions"],
+  "additionalProperties": false
+}`)
+
+// DecisionResponseSchema 决策输出的结构化schema（供支持JSON schema的提供商使用）
+func DecisionResponseSchema() *mcp.ResponseSchema {
+	return &mcp.ResponseSchema{Name: decisionSchemaName, Schema: decisionResponseSchema, Strict: true}
+}
+
+// structuredResponse 结构化输出的响应
+type structuredResponse struct {
+	Analysis  string     `json:"analysis"`
+	Decisions []Decision `json:"decisions"`
+}
+
+// parseStructuredResponse 解析结构化输出（{"analysis": "...", "decisions": [...]}），不是该格式时返回false
+func parseStructuredResponse(response string) (*structuredResponse, bool) {
+	trimmed := strings.TrimSpace(response)
+	if strings.HasPrefix(trimmed, "```") {
+		// 去掉markdown代码块
+		trimmed = strings.TrimPrefix(strings.TrimPrefix(trimmed, "```json"), "```")
+		trimmed = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(trimmed), "```"))
+	}
+	if !strings.HasPrefix(trimmed, "{") {
+		return nil, false
+	}
+
+	var raw struct {
+		Analysis  string          `json:"analysis"`
+		Decisions json.RawMessage `json:"decisions"`
+	}
+	if err := json.Unmarshal([]byte(trimmed), &raw); err != nil || len(raw.Decisions) == 0 {
+		return nil, false
+	}
+	result := &structuredResponse{Analysis: raw.Analysis}
+	if err := json.Unmarshal(raw.Decisions, &result.Decisions); err != nil {
+		return nil, false
+	}
+	return result, true
+}
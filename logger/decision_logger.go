@@ -7,24 +7,68 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
 // DecisionRecord 决策记录
 type DecisionRecord struct {
-	Timestamp      time.Time          `json:"timestamp"`       // 决策时间
-	CycleNumber    int                `json:"cycle_number"`    // 周期编号
-	SystemPrompt   string             `json:"system_prompt"`   // 系统提示词（发送给AI的系统prompt）
-	InputPrompt    string             `json:"input_prompt"`    // 发送给AI的输入prompt
-	CoTTrace       string             `json:"cot_trace"`       // AI思维链（输出）
-	DecisionJSON   string             `json:"decision_json"`   // 决策JSON
-	AccountState   AccountSnapshot    `json:"account_state"`   // 账户状态快照
-	Positions      []PositionSnapshot `json:"positions"`       // 持仓快照
-	CandidateCoins []string           `json:"candidate_coins"` // 候选币种列表
-	Decisions      []DecisionAction   `json:"decisions"`       // 执行的决策
-	ExecutionLog   []string           `json:"execution_log"`   // 执行日志
-	Success        bool               `json:"success"`         // 是否成功
-	ErrorMessage   string             `json:"error_message"`   // 错误信息（如果有）
+	Timestamp        time.Time          `json:"timestamp"`                   // 决策时间
+	CycleNumber      int                `json:"cycle_number"`                // 周期编号
+	SystemPrompt     string             `json:"system_prompt"`               // 系统提示词（发送给AI的系统prompt）
+	InputPrompt      string             `json:"input_prompt"`                // 发送给AI的输入prompt
+	CoTTrace         string             `json:"cot_trace"`                   // AI思维链（输出）
+	DecisionJSON     string             `json:"decision_json"`               // 决策JSON
+	RawResponse      string             `json:"raw_response,omitempty"`      // AI原始响应（用于离线回放）
+	StructuredOutput bool               `json:"structured_output,omitempty"` // 是否使用JSON schema结构化输出
+	ParseOutcome     string             `json:"parse_outcome,omitempty"`     // AI响应解析结果: ok / repaired / failed
+	ParseAttempts    []ParseAttempt     `json:"parse_attempts,omitempty"`    // 首次解析失败时的各次响应和错误（按顺序）
+	AIProvider       string             `json:"ai_provider,omitempty"`       // 给出决策的AI提供商（故障转移时为实际使用的备用模型）
+	Ensemble         *EnsembleRecord    `json:"ensemble,omitempty"`          // 集成决策时各模型的输出和投票结果
+	PromptTokens     int64              `json:"prompt_tokens,omitempty"`     // 本周期AI调用的输入token数（含修复请求和集成决策各模型）
+	CompletionTokens int64              `json:"completion_tokens,omitempty"` // 本周期AI调用的输出token数
+	AICostUSD        float64            `json:"ai_cost_usd,omitempty"`       // 本周期AI费用（美元）
+	AccountState     AccountSnapshot    `json:"account_state"`               // 账户状态快照
+	Positions        []PositionSnapshot `json:"positions"`                   // 持仓快照
+	CandidateCoins   []string           `json:"candidate_coins"`             // 候选币种列表
+	Decisions        []DecisionAction   `json:"decisions"`                   // 执行的决策
+	ExecutionLog     []string           `json:"execution_log"`               // 执行日志
+	Success          bool               `json:"success"`                     // 是否成功
+	ErrorMessage     string             `json:"error_message"`               // 错误信息（如果有）
+}
+
+// ParseAttempt AI响应的一次解析（Attempt为0表示首次响应，1..N为修复）
+type ParseAttempt struct {
+	Attempt  int    `json:"attempt"`
+	Response string `json:"response"`
+	Error    string `json:"error,omitempty"` // 解析/验证错误（空表示通过）
+}
+
+// EnsembleRecord 集成决策记录
+type EnsembleRecord struct {
+	Members []EnsembleMemberRecord `json:"members"` // 各模型的原始输出
+	Votes   []EnsembleVoteRecord   `json:"votes"`   // 各（币种, 操作）的投票结果
+}
+
+// EnsembleMemberRecord 单个模型的输出
+type EnsembleMemberRecord struct {
+	Model        string `json:"model"`
+	RawResponse  string `json:"raw_response,omitempty"`
+	DecisionJSON string `json:"decision_json,omitempty"`
+	ParseOutcome string `json:"parse_outcome,omitempty"`
+	Error        string `json:"error,omitempty"` // 调用或解析失败（不参与投票）
+}
+
+// EnsembleVoteRecord 投票结果
+type EnsembleVoteRecord struct {
+	Symbol   string   `json:"symbol"`
+	Action   string   `json:"action"`
+	Voters   []string `json:"voters"`   // 提出该决策的模型
+	Required int      `json:"required"` // 按规则需要的票数
+	Accepted bool     `json:"accepted"`
+	Reason   string   `json:"reason,omitempty"` // 未通过原因
 }
 
 // AccountSnapshot 账户状态快照
@@ -50,21 +94,62 @@ type PositionSnapshot struct {
 
 // DecisionAction 决策动作
 type DecisionAction struct {
-	Action    string    `json:"action"`    // open_long, open_short, close_long, close_short
+	Action    string    `json:"action"`    // open_long, open_short, close_long, close_short, reduce_*, add_*
 	Symbol    string    `json:"symbol"`    // 币种
 	Quantity  float64   `json:"quantity"`  // 数量
 	Leverage  int       `json:"leverage"`  // 杠杆（开仓时）
-	Price     float64   `json:"price"`     // 执行价格
-	OrderID   int64     `json:"order_id"`  // 订单ID
+	Price     float64   `json:"price"`     // 执行价格（成交均价，无法获取时为下单时的市场价）
+	Fee       float64   `json:"fee"`       // 支付的手续费
+	OrderID   string    `json:"order_id"`  // 交易所订单ID
 	Timestamp time.Time `json:"timestamp"` // 执行时间
 	Success   bool      `json:"success"`   // 是否成功
 	Error     string    `json:"error"`     // 错误信息
+
+	SizeUSD    float64 `json:"size_usd,omitempty"`    // 开仓名义价值（经保证金和组合风险调整后）
+	SizingNote string  `json:"sizing_note,omitempty"` // 仓位计算说明（波动率模式）
+
+	LiquidationPrice       float64 `json:"liquidation_price,omitempty"`        // 开仓前估算的强平价
+	LiquidationDistancePct float64 `json:"liquidation_distance_pct,omitempty"` // 强平价距入场价的百分比
+	LiquidationNote        string  `json:"liquidation_note,omitempty"`         // 强平距离检查说明（含降杠杆原因）
+
+	Funding     float64 `json:"funding,omitempty"`      // 平仓/减仓部分开仓以来已结算的资金费（正数为收到，负数为支付）
+	FundingNote string  `json:"funding_note,omitempty"` // 开仓前的资金费成本估算
+
+	Models []string `json:"models,omitempty"` // 集成决策时提出该决策的模型
+}
+
+// UnmarshalJSON 兼容旧日志中数字格式的订单ID
+func (a *DecisionAction) UnmarshalJSON(data []byte) error {
+	type decisionActionAlias DecisionAction
+	aux := struct {
+		*decisionActionAlias
+		OrderID json.RawMessage `json:"order_id"`
+	}{decisionActionAlias: (*decisionActionAlias)(a)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	a.OrderID = ""
+	if len(aux.OrderID) == 0 || string(aux.OrderID) == "null" {
+		return nil
+	}
+	if aux.OrderID[0] == '"' {
+		return json.Unmarshal(aux.OrderID, &a.OrderID)
+	}
+	// 旧格式：int64订单ID，0表示没有订单ID
+	if id := string(aux.OrderID); id != "0" {
+		a.OrderID = id
+	}
+	return nil
 }
 
 // DecisionLogger 决策日志记录器
 type DecisionLogger struct {
 	logDir      string
 	cycleNumber int
+	clock       func() time.Time // 时钟（回测时使用模拟时间，默认time.Now）
+	mu          sync.Mutex       // 保护cycleNumber（AI周期与实时风控可能同时写入记录）
 }
 
 // NewDecisionLogger 创建决策日志记录器
@@ -81,14 +166,29 @@ func NewDecisionLogger(logDir string) *DecisionLogger {
 	return &DecisionLogger{
 		logDir:      logDir,
 		cycleNumber: 0,
+		clock:       time.Now,
+	}
+}
+
+// SetClock 设置记录时间使用的时钟（回测时使用模拟时间，保证文件名按模拟时间排序）
+func (l *DecisionLogger) SetClock(clock func() time.Time) {
+	if clock != nil {
+		l.clock = clock
 	}
 }
 
+// GetLogDir 获取日志目录
+func (l *DecisionLogger) GetLogDir() string {
+	return l.logDir
+}
+
 // LogDecision 记录决策
 func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
+	l.mu.Lock()
 	l.cycleNumber++
 	record.CycleNumber = l.cycleNumber
-	record.Timestamp = time.Now()
+	l.mu.Unlock()
+	record.Timestamp = l.clock()
 
 	// 生成文件名：decision_YYYYMMDD_HHMMSS_cycleN.json
 	filename := fmt.Sprintf("decision_%s_cycle%d.json",
@@ -151,6 +251,38 @@ func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
 	return records, nil
 }
 
+// LoadDecisionRecords 读取目录下全部决策记录（按决策时间正序），用于离线回放和回测
+// 与NewDecisionLogger不同，不会创建不存在的目录
+func LoadDecisionRecords(logDir string) ([]*DecisionRecord, error) {
+	files, err := ioutil.ReadDir(logDir)
+	if err != nil {
+		return nil, fmt.Errorf("读取日志目录失败: %w", err)
+	}
+
+	records := make([]*DecisionRecord, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(logDir, file.Name()))
+		if err != nil {
+			continue
+		}
+
+		var record DecisionRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			continue
+		}
+		records = append(records, &record)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+	return records, nil
+}
+
 // GetRecordByDate 获取指定日期的所有记录
 func (l *DecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, error) {
 	dateStr := date.Format("20060102")
@@ -241,9 +373,9 @@ func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
 		for _, action := range record.Decisions {
 			if action.Success {
 				switch action.Action {
-				case "open_long", "open_short":
+				case "open_long", "open_short", "add_long", "add_short":
 					stats.TotalOpenPositions++
-				case "close_long", "close_short":
+				case "close_long", "close_short", "reduce_long", "reduce_short":
 					stats.TotalClosePositions++
 				}
 			}
@@ -278,7 +410,8 @@ type TradeOutcome struct {
 	ClosePrice    float64   `json:"close_price"`    // 平仓价
 	PositionValue float64   `json:"position_value"` // 仓位价值（quantity × openPrice）
 	MarginUsed    float64   `json:"margin_used"`    // 保证金使用（positionValue / leverage）
-	PnL           float64   `json:"pn_l"`           // 盈亏（USDT）
+	PnL           float64   `json:"pn_l"`           // 盈亏（USDT，含手续费和资金费）
+	Funding       float64   `json:"funding"`        // 持仓期间已结算的资金费（正数为收到，负数为支付）
 	PnLPct        float64   `json:"pn_l_pct"`       // 盈亏百分比（相对保证金）
 	Duration      string    `json:"duration"`       // 持仓时长
 	OpenTime      time.Time `json:"open_time"`      // 开仓时间
@@ -327,10 +460,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 		}, nil
 	}
 
-	analysis := &PerformanceAnalysis{
-		RecentTrades: []TradeOutcome{},
-		SymbolStats:  make(map[string]*SymbolPerformance),
-	}
+	var outcomes []TradeOutcome
 
 	// 追踪持仓状态：symbol_side -> {side, openPrice, openTime, quantity, leverage}
 	openPositions := make(map[string]map[string]interface{})
@@ -347,12 +477,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 				}
 
 				symbol := action.Symbol
-				side := ""
-				if action.Action == "open_long" || action.Action == "close_long" {
-					side = "long"
-				} else if action.Action == "open_short" || action.Action == "close_short" {
-					side = "short"
-				}
+				side := actionSide(action.Action)
 				posKey := symbol + "_" + side
 
 				switch action.Action {
@@ -364,7 +489,12 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 						"openTime":  action.Timestamp,
 						"quantity":  action.Quantity,
 						"leverage":  action.Leverage,
+						"fee":       action.Fee,
 					}
+				case "add_long", "add_short":
+					addToOpenPosition(openPositions, posKey, side, action)
+				case "reduce_long", "reduce_short":
+					reduceOpenPosition(openPositions, posKey, action.Quantity)
 				case "close_long", "close_short":
 					// 移除已平仓记录
 					delete(openPositions, posKey)
@@ -381,12 +511,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 			}
 
 			symbol := action.Symbol
-			side := ""
-			if action.Action == "open_long" || action.Action == "close_long" {
-				side = "long"
-			} else if action.Action == "open_short" || action.Action == "close_short" {
-				side = "short"
-			}
+			side := actionSide(action.Action)
 			posKey := symbol + "_" + side // 使用symbol_side作为key，区分多空持仓
 
 			switch action.Action {
@@ -398,9 +523,14 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 					"openTime":  action.Timestamp,
 					"quantity":  action.Quantity,
 					"leverage":  action.Leverage,
+					"fee":       action.Fee,
 				}
 
-			case "close_long", "close_short":
+			case "add_long", "add_short":
+				// 加仓：合并为同一笔交易，开仓价按数量加权
+				addToOpenPosition(openPositions, posKey, side, action)
+
+			case "close_long", "close_short", "reduce_long", "reduce_short":
 				// 查找对应的开仓记录（可能来自预填充或当前窗口）
 				if openPos, exists := openPositions[posKey]; exists {
 					openPrice := openPos["openPrice"].(float64)
@@ -409,6 +539,14 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 					quantity := openPos["quantity"].(float64)
 					leverage := openPos["leverage"].(int)
 
+					// 减仓：按减仓数量单独计为一笔交易，开仓手续费按比例分摊
+					partial := strings.HasPrefix(action.Action, "reduce_") && action.Quantity > 0 && action.Quantity < quantity
+					openFee, _ := openPos["fee"].(float64)
+					if partial {
+						openFee = openFee * action.Quantity / quantity
+						quantity = action.Quantity
+					}
+
 					// 计算实际盈亏（USDT）
 					// 合约交易 PnL 计算：quantity × 价格差
 					// 注意：杠杆不影响绝对盈亏，只影响保证金需求
@@ -418,6 +556,9 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 					} else {
 						pnl = quantity * (openPrice - action.Price)
 					}
+					// 扣除开平仓手续费（旧日志没有手续费记录，按0计算），计入持仓期间的资金费
+					pnl -= openFee + action.Fee
+					pnl += action.Funding
 
 					// 计算盈亏百分比（相对保证金）
 					positionValue := quantity * openPrice
@@ -439,44 +580,122 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 						MarginUsed:    marginUsed,
 						PnL:           pnl,
 						PnLPct:        pnlPct,
+						Funding:       action.Funding,
 						Duration:      action.Timestamp.Sub(openTime).String(),
 						OpenTime:      openTime,
 						CloseTime:     action.Timestamp,
 					}
 
-					analysis.RecentTrades = append(analysis.RecentTrades, outcome)
-					analysis.TotalTrades++
+					outcomes = append(outcomes, outcome)
 
-					// 分类交易：盈利、亏损、持平（避免将pnl=0算入亏损）
-					if pnl > 0 {
-						analysis.WinningTrades++
-						analysis.AvgWin += pnl
-					} else if pnl < 0 {
-						analysis.LosingTrades++
-						analysis.AvgLoss += pnl
+					// 移除已平仓记录（部分减仓时保留剩余持仓）
+					if partial {
+						reduceOpenPosition(openPositions, posKey, quantity)
+					} else {
+						delete(openPositions, posKey)
 					}
-					// pnl == 0 的交易不计入盈利也不计入亏损，但计入总交易数
+				}
+			}
+		}
+	}
 
-					// 更新币种统计
-					if _, exists := analysis.SymbolStats[symbol]; !exists {
-						analysis.SymbolStats[symbol] = &SymbolPerformance{
-							Symbol: symbol,
-						}
-					}
-					stats := analysis.SymbolStats[symbol]
-					stats.TotalTrades++
-					stats.TotalPnL += pnl
-					if pnl > 0 {
-						stats.WinningTrades++
-					} else if pnl < 0 {
-						stats.LosingTrades++
-					}
+	analysis := NewPerformanceAnalysis(outcomes)
 
-					// 移除已平仓记录
-					delete(openPositions, posKey)
-				}
+	// 计算夏普比率（需要至少2个数据点）
+	analysis.SharpeRatio = l.calculateSharpeRatio(records)
+
+	return analysis, nil
+}
+
+// actionSide 决策动作对应的持仓方向（open/close/add/reduce_long 为long，*_short 为short）
+func actionSide(action string) string {
+	switch {
+	case strings.HasSuffix(action, "_long"):
+		return "long"
+	case strings.HasSuffix(action, "_short"):
+		return "short"
+	}
+	return ""
+}
+
+// addToOpenPosition 加仓：累加数量和手续费，开仓价按数量加权（没有开仓记录时视为开仓）
+func addToOpenPosition(openPositions map[string]map[string]interface{}, posKey, side string, action DecisionAction) {
+	openPos, exists := openPositions[posKey]
+	if !exists {
+		openPositions[posKey] = map[string]interface{}{
+			"side":      side,
+			"openPrice": action.Price,
+			"openTime":  action.Timestamp,
+			"quantity":  action.Quantity,
+			"leverage":  action.Leverage,
+			"fee":       action.Fee,
+		}
+		return
+	}
+
+	quantity := openPos["quantity"].(float64)
+	total := quantity + action.Quantity
+	if total > 0 {
+		openPos["openPrice"] = (openPos["openPrice"].(float64)*quantity + action.Price*action.Quantity) / total
+	}
+	openPos["quantity"] = total
+	fee, _ := openPos["fee"].(float64)
+	openPos["fee"] = fee + action.Fee
+}
+
+// reduceOpenPosition 减仓：扣减数量并按比例扣减开仓手续费，全部减完时移除
+func reduceOpenPosition(openPositions map[string]map[string]interface{}, posKey string, reduced float64) {
+	openPos, exists := openPositions[posKey]
+	if !exists {
+		return
+	}
+	quantity := openPos["quantity"].(float64)
+	if reduced >= quantity || quantity <= 0 {
+		delete(openPositions, posKey)
+		return
+	}
+	fee, _ := openPos["fee"].(float64)
+	openPos["fee"] = fee * (quantity - reduced) / quantity
+	openPos["quantity"] = quantity - reduced
+}
+
+// NewPerformanceAnalysis 根据已完成的交易（按时间正序）统计交易表现（不含夏普比率）
+// 回测报告也使用此函数，保证统计口径与实盘一致
+func NewPerformanceAnalysis(outcomes []TradeOutcome) *PerformanceAnalysis {
+	analysis := &PerformanceAnalysis{
+		RecentTrades: []TradeOutcome{},
+		SymbolStats:  make(map[string]*SymbolPerformance),
+	}
+
+	for _, outcome := range outcomes {
+		pnl := outcome.PnL
+		analysis.RecentTrades = append(analysis.RecentTrades, outcome)
+		analysis.TotalTrades++
+
+		// 分类交易：盈利、亏损、持平（避免将pnl=0算入亏损）
+		if pnl > 0 {
+			analysis.WinningTrades++
+			analysis.AvgWin += pnl
+		} else if pnl < 0 {
+			analysis.LosingTrades++
+			analysis.AvgLoss += pnl
+		}
+		// pnl == 0 的交易不计入盈利也不计入亏损，但计入总交易数
+
+		// 更新币种统计
+		if _, exists := analysis.SymbolStats[outcome.Symbol]; !exists {
+			analysis.SymbolStats[outcome.Symbol] = &SymbolPerformance{
+				Symbol: outcome.Symbol,
 			}
 		}
+		stats := analysis.SymbolStats[outcome.Symbol]
+		stats.TotalTrades++
+		stats.TotalPnL += pnl
+		if pnl > 0 {
+			stats.WinningTrades++
+		} else if pnl < 0 {
+			stats.LosingTrades++
+		}
 	}
 
 	// 计算统计指标
@@ -537,10 +756,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 		}
 	}
 
-	// 计算夏普比率（需要至少2个数据点）
-	analysis.SharpeRatio = l.calculateSharpeRatio(records)
-
-	return analysis, nil
+	return analysis
 }
 
 // calculateSharpeRatio 计算夏普比率
@@ -562,6 +778,11 @@ func (l *DecisionLogger) calculateSharpeRatio(records []*DecisionRecord) float64
 		}
 	}
 
+	return CalculateSharpeRatio(equities)
+}
+
+// CalculateSharpeRatio 根据净值序列计算周期级夏普比率（非年化，无风险利率为0）
+func CalculateSharpeRatio(equities []float64) float64 {
 	if len(equities) < 2 {
 		return 0.0
 	}
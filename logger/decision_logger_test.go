@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDecisionActionOrderIDCompatibility(t *testing.T) {
+	cases := map[string]string{
+		`{"action":"open_long","order_id":123456}`:       "123456",
+		`{"action":"open_long","order_id":0}`:            "",
+		`{"action":"open_long","order_id":"1234-abcd"}`:  "1234-abcd",
+		`{"action":"open_long"}`:                         "",
+		`{"action":"open_long","order_id":null,"fee":1}`: "",
+	}
+	for input, want := range cases {
+		var action DecisionAction
+		if err := json.Unmarshal([]byte(input), &action); err != nil {
+			t.Fatalf("解析 %s 失败: %v", input, err)
+		}
+		if action.OrderID != want || action.Action != "open_long" {
+			t.Errorf("解析 %s: got %+v, want order_id %q", input, action, want)
+		}
+	}
+
+	data, _ := json.Marshal(DecisionAction{Action: "close_long", OrderID: "42", Fee: 0.5})
+	var roundTrip DecisionAction
+	if err := json.Unmarshal(data, &roundTrip); err != nil || roundTrip.OrderID != "42" || roundTrip.Fee != 0.5 {
+		t.Errorf("序列化往返失败: %+v %v", roundTrip, err)
+	}
+}
+
+func TestAnalyzePerformanceReduceAndAdd(t *testing.T) {
+	l := NewDecisionLogger(t.TempDir())
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.SetClock(func() time.Time { return now })
+
+	actions := []DecisionAction{
+		{Action: "open_long", Symbol: "BTCUSDT", Quantity: 1, Leverage: 5, Price: 100, Fee: 0.1},
+		{Action: "add_long", Symbol: "BTCUSDT", Quantity: 1, Leverage: 5, Price: 110, Fee: 0.1},
+		{Action: "reduce_long", Symbol: "BTCUSDT", Quantity: 0.5, Price: 120, Fee: 0.05, Funding: -0.2},
+		{Action: "close_long", Symbol: "BTCUSDT", Quantity: 1.5, Price: 100, Fee: 0.15, Funding: -0.3},
+	}
+	for _, action := range actions {
+		now = now.Add(3 * time.Minute)
+		action.Timestamp = now
+		action.Success = true
+		if err := l.LogDecision(&DecisionRecord{Decisions: []DecisionAction{action}, Success: true}); err != nil {
+			t.Fatalf("记录决策失败: %v", err)
+		}
+	}
+
+	analysis, err := l.AnalyzePerformance(10)
+	if err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+	// 加仓后均价105、数量2、开仓手续费0.2；减仓0.5分摊0.05开仓手续费，资金费计入盈亏（最近的交易在前）
+	want := []struct{ quantity, pnl, funding float64 }{
+		{1.5, 1.5*-5 - 0.15 - 0.15 - 0.3, -0.3},
+		{0.5, 0.5*15 - 0.05 - 0.05 - 0.2, -0.2},
+	}
+	if len(analysis.RecentTrades) != len(want) {
+		t.Fatalf("应有%d笔交易, got %+v", len(want), analysis.RecentTrades)
+	}
+	for i, trade := range analysis.RecentTrades {
+		if math.Abs(trade.Quantity-want[i].quantity) > 1e-9 || math.Abs(trade.PnL-want[i].pnl) > 1e-9 || trade.OpenPrice != 105 || trade.Funding != want[i].funding {
+			t.Errorf("第%d笔交易错误: got %+v, want %+v", i+1, trade, want[i])
+		}
+	}
+
+	stats, err := l.GetStatistics()
+	if err != nil {
+		t.Fatalf("统计失败: %v", err)
+	}
+	if stats.TotalOpenPositions != 2 || stats.TotalClosePositions != 2 {
+		t.Errorf("加仓应计为开仓、减仓应计为平仓: %+v", stats)
+	}
+}
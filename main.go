@@ -153,6 +153,22 @@ func main() {
         fmt.Println("╚════════════════════════════════════════════════════════════╝")
         fmt.Println()
 
+        // 子命令: backtest（离线回测，不连接数据库）
+        if len(os.Args) > 1 && os.Args[1] == "backtest" {
+                if err := runBacktestCommand(os.Args[2:]); err != nil {
+                        log.Fatalf("❌ 回测失败: %v", err)
+                }
+                return
+        }
+
+        // 子命令: replay（用当前代码重放决策日志，不调用AI）
+        if len(os.Args) > 1 && os.Args[1] == "replay" {
+                if err := runReplayCommand(os.Args[2:]); err != nil {
+                        log.Fatalf("❌ 回放失败: %v", err)
+                }
+                return
+        }
+
         // 初始化数据库配置
         dbPath := "config.db"
         if len(os.Args) > 1 {
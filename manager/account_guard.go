@@ -0,0 +1,94 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"nofx/config"
+	"sort"
+)
+
+// ErrAccountInUse 交易所账户正被其他运行中的交易员使用
+var ErrAccountInUse = errors.New("交易所账户正被其他交易员使用")
+
+// traderAccount 交易员实际操作的交易所账户，用于防止多个交易员同时操作同一账户
+type traderAccount struct {
+	key         string // ExchangeConfig.AccountKey()，为空表示不参与检测（如模拟盘）
+	label       string // 展示用的账户名称，不含任何密钥
+	allowShared bool   // 是否允许与其他交易员共用
+}
+
+func newTraderAccount(traderCfg *config.TraderRecord, exchangeCfg *config.ExchangeConfig) traderAccount {
+	label := exchangeCfg.ID + "/默认凭证"
+	if exchangeCfg.AccountName != "" {
+		label = exchangeCfg.ID + "/" + exchangeCfg.AccountName
+	}
+	if exchangeCfg.SubAccount != "" {
+		label += " (子账户 " + exchangeCfg.SubAccount + ")"
+	}
+	return traderAccount{
+		key:         exchangeCfg.AccountKey(),
+		label:       label,
+		allowShared: traderCfg.AllowSharedAccount,
+	}
+}
+
+// findAccountConflict 查找与self共用同一账户且正在运行的交易员，双方都允许共用时不算冲突
+func findAccountConflict(selfID string, accounts map[string]traderAccount, isRunning func(traderID string) bool) (string, bool) {
+	self, ok := accounts[selfID]
+	if !ok || self.key == "" {
+		return "", false
+	}
+
+	// 按ID排序，保证错误信息稳定
+	ids := make([]string, 0, len(accounts))
+	for id := range accounts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		other := accounts[id]
+		if id == selfID || other.key != self.key {
+			continue
+		}
+		if self.allowShared && other.allowShared {
+			continue
+		}
+		if isRunning(id) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// StartTrader 启动交易员，与其他运行中的交易员共用同一交易所账户时拒绝启动（双方都开启allow_shared_account除外）
+func (tm *TraderManager) StartTrader(traderID string) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.startTrader(traderID)
+}
+
+// startTrader 内部方法：启动交易员（不加锁，因为调用方已加锁）
+func (tm *TraderManager) startTrader(traderID string) error {
+	at, exists := tm.traders[traderID]
+	if !exists {
+		return fmt.Errorf("trader ID '%s' 不存在", traderID)
+	}
+	if at.IsRunning() {
+		return fmt.Errorf("交易员 %s 已在运行中", at.GetName())
+	}
+
+	isRunning := func(id string) bool {
+		t, ok := tm.traders[id]
+		return ok && t.IsRunning()
+	}
+	if otherID, conflict := findAccountConflict(traderID, tm.accounts, isRunning); conflict {
+		return fmt.Errorf("%w: 交易员 %s 正在使用账户 %s，如需共用请在两个交易员上都开启 allow_shared_account",
+			ErrAccountInUse, tm.traders[otherID].GetName(), tm.accounts[traderID].label)
+	}
+
+	log.Printf("▶️  启动 %s (账户 %s)...", at.GetName(), tm.accounts[traderID].label)
+	at.Start()
+	return nil
+}
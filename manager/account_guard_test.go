@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"nofx/config"
+	"strings"
+	"testing"
+)
+
+func TestFindAccountConflict(t *testing.T) {
+	binance := &config.ExchangeConfig{ID: "binance", APIKey: "key-a"}
+	subAccount := &config.ExchangeConfig{ID: "binance", APIKey: "key-b", SubAccount: "sub1@example.com", AccountName: "子账户1"}
+	paper := &config.ExchangeConfig{ID: "paper"}
+
+	accounts := map[string]traderAccount{
+		"a":        newTraderAccount(&config.TraderRecord{}, binance),
+		"b":        newTraderAccount(&config.TraderRecord{}, binance),
+		"sub":      newTraderAccount(&config.TraderRecord{}, subAccount),
+		"shared1":  newTraderAccount(&config.TraderRecord{AllowSharedAccount: true}, subAccount),
+		"shared2":  newTraderAccount(&config.TraderRecord{AllowSharedAccount: true}, subAccount),
+		"paper1":   newTraderAccount(&config.TraderRecord{}, paper),
+		"paper2":   newTraderAccount(&config.TraderRecord{}, paper),
+		"unloaded": {},
+	}
+	running := map[string]bool{}
+	isRunning := func(id string) bool { return running[id] }
+
+	if _, conflict := findAccountConflict("a", accounts, isRunning); conflict {
+		t.Error("没有其他运行中的交易员时不应冲突")
+	}
+
+	running["b"] = true
+	if other, conflict := findAccountConflict("a", accounts, isRunning); !conflict || other != "b" {
+		t.Errorf("同一账户已有交易员运行时应冲突, got %s %v", other, conflict)
+	}
+	if _, conflict := findAccountConflict("sub", accounts, isRunning); conflict {
+		t.Error("不同子账户不应冲突")
+	}
+
+	// 双方都允许共用时不冲突，只有一方允许时仍然冲突
+	running["shared1"] = true
+	if _, conflict := findAccountConflict("shared2", accounts, isRunning); conflict {
+		t.Error("双方都允许共用时不应冲突")
+	}
+	if other, conflict := findAccountConflict("sub", accounts, isRunning); !conflict || other != "shared1" {
+		t.Errorf("只有一方允许共用时应冲突, got %s %v", other, conflict)
+	}
+
+	// 模拟盘互不影响
+	running["paper1"] = true
+	if _, conflict := findAccountConflict("paper2", accounts, isRunning); conflict {
+		t.Error("模拟盘交易员不应冲突")
+	}
+	if _, conflict := findAccountConflict("missing", accounts, isRunning); conflict {
+		t.Error("未记录账户的交易员不应冲突")
+	}
+}
+
+func TestTraderAccountLabelHidesCredentials(t *testing.T) {
+	account := newTraderAccount(&config.TraderRecord{}, &config.ExchangeConfig{ID: "okx", APIKey: "secret-key", AccountName: "对冲", SubAccount: "hedge01"})
+	if account.label != "okx/对冲 (子账户 hedge01)" {
+		t.Errorf("账户名称错误: %s", account.label)
+	}
+	if strings.Contains(account.label, "secret-key") || strings.Contains(account.key, "secret-key") {
+		t.Errorf("账户标识不应包含API Key: %+v", account)
+	}
+}
@@ -0,0 +1,300 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"nofx/config"
+	"nofx/trader"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrExposureLimit 开仓会超过用户级组合风险上限
+var ErrExposureLimit = errors.New("超过组合风险上限")
+
+// exposureReservationTTL 已批准但未确认下单结果的开仓额度的保留时间（防止多个交易员同时开仓绕过上限；
+// 交易员异常退出未释放时按此时间自动过期）
+const exposureReservationTTL = time.Minute
+
+// PortfolioLimits 用户级组合风险上限（名义价值按USDT计，0表示不限制）
+type PortfolioLimits struct {
+	MaxSymbolNotional float64             `json:"max_symbol_notional"` // 单币种合计名义价值上限
+	MaxTotalNotional  float64             `json:"max_total_notional"`  // 所有持仓合计名义价值上限
+	MaxBucketNotional float64             `json:"max_bucket_notional"` // 同一相关性分组合计名义价值上限
+	MaxGrossLeverage  float64             `json:"max_gross_leverage"`  // 最大总杠杆 = 合计名义价值 / 合计净值
+	Buckets           map[string][]string `json:"buckets"`             // 相关性分组，如 {"majors": ["BTCUSDT", "ETHUSDT"]}
+}
+
+// bucketOf 币种所属的相关性分组（不属于任何分组时返回空）
+func (l PortfolioLimits) bucketOf(symbol string) string {
+	// 按分组名排序，币种出现在多个分组时结果稳定
+	names := make([]string, 0, len(l.Buckets))
+	for name := range l.Buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, s := range l.Buckets[name] {
+			if strings.EqualFold(s, symbol) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// exposureSource 组合风险汇总所需的交易员接口（由trader.AutoTrader实现）
+type exposureSource interface {
+	GetExposureSnapshot() (float64, []trader.Position, error)
+	PendingEntryExposure() []trader.Position // 未成交的限价入场单（按交易员统计）
+}
+
+// exposureMember 参与汇总的交易员，accountKey相同的交易员共用交易所账户，持仓只计算一次
+type exposureMember struct {
+	traderID   string
+	accountKey string
+	source     exposureSource
+}
+
+// exposureReservation 已批准的开仓额度
+// 下单成功后记录committedAt：此后获取的持仓已包含该笔开仓，只有在下单前开始获取的持仓快照需要计入预留
+type exposureReservation struct {
+	symbol      string
+	notional    float64
+	expires     time.Time
+	committedAt time.Time // 下单成功的时间（零值表示尚未下单）
+}
+
+// reservationHandle 实现trader.ExposureReservation
+type reservationHandle struct {
+	service *PortfolioRiskService
+	userID  string
+	r       *exposureReservation // 未设置上限时为nil
+}
+
+// Commit 下单成功：预留额度不再计入之后获取的持仓快照
+func (h *reservationHandle) Commit() {
+	if h.r == nil {
+		return
+	}
+	h.service.mu.Lock()
+	defer h.service.mu.Unlock()
+	if h.r.committedAt.IsZero() {
+		h.r.committedAt = h.service.clock()
+	}
+}
+
+// Release 未下单或下单失败：立即释放预留额度（已Commit时无效）
+func (h *reservationHandle) Release() {
+	if h.r == nil {
+		return
+	}
+	h.service.mu.Lock()
+	defer h.service.mu.Unlock()
+	if !h.r.committedAt.IsZero() {
+		return
+	}
+	reservations := h.service.reservations[h.userID]
+	for i, r := range reservations {
+		if r == h.r {
+			h.service.reservations[h.userID] = append(reservations[:i:i], reservations[i+1:]...)
+			return
+		}
+	}
+}
+
+// PortfolioRiskService 用户级组合风险服务
+// 汇总同一用户所有交易员（按交易所账户去重）的持仓，开仓前检查单币种、总名义价值、相关性分组和总杠杆上限
+type PortfolioRiskService struct {
+	members func(userID string) []exposureMember
+	clock   func() time.Time
+
+	mu           sync.Mutex // 保护limits和reservations（查询交易所期间不持有）
+	limits       PortfolioLimits
+	reservations map[string][]*exposureReservation // key: userID
+}
+
+// NewPortfolioRiskService 创建组合风险服务，members返回用户的所有交易员
+func NewPortfolioRiskService(members func(userID string) []exposureMember) *PortfolioRiskService {
+	return &PortfolioRiskService{
+		members:      members,
+		clock:        time.Now,
+		reservations: make(map[string][]*exposureReservation),
+	}
+}
+
+// SetLimits 更新风险上限
+func (s *PortfolioRiskService) SetLimits(limits PortfolioLimits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limits = limits
+}
+
+// Limits 返回当前风险上限
+func (s *PortfolioRiskService) Limits() PortfolioLimits {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limits
+}
+
+// portfolioExposure 用户的合计敞口
+type portfolioExposure struct {
+	Equity   float64
+	Total    float64
+	BySymbol map[string]float64
+	ByBucket map[string]float64
+}
+
+// add 计入一笔名义价值
+func (e *portfolioExposure) add(limits PortfolioLimits, symbol string, notional float64) {
+	e.Total += notional
+	e.BySymbol[symbol] += notional
+	if bucket := limits.bucketOf(symbol); bucket != "" {
+		e.ByBucket[bucket] += notional
+	}
+}
+
+// aggregate 汇总用户所有账户的净值和持仓名义价值（逐个查询交易所，调用方不应持有s.mu）
+// 获取失败的账户跳过（与单交易员的保证金检查一致，不因为查询失败阻止交易）
+func (s *PortfolioRiskService) aggregate(userID string, limits PortfolioLimits) portfolioExposure {
+	exposure := portfolioExposure{
+		BySymbol: make(map[string]float64),
+		ByBucket: make(map[string]float64),
+	}
+
+	seen := make(map[string]bool)
+	for _, member := range s.members(userID) {
+		// 限价入场单由各交易员自己跟踪，共用账户时也需逐个计入（成交后出现在持仓中并从挂单中移除）
+		for _, order := range member.source.PendingEntryExposure() {
+			exposure.add(limits, order.Symbol, math.Abs(order.Quantity*order.MarkPrice))
+		}
+
+		key := member.accountKey
+		if key == "" {
+			key = "trader:" + member.traderID // 模拟盘等不共用账户的交易员各自计算
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		equity, positions, err := member.source.GetExposureSnapshot()
+		if err != nil {
+			log.Printf("⚠️ 组合风险汇总: 获取交易员 %s 的持仓失败，跳过: %v", member.traderID, err)
+			continue
+		}
+		exposure.Equity += equity
+		for _, pos := range positions {
+			exposure.add(limits, pos.Symbol, math.Abs(pos.Quantity*pos.MarkPrice))
+		}
+	}
+	return exposure
+}
+
+// addReservations 计入未过期的预留额度并清理过期项，snapshotAt为开始获取持仓快照的时间（调用方需持有s.mu）
+func (s *PortfolioRiskService) addReservations(exposure *portfolioExposure, userID string, limits PortfolioLimits, snapshotAt, now time.Time) {
+	active := s.reservations[userID][:0]
+	for _, r := range s.reservations[userID] {
+		if !now.Before(r.expires) {
+			continue
+		}
+		active = append(active, r)
+		if r.committedAt.IsZero() || r.committedAt.After(snapshotAt) {
+			exposure.add(limits, r.symbol, r.notional)
+		}
+	}
+	s.reservations[userID] = active
+}
+
+// CheckExposure 实现trader.ExposureChecker：返回允许开仓的最大名义价值和预留额度，已达到任一上限时返回ErrExposureLimit
+// 持仓快照在锁外获取，单个交易所响应慢不会阻塞其他交易员的检查
+func (s *PortfolioRiskService) CheckExposure(req trader.ExposureRequest) (float64, trader.ExposureReservation, error) {
+	limits := s.Limits()
+	if limits.MaxSymbolNotional <= 0 && limits.MaxTotalNotional <= 0 && limits.MaxBucketNotional <= 0 && limits.MaxGrossLeverage <= 0 {
+		return req.NotionalUSD, &reservationHandle{}, nil
+	}
+
+	snapshotAt := s.clock()
+	exposure := s.aggregate(req.UserID, limits)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock()
+	s.addReservations(&exposure, req.UserID, limits, snapshotAt, now)
+	allowed := req.NotionalUSD
+	var limitedBy []string
+	clamp := func(name string, limit, used float64) {
+		if limit <= 0 {
+			return
+		}
+		if remaining := limit - used; remaining < allowed {
+			allowed = math.Max(remaining, 0)
+			limitedBy = append(limitedBy, fmt.Sprintf("%s (已用 $%.2f / 上限 $%.2f)", name, used, limit))
+		}
+	}
+	clamp(req.Symbol+" 合计敞口", limits.MaxSymbolNotional, exposure.BySymbol[req.Symbol])
+	clamp("总敞口", limits.MaxTotalNotional, exposure.Total)
+	if bucket := limits.bucketOf(req.Symbol); bucket != "" {
+		clamp(bucket+" 分组敞口", limits.MaxBucketNotional, exposure.ByBucket[bucket])
+	}
+	if limits.MaxGrossLeverage > 0 {
+		clamp(fmt.Sprintf("总杠杆 %.1fx", limits.MaxGrossLeverage), limits.MaxGrossLeverage*exposure.Equity, exposure.Total)
+	}
+
+	if allowed <= 0 {
+		return 0, nil, fmt.Errorf("%w: %s", ErrExposureLimit, strings.Join(limitedBy, ", "))
+	}
+	if len(limitedBy) > 0 {
+		log.Printf("⚠️ 用户 %s 组合风险: %s %s 开仓 $%.2f 调整为 $%.2f，受限于 %s",
+			req.UserID, req.Symbol, req.Side, req.NotionalUSD, allowed, strings.Join(limitedBy, ", "))
+	}
+
+	reservation := &exposureReservation{
+		symbol:   req.Symbol,
+		notional: allowed,
+		expires:  now.Add(exposureReservationTTL),
+	}
+	s.reservations[req.UserID] = append(s.reservations[req.UserID], reservation)
+	return allowed, &reservationHandle{service: s, userID: req.UserID, r: reservation}, nil
+}
+
+// exposureMembers 用户的所有交易员及其交易所账户
+func (tm *TraderManager) exposureMembers(userID string) []exposureMember {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	var members []exposureMember
+	for id, at := range tm.traders {
+		if at.GetUserID() != userID {
+			continue
+		}
+		members = append(members, exposureMember{
+			traderID:   id,
+			accountKey: tm.accounts[id].key,
+			source:     at,
+		})
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].traderID < members[j].traderID })
+	return members
+}
+
+// loadPortfolioLimits 从系统配置读取用户级组合风险上限（JSON，读取失败时不限制）
+func loadPortfolioLimits(database *config.Database) PortfolioLimits {
+	var limits PortfolioLimits
+	if database == nil {
+		return limits
+	}
+	v, err := database.GetSystemConfig("portfolio_risk_limits")
+	if err != nil || v == "" {
+		return limits
+	}
+	if err := json.Unmarshal([]byte(v), &limits); err != nil {
+		log.Printf("⚠️ 解析组合风险配置失败: %v，不限制组合敞口", err)
+		return PortfolioLimits{}
+	}
+	return limits
+}
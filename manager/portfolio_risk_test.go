@@ -0,0 +1,247 @@
+package manager
+
+import (
+	"errors"
+	"math"
+	"nofx/trader"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeExposureSource 固定净值、持仓和挂单的交易员
+type fakeExposureSource struct {
+	equity    float64
+	positions []trader.Position
+	pending   []trader.Position
+	err       error
+	calls     int
+}
+
+func (f *fakeExposureSource) GetExposureSnapshot() (float64, []trader.Position, error) {
+	f.calls++
+	return f.equity, f.positions, f.err
+}
+
+func (f *fakeExposureSource) PendingEntryExposure() []trader.Position {
+	return f.pending
+}
+
+func newTestRiskService(members []exposureMember, limits PortfolioLimits, now *time.Time) *PortfolioRiskService {
+	s := NewPortfolioRiskService(func(userID string) []exposureMember {
+		if userID != "u1" {
+			return nil
+		}
+		return members
+	})
+	s.clock = func() time.Time { return *now }
+	s.SetLimits(limits)
+	return s
+}
+
+func TestPortfolioRiskSymbolAndTotalCaps(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	shared := &fakeExposureSource{equity: 1000, positions: []trader.Position{
+		{Symbol: "BTCUSDT", Side: "long", Quantity: 0.01, MarkPrice: 40000}, // $400
+	}}
+	paper := &fakeExposureSource{equity: 1000, positions: []trader.Position{
+		{Symbol: "SOLUSDT", Side: "short", Quantity: 2, MarkPrice: 100}, // $200
+	}}
+	broken := &fakeExposureSource{err: errors.New("timeout")}
+	members := []exposureMember{
+		{traderID: "a", accountKey: "binance|key", source: shared},
+		{traderID: "b", accountKey: "binance|key", source: shared}, // 与a共用账户，只计算一次
+		{traderID: "p", source: paper},
+		{traderID: "x", accountKey: "okx|key", source: broken},
+	}
+	s := newTestRiskService(members, PortfolioLimits{MaxSymbolNotional: 500, MaxTotalNotional: 1000}, &now)
+
+	allowed, _, err := s.CheckExposure(trader.ExposureRequest{UserID: "u1", Symbol: "BTCUSDT", Side: "long", NotionalUSD: 300})
+	if err != nil || allowed != 100 {
+		t.Fatalf("BTC单币种上限500，已有400，应只允许100, got %.2f %v", allowed, err)
+	}
+	if shared.calls != 1 {
+		t.Errorf("共用账户的持仓应只查询一次, got %d", shared.calls)
+	}
+
+	// 刚批准的100仍在预留中，BTC已满
+	if _, _, err := s.CheckExposure(trader.ExposureRequest{UserID: "u1", Symbol: "BTCUSDT", Side: "long", NotionalUSD: 50}); !errors.Is(err, ErrExposureLimit) {
+		t.Fatalf("BTC敞口已满应拒绝, got %v", err)
+	}
+
+	// 总敞口：400 + 200 + 100(预留) = 700，剩余300
+	allowed, _, err = s.CheckExposure(trader.ExposureRequest{UserID: "u1", Symbol: "ETHUSDT", Side: "short", NotionalUSD: 450})
+	if err != nil || allowed != 300 {
+		t.Fatalf("总敞口上限应限制为300, got %.2f %v", allowed, err)
+	}
+
+	// 预留过期后只按实际持仓计算
+	now = now.Add(2 * exposureReservationTTL)
+	allowed, _, err = s.CheckExposure(trader.ExposureRequest{UserID: "u1", Symbol: "BTCUSDT", Side: "long", NotionalUSD: 50})
+	if err != nil || allowed != 50 {
+		t.Fatalf("预留过期后应允许开仓, got %.2f %v", allowed, err)
+	}
+
+	// 其他用户不受影响
+	allowed, _, err = s.CheckExposure(trader.ExposureRequest{UserID: "u2", Symbol: "BTCUSDT", Side: "long", NotionalUSD: 450})
+	if err != nil || allowed != 450 {
+		t.Fatalf("其他用户不应受限, got %.2f %v", allowed, err)
+	}
+}
+
+func TestPortfolioRiskBucketAndLeverage(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := &fakeExposureSource{equity: 500, positions: []trader.Position{
+		{Symbol: "BTCUSDT", Side: "long", Quantity: 0.01, MarkPrice: 40000}, // $400
+		{Symbol: "ETHUSDT", Side: "short", Quantity: 0.2, MarkPrice: 2000},  // $400
+		{Symbol: "DOGEUSDT", Side: "long", Quantity: 1000, MarkPrice: 0.2},  // $200
+	}}
+	limits := PortfolioLimits{
+		MaxBucketNotional: 1000,
+		MaxGrossLeverage:  3,
+		Buckets:           map[string][]string{"majors": {"BTCUSDT", "ETHUSDT", "BNBUSDT"}},
+	}
+	s := newTestRiskService([]exposureMember{{traderID: "a", accountKey: "k", source: source}}, limits, &now)
+
+	// majors分组已有800，上限1000
+	allowed, _, err := s.CheckExposure(trader.ExposureRequest{UserID: "u1", Symbol: "BNBUSDT", Side: "long", NotionalUSD: 400})
+	if err != nil || allowed != 200 {
+		t.Fatalf("分组上限应限制为200, got %.2f %v", allowed, err)
+	}
+
+	// 总杠杆：净值500×3 = 1500，已用 1000 + 200(预留)，剩余300；DOGE不属于majors
+	allowed, _, err = s.CheckExposure(trader.ExposureRequest{UserID: "u1", Symbol: "DOGEUSDT", Side: "long", NotionalUSD: 1000})
+	if err != nil || math.Abs(allowed-300) > 1e-9 {
+		t.Fatalf("总杠杆上限应限制为300, got %.2f %v", allowed, err)
+	}
+	if _, _, err := s.CheckExposure(trader.ExposureRequest{UserID: "u1", Symbol: "XRPUSDT", Side: "short", NotionalUSD: 10}); !errors.Is(err, ErrExposureLimit) {
+		t.Fatalf("总杠杆已满应拒绝, got %v", err)
+	}
+
+	// 未设置任何上限时不查询持仓
+	s.SetLimits(PortfolioLimits{})
+	calls := source.calls
+	if allowed, _, err := s.CheckExposure(trader.ExposureRequest{UserID: "u1", Symbol: "BTCUSDT", NotionalUSD: 5000}); err != nil || allowed != 5000 {
+		t.Fatalf("未设置上限时应原样放行, got %.2f %v", allowed, err)
+	}
+	if source.calls != calls {
+		t.Error("未设置上限时不应查询持仓")
+	}
+}
+
+func TestPortfolioRiskReservationCommitAndRelease(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := &fakeExposureSource{equity: 1000}
+	s := newTestRiskService([]exposureMember{{traderID: "a", accountKey: "k", source: source}}, PortfolioLimits{MaxSymbolNotional: 500}, &now)
+	request := trader.ExposureRequest{UserID: "u1", Symbol: "BTCUSDT", Side: "long", NotionalUSD: 300}
+
+	// 下单失败：释放后额度立即可用
+	_, reservation, err := s.CheckExposure(request)
+	if err != nil {
+		t.Fatalf("应允许开仓: %v", err)
+	}
+	reservation.Release()
+	if allowed, _, _ := s.CheckExposure(request); allowed != 300 {
+		t.Fatalf("释放后不应计入预留, got %.2f", allowed)
+	}
+
+	// 上一次检查的预留未确认，只剩200
+	allowed, reservation, _ := s.CheckExposure(request)
+	if allowed != 200 {
+		t.Fatalf("未确认的预留应计入, got %.2f", allowed)
+	}
+	reservation.Release()
+
+	// 下单成功：成交出现在持仓后不再重复计入预留
+	s.reservations["u1"] = nil
+	_, reservation, _ = s.CheckExposure(request)
+	now = now.Add(time.Second)
+	source.positions = []trader.Position{{Symbol: "BTCUSDT", Side: "long", Quantity: 0.01, MarkPrice: 30000}} // $300
+	reservation.Commit()
+	reservation.Release() // Commit后无效
+	now = now.Add(time.Second)
+	if allowed, _, _ := s.CheckExposure(trader.ExposureRequest{UserID: "u1", Symbol: "BTCUSDT", Side: "long", NotionalUSD: 500}); allowed != 200 {
+		t.Fatalf("已确认的开仓只应按持仓计算一次, got %.2f", allowed)
+	}
+}
+
+// blockingExposureSource 获取持仓时阻塞，直到release关闭
+type blockingExposureSource struct {
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (b *blockingExposureSource) GetExposureSnapshot() (float64, []trader.Position, error) {
+	b.once.Do(func() { close(b.started) })
+	<-b.release
+	return 1000, nil, nil
+}
+
+func (b *blockingExposureSource) PendingEntryExposure() []trader.Position {
+	return nil
+}
+
+func TestPortfolioRiskSnapshotOutsideLock(t *testing.T) {
+	slow := &blockingExposureSource{started: make(chan struct{}), release: make(chan struct{})}
+	fast := &fakeExposureSource{equity: 1000}
+	s := NewPortfolioRiskService(func(userID string) []exposureMember {
+		if userID == "slow" {
+			return []exposureMember{{traderID: "s", source: slow}}
+		}
+		return []exposureMember{{traderID: "f", source: fast}}
+	})
+	s.SetLimits(PortfolioLimits{MaxTotalNotional: 1000})
+
+	done := make(chan struct{})
+	go func() {
+		s.CheckExposure(trader.ExposureRequest{UserID: "slow", Symbol: "BTCUSDT", NotionalUSD: 100})
+		close(done)
+	}()
+	<-slow.started
+
+	// 一个交易所响应慢时，其他用户的检查不应被阻塞
+	checked := make(chan struct{})
+	go func() {
+		s.CheckExposure(trader.ExposureRequest{UserID: "fast", Symbol: "BTCUSDT", NotionalUSD: 100})
+		close(checked)
+	}()
+	select {
+	case <-checked:
+	case <-time.After(2 * time.Second):
+		t.Fatal("查询持仓期间不应持有锁")
+	}
+	close(slow.release)
+	<-done
+}
+
+func TestPortfolioRiskCountsPendingLimitEntries(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := &fakeExposureSource{equity: 1000}
+	b := &fakeExposureSource{equity: 1000}
+	members := []exposureMember{
+		{traderID: "a", accountKey: "binance|key", source: a},
+		{traderID: "b", accountKey: "binance|key", source: b}, // 共用账户，持仓只查询一次，但挂单各自计入
+	}
+	s := newTestRiskService(members, PortfolioLimits{MaxSymbolNotional: 500}, &now)
+	request := trader.ExposureRequest{UserID: "u1", Symbol: "BTCUSDT", Side: "long", NotionalUSD: 500}
+
+	// a的限价单已确认下单但未成交：预留已Commit，挂单仍需计入
+	_, reservation, _ := s.CheckExposure(trader.ExposureRequest{UserID: "u1", Symbol: "BTCUSDT", Side: "long", NotionalUSD: 200})
+	now = now.Add(time.Second)
+	a.pending = []trader.Position{{Symbol: "BTCUSDT", Side: "long", Quantity: 0.01, MarkPrice: 20000}} // $200
+	reservation.Commit()
+	now = now.Add(time.Second)
+	b.pending = []trader.Position{{Symbol: "BTCUSDT", Side: "short", Quantity: 0.005, MarkPrice: 20000}} // $100
+	if allowed, _, err := s.CheckExposure(request); err != nil || allowed != 200 {
+		t.Fatalf("未成交的限价单应计入敞口，只剩200, got %.2f %v", allowed, err)
+	}
+
+	// 挂单撤销后额度恢复
+	s.reservations["u1"] = nil
+	a.pending = nil
+	b.pending = nil
+	if allowed, _, _ := s.CheckExposure(request); allowed != 500 {
+		t.Errorf("撤单后不应再计入, got %.2f", allowed)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log"
 	"nofx/config"
+	"nofx/decision"
+	"nofx/mcp"
 	"nofx/trader"
 	"sort"
 	"strconv"
@@ -24,18 +26,23 @@ type CompetitionCache struct {
 // TraderManager 管理多个trader实例
 type TraderManager struct {
 	traders         map[string]*trader.AutoTrader // key: trader ID
+	accounts        map[string]traderAccount      // key: trader ID，交易员使用的交易所账户
 	competitionCache *CompetitionCache
+	risk            *PortfolioRiskService // 用户级组合风险检查，所有交易员共用
 	mu              sync.RWMutex
 }
 
 // NewTraderManager 创建trader管理器
 func NewTraderManager() *TraderManager {
-	return &TraderManager{
-		traders: make(map[string]*trader.AutoTrader),
+	tm := &TraderManager{
+		traders:  make(map[string]*trader.AutoTrader),
+		accounts: make(map[string]traderAccount),
 		competitionCache: &CompetitionCache{
 			data: make(map[string]interface{}),
 		},
 	}
+	tm.risk = NewPortfolioRiskService(tm.exposureMembers)
+	return tm
 }
 
 // LoadTradersFromDatabase 从数据库加载所有交易员到内存
@@ -64,6 +71,7 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 	}
 
 	log.Printf("📋 总共加载 %d 个交易员配置", len(allTraders))
+	tm.risk.SetLimits(loadPortfolioLimits(database))
 
 	// 获取系统配置（不包含信号源，信号源现在为用户级别）
 	maxDailyLossStr, _ := database.GetSystemConfig("max_daily_loss")
@@ -141,21 +149,9 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 			continue
 		}
 
-		var exchangeCfg *config.ExchangeConfig
-		for _, exchange := range exchanges {
-			if exchange.ID == traderCfg.ExchangeID {
-				exchangeCfg = exchange
-				break
-			}
-		}
-
-		if exchangeCfg == nil {
-			log.Printf("⚠️  交易员 %s 的交易所 %s 不存在，跳过", traderCfg.Name, traderCfg.ExchangeID)
-			continue
-		}
-
-		if !exchangeCfg.Enabled {
-			log.Printf("⚠️  交易员 %s 的交易所 %s 未启用，跳过", traderCfg.Name, traderCfg.ExchangeID)
+		exchangeCfg, err := resolveExchangeConfig(database, traderCfg, exchanges)
+		if err != nil {
+			log.Printf("⚠️  交易员 %s: %v，跳过", traderCfg.Name, err)
 			continue
 		}
 
@@ -258,20 +254,54 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		traderConfig.OKXAPIKey = exchangeCfg.APIKey
 		traderConfig.OKXSecretKey = exchangeCfg.SecretKey
 		traderConfig.OKXPassphrase = exchangeCfg.OKXPassphrase
+	} else if exchangeCfg.ID == "bybit" {
+		traderConfig.BybitAPIKey = exchangeCfg.APIKey
+		traderConfig.BybitSecretKey = exchangeCfg.SecretKey
+		traderConfig.BybitTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "gate" {
+		traderConfig.GateAPIKey = exchangeCfg.APIKey
+		traderConfig.GateSecretKey = exchangeCfg.SecretKey
+		traderConfig.GateTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "bitget" {
+		traderConfig.BitgetAPIKey = exchangeCfg.APIKey
+		traderConfig.BitgetSecretKey = exchangeCfg.SecretKey
+		traderConfig.BitgetPassphrase = exchangeCfg.OKXPassphrase // Bitget复用OKX的passphrase字段
+		traderConfig.BitgetTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "paper" {
+		traderConfig.PaperFeeRate, traderConfig.PaperSlippageRate = loadPaperTradingRates(database)
 	}
+	traderConfig.LimitOrderTTL, traderConfig.LimitOrderTimeInForce = loadLimitOrderSettings(database)
+	traderConfig.GuardianMaxLossPct, traderConfig.GuardianEquityFloorPct = loadGuardianSettings(database)
+	traderConfig.FlattenOnCircuitBreak = loadCircuitBreakerFlatten(database)
+	traderConfig.EnableStageConstraints = loadStageConstraintsEnabled(database)
+	traderConfig.LiquidationATRMultiple = loadLiquidationATRMultiple(database)
+	traderConfig.FundingGuard = loadFundingGuard(database)
+	traderConfig.SizingMode = trader.SizingMode(traderCfg.SizingMode)
+	traderConfig.RiskPerTradePct = traderCfg.RiskPerTradePct
+	traderConfig.TargetVolatilityPct = traderCfg.TargetVolatilityPct
+	traderConfig.FallbackModels = loadFallbackModels(database, traderCfg)
+	traderConfig.AIPriceTable = loadAIPriceTable(database)
+	traderConfig.EnsembleModels, traderConfig.EnsembleConfig = loadEnsemble(database, traderCfg)
 
 	// 根据AI模型设置API密钥
 	if aiModelCfg.Provider == "qwen" {
 		traderConfig.QwenKey = aiModelCfg.APIKey
 	} else if aiModelCfg.Provider == "deepseek" {
 		traderConfig.DeepSeekKey = aiModelCfg.APIKey
+	} else {
+		// custom / anthropic / openai / gemini 使用通用API密钥
+		traderConfig.CustomAPIKey = aiModelCfg.APIKey
 	}
+	temperature := aiModelCfg.Temperature
+	traderConfig.AITemperature = &temperature
+	traderConfig.AIMaxTokens = aiModelCfg.MaxTokens
 
 	// 创建trader实例
 	at, err := trader.NewAutoTrader(traderConfig)
 	if err != nil {
 		return fmt.Errorf("创建trader失败: %w", err)
 	}
+	at.SetExposureChecker(tm.risk)
 
 	// 设置自定义prompt（如果有）
 	if traderCfg.CustomPrompt != "" {
@@ -285,6 +315,7 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 	}
 
 	tm.traders[traderCfg.ID] = at
+	tm.accounts[traderCfg.ID] = newTraderAccount(traderCfg, exchangeCfg)
 	log.Printf("✓ Trader '%s' (%s + %s) 已加载到内存", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ID)
 	return nil
 }
@@ -369,20 +400,54 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		traderConfig.OKXAPIKey = exchangeCfg.APIKey
 		traderConfig.OKXSecretKey = exchangeCfg.SecretKey
 		traderConfig.OKXPassphrase = exchangeCfg.OKXPassphrase
+	} else if exchangeCfg.ID == "bybit" {
+		traderConfig.BybitAPIKey = exchangeCfg.APIKey
+		traderConfig.BybitSecretKey = exchangeCfg.SecretKey
+		traderConfig.BybitTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "gate" {
+		traderConfig.GateAPIKey = exchangeCfg.APIKey
+		traderConfig.GateSecretKey = exchangeCfg.SecretKey
+		traderConfig.GateTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "bitget" {
+		traderConfig.BitgetAPIKey = exchangeCfg.APIKey
+		traderConfig.BitgetSecretKey = exchangeCfg.SecretKey
+		traderConfig.BitgetPassphrase = exchangeCfg.OKXPassphrase // Bitget复用OKX的passphrase字段
+		traderConfig.BitgetTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "paper" {
+		traderConfig.PaperFeeRate, traderConfig.PaperSlippageRate = loadPaperTradingRates(database)
 	}
+	traderConfig.LimitOrderTTL, traderConfig.LimitOrderTimeInForce = loadLimitOrderSettings(database)
+	traderConfig.GuardianMaxLossPct, traderConfig.GuardianEquityFloorPct = loadGuardianSettings(database)
+	traderConfig.FlattenOnCircuitBreak = loadCircuitBreakerFlatten(database)
+	traderConfig.EnableStageConstraints = loadStageConstraintsEnabled(database)
+	traderConfig.LiquidationATRMultiple = loadLiquidationATRMultiple(database)
+	traderConfig.FundingGuard = loadFundingGuard(database)
+	traderConfig.SizingMode = trader.SizingMode(traderCfg.SizingMode)
+	traderConfig.RiskPerTradePct = traderCfg.RiskPerTradePct
+	traderConfig.TargetVolatilityPct = traderCfg.TargetVolatilityPct
+	traderConfig.FallbackModels = loadFallbackModels(database, traderCfg)
+	traderConfig.AIPriceTable = loadAIPriceTable(database)
+	traderConfig.EnsembleModels, traderConfig.EnsembleConfig = loadEnsemble(database, traderCfg)
 
 	// 根据AI模型设置API密钥
 	if aiModelCfg.Provider == "qwen" {
 		traderConfig.QwenKey = aiModelCfg.APIKey
 	} else if aiModelCfg.Provider == "deepseek" {
 		traderConfig.DeepSeekKey = aiModelCfg.APIKey
+	} else {
+		// custom / anthropic / openai / gemini 使用通用API密钥
+		traderConfig.CustomAPIKey = aiModelCfg.APIKey
 	}
+	temperature := aiModelCfg.Temperature
+	traderConfig.AITemperature = &temperature
+	traderConfig.AIMaxTokens = aiModelCfg.MaxTokens
 
 	// 创建trader实例
 	at, err := trader.NewAutoTrader(traderConfig)
 	if err != nil {
 		return fmt.Errorf("创建trader失败: %w", err)
 	}
+	at.SetExposureChecker(tm.risk)
 
 	// 设置自定义prompt（如果有）
 	if traderCfg.CustomPrompt != "" {
@@ -396,6 +461,7 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 	}
 
 	tm.traders[traderCfg.ID] = at
+	tm.accounts[traderCfg.ID] = newTraderAccount(traderCfg, exchangeCfg)
 	log.Printf("✓ Trader '%s' (%s + %s) 已添加", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ID)
 	return nil
 }
@@ -436,19 +502,22 @@ func (tm *TraderManager) GetTraderIDs() []string {
 	return ids
 }
 
-// StartAll 启动所有trader
+// StartAll 启动所有trader（与已启动的交易员共用账户的会被跳过）
 func (tm *TraderManager) StartAll() {
-	tm.mu.RLock()
-	defer tm.mu.RUnlock()
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 
 	log.Println("🚀 启动所有Trader...")
-	for id, t := range tm.traders {
-		go func(traderID string, at *trader.AutoTrader) {
-			log.Printf("▶️  启动 %s...", at.GetName())
-			if err := at.Run(); err != nil {
-				log.Printf("❌ %s 运行错误: %v", at.GetName(), err)
-			}
-		}(id, t)
+	ids := make([]string, 0, len(tm.traders))
+	for id := range tm.traders {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if err := tm.startTrader(id); err != nil {
+			log.Printf("⚠️  跳过 %s: %v", tm.traders[id].GetName(), err)
+		}
 	}
 }
 
@@ -605,6 +674,7 @@ func (tm *TraderManager) getConcurrentTraderData(traders []*trader.AutoTrader) [
 					"trader_name":     trader.GetName(),
 					"ai_model":        trader.GetAIModel(),
 					"exchange":        trader.GetExchange(),
+					"is_paper":        trader.GetExchange() == "paper",
 					"total_equity":    account["total_equity"],
 					"total_pnl":       account["total_pnl"],
 					"total_pnl_pct":   account["total_pnl_pct"],
@@ -620,6 +690,7 @@ func (tm *TraderManager) getConcurrentTraderData(traders []*trader.AutoTrader) [
 					"trader_name":     trader.GetName(),
 					"ai_model":        trader.GetAIModel(),
 					"exchange":        trader.GetExchange(),
+					"is_paper":        trader.GetExchange() == "paper",
 					"total_equity":    0.0,
 					"total_pnl":       0.0,
 					"total_pnl_pct":   0.0,
@@ -636,6 +707,7 @@ func (tm *TraderManager) getConcurrentTraderData(traders []*trader.AutoTrader) [
 					"trader_name":     trader.GetName(),
 					"ai_model":        trader.GetAIModel(),
 					"exchange":        trader.GetExchange(),
+					"is_paper":        trader.GetExchange() == "paper",
 					"total_equity":    0.0,
 					"total_pnl":       0.0,
 					"total_pnl_pct":   0.0,
@@ -689,6 +761,232 @@ func (tm *TraderManager) GetTopTradersData() (map[string]interface{}, error) {
 	return result, nil
 }
 
+// resolveExchangeConfig 获取交易员实际使用的交易所配置
+// 指定了命名凭证时用凭证覆盖交易所默认配置（不要求默认配置已启用），否则使用交易所默认配置
+func resolveExchangeConfig(database *config.Database, traderCfg *config.TraderRecord, exchanges []*config.ExchangeConfig) (*config.ExchangeConfig, error) {
+	var exchangeCfg *config.ExchangeConfig
+	for _, exchange := range exchanges {
+		if exchange.ID == traderCfg.ExchangeID {
+			exchangeCfg = exchange
+			break
+		}
+	}
+
+	if traderCfg.ExchangeAccountID != "" {
+		account, err := database.GetExchangeAccount(traderCfg.UserID, traderCfg.ExchangeAccountID)
+		if err != nil {
+			return nil, fmt.Errorf("交易所凭证 %s 不存在: %w", traderCfg.ExchangeAccountID, err)
+		}
+		if account.ExchangeID != traderCfg.ExchangeID {
+			return nil, fmt.Errorf("交易所凭证 %s 属于 %s，与交易所 %s 不一致", account.Name, account.ExchangeID, traderCfg.ExchangeID)
+		}
+		return account.ExchangeConfig(exchangeCfg), nil
+	}
+
+	if exchangeCfg == nil {
+		return nil, fmt.Errorf("交易所 %s 不存在", traderCfg.ExchangeID)
+	}
+	if !exchangeCfg.Enabled {
+		return nil, fmt.Errorf("交易所 %s 未启用", traderCfg.ExchangeID)
+	}
+	return exchangeCfg, nil
+}
+
+// loadPaperTradingRates 从系统配置读取模拟盘手续费率和滑点
+// 未配置或读取失败时返回nil（使用模拟盘默认值），配置为0时表示免手续费/无滑点
+func loadPaperTradingRates(database *config.Database) (feeRate, slippageRate *float64) {
+	if database == nil {
+		return nil, nil
+	}
+	return parsePaperRate(database, "paper_fee_rate"), parsePaperRate(database, "paper_slippage_rate")
+}
+
+// parsePaperRate 解析非负的比例配置，未配置或无效时返回nil
+func parsePaperRate(database *config.Database, key string) *float64 {
+	v, err := database.GetSystemConfig(key)
+	if err != nil || strings.TrimSpace(v) == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || f < 0 {
+		log.Printf("⚠️ 系统配置 %s 无效 (%q)，使用模拟盘默认值", key, v)
+		return nil
+	}
+	return &f
+}
+
+// loadLimitOrderSettings 从系统配置读取限价单超时时间和有效方式（读取失败时返回零值，使用AutoTrader默认值）
+func loadLimitOrderSettings(database *config.Database) (ttl time.Duration, tif trader.TimeInForce) {
+	if database == nil {
+		return 0, ""
+	}
+	if v, err := database.GetSystemConfig("limit_order_ttl_minutes"); err == nil && v != "" {
+		if minutes, err := strconv.Atoi(v); err == nil && minutes > 0 {
+			ttl = time.Duration(minutes) * time.Minute
+		}
+	}
+	if v, err := database.GetSystemConfig("limit_order_time_in_force"); err == nil && v != "" {
+		if parsed, err := trader.ParseTimeInForce(v); err == nil {
+			tif = parsed
+		} else {
+			log.Printf("⚠️ %v，使用默认GTC", err)
+		}
+	}
+	return ttl, tif
+}
+
+// loadGuardianSettings 从系统配置读取实时风控的单仓亏损上限和净值下限（百分比，读取失败或为0时不启用对应检查）
+func loadGuardianSettings(database *config.Database) (maxLossPct, equityFloorPct float64) {
+	if database == nil {
+		return 0, 0
+	}
+	if v, err := database.GetSystemConfig("guardian_max_loss_pct"); err == nil && v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 {
+			maxLossPct = f
+		}
+	}
+	if v, err := database.GetSystemConfig("guardian_equity_floor_pct"); err == nil && v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f < 100 {
+			equityFloorPct = f
+		}
+	}
+	return maxLossPct, equityFloorPct
+}
+
+// loadCircuitBreakerFlatten 从系统配置读取触发账户熔断时是否平掉所有持仓（默认不平仓，只暂停交易）
+func loadCircuitBreakerFlatten(database *config.Database) bool {
+	if database == nil {
+		return false
+	}
+	v, err := database.GetSystemConfig("circuit_breaker_flatten")
+	if err != nil || v == "" {
+		return false
+	}
+	flatten, err := strconv.ParseBool(v)
+	return err == nil && flatten
+}
+
+// loadStageConstraintsEnabled 从系统配置读取是否启用学习阶段约束（默认启用）
+func loadStageConstraintsEnabled(database *config.Database) bool {
+	if database == nil {
+		return true
+	}
+	v, err := database.GetSystemConfig("stage_constraints_enabled")
+	if err != nil || v == "" {
+		return true
+	}
+	enabled, err := strconv.ParseBool(v)
+	return err != nil || enabled
+}
+
+// loadLiquidationATRMultiple 从系统配置读取开仓时强平价距入场价的最小ATR倍数（0表示使用默认值）
+func loadLiquidationATRMultiple(database *config.Database) float64 {
+	if database == nil {
+		return 0
+	}
+	v, err := database.GetSystemConfig("liquidation_atr_multiple")
+	if err != nil || v == "" {
+		return 0
+	}
+	multiple, err := strconv.ParseFloat(v, 64)
+	if err != nil || multiple < 0 {
+		return 0
+	}
+	return multiple
+}
+
+// loadFundingGuard 从系统配置读取资金费成本控制（JSON，读取失败时不启用）
+func loadFundingGuard(database *config.Database) trader.FundingGuardConfig {
+	var guard trader.FundingGuardConfig
+	if database == nil {
+		return guard
+	}
+	v, err := database.GetSystemConfig("funding_cost_guard")
+	if err != nil || v == "" {
+		return guard
+	}
+	if err := json.Unmarshal([]byte(v), &guard); err != nil {
+		log.Printf("⚠️ 解析资金费成本配置失败: %v，不启用资金费检查", err)
+		return trader.FundingGuardConfig{}
+	}
+	return guard
+}
+
+// loadEnsemble 解析交易员的集成决策配置，并加载参与投票的AI模型（配置无效时使用单模型）
+func loadEnsemble(database *config.Database, traderCfg *config.TraderRecord) ([]trader.ModelEndpoint, decision.EnsembleConfig) {
+	ensembleConfig, err := decision.ParseEnsembleConfig(traderCfg.EnsembleConfig)
+	if err != nil {
+		log.Printf("⚠️ 交易员 %s 的%v，使用单模型决策", traderCfg.Name, err)
+		return nil, decision.EnsembleConfig{}
+	}
+	if ensembleConfig == nil {
+		return nil, decision.EnsembleConfig{}
+	}
+	models := loadModelEndpoints(database, traderCfg, ensembleConfig.ModelIDs, "集成决策模型")
+	if len(models) == 0 {
+		return nil, decision.EnsembleConfig{}
+	}
+	return models, *ensembleConfig
+}
+
+// loadAIPriceTable 加载AI模型单价（系统配置ai_model_pricing覆盖默认单价，配置无效时使用默认单价）
+func loadAIPriceTable(database *config.Database) mcp.PriceTable {
+	raw, _ := database.GetSystemConfig("ai_model_pricing")
+	prices, err := mcp.ParsePriceTable(raw)
+	if err != nil {
+		log.Printf("⚠️ %v，使用默认单价", err)
+		return mcp.DefaultPriceTable
+	}
+	return prices
+}
+
+// loadFallbackModels 加载交易员的故障转移备用模型（按配置顺序）
+func loadFallbackModels(database *config.Database, traderCfg *config.TraderRecord) []trader.ModelEndpoint {
+	var modelIDs []string
+	for _, modelID := range strings.Split(traderCfg.FallbackModelIDs, ",") {
+		if modelID = strings.TrimSpace(modelID); modelID != "" {
+			modelIDs = append(modelIDs, modelID)
+		}
+	}
+	return loadModelEndpoints(database, traderCfg, modelIDs, "备用模型")
+}
+
+// loadModelEndpoints 按ID加载用户的AI模型连接配置（跳过主模型、不存在或未启用的模型）
+func loadModelEndpoints(database *config.Database, traderCfg *config.TraderRecord, modelIDs []string, label string) []trader.ModelEndpoint {
+	if len(modelIDs) == 0 || database == nil {
+		return nil
+	}
+	models, err := database.GetAIModels(traderCfg.UserID)
+	if err != nil {
+		log.Printf("⚠️ 获取交易员 %s 的%s失败: %v", traderCfg.Name, label, err)
+		return nil
+	}
+	byID := make(map[string]*config.AIModelConfig, len(models))
+	for _, model := range models {
+		byID[model.ID] = model
+	}
+
+	var endpoints []trader.ModelEndpoint
+	for _, modelID := range modelIDs {
+		model, ok := byID[modelID]
+		if modelID == traderCfg.AIModelID || !ok || !model.Enabled {
+			log.Printf("⚠️ 交易员 %s 的%s %s 不可用，跳过", traderCfg.Name, label, modelID)
+			continue
+		}
+		temperature := model.Temperature
+		endpoints = append(endpoints, trader.ModelEndpoint{
+			Name:            model.ID,
+			Provider:        model.Provider,
+			APIKey:          model.APIKey,
+			CustomAPIURL:    model.CustomAPIURL,
+			CustomModelName: model.CustomModelName,
+			Temperature:     &temperature,
+			MaxTokens:       model.MaxTokens,
+		})
+	}
+	return endpoints
+}
+
 // isUserTrader 检查trader是否属于指定用户
 func isUserTrader(traderID, userID string) bool {
 	// trader ID格式: userID_traderName 或 randomUUID_modelName
@@ -731,6 +1029,7 @@ func (tm *TraderManager) LoadUserTraders(database *config.Database, userID strin
 	}
 
 	log.Printf("📋 为用户 %s 加载交易员配置: %d 个", userID, len(traders))
+	tm.risk.SetLimits(loadPortfolioLimits(database))
 
 	// 获取系统配置（不包含信号源，信号源现在为用户级别）
 	maxDailyLossStr, _ := database.GetSystemConfig("max_daily_loss")
@@ -824,21 +1123,9 @@ func (tm *TraderManager) LoadUserTraders(database *config.Database, userID strin
 			continue
 		}
 
-		var exchangeCfg *config.ExchangeConfig
-		for _, exchange := range exchanges {
-			if exchange.ID == traderCfg.ExchangeID {
-				exchangeCfg = exchange
-				break
-			}
-		}
-
-		if exchangeCfg == nil {
-			log.Printf("⚠️ 交易员 %s 的交易所 %s 不存在，跳过", traderCfg.Name, traderCfg.ExchangeID)
-			continue
-		}
-
-		if !exchangeCfg.Enabled {
-			log.Printf("⚠️ 交易员 %s 的交易所 %s 未启用，跳过", traderCfg.Name, traderCfg.ExchangeID)
+		exchangeCfg, err := resolveExchangeConfig(database, traderCfg, exchanges)
+		if err != nil {
+			log.Printf("⚠️ 交易员 %s: %v，跳过", traderCfg.Name, err)
 			continue
 		}
 
@@ -919,20 +1206,54 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		traderConfig.OKXAPIKey = exchangeCfg.APIKey
 		traderConfig.OKXSecretKey = exchangeCfg.SecretKey
 		traderConfig.OKXPassphrase = exchangeCfg.OKXPassphrase
+	} else if exchangeCfg.ID == "bybit" {
+		traderConfig.BybitAPIKey = exchangeCfg.APIKey
+		traderConfig.BybitSecretKey = exchangeCfg.SecretKey
+		traderConfig.BybitTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "gate" {
+		traderConfig.GateAPIKey = exchangeCfg.APIKey
+		traderConfig.GateSecretKey = exchangeCfg.SecretKey
+		traderConfig.GateTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "bitget" {
+		traderConfig.BitgetAPIKey = exchangeCfg.APIKey
+		traderConfig.BitgetSecretKey = exchangeCfg.SecretKey
+		traderConfig.BitgetPassphrase = exchangeCfg.OKXPassphrase // Bitget复用OKX的passphrase字段
+		traderConfig.BitgetTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "paper" {
+		traderConfig.PaperFeeRate, traderConfig.PaperSlippageRate = loadPaperTradingRates(database)
 	}
+	traderConfig.LimitOrderTTL, traderConfig.LimitOrderTimeInForce = loadLimitOrderSettings(database)
+	traderConfig.GuardianMaxLossPct, traderConfig.GuardianEquityFloorPct = loadGuardianSettings(database)
+	traderConfig.FlattenOnCircuitBreak = loadCircuitBreakerFlatten(database)
+	traderConfig.EnableStageConstraints = loadStageConstraintsEnabled(database)
+	traderConfig.LiquidationATRMultiple = loadLiquidationATRMultiple(database)
+	traderConfig.FundingGuard = loadFundingGuard(database)
+	traderConfig.SizingMode = trader.SizingMode(traderCfg.SizingMode)
+	traderConfig.RiskPerTradePct = traderCfg.RiskPerTradePct
+	traderConfig.TargetVolatilityPct = traderCfg.TargetVolatilityPct
+	traderConfig.FallbackModels = loadFallbackModels(database, traderCfg)
+	traderConfig.AIPriceTable = loadAIPriceTable(database)
+	traderConfig.EnsembleModels, traderConfig.EnsembleConfig = loadEnsemble(database, traderCfg)
 
 	// 根据AI模型设置API密钥
 	if aiModelCfg.Provider == "qwen" {
 		traderConfig.QwenKey = aiModelCfg.APIKey
 	} else if aiModelCfg.Provider == "deepseek" {
 		traderConfig.DeepSeekKey = aiModelCfg.APIKey
+	} else {
+		// custom / anthropic / openai / gemini 使用通用API密钥
+		traderConfig.CustomAPIKey = aiModelCfg.APIKey
 	}
+	temperature := aiModelCfg.Temperature
+	traderConfig.AITemperature = &temperature
+	traderConfig.AIMaxTokens = aiModelCfg.MaxTokens
 
 	// 创建trader实例
 	at, err := trader.NewAutoTrader(traderConfig)
 	if err != nil {
 		return fmt.Errorf("创建trader失败: %w", err)
 	}
+	at.SetExposureChecker(tm.risk)
 
 	// 设置自定义prompt（如果有）
 	if traderCfg.CustomPrompt != "" {
@@ -946,6 +1267,7 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 	}
 
 	tm.traders[traderCfg.ID] = at
+	tm.accounts[traderCfg.ID] = newTraderAccount(traderCfg, exchangeCfg)
 	log.Printf("✓ Trader '%s' (%s + %s) 已为用户加载到内存", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ID)
 	return nil
 }
\ No newline at end of file
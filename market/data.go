@@ -28,6 +28,32 @@ func Get(symbol string) (*Data, error) {
                 return nil, fmt.Errorf("获取4小时K线失败: %v", err)
         }
 
+        data, err := BuildData(symbol, klines3m, klines4h)
+        if err != nil {
+                return nil, err
+        }
+
+        // 获取OI数据
+        oiData, err := getOpenInterestData(symbol)
+        if err != nil {
+                // OI失败不影响整体,使用默认值
+                oiData = &OIData{Latest: 0, Average: 0}
+        }
+        data.OpenInterest = oiData
+
+        // 获取Funding Rate
+        data.FundingRate, _ = getFundingRate(symbol)
+
+        return data, nil
+}
+
+// BuildData 根据K线计算市场数据（不含OI和资金费率，回测使用历史K线调用）
+// klines3m/klines4h 需按时间正序排列，最后一根为当前K线
+func BuildData(symbol string, klines3m, klines4h []Kline) (*Data, error) {
+        if len(klines3m) == 0 {
+                return nil, fmt.Errorf("%s 缺少3分钟K线数据", symbol)
+        }
+
         // 计算当前指标 (基于3分钟最新数据)
         currentPrice := klines3m[len(klines3m)-1].Close
         currentEMA20 := calculateEMA(klines3m, 20)
@@ -53,16 +79,6 @@ func Get(symbol string) (*Data, error) {
                 }
         }
 
-        // 获取OI数据
-        oiData, err := getOpenInterestData(symbol)
-        if err != nil {
-                // OI失败不影响整体,使用默认值
-                oiData = &OIData{Latest: 0, Average: 0}
-        }
-
-        // 获取Funding Rate
-        fundingRate, _ := getFundingRate(symbol)
-
         // 计算日内系列数据
         intradayData := calculateIntradaySeries(klines3m)
 
@@ -77,8 +93,6 @@ func Get(symbol string) (*Data, error) {
                 CurrentEMA20:      currentEMA20,
                 CurrentMACD:       currentMACD,
                 CurrentRSI7:       currentRSI7,
-                OpenInterest:      oiData,
-                FundingRate:       fundingRate,
                 IntradaySeries:    intradayData,
                 LongerTermContext: longerTermData,
         }, nil
@@ -285,6 +285,46 @@ func (m *WSMonitor) GetCurrentKlines(symbol string, _time string) ([]Kline, erro
         return value.([]Kline), nil
 }
 
+// LatestPrice 从WebSocket实时K线缓存获取最新成交价
+// 监控器未启动、未订阅该币种或数据已过期（如退化为REST轮询）时返回错误，由调用方回退到REST接口
+func LatestPrice(symbol string) (float64, error) {
+        if WSMonitorCli == nil {
+                return 0, fmt.Errorf("WebSocket监控器未启动")
+        }
+        value, exists := WSMonitorCli.klineDataMap3m.Load(strings.ToUpper(symbol))
+        if !exists {
+                return 0, fmt.Errorf("%s 未订阅实时行情", symbol)
+        }
+        klines := value.([]Kline)
+        if len(klines) == 0 {
+                return 0, fmt.Errorf("%s 没有实时K线数据", symbol)
+        }
+        last := klines[len(klines)-1]
+        // 当前K线的收盘时间在未来，超过1分钟仍未更新说明实时数据已中断
+        if time.Since(time.UnixMilli(last.CloseTime)) > time.Minute {
+                return 0, fmt.Errorf("%s 实时行情已过期", symbol)
+        }
+        return last.Close, nil
+}
+
+// SubscribePrice 动态订阅币种的实时K线（供实时风控读取最新价），订阅后由LatestPrice读取
+// 已有实时数据时直接返回；监控器未启动或处于REST轮询模式时返回错误
+func SubscribePrice(symbol string) error {
+        if WSMonitorCli == nil {
+                return fmt.Errorf("WebSocket监控器未启动")
+        }
+        symbol = strings.ToUpper(symbol)
+        if _, exists := WSMonitorCli.klineDataMap3m.Load(symbol); exists {
+                return nil
+        }
+        streams := WSMonitorCli.subscribeSymbol(symbol, "3m")
+        if err := WSMonitorCli.combinedClient.subscribeStreams(streams); err != nil {
+                return fmt.Errorf("订阅%s实时行情失败: %w", symbol, err)
+        }
+        log.Printf("动态订阅流: %v", streams)
+        return nil
+}
+
 func (m *WSMonitor) Close() {
         m.wsClient.Close()
         close(m.alertsChan)
@@ -0,0 +1,82 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// anthropicVersion Anthropic Messages API版本
+const anthropicVersion = "2023-06-01"
+
+// anthropicRequest Anthropic Messages请求（system单独传入，temperature范围0-1）
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float64            `json:"temperature"`
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// buildAnthropicRequest 构建Messages请求体
+func (client *Client) buildAnthropicRequest(systemPrompt, userPrompt string) anthropicRequest {
+	return anthropicRequest{
+		Model:       client.Model,
+		System:      systemPrompt,
+		Messages:    []anthropicMessage{{Role: "user", Content: userPrompt}},
+		MaxTokens:   client.MaxTokens,
+		Temperature: math.Min(client.Temperature, 1),
+	}
+}
+
+// callAnthropic 调用Anthropic Messages接口
+func (client *Client) callAnthropic(systemPrompt, userPrompt string) (string, Usage, error) {
+	headers := map[string]string{
+		"x-api-key":         client.APIKey,
+		"anthropic-version": anthropicVersion,
+	}
+	body, err := client.post(client.requestURL("/messages"), headers, client.buildAnthropicRequest(systemPrompt, userPrompt))
+	if err != nil {
+		return "", Usage{}, err
+	}
+
+	var result anthropicResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", Usage{}, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	var text strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", Usage{}, fmt.Errorf("API返回空响应 (stop_reason: %s)", result.StopReason)
+	}
+
+	usage := Usage{
+		PromptTokens:     result.Usage.InputTokens,
+		CompletionTokens: result.Usage.OutputTokens,
+		TotalTokens:      result.Usage.InputTokens + result.Usage.OutputTokens,
+	}
+	return text.String(), usage, nil
+}
@@ -3,6 +3,7 @@ package mcp
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -15,11 +16,58 @@ import (
 type Provider string
 
 const (
-	ProviderDeepSeek Provider = "deepseek"
-	ProviderQwen     Provider = "qwen"
-	ProviderCustom   Provider = "custom"
+	ProviderDeepSeek  Provider = "deepseek"
+	ProviderQwen      Provider = "qwen"
+	ProviderCustom    Provider = "custom"
+	ProviderAnthropic Provider = "anthropic" // Anthropic Messages API
+	ProviderOpenAI    Provider = "openai"    // OpenAI Chat Completions（支持response_format JSON schema）
+	ProviderGemini    Provider = "gemini"    // Google Gemini generateContent
 )
 
+const (
+	defaultTemperature = 0.5 // 降低temperature以提高JSON格式稳定性
+	defaultMaxTokens   = 2000
+)
+
+// Usage 单次调用的token用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// CallUsage 一次调用的token用量及其提供商和模型（用于按模型计费）
+type CallUsage struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Usage
+}
+
+// UsageReporter 能报告最近一次成功调用token用量的AI客户端
+type UsageReporter interface {
+	LastCallUsage() CallUsage
+}
+
+// ResponseSchema 结构化输出的JSON schema（目前仅OpenAI使用response_format）
+type ResponseSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+	Strict bool            `json:"strict"`
+}
+
+// AIClient AI调用接口
+// *Client 实现该接口；回测时可注入录制响应或桩模型
+type AIClient interface {
+	CallWithMessages(systemPrompt, userPrompt string) (string, error)
+}
+
+// StructuredClient 支持JSON schema结构化输出的AI客户端
+type StructuredClient interface {
+	AIClient
+	SupportsResponseSchema() bool
+	CallWithSchema(systemPrompt, userPrompt string, schema *ResponseSchema) (string, error)
+}
+
 // Client AI API配置
 type Client struct {
 	Provider   Provider
@@ -28,6 +76,13 @@ type Client struct {
 	Model      string
 	Timeout    time.Duration
 	UseFullURL bool // 是否使用完整URL（不添加/chat/completions）
+	MaxRetries int  // 单次调用的最大尝试次数（0表示默认3次；故障转移链中为1，由链负责重试）
+
+	Temperature    float64         // 采样温度（默认0.5）
+	MaxTokens      int             // 单次输出的最大token数（默认2000）
+	ResponseSchema *ResponseSchema // 结构化输出schema（nil表示不限制输出格式）
+
+	lastUsage Usage // 最近一次成功调用的token用量（同一Client不应并发调用）
 }
 
 func New() *Client {
@@ -37,6 +92,9 @@ func New() *Client {
 		BaseURL:  "https://api.deepseek.com/v1",
 		Model:    "deepseek-chat",
 		Timeout:  120 * time.Second, // 增加到120秒，因为AI需要分析大量数据
+
+		Temperature: defaultTemperature,
+		MaxTokens:   defaultMaxTokens,
 	}
 }
 
@@ -108,6 +166,85 @@ func (client *Client) SetCustomAPI(apiURL, apiKey, modelName string) {
 	client.Timeout = 120 * time.Second
 }
 
+// SetProviderAPIKey 按提供商设置API密钥（deepseek / qwen / anthropic / openai / gemini）
+// customURL 为空时使用默认URL，customModel 为空时使用默认模型；自定义API请使用 SetCustomAPI
+func (client *Client) SetProviderAPIKey(provider Provider, apiKey, customURL, customModel string) error {
+	switch provider {
+	case ProviderDeepSeek:
+		client.SetDeepSeekAPIKey(apiKey, customURL, customModel)
+	case ProviderQwen:
+		client.SetQwenAPIKey(apiKey, customURL, customModel)
+	case ProviderAnthropic:
+		client.setProvider(provider, apiKey, customURL, customModel, "https://api.anthropic.com/v1", "claude-sonnet-4-5")
+	case ProviderOpenAI:
+		client.setProvider(provider, apiKey, customURL, customModel, "https://api.openai.com/v1", "gpt-4o")
+	case ProviderGemini:
+		client.setProvider(provider, apiKey, customURL, customModel, "https://generativelanguage.googleapis.com/v1beta", "gemini-2.5-flash")
+	default:
+		return fmt.Errorf("不支持的AI提供商: %s", provider)
+	}
+	return nil
+}
+
+// setProvider 设置原生提供商的地址、模型和密钥
+func (client *Client) setProvider(provider Provider, apiKey, customURL, customModel, defaultURL, defaultModel string) {
+	client.Provider = provider
+	client.APIKey = apiKey
+	client.UseFullURL = false
+	client.BaseURL = defaultURL
+	if customURL != "" {
+		client.BaseURL = customURL
+	}
+	client.Model = defaultModel
+	if customModel != "" {
+		client.Model = customModel
+	}
+	log.Printf("🔧 [MCP] %s BaseURL: %s, Model: %s", provider, client.BaseURL, client.Model)
+	if len(apiKey) > 8 {
+		log.Printf("🔧 [MCP] %s API Key: %s...%s", provider, apiKey[:4], apiKey[len(apiKey)-4:])
+	}
+}
+
+// SetGenerationParams 设置采样温度和最大输出token数
+// temperature 小于0、maxTokens 不大于0时保持当前值
+func (client *Client) SetGenerationParams(temperature float64, maxTokens int) {
+	if temperature >= 0 {
+		client.Temperature = temperature
+	}
+	if maxTokens > 0 {
+		client.MaxTokens = maxTokens
+	}
+}
+
+// SupportsResponseSchema 是否支持JSON schema结构化输出（目前仅OpenAI）
+func (client *Client) SupportsResponseSchema() bool {
+	return client.Provider == ProviderOpenAI
+}
+
+// CallWithSchema 使用指定的JSON schema调用（不修改Client的ResponseSchema）
+func (client *Client) CallWithSchema(systemPrompt, userPrompt string, schema *ResponseSchema) (string, error) {
+	call := *client
+	call.ResponseSchema = schema
+	result, err := call.CallWithMessages(systemPrompt, userPrompt)
+	client.lastUsage = call.lastUsage
+	return result, err
+}
+
+// LastUsage 最近一次成功调用的token用量
+func (client *Client) LastUsage() Usage {
+	return client.lastUsage
+}
+
+// LastCallUsage 最近一次成功调用的token用量（含提供商和模型）
+func (client *Client) LastCallUsage() CallUsage {
+	return CallUsage{Provider: string(client.Provider), Model: client.Model, Usage: client.lastUsage}
+}
+
+// LastProvider 调用使用的提供商
+func (client *Client) LastProvider() string {
+	return string(client.Provider)
+}
+
 // SetClient 设置完整的AI配置（高级用户）
 func (client *Client) SetClient(Client Client) {
 	if Client.Timeout == 0 {
@@ -119,11 +256,14 @@ func (client *Client) SetClient(Client Client) {
 // CallWithMessages 使用 system + user prompt 调用AI API（推荐）
 func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
 	if client.APIKey == "" {
-		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetDeepSeekAPIKey() 或 SetQwenAPIKey()")
+		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetDeepSeekAPIKey()、SetQwenAPIKey() 或 SetProviderAPIKey()")
 	}
 
 	// 重试配置
 	maxRetries := 3
+	if client.MaxRetries > 0 {
+		maxRetries = client.MaxRetries
+	}
 	var lastErr error
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
@@ -145,140 +285,110 @@ func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string,
 			return "", err
 		}
 
-		// 重试前等待
+		// 重试前等待（服务端给出Retry-After时按其等待，最多30秒）
 		if attempt < maxRetries {
 			waitTime := time.Duration(attempt) * 2 * time.Second
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.RetryAfter > waitTime {
+				waitTime = min(apiErr.RetryAfter, 30*time.Second)
+			}
 			fmt.Printf("⏳ 等待%v后重试...\n", waitTime)
 			time.Sleep(waitTime)
 		}
 	}
 
+	if maxRetries == 1 {
+		return "", lastErr
+	}
 	return "", fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
 }
 
-// callOnce 单次调用AI API（内部使用）
+// callOnce 单次调用AI API（内部使用），按提供商选择请求/响应格式
 func (client *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
 	// 打印当前 AI 配置
 	log.Printf("📡 [MCP] AI 请求配置:")
 	log.Printf("   Provider: %s", client.Provider)
 	log.Printf("   BaseURL: %s", client.BaseURL)
 	log.Printf("   Model: %s", client.Model)
+	log.Printf("   Temperature: %.2f, MaxTokens: %d", client.Temperature, client.MaxTokens)
 	log.Printf("   UseFullURL: %v", client.UseFullURL)
 	if len(client.APIKey) > 8 {
 		log.Printf("   API Key: %s...%s", client.APIKey[:4], client.APIKey[len(client.APIKey)-4:])
 	}
 
-	// 构建 messages 数组
-	messages := []map[string]string{}
-
-	// 如果有 system prompt，添加 system message
-	if systemPrompt != "" {
-		messages = append(messages, map[string]string{
-			"role":    "system",
-			"content": systemPrompt,
-		})
+	var (
+		content string
+		usage   Usage
+		err     error
+	)
+	switch client.Provider {
+	case ProviderAnthropic:
+		content, usage, err = client.callAnthropic(systemPrompt, userPrompt)
+	case ProviderGemini:
+		content, usage, err = client.callGemini(systemPrompt, userPrompt)
+	default:
+		// DeepSeek / Qwen / OpenAI / 自定义API 均使用OpenAI Chat Completions格式
+		content, usage, err = client.callOpenAI(systemPrompt, userPrompt)
 	}
-
-	// 添加 user message
-	messages = append(messages, map[string]string{
-		"role":    "user",
-		"content": userPrompt,
-	})
-
-	// 构建请求体
-	requestBody := map[string]interface{}{
-		"model":       client.Model,
-		"messages":    messages,
-		"temperature": 0.5, // 降低temperature以提高JSON格式稳定性
-		"max_tokens":  2000,
+	if err != nil {
+		return "", err
 	}
+	client.lastUsage = usage
+	log.Printf("📊 [MCP] Token用量: 输入 %d, 输出 %d, 合计 %d", usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens)
+	return content, nil
+}
 
-	// 注意：response_format 参数仅 OpenAI 支持，DeepSeek/Qwen 不支持
-	// 我们通过强化 prompt 和后处理来确保 JSON 格式正确
+// requestURL 请求地址：UseFullURL时直接使用BaseURL，否则拼接path
+func (client *Client) requestURL(path string) string {
+	if client.UseFullURL {
+		return client.BaseURL
+	}
+	return strings.TrimSuffix(client.BaseURL, "/") + path
+}
 
+// post 发送JSON请求，非200响应按提供商的错误格式解析为*APIError
+func (client *Client) post(url string, headers map[string]string, requestBody interface{}) ([]byte, error) {
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("序列化请求失败: %w", err)
-	}
-
-	// 创建HTTP请求
-	var url string
-	if client.UseFullURL {
-		// 使用完整URL，不添加/chat/completions
-		url = client.BaseURL
-	} else {
-		// 默认行为：添加/chat/completions
-		url = fmt.Sprintf("%s/chat/completions", client.BaseURL)
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
 	}
 	log.Printf("📡 [MCP] 请求 URL: %s", url)
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
+		return nil, fmt.Errorf("创建请求失败: %w", err)
 	}
-
 	req.Header.Set("Content-Type", "application/json")
-
-	// 根据不同的Provider设置认证方式
-	switch client.Provider {
-	case ProviderDeepSeek:
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", client.APIKey))
-	case ProviderQwen:
-		// 阿里云Qwen使用API-Key认证
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", client.APIKey))
-		// 注意：如果使用的不是兼容模式，可能需要不同的认证方式
-	default:
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", client.APIKey))
+	for key, value := range headers {
+		req.Header.Set(key, value)
 	}
 
 	// 发送请求
 	httpClient := &http.Client{Timeout: client.Timeout}
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("发送请求失败: %w", err)
+		return nil, fmt.Errorf("发送请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %w", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		// 特殊处理 402 余额不足错误
-		if resp.StatusCode == 402 {
-			return "", fmt.Errorf("AI API余额不足 (Insufficient Balance), 请检查充值: %s", string(body))
-		}
-		// 特殊处理 401 认证失败
-		if resp.StatusCode == 401 {
-			return "", fmt.Errorf("AI API密钥无效 (Unauthorized), 请检查配置: %s", string(body))
-		}
-		return "", fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
-	}
-
-	// 解析响应
-	var result struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("解析响应失败: %w", err)
-	}
-
-	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("API返回空响应")
+		return nil, classifyError(client.Provider, resp.StatusCode, resp.Header, body)
 	}
-
-	return result.Choices[0].Message.Content, nil
+	return body, nil
 }
 
 // isRetryableError 判断错误是否可重试
 func isRetryableError(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
 	errStr := err.Error()
 	// 网络错误、超时、EOF等可以重试
 	retryableErrors := []string{
@@ -0,0 +1,242 @@
+package mcp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// capturedRequest 测试服务器收到的请求
+type capturedRequest struct {
+	Path   string
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// newProviderServer 返回固定响应的测试服务器，并记录收到的请求
+func newProviderServer(t *testing.T, status int, response string, captured *capturedRequest) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		captured.Path = r.URL.Path
+		captured.Header = r.Header.Clone()
+		captured.Body = map[string]interface{}{}
+		if err := json.Unmarshal(body, &captured.Body); err != nil {
+			t.Errorf("请求体不是JSON: %v", err)
+		}
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestClient(t *testing.T, provider Provider, baseURL string) *Client {
+	t.Helper()
+	client := New()
+	if err := client.SetProviderAPIKey(provider, "test-api-key-123456", baseURL, "test-model"); err != nil {
+		t.Fatalf("设置提供商失败: %v", err)
+	}
+	client.SetGenerationParams(0.2, 512)
+	return client
+}
+
+func TestOpenAIRequestMapping(t *testing.T) {
+	var captured capturedRequest
+	server := newProviderServer(t, http.StatusOK, `{
+		"choices":[{"message":{"content":"[]"},"finish_reason":"stop"}],
+		"usage":{"prompt_tokens":120,"completion_tokens":30,"total_tokens":150}
+	}`, &captured)
+
+	client := newTestClient(t, ProviderOpenAI, server.URL)
+	client.ResponseSchema = &ResponseSchema{Name: "decisions", Schema: json.RawMessage(`{"type":"array"}`), Strict: true}
+	content, err := client.callOnce("system", "user")
+	if err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	if content != "[]" {
+		t.Errorf("响应内容错误: %q", content)
+	}
+	if captured.Path != "/chat/completions" || captured.Header.Get("Authorization") != "Bearer test-api-key-123456" {
+		t.Errorf("请求地址或认证错误: %s %s", captured.Path, captured.Header.Get("Authorization"))
+	}
+	if captured.Body["temperature"] != 0.2 || captured.Body["max_completion_tokens"] != 512.0 {
+		t.Errorf("应使用配置的生成参数: %v", captured.Body)
+	}
+	if _, ok := captured.Body["max_tokens"]; ok {
+		t.Error("OpenAI应使用max_completion_tokens")
+	}
+	format, _ := captured.Body["response_format"].(map[string]interface{})
+	schema, _ := format["json_schema"].(map[string]interface{})
+	if format["type"] != "json_schema" || schema["name"] != "decisions" || schema["strict"] != true {
+		t.Errorf("应发送response_format JSON schema: %v", captured.Body["response_format"])
+	}
+	messages, _ := captured.Body["messages"].([]interface{})
+	if len(messages) != 2 {
+		t.Errorf("应包含system和user消息: %v", messages)
+	}
+	if usage := client.LastUsage(); usage != (Usage{PromptTokens: 120, CompletionTokens: 30, TotalTokens: 150}) {
+		t.Errorf("token用量错误: %+v", usage)
+	}
+}
+
+func TestOpenAICompatibleProvidersKeepMaxTokens(t *testing.T) {
+	var captured capturedRequest
+	server := newProviderServer(t, http.StatusOK, `{"choices":[{"message":{"content":"ok"}}]}`, &captured)
+
+	client := New()
+	client.SetDeepSeekAPIKey("test-api-key-123456", server.URL, "")
+	client.ResponseSchema = &ResponseSchema{Name: "decisions", Schema: json.RawMessage(`{}`)}
+	if _, err := client.callOnce("system", "user"); err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	if captured.Body["temperature"] != 0.5 || captured.Body["max_tokens"] != 2000.0 {
+		t.Errorf("未配置时应使用默认生成参数: %v", captured.Body)
+	}
+	if _, ok := captured.Body["response_format"]; ok {
+		t.Error("DeepSeek不支持response_format，不应发送")
+	}
+}
+
+func TestAnthropicRequestMapping(t *testing.T) {
+	var captured capturedRequest
+	server := newProviderServer(t, http.StatusOK, `{
+		"content":[{"type":"text","text":"分析"},{"type":"text","text":"[]"}],
+		"stop_reason":"end_turn",
+		"usage":{"input_tokens":200,"output_tokens":40}
+	}`, &captured)
+
+	client := newTestClient(t, ProviderAnthropic, server.URL)
+	client.SetGenerationParams(1.5, 0)
+	content, err := client.callOnce("system", "user")
+	if err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	if content != "分析[]" {
+		t.Errorf("应拼接文本块: %q", content)
+	}
+	if captured.Path != "/messages" || captured.Header.Get("x-api-key") != "test-api-key-123456" || captured.Header.Get("anthropic-version") != anthropicVersion {
+		t.Errorf("请求地址或认证错误: %s %v", captured.Path, captured.Header)
+	}
+	if captured.Body["system"] != "system" || captured.Body["max_tokens"] != 512.0 || captured.Body["temperature"] != 1.0 {
+		t.Errorf("请求体错误（temperature应限制在1以内）: %v", captured.Body)
+	}
+	if usage := client.LastUsage(); usage != (Usage{PromptTokens: 200, CompletionTokens: 40, TotalTokens: 240}) {
+		t.Errorf("token用量错误: %+v", usage)
+	}
+}
+
+func TestGeminiRequestMapping(t *testing.T) {
+	var captured capturedRequest
+	server := newProviderServer(t, http.StatusOK, `{
+		"candidates":[{"content":{"role":"model","parts":[{"text":"[]"}]},"finishReason":"STOP"}],
+		"usageMetadata":{"promptTokenCount":300,"candidatesTokenCount":20,"totalTokenCount":320}
+	}`, &captured)
+
+	client := newTestClient(t, ProviderGemini, server.URL)
+	content, err := client.callOnce("system", "user")
+	if err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	if content != "[]" {
+		t.Errorf("响应内容错误: %q", content)
+	}
+	if captured.Path != "/models/test-model:generateContent" || captured.Header.Get("x-goog-api-key") != "test-api-key-123456" {
+		t.Errorf("请求地址或认证错误: %s %v", captured.Path, captured.Header)
+	}
+	config, _ := captured.Body["generationConfig"].(map[string]interface{})
+	if config["temperature"] != 0.2 || config["maxOutputTokens"] != 512.0 {
+		t.Errorf("生成参数错误: %v", config)
+	}
+	if _, ok := captured.Body["systemInstruction"]; !ok {
+		t.Error("应发送systemInstruction")
+	}
+	if usage := client.LastUsage(); usage != (Usage{PromptTokens: 300, CompletionTokens: 20, TotalTokens: 320}) {
+		t.Errorf("token用量错误: %+v", usage)
+	}
+
+	// 安全策略拦截
+	blocked := newProviderServer(t, http.StatusOK, `{"promptFeedback":{"blockReason":"SAFETY"}}`, &captured)
+	client = newTestClient(t, ProviderGemini, blocked.URL)
+	if _, err := client.callOnce("system", "user"); err == nil || !strings.Contains(err.Error(), "SAFETY") {
+		t.Errorf("应返回拦截原因: %v", err)
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		name      string
+		provider  Provider
+		status    int
+		body      string
+		kind      ErrorKind
+		code      string
+		retryable bool
+	}{
+		{"DeepSeek余额不足", ProviderDeepSeek, 402, `{"error":{"message":"Insufficient Balance","type":"unknown_error"}}`, ErrorBalance, "unknown_error", false},
+		{"OpenAI额度用尽", ProviderOpenAI, 429, `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`, ErrorBalance, "insufficient_quota", false},
+		{"OpenAI频率限制", ProviderOpenAI, 429, `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`, ErrorRateLimit, "rate_limit_exceeded", true},
+		{"Anthropic密钥无效", ProviderAnthropic, 401, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`, ErrorAuth, "authentication_error", false},
+		{"Anthropic过载", ProviderAnthropic, 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, ErrorOverloaded, "overloaded_error", true},
+		{"Anthropic余额不足", ProviderAnthropic, 400, `{"type":"error","error":{"type":"invalid_request_error","message":"Your credit balance is too low"}}`, ErrorBalance, "invalid_request_error", false},
+		{"Gemini密钥无效", ProviderGemini, 400, `{"error":{"code":400,"message":"API key not valid. Please pass a valid API key.","status":"INVALID_ARGUMENT"}}`, ErrorAuth, "INVALID_ARGUMENT", false},
+		{"Gemini配额", ProviderGemini, 429, `{"error":{"code":429,"message":"Quota exceeded","status":"RESOURCE_EXHAUSTED"}}`, ErrorRateLimit, "RESOURCE_EXHAUSTED", true},
+		{"Gemini服务端错误", ProviderGemini, 500, `{"error":{"code":500,"message":"Internal error","status":"INTERNAL"}}`, ErrorServer, "INTERNAL", true},
+		{"模型不存在", ProviderOpenAI, 404, `{"error":{"message":"The model does not exist","type":"invalid_request_error","code":"model_not_found"}}`, ErrorInvalidRequest, "model_not_found", false},
+		{"非JSON响应", ProviderCustom, 502, `Bad Gateway`, ErrorServer, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var captured capturedRequest
+			server := newProviderServer(t, tt.status, tt.body, &captured)
+			client := New()
+			if tt.provider == ProviderCustom {
+				client.SetCustomAPI(server.URL, "test-api-key-123456", "test-model")
+			} else if err := client.SetProviderAPIKey(tt.provider, "test-api-key-123456", server.URL, "test-model"); err != nil {
+				t.Fatalf("设置提供商失败: %v", err)
+			}
+
+			_, err := client.callOnce("system", "user")
+			if ErrorKindOf(err) != tt.kind {
+				t.Fatalf("错误分类应为%s: %v", tt.kind, err)
+			}
+			apiErr := err.(*APIError)
+			if apiErr.Code != tt.code || apiErr.StatusCode != tt.status || apiErr.RetryAfter != 7*time.Second {
+				t.Errorf("错误详情解析错误: %+v", apiErr)
+			}
+			if isRetryableError(err) != tt.retryable {
+				t.Errorf("可重试应为%v: %v", tt.retryable, err)
+			}
+		})
+	}
+
+	// 决策引擎按错误信息识别余额不足
+	balanceErr := &APIError{Provider: ProviderDeepSeek, StatusCode: 402, Kind: ErrorBalance, Message: "Insufficient Balance"}
+	if !strings.Contains(balanceErr.Error(), "余额不足") {
+		t.Errorf("余额不足错误信息应保持兼容: %s", balanceErr.Error())
+	}
+}
+
+func TestSetProviderAPIKey(t *testing.T) {
+	client := New()
+	if err := client.SetProviderAPIKey(ProviderGemini, "key", "", ""); err != nil {
+		t.Fatalf("设置失败: %v", err)
+	}
+	if client.BaseURL != "https://generativelanguage.googleapis.com/v1beta" || client.Model != "gemini-2.5-flash" {
+		t.Errorf("应使用默认地址和模型: %s %s", client.BaseURL, client.Model)
+	}
+	if err := client.SetProviderAPIKey("unknown", "key", "", ""); err == nil {
+		t.Error("未知提供商应返回错误")
+	}
+
+	client.SetGenerationParams(0, 0)
+	if client.Temperature != 0 || client.MaxTokens != defaultMaxTokens {
+		t.Errorf("temperature可设为0，maxTokens为0时保持默认: %v %d", client.Temperature, client.MaxTokens)
+	}
+}
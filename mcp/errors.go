@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorKind AI API错误分类
+type ErrorKind string
+
+const (
+	ErrorAuth           ErrorKind = "auth"            // API密钥无效或无权限
+	ErrorBalance        ErrorKind = "balance"         // 余额/额度不足
+	ErrorRateLimit      ErrorKind = "rate_limit"      // 请求频率超限
+	ErrorOverloaded     ErrorKind = "overloaded"      // 服务繁忙
+	ErrorServer         ErrorKind = "server"          // 服务端错误
+	ErrorInvalidRequest ErrorKind = "invalid_request" // 请求参数错误（模型不存在、上下文过长等）
+)
+
+// APIError AI API返回的错误（按提供商的错误格式解析并分类）
+type APIError struct {
+	Provider   Provider
+	StatusCode int
+	Kind       ErrorKind
+	Code       string        // 提供商的错误类型/代码（如 rate_limit_error、RESOURCE_EXHAUSTED）
+	Message    string        // 提供商返回的错误信息（无法解析时为原始响应）
+	RetryAfter time.Duration // 服务端要求的重试等待时间（Retry-After）
+}
+
+func (e *APIError) Error() string {
+	switch e.Kind {
+	case ErrorBalance:
+		return fmt.Sprintf("AI API余额不足 (Insufficient Balance), 请检查充值: [%s] %s", e.Provider, e.Message)
+	case ErrorAuth:
+		return fmt.Sprintf("AI API密钥无效 (Unauthorized), 请检查配置: [%s] %s", e.Provider, e.Message)
+	case ErrorRateLimit:
+		return fmt.Sprintf("AI API请求频率超限 (status %d): [%s] %s", e.StatusCode, e.Provider, e.Message)
+	case ErrorOverloaded:
+		return fmt.Sprintf("AI API服务繁忙 (status %d): [%s] %s", e.StatusCode, e.Provider, e.Message)
+	default:
+		return fmt.Sprintf("API返回错误 (status %d): [%s] %s", e.StatusCode, e.Provider, e.Message)
+	}
+}
+
+// Retryable 频率超限、服务繁忙和服务端错误可以重试
+func (e *APIError) Retryable() bool {
+	return e.Kind == ErrorRateLimit || e.Kind == ErrorOverloaded || e.Kind == ErrorServer
+}
+
+// ErrorKindOf 返回AI API错误的分类（非APIError返回空字符串）
+func ErrorKindOf(err error) ErrorKind {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind
+	}
+	return ""
+}
+
+// classifyError 按提供商的错误格式解析非200响应
+// OpenAI兼容:  {"error":{"message":"...","type":"...","code":"insufficient_quota"}}
+// Anthropic:  {"type":"error","error":{"type":"overloaded_error","message":"..."}}
+// Gemini:     {"error":{"code":429,"message":"...","status":"RESOURCE_EXHAUSTED"}}
+func classifyError(provider Provider, statusCode int, header http.Header, body []byte) *APIError {
+	apiErr := &APIError{Provider: provider, StatusCode: statusCode, Message: string(body)}
+
+	var parsed struct {
+		Error struct {
+			Message string          `json:"message"`
+			Type    string          `json:"type"`
+			Status  string          `json:"status"`
+			Code    json.RawMessage `json:"code"` // OpenAI为字符串，Gemini为数字
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &parsed); err == nil && parsed.Error.Message != "" {
+		apiErr.Message = parsed.Error.Message
+		switch {
+		case parsed.Error.Status != "":
+			apiErr.Code = parsed.Error.Status
+		case len(parsed.Error.Code) > 0 && parsed.Error.Code[0] == '"':
+			json.Unmarshal(parsed.Error.Code, &apiErr.Code)
+		default:
+			apiErr.Code = parsed.Error.Type
+		}
+	}
+
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	apiErr.Kind = errorKind(statusCode, apiErr.Code, apiErr.Message)
+	return apiErr
+}
+
+// errorKind 按状态码和提供商的错误代码分类
+func errorKind(statusCode int, code, message string) ErrorKind {
+	lowerMessage := strings.ToLower(message)
+	switch {
+	// OpenAI额度用尽返回429 insufficient_quota，Anthropic余额不足返回400并提示credit balance
+	case statusCode == http.StatusPaymentRequired || code == "insufficient_quota" ||
+		strings.Contains(lowerMessage, "credit balance") || strings.Contains(lowerMessage, "insufficient balance"):
+		return ErrorBalance
+	// Gemini密钥无效返回400 INVALID_ARGUMENT（API key not valid）
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden ||
+		code == "authentication_error" || code == "permission_error" ||
+		code == "UNAUTHENTICATED" || code == "PERMISSION_DENIED" || strings.Contains(lowerMessage, "api key not valid"):
+		return ErrorAuth
+	case statusCode == http.StatusTooManyRequests || code == "rate_limit_error" || code == "RESOURCE_EXHAUSTED":
+		return ErrorRateLimit
+	// Anthropic过载返回529
+	case statusCode == 529 || statusCode == http.StatusServiceUnavailable ||
+		code == "overloaded_error" || code == "UNAVAILABLE":
+		return ErrorOverloaded
+	case statusCode >= 500:
+		return ErrorServer
+	default:
+		return ErrorInvalidRequest
+	}
+}
@@ -0,0 +1,187 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+const (
+	// failoverMemberTimeout 故障转移链中单个提供商的请求超时（成员只调用一次，超时后由链切换到下一个提供商）
+	failoverMemberTimeout = 60 * time.Second
+	// singleProviderAttempts 链中只有一个提供商时的最大尝试次数（可重试的错误在等待后再试）
+	singleProviderAttempts = 2
+)
+
+// failoverRetryWait 重试同一提供商前的等待时间（服务端给出Retry-After时按其等待，最多30秒）
+var failoverRetryWait = 2 * time.Second
+
+// ProviderReporter 能报告最近一次成功调用由哪个提供商完成的AI客户端
+type ProviderReporter interface {
+	LastProvider() string
+}
+
+// FailoverMember 故障转移链中的一个AI模型
+type FailoverMember struct {
+	Name   string // 模型标识（记录在决策日志中）
+	Client AIClient
+}
+
+// FailoverClient 按顺序调用多个AI模型：跳过冷却中的提供商，调用失败时切换到下一个
+// 所有提供商都在冷却时尝试最早结束冷却的一个；只有一个提供商时由链重试可重试的错误；同一FailoverClient不应并发调用
+// 每次调用都记录到健康状态（单个提供商的交易员同样使用FailoverClient，以便在管理接口中查看）
+type FailoverClient struct {
+	Members []FailoverMember
+	Health  *HealthTracker
+
+	lastProvider string
+	lastUsage    CallUsage
+}
+
+// NewFailoverClient 创建故障转移客户端（使用全局健康状态）
+// *Client成员复制后改为只调用一次、超时不超过failoverMemberTimeout，避免一个超时的提供商在内部重试时占用整个链
+func NewFailoverClient(members []FailoverMember) *FailoverClient {
+	chain := make([]FailoverMember, len(members))
+	for i, member := range members {
+		if client, ok := member.Client.(*Client); ok {
+			single := *client
+			single.MaxRetries = 1
+			if single.Timeout <= 0 || single.Timeout > failoverMemberTimeout {
+				single.Timeout = failoverMemberTimeout
+			}
+			member.Client = &single
+		}
+		chain[i] = member
+	}
+	return &FailoverClient{Members: chain, Health: DefaultHealthTracker}
+}
+
+// healthIdentity 健康统计标识：*Client按提供商、模型和密钥后4位区分（同一账户的多个交易员共享冷却状态），其他客户端使用成员名称
+func healthIdentity(member FailoverMember) (key, provider, model string) {
+	client, ok := member.Client.(*Client)
+	if !ok {
+		return member.Name, member.Name, ""
+	}
+	suffix := client.APIKey
+	if len(suffix) > 4 {
+		suffix = suffix[len(suffix)-4:]
+	}
+	return fmt.Sprintf("%s/%s/****%s", client.Provider, client.Model, suffix), string(client.Provider), client.Model
+}
+
+// candidates 本次可调用的成员（保持配置顺序）
+func (f *FailoverClient) candidates() []FailoverMember {
+	var available, cooling []FailoverMember
+	for _, member := range f.Members {
+		key, _, _ := healthIdentity(member)
+		if f.Health.Available(key) {
+			available = append(available, member)
+		} else {
+			cooling = append(cooling, member)
+		}
+	}
+	if len(available) > 0 || len(cooling) == 0 {
+		return available
+	}
+	sort.SliceStable(cooling, func(i, j int) bool {
+		keyI, _, _ := healthIdentity(cooling[i])
+		keyJ, _, _ := healthIdentity(cooling[j])
+		return f.Health.CooldownUntil(keyI).Before(f.Health.CooldownUntil(keyJ))
+	})
+	log.Printf("⚠️ [MCP] 所有AI提供商都在冷却中，尝试最早恢复的 %s", cooling[0].Name)
+	return cooling[:1]
+}
+
+// CallWithMessages 依次调用可用的提供商，返回第一个成功的响应
+func (f *FailoverClient) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	return f.call(systemPrompt, userPrompt, nil)
+}
+
+// SupportsResponseSchema 首个可用的提供商是否支持结构化输出
+// 切换到不支持的提供商时以普通方式调用（决策解析同时兼容两种格式）
+func (f *FailoverClient) SupportsResponseSchema() bool {
+	candidates := f.candidates()
+	if len(candidates) == 0 {
+		return false
+	}
+	structured, ok := candidates[0].Client.(StructuredClient)
+	return ok && structured.SupportsResponseSchema()
+}
+
+// CallWithSchema 依次调用可用的提供商，支持结构化输出的提供商使用schema
+func (f *FailoverClient) CallWithSchema(systemPrompt, userPrompt string, schema *ResponseSchema) (string, error) {
+	return f.call(systemPrompt, userPrompt, schema)
+}
+
+func (f *FailoverClient) call(systemPrompt, userPrompt string, schema *ResponseSchema) (string, error) {
+	candidates := f.candidates()
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("未配置AI提供商")
+	}
+
+	attempts := len(candidates)
+	if len(f.Members) == 1 {
+		attempts = singleProviderAttempts
+	}
+
+	var errs []error
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		member := candidates[attempt%len(candidates)]
+		if attempt >= len(candidates) {
+			// 只有一个提供商：可重试的错误等待后再试一次
+			if !isRetryableError(lastErr) {
+				break
+			}
+			wait := failoverRetryWait
+			var apiErr *APIError
+			if errors.As(lastErr, &apiErr) && apiErr.RetryAfter > wait {
+				wait = min(apiErr.RetryAfter, 30*time.Second)
+			}
+			log.Printf("⏳ [MCP] AI提供商 %s 等待%v后重试...", member.Name, wait)
+			time.Sleep(wait)
+		}
+		key, provider, model := healthIdentity(member)
+		start := time.Now()
+		var result string
+		var err error
+		if structured, ok := member.Client.(StructuredClient); ok && schema != nil && structured.SupportsResponseSchema() {
+			result, err = structured.CallWithSchema(systemPrompt, userPrompt, schema)
+		} else {
+			result, err = member.Client.CallWithMessages(systemPrompt, userPrompt)
+		}
+		latency := time.Since(start)
+
+		if err != nil {
+			lastErr = err
+			f.Health.RecordFailure(key, provider, model, latency, err)
+			log.Printf("⚠️ [MCP] AI提供商 %s 调用失败: %v", member.Name, err)
+			errs = append(errs, fmt.Errorf("[%s] %w", member.Name, err))
+			continue
+		}
+
+		f.Health.RecordSuccess(key, provider, model, latency)
+		f.lastProvider = member.Name
+		f.lastUsage = CallUsage{Provider: provider, Model: model}
+		if reporter, ok := member.Client.(UsageReporter); ok {
+			f.lastUsage = reporter.LastCallUsage()
+		}
+		if member.Name != f.Members[0].Name {
+			log.Printf("🔀 [MCP] 使用备用AI提供商: %s", member.Name)
+		}
+		return result, nil
+	}
+	return "", fmt.Errorf("所有AI提供商调用失败: %w", errors.Join(errs...))
+}
+
+// LastProvider 最近一次成功调用的成员名称
+func (f *FailoverClient) LastProvider() string {
+	return f.lastProvider
+}
+
+// LastCallUsage 最近一次成功调用的token用量（含实际使用的提供商和模型）
+func (f *FailoverClient) LastCallUsage() CallUsage {
+	return f.lastUsage
+}
@@ -0,0 +1,207 @@
+package mcp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubClient 返回固定响应或错误，并记录调用次数
+type stubClient struct {
+	response string
+	err      error
+	calls    int
+}
+
+func (c *stubClient) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	c.calls++
+	return c.response, c.err
+}
+
+func newTestFailover(members ...FailoverMember) (*FailoverClient, *time.Time) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	health := NewHealthTracker()
+	health.now = func() time.Time { return now }
+	return &FailoverClient{Members: members, Health: health}, &now
+}
+
+func TestFailoverFallsBackInOrder(t *testing.T) {
+	primary := &stubClient{err: &APIError{Provider: ProviderDeepSeek, StatusCode: 402, Kind: ErrorBalance, Message: "Insufficient Balance"}}
+	backup := &stubClient{response: "[]"}
+	unused := &stubClient{response: "unused"}
+	client, now := newTestFailover(
+		FailoverMember{Name: "deepseek", Client: primary},
+		FailoverMember{Name: "openai", Client: backup},
+		FailoverMember{Name: "gemini", Client: unused},
+	)
+
+	result, err := client.CallWithMessages("system", "user")
+	if err != nil || result != "[]" || client.LastProvider() != "openai" {
+		t.Fatalf("应切换到第一个可用的备用模型: %q %v %s", result, err, client.LastProvider())
+	}
+	if usage := client.LastCallUsage(); usage.Provider != "openai" {
+		t.Errorf("token用量应来自实际使用的模型: %+v", usage)
+	}
+	if unused.calls != 0 {
+		t.Error("成功后不应继续调用后续模型")
+	}
+
+	// 余额不足立即进入冷却，下一周期直接跳过主模型
+	if client.Health.Available("deepseek") {
+		t.Fatal("余额不足应进入冷却")
+	}
+	client.CallWithMessages("system", "user")
+	if primary.calls != 1 || backup.calls != 2 {
+		t.Errorf("冷却中的模型应被跳过: primary=%d backup=%d", primary.calls, backup.calls)
+	}
+
+	// 冷却结束后恢复使用主模型
+	*now = now.Add(maxCooldown + time.Second)
+	primary.err = nil
+	primary.response = "primary"
+	if result, _ := client.CallWithMessages("system", "user"); result != "primary" || client.LastProvider() != "deepseek" {
+		t.Errorf("冷却结束后应恢复使用主模型: %q %s", result, client.LastProvider())
+	}
+}
+
+func TestFailoverAllProvidersFail(t *testing.T) {
+	client, _ := newTestFailover(
+		FailoverMember{Name: "a", Client: &stubClient{err: errors.New("timeout a")}},
+		FailoverMember{Name: "b", Client: &stubClient{err: errors.New("timeout b")}},
+	)
+	_, err := client.CallWithMessages("system", "user")
+	if err == nil || !strings.Contains(err.Error(), "[a] timeout a") || !strings.Contains(err.Error(), "[b] timeout b") {
+		t.Fatalf("应返回所有提供商的错误: %v", err)
+	}
+
+	// 连续失败3次后进入冷却；全部冷却时只尝试最早恢复的一个
+	client.CallWithMessages("system", "user")
+	client.CallWithMessages("system", "user")
+	if client.Health.Available("a") || client.Health.Available("b") {
+		t.Fatal("连续失败后应进入冷却")
+	}
+	a := client.Members[0].Client.(*stubClient)
+	b := client.Members[1].Client.(*stubClient)
+	client.CallWithMessages("system", "user")
+	if a.calls+b.calls != 7 {
+		t.Errorf("全部冷却时应只尝试一个提供商: a=%d b=%d", a.calls, b.calls)
+	}
+}
+
+func TestHealthTrackerStats(t *testing.T) {
+	tracker := NewHealthTracker()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	tracker.RecordSuccess("deepseek", "deepseek", "deepseek-chat", 100*time.Millisecond)
+	tracker.RecordSuccess("deepseek", "deepseek", "deepseek-chat", 200*time.Millisecond)
+	rateLimit := &APIError{Provider: ProviderDeepSeek, StatusCode: 429, Kind: ErrorRateLimit, RetryAfter: 5 * time.Minute}
+	for i := 0; i < cooldownFailureThreshold; i++ {
+		tracker.RecordFailure("deepseek", "deepseek", "deepseek-chat", 300*time.Millisecond, rateLimit)
+	}
+
+	health := tracker.Snapshot()
+	if len(health) != 1 {
+		t.Fatalf("应记录1个提供商: %+v", health)
+	}
+	h := health[0]
+	if h.Requests != 5 || h.Failures != 3 || h.ErrorRate != 0.6 || h.ConsecutiveFailures != 3 || h.LastErrorKind != ErrorRateLimit {
+		t.Errorf("统计错误: %+v", h)
+	}
+	if h.AvgLatencyMs <= 100 || h.AvgLatencyMs >= 300 {
+		t.Errorf("平均延迟错误: %.1f", h.AvgLatencyMs)
+	}
+	// 冷却时长不短于Retry-After
+	if !h.InCooldown || !h.CooldownUntil.Equal(now.Add(5*time.Minute)) {
+		t.Errorf("应按Retry-After冷却: %+v", h)
+	}
+
+	if !tracker.Reset("deepseek") || !tracker.Available("deepseek") || tracker.Reset("unknown") {
+		t.Error("重置后应恢复可用")
+	}
+	tracker.RecordFailure("deepseek", "deepseek", "deepseek-chat", 0, errors.New("timeout"))
+	if !tracker.Available("deepseek") {
+		t.Error("重置后单次普通失败不应进入冷却")
+	}
+}
+
+func TestHealthIdentitySharesAccount(t *testing.T) {
+	a, b := New(), New()
+	a.SetDeepSeekAPIKey("sk-test-key-abcd", "", "")
+	b.SetDeepSeekAPIKey("sk-test-key-abcd", "", "")
+	keyA, provider, model := healthIdentity(FailoverMember{Name: "trader1", Client: a})
+	keyB, _, _ := healthIdentity(FailoverMember{Name: "trader2", Client: b})
+	if keyA != keyB || keyA != "deepseek/deepseek-chat/****abcd" || provider != "deepseek" || model != "deepseek-chat" {
+		t.Errorf("同一账户应共享健康状态: %s %s", keyA, keyB)
+	}
+}
+
+// flakyClient 前failures次调用返回err，之后成功
+type flakyClient struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (c *flakyClient) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	c.calls++
+	if c.calls <= c.failures {
+		return "", c.err
+	}
+	return "ok", nil
+}
+
+func TestFailoverMembersCallOnce(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":{"message":"Internal error"}}`))
+	}))
+	defer server.Close()
+	primary := New()
+	primary.SetCustomAPI(server.URL, "test-api-key-123456", "test-model")
+
+	client := NewFailoverClient([]FailoverMember{
+		{Name: "primary", Client: primary},
+		{Name: "backup", Client: &stubClient{response: "[]"}},
+	})
+	client.Health = NewHealthTracker()
+	if member := client.Members[0].Client.(*Client); member.MaxRetries != 1 || member.Timeout != failoverMemberTimeout || primary.MaxRetries != 0 {
+		t.Fatalf("链中的客户端应只调用一次并缩短超时: %+v", member)
+	}
+
+	// 主模型服务端错误不在成员内部重试，由链直接切换到备用模型
+	if result, err := client.CallWithMessages("system", "user"); err != nil || result != "[]" {
+		t.Fatalf("应切换到备用模型: %q %v", result, err)
+	}
+	if requests != 1 {
+		t.Errorf("主模型应只请求一次, got %d", requests)
+	}
+}
+
+func TestFailoverSingleProviderRetries(t *testing.T) {
+	wait := failoverRetryWait
+	failoverRetryWait = 0
+	defer func() { failoverRetryWait = wait }()
+
+	// 只有一个提供商时由链重试可重试的错误，并记录健康状态
+	flaky := &flakyClient{failures: 1, err: errors.New("timeout")}
+	client, _ := newTestFailover(FailoverMember{Name: "deepseek", Client: flaky})
+	if result, err := client.CallWithMessages("system", "user"); err != nil || result != "ok" || flaky.calls != 2 {
+		t.Fatalf("可重试的错误应再试一次: %q %v calls=%d", result, err, flaky.calls)
+	}
+	if health := client.Health.Snapshot(); len(health) != 1 || health[0].Requests != 2 || health[0].Failures != 1 {
+		t.Errorf("单个提供商也应记录健康状态: %+v", health)
+	}
+
+	// 不可重试的错误不再重试
+	auth := &flakyClient{failures: 5, err: &APIError{Provider: ProviderOpenAI, StatusCode: 401, Kind: ErrorAuth, Message: "invalid key"}}
+	client, _ = newTestFailover(FailoverMember{Name: "openai", Client: auth})
+	if _, err := client.CallWithMessages("system", "user"); err == nil || auth.calls != 1 {
+		t.Errorf("密钥无效不应重试: calls=%d %v", auth.calls, err)
+	}
+}
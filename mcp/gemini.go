@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// geminiRequest Gemini generateContent请求
+type geminiRequest struct {
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent        `json:"contents"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature     float64 `json:"temperature"`
+	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// buildGeminiRequest 构建generateContent请求体
+func (client *Client) buildGeminiRequest(systemPrompt, userPrompt string) geminiRequest {
+	request := geminiRequest{
+		Contents: []geminiContent{{Role: "user", Parts: []geminiPart{{Text: userPrompt}}}},
+		GenerationConfig: geminiGenerationConfig{
+			Temperature:     client.Temperature,
+			MaxOutputTokens: client.MaxTokens,
+		},
+	}
+	if systemPrompt != "" {
+		request.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: systemPrompt}}}
+	}
+	return request
+}
+
+// callGemini 调用Gemini generateContent接口（模型名在URL路径中）
+func (client *Client) callGemini(systemPrompt, userPrompt string) (string, Usage, error) {
+	headers := map[string]string{"x-goog-api-key": client.APIKey}
+	path := fmt.Sprintf("/models/%s:generateContent", client.Model)
+	body, err := client.post(client.requestURL(path), headers, client.buildGeminiRequest(systemPrompt, userPrompt))
+	if err != nil {
+		return "", Usage{}, err
+	}
+
+	var result geminiResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", Usage{}, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if result.PromptFeedback.BlockReason != "" {
+		return "", Usage{}, fmt.Errorf("请求被Gemini安全策略拦截: %s", result.PromptFeedback.BlockReason)
+	}
+	if len(result.Candidates) == 0 {
+		return "", Usage{}, fmt.Errorf("API返回空响应")
+	}
+
+	var text strings.Builder
+	for _, part := range result.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+	if text.Len() == 0 {
+		return "", Usage{}, fmt.Errorf("API返回空响应 (finishReason: %s)", result.Candidates[0].FinishReason)
+	}
+
+	usage := Usage{
+		PromptTokens:     result.UsageMetadata.PromptTokenCount,
+		CompletionTokens: result.UsageMetadata.CandidatesTokenCount,
+		TotalTokens:      result.UsageMetadata.TotalTokenCount,
+	}
+	return text.String(), usage, nil
+}
@@ -0,0 +1,185 @@
+package mcp
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	healthWindowSize         = 20               // 错误率统计的最近调用次数
+	cooldownFailureThreshold = 3                // 连续失败达到该次数后进入冷却
+	baseCooldown             = time.Minute      // 首次冷却时长（之后每次翻倍）
+	maxCooldown              = 10 * time.Minute // 冷却时长上限
+	latencyEWMAWeight        = 0.2              // 平均延迟的指数平滑权重
+)
+
+// ProviderHealth AI提供商健康状态
+type ProviderHealth struct {
+	Key                 string    `json:"key"`                  // 提供商标识（提供商 + 模型 + 密钥后4位，同一账户的多个交易员共享）
+	Provider            string    `json:"provider"`             // 提供商类型
+	Model               string    `json:"model,omitempty"`      // 模型名称
+	Requests            int64     `json:"requests"`             // 累计调用次数
+	Failures            int64     `json:"failures"`             // 累计失败次数
+	ErrorRate           float64   `json:"error_rate"`           // 最近healthWindowSize次调用的错误率（0-1）
+	AvgLatencyMs        float64   `json:"avg_latency_ms"`       // 平均延迟（指数平滑，毫秒）
+	ConsecutiveFailures int       `json:"consecutive_failures"` // 连续失败次数
+	LastError           string    `json:"last_error,omitempty"` // 最近一次错误
+	LastErrorKind       ErrorKind `json:"last_error_kind,omitempty"`
+	LastSuccess         time.Time `json:"last_success,omitempty"`
+	LastFailure         time.Time `json:"last_failure,omitempty"`
+	CooldownUntil       time.Time `json:"cooldown_until,omitempty"` // 冷却结束时间（冷却期间故障转移时跳过）
+	InCooldown          bool      `json:"in_cooldown"`
+}
+
+// providerState 单个提供商的统计
+type providerState struct {
+	health    ProviderHealth
+	window    []bool // 最近的调用结果（true表示失败）
+	cooldowns int    // 连续进入冷却的次数（用于冷却时长翻倍）
+}
+
+// HealthTracker 记录各AI提供商的错误率和延迟，连续失败或余额不足/密钥无效时进入冷却
+type HealthTracker struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	providers map[string]*providerState
+}
+
+// DefaultHealthTracker 全局健康状态（所有交易员共享，供管理接口查询）
+var DefaultHealthTracker = NewHealthTracker()
+
+// NewHealthTracker 创建健康状态记录器
+func NewHealthTracker() *HealthTracker {
+	return &HealthTracker{now: time.Now, providers: make(map[string]*providerState)}
+}
+
+// state 返回提供商的统计（不存在时创建），调用方需持有锁
+func (t *HealthTracker) state(key, provider, model string) *providerState {
+	s, ok := t.providers[key]
+	if !ok {
+		s = &providerState{health: ProviderHealth{Key: key, Provider: provider, Model: model}}
+		t.providers[key] = s
+	}
+	return s
+}
+
+// record 更新调用次数、错误率窗口和平均延迟，调用方需持有锁
+func (s *providerState) record(failed bool, latency time.Duration) {
+	s.health.Requests++
+	if failed {
+		s.health.Failures++
+	}
+	s.window = append(s.window, failed)
+	if len(s.window) > healthWindowSize {
+		s.window = s.window[len(s.window)-healthWindowSize:]
+	}
+	failures := 0
+	for _, f := range s.window {
+		if f {
+			failures++
+		}
+	}
+	s.health.ErrorRate = float64(failures) / float64(len(s.window))
+
+	latencyMs := float64(latency) / float64(time.Millisecond)
+	if s.health.Requests == 1 {
+		s.health.AvgLatencyMs = latencyMs
+	} else {
+		s.health.AvgLatencyMs = s.health.AvgLatencyMs*(1-latencyEWMAWeight) + latencyMs*latencyEWMAWeight
+	}
+}
+
+// RecordSuccess 记录一次成功调用（清除连续失败和冷却）
+func (t *HealthTracker) RecordSuccess(key, provider, model string, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.state(key, provider, model)
+	s.record(false, latency)
+	s.health.ConsecutiveFailures = 0
+	s.health.LastSuccess = t.now()
+	s.health.CooldownUntil = time.Time{}
+	s.cooldowns = 0
+}
+
+// RecordFailure 记录一次失败调用
+// 余额不足和密钥无效需要人工处理，立即进入最长冷却；其他错误连续失败cooldownFailureThreshold次后进入冷却，
+// 冷却时长从baseCooldown开始翻倍，不短于服务端要求的Retry-After
+func (t *HealthTracker) RecordFailure(key, provider, model string, latency time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.state(key, provider, model)
+	s.record(true, latency)
+	now := t.now()
+	s.health.ConsecutiveFailures++
+	s.health.LastFailure = now
+	s.health.LastError = err.Error()
+	s.health.LastErrorKind = ErrorKindOf(err)
+
+	var cooldown time.Duration
+	switch {
+	case s.health.LastErrorKind == ErrorBalance || s.health.LastErrorKind == ErrorAuth:
+		cooldown = maxCooldown
+	case s.health.ConsecutiveFailures >= cooldownFailureThreshold:
+		cooldown = baseCooldown << s.cooldowns
+		if cooldown > maxCooldown || cooldown <= 0 {
+			cooldown = maxCooldown
+		}
+		s.cooldowns++
+	}
+	var apiErr *APIError
+	if cooldown > 0 && errors.As(err, &apiErr) && apiErr.RetryAfter > cooldown {
+		cooldown = apiErr.RetryAfter
+	}
+	if cooldown > 0 {
+		s.health.CooldownUntil = now.Add(cooldown)
+	}
+}
+
+// Available 提供商是否可用（不在冷却期）
+func (t *HealthTracker) Available(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.providers[key]
+	return !ok || !s.health.CooldownUntil.After(t.now())
+}
+
+// CooldownUntil 冷却结束时间（未冷却时为零值）
+func (t *HealthTracker) CooldownUntil(key string) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s, ok := t.providers[key]; ok {
+		return s.health.CooldownUntil
+	}
+	return time.Time{}
+}
+
+// Snapshot 所有提供商的健康状态（按标识排序）
+func (t *HealthTracker) Snapshot() []ProviderHealth {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	result := make([]ProviderHealth, 0, len(t.providers))
+	for _, s := range t.providers {
+		health := s.health
+		health.InCooldown = health.CooldownUntil.After(now)
+		result = append(result, health)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
+
+// Reset 清除提供商的冷却状态（如充值后手动恢复），不存在时返回false
+func (t *HealthTracker) Reset(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.providers[key]
+	if !ok {
+		return false
+	}
+	s.health.CooldownUntil = time.Time{}
+	s.health.ConsecutiveFailures = 0
+	s.cooldowns = 0
+	return true
+}
@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// openAIRequest OpenAI Chat Completions请求（DeepSeek / Qwen / 自定义API兼容此格式）
+type openAIRequest struct {
+	Model               string              `json:"model"`
+	Messages            []openAIMessage     `json:"messages"`
+	Temperature         float64             `json:"temperature"`
+	MaxTokens           int                 `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int                 `json:"max_completion_tokens,omitempty"`
+	ResponseFormat      *openAIResponseType `json:"response_format,omitempty"`
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIResponseType struct {
+	Type       string          `json:"type"`
+	JSONSchema *ResponseSchema `json:"json_schema,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+			Refusal string `json:"refusal"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// buildOpenAIRequest 构建Chat Completions请求体
+// OpenAI使用max_completion_tokens并支持response_format JSON schema；
+// DeepSeek/Qwen/自定义API只接受max_tokens，不发送response_format，通过prompt和后处理保证JSON格式
+func (client *Client) buildOpenAIRequest(systemPrompt, userPrompt string) openAIRequest {
+	messages := []openAIMessage{}
+	if systemPrompt != "" {
+		messages = append(messages, openAIMessage{Role: "system", Content: systemPrompt})
+	}
+	messages = append(messages, openAIMessage{Role: "user", Content: userPrompt})
+
+	request := openAIRequest{
+		Model:       client.Model,
+		Messages:    messages,
+		Temperature: client.Temperature,
+	}
+	if client.Provider == ProviderOpenAI {
+		request.MaxCompletionTokens = client.MaxTokens
+		if client.ResponseSchema != nil {
+			request.ResponseFormat = &openAIResponseType{Type: "json_schema", JSONSchema: client.ResponseSchema}
+		}
+	} else {
+		request.MaxTokens = client.MaxTokens
+	}
+	return request
+}
+
+// callOpenAI 调用OpenAI兼容的Chat Completions接口
+func (client *Client) callOpenAI(systemPrompt, userPrompt string) (string, Usage, error) {
+	headers := map[string]string{"Authorization": fmt.Sprintf("Bearer %s", client.APIKey)}
+	body, err := client.post(client.requestURL("/chat/completions"), headers, client.buildOpenAIRequest(systemPrompt, userPrompt))
+	if err != nil {
+		return "", Usage{}, err
+	}
+
+	var result openAIResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", Usage{}, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return "", Usage{}, fmt.Errorf("API返回空响应")
+	}
+	message := result.Choices[0].Message
+	if message.Content == "" && message.Refusal != "" {
+		return "", Usage{}, fmt.Errorf("模型拒绝回答: %s", message.Refusal)
+	}
+
+	usage := Usage{
+		PromptTokens:     result.Usage.PromptTokens,
+		CompletionTokens: result.Usage.CompletionTokens,
+		TotalTokens:      result.Usage.TotalTokens,
+	}
+	return message.Content, usage, nil
+}
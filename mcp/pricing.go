@@ -0,0 +1,75 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ModelPrice 模型单价（美元/百万token）
+type ModelPrice struct {
+	InputPerMillion  float64 `json:"input"`  // 输入（prompt）单价
+	OutputPerMillion float64 `json:"output"` // 输出（completion）单价
+}
+
+// PriceTable 模型单价表：先按模型名称匹配，再按提供商匹配（如自定义模型名称未列出时使用提供商的默认价格）
+type PriceTable map[string]ModelPrice
+
+// DefaultPriceTable 默认单价（各提供商默认模型的公开标价，可通过系统配置 ai_model_pricing 覆盖或补充）
+var DefaultPriceTable = PriceTable{
+	"deepseek-chat":     {InputPerMillion: 0.27, OutputPerMillion: 1.10},
+	"deepseek-reasoner": {InputPerMillion: 0.55, OutputPerMillion: 2.19},
+	"qwen-plus":         {InputPerMillion: 0.40, OutputPerMillion: 1.20},
+	"qwen-max":          {InputPerMillion: 1.60, OutputPerMillion: 6.40},
+	"claude-sonnet-4-5": {InputPerMillion: 3, OutputPerMillion: 15},
+	"gpt-4o":            {InputPerMillion: 2.50, OutputPerMillion: 10},
+	"gemini-2.5-flash":  {InputPerMillion: 0.30, OutputPerMillion: 2.50},
+
+	// 提供商默认价格（模型名称未列出时使用）
+	string(ProviderDeepSeek):  {InputPerMillion: 0.27, OutputPerMillion: 1.10},
+	string(ProviderQwen):      {InputPerMillion: 0.40, OutputPerMillion: 1.20},
+	string(ProviderAnthropic): {InputPerMillion: 3, OutputPerMillion: 15},
+	string(ProviderOpenAI):    {InputPerMillion: 2.50, OutputPerMillion: 10},
+	string(ProviderGemini):    {InputPerMillion: 0.30, OutputPerMillion: 2.50},
+}
+
+// ParsePriceTable 解析单价表配置（JSON: {"模型或提供商": {"input": 0.27, "output": 1.10}}），与默认单价合并，配置项优先
+func ParsePriceTable(raw string) (PriceTable, error) {
+	table := make(PriceTable, len(DefaultPriceTable))
+	for name, price := range DefaultPriceTable {
+		table[name] = price
+	}
+	if strings.TrimSpace(raw) == "" {
+		return table, nil
+	}
+
+	var overrides PriceTable
+	if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+		return nil, fmt.Errorf("解析模型单价配置失败: %w", err)
+	}
+	for name, price := range overrides {
+		if price.InputPerMillion < 0 || price.OutputPerMillion < 0 {
+			return nil, fmt.Errorf("模型单价不能为负数: %s", name)
+		}
+		table[name] = price
+	}
+	return table, nil
+}
+
+// Price 查找模型单价（模型名称优先，其次提供商），未找到时返回false
+func (t PriceTable) Price(provider, model string) (ModelPrice, bool) {
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+	price, ok := t[provider]
+	return price, ok
+}
+
+// Cost 计算一次调用的费用（美元），未配置单价时返回0和false
+func (t PriceTable) Cost(usage CallUsage) (float64, bool) {
+	price, ok := t.Price(usage.Provider, usage.Model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(usage.PromptTokens)*price.InputPerMillion + float64(usage.CompletionTokens)*price.OutputPerMillion) / 1e6, true
+}
@@ -0,0 +1,46 @@
+package mcp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPriceTableCost(t *testing.T) {
+	prices, err := ParsePriceTable(`{"deepseek-chat":{"input":1,"output":2},"my-model":{"input":0.5,"output":0.5}}`)
+	if err != nil {
+		t.Fatalf("解析单价失败: %v", err)
+	}
+
+	// 配置覆盖默认单价
+	cost, ok := prices.Cost(CallUsage{Provider: "deepseek", Model: "deepseek-chat", Usage: Usage{PromptTokens: 1_000_000, CompletionTokens: 500_000}})
+	if !ok || cost != 2 {
+		t.Errorf("应使用配置的单价: %v %v", cost, ok)
+	}
+	// 未列出的模型使用提供商默认价格
+	cost, ok = prices.Cost(CallUsage{Provider: "openai", Model: "gpt-custom", Usage: Usage{PromptTokens: 1000, CompletionTokens: 1000}})
+	if !ok || math.Abs(cost-0.0125) > 1e-12 {
+		t.Errorf("应使用提供商默认价格: %v %v", cost, ok)
+	}
+	if _, ok := prices.Cost(CallUsage{Provider: "custom", Model: "unknown"}); ok {
+		t.Error("未配置单价时应返回false")
+	}
+	if _, ok := DefaultPriceTable["my-model"]; ok {
+		t.Error("解析配置不应修改默认单价")
+	}
+
+	for _, raw := range []string{`not json`, `{"x":{"input":-1,"output":1}}`} {
+		if _, err := ParsePriceTable(raw); err == nil {
+			t.Errorf("无效配置应返回错误: %s", raw)
+		}
+	}
+}
+
+func TestClientLastCallUsage(t *testing.T) {
+	client := New()
+	client.SetDeepSeekAPIKey("sk-test", "", "")
+	client.lastUsage = Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}
+	usage := client.LastCallUsage()
+	if usage.Provider != "deepseek" || usage.Model != "deepseek-chat" || usage.TotalTokens != 15 {
+		t.Errorf("应返回提供商、模型和token用量: %+v", usage)
+	}
+}
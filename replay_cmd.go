@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"nofx/backtest"
+	"nofx/logger"
+)
+
+// runReplayCommand 用当前代码重放交易员的决策日志，报告解析和验证结果的变化（不调用AI）
+// 用法: nofx replay -dir decision_logs/<trader_id> [-fail-on-change]
+func runReplayCommand(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	dir := fs.String("dir", "", "决策日志目录（如 decision_logs/<trader_id>）")
+	btcEthLeverage := fs.Int("btc-eth-leverage", 5, "系统提示词中无法识别杠杆时使用的BTC/ETH杠杆上限")
+	altcoinLeverage := fs.Int("altcoin-leverage", 5, "系统提示词中无法识别杠杆时使用的山寨币杠杆上限")
+	includeUnchanged := fs.Bool("all", false, "报告中包含没有变化的周期")
+	failOnChange := fs.Bool("fail-on-change", false, "存在变化时以非零状态退出（用于回归测试）")
+	output := fs.String("out", "", "报告输出文件（为空则输出到标准输出）")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *dir == "" {
+		return fmt.Errorf("需要指定 -dir")
+	}
+
+	records, err := logger.LoadDecisionRecords(*dir)
+	if err != nil {
+		return err
+	}
+
+	report := backtest.ReplayRecords(records, backtest.ReplayOptions{
+		BTCETHLeverage:   *btcEthLeverage,
+		AltcoinLeverage:  *altcoinLeverage,
+		IncludeUnchanged: *includeUnchanged,
+	})
+
+	log.Printf("🔁 回放完成: %d 条记录, %d 条有AI输出, %d 条无变化", report.Records, report.Replayed, report.Unchanged)
+	log.Printf("   新拒绝 %d | 新通过 %d | 仓位变化 %d | 解析差异 %d",
+		report.NewlyRejected, report.NewlyAccepted, report.Resized, report.ParsedDifferently)
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化回放报告失败: %w", err)
+	}
+	if *output == "" {
+		fmt.Println(string(data))
+	} else {
+		if err := os.WriteFile(*output, data, 0644); err != nil {
+			return fmt.Errorf("写入回放报告失败: %w", err)
+		}
+		log.Printf("✓ 回放报告已保存: %s", *output)
+	}
+
+	if *failOnChange && report.Changed() {
+		return fmt.Errorf("%d 个周期的决策结果与日志不一致", report.Replayed-report.Unchanged)
+	}
+	return nil
+}
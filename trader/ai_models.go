@@ -0,0 +1,64 @@
+package trader
+
+import (
+	"fmt"
+
+	"nofx/mcp"
+)
+
+// ModelEndpoint 主模型之外的AI模型连接配置（集成决策成员、故障转移的备用模型）
+type ModelEndpoint struct {
+	Name            string   // 模型标识（记录在决策日志中）
+	Provider        string   // deepseek / qwen / anthropic / openai / gemini / custom
+	APIKey          string   // API密钥
+	CustomAPIURL    string   // 自定义API地址（为空时使用提供商默认地址）
+	CustomModelName string   // 自定义模型名称（为空时使用提供商默认模型）
+	Temperature     *float64 // 采样温度（nil表示默认）
+	MaxTokens       int      // 单次输出的最大token数（0表示默认）
+
+	Client mcp.AIClient // 注入的决策来源（回测/测试，设置后忽略以上连接配置）
+}
+
+// newModelClient 按模型配置创建AI客户端
+func newModelClient(model ModelEndpoint) (mcp.AIClient, error) {
+	if model.Client != nil {
+		return model.Client, nil
+	}
+	client := mcp.New()
+	if model.Provider == string(mcp.ProviderCustom) {
+		client.SetCustomAPI(model.CustomAPIURL, model.APIKey, model.CustomModelName)
+	} else if err := client.SetProviderAPIKey(mcp.Provider(model.Provider), model.APIKey, model.CustomAPIURL, model.CustomModelName); err != nil {
+		return nil, err
+	}
+	temperature := -1.0
+	if model.Temperature != nil {
+		temperature = *model.Temperature
+	}
+	client.SetGenerationParams(temperature, model.MaxTokens)
+	return client, nil
+}
+
+// newFailoverClient 创建故障转移链：主模型在前，备用模型按配置顺序
+// 未配置备用模型时，真实的AI客户端同样放入只有一个成员的链中（记录提供商健康状态）；注入的决策来源（回测/测试）直接返回
+func newFailoverClient(primaryName string, primary mcp.AIClient, fallbacks []ModelEndpoint) (mcp.AIClient, error) {
+	if len(fallbacks) == 0 {
+		if _, ok := primary.(*mcp.Client); !ok {
+			return primary, nil
+		}
+		return mcp.NewFailoverClient([]mcp.FailoverMember{{Name: primaryName, Client: primary}}), nil
+	}
+	members := []mcp.FailoverMember{{Name: primaryName, Client: primary}}
+	seen := map[string]bool{primaryName: true}
+	for _, model := range fallbacks {
+		if seen[model.Name] {
+			return nil, fmt.Errorf("备用模型重复: %s", model.Name)
+		}
+		seen[model.Name] = true
+		client, err := newModelClient(model)
+		if err != nil {
+			return nil, fmt.Errorf("创建备用模型 %s 失败: %w", model.Name, err)
+		}
+		members = append(members, mcp.FailoverMember{Name: model.Name, Client: client})
+	}
+	return mcp.NewFailoverClient(members), nil
+}
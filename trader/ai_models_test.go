@@ -0,0 +1,46 @@
+package trader
+
+import (
+	"testing"
+	"time"
+
+	"nofx/mcp"
+)
+
+func TestNewFailoverClient(t *testing.T) {
+	primary := staticAIClient{response: "[]"}
+	if client, err := newFailoverClient("deepseek", primary, nil); err != nil || client != primary {
+		t.Fatalf("未配置备用模型时应直接使用注入的决策来源: %v", err)
+	}
+
+	// 真实的AI客户端没有备用模型时也放入故障转移链，记录健康状态；链中只调用一次、超时缩短
+	real := mcp.New()
+	real.SetDeepSeekAPIKey("sk-test-key-abcd", "", "")
+	client, err := newFailoverClient("deepseek", real, nil)
+	single, ok := client.(*mcp.FailoverClient)
+	if err != nil || !ok || len(single.Members) != 1 || single.Health != mcp.DefaultHealthTracker {
+		t.Fatalf("未配置备用模型时应使用单成员故障转移链: %+v %v", client, err)
+	}
+	if member := single.Members[0].Client.(*mcp.Client); member.MaxRetries != 1 || member.Timeout > time.Minute || real.MaxRetries != 0 {
+		t.Errorf("链中的客户端应只调用一次且不修改原客户端: %+v", member)
+	}
+
+	client, err = newFailoverClient("deepseek", primary, []ModelEndpoint{
+		{Name: "user_gemini", Provider: "gemini", APIKey: "key"},
+		{Name: "local", Client: staticAIClient{response: "[]"}},
+	})
+	if err != nil {
+		t.Fatalf("创建故障转移链失败: %v", err)
+	}
+	failover, ok := client.(*mcp.FailoverClient)
+	if !ok || len(failover.Members) != 3 || failover.Members[0].Name != "deepseek" || failover.Members[1].Name != "user_gemini" {
+		t.Fatalf("主模型应在前，备用模型按配置顺序: %+v", client)
+	}
+	if gemini, ok := failover.Members[1].Client.(*mcp.Client); !ok || gemini.Provider != mcp.ProviderGemini {
+		t.Errorf("应按模型配置创建客户端: %+v", failover.Members[1].Client)
+	}
+
+	if _, err := newFailoverClient("deepseek", primary, []ModelEndpoint{{Name: "deepseek", Provider: "deepseek"}}); err == nil {
+		t.Error("备用模型与主模型重复时应返回错误")
+	}
+}
@@ -0,0 +1,122 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"nofx/config"
+	"nofx/logger"
+	"nofx/mcp"
+)
+
+// aiCostLookback 按实际AI费用折算积分时统计的时间范围
+const aiCostLookback = 7 * 24 * time.Hour
+
+// AIUsageStore AI用量存储
+type AIUsageStore interface {
+	SaveAIUsage(records []config.AIUsageRecord) error
+	GetAverageCycleAICost(traderID string, since time.Time) (float64, int, error)
+}
+
+// aggregateAIUsage 按提供商和模型汇总本周期各次调用的token用量并计算费用，返回汇总记录和未配置单价的模型
+func aggregateAIUsage(usage []mcp.CallUsage, prices mcp.PriceTable) ([]config.AIUsageRecord, []string) {
+	byModel := make(map[string]*config.AIUsageRecord)
+	var order []string
+	unpriced := make(map[string]bool)
+	for _, call := range usage {
+		key := call.Provider + "/" + call.Model
+		r, ok := byModel[key]
+		if !ok {
+			r = &config.AIUsageRecord{Provider: call.Provider, Model: call.Model}
+			byModel[key] = r
+			order = append(order, key)
+		}
+		r.Calls++
+		r.PromptTokens += int64(call.PromptTokens)
+		r.CompletionTokens += int64(call.CompletionTokens)
+		cost, ok := prices.Cost(call)
+		if !ok {
+			unpriced[key] = true
+		}
+		r.CostUSD += cost
+	}
+
+	records := make([]config.AIUsageRecord, 0, len(order))
+	for _, key := range order {
+		records = append(records, *byModel[key])
+	}
+	missing := make([]string, 0, len(unpriced))
+	for key := range unpriced {
+		missing = append(missing, key)
+	}
+	sort.Strings(missing)
+	return records, missing
+}
+
+// recordAIUsage 记录本周期的AI用量：写入决策记录并保存到数据库
+func (at *AutoTrader) recordAIUsage(record *logger.DecisionRecord, usage []mcp.CallUsage) {
+	if len(usage) == 0 {
+		return
+	}
+	records, missing := aggregateAIUsage(usage, at.aiPrices)
+	if len(missing) > 0 {
+		log.Printf("⚠️ [%s] 未配置模型单价，费用按0计算: %s", at.name, strings.Join(missing, ", "))
+	}
+
+	now := at.now()
+	for i := range records {
+		records[i].UserID = at.userID
+		records[i].TraderID = at.id
+		records[i].CycleNumber = at.callCount
+		records[i].CreatedAt = now
+		record.PromptTokens += records[i].PromptTokens
+		record.CompletionTokens += records[i].CompletionTokens
+		record.AICostUSD += records[i].CostUSD
+	}
+	record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🧾 AI用量: %d次调用, 输入 %d / 输出 %d tokens, 费用 $%.4f",
+		len(usage), record.PromptTokens, record.CompletionTokens, record.AICostUSD))
+
+	if at.aiUsageStore == nil {
+		return
+	}
+	if err := at.aiUsageStore.SaveAIUsage(records); err != nil {
+		log.Printf("⚠️ [%s] %v", at.name, err)
+	}
+}
+
+// decisionPointsCost 每次决策消耗的积分
+// 默认使用固定的trading_decision_points_cost；trading_decision_points_per_usd大于0且有近期用量时，
+// 按近7天平均每周期的AI费用折算（向上取整，至少1积分）
+func (at *AutoTrader) decisionPointsCost() int {
+	cost := 1
+	if costStr, err := at.db.GetSystemConfig("trading_decision_points_cost"); err != nil {
+		log.Printf("⚠️ 获取积分配置失败: %v，使用默认值1", err)
+	} else if flat, _ := strconv.Atoi(costStr); flat > 0 {
+		cost = flat
+	}
+
+	rateStr, _ := at.db.GetSystemConfig("trading_decision_points_per_usd")
+	pointsPerUSD, _ := strconv.ParseFloat(rateStr, 64)
+	if pointsPerUSD <= 0 || at.aiUsageStore == nil {
+		return cost
+	}
+	avgCost, cycles, err := at.aiUsageStore.GetAverageCycleAICost(at.id, at.now().Add(-aiCostLookback))
+	if err != nil {
+		log.Printf("⚠️ %v，使用固定积分 %d", err, cost)
+		return cost
+	}
+	if cycles == 0 {
+		return cost
+	}
+	derived := int(math.Ceil(avgCost * pointsPerUSD))
+	if derived < 1 {
+		derived = 1
+	}
+	log.Printf("💳 近%d个周期平均AI费用 $%.4f，折算 %d 积分", cycles, avgCost, derived)
+	return derived
+}
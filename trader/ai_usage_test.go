@@ -0,0 +1,85 @@
+package trader
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/config"
+	"nofx/logger"
+	"nofx/mcp"
+)
+
+// memoryAIUsageStore 内存中的AI用量存储
+type memoryAIUsageStore struct {
+	records []config.AIUsageRecord
+}
+
+func (s *memoryAIUsageStore) SaveAIUsage(records []config.AIUsageRecord) error {
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *memoryAIUsageStore) GetAverageCycleAICost(traderID string, since time.Time) (float64, int, error) {
+	return 0, 0, nil
+}
+
+func TestAggregateAIUsage(t *testing.T) {
+	prices := mcp.PriceTable{"deepseek-chat": {InputPerMillion: 1, OutputPerMillion: 2}}
+	usage := []mcp.CallUsage{
+		{Provider: "deepseek", Model: "deepseek-chat", Usage: mcp.Usage{PromptTokens: 1000, CompletionTokens: 100}},
+		{Provider: "custom", Model: "local", Usage: mcp.Usage{PromptTokens: 500, CompletionTokens: 50}},
+		{Provider: "deepseek", Model: "deepseek-chat", Usage: mcp.Usage{PromptTokens: 2000, CompletionTokens: 200}}, // 修复请求
+	}
+
+	records, missing := aggregateAIUsage(usage, prices)
+	if len(records) != 2 || records[0].Model != "deepseek-chat" || records[1].Model != "local" {
+		t.Fatalf("应按模型汇总并保持调用顺序: %+v", records)
+	}
+	r := records[0]
+	if r.Calls != 2 || r.PromptTokens != 3000 || r.CompletionTokens != 300 || math.Abs(r.CostUSD-0.0036) > 1e-12 {
+		t.Errorf("汇总结果错误: %+v", r)
+	}
+	if records[1].CostUSD != 0 || len(missing) != 1 || missing[0] != "custom/local" {
+		t.Errorf("未配置单价的模型费用应为0: %+v %v", records[1], missing)
+	}
+}
+
+func TestRecordAIUsage(t *testing.T) {
+	store := &memoryAIUsageStore{}
+	now := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	at := &AutoTrader{
+		id:           "trader1",
+		userID:       "user1",
+		name:         "test",
+		callCount:    7,
+		clock:        func() time.Time { return now },
+		aiPrices:     mcp.PriceTable{"deepseek": {InputPerMillion: 1, OutputPerMillion: 2}},
+		aiUsageStore: store,
+	}
+	record := &logger.DecisionRecord{}
+	at.recordAIUsage(record, []mcp.CallUsage{
+		{Provider: "deepseek", Model: "deepseek-chat", Usage: mcp.Usage{PromptTokens: 1_000_000, CompletionTokens: 500_000}},
+	})
+
+	if record.PromptTokens != 1_000_000 || record.CompletionTokens != 500_000 || record.AICostUSD != 2 {
+		t.Errorf("决策记录应包含本周期用量: %+v", record)
+	}
+	if len(record.ExecutionLog) != 1 || !strings.Contains(record.ExecutionLog[0], "$2.0000") {
+		t.Errorf("执行日志应包含费用: %v", record.ExecutionLog)
+	}
+	if len(store.records) != 1 {
+		t.Fatalf("应保存1条用量记录: %+v", store.records)
+	}
+	saved := store.records[0]
+	if saved.UserID != "user1" || saved.TraderID != "trader1" || saved.CycleNumber != 7 || !saved.CreatedAt.Equal(now) {
+		t.Errorf("用量记录应按交易员和用户保存: %+v", saved)
+	}
+
+	// 没有AI调用时不记录
+	at.recordAIUsage(&logger.DecisionRecord{}, nil)
+	if len(store.records) != 1 {
+		t.Error("没有用量时不应保存记录")
+	}
+}
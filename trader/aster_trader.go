@@ -36,6 +36,9 @@ type AsterTrader struct {
 	// 缓存交易对精度信息
 	symbolPrecision map[string]SymbolPrecision
 	mu              sync.RWMutex
+
+	// 跟踪止损在本地模拟
+	trailing *trailingStopEmulator
 }
 
 // SymbolPrecision 交易对精度信息
@@ -57,7 +60,7 @@ func NewAsterTrader(user, signer, privateKeyHex string) (*AsterTrader, error) {
 		return nil, fmt.Errorf("解析私钥失败: %w", err)
 	}
 
-	return &AsterTrader{
+	t := &AsterTrader{
 		ctx:             context.Background(),
 		user:            user,
 		signer:          signer,
@@ -72,7 +75,9 @@ func NewAsterTrader(user, signer, privateKeyHex string) (*AsterTrader, error) {
 			},
 		},
 		baseURL: "https://fapi.asterdex.com",
-	}, nil
+	}
+	t.trailing = newTrailingStopEmulator("Aster", t)
+	return t, nil
 }
 
 // genNonce 生成微秒时间戳
@@ -182,13 +187,13 @@ func (t *AsterTrader) formatQuantity(symbol string, quantity float64) (float64,
 	}
 
 	// 优先使用step size，确保数量是step size的整数倍
+	// 数量向下取整，避免四舍五入后超出持仓数量（单向持仓模式下会反向开仓）
 	if prec.StepSize > 0 {
-		return roundToTickSize(quantity, prec.StepSize), nil
+		return floorToStep(quantity, prec.StepSize), nil
 	}
 
-	// 如果没有step size，则按精度四舍五入
-	multiplier := math.Pow10(prec.QuantityPrecision)
-	return math.Round(quantity*multiplier) / multiplier, nil
+	// 如果没有step size，则按精度向下取整
+	return floorToStep(quantity, math.Pow10(-prec.QuantityPrecision)), nil
 }
 
 // formatFloatWithPrecision 将浮点数格式化为指定精度的字符串（去除末尾的0）
@@ -426,77 +431,72 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 }
 
 // GetBalance 获取账户余额
-func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
+func (t *AsterTrader) GetBalance() (*Balance, error) {
 	params := make(map[string]interface{})
 	body, err := t.request("GET", "/fapi/v3/balance", params)
 	if err != nil {
 		return nil, err
 	}
+	return parseAsterBalance(body)
+}
 
-	var balances []map[string]interface{}
+// parseAsterBalance 解析余额响应（只统计USDT）
+func parseAsterBalance(body []byte) (*Balance, error) {
+	var balances []struct {
+		Asset            string `json:"asset"`
+		Balance          string `json:"balance"`
+		AvailableBalance string `json:"availableBalance"`
+		CrossUnPnl       string `json:"crossUnPnl"`
+	}
 	if err := json.Unmarshal(body, &balances); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("解析余额失败: %w", err)
 	}
 
-	// 查找USDT余额
-	totalBalance := 0.0
-	availableBalance := 0.0
-	crossUnPnl := 0.0
-
+	balance := &Balance{}
 	for _, bal := range balances {
-		if asset, ok := bal["asset"].(string); ok && asset == "USDT" {
-			if wb, ok := bal["balance"].(string); ok {
-				totalBalance, _ = strconv.ParseFloat(wb, 64)
-			}
-			if avail, ok := bal["availableBalance"].(string); ok {
-				availableBalance, _ = strconv.ParseFloat(avail, 64)
-			}
-			if unpnl, ok := bal["crossUnPnl"].(string); ok {
-				crossUnPnl, _ = strconv.ParseFloat(unpnl, 64)
-			}
+		if bal.Asset == "USDT" {
+			balance.WalletBalance = parseFloatOrZero(bal.Balance)
+			balance.AvailableBalance = parseFloatOrZero(bal.AvailableBalance)
+			balance.UnrealizedProfit = parseFloatOrZero(bal.CrossUnPnl)
 			break
 		}
 	}
-
-	// 返回与Binance相同的字段名，确保AutoTrader能正确解析
-	return map[string]interface{}{
-		"totalWalletBalance":    totalBalance,
-		"availableBalance":      availableBalance,
-		"totalUnrealizedProfit": crossUnPnl,
-	}, nil
+	return balance, nil
 }
 
 // GetPositions 获取持仓信息
-func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
+func (t *AsterTrader) GetPositions() ([]Position, error) {
 	params := make(map[string]interface{})
 	body, err := t.request("GET", "/fapi/v3/positionRisk", params)
 	if err != nil {
 		return nil, err
 	}
+	return parseAsterPositions(body)
+}
 
-	var positions []map[string]interface{}
+// parseAsterPositions 解析持仓响应（跳过空仓位）
+func parseAsterPositions(body []byte) ([]Position, error) {
+	var positions []struct {
+		Symbol           string `json:"symbol"`
+		PositionAmt      string `json:"positionAmt"`
+		EntryPrice       string `json:"entryPrice"`
+		MarkPrice        string `json:"markPrice"`
+		UnRealizedProfit string `json:"unRealizedProfit"`
+		Leverage         string `json:"leverage"`
+		LiquidationPrice string `json:"liquidationPrice"`
+		MarginType       string `json:"marginType"`
+	}
 	if err := json.Unmarshal(body, &positions); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("解析持仓失败: %w", err)
 	}
 
-	result := []map[string]interface{}{}
+	result := []Position{}
 	for _, pos := range positions {
-		posAmtStr, ok := pos["positionAmt"].(string)
-		if !ok {
-			continue
-		}
-
-		posAmt, _ := strconv.ParseFloat(posAmtStr, 64)
+		posAmt := parseFloatOrZero(pos.PositionAmt)
 		if posAmt == 0 {
 			continue // 跳过空仓位
 		}
 
-		entryPrice, _ := strconv.ParseFloat(pos["entryPrice"].(string), 64)
-		markPrice, _ := strconv.ParseFloat(pos["markPrice"].(string), 64)
-		unRealizedProfit, _ := strconv.ParseFloat(pos["unRealizedProfit"].(string), 64)
-		leverageVal, _ := strconv.ParseFloat(pos["leverage"].(string), 64)
-		liquidationPrice, _ := strconv.ParseFloat(pos["liquidationPrice"].(string), 64)
-
 		// 判断方向（与Binance一致）
 		side := "long"
 		if posAmt < 0 {
@@ -504,16 +504,16 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 			posAmt = -posAmt
 		}
 
-		// 返回与Binance相同的字段名
-		result = append(result, map[string]interface{}{
-			"symbol":           pos["symbol"],
-			"side":             side,
-			"positionAmt":      posAmt,
-			"entryPrice":       entryPrice,
-			"markPrice":        markPrice,
-			"unRealizedProfit": unRealizedProfit,
-			"leverage":         leverageVal,
-			"liquidationPrice": liquidationPrice,
+		result = append(result, Position{
+			Symbol:           pos.Symbol,
+			Side:             side,
+			Quantity:         posAmt,
+			EntryPrice:       parseFloatOrZero(pos.EntryPrice),
+			MarkPrice:        parseFloatOrZero(pos.MarkPrice),
+			UnrealizedProfit: parseFloatOrZero(pos.UnRealizedProfit),
+			Leverage:         int(parseFloatOrZero(pos.Leverage)),
+			LiquidationPrice: parseFloatOrZero(pos.LiquidationPrice),
+			MarginMode:       binanceMarginMode(pos.MarginType),
 		})
 	}
 
@@ -521,7 +521,7 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 }
 
 // OpenLong 开多单
-func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (*OrderResult, error) {
 	// 开仓前先取消所有挂单,防止残留挂单导致仓位叠加
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
@@ -579,8 +579,8 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (m
 		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
+	result, err := asterOrderResult(symbol, body)
+	if err != nil {
 		return nil, err
 	}
 
@@ -588,7 +588,7 @@ func (t *AsterTrader) OpenLong(symbol string, quantity float64, leverage int) (m
 }
 
 // OpenShort 开空单
-func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (*OrderResult, error) {
 	// 开仓前先取消所有挂单,防止残留挂单导致仓位叠加
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消挂单失败(继续开仓): %v", err)
@@ -646,8 +646,8 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (
 		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
+	result, err := asterOrderResult(symbol, body)
+	if err != nil {
 		return nil, err
 	}
 
@@ -655,7 +655,7 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (
 }
 
 // CloseLong 平多单
-func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *AsterTrader) CloseLong(symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -664,8 +664,8 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 		}
 
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
+			if pos.Symbol == symbol && pos.Side == "long" {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -714,6 +714,7 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 		"timeInForce":  "GTC",
 		"quantity":     qtyStr,
 		"price":        priceStr,
+		"reduceOnly":   "true", // 单向持仓模式，只减仓避免反向开仓
 	}
 
 	body, err := t.request("POST", "/fapi/v3/order", params)
@@ -721,8 +722,8 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
+	result, err := asterOrderResult(symbol, body)
+	if err != nil {
 		return nil, err
 	}
 
@@ -737,7 +738,7 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 }
 
 // CloseShort 平空单
-func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *AsterTrader) CloseShort(symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -746,9 +747,9 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 		}
 
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
+			if pos.Symbol == symbol && pos.Side == "short" {
 				// Aster的GetPositions已经将空仓数量转换为正数，直接使用
-				quantity = pos["positionAmt"].(float64)
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -797,6 +798,7 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 		"timeInForce":  "GTC",
 		"quantity":     qtyStr,
 		"price":        priceStr,
+		"reduceOnly":   "true", // 单向持仓模式，只减仓避免反向开仓
 	}
 
 	body, err := t.request("POST", "/fapi/v3/order", params)
@@ -804,8 +806,8 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
+	result, err := asterOrderResult(symbol, body)
+	if err != nil {
 		return nil, err
 	}
 
@@ -924,6 +926,7 @@ func (t *AsterTrader) SetStopLoss(symbol string, positionSide string, quantity,
 		"stopPrice":    priceStr,
 		"quantity":     qtyStr,
 		"timeInForce":  "GTC",
+		"reduceOnly":   "true", // 无持仓时触发不应开新仓
 	}
 
 	_, err = t.request("POST", "/fapi/v3/order", params)
@@ -965,14 +968,29 @@ func (t *AsterTrader) SetTakeProfit(symbol string, positionSide string, quantity
 		"stopPrice":    priceStr,
 		"quantity":     qtyStr,
 		"timeInForce":  "GTC",
+		"reduceOnly":   "true", // 无持仓时触发不应开新仓
 	}
 
 	_, err = t.request("POST", "/fapi/v3/order", params)
 	return err
 }
 
+// trailingStops 本地跟踪止损模拟器（由AutoTrader接管触发平仓和持久化）
+func (t *AsterTrader) trailingStops() *trailingStopEmulator {
+	return t.trailing
+}
+
+// SetTrailingStop 设置跟踪止损（本地根据实时行情模拟，触发后市价平仓）
+func (t *AsterTrader) SetTrailingStop(symbol string, positionSide string, quantity, callbackRate, activationPrice float64) error {
+	if err := t.trailing.Set(symbol, positionSide, quantity, callbackRate, activationPrice); err != nil {
+		return fmt.Errorf("设置跟踪止损失败: %w", err)
+	}
+	return nil
+}
+
 // CancelAllOrders 取消所有订单
 func (t *AsterTrader) CancelAllOrders(symbol string) error {
+	t.trailing.Cancel(symbol)
 	params := map[string]interface{}{
 		"symbol": symbol,
 	}
@@ -987,5 +1005,390 @@ func (t *AsterTrader) FormatQuantity(symbol string, quantity float64) (string, e
 	if err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("%v", formatted), nil
+	prec, err := t.getPrecision(symbol)
+	if err != nil {
+		return "", err
+	}
+	// 按精度输出，避免浮点误差（如0.013000000000000001）
+	return t.formatFloatWithPrecision(formatted, prec.QuantityPrecision), nil
+}
+
+// asterTimeInForce 转换为Aster的有效方式（与币安一致，post-only对应GTX）
+func asterTimeInForce(tif TimeInForce) (string, error) {
+	switch tif {
+	case TimeInForceGTC, "":
+		return "GTC", nil
+	case TimeInForceIOC:
+		return "IOC", nil
+	case TimeInForceFOK:
+		return "FOK", nil
+	case TimeInForcePostOnly:
+		return "GTX", nil
+	default:
+		return "", fmt.Errorf("不支持的限价单有效方式: %s", tif)
+	}
+}
+
+// asterOrderResult 解析下单响应
+func asterOrderResult(symbol string, body []byte) (*OrderResult, error) {
+	var order asterOrder
+	if err := json.Unmarshal(body, &order); err != nil {
+		return nil, fmt.Errorf("解析订单响应失败: %w", err)
+	}
+	if order.Symbol == "" {
+		order.Symbol = symbol
+	}
+	return order.toOrderResult(), nil
+}
+
+// placeLimitOrder 提交限价单（side: BUY/SELL）
+func (t *AsterTrader) placeLimitOrder(symbol, side string, quantity, price float64, timeInForce string) (*OrderResult, error) {
+	formattedPrice, err := t.formatPrice(symbol, price)
+	if err != nil {
+		return nil, err
+	}
+	formattedQty, err := t.formatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	prec, err := t.getPrecision(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	priceStr := t.formatFloatWithPrecision(formattedPrice, prec.PricePrecision)
+	qtyStr := t.formatFloatWithPrecision(formattedQty, prec.QuantityPrecision)
+
+	params := map[string]interface{}{
+		"symbol":       symbol,
+		"positionSide": "BOTH",
+		"type":         "LIMIT",
+		"side":         side,
+		"timeInForce":  timeInForce,
+		"quantity":     qtyStr,
+		"price":        priceStr,
+	}
+
+	body, err := t.request("POST", "/fapi/v3/order", params)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := asterOrderResult(symbol, body)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("✓ 限价单已提交: %s %s 数量: %s 价格: %s (%s) 状态: %v", symbol, side, qtyStr, priceStr, timeInForce, result.Status)
+	return result, nil
+}
+
+// PlaceLimitOrder 下限价开仓单
+func (t *AsterTrader) PlaceLimitOrder(symbol string, positionSide string, quantity, price float64, leverage int, tif TimeInForce) (*OrderResult, error) {
+	timeInForce, err := asterTimeInForce(tif)
+	if err != nil {
+		return nil, err
+	}
+
+	// 设置杠杆（限价单不取消旧委托单，避免撤掉其他挂单）
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, fmt.Errorf("设置杠杆失败: %w", err)
+	}
+
+	side := "BUY"
+	if positionSide == "SHORT" {
+		side = "SELL"
+	}
+	return t.placeLimitOrder(symbol, side, quantity, price, timeInForce)
+}
+
+// AmendOrder 修改未成交限价单的价格和数量
+// Aster没有改单接口，通过撤单后按原方向和有效方式重新下单实现（订单ID会变化）
+func (t *AsterTrader) AmendOrder(symbol string, orderID string, quantity, price float64) (*OrderResult, error) {
+	body, err := t.request("GET", "/fapi/v3/order", map[string]interface{}{
+		"symbol":  symbol,
+		"orderId": orderID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	var existing struct {
+		Side        string `json:"side"`
+		Status      string `json:"status"`
+		Price       string `json:"price"`
+		OrigQty     string `json:"origQty"`
+		ExecutedQty string `json:"executedQty"`
+		TimeInForce string `json:"timeInForce"`
+	}
+	if err := json.Unmarshal(body, &existing); err != nil {
+		return nil, fmt.Errorf("解析订单失败: %w", err)
+	}
+	if existing.Status != "NEW" && existing.Status != "PARTIALLY_FILLED" {
+		return nil, fmt.Errorf("订单 %s 状态为 %s，无法修改", orderID, existing.Status)
+	}
+
+	// 默认使用剩余未成交数量和原价格
+	if quantity <= 0 {
+		origQty, _ := strconv.ParseFloat(existing.OrigQty, 64)
+		executedQty, _ := strconv.ParseFloat(existing.ExecutedQty, 64)
+		quantity = origQty - executedQty
+	}
+	if price <= 0 {
+		price, _ = strconv.ParseFloat(existing.Price, 64)
+	}
+
+	if err := t.CancelOrder(symbol, orderID); err != nil {
+		return nil, err
+	}
+	result, err := t.placeLimitOrder(symbol, existing.Side, quantity, price, existing.TimeInForce)
+	if err != nil {
+		return nil, fmt.Errorf("撤单后重新下单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已修改订单 %s -> %s", orderID, result.OrderID)
+	return result, nil
+}
+
+// CancelOrder 取消单个订单
+func (t *AsterTrader) CancelOrder(symbol string, orderID string) error {
+	params := map[string]interface{}{
+		"symbol":  symbol,
+		"orderId": orderID,
+	}
+
+	if _, err := t.request("DELETE", "/fapi/v3/order", params); err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已取消 %s 订单 %s", symbol, orderID)
+	return nil
+}
+
+// asterOrder Aster订单查询结果
+type asterOrder struct {
+	OrderID      json.Number `json:"orderId"`
+	Symbol       string      `json:"symbol"`
+	Status       string      `json:"status"`
+	Type         string      `json:"type"`
+	Side         string      `json:"side"`
+	PositionSide string      `json:"positionSide"`
+	Price        string      `json:"price"`
+	StopPrice    string      `json:"stopPrice"`
+	OrigQty      string      `json:"origQty"`
+	ExecutedQty  string      `json:"executedQty"`
+	AvgPrice     string      `json:"avgPrice"`
+	Time         int64       `json:"time"`
+	UpdateTime   int64       `json:"updateTime"`
+}
+
+// toOrderResult 转换为统一格式
+func (o *asterOrder) toOrderResult() *OrderResult {
+	return &OrderResult{
+		OrderID:      o.OrderID.String(),
+		Symbol:       o.Symbol,
+		Status:       o.Status,
+		Type:         o.Type,
+		Side:         o.Side,
+		PositionSide: o.PositionSide,
+		Price:        parseFloatOrZero(o.Price),
+		StopPrice:    parseFloatOrZero(o.StopPrice),
+		Quantity:     parseFloatOrZero(o.OrigQty),
+		ExecutedQty:  parseFloatOrZero(o.ExecutedQty),
+		AvgPrice:     parseFloatOrZero(o.AvgPrice),
+		Time:         o.Time,
+		UpdateTime:   o.UpdateTime,
+	}
+}
+
+// GetOrder 查询单个订单
+func (t *AsterTrader) GetOrder(symbol string, orderID string) (*OrderResult, error) {
+	body, err := t.request("GET", "/fapi/v3/order", map[string]interface{}{
+		"symbol":  symbol,
+		"orderId": orderID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	var order asterOrder
+	if err := json.Unmarshal(body, &order); err != nil {
+		return nil, fmt.Errorf("解析订单失败: %w", err)
+	}
+	return order.toOrderResult(), nil
+}
+
+// GetOpenOrders 获取未成交挂单（symbol为空表示所有币种）
+func (t *AsterTrader) GetOpenOrders(symbol string) ([]OrderResult, error) {
+	params := map[string]interface{}{}
+	if symbol != "" {
+		params["symbol"] = symbol
+	}
+
+	body, err := t.request("GET", "/fapi/v3/openOrders", params)
+	if err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	var orders []asterOrder
+	if err := json.Unmarshal(body, &orders); err != nil {
+		return nil, fmt.Errorf("解析挂单失败: %w", err)
+	}
+
+	result := make([]OrderResult, 0, len(orders))
+	for i := range orders {
+		result = append(result, *orders[i].toOrderResult())
+	}
+	return result, nil
+}
+
+// GetProtectiveOrders 获取止盈止损条件单（与币安相同，条件单在挂单列表中返回）
+func (t *AsterTrader) GetProtectiveOrders(symbol string) ([]ProtectiveOrder, error) {
+	orders, err := t.GetOpenOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return filterProtectiveOrders(orders, binanceProtectiveKind), nil
+}
+
+// CancelProtectiveOrder 取消单个止盈止损条件单
+func (t *AsterTrader) CancelProtectiveOrder(symbol string, orderID string) error {
+	return t.CancelOrder(symbol, orderID)
+}
+
+// GetFills 获取某币种自since以来的成交记录
+func (t *AsterTrader) GetFills(symbol string, since time.Time) ([]Fill, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("Aster查询成交记录需要指定币种")
+	}
+
+	body, err := t.request("GET", "/fapi/v3/userTrades", map[string]interface{}{
+		"symbol":    symbol,
+		"startTime": since.UnixMilli(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取成交记录失败: %w", err)
+	}
+	return parseAsterFills(body)
+}
+
+// parseAsterFills 解析成交记录响应
+func parseAsterFills(body []byte) ([]Fill, error) {
+	var trades []struct {
+		ID              json.Number `json:"id"`
+		OrderID         json.Number `json:"orderId"`
+		Symbol          string      `json:"symbol"`
+		Side            string      `json:"side"`
+		PositionSide    string      `json:"positionSide"`
+		Price           string      `json:"price"`
+		Qty             string      `json:"qty"`
+		Commission      string      `json:"commission"`
+		CommissionAsset string      `json:"commissionAsset"`
+		Maker           bool        `json:"maker"`
+		Time            int64       `json:"time"`
+	}
+	if err := json.Unmarshal(body, &trades); err != nil {
+		return nil, fmt.Errorf("解析成交记录失败: %w", err)
+	}
+
+	fills := make([]Fill, 0, len(trades))
+	for _, trade := range trades {
+		role := "taker"
+		if trade.Maker {
+			role = "maker"
+		}
+
+		fills = append(fills, Fill{
+			FillID:       trade.ID.String(),
+			OrderID:      trade.OrderID.String(),
+			Symbol:       trade.Symbol,
+			Side:         strings.ToLower(trade.Side),
+			PositionSide: trade.PositionSide,
+			Price:        parseFloatOrZero(trade.Price),
+			Quantity:     parseFloatOrZero(trade.Qty),
+			Fee:          parseFloatOrZero(trade.Commission),
+			FeeCurrency:  trade.CommissionAsset,
+			Role:         role,
+			Timestamp:    trade.Time,
+		})
+	}
+	return fills, nil
+}
+
+// GetFundingPayments 获取某币种自since以来的资金费结算记录（收益流水中的FUNDING_FEE）
+func (t *AsterTrader) GetFundingPayments(symbol string, since time.Time) ([]FundingPayment, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("Aster查询资金费需要指定币种")
+	}
+
+	body, err := t.request("GET", "/fapi/v3/income", map[string]interface{}{
+		"symbol":     symbol,
+		"incomeType": "FUNDING_FEE",
+		"startTime":  since.UnixMilli(),
+		"limit":      1000,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费记录失败: %w", err)
+	}
+	return parseAsterFundingPayments(body)
+}
+
+// parseAsterFundingPayments 解析收益流水响应（income为正表示收到）
+func parseAsterFundingPayments(body []byte) ([]FundingPayment, error) {
+	var incomes []struct {
+		Symbol     string `json:"symbol"`
+		IncomeType string `json:"incomeType"`
+		Income     string `json:"income"`
+		Time       int64  `json:"time"`
+	}
+	if err := json.Unmarshal(body, &incomes); err != nil {
+		return nil, fmt.Errorf("解析资金费记录失败: %w", err)
+	}
+
+	payments := make([]FundingPayment, 0, len(incomes))
+	for _, income := range incomes {
+		if income.IncomeType != "" && income.IncomeType != "FUNDING_FEE" {
+			continue
+		}
+		payments = append(payments, FundingPayment{
+			Symbol:    income.Symbol,
+			Amount:    parseFloatOrZero(income.Income),
+			Timestamp: income.Time,
+		})
+	}
+	return payments, nil
+}
+
+// GetTransfers 获取自since以来合约账户的USDT划转记录（收益流水中的TRANSFER）
+func (t *AsterTrader) GetTransfers(since time.Time) ([]Transfer, error) {
+	body, err := t.request("GET", "/fapi/v3/income", map[string]interface{}{
+		"incomeType": "TRANSFER",
+		"startTime":  since.UnixMilli(),
+		"limit":      1000,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取划转记录失败: %w", err)
+	}
+	return parseAsterTransfers(body)
+}
+
+// parseAsterTransfers 解析收益流水中的USDT划转记录（income为正表示转入）
+func parseAsterTransfers(body []byte) ([]Transfer, error) {
+	var incomes []struct {
+		Asset      string `json:"asset"`
+		IncomeType string `json:"incomeType"`
+		Income     string `json:"income"`
+		Time       int64  `json:"time"`
+	}
+	if err := json.Unmarshal(body, &incomes); err != nil {
+		return nil, fmt.Errorf("解析划转记录失败: %w", err)
+	}
+
+	transfers := make([]Transfer, 0, len(incomes))
+	for _, income := range incomes {
+		if income.IncomeType != "TRANSFER" || income.Asset != "USDT" {
+			continue
+		}
+		transfers = append(transfers, Transfer{Amount: parseFloatOrZero(income.Income), Timestamp: income.Time})
+	}
+	return transfers, nil
 }
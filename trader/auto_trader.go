@@ -12,8 +12,8 @@ import (
 	"nofx/mcp"
 	"nofx/pool"
 	"nofx/service/credits"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,10 +23,10 @@ type AutoTraderConfig struct {
 	ID      string // Trader唯一标识（用于日志目录等）
 	UserID  string // 所属用户ID
 	Name    string // Trader显示名称
-	AIModel string // AI模型: "qwen" 或 "deepseek"
+	AIModel string // AI模型: "qwen"、"deepseek"、"anthropic"、"openai"、"gemini" 或 "custom"
 
 	// 交易平台选择
-	Exchange string // "binance", "hyperliquid" 或 "aster"
+	Exchange string // "binance", "hyperliquid", "aster", "okx", "bybit", "gate", "bitget" 或 "paper"（模拟盘）
 
 	// 币安API配置
 	BinanceAPIKey    string
@@ -48,6 +48,26 @@ type AutoTraderConfig struct {
 	OKXPassphrase string // OKX Passphrase
 	OKXTestnet    bool   // OKX是否使用测试网络
 
+	// Bybit配置
+	BybitAPIKey    string // Bybit API密钥
+	BybitSecretKey string // Bybit Secret密钥
+	BybitTestnet   bool   // Bybit是否使用测试网络
+
+	// Gate.io配置
+	GateAPIKey    string // Gate.io API密钥
+	GateSecretKey string // Gate.io Secret密钥
+	GateTestnet   bool   // Gate.io是否使用测试网络
+
+	// Bitget配置
+	BitgetAPIKey     string // Bitget API密钥
+	BitgetSecretKey  string // Bitget Secret密钥
+	BitgetPassphrase string // Bitget Passphrase
+	BitgetTestnet    bool   // Bitget是否使用模拟盘
+
+	// 模拟盘配置
+	PaperFeeRate      *float64 // 模拟盘手续费率（nil表示使用默认值0.04%，0表示免手续费）
+	PaperSlippageRate *float64 // 模拟盘滑点比例（nil表示使用默认值0.05%，0表示无滑点）
+
 	CoinPoolAPIURL string
 
 	// AI配置
@@ -55,11 +75,25 @@ type AutoTraderConfig struct {
 	DeepSeekKey string
 	QwenKey     string
 
-	// 自定义AI API配置
+	// 自定义AI API配置（anthropic / openai / gemini 也使用这组配置，URL和模型名为空时使用默认值）
 	CustomAPIURL    string
 	CustomAPIKey    string
 	CustomModelName string
 
+	// AI生成参数
+	AITemperature *float64 // 采样温度（nil表示默认0.5）
+	AIMaxTokens   int      // 单次输出的最大token数（0表示默认2000）
+
+	// 故障转移：主模型调用失败或处于冷却期时按顺序使用的备用模型
+	FallbackModels []ModelEndpoint
+
+	// 集成决策：主模型与以下模型并行决策后投票合并（为空时使用单模型）
+	EnsembleModels []ModelEndpoint
+	EnsembleConfig decision.EnsembleConfig
+
+	// AI用量计费：模型单价（nil时使用默认单价）
+	AIPriceTable mcp.PriceTable
+
 	// 扫描配置
 	ScanInterval time.Duration // 扫描间隔（建议3分钟）
 
@@ -70,14 +104,39 @@ type AutoTraderConfig struct {
 	BTCETHLeverage  int // BTC和ETH的杠杆倍数
 	AltcoinLeverage int // 山寨币的杠杆倍数
 
-	// 风险控制（仅作为提示，AI可自主决定）
-	MaxDailyLoss    float64       // 最大日亏损百分比（提示）
-	MaxDrawdown     float64       // 最大回撤百分比（提示）
-	StopTradingTime time.Duration // 触发风控后暂停时长
+	// 风险控制
+	// 账户熔断：日亏损或回撤达到阈值时暂停交易（0表示不检查对应项）
+	MaxDailyLoss          float64       // 最大日亏损百分比（相对当日起始净值）
+	MaxDrawdown           float64       // 最大回撤百分比（相对净值峰值）
+	StopTradingTime       time.Duration // 触发熔断后暂停时长（0表示默认1小时）
+	FlattenOnCircuitBreak bool          // 触发熔断时是否平掉所有持仓
+
+	// 学习阶段约束：按已完成交易数分阶段限制杠杆、单笔/日亏损、并发仓位和最小持仓时间
+	EnableStageConstraints bool
+
+	// 实时风控（独立于AI决策周期，按WebSocket实时价格检查，0表示不启用对应检查）
+	GuardianMaxLossPct     float64       // 单个持仓最大亏损（占保证金百分比），超过时立即平仓
+	GuardianEquityFloorPct float64       // 账户净值下限（占初始资金百分比），低于时平掉所有持仓
+	GuardianInterval       time.Duration // 检查间隔（0表示默认2秒）
 
 	// 仓位模式
 	IsCrossMargin bool // true=全仓模式, false=逐仓模式
 
+	// 强平距离检查：开仓时强平价距入场价至少为ATR14的倍数（0表示默认2倍）
+	LiquidationATRMultiple float64
+
+	// 资金费成本控制：预计持有期资金费超过止盈距离的设定比例时拒绝开仓（可选自动平仓）
+	FundingGuard FundingGuardConfig
+
+	// 仓位计算
+	SizingMode          SizingMode // 开仓金额计算方式（空表示使用AI给出的金额）
+	RiskPerTradePct     float64    // 波动率模式：每笔交易风险预算（占净值百分比，0表示默认1%）
+	TargetVolatilityPct float64    // 波动率模式：目标波动率（ATR14占价格百分比），高于此值的币种按比例缩小仓位（0表示不缩放）
+
+	// 限价单配置
+	LimitOrderTTL         time.Duration // 限价入场单未成交的最长等待时间（0表示默认30分钟）
+	LimitOrderTimeInForce TimeInForce   // 限价入场单的有效方式（空表示GTC）
+
 	// 币种配置
 	DefaultCoins []string // 默认币种列表（从数据库获取）
 	TradingCoins []string // 实际交易币种列表
@@ -87,6 +146,13 @@ type AutoTraderConfig struct {
 
 	// 数据库引用
 	Database *config.Database
+
+	// 回测注入（留空时使用实盘默认实现）
+	Trader         Trader                                  // 直接注入交易器（优先于Exchange）
+	AIClient       mcp.AIClient                            // 决策来源（默认使用mcp客户端）
+	MarketData     func(symbol string) (*market.Data, error) // 市场数据来源（默认market.Get）
+	Clock          func() time.Time                        // 时钟（默认time.Now）
+	DecisionLogDir string                                  // 决策日志目录（默认decision_logs/<ID>）
 }
 
 // AutoTrader 自动交易器
@@ -99,6 +165,10 @@ type AutoTrader struct {
 	config                AutoTraderConfig
 	trader                Trader // 使用Trader接口（支持多平台）
 	mcpClient             *mcp.Client
+	aiClient              mcp.AIClient                               // 实际使用的决策来源
+	ensemble              *decision.Ensemble                         // 集成决策（未配置时为nil）
+	marketData            func(symbol string) (*market.Data, error) // 市场数据来源
+	clock                 func() time.Time                           // 时钟
 	decisionLogger        *logger.DecisionLogger     // 决策日志记录器
 	kellyManager          *decision.KellyStopManager // 凯利公式止盈止损管理器
 	creditService         credits.Service            // 积分服务
@@ -116,6 +186,20 @@ type AutoTrader struct {
 	startTime             time.Time        // 系统启动时间
 	callCount             int              // AI调用次数
 	positionFirstSeenTime map[string]int64 // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
+	funding               *fundingTracker  // 持仓已结算的资金费
+	pendingLimitOrders    map[string]*pendingLimitOrder // 未成交的限价入场单 (symbol_side -> 订单)
+	limitOrderStore       LimitOrderStore               // 限价入场单存储（未设置数据库时为nil）
+	guardian              *positionGuardian             // 实时风控（未启用时为nil）
+	breaker               *circuitBreaker               // 账户熔断（未启用时为nil）
+	exposure              ExposureChecker               // 用户级组合风险检查（未设置时为nil）
+	constraints           *ConstraintsManager           // 学习阶段约束（未启用时为nil）
+	positionTracker       *PositionTracker              // 学习阶段约束使用的持仓跟踪
+	constraintsStore      ConstraintsStore              // 学习阶段约束状态存储
+	aiUsageStore          AIUsageStore                  // AI用量存储（未设置数据库时为nil）
+	aiPrices              mcp.PriceTable                // AI模型单价
+	execMu                sync.Mutex                    // 交易执行锁（AI周期与实时风控互斥）
+	pendingEntryMu        sync.Mutex                    // 保护pendingEntries
+	pendingEntries        []Position                    // 未成交限价入场单的快照（组合风险汇总读取，不持有execMu）
 }
 
 // NewAutoTrader 创建自动交易器
@@ -138,10 +222,19 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 	mcpClient := mcp.New()
 
 	// 初始化AI
-	if config.AIModel == "custom" {
+	if config.AIClient != nil {
+		// 使用注入的决策来源（回测）
+		log.Printf("🤖 [%s] 使用注入的决策来源: %T", config.Name, config.AIClient)
+	} else if config.AIModel == "custom" {
 		// 使用自定义API
 		mcpClient.SetCustomAPI(config.CustomAPIURL, config.CustomAPIKey, config.CustomModelName)
 		log.Printf("🤖 [%s] 使用自定义AI API: %s (模型: %s)", config.Name, config.CustomAPIURL, config.CustomModelName)
+	} else if config.AIModel == "anthropic" || config.AIModel == "openai" || config.AIModel == "gemini" {
+		// 原生提供商（各自的请求/响应格式）
+		if err := mcpClient.SetProviderAPIKey(mcp.Provider(config.AIModel), config.CustomAPIKey, config.CustomAPIURL, config.CustomModelName); err != nil {
+			return nil, err
+		}
+		log.Printf("🤖 [%s] 使用%s AI (模型: %s)", config.Name, config.AIModel, mcpClient.Model)
 	} else if config.UseQwen || config.AIModel == "qwen" {
 		// 使用Qwen (支持自定义URL和Model)
 		mcpClient.SetQwenAPIKey(config.QwenKey, config.CustomAPIURL, config.CustomModelName)
@@ -159,6 +252,11 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 			log.Printf("🤖 [%s] 使用DeepSeek AI", config.Name)
 		}
 	}
+	temperature := -1.0
+	if config.AITemperature != nil {
+		temperature = *config.AITemperature
+	}
+	mcpClient.SetGenerationParams(temperature, config.AIMaxTokens)
 
 	// 初始化币种池API
 	if config.CoinPoolAPIURL != "" {
@@ -181,28 +279,67 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 	}
 	log.Printf("📊 [%s] 仓位模式: %s", config.Name, marginModeStr)
 
-	switch config.Exchange {
-	case "binance":
+	switch {
+	case config.Trader != nil:
+		log.Printf("🏦 [%s] 使用注入的交易器（%s）", config.Name, config.Exchange)
+		trader = config.Trader
+	case config.Exchange == "binance":
 		log.Printf("🏦 [%s] 使用币安合约交易", config.Name)
 		trader = NewFuturesTrader(config.BinanceAPIKey, config.BinanceSecretKey)
-	case "hyperliquid":
+	case config.Exchange == "hyperliquid":
 		log.Printf("🏦 [%s] 使用Hyperliquid交易", config.Name)
 		trader, err = NewHyperliquidTrader(config.HyperliquidPrivateKey, config.HyperliquidWalletAddr, config.HyperliquidTestnet)
 		if err != nil {
 			return nil, fmt.Errorf("初始化Hyperliquid交易器失败: %w", err)
 		}
-	case "aster":
+	case config.Exchange == "aster":
 		log.Printf("🏦 [%s] 使用Aster交易", config.Name)
 		trader, err = NewAsterTrader(config.AsterUser, config.AsterSigner, config.AsterPrivateKey)
 		if err != nil {
 			return nil, fmt.Errorf("初始化Aster交易器失败: %w", err)
 		}
-	case "okx":
+	case config.Exchange == "okx":
 		log.Printf("🏦 [%s] 使用OKX交易", config.Name)
 		trader, err = NewOKXTrader(config.OKXAPIKey, config.OKXSecretKey, config.OKXPassphrase, config.OKXTestnet)
 		if err != nil {
 			return nil, fmt.Errorf("初始化OKX交易器失败: %w", err)
 		}
+	case config.Exchange == "bybit":
+		log.Printf("🏦 [%s] 使用Bybit交易", config.Name)
+		trader, err = NewBybitTrader(config.BybitAPIKey, config.BybitSecretKey, config.BybitTestnet)
+		if err != nil {
+			return nil, fmt.Errorf("初始化Bybit交易器失败: %w", err)
+		}
+	case config.Exchange == "gate":
+		log.Printf("🏦 [%s] 使用Gate.io交易", config.Name)
+		trader, err = NewGateTrader(config.GateAPIKey, config.GateSecretKey, config.GateTestnet)
+		if err != nil {
+			return nil, fmt.Errorf("初始化Gate.io交易器失败: %w", err)
+		}
+	case config.Exchange == "bitget":
+		log.Printf("🏦 [%s] 使用Bitget交易", config.Name)
+		trader, err = NewBitgetTrader(config.BitgetAPIKey, config.BitgetSecretKey, config.BitgetPassphrase, config.BitgetTestnet)
+		if err != nil {
+			return nil, fmt.Errorf("初始化Bitget交易器失败: %w", err)
+		}
+	case config.Exchange == "paper":
+		log.Printf("🏦 [%s] 使用模拟盘交易（不会真实下单）", config.Name)
+		if config.InitialBalance <= 0 {
+			return nil, fmt.Errorf("初始金额必须大于0，请在配置中设置InitialBalance")
+		}
+		paperConfig := PaperTraderConfig{
+			TraderID:       config.ID,
+			InitialBalance: config.InitialBalance,
+			FeeRate:        config.PaperFeeRate,
+			SlippageRate:   config.PaperSlippageRate,
+		}
+		if config.Database != nil {
+			paperConfig.Store = config.Database
+		}
+		trader, err = NewPaperTrader(paperConfig)
+		if err != nil {
+			return nil, fmt.Errorf("初始化模拟盘交易器失败: %w", err)
+		}
 	default:
 		return nil, fmt.Errorf("不支持的交易平台: %s", config.Exchange)
 	}
@@ -212,10 +349,50 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 		return nil, fmt.Errorf("初始金额必须大于0，请在配置中设置InitialBalance")
 	}
 
+	// 限价单默认配置
+	if config.LimitOrderTTL <= 0 {
+		config.LimitOrderTTL = defaultLimitOrderTTL
+	}
+	if config.LimitOrderTimeInForce == "" {
+		config.LimitOrderTimeInForce = TimeInForceGTC
+	}
+
 	// 初始化决策日志记录器（使用trader ID创建独立目录）
-	logDir := fmt.Sprintf("decision_logs/%s", config.ID)
+	logDir := config.DecisionLogDir
+	if logDir == "" {
+		logDir = fmt.Sprintf("decision_logs/%s", config.ID)
+	}
 	decisionLogger := logger.NewDecisionLogger(logDir)
 
+	// 决策来源、市场数据和时钟（回测时可注入）
+	var aiClient mcp.AIClient = mcpClient
+	if config.AIClient != nil {
+		aiClient = config.AIClient
+	}
+	aiClient, err = newFailoverClient(config.AIModel, aiClient, config.FallbackModels)
+	if err != nil {
+		return nil, fmt.Errorf("初始化备用AI模型失败: %w", err)
+	}
+	if len(config.FallbackModels) > 0 {
+		log.Printf("🔀 [%s] 启用AI故障转移: 主模型 + %d个备用模型", config.Name, len(config.FallbackModels))
+	}
+	ensemble, err := newEnsemble(config.AIModel, aiClient, config.EnsembleModels, config.EnsembleConfig)
+	if err != nil {
+		return nil, fmt.Errorf("初始化集成决策失败: %w", err)
+	}
+	if ensemble != nil {
+		log.Printf("🗳️ [%s] 启用集成决策: %d个模型参与投票", config.Name, len(ensemble.Members))
+	}
+	marketData := config.MarketData
+	if marketData == nil {
+		marketData = market.Get
+	}
+	clock := config.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+	decisionLogger.SetClock(clock)
+
 	// 初始化凯利公式止盈止损管理器
 	kellyManager := decision.NewKellyStopManager()
 
@@ -231,7 +408,37 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 		creditService = credits.NewCreditService(config.Database)
 	}
 
-	return &AutoTrader{
+	// 初始化账户熔断（恢复重启前的暂停状态）
+	var breakerStore CircuitBreakerStore
+	var constraintsStore ConstraintsStore
+	var aiUsageStore AIUsageStore
+	var guardianStore GuardianStore
+	var limitOrderStore LimitOrderStore
+	var trailingStopStore TrailingStopStore
+	var fundingStore FundingStore
+	if config.Database != nil {
+		breakerStore = config.Database
+		guardianStore = config.Database
+		limitOrderStore = config.Database
+		trailingStopStore = config.Database
+		fundingStore = config.Database
+		constraintsStore = config.Database
+		aiUsageStore = config.Database
+	}
+	breaker := newCircuitBreaker(config, breakerStore)
+	aiPrices := config.AIPriceTable
+	if aiPrices == nil {
+		aiPrices = mcp.DefaultPriceTable
+	}
+	var stopUntil time.Time
+	if breaker != nil {
+		if state := breaker.State(); clock().Before(state.HaltedUntil) {
+			stopUntil = state.HaltedUntil
+			log.Printf("⏸ [%s] 恢复熔断暂停状态: %s，暂停至 %s", config.Name, state.Reason, stopUntil.Format("2006-01-02 15:04:05"))
+		}
+	}
+
+	at := &AutoTrader{
 		id:                    config.ID,
 		userID:                config.UserID,
 		name:                  config.Name,
@@ -240,6 +447,10 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 		config:                config,
 		trader:                trader,
 		mcpClient:             mcpClient,
+		aiClient:              aiClient,
+		ensemble:              ensemble,
+		marketData:            marketData,
+		clock:                 clock,
 		decisionLogger:        decisionLogger,
 		kellyManager:          kellyManager,
 		creditService:         creditService,
@@ -248,12 +459,27 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 		systemPromptTemplate:  systemPromptTemplate,
 		defaultCoins:          config.DefaultCoins,
 		tradingCoins:          config.TradingCoins,
-		lastResetTime:         time.Now(),
-		startTime:             time.Now(),
+		lastResetTime:         clock(),
+		startTime:             clock(),
 		callCount:             0,
 		isRunning:             false,
 		positionFirstSeenTime: make(map[string]int64),
-	}, nil
+		funding:               newFundingTracker(fundingStore, config.ID, config.Name),
+		pendingLimitOrders:    loadPendingLimitOrders(limitOrderStore, config.ID, config.Name),
+		limitOrderStore:       limitOrderStore,
+		guardian:              newPositionGuardian(config, guardianStore),
+		breaker:               breaker,
+		stopUntil:             stopUntil,
+		constraints:           newStageConstraints(config, constraintsStore, clock),
+		positionTracker:       NewPositionTracker(),
+		constraintsStore:      constraintsStore,
+		aiUsageStore:          aiUsageStore,
+		aiPrices:              aiPrices,
+	}
+	// 本地模拟的跟踪止损：恢复重启前的状态，触发时走风控平仓路径
+	at.attachTrailingStops(trailingStopStore)
+	at.publishPendingEntries() // 重启前的挂单计入组合风险
+	return at, nil
 }
 
 // Run 运行自动交易主循环
@@ -267,6 +493,16 @@ func (at *AutoTrader) Run() error {
 	ticker := time.NewTicker(at.config.ScanInterval)
 	defer ticker.Stop()
 
+	// 模拟盘：在交易运行期间持续检查止盈止损和强平
+	if paper, ok := at.trader.(*PaperTrader); ok {
+		paper.StartMonitor(paperMonitorInterval)
+		defer paper.StopMonitor()
+	}
+
+	// 实时风控：在AI决策周期之间按实时价格保护持仓
+	at.startGuardian()
+	defer at.stopGuardian()
+
 	// 首次立即执行
 	if err := at.runCycle(); err != nil {
 		log.Printf("❌ 执行失败: %v", err)
@@ -284,18 +520,43 @@ func (at *AutoTrader) Run() error {
 	return nil
 }
 
+// Start 在后台运行自动交易主循环，返回前即标记为运行中，避免启动期间被重复启动
+func (at *AutoTrader) Start() {
+	at.isRunning = true
+	go func() {
+		if err := at.Run(); err != nil {
+			log.Printf("❌ %s 运行错误: %v", at.name, err)
+		}
+	}()
+}
+
+// IsRunning 是否正在运行
+func (at *AutoTrader) IsRunning() bool {
+	return at.isRunning
+}
+
 // Stop 停止自动交易
 func (at *AutoTrader) Stop() {
 	at.isRunning = false
 	log.Println("⏹ 自动交易系统停止")
 }
 
+// RunOnce 执行单个交易周期（供回测引擎按模拟时间驱动）
+func (at *AutoTrader) RunOnce() error {
+	return at.runCycle()
+}
+
+// now 返回当前时间（回测时为模拟时间）
+func (at *AutoTrader) now() time.Time {
+	return at.clock()
+}
+
 // runCycle 运行一个交易周期（使用AI全权决策）
 func (at *AutoTrader) runCycle() error {
 	at.callCount++
 
 	log.Println(strings.Repeat("=", 70))
-	log.Printf("⏰ %s - AI决策周期 #%d", time.Now().Format("2006-01-02 15:04:05"), at.callCount)
+	log.Printf("⏰ %s - AI决策周期 #%d", at.now().Format("2006-01-02 15:04:05"), at.callCount)
 	log.Println(strings.Repeat("=", 70))
 
 	// 创建决策记录
@@ -306,20 +567,12 @@ func (at *AutoTrader) runCycle() error {
 
 	// 0. 积分消耗检查 (TopTrader专属)
 	if at.name == "TopTrader" && at.creditService != nil && at.db != nil {
-		// 从配置获取消耗点数
-		costStr, err := at.db.GetSystemConfig("trading_decision_points_cost")
-		if err != nil {
-			log.Printf("⚠️ 获取积分配置失败: %v，使用默认值1", err)
-			costStr = "1"
-		}
-		cost, _ := strconv.Atoi(costStr)
-		if cost <= 0 {
-			cost = 1
-		}
+		// 从配置获取消耗点数（配置了每美元积分数时按近期实际AI费用折算）
+		cost := at.decisionPointsCost()
 
 		// 执行扣减
 		log.Printf("💳 TopTrader: 准备消耗 %d 积分进行决策...", cost)
-		err = at.creditService.DeductCredits(context.Background(), at.userID, cost, "decision", fmt.Sprintf("AI决策周期 #%d", at.callCount), fmt.Sprintf("cycle_%s_%d", at.id, at.callCount))
+		err := at.creditService.DeductCredits(context.Background(), at.userID, cost, "decision", fmt.Sprintf("AI决策周期 #%d", at.callCount), fmt.Sprintf("cycle_%s_%d", at.id, at.callCount))
 		if err != nil {
 			errorMsg := fmt.Sprintf("❌ 积分不足，无法执行AI决策: %v", err)
 			log.Println(errorMsg)
@@ -332,24 +585,36 @@ func (at *AutoTrader) runCycle() error {
 		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("💳 消耗 %d 积分", cost))
 	}
 
-	// 1. 检查是否需要停止交易
-	if time.Now().Before(at.stopUntil) {
-		remaining := at.stopUntil.Sub(time.Now())
+	// 跟踪未成交的限价单（成交后补设止盈止损，超时撤单；风控暂停期间也需要执行）
+	at.execMu.Lock()
+	record.ExecutionLog = append(record.ExecutionLog, at.checkPendingLimitOrders()...)
+	at.execMu.Unlock()
+
+	// 1. 账户熔断检查（按净值计算日盈亏和回撤，触发时设置暂停时间），然后检查是否需要停止交易
+	at.checkCircuitBreaker(record)
+	if at.now().Before(at.stopUntil) {
+		remaining := at.stopUntil.Sub(at.now())
 		log.Printf("⏸ 风险控制：暂停交易中，剩余 %.0f 分钟", remaining.Minutes())
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("风险控制暂停中，剩余 %.0f 分钟", remaining.Minutes())
 		at.decisionLogger.LogDecision(record)
 		return nil
 	}
-
-	// 2. 重置日盈亏（每天重置）
-	if time.Since(at.lastResetTime) > 24*time.Hour {
-		at.dailyPnL = 0
-		at.lastResetTime = time.Now()
-		log.Println("📅 日盈亏已重置")
+	// 实时风控触发净值下限后暂停交易，直到人工重置
+	if reason := at.guardianHaltReason(); reason != "" {
+		log.Printf("⏸ 实时风控：净值下限触发暂停（%s），需人工重置后恢复交易", reason)
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("实时风控暂停中，需人工重置: %s", reason)
+		at.decisionLogger.LogDecision(record)
+		return nil
 	}
 
-	// 3. 收集交易上下文
+	// 资金费成本检查：预计持有期资金费超过剩余止盈空间的设定比例时平仓（需启用自动平仓）
+	at.execMu.Lock()
+	at.checkFundingAutoClose(record)
+	at.execMu.Unlock()
+
+	// 2. 收集交易上下文
 	ctx, err := at.buildTradingContext()
 	if err != nil {
 		record.Success = false
@@ -386,22 +651,47 @@ func (at *AutoTrader) runCycle() error {
 		record.CandidateCoins = append(record.CandidateCoins, coin.Symbol)
 	}
 
+	// 3. 学习阶段约束：同步持仓跟踪，按阶段杠杆上限调整上下文
+	if at.constraints != nil {
+		at.syncStageConstraints(ctx)
+		record.ExecutionLog = append(record.ExecutionLog, at.stageConstraintsLine())
+	}
+
 	log.Printf("📊 账户净值: %.2f USDT | 可用: %.2f USDT | 持仓: %d",
 		ctx.Account.TotalEquity, ctx.Account.AvailableBalance, ctx.Account.PositionCount)
 
 	// 4. 调用AI获取完整决策
 	log.Printf("🤖 正在请求AI分析并决策... [模板: %s]", at.systemPromptTemplate)
-	decision, err := decision.GetFullDecisionWithCustomPrompt(ctx, at.mcpClient, at.customPrompt, at.overrideBasePrompt, at.systemPromptTemplate)
+	decision, err := decision.GetFullDecisionWithCustomPrompt(ctx, at.aiClient, at.customPrompt, at.overrideBasePrompt, at.systemPromptTemplate)
 
 	// 即使有错误，也保存思维链、决策和输入prompt（用于debug）
 	if decision != nil {
 		record.SystemPrompt = decision.SystemPrompt // 保存系统提示词
 		record.InputPrompt = decision.UserPrompt
 		record.CoTTrace = decision.CoTTrace
+		record.RawResponse = decision.RawResponse
+		record.StructuredOutput = decision.StructuredOutput
+		record.ParseOutcome = decision.ParseOutcome
+		record.AIProvider = decision.AIProvider
+		if decision.AIProvider != "" && decision.AIProvider != at.aiModel {
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔀 主模型不可用，本周期由备用模型 %s 决策", decision.AIProvider))
+		}
+		for _, attempt := range decision.ParseAttempts {
+			record.ParseAttempts = append(record.ParseAttempts, logger.ParseAttempt{
+				Attempt:  attempt.Attempt,
+				Response: attempt.Response,
+				Error:    attempt.Error,
+			})
+		}
+		if decision.ParseOutcome == "repaired" {
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔧 AI响应经%d次修复后通过校验", len(decision.ParseAttempts)-1))
+		}
 		if len(decision.Decisions) > 0 {
 			decisionJSON, _ := json.MarshalIndent(decision.Decisions, "", "  ")
 			record.DecisionJSON = string(decisionJSON)
 		}
+		record.Ensemble = buildEnsembleRecord(decision.Ensemble)
+		at.recordAIUsage(record, decision.Usage) // 解析失败时token同样已消耗
 	}
 
 	if err != nil {
@@ -465,7 +755,12 @@ func (at *AutoTrader) runCycle() error {
 	}
 	log.Println()
 
-	// 执行决策并记录结果
+	// 执行决策并记录结果（与实时风控互斥，执行后风控重新获取持仓）
+	at.execMu.Lock()
+	defer at.execMu.Unlock()
+	if at.guardian != nil {
+		defer at.guardian.invalidate()
+	}
 	for _, d := range sortedDecisions {
 		actionRecord := logger.DecisionAction{
 			Action:    d.Action,
@@ -473,32 +768,42 @@ func (at *AutoTrader) runCycle() error {
 			Quantity:  0,
 			Leverage:  d.Leverage,
 			Price:     0,
-			Timestamp: time.Now(),
+			Timestamp: at.now(),
 			Success:   false,
+			Models:    decision.Ensemble.Voters(d.Symbol, d.Action),
 		}
 
-		if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
+		if err := at.checkStageConstraints(&d, ctx.Account.TotalEquity); err != nil {
+			actionRecord.Error = err.Error()
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⛔ %s %s 被学习阶段约束拒绝: %v", d.Symbol, d.Action, err))
+		} else if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
 			log.Printf("❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
 			actionRecord.Error = err.Error()
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
 		} else {
 			actionRecord.Success = true
+			at.trackExecutedDecision(&d, actionRecord.Price, actionRecord.Quantity, actionRecord.Leverage)
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
-			// 成功执行后短暂延迟
-			time.Sleep(1 * time.Second)
+			// 成功执行后短暂延迟（注入时钟的回测模式下跳过）
+			if at.config.Clock == nil {
+				time.Sleep(1 * time.Second)
+			}
 		}
 
 		record.Decisions = append(record.Decisions, actionRecord)
 	}
+	at.saveConstraintsState()
 
 	// 9. 检查并更新现有持仓的止盈止损单（使用凯利公式优化）
 	log.Println("🔄 开始执行凯利公式动态止盈止损检查...")
-	if err := at.checkAndUpdateStopOrders(); err != nil {
+	if lines, err := at.checkAndUpdateStopOrders(); err != nil {
 		log.Printf("⚠ 更新止盈止损单失败: %v", err)
 		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⚠ 止盈止损更新失败: %v", err))
 	} else {
-		log.Println("✅ 所有持仓的止盈止损单已更新（凯利公式优化）")
-		record.ExecutionLog = append(record.ExecutionLog, "✅ 止盈止损单已更新")
+		for _, line := range lines {
+			log.Println(line)
+		}
+		record.ExecutionLog = append(record.ExecutionLog, lines...)
 	}
 
 	// 10. 保存决策记录
@@ -517,28 +822,8 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		return nil, fmt.Errorf("获取账户余额失败: %w", err)
 	}
 
-	// 获取账户字段（使用OKXTrader返回的正确字段名）
-	totalWalletBalance := 0.0
-	totalUnrealizedProfit := 0.0
-	availableBalance := 0.0
-
-	// 从OKXTrader.GetBalance()返回的"total"字段获取总资产
-	if total, ok := balance["total"].(float64); ok {
-		totalWalletBalance = total
-		log.Printf("✓ 从OKX获取总资产: %.8f", total)
-	}
-	// 从OKXTrader.GetBalance()返回的"free"字段获取可用余额
-	if free, ok := balance["free"].(float64); ok {
-		availableBalance = free
-		log.Printf("✓ 从OKX获取可用余额: %.8f", free)
-	}
-	// 从OKXTrader.GetBalance()返回的"used"字段获取已用余额
-	if used, ok := balance["used"].(float64); ok {
-		log.Printf("✓ 从OKX获取已用余额: %.8f", used)
-	}
-
-	// Total Equity = 钱包余额 + 未实现盈亏
-	totalEquity := totalWalletBalance + totalUnrealizedProfit
+	totalEquity := balance.TotalEquity()
+	availableBalance := balance.AvailableBalance
 
 	// 2. 获取持仓信息
 	positions, err := at.trader.GetPositions()
@@ -553,82 +838,58 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	currentPositionKeys := make(map[string]bool)
 
 	for _, pos := range positions {
-		// 安全类型断言，避免nil值导致panic
-		symbol := ""
-		if s, ok := pos["symbol"].(string); ok {
-			symbol = s
-		}
-		side := ""
-		if s, ok := pos["side"].(string); ok {
-			side = s
-		}
-		entryPrice := 0.0
-		if ep, ok := pos["entryPrice"].(float64); ok {
-			entryPrice = ep
-		}
-		markPrice := 0.0
-		if mp, ok := pos["markPrice"].(float64); ok {
-			markPrice = mp
-		}
-		quantity := 0.0
-		if qty, ok := pos["positionAmt"].(float64); ok {
-			quantity = qty
-		}
-		if quantity < 0 {
-			quantity = -quantity // 空仓数量为负，转为正数
-		}
-		unrealizedPnl := 0.0
-		if upl, ok := pos["unRealizedProfit"].(float64); ok {
-			unrealizedPnl = upl
-		}
-		liquidationPrice := 0.0
-		if lp, ok := pos["liquidationPrice"].(float64); ok {
-			liquidationPrice = lp
-		}
-
 		// 跳过无效持仓数据
-		if symbol == "" || side == "" || markPrice == 0 {
+		if pos.Symbol == "" || pos.Side == "" || pos.MarkPrice == 0 {
 			continue
 		}
 
 		// 计算盈亏百分比
 		pnlPct := 0.0
-		if entryPrice > 0 {
-			if side == "long" {
-				pnlPct = ((markPrice - entryPrice) / entryPrice) * 100
+		if pos.EntryPrice > 0 {
+			if pos.Side == "long" {
+				pnlPct = ((pos.MarkPrice - pos.EntryPrice) / pos.EntryPrice) * 100
 			} else {
-				pnlPct = ((entryPrice - markPrice) / entryPrice) * 100
+				pnlPct = ((pos.EntryPrice - pos.MarkPrice) / pos.EntryPrice) * 100
 			}
 		}
 
-		// 计算占用保证金（估算）
-		leverage := 10 // 默认值，实际应该从持仓信息获取
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
+		// 计算占用保证金（估算，交易所未返回杠杆时按10倍估算）
+		leverage := pos.Leverage
+		if leverage <= 0 {
+			leverage = 10
 		}
-		marginUsed := (quantity * markPrice) / float64(leverage)
+		marginUsed := (pos.Quantity * pos.MarkPrice) / float64(leverage)
 		totalMarginUsed += marginUsed
 
 		// 跟踪持仓首次出现时间
-		posKey := symbol + "_" + side
+		posKey := pos.Key()
 		currentPositionKeys[posKey] = true
 		if _, exists := at.positionFirstSeenTime[posKey]; !exists {
-			// 新持仓，记录当前时间
-			at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+			// 新持仓，记录当前时间（重启前已跟踪的持仓沿用资金费统计的开仓时间）
+			firstSeen := at.now()
+			if since := at.funding.since(posKey); !since.IsZero() {
+				firstSeen = since
+			}
+			at.positionFirstSeenTime[posKey] = firstSeen.UnixMilli()
 		}
 		updateTime := at.positionFirstSeenTime[posKey]
 
+		// 开仓以来已结算的资金费
+		at.funding.track(posKey, time.UnixMilli(updateTime))
+		accruedFunding := at.syncPositionFunding(pos.Symbol, posKey)
+
 		positionInfos = append(positionInfos, decision.PositionInfo{
-			Symbol:           symbol,
-			Side:             side,
-			EntryPrice:       entryPrice,
-			MarkPrice:        markPrice,
-			Quantity:         quantity,
+			Symbol:           pos.Symbol,
+			Side:             pos.Side,
+			EntryPrice:       pos.EntryPrice,
+			MarkPrice:        pos.MarkPrice,
+			Quantity:         pos.Quantity,
 			Leverage:         leverage,
-			UnrealizedPnL:    unrealizedPnl,
+			UnrealizedPnL:    pos.UnrealizedProfit,
 			UnrealizedPnLPct: pnlPct,
-			LiquidationPrice: liquidationPrice,
+			LiquidationPrice: pos.LiquidationPrice,
 			MarginUsed:       marginUsed,
+			AccruedFunding:   accruedFunding,
 			UpdateTime:       updateTime,
 		})
 	}
@@ -639,6 +900,7 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 			delete(at.positionFirstSeenTime, key)
 		}
 	}
+	at.funding.prune(currentPositionKeys)
 
 	// 3. 获取交易员的候选币种池
 	candidateCoins, err := at.getCandidateCoins()
@@ -669,8 +931,8 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 
 	// 6. 构建上下文
 	ctx := &decision.Context{
-		CurrentTime:     time.Now().Format("2006-01-02 15:04:05"),
-		RuntimeMinutes:  int(time.Since(at.startTime).Minutes()),
+		CurrentTime:     at.now().Format("2006-01-02 15:04:05"),
+		RuntimeMinutes:  int(at.now().Sub(at.startTime).Minutes()),
 		CallCount:       at.callCount,
 		BTCETHLeverage:  at.config.BTCETHLeverage,  // 使用配置的杠杆倍数
 		AltcoinLeverage: at.config.AltcoinLeverage, // 使用配置的杠杆倍数
@@ -686,6 +948,10 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		Positions:      positionInfos,
 		CandidateCoins: candidateCoins,
 		Performance:    performance, // 添加历史表现分析
+
+		MarketDataProvider: at.config.MarketData,
+		Clock:              at.config.Clock,
+		Ensemble:           at.ensemble,
 	}
 
 	return ctx, nil
@@ -702,6 +968,8 @@ func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, act
 		return at.executeCloseLongWithRecord(decision, actionRecord)
 	case "close_short":
 		return at.executeCloseShortWithRecord(decision, actionRecord)
+	case "reduce_long", "reduce_short", "add_long", "add_short":
+		return at.executeAdjustWithRecord(decision, actionRecord)
 	case "hold", "wait":
 		// 无需执行，仅记录
 		return nil
@@ -718,53 +986,73 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	positions, err := at.trader.GetPositions()
 	if err == nil {
 		for _, pos := range positions {
-			if pos["symbol"] == decision.Symbol && pos["side"] == "long" {
+			if pos.Symbol == decision.Symbol && pos.Side == "long" {
 				return fmt.Errorf("❌ %s 已有多仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_long 决策", decision.Symbol)
 			}
 		}
 	}
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := at.marketData(decision.Symbol)
 	if err != nil {
 		return err
 	}
 
+	// 资金费成本检查（预计持有期资金费超过止盈距离的设定比例时拒绝开仓）
+	if err := at.checkFundingCost(decision, marketData, actionRecord); err != nil {
+		return err
+	}
+
 	// ===== 保证金检查与自动调整 =====
-	adjustedPositionSizeUSD := decision.PositionSizeUSD
 	balance, balanceErr := at.trader.GetBalance()
+	// 开仓金额（波动率模式下按风险预算和ATR计算，否则使用AI给出的金额）
+	adjustedPositionSizeUSD := at.targetPositionSize(decision, marketData, balance, actionRecord)
+	requestedSizeUSD := adjustedPositionSizeUSD
+
 	if balanceErr == nil {
-		availableBalance := 0.0
-		if free, ok := balance["free"].(float64); ok {
-			availableBalance = free
-		}
+		availableBalance := balance.AvailableBalance
 
 		// 计算最大可开仓价值 = 可用保证金 * 80% * 杠杆
 		// 保留20%作为安全边际，防止价格波动导致保证金不足
 		maxMarginToUse := availableBalance * 0.80
 		maxPositionValue := maxMarginToUse * float64(decision.Leverage)
 
-		if decision.PositionSizeUSD > maxPositionValue {
+		if requestedSizeUSD > maxPositionValue {
 			log.Printf("  ⚠️ 保证金检查: AI请求开仓 $%.2f，但可用保证金 $%.2f (80%% = $%.2f)，杠杆 %dx，最大可开仓 $%.2f",
-				decision.PositionSizeUSD, availableBalance, maxMarginToUse, decision.Leverage, maxPositionValue)
+				requestedSizeUSD, availableBalance, maxMarginToUse, decision.Leverage, maxPositionValue)
 
 			if maxPositionValue < 10 {
 				// 如果最大可开仓金额太小（<$10），拒绝开仓
 				return fmt.Errorf("保证金不足: 可用 $%.2f, 需要至少 $%.2f 保证金才能开仓 (杠杆 %dx)", 
-					availableBalance, decision.PositionSizeUSD/float64(decision.Leverage)/0.8, decision.Leverage)
+					availableBalance, requestedSizeUSD/float64(decision.Leverage)/0.8, decision.Leverage)
 			}
 
 			// 自动调整到最大可开仓值
 			adjustedPositionSizeUSD = maxPositionValue
-			log.Printf("  ✅ 自动调整开仓金额: $%.2f -> $%.2f (可用保证金的80%%)", decision.PositionSizeUSD, adjustedPositionSizeUSD)
+			log.Printf("  ✅ 自动调整开仓金额: $%.2f -> $%.2f (可用保证金的80%%)", requestedSizeUSD, adjustedPositionSizeUSD)
 		} else {
 			log.Printf("  ✅ 保证金检查通过: 开仓 $%.2f, 可用保证金 $%.2f, 杠杆 %dx", 
-				decision.PositionSizeUSD, availableBalance, decision.Leverage)
+				requestedSizeUSD, availableBalance, decision.Leverage)
 		}
 	} else {
 		log.Printf("  ⚠️ 无法获取账户余额进行保证金检查: %v, 继续使用AI决定的仓位", balanceErr)
 	}
 	
+	// 组合风险检查（同一用户所有交易员的合计敞口）
+	adjustedPositionSizeUSD, reservation, err := at.applyExposureLimit(decision.Symbol, "long", adjustedPositionSizeUSD, decision.Leverage)
+	if err != nil {
+		return err
+	}
+	defer reservation.Release() // 未下单或下单失败时释放预留额度（下单成功后已Commit）
+
+	// 强平距离检查（按调整后的最终金额：止损需在强平前触发、强平价距入场价不少于N×ATR，
+	// 否则逐仓降低杠杆、全仓减小金额，仍不满足时拒绝开仓）
+	adjustedPositionSizeUSD, err = at.checkLiquidationDistance(decision, marketData, balance, adjustedPositionSizeUSD, nil, actionRecord)
+	if err != nil {
+		return err
+	}
+	actionRecord.SizeUSD = adjustedPositionSizeUSD
+
 	// 最小开仓金额检查（无论是否调整过，都需要检查）
 	const minPositionSizeUSD = 10.0
 	if adjustedPositionSizeUSD < minPositionSizeUSD {
@@ -772,10 +1060,14 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	}
 	// ===== 保证金检查结束 =====
 
-	// 计算数量（使用调整后的仓位大小）
-	quantity := adjustedPositionSizeUSD / marketData.CurrentPrice
+	// 计算数量（使用调整后的仓位大小，限价单按限价计算）
+	entryPrice := marketData.CurrentPrice
+	if decision.LimitPrice > 0 {
+		entryPrice = decision.LimitPrice
+	}
+	quantity := adjustedPositionSizeUSD / entryPrice
 	actionRecord.Quantity = quantity
-	actionRecord.Price = marketData.CurrentPrice
+	actionRecord.Price = entryPrice
 
 	// 设置仓位模式
 	if err := at.trader.SetMarginMode(decision.Symbol, at.config.IsCrossMargin); err != nil {
@@ -783,22 +1075,33 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 		// 继续执行，不影响交易
 	}
 
+	// 限价开仓：挂单后跨周期跟踪，成交后再设置止盈止损
+	if decision.LimitPrice > 0 {
+		if err := at.executeLimitEntryWithRecord(decision, "LONG", quantity, actionRecord); err != nil {
+			return err
+		}
+		reservation.Commit() // 挂单期间通过PendingEntryExposure计入组合风险
+		return nil
+	}
+	at.cancelPendingLimitOrder(decision.Symbol, "LONG", "改为市价开仓")
+
 	// 开仓
+	submittedAt := at.now()
 	order, err := at.trader.OpenLong(decision.Symbol, quantity, decision.Leverage)
 	if err != nil {
 		return err
 	}
+	reservation.Commit()
 
-	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	// 记录订单ID、成交均价和手续费
+	at.recordOrderExecution(decision.Symbol, order, submittedAt, actionRecord)
 
-	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
+	log.Printf("  ✓ 开仓成功，订单ID: %s, 数量: %.4f, 成交均价: %.4f", actionRecord.OrderID, quantity, actionRecord.Price)
 
 	// 记录开仓时间
 	posKey := decision.Symbol + "_long"
-	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	at.positionFirstSeenTime[posKey] = at.now().UnixMilli()
+	at.funding.open(posKey, at.now())
 
 	// 设置止损止盈
 	if err := at.trader.SetStopLoss(decision.Symbol, "LONG", quantity, decision.StopLoss); err != nil {
@@ -819,53 +1122,73 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	positions, err := at.trader.GetPositions()
 	if err == nil {
 		for _, pos := range positions {
-			if pos["symbol"] == decision.Symbol && pos["side"] == "short" {
+			if pos.Symbol == decision.Symbol && pos.Side == "short" {
 				return fmt.Errorf("❌ %s 已有空仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_short 决策", decision.Symbol)
 			}
 		}
 	}
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := at.marketData(decision.Symbol)
 	if err != nil {
 		return err
 	}
 
+	// 资金费成本检查（预计持有期资金费超过止盈距离的设定比例时拒绝开仓）
+	if err := at.checkFundingCost(decision, marketData, actionRecord); err != nil {
+		return err
+	}
+
 	// ===== 保证金检查与自动调整 =====
-	adjustedPositionSizeUSD := decision.PositionSizeUSD
 	balance, balanceErr := at.trader.GetBalance()
+	// 开仓金额（波动率模式下按风险预算和ATR计算，否则使用AI给出的金额）
+	adjustedPositionSizeUSD := at.targetPositionSize(decision, marketData, balance, actionRecord)
+	requestedSizeUSD := adjustedPositionSizeUSD
+
 	if balanceErr == nil {
-		availableBalance := 0.0
-		if free, ok := balance["free"].(float64); ok {
-			availableBalance = free
-		}
+		availableBalance := balance.AvailableBalance
 
 		// 计算最大可开仓价值 = 可用保证金 * 80% * 杠杆
 		// 保留20%作为安全边际，防止价格波动导致保证金不足
 		maxMarginToUse := availableBalance * 0.80
 		maxPositionValue := maxMarginToUse * float64(decision.Leverage)
 
-		if decision.PositionSizeUSD > maxPositionValue {
+		if requestedSizeUSD > maxPositionValue {
 			log.Printf("  ⚠️ 保证金检查: AI请求开仓 $%.2f，但可用保证金 $%.2f (80%% = $%.2f)，杠杆 %dx，最大可开仓 $%.2f",
-				decision.PositionSizeUSD, availableBalance, maxMarginToUse, decision.Leverage, maxPositionValue)
+				requestedSizeUSD, availableBalance, maxMarginToUse, decision.Leverage, maxPositionValue)
 
 			if maxPositionValue < 10 {
 				// 如果最大可开仓金额太小（<$10），拒绝开仓
 				return fmt.Errorf("保证金不足: 可用 $%.2f, 需要至少 $%.2f 保证金才能开仓 (杠杆 %dx)", 
-					availableBalance, decision.PositionSizeUSD/float64(decision.Leverage)/0.8, decision.Leverage)
+					availableBalance, requestedSizeUSD/float64(decision.Leverage)/0.8, decision.Leverage)
 			}
 
 			// 自动调整到最大可开仓值
 			adjustedPositionSizeUSD = maxPositionValue
-			log.Printf("  ✅ 自动调整开仓金额: $%.2f -> $%.2f (可用保证金的80%%)", decision.PositionSizeUSD, adjustedPositionSizeUSD)
+			log.Printf("  ✅ 自动调整开仓金额: $%.2f -> $%.2f (可用保证金的80%%)", requestedSizeUSD, adjustedPositionSizeUSD)
 		} else {
 			log.Printf("  ✅ 保证金检查通过: 开仓 $%.2f, 可用保证金 $%.2f, 杠杆 %dx", 
-				decision.PositionSizeUSD, availableBalance, decision.Leverage)
+				requestedSizeUSD, availableBalance, decision.Leverage)
 		}
 	} else {
 		log.Printf("  ⚠️ 无法获取账户余额进行保证金检查: %v, 继续使用AI决定的仓位", balanceErr)
 	}
 	
+	// 组合风险检查（同一用户所有交易员的合计敞口）
+	adjustedPositionSizeUSD, reservation, err := at.applyExposureLimit(decision.Symbol, "short", adjustedPositionSizeUSD, decision.Leverage)
+	if err != nil {
+		return err
+	}
+	defer reservation.Release() // 未下单或下单失败时释放预留额度（下单成功后已Commit）
+
+	// 强平距离检查（按调整后的最终金额：止损需在强平前触发、强平价距入场价不少于N×ATR，
+	// 否则逐仓降低杠杆、全仓减小金额，仍不满足时拒绝开仓）
+	adjustedPositionSizeUSD, err = at.checkLiquidationDistance(decision, marketData, balance, adjustedPositionSizeUSD, nil, actionRecord)
+	if err != nil {
+		return err
+	}
+	actionRecord.SizeUSD = adjustedPositionSizeUSD
+
 	// 最小开仓金额检查（无论是否调整过，都需要检查）
 	const minPositionSizeUSD = 10.0
 	if adjustedPositionSizeUSD < minPositionSizeUSD {
@@ -873,10 +1196,14 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	}
 	// ===== 保证金检查结束 =====
 
-	// 计算数量（使用调整后的仓位大小）
-	quantity := adjustedPositionSizeUSD / marketData.CurrentPrice
+	// 计算数量（使用调整后的仓位大小，限价单按限价计算）
+	entryPrice := marketData.CurrentPrice
+	if decision.LimitPrice > 0 {
+		entryPrice = decision.LimitPrice
+	}
+	quantity := adjustedPositionSizeUSD / entryPrice
 	actionRecord.Quantity = quantity
-	actionRecord.Price = marketData.CurrentPrice
+	actionRecord.Price = entryPrice
 
 	// 设置仓位模式
 	if err := at.trader.SetMarginMode(decision.Symbol, at.config.IsCrossMargin); err != nil {
@@ -884,22 +1211,33 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 		// 继续执行，不影响交易
 	}
 
+	// 限价开仓：挂单后跨周期跟踪，成交后再设置止盈止损
+	if decision.LimitPrice > 0 {
+		if err := at.executeLimitEntryWithRecord(decision, "SHORT", quantity, actionRecord); err != nil {
+			return err
+		}
+		reservation.Commit() // 挂单期间通过PendingEntryExposure计入组合风险
+		return nil
+	}
+	at.cancelPendingLimitOrder(decision.Symbol, "SHORT", "改为市价开仓")
+
 	// 开仓
+	submittedAt := at.now()
 	order, err := at.trader.OpenShort(decision.Symbol, quantity, decision.Leverage)
 	if err != nil {
 		return err
 	}
+	reservation.Commit()
 
-	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	// 记录订单ID、成交均价和手续费
+	at.recordOrderExecution(decision.Symbol, order, submittedAt, actionRecord)
 
-	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
+	log.Printf("  ✓ 开仓成功，订单ID: %s, 数量: %.4f, 成交均价: %.4f", actionRecord.OrderID, quantity, actionRecord.Price)
 
 	// 记录开仓时间
 	posKey := decision.Symbol + "_short"
-	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	at.positionFirstSeenTime[posKey] = at.now().UnixMilli()
+	at.funding.open(posKey, at.now())
 
 	// 设置止损止盈
 	if err := at.trader.SetStopLoss(decision.Symbol, "SHORT", quantity, decision.StopLoss); err != nil {
@@ -913,18 +1251,21 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 }
 
 // executeCloseLongWithRecord 执行平多仓并记录详细信息
-func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) (err error) {
 	log.Printf("  🔄 平多仓: %s", decision.Symbol)
 
 	// 记录平仓前持仓信息（用于计算盈利）
 	positions, err := at.trader.GetPositions()
 	if err == nil {
 		for _, pos := range positions {
-			if pos["symbol"] == decision.Symbol && pos["side"] == "long" {
+			if pos.Symbol == decision.Symbol && pos.Side == "long" {
 				// 获取持仓详情
-				entryPrice, _ := pos["entryPrice"].(float64)
-				markPrice, _ := pos["markPrice"].(float64)
-				unrealizedPnl, _ := pos["unRealizedProfit"].(float64)
+				entryPrice := pos.EntryPrice
+				markPrice := pos.MarkPrice
+				unrealizedPnl := pos.UnrealizedProfit
+
+				// 开仓以来已结算的资金费计入该笔交易盈亏
+				actionRecord.Funding = at.syncPositionFunding(pos.Symbol, pos.Key())
 
 				// 计算盈亏百分比
 			profitPct := 0.0
@@ -933,52 +1274,59 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 			}
 
 			// 记录交易结果（延迟到平仓成功后执行）
-			defer func(symbol string, isWin bool, profit float64) {
-				at.recordTradeResult(symbol, isWin, profit)
-			}(decision.Symbol, profitPct >= 0, profitPct*100)
+			defer func(symbol string, isWin bool, profit, pnl float64) {
+				if err == nil {
+					at.recordTradeResult(symbol, isWin, profit, pnl)
+				}
+			}(decision.Symbol, profitPct >= 0, profitPct*100, unrealizedPnl+actionRecord.Funding)
 
-			log.Printf("  📊 平仓前: 入场价=%.6f, 当前价=%.6f, 未实现盈亏=%.2f, 盈亏比例=%.2f%%",
-				entryPrice, markPrice, unrealizedPnl, profitPct*100)
+			log.Printf("  📊 平仓前: 入场价=%.6f, 当前价=%.6f, 未实现盈亏=%.2f, 资金费=%+.2f, 盈亏比例=%.2f%%",
+				entryPrice, markPrice, unrealizedPnl, actionRecord.Funding, profitPct*100)
 			break
 		}
 		}
 	}
 
+	// 取消该方向未成交的限价入场单
+	at.cancelPendingLimitOrder(decision.Symbol, "LONG", "AI决定平仓")
+
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := at.marketData(decision.Symbol)
 	if err != nil {
 		return err
 	}
 	actionRecord.Price = marketData.CurrentPrice
 
 	// 平仓
+	submittedAt := at.now()
 	order, err := at.trader.CloseLong(decision.Symbol, 0) // 0 = 全部平仓
 	if err != nil {
 		return err
 	}
 
-	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	// 记录订单ID、成交均价和手续费
+	at.recordOrderExecution(decision.Symbol, order, submittedAt, actionRecord)
 
 	log.Printf("  ✓ 平多仓成功")
 	return nil
 }
 
 // executeCloseShortWithRecord 执行平空仓并记录详细信息
-func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
+func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) (err error) {
 	log.Printf("  🔄 平空仓: %s", decision.Symbol)
 
 	// 记录平仓前持仓信息（用于计算盈利）
 	positions, err := at.trader.GetPositions()
 	if err == nil {
 		for _, pos := range positions {
-			if pos["symbol"] == decision.Symbol && pos["side"] == "short" {
+			if pos.Symbol == decision.Symbol && pos.Side == "short" {
 				// 获取持仓详情
-				entryPrice, _ := pos["entryPrice"].(float64)
-				markPrice, _ := pos["markPrice"].(float64)
-				unrealizedPnl, _ := pos["unRealizedProfit"].(float64)
+				entryPrice := pos.EntryPrice
+				markPrice := pos.MarkPrice
+				unrealizedPnl := pos.UnrealizedProfit
+
+				// 开仓以来已结算的资金费计入该笔交易盈亏
+				actionRecord.Funding = at.syncPositionFunding(pos.Symbol, pos.Key())
 
 				// 计算盈亏百分比（空仓相反）
 			profitPct := 0.0
@@ -987,34 +1335,38 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 			}
 
 			// 记录交易结果（延迟到平仓成功后执行）
-			defer func(symbol string, isWin bool, profit float64) {
-				at.recordTradeResult(symbol, isWin, profit)
-			}(decision.Symbol, profitPct >= 0, profitPct*100)
+			defer func(symbol string, isWin bool, profit, pnl float64) {
+				if err == nil {
+					at.recordTradeResult(symbol, isWin, profit, pnl)
+				}
+			}(decision.Symbol, profitPct >= 0, profitPct*100, unrealizedPnl+actionRecord.Funding)
 
-			log.Printf("  📊 平仓前: 入场价=%.6f, 当前价=%.6f, 未实现盈亏=%.2f, 盈亏比例=%.2f%%",
-				entryPrice, markPrice, unrealizedPnl, profitPct*100)
+			log.Printf("  📊 平仓前: 入场价=%.6f, 当前价=%.6f, 未实现盈亏=%.2f, 资金费=%+.2f, 盈亏比例=%.2f%%",
+				entryPrice, markPrice, unrealizedPnl, actionRecord.Funding, profitPct*100)
 			break
 		}
 		}
 	}
 
+	// 取消该方向未成交的限价入场单
+	at.cancelPendingLimitOrder(decision.Symbol, "SHORT", "AI决定平仓")
+
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := at.marketData(decision.Symbol)
 	if err != nil {
 		return err
 	}
 	actionRecord.Price = marketData.CurrentPrice
 
 	// 平仓
+	submittedAt := at.now()
 	order, err := at.trader.CloseShort(decision.Symbol, 0) // 0 = 全部平仓
 	if err != nil {
 		return err
 	}
 
-	// 记录订单ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
+	// 记录订单ID、成交均价和手续费
+	at.recordOrderExecution(decision.Symbol, order, submittedAt, actionRecord)
 
 	log.Printf("  ✓ 平空仓成功")
 	return nil
@@ -1073,19 +1425,24 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 	}
 
 	return map[string]interface{}{
-		"trader_id":       at.id,
-		"trader_name":     at.name,
-		"ai_model":        at.aiModel,
-		"exchange":        at.exchange,
-		"is_running":      at.isRunning,
-		"start_time":      at.startTime.Format(time.RFC3339),
-		"runtime_minutes": int(time.Since(at.startTime).Minutes()),
-		"call_count":      at.callCount,
-		"initial_balance": at.initialBalance,
-		"scan_interval":   at.config.ScanInterval.String(),
-		"stop_until":      at.stopUntil.Format(time.RFC3339),
-		"last_reset_time": at.lastResetTime.Format(time.RFC3339),
-		"ai_provider":     aiProvider,
+		"trader_id":            at.id,
+		"trader_name":          at.name,
+		"ai_model":             at.aiModel,
+		"exchange":             at.exchange,
+		"is_running":           at.isRunning,
+		"start_time":           at.startTime.Format(time.RFC3339),
+		"runtime_minutes":      int(at.now().Sub(at.startTime).Minutes()),
+		"call_count":           at.callCount,
+		"initial_balance":      at.initialBalance,
+		"scan_interval":        at.config.ScanInterval.String(),
+		"stop_until":           at.stopUntil.Format(time.RFC3339),
+		"last_reset_time":      at.lastResetTime.Format(time.RFC3339),
+		"ai_provider":          aiProvider,
+		"pending_limit_orders": len(at.pendingLimitOrders),
+		"daily_pnl":            at.dailyPnL,
+		"circuit_breaker":      at.circuitBreakerStatus(),
+		"guardian":             at.guardianStatus(),
+		"stage_constraints":    at.stageConstraintsStatus(),
 	}
 }
 
@@ -1096,33 +1453,12 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 		return nil, fmt.Errorf("获取余额失败: %w", err)
 	}
 
-	// 获取账户字段（使用OKXTrader返回的正确字段名）
-	totalWalletBalance := 0.0
-	totalUnrealizedProfit := 0.0
-	availableBalance := 0.0
-
-	// 从OKXTrader.GetBalance()返回的"total"字段获取总资产
-	if total, ok := balance["total"].(float64); ok {
-		totalWalletBalance = total
-		log.Printf("✓ 从OKX获取总资产: %.8f", total)
-	}
-	// 从OKXTrader.GetBalance()返回的"free"字段获取可用余额
-	if free, ok := balance["free"].(float64); ok {
-		availableBalance = free
-		log.Printf("✓ 从OKX获取可用余额: %.8f", free)
-	}
-	// 从OKXTrader.GetBalance()返回的"used"字段获取已用余额
-	if used, ok := balance["used"].(float64); ok {
-		log.Printf("✓ 从OKX获取已用余额: %.8f", used)
-	}
+	totalWalletBalance := balance.WalletBalance
+	totalUnrealizedProfit := balance.UnrealizedProfit
+	availableBalance := balance.AvailableBalance
 
 	// Total Equity = 钱包余额 + 未实现盈亏
-	totalEquity := totalWalletBalance + totalUnrealizedProfit
-
-	if totalWalletBalance > 0 {
-		log.Printf("✓ 账户余额映射成功: 总资产=%.2f, 可用=%.2f",
-			totalWalletBalance, availableBalance)
-	}
+	totalEquity := balance.TotalEquity()
 
 	// 获取持仓计算总保证金
 	positions, err := at.trader.GetPositions()
@@ -1133,37 +1469,18 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 	totalMarginUsed := 0.0
 	totalUnrealizedPnL := 0.0
 	for _, pos := range positions {
-		// 安全类型断言，避免nil值导致panic
-		markPrice := 0.0
-		if mp, ok := pos["markPrice"].(float64); ok {
-			markPrice = mp
-		}
-
-		quantity := 0.0
-		if qty, ok := pos["positionAmt"].(float64); ok {
-			quantity = qty
-		}
-		if quantity < 0 {
-			quantity = -quantity
-		}
-
-		unrealizedPnl := 0.0
-		if upl, ok := pos["unRealizedProfit"].(float64); ok {
-			unrealizedPnl = upl
-		}
-		totalUnrealizedPnL += unrealizedPnl
-
-		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
-		}
+		totalUnrealizedPnL += pos.UnrealizedProfit
 
 		// 跳过无效持仓数据
-		if markPrice == 0 || quantity == 0 {
+		if pos.MarkPrice == 0 || pos.Quantity == 0 {
 			continue
 		}
 
-		marginUsed := (quantity * markPrice) / float64(leverage)
+		leverage := pos.Leverage
+		if leverage <= 0 {
+			leverage = 10
+		}
+		marginUsed := (pos.Quantity * pos.MarkPrice) / float64(leverage)
 		totalMarginUsed += marginUsed
 	}
 
@@ -1208,69 +1525,36 @@ func (at *AutoTrader) GetPositions() ([]map[string]interface{}, error) {
 
 	var result []map[string]interface{}
 	for _, pos := range positions {
-		// 安全类型断言，避免nil值导致panic
-		symbol := ""
-		if s, ok := pos["symbol"].(string); ok {
-			symbol = s
-		}
-		side := ""
-		if s, ok := pos["side"].(string); ok {
-			side = s
-		}
-		entryPrice := 0.0
-		if ep, ok := pos["entryPrice"].(float64); ok {
-			entryPrice = ep
-		}
-		markPrice := 0.0
-		if mp, ok := pos["markPrice"].(float64); ok {
-			markPrice = mp
-		}
-		quantity := 0.0
-		if qty, ok := pos["positionAmt"].(float64); ok {
-			quantity = qty
-		}
-		if quantity < 0 {
-			quantity = -quantity
-		}
-		unrealizedPnl := 0.0
-		if upl, ok := pos["unRealizedProfit"].(float64); ok {
-			unrealizedPnl = upl
-		}
-		liquidationPrice := 0.0
-		if lp, ok := pos["liquidationPrice"].(float64); ok {
-			liquidationPrice = lp
-		}
-
 		// 跳过无效持仓数据
-		if symbol == "" || side == "" || markPrice == 0 {
+		if pos.Symbol == "" || pos.Side == "" || pos.MarkPrice == 0 {
 			continue
 		}
 
-		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
+		leverage := pos.Leverage
+		if leverage <= 0 {
+			leverage = 10
 		}
 
 		// 计算占用保证金
-		marginUsed := (quantity * markPrice) / float64(leverage)
+		marginUsed := (pos.Quantity * pos.MarkPrice) / float64(leverage)
 
 		// 计算盈亏百分比（基于保证金）
 		// 收益率 = 未实现盈亏 / 保证金 × 100%
 		pnlPct := 0.0
 		if marginUsed > 0 {
-			pnlPct = (unrealizedPnl / marginUsed) * 100
+			pnlPct = (pos.UnrealizedProfit / marginUsed) * 100
 		}
 
 		result = append(result, map[string]interface{}{
-			"symbol":             symbol,
-			"side":               side,
-			"entry_price":        entryPrice,
-			"mark_price":         markPrice,
-			"quantity":           quantity,
+			"symbol":             pos.Symbol,
+			"side":               pos.Side,
+			"entry_price":        pos.EntryPrice,
+			"mark_price":         pos.MarkPrice,
+			"quantity":           pos.Quantity,
 			"leverage":           leverage,
-			"unrealized_pnl":     unrealizedPnl,
+			"unrealized_pnl":     pos.UnrealizedProfit,
 			"unrealized_pnl_pct": pnlPct,
-			"liquidation_price":  liquidationPrice,
+			"liquidation_price":  pos.LiquidationPrice,
 			"margin_used":        marginUsed,
 		})
 	}
@@ -1278,7 +1562,7 @@ func (at *AutoTrader) GetPositions() ([]map[string]interface{}, error) {
 	return result, nil
 }
 
-// sortDecisionsByPriority 对决策排序：先平仓，再开仓，最后hold/wait
+// sortDecisionsByPriority 对决策排序：先平仓/减仓，再开仓/加仓，最后hold/wait
 // 这样可以避免换仓时仓位叠加超限
 func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision {
 	if len(decisions) <= 1 {
@@ -1288,10 +1572,10 @@ func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision
 	// 定义优先级
 	getActionPriority := func(action string) int {
 		switch action {
-		case "close_long", "close_short":
-			return 1 // 最高优先级：先平仓
-		case "open_long", "open_short":
-			return 2 // 次优先级：后开仓
+		case "close_long", "close_short", "reduce_long", "reduce_short":
+			return 1 // 最高优先级：先平仓/减仓
+		case "open_long", "open_short", "add_long", "add_short":
+			return 2 // 次优先级：后开仓/加仓
 		case "hold", "wait":
 			return 3 // 最低优先级：观望
 		default:
@@ -1387,47 +1671,34 @@ func normalizeSymbol(symbol string) string {
 }
 
 // checkAndUpdateStopOrders 检查并更新止盈止损单（使用凯利公式优化）
+// 计算出的止盈止损价交给reconcileProtectiveOrders与交易所现有条件单核对，只修改有变化的部分，返回执行日志
 // 该方法在每个交易周期末尾调用，确保现有持仓的止盈止损点是最优的
-func (at *AutoTrader) checkAndUpdateStopOrders() error {
+func (at *AutoTrader) checkAndUpdateStopOrders() ([]string, error) {
 	// 1. 获取当前持仓
 	positions, err := at.trader.GetPositions()
 	if err != nil {
-		return fmt.Errorf("获取持仓失败: %w", err)
-	}
-
-	if len(positions) == 0 {
-		log.Println("ℹ️ 当前无持仓，跳过止盈止损更新")
-		return nil
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
 	log.Printf("📊 检查 %d 个持仓的止盈止损单...", len(positions))
 
-	// 2. 对每个持仓进行止盈止损检查
+	// 2. 对每个持仓计算止盈止损（计算失败的持仓保留现有条件单）
+	targets := make([]protectiveTarget, 0, len(positions))
 	for _, pos := range positions {
-		symbol := ""
-		if s, ok := pos["symbol"].(string); ok {
-			symbol = s
-		}
-		side := ""
-		if s, ok := pos["side"].(string); ok {
-			side = s
-		}
+		symbol := pos.Symbol
+		side := pos.Side
 
 		// 安全检查
 		if symbol == "" || side == "" {
-			log.Printf("⚠️ 跳过无效持仓数据: %v", pos)
+			log.Printf("⚠️ 跳过无效持仓数据: %+v", pos)
 			continue
 		}
+		targets = append(targets, protectiveTargetOf(pos))
+		target := &targets[len(targets)-1]
 
 		// 3. 使用凯利公式计算动态止盈止损
-		entryPrice := 0.0
-		if ep, ok := pos["entryPrice"].(float64); ok {
-			entryPrice = ep
-		}
-		currentPrice := 0.0
-		if mp, ok := pos["markPrice"].(float64); ok {
-			currentPrice = mp
-		}
+		entryPrice := pos.EntryPrice
+		currentPrice := pos.MarkPrice
 
 		if entryPrice <= 0 || currentPrice <= 0 {
 			log.Printf("⚠️ %s 无效价格: entry=%.6f, current=%.6f", symbol, entryPrice, currentPrice)
@@ -1471,51 +1742,27 @@ func (at *AutoTrader) checkAndUpdateStopOrders() error {
 			continue
 		}
 
-		// 4. 更新止盈止损单
-		quantity := 0.0
-		if qty, ok := pos["positionAmt"].(float64); ok {
-			quantity = qty
-		}
-		if quantity < 0 {
-			quantity = -quantity
-		}
-
-		positionSide := strings.ToUpper(side)
-
-		// 更新止损单
-		if err := at.trader.SetStopLoss(symbol, positionSide, quantity, stopLossPrice); err != nil {
-			log.Printf("⚠️ 更新止损单失败 (%s %s @ %.6f): %v", symbol, positionSide, stopLossPrice, err)
-		} else {
-			log.Printf("✅ 更新止损单成功: %s %s @ %.6f (保护%.1f%%利润)",
-				symbol, positionSide, stopLossPrice, currentProfitPct*100)
-		}
-
-		// 更新止盈单
-		if err := at.trader.SetTakeProfit(symbol, positionSide, quantity, takeProfitPrice); err != nil {
-			log.Printf("⚠️ 更新止盈单失败 (%s %s @ %.6f): %v", symbol, positionSide, takeProfitPrice, err)
-		} else {
-			log.Printf("✅ 更新止盈单成功: %s %s @ %.6f (目标%.1f%%收益)",
-				symbol, positionSide, takeProfitPrice,
-				func() float64 {
-					if side == "long" {
-						return (takeProfitPrice-entryPrice)/entryPrice*100
-					}
-					return (entryPrice-takeProfitPrice)/entryPrice*100
-				}())
-		}
-
-		// 更新历史统计数据（如果这次交易完成）
-		// 注意：这里仅在持仓盈利或亏损时更新，实际平仓时会在平仓逻辑中更新
+		// 4. 记录期望的止盈止损，统一核对
+		target.StopLoss = stopLossPrice
+		target.TakeProfit = takeProfitPrice
+		log.Printf("🎯 [%s %s] 止损 %.6f (保护%.1f%%利润), 止盈 %.6f",
+			symbol, target.PositionSide, stopLossPrice, currentProfitPct*100, takeProfitPrice)
 	}
 
-	return nil
+	// 5. 与交易所现有条件单核对（同时撤销已平仓持仓的孤立条件单）
+	return reconcileProtectiveOrders(at.trader, targets)
 }
 
-// recordTradeResult 记录交易结果到凯利公式管理器
+// recordTradeResult 记录交易结果到凯利公式管理器和学习阶段约束
 // isWin: 是否盈利
 // profitPct: 盈利百分比（正数为盈利，负数为亏损）
-func (at *AutoTrader) recordTradeResult(symbol string, isWin bool, profitPct float64) {
+// pnl: 盈亏金额（USDT）
+func (at *AutoTrader) recordTradeResult(symbol string, isWin bool, profitPct, pnl float64) {
 	at.kellyManager.UpdateHistoricalStats(symbol, isWin, profitPct)
+	if at.constraints != nil {
+		at.constraints.RecordTradeResult(isWin, pnl, pnl/at.initialBalance)
+		at.saveConstraintsState()
+	}
 	log.Printf("📊 记录交易结果: %s %s, 盈利%.2f%%",
 		symbol, func() string {
 			if isWin {
@@ -0,0 +1,210 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"strings"
+)
+
+// executeAdjustWithRecord 执行减仓(reduce_*)/加仓(add_*)并记录详细信息
+// 数量按占当前持仓的百分比或美元价值计算，完成后按新的持仓数量调整止盈止损单
+func (at *AutoTrader) executeAdjustWithRecord(d *decision.Decision, actionRecord *logger.DecisionAction) error {
+	side, positionSide := "long", "LONG"
+	if strings.HasSuffix(d.Action, "_short") {
+		side, positionSide = "short", "SHORT"
+	}
+	reducing := strings.HasPrefix(d.Action, "reduce_")
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return fmt.Errorf("获取持仓失败: %w", err)
+	}
+	var pos *Position
+	for i := range positions {
+		if positions[i].Symbol == d.Symbol && positions[i].Side == side {
+			pos = &positions[i]
+			break
+		}
+	}
+	if pos == nil || pos.Quantity <= 0 {
+		return fmt.Errorf("❌ %s 没有%s仓，无法执行 %s", d.Symbol, map[string]string{"long": "多", "short": "空"}[side], d.Action)
+	}
+
+	marketData, err := at.marketData(d.Symbol)
+	if err != nil {
+		return err
+	}
+	price := marketData.CurrentPrice
+	quantity := pos.Quantity * d.SizePercent / 100
+	if d.PositionSizeUSD > 0 {
+		quantity = d.PositionSizeUSD / price
+	}
+	actionRecord.Quantity = quantity
+	actionRecord.Price = price
+
+	// 先记下当前的止盈止损价，下单后按新数量重新设置（部分交易所开仓时会撤销该币种的所有挂单）
+	stopLoss, takeProfit := 0.0, 0.0
+	if orders, err := at.trader.GetProtectiveOrders(d.Symbol); err != nil {
+		log.Printf("  ⚠ 查询止盈止损单失败，调整后不重设: %v", err)
+	} else {
+		stopLoss, takeProfit = protectivePrices(orders, d.Symbol, positionSide)
+	}
+
+	var remaining float64
+	if reducing {
+		// 减仓数量达到持仓数量时按平仓处理（同时记录交易结果）
+		if quantity >= pos.Quantity*(1-1e-9) {
+			log.Printf("  ℹ️ %s 减仓数量 %.6f 不小于持仓 %.6f，按全部平仓处理", d.Symbol, quantity, pos.Quantity)
+			if side == "long" {
+				return at.executeCloseLongWithRecord(d, actionRecord)
+			}
+			return at.executeCloseShortWithRecord(d, actionRecord)
+		}
+		if err := at.executeReduce(d.Symbol, positionSide, quantity, actionRecord); err != nil {
+			return err
+		}
+		at.bookReduceFunding(pos, actionRecord.Quantity, actionRecord)
+		remaining = pos.Quantity - actionRecord.Quantity
+	} else {
+		if err := at.executeAdd(d, pos, positionSide, quantity, marketData, stopLoss, takeProfit, actionRecord); err != nil {
+			return err
+		}
+		remaining = pos.Quantity + actionRecord.Quantity
+		// 加仓时给出的止损止盈作为整个持仓的新价格
+		if d.StopLoss > 0 {
+			stopLoss = d.StopLoss
+		}
+		if d.TakeProfit > 0 {
+			takeProfit = d.TakeProfit
+		}
+	}
+
+	// 按调整后的持仓数量重设止盈止损单
+	if stopLoss <= 0 && takeProfit <= 0 {
+		return nil
+	}
+	lines, err := resizeProtectiveOrders(at.trader, protectiveTarget{
+		Symbol:       d.Symbol,
+		PositionSide: positionSide,
+		Quantity:     remaining,
+		StopLoss:     stopLoss,
+		TakeProfit:   takeProfit,
+	})
+	if err != nil {
+		log.Printf("  ⚠ 调整止盈止损单失败: %v", err)
+		return nil
+	}
+	for _, line := range lines {
+		log.Printf("  %s", line)
+	}
+	return nil
+}
+
+// executeReduce 按数量部分平仓
+func (at *AutoTrader) executeReduce(symbol, positionSide string, quantity float64, actionRecord *logger.DecisionAction) error {
+	log.Printf("  ➖ 减仓: %s %s 数量 %.6f", symbol, positionSide, quantity)
+
+	submittedAt := at.now()
+	var order *OrderResult
+	var err error
+	if positionSide == "LONG" {
+		order, err = at.trader.CloseLong(symbol, quantity)
+	} else {
+		order, err = at.trader.CloseShort(symbol, quantity)
+	}
+	if err != nil {
+		return err
+	}
+
+	at.recordOrderExecution(symbol, order, submittedAt, actionRecord)
+	log.Printf("  ✓ 减仓成功，订单ID: %s, 数量: %.6f, 成交均价: %.4f", actionRecord.OrderID, actionRecord.Quantity, actionRecord.Price)
+	return nil
+}
+
+// executeAdd 按市价加仓（杠杆未指定时沿用持仓杠杆）
+// 风控检查与开仓一致：资金费成本、保证金、单币种持仓价值上限、组合风险和强平距离（按加仓后的合并持仓）；
+// 未给出新的止损止盈时按持仓当前的止损止盈（stopLoss/takeProfit）检查
+func (at *AutoTrader) executeAdd(d *decision.Decision, pos *Position, positionSide string, quantity float64, marketData *market.Data, stopLoss, takeProfit float64, actionRecord *logger.DecisionAction) error {
+	leverage := d.Leverage
+	if leverage <= 0 {
+		leverage = pos.Leverage
+	}
+	if leverage <= 0 {
+		return fmt.Errorf("无法确定 %s 的加仓杠杆", d.Symbol)
+	}
+	actionRecord.Leverage = leverage
+
+	check := *d
+	check.Leverage = leverage
+	if check.StopLoss <= 0 {
+		check.StopLoss = stopLoss
+	}
+	if check.TakeProfit <= 0 {
+		check.TakeProfit = takeProfit
+	}
+
+	// 资金费成本检查（预计持有期资金费超过止盈距离的设定比例时拒绝加仓）
+	if err := at.checkFundingCost(&check, marketData, actionRecord); err != nil {
+		return err
+	}
+
+	sizeUSD := quantity * actionRecord.Price
+	balance, balanceErr := at.trader.GetBalance()
+	if balanceErr == nil {
+		maxPositionValue := balance.AvailableBalance * 0.80 * float64(leverage)
+		if sizeUSD > maxPositionValue {
+			log.Printf("  ⚠️ 保证金检查: AI请求加仓 $%.2f，可用保证金 $%.2f，杠杆 %dx，最大可加仓 $%.2f",
+				sizeUSD, balance.AvailableBalance, leverage, maxPositionValue)
+			sizeUSD = maxPositionValue
+		}
+
+		// 单币种持仓价值上限（与开仓相同）：加仓后的合计持仓不能超过上限
+		existingValue := pos.Quantity * actionRecord.Price
+		maxSymbolValue := decision.MaxPositionValue(d.Symbol, balance.TotalEquity())
+		if existingValue+sizeUSD > maxSymbolValue*1.01 {
+			return fmt.Errorf("加仓后 %s 持仓价值 $%.0f 超过单币种上限 $%.0f（当前持仓 $%.0f）",
+				d.Symbol, existingValue+sizeUSD, maxSymbolValue, existingValue)
+		}
+	} else {
+		log.Printf("  ⚠️ 无法获取账户余额进行保证金检查: %v, 继续使用AI决定的仓位", balanceErr)
+	}
+	// 组合风险检查（同一用户所有交易员的合计敞口）
+	sizeUSD, reservation, err := at.applyExposureLimit(d.Symbol, strings.ToLower(positionSide), sizeUSD, leverage)
+	if err != nil {
+		return err
+	}
+	defer reservation.Release() // 未下单或下单失败时释放预留额度
+
+	// 强平距离检查（按调整后的最终金额和加仓后的合并持仓计算，不满足时逐仓降低杠杆、全仓减小金额或拒绝加仓）
+	sizeUSD, err = at.checkLiquidationDistance(&check, marketData, balance, sizeUSD, pos, actionRecord)
+	if err != nil {
+		return err
+	}
+	leverage = check.Leverage
+	quantity = sizeUSD / actionRecord.Price
+	const minPositionSizeUSD = 10.0
+	if sizeUSD < minPositionSizeUSD {
+		return fmt.Errorf("加仓金额过小: $%.2f < 最小要求 $%.2f", sizeUSD, minPositionSizeUSD)
+	}
+	actionRecord.Quantity = quantity
+
+	log.Printf("  ➕ 加仓: %s %s 数量 %.6f (约 $%.2f, %dx)", d.Symbol, positionSide, quantity, sizeUSD, leverage)
+	submittedAt := at.now()
+	var order *OrderResult
+	if positionSide == "LONG" {
+		order, err = at.trader.OpenLong(d.Symbol, quantity, leverage)
+	} else {
+		order, err = at.trader.OpenShort(d.Symbol, quantity, leverage)
+	}
+	if err != nil {
+		return err
+	}
+	reservation.Commit()
+
+	at.recordOrderExecution(d.Symbol, order, submittedAt, actionRecord)
+	log.Printf("  ✓ 加仓成功，订单ID: %s, 数量: %.6f, 成交均价: %.4f", actionRecord.OrderID, actionRecord.Quantity, actionRecord.Price)
+	return nil
+}
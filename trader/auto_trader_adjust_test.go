@@ -0,0 +1,147 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+)
+
+// requireProtective 返回持仓唯一的某类型止盈止损单
+func requireProtective(t *testing.T, tr Trader, symbol, kind string) ProtectiveOrder {
+	t.Helper()
+	orders := protectiveOrdersOf(t, tr, symbol, kind)
+	if len(orders) != 1 {
+		t.Fatalf("应有1个%s单, got %+v", protectiveKindName(kind), orders)
+	}
+	return orders[0]
+}
+
+func TestAutoTraderReduceAndAddResizeStops(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, paper := newLimitTestAutoTrader(t, feed, &now)
+
+	open := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 500, StopLoss: 90, TakeProfit: 130}
+	if err := at.executeDecisionWithRecord(open, &logger.DecisionAction{}); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	pos := requirePosition(t, paper, "BTCUSDT", "long")
+
+	// 减仓40%：止盈止损价不变，数量随持仓调整
+	reduce := &decision.Decision{Symbol: "BTCUSDT", Action: "reduce_long", SizePercent: 40}
+	record := &logger.DecisionAction{}
+	if err := at.executeDecisionWithRecord(reduce, record); err != nil {
+		t.Fatalf("减仓失败: %v", err)
+	}
+	remaining := pos.Quantity * 0.6
+	if got := requirePosition(t, paper, "BTCUSDT", "long"); !almostEqual(got.Quantity, remaining) {
+		t.Errorf("减仓后持仓应为 %.6f, got %.6f", remaining, got.Quantity)
+	}
+	if !almostEqual(record.Quantity, pos.Quantity*0.4) || record.OrderID == "" {
+		t.Errorf("减仓记录错误: %+v", record)
+	}
+	stop := requireProtective(t, paper, "BTCUSDT", ProtectiveKindStopLoss)
+	takeProfit := requireProtective(t, paper, "BTCUSDT", ProtectiveKindTakeProfit)
+	if !almostEqual(stop.TriggerPrice, 90) || !almostEqual(stop.Quantity, remaining) ||
+		!almostEqual(takeProfit.TriggerPrice, 130) || !almostEqual(takeProfit.Quantity, remaining) {
+		t.Errorf("减仓后止盈止损单应按新数量调整: %+v %+v", stop, takeProfit)
+	}
+
+	// 按金额加仓并给出新止损：沿用持仓杠杆，止盈价不变
+	add := &decision.Decision{Symbol: "BTCUSDT", Action: "add_long", PositionSizeUSD: 200, StopLoss: 95}
+	record = &logger.DecisionAction{}
+	if err := at.executeDecisionWithRecord(add, record); err != nil {
+		t.Fatalf("加仓失败: %v", err)
+	}
+	total := remaining + record.Quantity
+	if got := requirePosition(t, paper, "BTCUSDT", "long"); !almostEqual(got.Quantity, total) || record.Leverage != 5 {
+		t.Errorf("加仓后持仓应为 %.6f (5x), got %+v, 记录 %+v", total, got, record)
+	}
+	stop = requireProtective(t, paper, "BTCUSDT", ProtectiveKindStopLoss)
+	takeProfit = requireProtective(t, paper, "BTCUSDT", ProtectiveKindTakeProfit)
+	if !almostEqual(stop.TriggerPrice, 95) || !almostEqual(stop.Quantity, total) ||
+		!almostEqual(takeProfit.TriggerPrice, 130) || !almostEqual(takeProfit.Quantity, total) {
+		t.Errorf("加仓后止盈止损单错误: %+v %+v", stop, takeProfit)
+	}
+
+	// 没有空仓时不能减空仓
+	if err := at.executeDecisionWithRecord(&decision.Decision{Symbol: "BTCUSDT", Action: "reduce_short", SizePercent: 50}, &logger.DecisionAction{}); err == nil {
+		t.Error("没有持仓时减仓应失败")
+	}
+
+	// 减仓100%等同于平仓
+	if err := at.executeDecisionWithRecord(&decision.Decision{Symbol: "BTCUSDT", Action: "reduce_long", SizePercent: 100}, &logger.DecisionAction{}); err != nil {
+		t.Fatalf("全部减仓失败: %v", err)
+	}
+	if pos, ok := findPosition(t, paper, "BTCUSDT", "long"); ok {
+		t.Errorf("减仓100%%后不应有持仓: %+v", pos)
+	}
+}
+
+func TestAutoTraderAddRunsOpenRiskChecks(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := newFakePriceFeed(map[string]float64{"SOLUSDT": 100})
+	at, paper := newLimitTestAutoTrader(t, feed, &now)
+
+	open := &decision.Decision{Symbol: "SOLUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 1000, StopLoss: 90, TakeProfit: 130}
+	if err := at.executeDecisionWithRecord(open, &logger.DecisionAction{}); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	pos := requirePosition(t, paper, "SOLUSDT", "long")
+
+	// 山寨币单币种上限为1.5倍净值（约$1500），已有约$1000，再加$600超过上限
+	err := at.executeDecisionWithRecord(&decision.Decision{Symbol: "SOLUSDT", Action: "add_long", PositionSizeUSD: 600}, &logger.DecisionAction{})
+	if err == nil || !strings.Contains(err.Error(), "单币种上限") {
+		t.Fatalf("加仓后超过单币种上限应拒绝: %v", err)
+	}
+
+	// 20x加仓时合并持仓的强平价在止损90之上，应降低杠杆
+	record := &logger.DecisionAction{}
+	if err := at.executeDecisionWithRecord(&decision.Decision{Symbol: "SOLUSDT", Action: "add_long", Leverage: 20, PositionSizeUSD: 200}, record); err != nil {
+		t.Fatalf("加仓失败: %v", err)
+	}
+	if record.Leverage >= 20 || record.LiquidationPrice >= 90 || !strings.Contains(record.LiquidationNote, "20x →") {
+		t.Errorf("加仓应按合并持仓检查强平距离并降低杠杆: %+v", record)
+	}
+	if got := requirePosition(t, paper, "SOLUSDT", "long"); !almostEqual(got.Quantity, pos.Quantity+record.Quantity) {
+		t.Errorf("降杠杆后应继续加仓: %+v", got)
+	}
+
+	// 资金费成本过高时拒绝加仓（按持仓当前的止盈价计算）
+	at.config.FundingGuard = FundingGuardConfig{MaxTPFraction: 0.3}
+	at.marketData = func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 100, FundingRate: 0.05}, nil
+	}
+	err = at.executeDecisionWithRecord(&decision.Decision{Symbol: "SOLUSDT", Action: "add_long", PositionSizeUSD: 50}, &logger.DecisionAction{})
+	if err == nil || !strings.Contains(err.Error(), "资金费成本过高") {
+		t.Fatalf("资金费成本过高时应拒绝加仓: %v", err)
+	}
+}
+
+func TestSortDecisionsByPriorityAdjustActions(t *testing.T) {
+	sorted := sortDecisionsByPriority([]decision.Decision{
+		{Symbol: "A", Action: "hold"},
+		{Symbol: "B", Action: "add_long"},
+		{Symbol: "C", Action: "reduce_short"},
+		{Symbol: "D", Action: "open_long"},
+		{Symbol: "E", Action: "close_long"},
+	})
+	// 减仓与平仓一起先执行，加仓与开仓一起后执行
+	groups := []string{"CE", "BD", "A"}
+	for i, d := range sorted {
+		group := groups[0]
+		if i >= 2 {
+			group = groups[1]
+		}
+		if i >= 4 {
+			group = groups[2]
+		}
+		if !strings.Contains(group, d.Symbol) {
+			t.Errorf("第%d个决策应属于 %s, got %s %s", i+1, group, d.Symbol, d.Action)
+		}
+	}
+}
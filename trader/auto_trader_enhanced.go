@@ -3,17 +3,32 @@ package trader
 import (
         "fmt"
         "log"
+        "math"
         "nofx/decision"
         "nofx/logger"
-        "strings"
+        "sync"
         "time"
 )
 
+// StopMode 持仓的止损方式
+type StopMode string
+
+const (
+        StopModeFixed    StopMode = "fixed"    // 每个周期按凯利公式重新计算固定止损价
+        StopModeTrailing StopMode = "trailing" // 跟踪止损（交易所原生或本地模拟），由交易所侧实时跟随价格
+)
+
 // EnhancedAutoTrader 增强版自动交易器
 // 集成增强版Kelly公式管理器，支持实时峰值追踪和数据持久化
 type EnhancedAutoTrader struct {
         *AutoTrader
         kellyManagerEnhanced *decision.KellyStopManagerEnhanced // 增强版凯利公式管理器
+
+        // 止损方式：默认方式和按持仓（symbol_side）单独指定的方式
+        stopModeMu      sync.Mutex
+        defaultStopMode StopMode
+        stopModes       map[string]StopMode
+        trailingPlaced  map[string]bool // 已设置跟踪止损的持仓，避免每个周期重复下单
 }
 
 // NewEnhancedAutoTrader 创建增强版自动交易器
@@ -33,6 +48,9 @@ func NewEnhancedAutoTrader(config AutoTraderConfig) (*EnhancedAutoTrader, error)
         return &EnhancedAutoTrader{
                 AutoTrader:           baseTrader,
                 kellyManagerEnhanced: kellyManager,
+                defaultStopMode:      StopModeFixed,
+                stopModes:            make(map[string]StopMode),
+                trailingPlaced:       make(map[string]bool),
         }, nil
 }
 
@@ -52,6 +70,68 @@ func (eat *EnhancedAutoTrader) Shutdown() error {
         return nil
 }
 
+// SetDefaultStopMode 设置未单独指定的持仓使用的止损方式
+func (eat *EnhancedAutoTrader) SetDefaultStopMode(mode StopMode) error {
+        if mode != StopModeFixed && mode != StopModeTrailing {
+                return fmt.Errorf("无效的止损方式: %s", mode)
+        }
+        eat.stopModeMu.Lock()
+        defer eat.stopModeMu.Unlock()
+        eat.defaultStopMode = mode
+        return nil
+}
+
+// SetPositionStopMode 为单个持仓指定止损方式（side: long/short）
+func (eat *EnhancedAutoTrader) SetPositionStopMode(symbol, side string, mode StopMode) error {
+        if mode != StopModeFixed && mode != StopModeTrailing {
+                return fmt.Errorf("无效的止损方式: %s", mode)
+        }
+        eat.stopModeMu.Lock()
+        defer eat.stopModeMu.Unlock()
+        eat.stopModes[symbol+"_"+side] = mode
+        log.Printf("⚙️ [%s] %s 持仓止损方式设置为 %s", symbol, side, mode)
+        return nil
+}
+
+// stopModeOf 获取持仓的止损方式
+func (eat *EnhancedAutoTrader) stopModeOf(posKey string) StopMode {
+        eat.stopModeMu.Lock()
+        defer eat.stopModeMu.Unlock()
+        if mode, ok := eat.stopModes[posKey]; ok {
+                return mode
+        }
+        if eat.defaultStopMode == "" {
+                return StopModeFixed
+        }
+        return eat.defaultStopMode
+}
+
+// pruneStopModes 清除已平仓持仓的止损方式和跟踪止损记录
+func (eat *EnhancedAutoTrader) pruneStopModes(open map[string]bool) {
+        eat.stopModeMu.Lock()
+        defer eat.stopModeMu.Unlock()
+        for key := range eat.stopModes {
+                if !open[key] {
+                        delete(eat.stopModes, key)
+                }
+        }
+        for key := range eat.trailingPlaced {
+                if !open[key] {
+                        delete(eat.trailingPlaced, key)
+                }
+        }
+}
+
+// trailingCallbackRate 以凯利动态止损价与当前价的距离作为跟踪止损回调比例（限制在交易所允许范围内）
+// 跟踪止损设置时的止损位置与固定止损一致，之后随价格有利变动自动上移
+func trailingCallbackRate(currentPrice, stopPrice float64) float64 {
+        if currentPrice <= 0 || stopPrice <= 0 {
+                return defaultTrailingCallbackRate
+        }
+        rate := math.Abs(currentPrice-stopPrice) / currentPrice
+        return math.Min(math.Max(rate, minTrailingCallbackRate), maxTrailingCallbackRate)
+}
+
 // checkAndUpdateStopOrdersEnhanced 增强版止盈止损检查
 func (eat *EnhancedAutoTrader) checkAndUpdateStopOrdersEnhanced() error {
         log.Println("🔄 开始执行增强版凯利公式动态止盈止损检查...")
@@ -64,12 +144,22 @@ func (eat *EnhancedAutoTrader) checkAndUpdateStopOrdersEnhanced() error {
 
         log.Printf("📊 当前持仓数量: %d", len(positions))
 
-        // 2. 对每个持仓进行止盈止损检查
+        openPositions := make(map[string]bool, len(positions))
         for _, pos := range positions {
-                symbol := pos["symbol"].(string)
-                side := pos["side"].(string)
-                entryPrice := pos["entryPrice"].(float64)
-                currentPrice := pos["markPrice"].(float64)
+                openPositions[pos.Symbol+"_"+pos.Side] = true
+        }
+        eat.pruneStopModes(openPositions)
+
+        // 2. 对每个持仓进行止盈止损检查（计算失败的持仓保留现有条件单）
+        targets := make([]protectiveTarget, 0, len(positions))
+        for _, pos := range positions {
+                symbol := pos.Symbol
+                side := pos.Side
+                posKey := symbol + "_" + side
+                targets = append(targets, protectiveTargetOf(pos))
+                target := &targets[len(targets)-1]
+                entryPrice := pos.EntryPrice
+                currentPrice := pos.MarkPrice
 
                 // 计算当前盈利百分比并更新峰值
                 var currentProfitPct float64
@@ -105,31 +195,45 @@ func (eat *EnhancedAutoTrader) checkAndUpdateStopOrdersEnhanced() error {
                         continue
                 }
 
-                // 4. 更新止盈止损单
-                quantity := pos["positionAmt"].(float64)
-                if quantity < 0 {
-                        quantity = -quantity
-                }
-
-                positionSide := strings.ToUpper(side)
-
-                // 更新止损单
-                if err := eat.trader.SetStopLoss(symbol, positionSide, quantity, dynamicStopLossPrice); err != nil {
-                        log.Printf("⚠️ [%s] 更新增强版止损单失败 (%s): %v", symbol, positionSide, err)
+                // 4. 记录期望的止盈止损，统一核对
+                positionSide := pos.PositionSide()
+                target.TakeProfit = optimalTakeProfitPrice
+                if eat.stopModeOf(posKey) == StopModeTrailing {
+                        // 跟踪止损只设置一次，之后由交易所（或本地模拟）跟随价格移动，固定止损不再管理
+                        eat.stopModeMu.Lock()
+                        placed := eat.trailingPlaced[posKey]
+                        eat.stopModeMu.Unlock()
+                        if !placed && eat.hasEmulatedTrailingStop(symbol, positionSide) {
+                                // 重启后恢复的本地跟踪止损继续使用，不重新设置（保留已记录的极值价）
+                                eat.stopModeMu.Lock()
+                                eat.trailingPlaced[posKey] = true
+                                eat.stopModeMu.Unlock()
+                                placed = true
+                        }
+                        if !placed {
+                                callbackRate := trailingCallbackRate(currentPrice, dynamicStopLossPrice)
+                                if err := eat.trader.SetTrailingStop(symbol, positionSide, pos.Quantity, callbackRate, 0); err != nil {
+                                        log.Printf("⚠️ [%s] 设置跟踪止损失败 (%s): %v", symbol, positionSide, err)
+                                } else {
+                                        eat.stopModeMu.Lock()
+                                        eat.trailingPlaced[posKey] = true
+                                        eat.stopModeMu.Unlock()
+                                        log.Printf("✅ [%s] 设置跟踪止损成功: %s 回调 %.2f%%", symbol, positionSide, callbackRate*100)
+                                }
+                        }
                 } else {
-                        log.Printf("✅ [%s] 更新增强版止损单成功: %s @ %.6f (峰值盈利: %.2f%%)",
-                                symbol, positionSide, dynamicStopLossPrice, peakProfit*100)
-                }
-
-                // 更新止盈单
-                if err := eat.trader.SetTakeProfit(symbol, positionSide, quantity, optimalTakeProfitPrice); err != nil {
-                        log.Printf("⚠️ [%s] 更新增强版止盈单失败 (%s): %v", symbol, positionSide, err)
-                } else {
-                        log.Printf("✅ [%s] 更新增强版止盈单成功: %s @ %.6f", symbol, positionSide, optimalTakeProfitPrice)
+                        target.StopLoss = dynamicStopLossPrice
+                        log.Printf("🎯 [%s] 增强版止损 %s @ %.6f (峰值盈利: %.2f%%)", symbol, positionSide, dynamicStopLossPrice, peakProfit*100)
                 }
+        }
 
-                // 短暂延迟，避免API调用过于频繁
-                time.Sleep(500 * time.Millisecond)
+        // 5. 与交易所现有条件单核对，只修改有变化的部分
+        lines, err := reconcileProtectiveOrders(eat.trader, targets)
+        if err != nil {
+                return err
+        }
+        for _, line := range lines {
+                log.Println(line)
         }
 
         log.Printf("✅ 增强版止盈止损检查完成，共处理 %d 个持仓", len(positions))
@@ -311,6 +415,15 @@ func (eat *EnhancedAutoTrader) OptimizeParameters() {
 func (eat *EnhancedAutoTrader) ensureInitialized() {
         eat.initializeEnhancedFields()
 
+        eat.stopModeMu.Lock()
+        if eat.stopModes == nil {
+                eat.stopModes = make(map[string]StopMode)
+        }
+        if eat.trailingPlaced == nil {
+                eat.trailingPlaced = make(map[string]bool)
+        }
+        eat.stopModeMu.Unlock()
+
         // 确保Kelly管理器已初始化
         if eat.kellyManagerEnhanced == nil {
                 dataFilePath := fmt.Sprintf("data/kelly_stats_%s.json", eat.id)
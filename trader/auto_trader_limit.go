@@ -0,0 +1,250 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultLimitOrderTTL 限价入场单默认最长等待时间
+	defaultLimitOrderTTL = 30 * time.Minute
+	// maxLimitOrderCancelAttempts 撤单失败的最大重试次数（超过后不再跟踪，避免已消失的订单一直残留）
+	maxLimitOrderCancelAttempts = 3
+)
+
+// LimitOrderStore 未成交限价入场单持久化接口（由config.Database实现）
+// 止盈止损价只在本地记录，重启后需要恢复才能在成交后补设止盈止损、超时撤单
+type LimitOrderStore interface {
+	GetLimitOrderState(traderID string) (string, error)
+	SaveLimitOrderState(traderID string, state string) error
+}
+
+// pendingLimitOrder 等待成交的限价入场单（跨周期跟踪，成交后补设止盈止损，超时撤单）
+type pendingLimitOrder struct {
+	OrderID        string    `json:"order_id"`
+	Symbol         string    `json:"symbol"`
+	PositionSide   string    `json:"position_side"` // LONG/SHORT
+	Quantity       float64   `json:"quantity"`
+	LimitPrice     float64   `json:"limit_price"`
+	StopLoss       float64   `json:"stop_loss"`
+	TakeProfit     float64   `json:"take_profit"`
+	PlacedAt       time.Time `json:"placed_at"`
+	cancelAttempts int
+}
+
+// loadPendingLimitOrders 恢复重启前跟踪中的限价入场单（读取失败时返回空表）
+func loadPendingLimitOrders(store LimitOrderStore, traderID, name string) map[string]*pendingLimitOrder {
+	orders := make(map[string]*pendingLimitOrder)
+	if store == nil {
+		return orders
+	}
+	data, err := store.GetLimitOrderState(traderID)
+	if err != nil {
+		log.Printf("⚠️ [%s] 读取限价入场单失败: %v", name, err)
+		return orders
+	}
+	if data == "" {
+		return orders
+	}
+	if err := json.Unmarshal([]byte(data), &orders); err != nil {
+		log.Printf("⚠️ [%s] 解析限价入场单失败: %v", name, err)
+		return make(map[string]*pendingLimitOrder)
+	}
+	if len(orders) > 0 {
+		log.Printf("⏳ [%s] 恢复 %d 个未成交限价入场单", name, len(orders))
+	}
+	return orders
+}
+
+// savePendingLimitOrders 持久化跟踪中的限价入场单并更新组合风险汇总的挂单快照（调用方需持有execMu）
+func (at *AutoTrader) savePendingLimitOrders() {
+	at.publishPendingEntries()
+	if at.limitOrderStore == nil {
+		return
+	}
+	data, err := json.Marshal(at.pendingLimitOrders)
+	if err != nil {
+		log.Printf("⚠️ 序列化限价入场单失败: %v", err)
+		return
+	}
+	if err := at.limitOrderStore.SaveLimitOrderState(at.id, string(data)); err != nil {
+		log.Printf("⚠️ 保存限价入场单失败: %v", err)
+	}
+}
+
+// executeLimitEntryWithRecord 以限价单开仓
+// 同方向已有挂单时：价格相同则保留原挂单（不重置超时），价格不同则改单
+func (at *AutoTrader) executeLimitEntryWithRecord(d *decision.Decision, positionSide string, quantity float64, actionRecord *logger.DecisionAction) error {
+	defer at.savePendingLimitOrders()
+	key := d.Symbol + "_" + strings.ToLower(positionSide)
+	actionRecord.Price = d.LimitPrice
+
+	if pending, ok := at.pendingLimitOrders[key]; ok {
+		pending.StopLoss = d.StopLoss
+		pending.TakeProfit = d.TakeProfit
+		actionRecord.OrderID = pending.OrderID
+
+		if pending.LimitPrice == d.LimitPrice {
+			log.Printf("  ⏳ %s 已有相同价格的限价单挂单中 (订单ID: %s)，更新止盈止损后继续等待", d.Symbol, pending.OrderID)
+			return nil
+		}
+
+		order, err := at.trader.AmendOrder(d.Symbol, pending.OrderID, quantity, d.LimitPrice)
+		if err == nil {
+			log.Printf("  ✏️ 限价单已改价: %s %.4f -> %.4f", d.Symbol, pending.LimitPrice, d.LimitPrice)
+			pending.LimitPrice = d.LimitPrice
+			pending.Quantity = quantity
+			if order.OrderID != "" {
+				pending.OrderID = order.OrderID
+				actionRecord.OrderID = order.OrderID
+			}
+			if order.Status == OrderStatusFilled {
+				delete(at.pendingLimitOrders, key)
+				at.onLimitEntryFilled(pending, quantity)
+			}
+			return nil
+		}
+
+		// 改单失败（可能已成交或已被撤销），撤掉旧单后重新下单
+		log.Printf("  ⚠ 修改限价单失败，撤单后重新下单: %v", err)
+		at.cancelPendingLimitOrder(d.Symbol, positionSide, "改单失败")
+	}
+
+	submittedAt := at.now()
+	order, err := at.trader.PlaceLimitOrder(d.Symbol, positionSide, quantity, d.LimitPrice, d.Leverage, at.config.LimitOrderTimeInForce)
+	if err != nil {
+		return err
+	}
+
+	orderID := order.OrderID
+	actionRecord.OrderID = orderID
+
+	pending := &pendingLimitOrder{
+		OrderID:      orderID,
+		Symbol:       d.Symbol,
+		PositionSide: positionSide,
+		Quantity:     quantity,
+		LimitPrice:   d.LimitPrice,
+		StopLoss:     d.StopLoss,
+		TakeProfit:   d.TakeProfit,
+		PlacedAt:     at.now(),
+	}
+
+	status := order.Status
+	executedQty := order.ExecutedQty
+	switch {
+	case status == OrderStatusFilled:
+		at.recordOrderExecution(d.Symbol, order, submittedAt, actionRecord)
+		log.Printf("  ✓ 限价单立即成交，订单ID: %s, 数量: %.4f, 成交均价: %.4f", orderID, quantity, actionRecord.Price)
+		at.onLimitEntryFilled(pending, quantity)
+		return nil
+	case executedQty > 0 && (status == OrderStatusExpired || status == OrderStatusCanceled):
+		// IOC部分成交后剩余部分被撤销
+		at.recordOrderExecution(d.Symbol, order, submittedAt, actionRecord)
+		log.Printf("  ✓ 限价单部分成交，订单ID: %s, 成交数量: %.4f/%.4f", orderID, executedQty, quantity)
+		at.onLimitEntryFilled(pending, executedQty)
+		return nil
+	case status == OrderStatusExpired || status == OrderStatusCanceled || status == OrderStatusRejected:
+		return fmt.Errorf("限价单未成交（%s）: %s 限价 %.4f", status, d.Symbol, d.LimitPrice)
+	}
+
+	at.pendingLimitOrders[key] = pending
+	log.Printf("  ⏳ 限价单挂单中，订单ID: %s, 数量: %.4f, 限价: %.4f, 最长等待 %v",
+		orderID, quantity, d.LimitPrice, at.config.LimitOrderTTL)
+	return nil
+}
+
+// onLimitEntryFilled 限价单成交后记录开仓时间并设置止盈止损
+func (at *AutoTrader) onLimitEntryFilled(order *pendingLimitOrder, quantity float64) {
+	posKey := order.Symbol + "_" + strings.ToLower(order.PositionSide)
+	if _, exists := at.positionFirstSeenTime[posKey]; !exists {
+		at.positionFirstSeenTime[posKey] = at.now().UnixMilli()
+	}
+
+	if err := at.trader.SetStopLoss(order.Symbol, order.PositionSide, quantity, order.StopLoss); err != nil {
+		log.Printf("  ⚠ 设置止损失败: %v", err)
+	}
+	if err := at.trader.SetTakeProfit(order.Symbol, order.PositionSide, quantity, order.TakeProfit); err != nil {
+		log.Printf("  ⚠ 设置止盈失败: %v", err)
+	}
+}
+
+// cancelPendingLimitOrder 取消某币种某方向跟踪中的限价单，返回是否存在该挂单
+func (at *AutoTrader) cancelPendingLimitOrder(symbol, positionSide, reason string) bool {
+	key := symbol + "_" + strings.ToLower(positionSide)
+	pending, ok := at.pendingLimitOrders[key]
+	if !ok {
+		return false
+	}
+	delete(at.pendingLimitOrders, key)
+	at.savePendingLimitOrders()
+
+	if err := at.trader.CancelOrder(symbol, pending.OrderID); err != nil {
+		log.Printf("  ⚠ 取消限价单失败 (%s %s): %v", symbol, pending.OrderID, err)
+	} else {
+		log.Printf("  🗑 已取消 %s %s 限价单 %s（%s）", symbol, positionSide, pending.OrderID, reason)
+	}
+	return true
+}
+
+// checkPendingLimitOrders 检查跟踪中的限价单：已成交的补设止盈止损并撤销剩余部分，超时未成交的撤单
+// 返回需要写入执行日志的事件
+func (at *AutoTrader) checkPendingLimitOrders() []string {
+	if len(at.pendingLimitOrders) == 0 {
+		return nil
+	}
+	defer at.savePendingLimitOrders()
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠ 检查限价单失败，获取持仓失败: %v", err)
+		return nil
+	}
+	filledQty := make(map[string]float64)
+	for _, pos := range positions {
+		if pos.Quantity > 0 {
+			filledQty[pos.Key()] = pos.Quantity
+		}
+	}
+
+	var events []string
+	for key, pending := range at.pendingLimitOrders {
+		// 出现同方向持仓说明限价单已成交（开仓前已确认没有同方向持仓）
+		if quantity, filled := filledQty[key]; filled {
+			delete(at.pendingLimitOrders, key)
+			// 部分成交时撤销剩余部分，避免持仓在止盈止损设置后继续变化
+			if err := at.trader.CancelOrder(pending.Symbol, pending.OrderID); err == nil {
+				log.Printf("  🗑 已撤销 %s 限价单 %s 的未成交部分", pending.Symbol, pending.OrderID)
+			}
+			at.onLimitEntryFilled(pending, quantity)
+			log.Printf("✅ 限价单成交: %s %s 数量 %.4f @ 限价 %.4f", pending.Symbol, pending.PositionSide, quantity, pending.LimitPrice)
+			events = append(events, fmt.Sprintf("✅ %s %s 限价单成交 (订单ID: %s)", pending.Symbol, pending.PositionSide, pending.OrderID))
+			continue
+		}
+
+		age := at.now().Sub(pending.PlacedAt)
+		if age < at.config.LimitOrderTTL {
+			log.Printf("⏳ 限价单等待成交: %s %s @ %.4f (已挂 %.0f 分钟)", pending.Symbol, pending.PositionSide, pending.LimitPrice, age.Minutes())
+			continue
+		}
+
+		if err := at.trader.CancelOrder(pending.Symbol, pending.OrderID); err != nil {
+			pending.cancelAttempts++
+			if pending.cancelAttempts < maxLimitOrderCancelAttempts {
+				// 可能刚刚成交，下个周期通过持仓再确认一次
+				log.Printf("⚠ 超时限价单撤单失败 (%s %s)，下个周期重试: %v", pending.Symbol, pending.OrderID, err)
+				continue
+			}
+			log.Printf("⚠ 超时限价单撤单失败 %d 次，停止跟踪 (%s %s): %v", pending.cancelAttempts, pending.Symbol, pending.OrderID, err)
+		}
+		delete(at.pendingLimitOrders, key)
+		log.Printf("⌛ 限价单超时未成交，已撤单: %s %s @ %.4f (已挂 %.0f 分钟)", pending.Symbol, pending.PositionSide, pending.LimitPrice, age.Minutes())
+		events = append(events, fmt.Sprintf("⌛ %s %s 限价单超时撤单 (订单ID: %s)", pending.Symbol, pending.PositionSide, pending.OrderID))
+	}
+	return events
+}
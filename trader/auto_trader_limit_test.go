@@ -0,0 +1,179 @@
+package trader
+
+import (
+	"testing"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+)
+
+// newLimitTestAutoTrader 创建使用模拟盘和可控时钟的AutoTrader
+func newLimitTestAutoTrader(t *testing.T, feed *fakePriceFeed, now *time.Time) (*AutoTrader, *PaperTrader) {
+	t.Helper()
+	paper := newTestPaperTrader(t, feed, nil)
+	at, err := NewAutoTrader(AutoTraderConfig{
+		ID:              "limit_test",
+		Name:            "limit_test",
+		AIModel:         "test",
+		Exchange:        "paper",
+		InitialBalance:  1000,
+		BTCETHLeverage:  5,
+		AltcoinLeverage: 5,
+		LimitOrderTTL:   10 * time.Minute,
+		Trader:          paper,
+		MarketData: func(symbol string) (*market.Data, error) {
+			price, err := feed.get(symbol)
+			if err != nil {
+				return nil, err
+			}
+			return &market.Data{Symbol: symbol, CurrentPrice: price}, nil
+		},
+		Clock:          func() time.Time { return *now },
+		DecisionLogDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("创建AutoTrader失败: %v", err)
+	}
+	return at, paper
+}
+
+func limitOpenLong(limitPrice float64) *decision.Decision {
+	return &decision.Decision{
+		Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 500,
+		StopLoss: 90, TakeProfit: 110, LimitPrice: limitPrice,
+	}
+}
+
+// countPaperOrders 统计模拟盘中某类型的挂单数量
+func countPaperOrders(pt *PaperTrader, orderType string) int {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	count := 0
+	for _, order := range pt.state.Orders {
+		if order.Type == orderType {
+			count++
+		}
+	}
+	return count
+}
+
+func TestAutoTraderLimitEntryFillSetsStops(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, paper := newLimitTestAutoTrader(t, feed, &now)
+
+	if err := at.executeOpenLongWithRecord(limitOpenLong(95), &logger.DecisionAction{}); err != nil {
+		t.Fatalf("限价开仓失败: %v", err)
+	}
+	if len(at.pendingLimitOrders) != 1 || countPaperOrders(paper, paperOrderTypeLimit) != 1 {
+		t.Fatalf("应有1个跟踪中的限价单")
+	}
+
+	// 相同价格再次决策不重复下单，改价则修改原挂单
+	if err := at.executeOpenLongWithRecord(limitOpenLong(95), &logger.DecisionAction{}); err != nil {
+		t.Fatalf("重复限价决策失败: %v", err)
+	}
+	if err := at.executeOpenLongWithRecord(limitOpenLong(96), &logger.DecisionAction{}); err != nil {
+		t.Fatalf("改价失败: %v", err)
+	}
+	if countPaperOrders(paper, paperOrderTypeLimit) != 1 || at.pendingLimitOrders["BTCUSDT_long"].LimitPrice != 96 {
+		t.Fatalf("改价后应只有1个限价单且价格为96")
+	}
+	// 挂单期间按限价计入组合风险敞口
+	if pending := at.PendingEntryExposure(); len(pending) != 1 || pending[0].Side != "long" || pending[0].MarkPrice != 96 {
+		t.Errorf("未成交的限价单应计入组合风险: %+v", pending)
+	}
+
+	// 成交前检查不产生事件
+	if events := at.checkPendingLimitOrders(); len(events) != 0 {
+		t.Fatalf("未成交时不应有事件: %v", events)
+	}
+
+	paper.UpdateMarkPrice("BTCUSDT", 95.5)
+	events := at.checkPendingLimitOrders()
+	if len(events) != 1 || len(at.pendingLimitOrders) != 0 {
+		t.Fatalf("成交后应记录事件并停止跟踪: %v", events)
+	}
+	if pending := at.PendingEntryExposure(); len(pending) != 0 {
+		t.Errorf("成交后挂单不应再计入组合风险: %+v", pending)
+	}
+	if countPaperOrders(paper, paperOrderTypeStop) != 1 || countPaperOrders(paper, paperOrderTypeTakeProfit) != 1 {
+		t.Errorf("限价单成交后应设置止盈止损")
+	}
+}
+
+func TestAutoTraderLimitEntryExpires(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, paper := newLimitTestAutoTrader(t, feed, &now)
+
+	if err := at.executeOpenLongWithRecord(limitOpenLong(95), &logger.DecisionAction{}); err != nil {
+		t.Fatalf("限价开仓失败: %v", err)
+	}
+
+	now = now.Add(5 * time.Minute)
+	if events := at.checkPendingLimitOrders(); len(events) != 0 || len(at.pendingLimitOrders) != 1 {
+		t.Fatalf("未超时不应撤单: %v", events)
+	}
+
+	now = now.Add(6 * time.Minute)
+	events := at.checkPendingLimitOrders()
+	if len(events) != 1 || len(at.pendingLimitOrders) != 0 {
+		t.Fatalf("超时后应撤单: %v", events)
+	}
+	if countPaperOrders(paper, paperOrderTypeLimit) != 0 {
+		t.Error("超时的限价单应从交易所撤销")
+	}
+	if pending := at.PendingEntryExposure(); len(pending) != 0 {
+		t.Errorf("撤单后挂单不应再计入组合风险: %+v", pending)
+	}
+	if fills := paper.UpdateMarkPrice("BTCUSDT", 90); len(fills) != 0 {
+		t.Errorf("已撤销的限价单不应成交: %+v", fills)
+	}
+}
+
+// memoryLimitOrderStore 内存中的限价入场单存储
+type memoryLimitOrderStore map[string]string
+
+func (m memoryLimitOrderStore) GetLimitOrderState(traderID string) (string, error) {
+	return m[traderID], nil
+}
+
+func (m memoryLimitOrderStore) SaveLimitOrderState(traderID string, state string) error {
+	m[traderID] = state
+	return nil
+}
+
+func TestAutoTraderLimitEntrySurvivesRestart(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, paper := newLimitTestAutoTrader(t, feed, &now)
+	store := memoryLimitOrderStore{}
+	at.limitOrderStore = store
+
+	if err := at.executeOpenLongWithRecord(limitOpenLong(95), &logger.DecisionAction{}); err != nil {
+		t.Fatalf("限价开仓失败: %v", err)
+	}
+
+	// 重启：内存中的跟踪丢失，从存储恢复（止盈止损和挂单时间保持不变）
+	at.pendingLimitOrders = loadPendingLimitOrders(store, "limit_test", "limit_test")
+	restored, ok := at.pendingLimitOrders["BTCUSDT_long"]
+	if !ok || restored.StopLoss != 90 || restored.TakeProfit != 110 || !restored.PlacedAt.Equal(now) {
+		t.Fatalf("应恢复限价单: %+v", restored)
+	}
+
+	// 恢复后成交仍补设止盈止损
+	now = now.Add(5 * time.Minute)
+	paper.UpdateMarkPrice("BTCUSDT", 94.5)
+	if events := at.checkPendingLimitOrders(); len(events) != 1 {
+		t.Fatalf("恢复的限价单成交后应记录事件: %v", events)
+	}
+	if countPaperOrders(paper, paperOrderTypeStop) != 1 || countPaperOrders(paper, paperOrderTypeTakeProfit) != 1 {
+		t.Errorf("恢复的限价单成交后应设置止盈止损")
+	}
+	if reloaded := loadPendingLimitOrders(store, "limit_test", "limit_test"); len(reloaded) != 0 {
+		t.Errorf("成交后应从存储中移除: %+v", reloaded)
+	}
+}
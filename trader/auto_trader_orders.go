@@ -0,0 +1,64 @@
+package trader
+
+import (
+	"log"
+	"nofx/logger"
+	"time"
+)
+
+// orderFillLookback 查询成交记录时向前多取的时间（容忍本地与交易所的时钟偏差）
+const orderFillLookback = time.Minute
+
+// recordOrderExecution 记录订单的交易所订单ID、成交均价和手续费
+// 优先使用该订单的成交记录，其次使用订单查询结果，都获取不到时保留下单前的市场价
+func (at *AutoTrader) recordOrderExecution(symbol string, order *OrderResult, submittedAt time.Time, actionRecord *logger.DecisionAction) {
+	if order == nil {
+		return
+	}
+	actionRecord.OrderID = order.OrderID
+	if order.AvgPrice > 0 {
+		actionRecord.Price = order.AvgPrice
+	}
+	if order.Fee > 0 {
+		actionRecord.Fee = order.Fee
+	}
+	if order.OrderID == "" {
+		return
+	}
+
+	fills, err := at.trader.GetFills(symbol, submittedAt.Add(-orderFillLookback))
+	if err != nil {
+		log.Printf("  ⚠ 获取成交记录失败: %v", err)
+	}
+	notional, filledQty, fee := 0.0, 0.0, 0.0
+	for _, fill := range fills {
+		if fill.OrderID != order.OrderID {
+			continue
+		}
+		notional += fill.Price * fill.Quantity
+		filledQty += fill.Quantity
+		fee += fill.Fee
+	}
+	if filledQty > 0 {
+		actionRecord.Price = notional / filledQty
+		actionRecord.Quantity = filledQty
+		actionRecord.Fee = fee
+		return
+	}
+
+	// 成交记录尚未同步时使用订单查询结果
+	details, err := at.trader.GetOrder(symbol, order.OrderID)
+	if err != nil {
+		log.Printf("  ⚠ 查询订单 %s 失败，使用下单前的市场价记录: %v", order.OrderID, err)
+		return
+	}
+	if details.AvgPrice > 0 {
+		actionRecord.Price = details.AvgPrice
+	}
+	if details.ExecutedQty > 0 {
+		actionRecord.Quantity = details.ExecutedQty
+	}
+	if details.Fee > 0 {
+		actionRecord.Fee = details.Fee
+	}
+}
@@ -0,0 +1,43 @@
+package trader
+
+import (
+	"testing"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+)
+
+func TestAutoTraderRecordsFillPriceAndFee(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, _ := newLimitTestAutoTrader(t, feed, &now)
+
+	openRecord := &logger.DecisionAction{}
+	err := at.executeOpenLongWithRecord(&decision.Decision{
+		Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 500,
+		StopLoss: 90, TakeProfit: 110,
+	}, openRecord)
+	if err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	// 模拟盘按0.1%滑点成交，记录的应是成交价而不是下单前的市场价
+	if openRecord.OrderID == "" || !almostEqual(openRecord.Price, 100.1) {
+		t.Fatalf("应记录订单ID和成交均价: %+v", openRecord)
+	}
+	if !almostEqual(openRecord.Fee, openRecord.Quantity*100.1*0.001) {
+		t.Errorf("手续费错误: %+v", openRecord)
+	}
+
+	feed.set("BTCUSDT", 105)
+	closeRecord := &logger.DecisionAction{}
+	if err := at.executeCloseLongWithRecord(&decision.Decision{Symbol: "BTCUSDT", Action: "close_long"}, closeRecord); err != nil {
+		t.Fatalf("平仓失败: %v", err)
+	}
+	if closeRecord.OrderID == "" || closeRecord.OrderID == openRecord.OrderID || !almostEqual(closeRecord.Price, 105*0.999) {
+		t.Fatalf("平仓应记录自己的订单ID和成交均价: %+v", closeRecord)
+	}
+	if !almostEqual(closeRecord.Quantity, openRecord.Quantity) || closeRecord.Fee <= 0 {
+		t.Errorf("平仓应记录成交数量和手续费: %+v", closeRecord)
+	}
+}
@@ -0,0 +1,117 @@
+package trader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"nofx/logger"
+)
+
+// attachTrailingStops 接管交易器的本地模拟跟踪止损：恢复重启前保存的跟踪止损，
+// 变化时持久化，触发时在交易执行锁内走风控平仓路径（记录交易结果和决策日志）
+func (at *AutoTrader) attachTrailingStops(store TrailingStopStore) {
+	emulated, ok := at.trader.(trailingStopEmulated)
+	if !ok {
+		return // 交易所原生跟踪止损，由交易所保存
+	}
+	em := emulated.trailingStops()
+	if em == nil {
+		return
+	}
+
+	var persist func(orders []trailingStopOrder)
+	if store != nil {
+		persist = func(orders []trailingStopOrder) {
+			data, err := json.Marshal(orders)
+			if err != nil {
+				log.Printf("⚠️ 序列化本地跟踪止损失败: %v", err)
+				return
+			}
+			if err := store.SaveTrailingStopState(at.id, string(data)); err != nil {
+				log.Printf("⚠️ 保存本地跟踪止损失败: %v", err)
+			}
+		}
+	}
+	em.attach(at.trailingStopTriggered, persist)
+
+	if store == nil {
+		return
+	}
+	data, err := store.GetTrailingStopState(at.id)
+	if err != nil {
+		log.Printf("⚠️ [%s] 读取本地跟踪止损失败: %v", at.name, err)
+		return
+	}
+	if data == "" {
+		return
+	}
+	var orders []trailingStopOrder
+	if err := json.Unmarshal([]byte(data), &orders); err != nil {
+		log.Printf("⚠️ [%s] 解析本地跟踪止损失败: %v", at.name, err)
+		return
+	}
+	if restored := em.restore(orders); restored > 0 {
+		log.Printf("🎯 [%s] 恢复 %d 个本地跟踪止损", at.name, restored)
+	}
+}
+
+// hasEmulatedTrailingStop 该持仓方向是否已有本地模拟的跟踪止损（如重启后恢复的）
+func (at *AutoTrader) hasEmulatedTrailingStop(symbol, positionSide string) bool {
+	emulated, ok := at.trader.(trailingStopEmulated)
+	if !ok || emulated.trailingStops() == nil {
+		return false
+	}
+	_, exists := emulated.trailingStops().Get(symbol, positionSide)
+	return exists
+}
+
+// trailingStopTriggered 本地跟踪止损触发后平仓
+// 与AI周期和实时风控互斥执行，平仓方式与风控平仓相同（记录交易结果、同步资金费），返回错误时下次检查重试
+func (at *AutoTrader) trailingStopTriggered(order trailingStopOrder, price float64) error {
+	at.execMu.Lock()
+	defer at.execMu.Unlock()
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return fmt.Errorf("获取持仓失败: %w", err)
+	}
+	var pos *Position
+	for i := range positions {
+		if positions[i].Symbol == order.Symbol && positions[i].PositionSide() == order.PositionSide && positions[i].Quantity > 0 {
+			pos = &positions[i]
+			break
+		}
+	}
+	if pos == nil {
+		log.Printf("ℹ️ [%s] %s %s 持仓已不存在，移除跟踪止损", at.name, order.Symbol, order.PositionSide)
+		return nil
+	}
+
+	reason := fmt.Sprintf("跟踪止损触发（自极值 %.4f 回撤超过 %.2f%%）", order.ExtremePrice, order.CallbackRate*100)
+	// 只平掉跟踪止损的数量（设置后加仓的部分不受影响），与交易所原生跟踪止损一致
+	actionRecord := at.guardianClose(guardianBreach{Position: *pos, Price: price, Reason: reason, Quantity: math.Min(order.Quantity, pos.Quantity)})
+	if at.guardian != nil {
+		at.guardian.invalidate()
+	}
+
+	record := &logger.DecisionRecord{
+		ExecutionLog: []string{},
+		Success:      actionRecord.Success,
+		Decisions:    []logger.DecisionAction{actionRecord},
+	}
+	if actionRecord.Success {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🎯 %s %s 跟踪止损平仓: %s", pos.Symbol, pos.Side, reason))
+	} else {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 跟踪止损平仓失败: %s", pos.Symbol, pos.Side, actionRecord.Error))
+	}
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存跟踪止损记录失败: %v", err)
+	}
+
+	if !actionRecord.Success {
+		return errors.New(actionRecord.Error)
+	}
+	return nil
+}
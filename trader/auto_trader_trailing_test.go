@@ -0,0 +1,161 @@
+package trader
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// memoryTrailingStopStore 内存中的本地跟踪止损存储
+type memoryTrailingStopStore map[string]string
+
+func (m memoryTrailingStopStore) GetTrailingStopState(traderID string) (string, error) {
+	return m[traderID], nil
+}
+
+func (m memoryTrailingStopStore) SaveTrailingStopState(traderID string, state string) error {
+	m[traderID] = state
+	return nil
+}
+
+// newTrailingTestAutoTrader 模拟盘AutoTrader，本地跟踪止损由测试手动驱动检查
+func newTrailingTestAutoTrader(t *testing.T, feed *fakePriceFeed, store memoryTrailingStopStore) (*AutoTrader, *PaperTrader, *trailingStopEmulator) {
+	t.Helper()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	at, paper := newLimitTestAutoTrader(t, feed, &now)
+	em := paper.trailingStops()
+	em.interval = time.Hour
+	em.price = feed.get
+	at.attachTrailingStops(store)
+	return at, paper, em
+}
+
+func TestTrailingStopTriggerClosesUnderExecLockAndRecordsTrade(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	store := memoryTrailingStopStore{}
+	at, paper, em := newTrailingTestAutoTrader(t, feed, store)
+
+	open := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 500, StopLoss: 80, TakeProfit: 150}
+	if err := at.executeOpenLongWithRecord(open, &logger.DecisionAction{}); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	pos := requirePosition(t, paper, "BTCUSDT", "long")
+	if err := paper.SetTrailingStop("BTCUSDT", "LONG", pos.Quantity, 0.01, 0); err != nil {
+		t.Fatalf("设置跟踪止损失败: %v", err)
+	}
+	if store["limit_test"] == "" {
+		t.Fatal("设置跟踪止损后应保存")
+	}
+
+	em.check() // 100 激活
+	feed.set("BTCUSDT", 110)
+	em.check() // 新高 110
+	feed.set("BTCUSDT", 108.5)
+
+	// AI周期执行期间触发：等待交易执行锁释放后才平仓
+	at.execMu.Lock()
+	done := make(chan struct{})
+	go func() {
+		em.check()
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if _, open := findPosition(t, paper, "BTCUSDT", "long"); !open {
+		at.execMu.Unlock()
+		t.Fatal("持有交易执行锁时不应平仓")
+	}
+	at.execMu.Unlock()
+	<-done
+
+	if _, open := findPosition(t, paper, "BTCUSDT", "long"); open {
+		t.Fatal("跟踪止损触发后应平仓")
+	}
+	if stats := at.kellyManager.GetHistoricalStats("BTCUSDT"); stats == nil || stats.TotalTrades != 1 || stats.ProfitableTrades != 1 {
+		t.Errorf("跟踪止损平仓应记录交易结果: %+v", stats)
+	}
+	if _, ok := em.Get("BTCUSDT", "LONG"); ok {
+		t.Error("触发后跟踪止损应被移除")
+	}
+	if store["limit_test"] != "[]" {
+		t.Errorf("触发后应保存空的跟踪止损列表, got %s", store["limit_test"])
+	}
+}
+
+func TestTrailingStopSurvivesRestart(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	store := memoryTrailingStopStore{}
+	at, paper, em := newTrailingTestAutoTrader(t, feed, store)
+
+	if _, err := paper.OpenLong("BTCUSDT", 5, 5); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	if err := paper.SetTrailingStop("BTCUSDT", "LONG", 5, 0.02, 0); err != nil {
+		t.Fatalf("设置跟踪止损失败: %v", err)
+	}
+	em.check()
+	feed.set("BTCUSDT", 120)
+	em.check()
+	em.save() // 极值价变化按间隔保存，这里模拟到达保存间隔
+
+	var saved []trailingStopOrder
+	if err := json.Unmarshal([]byte(store[at.id]), &saved); err != nil || len(saved) != 1 || saved[0].ExtremePrice != 120 {
+		t.Fatalf("应保存激活状态和极值价: %s (%v)", store[at.id], err)
+	}
+
+	// 重启：交易所上的持仓仍在，新的交易器从存储恢复跟踪止损，极值价保持不变，回撤超过2%即平仓
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	restarted, restartedPaper := newLimitTestAutoTrader(t, feed, &now)
+	if _, err := restartedPaper.OpenLong("BTCUSDT", 5, 5); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	restartedEm := restartedPaper.trailingStops()
+	restartedEm.interval = time.Hour
+	restartedEm.price = feed.get
+	restarted.attachTrailingStops(store)
+	restored, ok := restartedEm.Get("BTCUSDT", "LONG")
+	if !ok || !restored.Activated || restored.ExtremePrice != 120 || restored.Quantity != 5 {
+		t.Fatalf("重启后应恢复跟踪止损: %+v (%v)", restored, ok)
+	}
+	if !restarted.hasEmulatedTrailingStop("BTCUSDT", "LONG") {
+		t.Error("恢复的跟踪止损不应被重新设置")
+	}
+
+	feed.set("BTCUSDT", 117)
+	restartedEm.check()
+	if _, open := findPosition(t, restartedPaper, "BTCUSDT", "long"); open {
+		t.Error("恢复的跟踪止损应按重启前的极值价触发平仓")
+	}
+}
+
+func TestTrailingStopClosesOnlyItsQuantity(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, paper, em := newTrailingTestAutoTrader(t, feed, memoryTrailingStopStore{})
+
+	if _, err := paper.OpenLong("BTCUSDT", 2, 5); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	if err := paper.SetTrailingStop("BTCUSDT", "LONG", 2, 0.01, 0); err != nil {
+		t.Fatalf("设置跟踪止损失败: %v", err)
+	}
+	// 设置跟踪止损后加仓（同方向开仓会撤销挂单，这里直接加到模拟盘持仓上）
+	paper.mu.Lock()
+	paper.state.Positions["BTCUSDT_long"].Quantity += 3
+	paper.mu.Unlock()
+
+	em.check()
+	feed.set("BTCUSDT", 110)
+	em.check()
+	feed.set("BTCUSDT", 108.5)
+	em.check()
+
+	pos := requirePosition(t, paper, "BTCUSDT", "long")
+	if !almostEqual(pos.Quantity, 3) {
+		t.Errorf("跟踪止损只应平掉其设置的数量2，剩余应为3, got %.6f", pos.Quantity)
+	}
+	if stats := at.kellyManager.GetHistoricalStats("BTCUSDT"); stats == nil || stats.TotalTrades != 1 {
+		t.Errorf("部分平仓应记录交易结果: %+v", stats)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,30 +18,45 @@ type FuturesTrader struct {
 	client *futures.Client
 
 	// 余额缓存
-	cachedBalance     map[string]interface{}
+	cachedBalance     *Balance
 	balanceCacheTime  time.Time
 	balanceCacheMutex sync.RWMutex
 
 	// 持仓缓存
-	cachedPositions     []map[string]interface{}
+	cachedPositions     []Position
 	positionsCacheTime  time.Time
 	positionsCacheMutex sync.RWMutex
 
 	// 缓存有效期（15秒）
 	cacheDuration time.Duration
+
+	// 切换杠杆后的等待时间（避免冷却期错误）
+	leverageCooldown time.Duration
 }
 
 // NewFuturesTrader 创建合约交易器
 func NewFuturesTrader(apiKey, secretKey string) *FuturesTrader {
 	client := futures.NewClient(apiKey, secretKey)
 	return &FuturesTrader{
-		client:        client,
-		cacheDuration: 15 * time.Second, // 15秒缓存
+		client:           client,
+		cacheDuration:    15 * time.Second, // 15秒缓存
+		leverageCooldown: 5 * time.Second,
 	}
 }
 
+// invalidateCache 下单或调整杠杆后清除余额和持仓缓存，避免随后的查询读到旧数据
+func (t *FuturesTrader) invalidateCache() {
+	t.balanceCacheMutex.Lock()
+	t.cachedBalance = nil
+	t.balanceCacheMutex.Unlock()
+
+	t.positionsCacheMutex.Lock()
+	t.cachedPositions = nil
+	t.positionsCacheMutex.Unlock()
+}
+
 // GetBalance 获取账户余额（带缓存）
-func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
+func (t *FuturesTrader) GetBalance() (*Balance, error) {
 	// 先检查缓存是否有效
 	t.balanceCacheMutex.RLock()
 	if t.cachedBalance != nil && time.Since(t.balanceCacheTime) < t.cacheDuration {
@@ -58,10 +75,7 @@ func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
 		return nil, fmt.Errorf("获取账户信息失败: %w", err)
 	}
 
-	result := make(map[string]interface{})
-	result["totalWalletBalance"], _ = strconv.ParseFloat(account.TotalWalletBalance, 64)
-	result["availableBalance"], _ = strconv.ParseFloat(account.AvailableBalance, 64)
-	result["totalUnrealizedProfit"], _ = strconv.ParseFloat(account.TotalUnrealizedProfit, 64)
+	result := binanceBalance(account)
 
 	log.Printf("✓ 币安API返回: 总余额=%s, 可用=%s, 未实现盈亏=%s",
 		account.TotalWalletBalance,
@@ -78,7 +92,7 @@ func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
 }
 
 // GetPositions 获取所有持仓（带缓存）
-func (t *FuturesTrader) GetPositions() ([]map[string]interface{}, error) {
+func (t *FuturesTrader) GetPositions() ([]Position, error) {
 	// 先检查缓存是否有效
 	t.positionsCacheMutex.RLock()
 	if t.cachedPositions != nil && time.Since(t.positionsCacheTime) < t.cacheDuration {
@@ -96,30 +110,11 @@ func (t *FuturesTrader) GetPositions() ([]map[string]interface{}, error) {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
-	var result []map[string]interface{}
+	result := []Position{}
 	for _, pos := range positions {
-		posAmt, _ := strconv.ParseFloat(pos.PositionAmt, 64)
-		if posAmt == 0 {
-			continue // 跳过无持仓的
+		if position, ok := binancePosition(pos); ok {
+			result = append(result, position)
 		}
-
-		posMap := make(map[string]interface{})
-		posMap["symbol"] = pos.Symbol
-		posMap["positionAmt"], _ = strconv.ParseFloat(pos.PositionAmt, 64)
-		posMap["entryPrice"], _ = strconv.ParseFloat(pos.EntryPrice, 64)
-		posMap["markPrice"], _ = strconv.ParseFloat(pos.MarkPrice, 64)
-		posMap["unRealizedProfit"], _ = strconv.ParseFloat(pos.UnRealizedProfit, 64)
-		posMap["leverage"], _ = strconv.ParseFloat(pos.Leverage, 64)
-		posMap["liquidationPrice"], _ = strconv.ParseFloat(pos.LiquidationPrice, 64)
-
-		// 判断方向
-		if posAmt > 0 {
-			posMap["side"] = "long"
-		} else {
-			posMap["side"] = "short"
-		}
-
-		result = append(result, posMap)
 	}
 
 	// 更新缓存
@@ -178,11 +173,9 @@ func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
 	positions, err := t.GetPositions()
 	if err == nil {
 		for _, pos := range positions {
-			if pos["symbol"] == symbol {
-				if lev, ok := pos["leverage"].(float64); ok {
-					currentLeverage = int(lev)
-					break
-				}
+			if pos.Symbol == symbol {
+				currentLeverage = pos.Leverage
+				break
 			}
 		}
 	}
@@ -209,16 +202,19 @@ func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
 	}
 
 	log.Printf("  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
+	t.invalidateCache()
 
-	// 切换杠杆后等待5秒（避免冷却期错误）
-	log.Printf("  ⏱ 等待5秒冷却期...")
-	time.Sleep(5 * time.Second)
+	// 切换杠杆后等待冷却期（避免冷却期错误）
+	if t.leverageCooldown > 0 {
+		log.Printf("  ⏱ 等待%.0f秒冷却期...", t.leverageCooldown.Seconds())
+		time.Sleep(t.leverageCooldown)
+	}
 
 	return nil
 }
 
 // OpenLong 开多仓
-func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int) (*OrderResult, error) {
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
@@ -249,19 +245,16 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 	if err != nil {
 		return nil, fmt.Errorf("开多仓失败: %w", err)
 	}
+	t.invalidateCache()
 
 	log.Printf("✓ 开多仓成功: %s 数量: %s", symbol, quantityStr)
 	log.Printf("  订单ID: %d", order.OrderID)
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return binanceCreateOrderResult(order), nil
 }
 
 // OpenShort 开空仓
-func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int) (*OrderResult, error) {
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
@@ -292,19 +285,16 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 	if err != nil {
 		return nil, fmt.Errorf("开空仓失败: %w", err)
 	}
+	t.invalidateCache()
 
 	log.Printf("✓ 开空仓成功: %s 数量: %s", symbol, quantityStr)
 	log.Printf("  订单ID: %d", order.OrderID)
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return binanceCreateOrderResult(order), nil
 }
 
 // CloseLong 平多仓
-func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -313,8 +303,8 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 		}
 
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
+			if pos.Symbol == symbol && pos.Side == "long" {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -342,6 +332,7 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 	if err != nil {
 		return nil, fmt.Errorf("平多仓失败: %w", err)
 	}
+	t.invalidateCache()
 
 	log.Printf("✓ 平多仓成功: %s 数量: %s", symbol, quantityStr)
 
@@ -350,15 +341,11 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return binanceCreateOrderResult(order), nil
 }
 
 // CloseShort 平空仓
-func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -367,8 +354,8 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 		}
 
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				quantity = -pos["positionAmt"].(float64) // 空仓数量是负的，取绝对值
+			if pos.Symbol == symbol && pos.Side == "short" {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -396,6 +383,7 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 	if err != nil {
 		return nil, fmt.Errorf("平空仓失败: %w", err)
 	}
+	t.invalidateCache()
 
 	log.Printf("✓ 平空仓成功: %s 数量: %s", symbol, quantityStr)
 
@@ -404,11 +392,7 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return binanceCreateOrderResult(order), nil
 }
 
 // CancelAllOrders 取消该币种的所有挂单
@@ -528,6 +512,54 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 	return nil
 }
 
+// SetTrailingStop 设置跟踪止损单（币安原生TRAILING_STOP_MARKET，回调比例以百分比提交）
+func (t *FuturesTrader) SetTrailingStop(symbol string, positionSide string, quantity, callbackRate, activationPrice float64) error {
+	if err := validateTrailingStop(positionSide, quantity, callbackRate, activationPrice); err != nil {
+		return err
+	}
+
+	var side futures.SideType
+	var posSide futures.PositionSideType
+
+	if positionSide == "LONG" {
+		side = futures.SideTypeSell
+		posSide = futures.PositionSideTypeLong
+	} else {
+		side = futures.SideTypeBuy
+		posSide = futures.PositionSideTypeShort
+	}
+
+	// 格式化数量
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return err
+	}
+
+	service := t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(side).
+		PositionSide(posSide).
+		Type(futures.OrderTypeTrailingStopMarket).
+		CallbackRate(strconv.FormatFloat(callbackRate*100, 'f', 1, 64)).
+		Quantity(quantityStr).
+		WorkingType(futures.WorkingTypeContractPrice)
+
+	if activationPrice > 0 {
+		priceStr, err := t.FormatPrice(symbol, activationPrice)
+		if err != nil {
+			return err
+		}
+		service = service.ActivationPrice(priceStr)
+	}
+
+	if _, err := service.Do(context.Background()); err != nil {
+		return fmt.Errorf("设置跟踪止损失败: %w", err)
+	}
+
+	log.Printf("  跟踪止损设置: 回调%.1f%%, 激活价 %.4f", callbackRate*100, activationPrice)
+	return nil
+}
+
 // GetSymbolPrecision 获取交易对的数量精度
 func (t *FuturesTrader) GetSymbolPrecision(symbol string) (int, error) {
 	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
@@ -604,6 +636,8 @@ func (t *FuturesTrader) FormatQuantity(symbol string, quantity float64) (string,
 		return fmt.Sprintf("%.3f", quantity), nil
 	}
 
+	// 向下取整到LOT_SIZE精度，避免四舍五入后超出持仓数量或可用保证金
+	quantity = floorToStep(quantity, math.Pow10(-precision))
 	format := fmt.Sprintf("%%.%df", precision)
 	return fmt.Sprintf(format, quantity), nil
 }
@@ -621,3 +655,416 @@ func stringContains(s, substr string) bool {
 	}
 	return false
 }
+
+// GetSymbolPricePrecision 获取交易对的价格精度
+func (t *FuturesTrader) GetSymbolPricePrecision(symbol string) (int, error) {
+	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
+	if err != nil {
+		return 0, fmt.Errorf("获取交易规则失败: %w", err)
+	}
+
+	for _, s := range exchangeInfo.Symbols {
+		if s.Symbol == symbol {
+			// 从PRICE_FILTER filter获取精度
+			for _, filter := range s.Filters {
+				if filter["filterType"] == "PRICE_FILTER" {
+					tickSize := filter["tickSize"].(string)
+					return calculatePrecision(tickSize), nil
+				}
+			}
+			return s.PricePrecision, nil
+		}
+	}
+
+	log.Printf("  ⚠ %s 未找到价格精度信息，使用默认精度2", symbol)
+	return 2, nil // 默认精度为2
+}
+
+// FormatPrice 格式化价格到正确的精度
+func (t *FuturesTrader) FormatPrice(symbol string, price float64) (string, error) {
+	precision, err := t.GetSymbolPricePrecision(symbol)
+	if err != nil {
+		return fmt.Sprintf("%.2f", price), nil
+	}
+
+	format := fmt.Sprintf("%%.%df", precision)
+	return fmt.Sprintf(format, price), nil
+}
+
+// binanceTimeInForce 转换为币安的有效方式（post-only对应GTX）
+func binanceTimeInForce(tif TimeInForce) (futures.TimeInForceType, error) {
+	switch tif {
+	case TimeInForceGTC, "":
+		return futures.TimeInForceTypeGTC, nil
+	case TimeInForceIOC:
+		return futures.TimeInForceTypeIOC, nil
+	case TimeInForceFOK:
+		return futures.TimeInForceTypeFOK, nil
+	case TimeInForcePostOnly:
+		return futures.TimeInForceTypeGTX, nil
+	default:
+		return "", fmt.Errorf("不支持的限价单有效方式: %s", tif)
+	}
+}
+
+// PlaceLimitOrder 下限价开仓单
+func (t *FuturesTrader) PlaceLimitOrder(symbol string, positionSide string, quantity, price float64, leverage int, tif TimeInForce) (*OrderResult, error) {
+	timeInForce, err := binanceTimeInForce(tif)
+	if err != nil {
+		return nil, err
+	}
+
+	side := futures.SideTypeBuy
+	posSide := futures.PositionSideTypeLong
+	if positionSide == "SHORT" {
+		side = futures.SideTypeSell
+		posSide = futures.PositionSideTypeShort
+	}
+
+	// 设置杠杆（限价单不取消旧委托单，避免撤掉其他挂单）
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	priceStr, err := t.FormatPrice(symbol, price)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(side).
+		PositionSide(posSide).
+		Type(futures.OrderTypeLimit).
+		TimeInForce(timeInForce).
+		Quantity(quantityStr).
+		Price(priceStr).
+		Do(context.Background())
+
+	if err != nil {
+		return nil, fmt.Errorf("下限价单失败: %w", err)
+	}
+	t.invalidateCache()
+
+	log.Printf("✓ 限价单已提交: %s %s 数量: %s 价格: %s (%s)", symbol, positionSide, quantityStr, priceStr, tif)
+	log.Printf("  订单ID: %d 状态: %s", order.OrderID, order.Status)
+
+	return binanceCreateOrderResult(order), nil
+}
+
+// AmendOrder 修改未成交限价单的价格和数量
+func (t *FuturesTrader) AmendOrder(symbol string, orderID string, quantity, price float64) (*OrderResult, error) {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+
+	// 币安修改订单需要提供方向和数量
+	existing, err := t.client.NewGetOrderService().
+		Symbol(symbol).
+		OrderID(id).
+		Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	quantityStr := existing.OrigQuantity
+	if quantity > 0 {
+		quantityStr, err = t.FormatQuantity(symbol, quantity)
+		if err != nil {
+			return nil, err
+		}
+	}
+	priceStr, err := t.FormatPrice(symbol, price)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := t.client.NewModifyOrderService().
+		Symbol(symbol).
+		OrderID(id).
+		Side(existing.Side).
+		Quantity(quantityStr).
+		Price(priceStr).
+		Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("修改订单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已修改订单 %s: 数量 %s 价格 %s", orderID, quantityStr, priceStr)
+
+	result := &OrderResult{
+		OrderID:      strconv.FormatInt(order.OrderID, 10),
+		Symbol:       order.Symbol,
+		Status:       string(order.Status),
+		Type:         string(order.Type),
+		Side:         string(order.Side),
+		PositionSide: string(order.PositionSide),
+		Price:        parseFloatOrZero(order.Price),
+		Quantity:     parseFloatOrZero(order.OriginalQuantity),
+		ExecutedQty:  parseFloatOrZero(order.ExecutedQuantity),
+		AvgPrice:     parseFloatOrZero(order.AveragePrice),
+		UpdateTime:   order.UpdateTime,
+	}
+	return result, nil
+}
+
+// CancelOrder 取消单个订单
+func (t *FuturesTrader) CancelOrder(symbol string, orderID string) error {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+
+	_, err = t.client.NewCancelOrderService().
+		Symbol(symbol).
+		OrderID(id).
+		Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已取消 %s 订单 %s", symbol, orderID)
+	return nil
+}
+
+// binanceBalance 将币安账户信息转换为统一格式
+func binanceBalance(account *futures.Account) *Balance {
+	return &Balance{
+		WalletBalance:    parseFloatOrZero(account.TotalWalletBalance),
+		UnrealizedProfit: parseFloatOrZero(account.TotalUnrealizedProfit),
+		AvailableBalance: parseFloatOrZero(account.AvailableBalance),
+	}
+}
+
+// binancePosition 将币安持仓风险信息转换为统一格式（无持仓时返回false）
+func binancePosition(pos *futures.PositionRisk) (Position, bool) {
+	posAmt := parseFloatOrZero(pos.PositionAmt)
+	if posAmt == 0 {
+		return Position{}, false
+	}
+
+	position := Position{
+		Symbol:           pos.Symbol,
+		Side:             "long",
+		Quantity:         posAmt,
+		EntryPrice:       parseFloatOrZero(pos.EntryPrice),
+		MarkPrice:        parseFloatOrZero(pos.MarkPrice),
+		UnrealizedProfit: parseFloatOrZero(pos.UnRealizedProfit),
+		Leverage:         int(parseFloatOrZero(pos.Leverage)),
+		LiquidationPrice: parseFloatOrZero(pos.LiquidationPrice),
+		MarginMode:       binanceMarginMode(pos.MarginType),
+	}
+	// 空仓数量为负
+	if posAmt < 0 {
+		position.Side = "short"
+		position.Quantity = -posAmt
+	}
+	return position, true
+}
+
+// binanceMarginMode 转换保证金模式（cross/isolated）
+func binanceMarginMode(marginType string) string {
+	if strings.EqualFold(marginType, "isolated") {
+		return "isolated"
+	}
+	return "cross"
+}
+
+// binanceCreateOrderResult 将下单响应转换为统一格式
+func binanceCreateOrderResult(order *futures.CreateOrderResponse) *OrderResult {
+	return &OrderResult{
+		OrderID:      strconv.FormatInt(order.OrderID, 10),
+		Symbol:       order.Symbol,
+		Status:       string(order.Status),
+		Type:         string(order.Type),
+		Side:         string(order.Side),
+		PositionSide: string(order.PositionSide),
+		Price:        parseFloatOrZero(order.Price),
+		StopPrice:    parseFloatOrZero(order.StopPrice),
+		Quantity:     parseFloatOrZero(order.OrigQuantity),
+		ExecutedQty:  parseFloatOrZero(order.ExecutedQuantity),
+		AvgPrice:     parseFloatOrZero(order.AvgPrice),
+		UpdateTime:   order.UpdateTime,
+	}
+}
+
+// binanceOrderResult 将币安订单转换为统一格式
+func binanceOrderResult(order *futures.Order) *OrderResult {
+	return &OrderResult{
+		OrderID:      strconv.FormatInt(order.OrderID, 10),
+		Symbol:       order.Symbol,
+		Status:       string(order.Status),
+		Type:         string(order.Type),
+		Side:         string(order.Side),
+		PositionSide: string(order.PositionSide),
+		Price:        parseFloatOrZero(order.Price),
+		StopPrice:    parseFloatOrZero(order.StopPrice),
+		Quantity:     parseFloatOrZero(order.OrigQuantity),
+		ExecutedQty:  parseFloatOrZero(order.ExecutedQuantity),
+		AvgPrice:     parseFloatOrZero(order.AvgPrice),
+		Time:         order.Time,
+		UpdateTime:   order.UpdateTime,
+	}
+}
+
+// binanceFill 将币安成交记录转换为统一格式
+func binanceFill(trade *futures.AccountTrade) Fill {
+	role := "taker"
+	if trade.Maker {
+		role = "maker"
+	}
+	return Fill{
+		FillID:       strconv.FormatInt(trade.ID, 10),
+		OrderID:      strconv.FormatInt(trade.OrderID, 10),
+		Symbol:       trade.Symbol,
+		Side:         strings.ToLower(string(trade.Side)),
+		PositionSide: string(trade.PositionSide),
+		Price:        parseFloatOrZero(trade.Price),
+		Quantity:     parseFloatOrZero(trade.Quantity),
+		Fee:          parseFloatOrZero(trade.Commission),
+		FeeCurrency:  trade.CommissionAsset,
+		Role:         role,
+		Timestamp:    trade.Time,
+	}
+}
+
+// GetOrder 查询单个订单
+func (t *FuturesTrader) GetOrder(symbol string, orderID string) (*OrderResult, error) {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+
+	order, err := t.client.NewGetOrderService().
+		Symbol(symbol).
+		OrderID(id).
+		Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	return binanceOrderResult(order), nil
+}
+
+// GetOpenOrders 获取未成交挂单（symbol为空表示所有币种）
+func (t *FuturesTrader) GetOpenOrders(symbol string) ([]OrderResult, error) {
+	service := t.client.NewListOpenOrdersService()
+	if symbol != "" {
+		service = service.Symbol(symbol)
+	}
+
+	orders, err := service.Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	result := make([]OrderResult, 0, len(orders))
+	for _, order := range orders {
+		result = append(result, *binanceOrderResult(order))
+	}
+	return result, nil
+}
+
+// binanceProtectiveKind 币安（及Aster）的止盈止损条件单类型
+func binanceProtectiveKind(order *OrderResult) string {
+	switch order.Type {
+	case "STOP_MARKET", "STOP":
+		return ProtectiveKindStopLoss
+	case "TAKE_PROFIT_MARKET", "TAKE_PROFIT":
+		return ProtectiveKindTakeProfit
+	}
+	return ""
+}
+
+// GetProtectiveOrders 获取止盈止损条件单（币安条件单与普通委托在同一挂单列表中返回）
+func (t *FuturesTrader) GetProtectiveOrders(symbol string) ([]ProtectiveOrder, error) {
+	orders, err := t.GetOpenOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return filterProtectiveOrders(orders, binanceProtectiveKind), nil
+}
+
+// CancelProtectiveOrder 取消单个止盈止损条件单
+func (t *FuturesTrader) CancelProtectiveOrder(symbol string, orderID string) error {
+	return t.CancelOrder(symbol, orderID)
+}
+
+// GetFills 获取某币种自since以来的成交记录
+func (t *FuturesTrader) GetFills(symbol string, since time.Time) ([]Fill, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("币安查询成交记录需要指定币种")
+	}
+
+	trades, err := t.client.NewListAccountTradeService().
+		Symbol(symbol).
+		StartTime(since.UnixMilli()).
+		Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取成交记录失败: %w", err)
+	}
+
+	fills := make([]Fill, 0, len(trades))
+	for _, trade := range trades {
+		fills = append(fills, binanceFill(trade))
+	}
+	return fills, nil
+}
+
+// GetFundingPayments 获取某币种自since以来的资金费结算记录（收益流水中的FUNDING_FEE）
+func (t *FuturesTrader) GetFundingPayments(symbol string, since time.Time) ([]FundingPayment, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("币安查询资金费需要指定币种")
+	}
+
+	incomes, err := t.client.NewGetIncomeHistoryService().
+		Symbol(symbol).
+		IncomeType("FUNDING_FEE").
+		StartTime(since.UnixMilli()).
+		Limit(1000).
+		Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费记录失败: %w", err)
+	}
+
+	payments := make([]FundingPayment, 0, len(incomes))
+	for _, income := range incomes {
+		payments = append(payments, binanceFundingPayment(income))
+	}
+	return payments, nil
+}
+
+// binanceFundingPayment 将收益流水转换为资金费记录（income为正表示收到）
+func binanceFundingPayment(income *futures.IncomeHistory) FundingPayment {
+	return FundingPayment{
+		Symbol:    income.Symbol,
+		Amount:    parseFloatOrZero(income.Income),
+		Timestamp: income.Time,
+	}
+}
+
+// GetTransfers 获取自since以来合约账户的USDT划转记录（收益流水中的TRANSFER）
+func (t *FuturesTrader) GetTransfers(since time.Time) ([]Transfer, error) {
+	incomes, err := t.client.NewGetIncomeHistoryService().
+		IncomeType("TRANSFER").
+		StartTime(since.UnixMilli()).
+		Limit(1000).
+		Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取划转记录失败: %w", err)
+	}
+
+	transfers := make([]Transfer, 0, len(incomes))
+	for _, income := range incomes {
+		if income.Asset != "USDT" {
+			continue
+		}
+		transfers = append(transfers, Transfer{Amount: parseFloatOrZero(income.Income), Timestamp: income.Time})
+	}
+	return transfers, nil
+}
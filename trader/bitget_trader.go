@@ -0,0 +1,1082 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bitgetMixPath Bitget V2合约接口前缀
+const bitgetMixPath = "/api/v2/mix"
+
+// Bitget V2接口中需要特殊处理的返回码
+const (
+	bitgetCodeSuccess         = "00000"
+	bitgetCodeNoOrderToCancel = "22001" // 没有可撤销的订单
+)
+
+// bitgetErrorKinds Bitget返回码到统一错误分类的映射
+var bitgetErrorKinds = map[string]ExchangeErrorKind{
+	"40006": ExchangeErrAuth,               // Invalid ACCESS_KEY
+	"40009": ExchangeErrAuth,               // sign signature error
+	"40012": ExchangeErrAuth,               // apikey/password is incorrect
+	"40037": ExchangeErrAuth,               // Apikey does not exist
+	"429":   ExchangeErrRateLimit,          // Too Many Requests
+	"40762": ExchangeErrInsufficientMargin, // The order amount exceeds the balance
+	"43012": ExchangeErrInsufficientMargin, // Insufficient balance
+	"40768": ExchangeErrOrderNotFound,      // Order does not exist
+	"43001": ExchangeErrOrderNotFound,      // The order does not exist
+	"22002": ExchangeErrReduceOnly,         // No position to close
+	"45110": ExchangeErrInvalidQuantity,    // less than the minimum order quantity
+}
+
+// BitgetTrader Bitget USDT永续合约交易器（V2接口，双向持仓模式）
+type BitgetTrader struct {
+	apiKey     string
+	secretKey  string
+	passphrase string
+	rest       *restClient
+
+	// 模拟盘：请求头带paptrading=1，产品类型和交易对使用S前缀的模拟币种
+	demo        bool
+	productType string
+	marginCoin  string
+
+	// 余额和持仓缓存（15秒）
+	cache *accountCache
+
+	// 交易对规格缓存（数量步长、价格步长）
+	instruments *instrumentCache
+
+	// 账户是否已确认处于双向持仓模式
+	hedgeModeReady bool
+	hedgeModeMutex sync.Mutex
+
+	// 各交易对的保证金模式（crossed/isolated），下单时必须携带
+	marginModes      map[string]string
+	marginModesMutex sync.RWMutex
+
+	// 交易所不支持原生跟踪止损，在本地模拟
+	trailing *trailingStopEmulator
+}
+
+// decodeBitgetResponse 解析V2响应，code不为00000时返回原生错误码
+func decodeBitgetResponse(status int, body []byte, out interface{}) (string, string, error) {
+	var result struct {
+		Code string          `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil || result.Code == "" {
+		return "", "", fmt.Errorf("解析响应失败 (HTTP %d): %s", status, string(body))
+	}
+	if result.Code != bitgetCodeSuccess {
+		return result.Code, result.Msg, nil
+	}
+
+	if out != nil && len(result.Data) > 0 {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return "", "", fmt.Errorf("解析响应数据失败: %w", err)
+		}
+	}
+	return "", "", nil
+}
+
+// NewBitgetTrader 创建Bitget交易器（testnet为true时使用模拟盘）
+func NewBitgetTrader(apiKey, secretKey, passphrase string, testnet bool) (*BitgetTrader, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("API密钥不能为空")
+	}
+	if secretKey == "" {
+		return nil, fmt.Errorf("Secret密钥不能为空")
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("Passphrase不能为空")
+	}
+
+	t := &BitgetTrader{
+		apiKey:      apiKey,
+		secretKey:   secretKey,
+		passphrase:  passphrase,
+		productType: "USDT-FUTURES",
+		marginCoin:  "USDT",
+		cache:       newAccountCache("Bitget", 15*time.Second),
+		marginModes: make(map[string]string),
+	}
+	if testnet {
+		t.demo = true
+		t.productType = "SUSDT-FUTURES"
+		t.marginCoin = "SUSDT"
+		log.Println("✅ Bitget模拟盘模式已启用")
+	}
+	t.rest = newRESTClient("Bitget", "https://api.bitget.com", t.sign, decodeBitgetResponse, bitgetErrorKinds)
+	t.instruments = newInstrumentCache(t.loadInstrument)
+	t.trailing = newTrailingStopEmulator("Bitget", t)
+	return t, nil
+}
+
+// sign 添加V2签名请求头：Base64(HMAC_SHA256(timestamp + method + path + ["?" + querystring] + body))
+func (t *BitgetTrader) sign(req *restRequest) {
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	payload := timestamp + req.Method + req.Path
+	if req.Query != "" {
+		payload += "?" + req.Query
+	}
+	payload += req.Body
+
+	req.Header.Set("ACCESS-KEY", t.apiKey)
+	req.Header.Set("ACCESS-SIGN", hmacSHA256Base64(t.secretKey, payload))
+	req.Header.Set("ACCESS-TIMESTAMP", timestamp)
+	req.Header.Set("ACCESS-PASSPHRASE", t.passphrase)
+	req.Header.Set("locale", "en-US")
+	if t.demo {
+		req.Header.Set("paptrading", "1")
+	}
+}
+
+// invalidateCache 下单或调整杠杆后清除余额和持仓缓存
+func (t *BitgetTrader) invalidateCache() {
+	t.cache.Invalidate()
+}
+
+// exchangeSymbol 交易对转换为Bitget交易对（模拟盘使用S前缀的模拟币种，如 BTCUSDT -> SBTCSUSDT）
+func (t *BitgetTrader) exchangeSymbol(symbol string) string {
+	if !t.demo || !strings.HasSuffix(symbol, "USDT") {
+		return symbol
+	}
+	return "S" + strings.TrimSuffix(symbol, "USDT") + "SUSDT"
+}
+
+// symbolOf Bitget交易对转换为统一交易对
+func (t *BitgetTrader) symbolOf(exchangeSymbol string) string {
+	if !t.demo || !strings.HasPrefix(exchangeSymbol, "S") || !strings.HasSuffix(exchangeSymbol, "SUSDT") {
+		return exchangeSymbol
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(exchangeSymbol, "S"), "SUSDT") + "USDT"
+}
+
+// params 构造带产品类型和保证金币种的请求参数
+func (t *BitgetTrader) params(symbol string) map[string]interface{} {
+	params := map[string]interface{}{
+		"productType": t.productType,
+		"marginCoin":  t.marginCoin,
+	}
+	if symbol != "" {
+		params["symbol"] = t.exchangeSymbol(symbol)
+	}
+	return params
+}
+
+// query 构造带产品类型的查询参数
+func (t *BitgetTrader) query(symbol string) url.Values {
+	query := url.Values{"productType": {t.productType}}
+	if symbol != "" {
+		query.Set("symbol", t.exchangeSymbol(symbol))
+	}
+	return query
+}
+
+// loadInstrument 从交易所查询交易对的下单规格（由instrumentCache缓存）
+func (t *BitgetTrader) loadInstrument(symbol string) (instrumentSpec, error) {
+	var list []struct {
+		Symbol         string `json:"symbol"`
+		MinTradeNum    string `json:"minTradeNum"`
+		SizeMultiplier string `json:"sizeMultiplier"`
+		VolumePlace    string `json:"volumePlace"`
+		PricePlace     string `json:"pricePlace"`
+		PriceEndStep   string `json:"priceEndStep"`
+	}
+	if err := t.rest.get(bitgetMixPath+"/market/contracts", t.query(symbol), &list); err != nil {
+		return instrumentSpec{}, fmt.Errorf("获取Bitget交易规则失败: %w", err)
+	}
+	if len(list) == 0 {
+		return instrumentSpec{}, fmt.Errorf("未找到Bitget交易对 %s", symbol)
+	}
+
+	info := list[0]
+	qtyStep := parseFloatOrZero(info.SizeMultiplier)
+	if qtyStep <= 0 {
+		qtyStep = math.Pow10(-int(parseFloatOrZero(info.VolumePlace)))
+	}
+	// 价格步长 = priceEndStep × 10^-pricePlace（如pricePlace=1、priceEndStep=1时为0.1）
+	tickSize := parseFloatOrZero(info.PriceEndStep) * math.Pow10(-int(parseFloatOrZero(info.PricePlace)))
+	log.Printf("📋 Bitget交易规则 %s: sizeMultiplier=%s, minTradeNum=%s, pricePlace=%s, priceEndStep=%s",
+		info.Symbol, info.SizeMultiplier, info.MinTradeNum, info.PricePlace, info.PriceEndStep)
+	return instrumentSpec{
+		QtyStep:  qtyStep,
+		MinQty:   parseFloatOrZero(info.MinTradeNum),
+		TickSize: tickSize,
+	}, nil
+}
+
+// FormatQuantity 格式化数量到正确的精度（向下取整到sizeMultiplier）
+func (t *BitgetTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	spec, err := t.instruments.Get(symbol)
+	if err != nil {
+		return "", err
+	}
+	return spec.FormatQuantity(quantity), nil
+}
+
+// formatOrderQuantity 格式化下单数量，取整后不足最小下单量时返回错误
+func (t *BitgetTrader) formatOrderQuantity(symbol string, quantity float64) (string, error) {
+	spec, err := t.instruments.Get(symbol)
+	if err != nil {
+		return "", err
+	}
+	return spec.FormatOrderQuantity(symbol, quantity)
+}
+
+// formatPrice 格式化价格到价格步长的整数倍
+func (t *BitgetTrader) formatPrice(symbol string, price float64) (string, error) {
+	spec, err := t.instruments.Get(symbol)
+	if err != nil {
+		return "", err
+	}
+	return spec.FormatPrice(price), nil
+}
+
+// GetBalance 获取账户余额（带缓存）
+func (t *BitgetTrader) GetBalance() (*Balance, error) {
+	return t.cache.Balance(func() (*Balance, error) {
+		var accounts []struct {
+			MarginCoin    string `json:"marginCoin"`
+			Available     string `json:"available"`
+			AccountEquity string `json:"accountEquity"`
+			UnrealizedPL  string `json:"unrealizedPL"`
+		}
+		if err := t.rest.get(bitgetMixPath+"/account/accounts", t.query(""), &accounts); err != nil {
+			return nil, fmt.Errorf("获取Bitget余额失败: %w", err)
+		}
+
+		for _, account := range accounts {
+			if account.MarginCoin != t.marginCoin {
+				continue
+			}
+			// accountEquity包含未实现盈亏
+			unrealizedProfit := parseFloatOrZero(account.UnrealizedPL)
+			balance := &Balance{
+				WalletBalance:    parseFloatOrZero(account.AccountEquity) - unrealizedProfit,
+				UnrealizedProfit: unrealizedProfit,
+				AvailableBalance: parseFloatOrZero(account.Available),
+			}
+			log.Printf("✅ Bitget余额获取成功: total=%.2f, used=%.2f, free=%.2f",
+				balance.TotalEquity(), balance.UsedMargin(), balance.AvailableBalance)
+			return balance, nil
+		}
+		return nil, fmt.Errorf("Bitget未返回%s保证金账户", t.marginCoin)
+	})
+}
+
+// bitgetPositionInfo 持仓信息
+type bitgetPositionInfo struct {
+	Symbol           string `json:"symbol"`
+	HoldSide         string `json:"holdSide"` // long/short
+	Total            string `json:"total"`
+	OpenPriceAvg     string `json:"openPriceAvg"`
+	MarkPrice        string `json:"markPrice"`
+	UnrealizedPL     string `json:"unrealizedPL"`
+	Leverage         string `json:"leverage"`
+	LiquidationPrice string `json:"liquidationPrice"`
+	MarginMode       string `json:"marginMode"` // crossed/isolated
+}
+
+// bitgetPosition 将Bitget持仓转换为统一格式（无持仓时返回false）
+func bitgetPosition(pos bitgetPositionInfo) (Position, bool) {
+	size := parseFloatOrZero(pos.Total)
+	if size == 0 {
+		return Position{}, false
+	}
+
+	side := "long"
+	if pos.HoldSide == "short" {
+		side = "short"
+	}
+	marginMode := "cross"
+	if pos.MarginMode == "isolated" {
+		marginMode = "isolated"
+	}
+
+	return Position{
+		Symbol:           pos.Symbol,
+		Side:             side,
+		Quantity:         math.Abs(size),
+		EntryPrice:       parseFloatOrZero(pos.OpenPriceAvg),
+		MarkPrice:        parseFloatOrZero(pos.MarkPrice),
+		UnrealizedProfit: parseFloatOrZero(pos.UnrealizedPL),
+		Leverage:         int(parseFloatOrZero(pos.Leverage)),
+		LiquidationPrice: parseFloatOrZero(pos.LiquidationPrice),
+		MarginMode:       marginMode,
+	}, true
+}
+
+// GetPositions 获取所有持仓（带缓存）
+func (t *BitgetTrader) GetPositions() ([]Position, error) {
+	return t.cache.Positions(func() ([]Position, error) {
+		query := t.query("")
+		query.Set("marginCoin", t.marginCoin)
+
+		var list []bitgetPositionInfo
+		if err := t.rest.get(bitgetMixPath+"/position/all-position", query, &list); err != nil {
+			return nil, fmt.Errorf("获取Bitget持仓失败: %w", err)
+		}
+
+		positions := []Position{}
+		for _, item := range list {
+			if position, ok := bitgetPosition(item); ok {
+				position.Symbol = t.symbolOf(position.Symbol)
+				positions = append(positions, position)
+			}
+		}
+
+		log.Printf("✅ Bitget持仓获取成功: %d个持仓", len(positions))
+		return positions, nil
+	})
+}
+
+// ensureHedgeMode 确保合约账户处于双向持仓模式
+// 有持仓或挂单时Bitget不允许切换，此时只记录警告，由随后的下单返回具体错误
+func (t *BitgetTrader) ensureHedgeMode() {
+	t.hedgeModeMutex.Lock()
+	defer t.hedgeModeMutex.Unlock()
+	if t.hedgeModeReady {
+		return
+	}
+
+	params := map[string]interface{}{
+		"productType": t.productType,
+		"posMode":     "hedge_mode",
+	}
+	if err := t.rest.post(bitgetMixPath+"/account/set-position-mode", params, nil); err != nil {
+		log.Printf("⚠️ 设置Bitget双向持仓模式失败: %v，继续尝试下单", err)
+		return
+	}
+	t.hedgeModeReady = true
+	log.Printf("✓ Bitget账户已处于双向持仓模式")
+}
+
+// marginModeOf 交易对使用的保证金模式（crossed/isolated，未设置过时以交易所当前设置为准）
+func (t *BitgetTrader) marginModeOf(symbol string) string {
+	t.marginModesMutex.RLock()
+	mode, ok := t.marginModes[symbol]
+	t.marginModesMutex.RUnlock()
+	if ok {
+		return mode
+	}
+
+	query := t.query(symbol)
+	query.Set("marginCoin", t.marginCoin)
+	var account struct {
+		MarginMode string `json:"marginMode"`
+	}
+	if err := t.rest.get(bitgetMixPath+"/account/account", query, &account); err != nil || account.MarginMode == "" {
+		log.Printf("⚠️ 查询Bitget保证金模式失败: %v，按全仓处理", err)
+		return "crossed"
+	}
+
+	t.marginModesMutex.Lock()
+	t.marginModes[symbol] = account.MarginMode
+	t.marginModesMutex.Unlock()
+	return account.MarginMode
+}
+
+// SetLeverage 设置杠杆（逐仓模式下多空分别设置）
+func (t *BitgetTrader) SetLeverage(symbol string, leverage int) error {
+	holdSides := []string{""}
+	if t.marginModeOf(symbol) == "isolated" {
+		holdSides = []string{"long", "short"}
+	}
+
+	for _, holdSide := range holdSides {
+		params := t.params(symbol)
+		params["leverage"] = strconv.Itoa(leverage)
+		if holdSide != "" {
+			params["holdSide"] = holdSide
+		}
+		if err := t.rest.post(bitgetMixPath+"/account/set-leverage", params, nil); err != nil {
+			return fmt.Errorf("设置Bitget杠杆失败: %w", err)
+		}
+	}
+
+	log.Printf("  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
+	t.invalidateCache()
+	return nil
+}
+
+// SetMarginMode 设置仓位模式
+func (t *BitgetTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+	mode := "crossed"
+	marginModeStr := "全仓"
+	if !isCrossMargin {
+		mode = "isolated"
+		marginModeStr = "逐仓"
+	}
+
+	params := t.params(symbol)
+	params["marginMode"] = mode
+	if err := t.rest.post(bitgetMixPath+"/account/set-margin-mode", params, nil); err != nil {
+		return fmt.Errorf("设置Bitget仓位模式失败: %w", err)
+	}
+
+	t.marginModesMutex.Lock()
+	t.marginModes[symbol] = mode
+	t.marginModesMutex.Unlock()
+
+	log.Printf("  ✓ %s 仓位模式已设置为 %s", symbol, marginModeStr)
+	t.invalidateCache()
+	return nil
+}
+
+// GetMarketPrice 获取市场价格
+func (t *BitgetTrader) GetMarketPrice(symbol string) (float64, error) {
+	var tickers []struct {
+		Symbol    string `json:"symbol"`
+		LastPr    string `json:"lastPr"`
+		MarkPrice string `json:"markPrice"`
+	}
+	if err := t.rest.get(bitgetMixPath+"/market/ticker", t.query(symbol), &tickers); err != nil {
+		return 0, fmt.Errorf("获取Bitget市场价格失败: %w", err)
+	}
+	if len(tickers) == 0 {
+		return 0, fmt.Errorf("未找到 %s 的价格", symbol)
+	}
+
+	price, err := strconv.ParseFloat(tickers[0].LastPr, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析价格失败: %w", err)
+	}
+	return price, nil
+}
+
+// bitgetOrderSide 持仓方向（LONG/SHORT）对应的side
+// Bitget V2双向持仓模式下side表示持仓方向：开多和平多都是buy，开空和平空都是sell，由tradeSide区分开平
+func bitgetOrderSide(positionSide string) (side, holdSide string) {
+	if strings.EqualFold(positionSide, "short") {
+		return "sell", "short"
+	}
+	return "buy", "long"
+}
+
+// bitgetTradeDirection 订单实际的买卖方向（平仓方向与side相反）
+func bitgetTradeDirection(side, tradeSide string) string {
+	if strings.Contains(tradeSide, "close") {
+		if side == "buy" {
+			return "SELL"
+		}
+		return "BUY"
+	}
+	return strings.ToUpper(side)
+}
+
+// placeOrder 提交订单，返回交易所订单ID（下单响应不包含成交信息，需要通过订单或成交记录查询确认）
+func (t *BitgetTrader) placeOrder(symbol, positionSide, tradeSide, orderType, size string, extra map[string]interface{}) (*OrderResult, error) {
+	side, _ := bitgetOrderSide(positionSide)
+	params := t.params(symbol)
+	params["marginMode"] = t.marginModeOf(symbol)
+	params["side"] = side
+	params["tradeSide"] = tradeSide
+	params["orderType"] = orderType
+	params["size"] = size
+	for key, value := range extra {
+		params[key] = value
+	}
+	log.Printf("📤 Bitget下单请求: %v", params)
+
+	var result struct {
+		OrderID string `json:"orderId"`
+	}
+	if err := t.rest.post(bitgetMixPath+"/order/place-order", params, &result); err != nil {
+		return nil, err
+	}
+	t.invalidateCache()
+
+	price, _ := extra["price"].(string)
+	return &OrderResult{
+		OrderID:      result.OrderID,
+		Symbol:       symbol,
+		Status:       OrderStatusNew,
+		Type:         strings.ToUpper(orderType),
+		Side:         bitgetTradeDirection(side, tradeSide),
+		PositionSide: strings.ToUpper(positionSide),
+		Price:        parseFloatOrZero(price),
+		Quantity:     parseFloatOrZero(size),
+	}, nil
+}
+
+// openPosition 市价开仓
+func (t *BitgetTrader) openPosition(symbol, positionSide string, quantity float64, leverage int) (*OrderResult, error) {
+	// 先取消该币种的止盈止损单（保留未成交的限价单）
+	if err := t.cancelPlanOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消旧条件单失败（可能没有条件单）: %v", err)
+	}
+
+	t.ensureHedgeMode()
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	size, err := t.formatOrderQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := t.placeOrder(symbol, positionSide, "open", "market", size, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Bitget开仓成功: %s %s 数量: %s 订单ID: %s", symbol, positionSide, size, result.OrderID)
+	return result, nil
+}
+
+// OpenLong 开多仓
+func (t *BitgetTrader) OpenLong(symbol string, quantity float64, leverage int) (*OrderResult, error) {
+	result, err := t.openPosition(symbol, "LONG", quantity, leverage)
+	if err != nil {
+		return nil, fmt.Errorf("开多仓失败: %w", err)
+	}
+	return result, nil
+}
+
+// OpenShort 开空仓
+func (t *BitgetTrader) OpenShort(symbol string, quantity float64, leverage int) (*OrderResult, error) {
+	result, err := t.openPosition(symbol, "SHORT", quantity, leverage)
+	if err != nil {
+		return nil, fmt.Errorf("开空仓失败: %w", err)
+	}
+	return result, nil
+}
+
+// closePosition 市价平仓（quantity=0或超过持仓时全部平仓），全部平仓后取消该币种的止盈止损单
+func (t *BitgetTrader) closePosition(symbol, side string, quantity float64) (*OrderResult, error) {
+	positions, err := t.GetPositions()
+	if err != nil {
+		return nil, err
+	}
+
+	var positionSize float64
+	for _, pos := range positions {
+		if pos.Symbol == symbol && pos.Side == side {
+			positionSize = pos.Quantity
+			break
+		}
+	}
+	if positionSize <= 0 {
+		return nil, fmt.Errorf("没有找到 %s 的%s持仓", symbol, side)
+	}
+
+	closeAll := quantity <= 0 || quantity >= positionSize
+	if closeAll {
+		quantity = positionSize
+	}
+
+	size, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := t.placeOrder(symbol, side, "close", "market", size, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Bitget平仓成功: %s %s 数量: %s", symbol, side, size)
+
+	if closeAll {
+		if err := t.cancelPlanOrders(symbol); err != nil {
+			log.Printf("  ⚠ 取消条件单失败: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// CloseLong 平多仓
+func (t *BitgetTrader) CloseLong(symbol string, quantity float64) (*OrderResult, error) {
+	result, err := t.closePosition(symbol, "long", quantity)
+	if err != nil {
+		return nil, fmt.Errorf("平多仓失败: %w", err)
+	}
+	return result, nil
+}
+
+// CloseShort 平空仓
+func (t *BitgetTrader) CloseShort(symbol string, quantity float64) (*OrderResult, error) {
+	result, err := t.closePosition(symbol, "short", quantity)
+	if err != nil {
+		return nil, fmt.Errorf("平空仓失败: %w", err)
+	}
+	return result, nil
+}
+
+// placeTPSLOrder 提交止盈止损单（planType: loss_plan/profit_plan），按最新成交价触发后市价平仓
+func (t *BitgetTrader) placeTPSLOrder(symbol, positionSide string, quantity, triggerPrice float64, planType string) error {
+	_, holdSide := bitgetOrderSide(positionSide)
+
+	size, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return err
+	}
+	price, err := t.formatPrice(symbol, triggerPrice)
+	if err != nil {
+		return err
+	}
+
+	params := t.params(symbol)
+	params["planType"] = planType
+	params["triggerPrice"] = price
+	params["triggerType"] = "fill_price"
+	params["executePrice"] = "0" // 0表示触发后市价成交
+	params["holdSide"] = holdSide
+	params["size"] = size
+	if err := t.rest.post(bitgetMixPath+"/order/place-tpsl-order", params, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetStopLoss 设置止损单
+func (t *BitgetTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	if err := t.placeTPSLOrder(symbol, positionSide, quantity, stopPrice, "loss_plan"); err != nil {
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
+
+	log.Printf("  止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// SetTakeProfit 设置止盈单
+func (t *BitgetTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	if err := t.placeTPSLOrder(symbol, positionSide, quantity, takeProfitPrice, "profit_plan"); err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
+	}
+
+	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
+
+// trailingStops 本地跟踪止损模拟器（由AutoTrader接管触发平仓和持久化）
+func (t *BitgetTrader) trailingStops() *trailingStopEmulator {
+	return t.trailing
+}
+
+// SetTrailingStop 设置跟踪止损（本地根据实时行情模拟，触发后市价平仓）
+func (t *BitgetTrader) SetTrailingStop(symbol string, positionSide string, quantity, callbackRate, activationPrice float64) error {
+	if err := t.trailing.Set(symbol, positionSide, quantity, callbackRate, activationPrice); err != nil {
+		return fmt.Errorf("设置跟踪止损失败: %w", err)
+	}
+	return nil
+}
+
+// cancelPlanOrders 取消该币种的所有止盈止损单（没有可撤销的订单不视为错误）
+func (t *BitgetTrader) cancelPlanOrders(symbol string) error {
+	params := t.params(symbol)
+	params["planType"] = "profit_loss"
+	err := t.rest.post(bitgetMixPath+"/order/cancel-plan-order", params, nil)
+	if err != nil && exchangeErrorCode(err) != bitgetCodeNoOrderToCancel {
+		return err
+	}
+	return nil
+}
+
+// GetProtectiveOrders 获取止盈止损计划单（跟踪止损为本地模拟，不在此列出）
+func (t *BitgetTrader) GetProtectiveOrders(symbol string) ([]ProtectiveOrder, error) {
+	query := t.query(symbol)
+	query.Set("planType", "profit_loss")
+
+	var result struct {
+		EntrustedList []struct {
+			OrderID      string `json:"orderId"`
+			Symbol       string `json:"symbol"`
+			PlanType     string `json:"planType"`
+			TriggerPrice string `json:"triggerPrice"`
+			Size         string `json:"size"`
+			PosSide      string `json:"posSide"`
+		} `json:"entrustedList"`
+	}
+	if err := t.rest.get(bitgetMixPath+"/order/orders-plan-pending", query, &result); err != nil {
+		return nil, fmt.Errorf("获取止盈止损单失败: %w", err)
+	}
+
+	orders := []ProtectiveOrder{}
+	for _, item := range result.EntrustedList {
+		var kind string
+		switch item.PlanType {
+		case "loss_plan", "pos_loss":
+			kind = ProtectiveKindStopLoss
+		case "profit_plan", "pos_profit":
+			kind = ProtectiveKindTakeProfit
+		default:
+			continue
+		}
+		quantity := parseFloatOrZero(item.Size)
+		orders = append(orders, ProtectiveOrder{
+			OrderID:       item.OrderID,
+			Symbol:        t.symbolOf(item.Symbol),
+			PositionSide:  strings.ToUpper(item.PosSide),
+			Kind:          kind,
+			TriggerPrice:  parseFloatOrZero(item.TriggerPrice),
+			Quantity:      quantity,
+			ClosePosition: quantity == 0,
+		})
+	}
+	return orders, nil
+}
+
+// CancelProtectiveOrder 取消单个止盈止损计划单
+func (t *BitgetTrader) CancelProtectiveOrder(symbol string, orderID string) error {
+	params := t.params(symbol)
+	params["planType"] = "profit_loss"
+	params["orderIdList"] = []map[string]string{{"orderId": orderID}}
+
+	var result struct {
+		FailureList []struct {
+			OrderID  string `json:"orderId"`
+			ErrorMsg string `json:"errorMsg"`
+		} `json:"failureList"`
+	}
+	if err := t.rest.post(bitgetMixPath+"/order/cancel-plan-order", params, &result); err != nil {
+		return fmt.Errorf("取消止盈止损单失败: %w", err)
+	}
+	if len(result.FailureList) > 0 {
+		return fmt.Errorf("取消止盈止损单失败: %s", result.FailureList[0].ErrorMsg)
+	}
+
+	log.Printf("  ✓ 已取消 %s 止盈止损单 %s", symbol, orderID)
+	return nil
+}
+
+// CancelAllOrders 取消该币种的所有挂单（包括限价单和止盈止损单）
+func (t *BitgetTrader) CancelAllOrders(symbol string) error {
+	t.trailing.Cancel(symbol)
+	// 不传orderIdList时撤销该币种的所有普通委托
+	err := t.rest.post(bitgetMixPath+"/order/batch-cancel-orders", t.params(symbol), nil)
+	if err != nil && exchangeErrorCode(err) != bitgetCodeNoOrderToCancel {
+		return fmt.Errorf("取消挂单失败: %w", err)
+	}
+	if err := t.cancelPlanOrders(symbol); err != nil {
+		return fmt.Errorf("取消条件单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	return nil
+}
+
+// bitgetForce 转换为Bitget的有效方式
+func bitgetForce(tif TimeInForce) (string, error) {
+	switch tif {
+	case TimeInForceGTC, "":
+		return "gtc", nil
+	case TimeInForceIOC:
+		return "ioc", nil
+	case TimeInForceFOK:
+		return "fok", nil
+	case TimeInForcePostOnly:
+		return "post_only", nil
+	default:
+		return "", fmt.Errorf("不支持的限价单有效方式: %s", tif)
+	}
+}
+
+// PlaceLimitOrder 下限价开仓单
+func (t *BitgetTrader) PlaceLimitOrder(symbol string, positionSide string, quantity, price float64, leverage int, tif TimeInForce) (*OrderResult, error) {
+	force, err := bitgetForce(tif)
+	if err != nil {
+		return nil, err
+	}
+
+	// 设置杠杆（限价单不取消旧委托单，避免撤掉其他挂单）
+	t.ensureHedgeMode()
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	size, err := t.formatOrderQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	priceStr, err := t.formatPrice(symbol, price)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := t.placeOrder(symbol, positionSide, "open", "limit", size, map[string]interface{}{
+		"price": priceStr,
+		"force": force,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("下限价单失败: %w", err)
+	}
+
+	log.Printf("✓ 限价单已提交: %s %s 数量: %s 价格: %s (%s) 订单ID: %s", symbol, positionSide, size, priceStr, tif, result.OrderID)
+	return result, nil
+}
+
+// AmendOrder 修改未成交限价单的价格和数量（Bitget要求newSize和newPrice同时提交，未指定的沿用原订单）
+func (t *BitgetTrader) AmendOrder(symbol string, orderID string, quantity, price float64) (*OrderResult, error) {
+	original, err := t.GetOrder(symbol, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("修改订单失败: %w", err)
+	}
+	if quantity <= 0 {
+		quantity = original.Quantity
+	}
+	if price <= 0 {
+		price = original.Price
+	}
+
+	size, err := t.formatOrderQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	priceStr, err := t.formatPrice(symbol, price)
+	if err != nil {
+		return nil, err
+	}
+
+	params := t.params(symbol)
+	params["orderId"] = orderID
+	params["newClientOid"] = fmt.Sprintf("nofx-%d", time.Now().UnixNano())
+	params["newSize"] = size
+	params["newPrice"] = priceStr
+
+	var result struct {
+		OrderID string `json:"orderId"`
+	}
+	if err := t.rest.post(bitgetMixPath+"/order/modify-order", params, &result); err != nil {
+		return nil, fmt.Errorf("修改订单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已修改订单 %s: 数量 %s 价格 %s", orderID, size, priceStr)
+
+	if result.OrderID != "" {
+		orderID = result.OrderID
+	}
+	return t.GetOrder(symbol, orderID)
+}
+
+// CancelOrder 取消单个订单
+func (t *BitgetTrader) CancelOrder(symbol string, orderID string) error {
+	params := t.params(symbol)
+	params["orderId"] = orderID
+	if err := t.rest.post(bitgetMixPath+"/order/cancel-order", params, nil); err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已取消 %s 订单 %s", symbol, orderID)
+	return nil
+}
+
+// bitgetOrder 订单信息
+type bitgetOrder struct {
+	OrderID    string `json:"orderId"`
+	Symbol     string `json:"symbol"`
+	State      string `json:"state"`
+	OrderType  string `json:"orderType"`
+	Side       string `json:"side"`
+	TradeSide  string `json:"tradeSide"`
+	PosSide    string `json:"posSide"`
+	Force      string `json:"force"`
+	Price      string `json:"price"`
+	Size       string `json:"size"`
+	BaseVolume string `json:"baseVolume"`
+	PriceAvg   string `json:"priceAvg"`
+	Fee        string `json:"fee"`
+	CTime      string `json:"cTime"`
+	UTime      string `json:"uTime"`
+}
+
+// bitgetOrderStatus 将Bitget订单状态转换为统一格式
+// IOC/FOK订单未能立即成交而被撤销时视为过期，与币安的EXPIRED一致
+func bitgetOrderStatus(state, force string) string {
+	switch state {
+	case "live", "new", "init":
+		return OrderStatusNew
+	case "partially_filled":
+		return OrderStatusPartiallyFilled
+	case "filled":
+		return OrderStatusFilled
+	case "canceled", "cancelled":
+		if force == "ioc" || force == "fok" {
+			return OrderStatusExpired
+		}
+		return OrderStatusCanceled
+	default:
+		return OrderStatusCanceled
+	}
+}
+
+// toOrderResult 将Bitget订单转换为统一格式（fee为负表示支付的手续费）
+func (o *bitgetOrder) toOrderResult(symbol string) *OrderResult {
+	cTime, _ := strconv.ParseInt(o.CTime, 10, 64)
+	uTime, _ := strconv.ParseInt(o.UTime, 10, 64)
+	// 单向持仓模式下posSide为net，不对应持仓方向
+	var positionSide string
+	if o.PosSide == "long" || o.PosSide == "short" {
+		positionSide = strings.ToUpper(o.PosSide)
+	}
+
+	return &OrderResult{
+		OrderID:      o.OrderID,
+		Symbol:       symbol,
+		Status:       bitgetOrderStatus(o.State, o.Force),
+		Type:         strings.ToUpper(o.OrderType),
+		Side:         bitgetTradeDirection(o.Side, o.TradeSide),
+		PositionSide: positionSide,
+		Price:        parseFloatOrZero(o.Price),
+		Quantity:     parseFloatOrZero(o.Size),
+		ExecutedQty:  parseFloatOrZero(o.BaseVolume),
+		AvgPrice:     parseFloatOrZero(o.PriceAvg),
+		Fee:          -parseFloatOrZero(o.Fee),
+		Time:         cTime,
+		UpdateTime:   uTime,
+	}
+}
+
+// GetOrder 查询单个订单
+func (t *BitgetTrader) GetOrder(symbol string, orderID string) (*OrderResult, error) {
+	query := t.query(symbol)
+	query.Set("orderId", orderID)
+
+	var order bitgetOrder
+	if err := t.rest.get(bitgetMixPath+"/order/detail", query, &order); err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	return order.toOrderResult(symbol), nil
+}
+
+// GetOpenOrders 获取未成交挂单（symbol为空表示所有币种）
+func (t *BitgetTrader) GetOpenOrders(symbol string) ([]OrderResult, error) {
+	var result struct {
+		EntrustedList []bitgetOrder `json:"entrustedList"`
+	}
+	if err := t.rest.get(bitgetMixPath+"/order/orders-pending", t.query(symbol), &result); err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	orders := make([]OrderResult, 0, len(result.EntrustedList))
+	for i := range result.EntrustedList {
+		order := &result.EntrustedList[i]
+		orders = append(orders, *order.toOrderResult(t.symbolOf(order.Symbol)))
+	}
+	return orders, nil
+}
+
+// bitgetFillInfo 成交记录
+type bitgetFillInfo struct {
+	TradeID    string `json:"tradeId"`
+	OrderID    string `json:"orderId"`
+	Symbol     string `json:"symbol"`
+	Side       string `json:"side"`
+	TradeSide  string `json:"tradeSide"`
+	Price      string `json:"price"`
+	BaseVolume string `json:"baseVolume"`
+	FeeDetail  []struct {
+		FeeCoin  string `json:"feeCoin"`
+		TotalFee string `json:"totalFee"`
+	} `json:"feeDetail"`
+	TradeScope string `json:"tradeScope"` // taker/maker
+	CTime      string `json:"cTime"`
+}
+
+// bitgetFill 将Bitget成交记录转换为统一格式（totalFee为负表示支付的手续费）
+func bitgetFill(fill bitgetFillInfo) Fill {
+	var fee float64
+	feeCurrency := "USDT"
+	for _, detail := range fill.FeeDetail {
+		fee -= parseFloatOrZero(detail.TotalFee)
+		if detail.FeeCoin != "" {
+			feeCurrency = detail.FeeCoin
+		}
+	}
+	positionSide := "LONG"
+	if fill.Side == "sell" {
+		positionSide = "SHORT"
+	}
+	timestamp, _ := strconv.ParseInt(fill.CTime, 10, 64)
+
+	return Fill{
+		FillID:       fill.TradeID,
+		OrderID:      fill.OrderID,
+		Symbol:       fill.Symbol,
+		Side:         strings.ToLower(bitgetTradeDirection(fill.Side, fill.TradeSide)),
+		PositionSide: positionSide,
+		Price:        parseFloatOrZero(fill.Price),
+		Quantity:     parseFloatOrZero(fill.BaseVolume),
+		Fee:          fee,
+		FeeCurrency:  feeCurrency,
+		Role:         fill.TradeScope,
+		Timestamp:    timestamp,
+	}
+}
+
+// GetFills 获取自since以来的成交记录（symbol为空表示所有币种）
+func (t *BitgetTrader) GetFills(symbol string, since time.Time) ([]Fill, error) {
+	query := t.query(symbol)
+	query.Set("startTime", strconv.FormatInt(since.UnixMilli(), 10))
+	query.Set("limit", "100")
+
+	var result struct {
+		FillList []bitgetFillInfo `json:"fillList"`
+	}
+	if err := t.rest.get(bitgetMixPath+"/order/fills", query, &result); err != nil {
+		return nil, fmt.Errorf("获取成交记录失败: %w", err)
+	}
+
+	fills := make([]Fill, 0, len(result.FillList))
+	for _, item := range result.FillList {
+		fill := bitgetFill(item)
+		fill.Symbol = t.symbolOf(fill.Symbol)
+		fills = append(fills, fill)
+	}
+	return fills, nil
+}
+
+// bitgetBill 账务流水（businessType=contract_settle_fee为资金费，amount为正表示收到）
+type bitgetBill struct {
+	Symbol       string `json:"symbol"`
+	Amount       string `json:"amount"`
+	BusinessType string `json:"businessType"`
+	CTime        string `json:"cTime"`
+}
+
+// GetFundingPayments 获取自since以来的资金费结算记录（symbol为空表示所有币种）
+func (t *BitgetTrader) GetFundingPayments(symbol string, since time.Time) ([]FundingPayment, error) {
+	query := t.query(symbol)
+	query.Set("businessType", "contract_settle_fee")
+	query.Set("startTime", strconv.FormatInt(since.UnixMilli(), 10))
+	query.Set("limit", "100")
+
+	var result struct {
+		Bills []bitgetBill `json:"bills"`
+	}
+	if err := t.rest.get(bitgetMixPath+"/account/bill", query, &result); err != nil {
+		return nil, fmt.Errorf("获取资金费记录失败: %w", err)
+	}
+
+	payments := make([]FundingPayment, 0, len(result.Bills))
+	for _, bill := range result.Bills {
+		if bill.BusinessType != "contract_settle_fee" {
+			continue
+		}
+		payment := bitgetFundingPayment(bill)
+		payment.Symbol = t.symbolOf(payment.Symbol)
+		payments = append(payments, payment)
+	}
+	return payments, nil
+}
+
+// bitgetFundingPayment 将资金费流水转换为统一格式
+func bitgetFundingPayment(bill bitgetBill) FundingPayment {
+	timestamp, _ := strconv.ParseInt(bill.CTime, 10, 64)
+	return FundingPayment{
+		Symbol:    bill.Symbol,
+		Amount:    parseFloatOrZero(bill.Amount),
+		Timestamp: timestamp,
+	}
+}
@@ -0,0 +1,280 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestBitgetTrader 创建连接到模拟Bitget服务器的交易器
+func newTestBitgetTrader(t *testing.T, ex *fakeExchange) (*BitgetTrader, *fakeBitgetAPI) {
+	t.Helper()
+	srv, api := newFakeBitgetServer(t, ex)
+	tr, err := NewBitgetTrader("test-key", fakeBitgetSecret, fakeBitgetPassphrase, false)
+	if err != nil {
+		t.Fatalf("创建Bitget交易器失败: %v", err)
+	}
+	tr.rest.baseURL = srv.URL
+	return tr, api
+}
+
+func TestNewBitgetTrader(t *testing.T) {
+	if _, err := NewBitgetTrader("", "secret", "pass", false); err == nil {
+		t.Error("API密钥为空时应返回错误")
+	}
+	if _, err := NewBitgetTrader("key", "", "pass", false); err == nil {
+		t.Error("Secret密钥为空时应返回错误")
+	}
+	if _, err := NewBitgetTrader("key", "secret", "", false); err == nil {
+		t.Error("Passphrase为空时应返回错误")
+	}
+
+	tr, err := NewBitgetTrader("key", "secret", "pass", true)
+	if err != nil {
+		t.Fatalf("创建Bitget交易器失败: %v", err)
+	}
+	if tr.productType != "SUSDT-FUTURES" || tr.marginCoin != "SUSDT" {
+		t.Errorf("模拟盘产品类型错误: %s %s", tr.productType, tr.marginCoin)
+	}
+	if got := tr.exchangeSymbol("BTCUSDT"); got != "SBTCSUSDT" {
+		t.Errorf("模拟盘交易对应为 SBTCSUSDT, got %s", got)
+	}
+	if got := tr.symbolOf("SBTCSUSDT"); got != "BTCUSDT" {
+		t.Errorf("模拟盘交易对应还原为 BTCUSDT, got %s", got)
+	}
+}
+
+func TestBitgetRejectsInvalidCredentials(t *testing.T) {
+	srv, _ := newFakeBitgetServer(t, newFakeExchange())
+	for _, tc := range []struct {
+		secret, passphrase, code string
+	}{
+		{"wrong-secret", fakeBitgetPassphrase, bitgetErrSign},
+		{fakeBitgetSecret, "wrong-passphrase", bitgetErrPassphrase},
+	} {
+		tr, err := NewBitgetTrader("test-key", tc.secret, tc.passphrase, false)
+		if err != nil {
+			t.Fatalf("创建Bitget交易器失败: %v", err)
+		}
+		tr.rest.baseURL = srv.URL
+
+		_, err = tr.GetBalance()
+		if exchangeErrorCode(err) != tc.code || !IsExchangeErrorKind(err, ExchangeErrAuth) {
+			t.Errorf("凭证错误时应返回%s鉴权错误, got: %v", tc.code, err)
+		}
+	}
+}
+
+func TestBitgetGetBalance(t *testing.T) {
+	ex := newFakeExchange()
+	tr, _ := newTestBitgetTrader(t, ex)
+
+	if _, err := tr.OpenLong("BTCUSDT", 0.1, 10); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	ex.mu.Lock()
+	ex.specs["BTCUSDT"] = fakeSymbolSpec{Price: 51000, QtyStep: 0.001, TickSize: 0.1}
+	ex.mu.Unlock()
+
+	balance, err := tr.GetBalance()
+	if err != nil {
+		t.Fatalf("获取余额失败: %v", err)
+	}
+	// accountEquity包含未实现盈亏，钱包余额需扣除
+	if !almostEqual(balance.WalletBalance, 10000) || !almostEqual(balance.UnrealizedProfit, 100) {
+		t.Errorf("余额转换错误: %+v", balance)
+	}
+	if !almostEqual(balance.AvailableBalance, 9600) || !almostEqual(balance.UsedMargin(), 500) {
+		t.Errorf("可用保证金应为9600、占用500: %+v", balance)
+	}
+}
+
+func TestBitgetSwitchesToHedgeMode(t *testing.T) {
+	tr, api := newTestBitgetTrader(t, newFakeExchange())
+	if api.isHedgeMode() {
+		t.Fatal("模拟账户初始应为单向持仓模式")
+	}
+
+	if _, err := tr.OpenShort("BTCUSDT", 0.01, 5); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	if !api.isHedgeMode() {
+		t.Error("开仓前应切换到双向持仓模式")
+	}
+
+	if _, err := tr.OpenLong("BTCUSDT", 0.01, 5); err != nil {
+		t.Fatalf("双向持仓模式下开反向仓失败: %v", err)
+	}
+	requirePosition(t, tr, "BTCUSDT", "long")
+	requirePosition(t, tr, "BTCUSDT", "short")
+
+	// 平空的side为sell（持仓方向），实际为买入
+	order, err := tr.CloseShort("BTCUSDT", 0)
+	if err != nil {
+		t.Fatalf("平空仓失败: %v", err)
+	}
+	if order.Side != "BUY" || order.PositionSide != "SHORT" {
+		t.Errorf("平空订单方向错误: %+v", order)
+	}
+	if _, ok := findPosition(t, tr, "BTCUSDT", "short"); ok {
+		t.Error("空仓应已全部平掉")
+	}
+}
+
+func TestBitgetLimitOrderLifecycle(t *testing.T) {
+	tr, _ := newTestBitgetTrader(t, newFakeExchange())
+
+	// 低于市价的买单挂单等待，价格按pricePlace/priceEndStep取整
+	order, err := tr.PlaceLimitOrder("BTCUSDT", "LONG", 0.01, 49000.04, 5, TimeInForceGTC)
+	if err != nil {
+		t.Fatalf("下限价单失败: %v", err)
+	}
+	if order.OrderID == "" || order.Status != OrderStatusNew || !almostEqual(order.Price, 49000) || !almostEqual(order.Quantity, 0.01) {
+		t.Errorf("限价单结果错误: %+v", order)
+	}
+
+	open, err := tr.GetOpenOrders("BTCUSDT")
+	if err != nil {
+		t.Fatalf("获取挂单失败: %v", err)
+	}
+	if len(open) != 1 || open[0].OrderID != order.OrderID || open[0].PositionSide != "LONG" || open[0].Type != "LIMIT" {
+		t.Fatalf("挂单列表错误: %+v", open)
+	}
+
+	// 只改价格时沿用原数量
+	amended, err := tr.AmendOrder("BTCUSDT", order.OrderID, 0, 49500)
+	if err != nil {
+		t.Fatalf("修改订单失败: %v", err)
+	}
+	if amended.OrderID != order.OrderID || !almostEqual(amended.Price, 49500) || !almostEqual(amended.Quantity, 0.01) {
+		t.Errorf("修改后的订单错误: %+v", amended)
+	}
+
+	if err := tr.CancelOrder("BTCUSDT", order.OrderID); err != nil {
+		t.Fatalf("取消订单失败: %v", err)
+	}
+	cancelled, err := tr.GetOrder("BTCUSDT", order.OrderID)
+	if err != nil {
+		t.Fatalf("查询订单失败: %v", err)
+	}
+	if cancelled.Status != OrderStatusCanceled {
+		t.Errorf("取消后订单状态应为 %s, got %s", OrderStatusCanceled, cancelled.Status)
+	}
+	err = tr.CancelOrder("BTCUSDT", order.OrderID)
+	if !IsExchangeErrorKind(err, ExchangeErrOrderNotFound) || !strings.Contains(err.Error(), bitgetErrorMessages[bitgetErrOrderNotFound]) {
+		t.Errorf("重复取消应返回交易所原生错误, got: %v", err)
+	}
+
+	// 无法立即成交的IOC单视为过期
+	ioc, err := tr.PlaceLimitOrder("BTCUSDT", "LONG", 0.01, 49000, 5, TimeInForceIOC)
+	if err != nil {
+		t.Fatalf("下IOC单失败: %v", err)
+	}
+	details, err := tr.GetOrder("BTCUSDT", ioc.OrderID)
+	if err != nil {
+		t.Fatalf("查询订单失败: %v", err)
+	}
+	if details.Status != OrderStatusExpired {
+		t.Errorf("未成交的IOC单状态应为 %s, got %+v", OrderStatusExpired, details)
+	}
+}
+
+func TestBitgetOrderAndFillQueries(t *testing.T) {
+	tr, _ := newTestBitgetTrader(t, newFakeExchange())
+	since := time.Now().Add(-time.Minute)
+
+	order, err := tr.OpenLong("BTCUSDT", 0.02, 5)
+	if err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+
+	details, err := tr.GetOrder("BTCUSDT", order.OrderID)
+	if err != nil {
+		t.Fatalf("查询订单失败: %v", err)
+	}
+	if details.Status != OrderStatusFilled || !almostEqual(details.AvgPrice, 50000) || !almostEqual(details.ExecutedQty, 0.02) {
+		t.Errorf("订单查询结果错误: %+v", details)
+	}
+	// Bitget手续费为负数，统一为正数表示支付
+	if !almostEqual(details.Fee, 50000*0.02*fakeBitgetTakerFee) || details.Side != "BUY" || details.Type != "MARKET" {
+		t.Errorf("订单手续费或方向错误: %+v", details)
+	}
+
+	fills, err := tr.GetFills("BTCUSDT", since)
+	if err != nil {
+		t.Fatalf("获取成交记录失败: %v", err)
+	}
+	if len(fills) != 1 {
+		t.Fatalf("应有1条成交记录, got %d", len(fills))
+	}
+	fill := fills[0]
+	if fill.OrderID != order.OrderID || fill.Side != "buy" || fill.PositionSide != "LONG" || fill.Role != "taker" {
+		t.Errorf("成交记录转换错误: %+v", fill)
+	}
+	if !almostEqual(fill.Price, 50000) || !almostEqual(fill.Quantity, 0.02) || !almostEqual(fill.Fee, details.Fee) || fill.FeeCurrency != "USDT" {
+		t.Errorf("成交价格、数量或手续费错误: %+v", fill)
+	}
+
+	if fills, _ := tr.GetFills("BTCUSDT", time.Now().Add(time.Minute)); len(fills) != 0 {
+		t.Errorf("since之后没有成交, got %d", len(fills))
+	}
+}
+
+func TestBitgetCancelAllOrders(t *testing.T) {
+	ex := newFakeExchange()
+	tr, _ := newTestBitgetTrader(t, ex)
+
+	// 没有任何挂单时交易所返回22001，不应视为错误
+	if err := tr.CancelAllOrders("BTCUSDT"); err != nil {
+		t.Fatalf("没有挂单时取消不应失败: %v", err)
+	}
+
+	if _, err := tr.OpenLong("BTCUSDT", 0.01, 5); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	if err := tr.SetStopLoss("BTCUSDT", "LONG", 0.01, 48000); err != nil {
+		t.Fatalf("设置止损失败: %v", err)
+	}
+	if _, err := tr.PlaceLimitOrder("BTCUSDT", "LONG", 0.01, 49000, 5, TimeInForceGTC); err != nil {
+		t.Fatalf("下限价单失败: %v", err)
+	}
+
+	if err := tr.CancelAllOrders("BTCUSDT"); err != nil {
+		t.Fatalf("取消所有挂单失败: %v", err)
+	}
+	if _, ok := ex.trigger("BTCUSDT", "long", "sl"); ok {
+		t.Error("止损单应被取消")
+	}
+	open, err := tr.GetOpenOrders("")
+	if err != nil {
+		t.Fatalf("获取挂单失败: %v", err)
+	}
+	if len(open) != 0 {
+		t.Errorf("取消后不应有挂单: %+v", open)
+	}
+}
+
+func TestBitgetOrderStatus(t *testing.T) {
+	for _, tc := range []struct {
+		state, force, want string
+	}{
+		{"live", "gtc", OrderStatusNew},
+		{"partially_filled", "gtc", OrderStatusPartiallyFilled},
+		{"filled", "ioc", OrderStatusFilled},
+		{"canceled", "gtc", OrderStatusCanceled},
+		{"canceled", "post_only", OrderStatusCanceled},
+		{"canceled", "ioc", OrderStatusExpired},
+		{"canceled", "fok", OrderStatusExpired},
+	} {
+		if got := bitgetOrderStatus(tc.state, tc.force); got != tc.want {
+			t.Errorf("bitgetOrderStatus(%s, %s) = %s, want %s", tc.state, tc.force, got, tc.want)
+		}
+	}
+}
+
+func TestBitgetFundingPayment(t *testing.T) {
+	payment := bitgetFundingPayment(bitgetBill{Symbol: "BTCUSDT", Amount: "0.42", BusinessType: "contract_settle_fee", CTime: "1700006400000"})
+	if payment.Symbol != "BTCUSDT" || !almostEqual(payment.Amount, 0.42) || payment.Timestamp != 1700006400000 {
+		t.Errorf("资金费转换错误: %+v", payment)
+	}
+}
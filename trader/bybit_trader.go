@@ -0,0 +1,1177 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bybit双向持仓模式下的positionIdx
+const (
+	bybitPositionIdxLong  = 1
+	bybitPositionIdxShort = 2
+)
+
+// bybitErrorKinds Bybit V5返回码到统一错误分类的映射
+var bybitErrorKinds = map[string]ExchangeErrorKind{
+	"10003":  ExchangeErrAuth,               // API key is invalid
+	"10004":  ExchangeErrAuth,               // error sign
+	"10006":  ExchangeErrRateLimit,          // too many visits
+	"10018":  ExchangeErrRateLimit,          // exceeded the IP rate limit
+	"110001": ExchangeErrOrderNotFound,      // order not exists or too late to cancel
+	"110004": ExchangeErrInsufficientMargin, // wallet balance is insufficient
+	"110007": ExchangeErrInsufficientMargin, // available balance is insufficient
+	"110012": ExchangeErrInsufficientMargin, // insufficient available balance
+	"110017": ExchangeErrReduceOnly,         // reduce-only order qty exceeds position
+	"110025": ExchangeErrNotModified,        // position mode is not modified
+	"110026": ExchangeErrNotModified,        // cross/isolated margin mode is not modified
+	"110043": ExchangeErrNotModified,        // leverage not modified
+}
+
+// BybitTrader Bybit USDT永续合约交易器（V5接口，双向持仓模式）
+type BybitTrader struct {
+	apiKey     string
+	secretKey  string
+	recvWindow string
+	rest       *restClient
+
+	// 钱包类型：统一账户为UNIFIED，经典账户为CONTRACT
+	accountType string
+
+	// 余额和持仓缓存（15秒）
+	cache *accountCache
+
+	// 交易对规格缓存（数量步长、价格步长）
+	instruments *instrumentCache
+
+	// 账户是否已确认处于双向持仓模式
+	hedgeModeReady bool
+	hedgeModeMutex sync.Mutex
+
+	// 部分仓位的跟踪止损在本地模拟（Bybit原生跟踪止损只作用于整个仓位）
+	trailing *trailingStopEmulator
+}
+
+// bybitResponse V5接口的统一响应格式
+type bybitResponse struct {
+	RetCode int             `json:"retCode"`
+	RetMsg  string          `json:"retMsg"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// decodeBybitResponse 解析V5响应，retCode非0时返回原生错误码
+func decodeBybitResponse(status int, body []byte, out interface{}) (string, string, error) {
+	var result bybitResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", "", fmt.Errorf("解析响应失败 (HTTP %d): %s", status, string(body))
+	}
+	if result.RetCode != 0 {
+		return strconv.Itoa(result.RetCode), result.RetMsg, nil
+	}
+
+	if out != nil && len(result.Result) > 0 {
+		if err := json.Unmarshal(result.Result, out); err != nil {
+			return "", "", fmt.Errorf("解析响应数据失败: %w", err)
+		}
+	}
+	return "", "", nil
+}
+
+// NewBybitTrader 创建Bybit交易器
+func NewBybitTrader(apiKey, secretKey string, testnet bool) (*BybitTrader, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("API密钥不能为空")
+	}
+	if secretKey == "" {
+		return nil, fmt.Errorf("Secret密钥不能为空")
+	}
+
+	baseURL := "https://api.bybit.com"
+	if testnet {
+		baseURL = "https://api-testnet.bybit.com"
+		log.Println("✅ Bybit测试网模式已启用")
+	}
+
+	t := &BybitTrader{
+		apiKey:      apiKey,
+		secretKey:   secretKey,
+		recvWindow:  "5000",
+		accountType: "UNIFIED",
+		cache:       newAccountCache("Bybit", 15*time.Second),
+	}
+	t.rest = newRESTClient("Bybit", baseURL, t.sign, decodeBybitResponse, bybitErrorKinds)
+	t.instruments = newInstrumentCache(t.loadInstrument)
+	t.trailing = newTrailingStopEmulator("Bybit", t)
+	return t, nil
+}
+
+// invalidateCache 下单或调整杠杆后清除余额和持仓缓存，避免随后的查询读到旧数据
+func (t *BybitTrader) invalidateCache() {
+	t.cache.Invalidate()
+}
+
+// sign 添加V5签名请求头：HMAC_SHA256(timestamp + apiKey + recvWindow + queryString或body)，十六进制小写
+func (t *BybitTrader) sign(req *restRequest) {
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	req.Header.Set("X-BAPI-API-KEY", t.apiKey)
+	req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
+	req.Header.Set("X-BAPI-RECV-WINDOW", t.recvWindow)
+	req.Header.Set("X-BAPI-SIGN", hmacSHA256Hex(t.secretKey, timestamp+t.apiKey+t.recvWindow+req.Query+req.Body))
+}
+
+// request 发送签名请求，retCode非0时返回*ExchangeAPIError；out不为nil时解析result字段
+// GET请求参数放在querystring中，POST请求参数以JSON放在body中
+func (t *BybitTrader) request(method, endpoint string, params map[string]interface{}, out interface{}) error {
+	if method == http.MethodGet {
+		query := url.Values{}
+		for key, value := range params {
+			query.Set(key, fmt.Sprint(value))
+		}
+		return t.rest.get(endpoint, query, out)
+	}
+
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return t.rest.do(method, endpoint, nil, params, out)
+}
+
+// bybitWalletAccount 钱包余额（统一账户使用total*汇总字段，经典账户使用coin明细）
+type bybitWalletAccount struct {
+	TotalWalletBalance    string `json:"totalWalletBalance"`
+	TotalPerpUPL          string `json:"totalPerpUPL"`
+	TotalAvailableBalance string `json:"totalAvailableBalance"`
+	Coin                  []struct {
+		Coin                string `json:"coin"`
+		WalletBalance       string `json:"walletBalance"`
+		UnrealisedPnl       string `json:"unrealisedPnl"`
+		AvailableToWithdraw string `json:"availableToWithdraw"`
+	} `json:"coin"`
+}
+
+// bybitBalance 将钱包余额转换为统一格式
+func bybitBalance(account bybitWalletAccount) *Balance {
+	if account.TotalWalletBalance != "" {
+		return &Balance{
+			WalletBalance:    parseFloatOrZero(account.TotalWalletBalance),
+			UnrealizedProfit: parseFloatOrZero(account.TotalPerpUPL),
+			AvailableBalance: parseFloatOrZero(account.TotalAvailableBalance),
+		}
+	}
+
+	result := &Balance{}
+	for _, coin := range account.Coin {
+		if coin.Coin == "USDT" {
+			result.WalletBalance = parseFloatOrZero(coin.WalletBalance)
+			result.UnrealizedProfit = parseFloatOrZero(coin.UnrealisedPnl)
+			result.AvailableBalance = parseFloatOrZero(coin.AvailableToWithdraw)
+		}
+	}
+	return result
+}
+
+// GetBalance 获取账户余额（带缓存）
+func (t *BybitTrader) GetBalance() (*Balance, error) {
+	return t.cache.Balance(func() (*Balance, error) {
+		var result struct {
+			List []bybitWalletAccount `json:"list"`
+		}
+		params := map[string]interface{}{"accountType": t.accountType}
+		if err := t.request(http.MethodGet, "/v5/account/wallet-balance", params, &result); err != nil {
+			return nil, fmt.Errorf("获取Bybit余额失败: %w", err)
+		}
+		if len(result.List) == 0 {
+			return nil, fmt.Errorf("Bybit未返回%s钱包余额", t.accountType)
+		}
+
+		balance := bybitBalance(result.List[0])
+		log.Printf("✅ Bybit余额获取成功: total=%.2f, used=%.2f, free=%.2f",
+			balance.TotalEquity(), balance.UsedMargin(), balance.AvailableBalance)
+		return balance, nil
+	})
+}
+
+// bybitPositionInfo 持仓信息
+type bybitPositionInfo struct {
+	PositionIdx   int    `json:"positionIdx"`
+	Symbol        string `json:"symbol"`
+	Side          string `json:"side"`
+	Size          string `json:"size"`
+	AvgPrice      string `json:"avgPrice"`
+	MarkPrice     string `json:"markPrice"`
+	UnrealisedPnl string `json:"unrealisedPnl"`
+	Leverage      string `json:"leverage"`
+	LiqPrice      string `json:"liqPrice"`
+	TradeMode     int    `json:"tradeMode"` // 0=全仓 1=逐仓
+}
+
+// bybitPosition 将Bybit持仓转换为统一格式（无持仓时返回false）
+func bybitPosition(pos bybitPositionInfo) (Position, bool) {
+	size := parseFloatOrZero(pos.Size)
+	if size == 0 {
+		return Position{}, false
+	}
+
+	// 双向持仓下由positionIdx区分方向，单向持仓(positionIdx=0)下由side区分
+	side := "long"
+	switch pos.PositionIdx {
+	case bybitPositionIdxShort:
+		side = "short"
+	case bybitPositionIdxLong:
+	default:
+		if pos.Side == "Sell" {
+			side = "short"
+		}
+	}
+
+	marginMode := "cross"
+	if pos.TradeMode == 1 {
+		marginMode = "isolated"
+	}
+
+	return Position{
+		Symbol:           pos.Symbol,
+		Side:             side,
+		Quantity:         math.Abs(size),
+		EntryPrice:       parseFloatOrZero(pos.AvgPrice),
+		MarkPrice:        parseFloatOrZero(pos.MarkPrice),
+		UnrealizedProfit: parseFloatOrZero(pos.UnrealisedPnl),
+		Leverage:         int(parseFloatOrZero(pos.Leverage)),
+		LiquidationPrice: parseFloatOrZero(pos.LiqPrice),
+		MarginMode:       marginMode,
+	}, true
+}
+
+// listPositions 查询持仓列表（symbol为空时查询所有USDT结算的合约，自动翻页）
+func (t *BybitTrader) listPositions(symbol string) ([]bybitPositionInfo, error) {
+	params := map[string]interface{}{
+		"category": "linear",
+		"limit":    200,
+	}
+	if symbol != "" {
+		params["symbol"] = symbol
+	} else {
+		params["settleCoin"] = "USDT"
+	}
+
+	var positions []bybitPositionInfo
+	for {
+		var result struct {
+			List           []bybitPositionInfo `json:"list"`
+			NextPageCursor string              `json:"nextPageCursor"`
+		}
+		if err := t.request(http.MethodGet, "/v5/position/list", params, &result); err != nil {
+			return nil, err
+		}
+		positions = append(positions, result.List...)
+		if result.NextPageCursor == "" || len(result.List) == 0 {
+			return positions, nil
+		}
+		params["cursor"] = result.NextPageCursor
+	}
+}
+
+// GetPositions 获取所有持仓（带缓存）
+func (t *BybitTrader) GetPositions() ([]Position, error) {
+	return t.cache.Positions(func() ([]Position, error) {
+		list, err := t.listPositions("")
+		if err != nil {
+			return nil, fmt.Errorf("获取Bybit持仓失败: %w", err)
+		}
+
+		positions := []Position{}
+		for _, item := range list {
+			if position, ok := bybitPosition(item); ok {
+				positions = append(positions, position)
+			}
+		}
+
+		log.Printf("✅ Bybit持仓获取成功: %d个持仓", len(positions))
+		return positions, nil
+	})
+}
+
+// ensureHedgeMode 确保USDT永续合约处于双向持仓模式（positionIdx=1/2需要）
+// 账户有持仓或挂单时Bybit不允许切换，此时只记录警告，由随后的下单返回具体错误
+func (t *BybitTrader) ensureHedgeMode() {
+	t.hedgeModeMutex.Lock()
+	defer t.hedgeModeMutex.Unlock()
+	if t.hedgeModeReady {
+		return
+	}
+
+	params := map[string]interface{}{
+		"category": "linear",
+		"coin":     "USDT",
+		"mode":     3, // 3=双向持仓 0=单向持仓
+	}
+	err := t.request(http.MethodPost, "/v5/position/switch-mode", params, nil)
+	if err != nil && !IsExchangeErrorKind(err, ExchangeErrNotModified) {
+		log.Printf("⚠️ 设置Bybit双向持仓模式失败: %v，继续尝试下单", err)
+		return
+	}
+	t.hedgeModeReady = true
+	log.Printf("✓ Bybit账户已处于双向持仓模式")
+}
+
+// SetLeverage 设置杠杆（多空使用相同杠杆）
+func (t *BybitTrader) SetLeverage(symbol string, leverage int) error {
+	params := map[string]interface{}{
+		"category":     "linear",
+		"symbol":       symbol,
+		"buyLeverage":  strconv.Itoa(leverage),
+		"sellLeverage": strconv.Itoa(leverage),
+	}
+	err := t.request(http.MethodPost, "/v5/position/set-leverage", params, nil)
+	if IsExchangeErrorKind(err, ExchangeErrNotModified) {
+		log.Printf("  ✓ %s 杠杆已是 %dx", symbol, leverage)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("设置Bybit杠杆失败: %w", err)
+	}
+
+	log.Printf("  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
+	t.invalidateCache()
+	return nil
+}
+
+// currentLeverage 查询该币种当前的杠杆（没有持仓时Bybit也会返回数量为0的持仓记录）
+func (t *BybitTrader) currentLeverage(symbol string) (int, error) {
+	list, err := t.listPositions(symbol)
+	if err != nil {
+		return 0, err
+	}
+	for _, pos := range list {
+		if leverage := int(parseFloatOrZero(pos.Leverage)); leverage > 0 {
+			return leverage, nil
+		}
+	}
+	return 0, fmt.Errorf("未找到 %s 的杠杆设置", symbol)
+}
+
+// SetMarginMode 设置仓位模式
+// Bybit切换全仓/逐仓时必须同时提交杠杆，这里沿用该币种当前的杠杆
+func (t *BybitTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+	tradeMode := 0
+	marginModeStr := "全仓"
+	if !isCrossMargin {
+		tradeMode = 1
+		marginModeStr = "逐仓"
+	}
+
+	leverage, err := t.currentLeverage(symbol)
+	if err != nil {
+		return fmt.Errorf("设置Bybit仓位模式失败: %w", err)
+	}
+
+	params := map[string]interface{}{
+		"category":     "linear",
+		"symbol":       symbol,
+		"tradeMode":    tradeMode,
+		"buyLeverage":  strconv.Itoa(leverage),
+		"sellLeverage": strconv.Itoa(leverage),
+	}
+	err = t.request(http.MethodPost, "/v5/position/switch-isolated", params, nil)
+	if IsExchangeErrorKind(err, ExchangeErrNotModified) {
+		log.Printf("  ✓ %s 仓位模式已是 %s", symbol, marginModeStr)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("设置Bybit仓位模式失败: %w", err)
+	}
+
+	log.Printf("  ✓ %s 仓位模式已设置为 %s", symbol, marginModeStr)
+	t.invalidateCache()
+	return nil
+}
+
+// loadInstrument 从交易所查询交易对的下单规格（由instrumentCache缓存）
+func (t *BybitTrader) loadInstrument(symbol string) (instrumentSpec, error) {
+	var result struct {
+		List []struct {
+			Symbol        string `json:"symbol"`
+			LotSizeFilter struct {
+				QtyStep     string `json:"qtyStep"`
+				MinOrderQty string `json:"minOrderQty"`
+			} `json:"lotSizeFilter"`
+			PriceFilter struct {
+				TickSize string `json:"tickSize"`
+			} `json:"priceFilter"`
+		} `json:"list"`
+	}
+	params := map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+	}
+	if err := t.request(http.MethodGet, "/v5/market/instruments-info", params, &result); err != nil {
+		return instrumentSpec{}, fmt.Errorf("获取Bybit交易规则失败: %w", err)
+	}
+	if len(result.List) == 0 {
+		return instrumentSpec{}, fmt.Errorf("未找到Bybit交易对 %s", symbol)
+	}
+
+	info := result.List[0]
+	log.Printf("📋 Bybit交易规则 %s: qtyStep=%s, minOrderQty=%s, tickSize=%s",
+		symbol, info.LotSizeFilter.QtyStep, info.LotSizeFilter.MinOrderQty, info.PriceFilter.TickSize)
+	return instrumentSpec{
+		QtyStep:  parseFloatOrZero(info.LotSizeFilter.QtyStep),
+		MinQty:   parseFloatOrZero(info.LotSizeFilter.MinOrderQty),
+		TickSize: parseFloatOrZero(info.PriceFilter.TickSize),
+	}, nil
+}
+
+// FormatQuantity 格式化数量到正确的精度（向下取整到qtyStep，避免超出持仓或可用保证金）
+func (t *BybitTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	spec, err := t.instruments.Get(symbol)
+	if err != nil {
+		return "", err
+	}
+	return spec.FormatQuantity(quantity), nil
+}
+
+// formatOrderQuantity 格式化下单数量，取整后不足最小下单量时返回错误
+func (t *BybitTrader) formatOrderQuantity(symbol string, quantity float64) (string, error) {
+	spec, err := t.instruments.Get(symbol)
+	if err != nil {
+		return "", err
+	}
+	return spec.FormatOrderQuantity(symbol, quantity)
+}
+
+// formatPrice 格式化价格到tickSize的整数倍
+func (t *BybitTrader) formatPrice(symbol string, price float64) (string, error) {
+	spec, err := t.instruments.Get(symbol)
+	if err != nil {
+		return "", err
+	}
+	return spec.FormatPrice(price), nil
+}
+
+// GetMarketPrice 获取市场价格
+func (t *BybitTrader) GetMarketPrice(symbol string) (float64, error) {
+	var result struct {
+		List []struct {
+			LastPrice string `json:"lastPrice"`
+		} `json:"list"`
+	}
+	params := map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+	}
+	if err := t.request(http.MethodGet, "/v5/market/tickers", params, &result); err != nil {
+		return 0, fmt.Errorf("获取Bybit市场价格失败: %w", err)
+	}
+	if len(result.List) == 0 {
+		return 0, fmt.Errorf("未找到 %s 的价格", symbol)
+	}
+
+	price, err := strconv.ParseFloat(result.List[0].LastPrice, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析价格失败: %w", err)
+	}
+	return price, nil
+}
+
+// bybitOrderSide 持仓方向（LONG/SHORT）对应的开仓方向和positionIdx
+func bybitOrderSide(positionSide string) (openSide, closeSide string, positionIdx int) {
+	if strings.EqualFold(positionSide, "short") {
+		return "Sell", "Buy", bybitPositionIdxShort
+	}
+	return "Buy", "Sell", bybitPositionIdxLong
+}
+
+// placeOrder 提交订单，返回交易所订单ID（下单响应不包含成交信息，需要通过订单或成交记录查询确认）
+func (t *BybitTrader) placeOrder(params map[string]interface{}) (*OrderResult, error) {
+	params["category"] = "linear"
+	log.Printf("📤 Bybit下单请求: %v", params)
+
+	var result struct {
+		OrderID string `json:"orderId"`
+	}
+	if err := t.request(http.MethodPost, "/v5/order/create", params, &result); err != nil {
+		return nil, err
+	}
+	t.invalidateCache()
+
+	symbol, _ := params["symbol"].(string)
+	side, _ := params["side"].(string)
+	orderType, _ := params["orderType"].(string)
+	qty, _ := params["qty"].(string)
+	price, _ := params["price"].(string)
+	triggerPrice, _ := params["triggerPrice"].(string)
+	positionSide := "LONG"
+	if params["positionIdx"] == bybitPositionIdxShort {
+		positionSide = "SHORT"
+	}
+
+	return &OrderResult{
+		OrderID:      result.OrderID,
+		Symbol:       symbol,
+		Status:       OrderStatusNew,
+		Type:         strings.ToUpper(orderType),
+		Side:         strings.ToUpper(side),
+		PositionSide: positionSide,
+		Price:        parseFloatOrZero(price),
+		StopPrice:    parseFloatOrZero(triggerPrice),
+		Quantity:     parseFloatOrZero(qty),
+	}, nil
+}
+
+// openPosition 市价开仓
+func (t *BybitTrader) openPosition(symbol, positionSide string, quantity float64, leverage int) (*OrderResult, error) {
+	openSide, _, positionIdx := bybitOrderSide(positionSide)
+
+	// 先取消该币种的条件单（清理旧的止损止盈单，保留未成交的限价单）
+	if err := t.cancelStopOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消旧条件单失败（可能没有条件单）: %v", err)
+	}
+
+	t.ensureHedgeMode()
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	qty, err := t.formatOrderQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := t.placeOrder(map[string]interface{}{
+		"symbol":      symbol,
+		"side":        openSide,
+		"orderType":   "Market",
+		"qty":         qty,
+		"positionIdx": positionIdx,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Bybit开仓成功: %s %s 数量: %s 订单ID: %s", symbol, positionSide, qty, result.OrderID)
+	return result, nil
+}
+
+// OpenLong 开多仓
+func (t *BybitTrader) OpenLong(symbol string, quantity float64, leverage int) (*OrderResult, error) {
+	result, err := t.openPosition(symbol, "LONG", quantity, leverage)
+	if err != nil {
+		return nil, fmt.Errorf("开多仓失败: %w", err)
+	}
+	return result, nil
+}
+
+// OpenShort 开空仓
+func (t *BybitTrader) OpenShort(symbol string, quantity float64, leverage int) (*OrderResult, error) {
+	result, err := t.openPosition(symbol, "SHORT", quantity, leverage)
+	if err != nil {
+		return nil, fmt.Errorf("开空仓失败: %w", err)
+	}
+	return result, nil
+}
+
+// closePosition 市价平仓（quantity=0或超过持仓时全部平仓），全部平仓后取消该币种的条件单
+func (t *BybitTrader) closePosition(symbol, side string, quantity float64) (*OrderResult, error) {
+	positions, err := t.GetPositions()
+	if err != nil {
+		return nil, err
+	}
+
+	var positionSize float64
+	for _, pos := range positions {
+		if pos.Symbol == symbol && pos.Side == side {
+			positionSize = pos.Quantity
+			break
+		}
+	}
+	if positionSize <= 0 {
+		return nil, fmt.Errorf("没有找到 %s 的%s持仓", symbol, side)
+	}
+
+	closeAll := quantity <= 0 || quantity >= positionSize
+	if closeAll {
+		quantity = positionSize
+	}
+
+	qty, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	_, closeSide, positionIdx := bybitOrderSide(side)
+	result, err := t.placeOrder(map[string]interface{}{
+		"symbol":      symbol,
+		"side":        closeSide,
+		"orderType":   "Market",
+		"qty":         qty,
+		"positionIdx": positionIdx,
+		"reduceOnly":  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Bybit平仓成功: %s %s 数量: %s", symbol, side, qty)
+
+	if closeAll {
+		if err := t.cancelStopOrders(symbol); err != nil {
+			log.Printf("  ⚠ 取消条件单失败: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// CloseLong 平多仓
+func (t *BybitTrader) CloseLong(symbol string, quantity float64) (*OrderResult, error) {
+	result, err := t.closePosition(symbol, "long", quantity)
+	if err != nil {
+		return nil, fmt.Errorf("平多仓失败: %w", err)
+	}
+	return result, nil
+}
+
+// CloseShort 平空仓
+func (t *BybitTrader) CloseShort(symbol string, quantity float64) (*OrderResult, error) {
+	result, err := t.closePosition(symbol, "short", quantity)
+	if err != nil {
+		return nil, fmt.Errorf("平空仓失败: %w", err)
+	}
+	return result, nil
+}
+
+// placeConditionalOrder 提交止盈止损条件单（kind: sl/tp），按最新价触发后市价只减仓
+// triggerDirection: 1=价格上涨到触发价时触发，2=价格下跌到触发价时触发
+func (t *BybitTrader) placeConditionalOrder(symbol, positionSide string, quantity, triggerPrice float64, kind string) error {
+	_, closeSide, positionIdx := bybitOrderSide(positionSide)
+
+	// 多仓止损和空仓止盈在价格下跌时触发
+	triggerDirection := 1
+	if (positionIdx == bybitPositionIdxLong) == (kind == "sl") {
+		triggerDirection = 2
+	}
+
+	qty, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return err
+	}
+	price, err := t.formatPrice(symbol, triggerPrice)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.placeOrder(map[string]interface{}{
+		"symbol":           symbol,
+		"side":             closeSide,
+		"orderType":        "Market",
+		"qty":              qty,
+		"positionIdx":      positionIdx,
+		"triggerPrice":     price,
+		"triggerDirection": triggerDirection,
+		"triggerBy":        "LastPrice",
+		"reduceOnly":       true,
+		"closeOnTrigger":   true,
+	})
+	return err
+}
+
+// SetStopLoss 设置止损单
+func (t *BybitTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	if err := t.placeConditionalOrder(symbol, positionSide, quantity, stopPrice, "sl"); err != nil {
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
+
+	log.Printf("  止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// SetTakeProfit 设置止盈单
+func (t *BybitTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	if err := t.placeConditionalOrder(symbol, positionSide, quantity, takeProfitPrice, "tp"); err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
+	}
+
+	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
+
+// trailingStops 本地跟踪止损模拟器（由AutoTrader接管触发平仓和持久化）
+func (t *BybitTrader) trailingStops() *trailingStopEmulator {
+	return t.trailing
+}
+
+// SetTrailingStop 设置跟踪止损
+// Bybit原生跟踪止损通过/v5/position/trading-stop设置在整个仓位上，trailingStop为价格距离；
+// 只保护部分仓位时改为本地模拟
+func (t *BybitTrader) SetTrailingStop(symbol string, positionSide string, quantity, callbackRate, activationPrice float64) error {
+	if err := validateTrailingStop(positionSide, quantity, callbackRate, activationPrice); err != nil {
+		return err
+	}
+	_, _, positionIdx := bybitOrderSide(positionSide)
+
+	list, err := t.listPositions(symbol)
+	if err != nil {
+		return fmt.Errorf("设置跟踪止损失败: %w", err)
+	}
+	var size float64
+	for _, item := range list {
+		if item.PositionIdx == positionIdx {
+			size = math.Abs(parseFloatOrZero(item.Size))
+		}
+	}
+	if size == 0 {
+		return fmt.Errorf("设置跟踪止损失败: %s %s 没有持仓", symbol, positionSide)
+	}
+	if quantity < size-1e-9 {
+		return t.trailing.Set(symbol, positionSide, quantity, callbackRate, activationPrice)
+	}
+
+	// 回调距离按激活价（未设置时按当前价）换算
+	reference := activationPrice
+	if reference <= 0 {
+		if reference, err = t.GetMarketPrice(symbol); err != nil {
+			return fmt.Errorf("设置跟踪止损失败: %w", err)
+		}
+	}
+	distance, err := t.formatPrice(symbol, reference*callbackRate)
+	if err != nil {
+		return err
+	}
+
+	params := map[string]interface{}{
+		"category":     "linear",
+		"symbol":       symbol,
+		"positionIdx":  positionIdx,
+		"tpslMode":     "Full",
+		"trailingStop": distance,
+	}
+	if activationPrice > 0 {
+		activePrice, err := t.formatPrice(symbol, activationPrice)
+		if err != nil {
+			return err
+		}
+		params["activePrice"] = activePrice
+	}
+	if err := t.request(http.MethodPost, "/v5/position/trading-stop", params, nil); err != nil {
+		return fmt.Errorf("设置跟踪止损失败: %w", err)
+	}
+
+	log.Printf("  跟踪止损设置: 回调距离 %s, 激活价 %.4f", distance, activationPrice)
+	return nil
+}
+
+// cancelAll 取消该币种的订单（orderFilter为空表示所有订单，StopOrder表示只取消条件单）
+func (t *BybitTrader) cancelAll(symbol, orderFilter string) error {
+	params := map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+	}
+	if orderFilter != "" {
+		params["orderFilter"] = orderFilter
+	}
+	return t.request(http.MethodPost, "/v5/order/cancel-all", params, nil)
+}
+
+// cancelStopOrders 取消该币种的所有条件单（止盈止损）
+func (t *BybitTrader) cancelStopOrders(symbol string) error {
+	return t.cancelAll(symbol, "StopOrder")
+}
+
+// CancelAllOrders 取消该币种的所有挂单（包括限价单和条件单）
+func (t *BybitTrader) CancelAllOrders(symbol string) error {
+	t.trailing.Cancel(symbol)
+	if err := t.cancelAll(symbol, ""); err != nil {
+		return fmt.Errorf("取消挂单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	return nil
+}
+
+// bybitTimeInForce 转换为Bybit的有效方式
+func bybitTimeInForce(tif TimeInForce) (string, error) {
+	switch tif {
+	case TimeInForceGTC, "":
+		return "GTC", nil
+	case TimeInForceIOC:
+		return "IOC", nil
+	case TimeInForceFOK:
+		return "FOK", nil
+	case TimeInForcePostOnly:
+		return "PostOnly", nil
+	default:
+		return "", fmt.Errorf("不支持的限价单有效方式: %s", tif)
+	}
+}
+
+// PlaceLimitOrder 下限价开仓单
+func (t *BybitTrader) PlaceLimitOrder(symbol string, positionSide string, quantity, price float64, leverage int, tif TimeInForce) (*OrderResult, error) {
+	timeInForce, err := bybitTimeInForce(tif)
+	if err != nil {
+		return nil, err
+	}
+	openSide, _, positionIdx := bybitOrderSide(positionSide)
+
+	// 设置杠杆（限价单不取消旧委托单，避免撤掉其他挂单）
+	t.ensureHedgeMode()
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	qty, err := t.formatOrderQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	priceStr, err := t.formatPrice(symbol, price)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := t.placeOrder(map[string]interface{}{
+		"symbol":      symbol,
+		"side":        openSide,
+		"orderType":   "Limit",
+		"qty":         qty,
+		"price":       priceStr,
+		"timeInForce": timeInForce,
+		"positionIdx": positionIdx,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("下限价单失败: %w", err)
+	}
+
+	log.Printf("✓ 限价单已提交: %s %s 数量: %s 价格: %s (%s) 订单ID: %s", symbol, positionSide, qty, priceStr, tif, result.OrderID)
+	return result, nil
+}
+
+// AmendOrder 修改未成交限价单的价格和数量
+func (t *BybitTrader) AmendOrder(symbol string, orderID string, quantity, price float64) (*OrderResult, error) {
+	params := map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+		"orderId":  orderID,
+	}
+	if quantity > 0 {
+		qty, err := t.formatOrderQuantity(symbol, quantity)
+		if err != nil {
+			return nil, err
+		}
+		params["qty"] = qty
+	}
+	if price > 0 {
+		priceStr, err := t.formatPrice(symbol, price)
+		if err != nil {
+			return nil, err
+		}
+		params["price"] = priceStr
+	}
+
+	if err := t.request(http.MethodPost, "/v5/order/amend", params, nil); err != nil {
+		return nil, fmt.Errorf("修改订单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已修改订单 %s: 数量 %v 价格 %v", orderID, params["qty"], params["price"])
+
+	// Bybit改单不改变订单ID，返回修改后的最新状态
+	return t.GetOrder(symbol, orderID)
+}
+
+// CancelOrder 取消单个订单
+func (t *BybitTrader) CancelOrder(symbol string, orderID string) error {
+	params := map[string]interface{}{
+		"category": "linear",
+		"symbol":   symbol,
+		"orderId":  orderID,
+	}
+	if err := t.request(http.MethodPost, "/v5/order/cancel", params, nil); err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已取消 %s 订单 %s", symbol, orderID)
+	return nil
+}
+
+// bybitOrder 订单信息
+type bybitOrder struct {
+	OrderID      string `json:"orderId"`
+	Symbol       string `json:"symbol"`
+	OrderStatus  string `json:"orderStatus"`
+	OrderType    string `json:"orderType"`
+	Side         string `json:"side"`
+	PositionIdx  int    `json:"positionIdx"`
+	TimeInForce  string `json:"timeInForce"`
+	Price        string `json:"price"`
+	TriggerPrice string `json:"triggerPrice"`
+	Qty          string `json:"qty"`
+	CumExecQty   string `json:"cumExecQty"`
+	AvgPrice     string `json:"avgPrice"`
+	CumExecFee   string `json:"cumExecFee"`
+	CreatedTime  string `json:"createdTime"`
+	UpdatedTime  string `json:"updatedTime"`
+
+	// 条件单触发方向：1=价格上涨到触发价时触发，2=价格下跌到触发价时触发
+	TriggerDirection int `json:"triggerDirection"`
+}
+
+// bybitOrderStatus 将Bybit订单状态转换为统一格式
+// IOC/FOK订单未能立即成交而被撤销时视为过期，与币安的EXPIRED一致
+func bybitOrderStatus(status, timeInForce string) string {
+	switch status {
+	case "New", "Untriggered", "Triggered", "Created", "Active":
+		return OrderStatusNew
+	case "PartiallyFilled":
+		return OrderStatusPartiallyFilled
+	case "Filled":
+		return OrderStatusFilled
+	case "Rejected":
+		return OrderStatusRejected
+	case "Cancelled", "PartiallyFilledCanceled":
+		if timeInForce == "IOC" || timeInForce == "FOK" {
+			return OrderStatusExpired
+		}
+		return OrderStatusCanceled
+	default:
+		// Deactivated等
+		return OrderStatusCanceled
+	}
+}
+
+// toOrderResult 将Bybit订单转换为统一格式
+func (o *bybitOrder) toOrderResult() *OrderResult {
+	positionSide := ""
+	switch o.PositionIdx {
+	case bybitPositionIdxLong:
+		positionSide = "LONG"
+	case bybitPositionIdxShort:
+		positionSide = "SHORT"
+	}
+	createdTime, _ := strconv.ParseInt(o.CreatedTime, 10, 64)
+	updatedTime, _ := strconv.ParseInt(o.UpdatedTime, 10, 64)
+
+	return &OrderResult{
+		OrderID:      o.OrderID,
+		Symbol:       o.Symbol,
+		Status:       bybitOrderStatus(o.OrderStatus, o.TimeInForce),
+		Type:         strings.ToUpper(o.OrderType),
+		Side:         strings.ToUpper(o.Side),
+		PositionSide: positionSide,
+		Price:        parseFloatOrZero(o.Price),
+		StopPrice:    parseFloatOrZero(o.TriggerPrice),
+		Quantity:     parseFloatOrZero(o.Qty),
+		ExecutedQty:  parseFloatOrZero(o.CumExecQty),
+		AvgPrice:     parseFloatOrZero(o.AvgPrice),
+		Fee:          parseFloatOrZero(o.CumExecFee),
+		Time:         createdTime,
+		UpdateTime:   updatedTime,
+	}
+}
+
+// listOrders 查询订单列表（endpoint: /v5/order/realtime 或 /v5/order/history）
+func (t *BybitTrader) listOrders(endpoint string, params map[string]interface{}) ([]bybitOrder, error) {
+	var result struct {
+		List []bybitOrder `json:"list"`
+	}
+	params["category"] = "linear"
+	if err := t.request(http.MethodGet, endpoint, params, &result); err != nil {
+		return nil, err
+	}
+	return result.List, nil
+}
+
+// GetOrder 查询单个订单（先查实时订单，已结束较久的订单再查历史订单）
+func (t *BybitTrader) GetOrder(symbol string, orderID string) (*OrderResult, error) {
+	for _, endpoint := range []string{"/v5/order/realtime", "/v5/order/history"} {
+		orders, err := t.listOrders(endpoint, map[string]interface{}{
+			"symbol":  symbol,
+			"orderId": orderID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("查询订单失败: %w", err)
+		}
+		if len(orders) > 0 {
+			return orders[0].toOrderResult(), nil
+		}
+	}
+	return nil, fmt.Errorf("未找到Bybit订单 %s", orderID)
+}
+
+// GetOpenOrders 获取未成交挂单（symbol为空表示所有USDT合约）
+func (t *BybitTrader) GetOpenOrders(symbol string) ([]OrderResult, error) {
+	params := map[string]interface{}{
+		"openOnly": 0,
+		"limit":    50,
+	}
+	if symbol != "" {
+		params["symbol"] = symbol
+	} else {
+		params["settleCoin"] = "USDT"
+	}
+
+	orders, err := t.listOrders("/v5/order/realtime", params)
+	if err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	result := make([]OrderResult, 0, len(orders))
+	for i := range orders {
+		result = append(result, *orders[i].toOrderResult())
+	}
+	return result, nil
+}
+
+// GetProtectiveOrders 获取止盈止损条件单（由平仓方向和触发方向区分止损和止盈）
+func (t *BybitTrader) GetProtectiveOrders(symbol string) ([]ProtectiveOrder, error) {
+	params := map[string]interface{}{
+		"orderFilter": "StopOrder",
+		"limit":       50,
+	}
+	if symbol != "" {
+		params["symbol"] = symbol
+	} else {
+		params["settleCoin"] = "USDT"
+	}
+
+	orders, err := t.listOrders("/v5/order/realtime", params)
+	if err != nil {
+		return nil, fmt.Errorf("获取条件单失败: %w", err)
+	}
+
+	result := make([]OrderResult, 0, len(orders))
+	directions := make(map[string]int, len(orders))
+	for i := range orders {
+		result = append(result, *orders[i].toOrderResult())
+		directions[orders[i].OrderID] = orders[i].TriggerDirection
+	}
+	return filterProtectiveOrders(result, func(order *OrderResult) string {
+		direction := directions[order.OrderID]
+		if order.StopPrice <= 0 || (direction != 1 && direction != 2) {
+			return ""
+		}
+		// 多仓止损和空仓止盈在价格下跌时触发
+		if (protectiveSide(order.PositionSide, order.Side) == "LONG") == (direction == 2) {
+			return ProtectiveKindStopLoss
+		}
+		return ProtectiveKindTakeProfit
+	}), nil
+}
+
+// CancelProtectiveOrder 取消单个止盈止损条件单
+func (t *BybitTrader) CancelProtectiveOrder(symbol string, orderID string) error {
+	return t.CancelOrder(symbol, orderID)
+}
+
+// bybitExecution 成交记录
+type bybitExecution struct {
+	ExecID      string `json:"execId"`
+	OrderID     string `json:"orderId"`
+	Symbol      string `json:"symbol"`
+	Side        string `json:"side"`
+	ExecPrice   string `json:"execPrice"`
+	ExecQty     string `json:"execQty"`
+	ExecFee     string `json:"execFee"`
+	ExecFeeRate string `json:"execFeeRate"`
+	FeeCurrency string `json:"feeCurrency"`
+	ExecType    string `json:"execType"`
+	IsMaker     bool   `json:"isMaker"`
+	ExecTime    string `json:"execTime"`
+}
+
+// bybitFill 将Bybit成交记录转换为统一格式（execFee为正表示支付的手续费）
+func bybitFill(exec bybitExecution) Fill {
+	role := "taker"
+	if exec.IsMaker {
+		role = "maker"
+	}
+	feeCurrency := exec.FeeCurrency
+	if feeCurrency == "" {
+		feeCurrency = "USDT"
+	}
+	timestamp, _ := strconv.ParseInt(exec.ExecTime, 10, 64)
+
+	return Fill{
+		FillID:      exec.ExecID,
+		OrderID:     exec.OrderID,
+		Symbol:      exec.Symbol,
+		Side:        strings.ToLower(exec.Side),
+		Price:       parseFloatOrZero(exec.ExecPrice),
+		Quantity:    parseFloatOrZero(exec.ExecQty),
+		Fee:         parseFloatOrZero(exec.ExecFee),
+		FeeCurrency: feeCurrency,
+		Role:        role,
+		Timestamp:   timestamp,
+	}
+}
+
+// GetFills 获取自since以来的成交记录（symbol为空表示所有币种，不包含资金费结算记录）
+func (t *BybitTrader) GetFills(symbol string, since time.Time) ([]Fill, error) {
+	params := map[string]interface{}{
+		"category":  "linear",
+		"startTime": since.UnixMilli(),
+		"limit":     100,
+	}
+	if symbol != "" {
+		params["symbol"] = symbol
+	}
+
+	var result struct {
+		List []bybitExecution `json:"list"`
+	}
+	if err := t.request(http.MethodGet, "/v5/execution/list", params, &result); err != nil {
+		return nil, fmt.Errorf("获取成交记录失败: %w", err)
+	}
+
+	fills := make([]Fill, 0, len(result.List))
+	for _, exec := range result.List {
+		if exec.ExecType != "" && exec.ExecType != "Trade" {
+			continue
+		}
+		fills = append(fills, bybitFill(exec))
+	}
+	return fills, nil
+}
+
+// GetFundingPayments 获取自since以来的资金费结算记录（成交记录中execType=Funding的条目，symbol为空表示所有币种）
+func (t *BybitTrader) GetFundingPayments(symbol string, since time.Time) ([]FundingPayment, error) {
+	params := map[string]interface{}{
+		"category":  "linear",
+		"execType":  "Funding",
+		"startTime": since.UnixMilli(),
+		"limit":     100,
+	}
+	if symbol != "" {
+		params["symbol"] = symbol
+	}
+
+	var result struct {
+		List []bybitExecution `json:"list"`
+	}
+	if err := t.request(http.MethodGet, "/v5/execution/list", params, &result); err != nil {
+		return nil, fmt.Errorf("获取资金费记录失败: %w", err)
+	}
+
+	payments := make([]FundingPayment, 0, len(result.List))
+	for _, exec := range result.List {
+		if exec.ExecType != "Funding" {
+			continue
+		}
+		payments = append(payments, bybitFundingPayment(exec))
+	}
+	return payments, nil
+}
+
+// bybitFundingPayment 将资金费结算记录转换为统一格式（execFee为正表示支付，execFeeRate为资金费率）
+func bybitFundingPayment(exec bybitExecution) FundingPayment {
+	timestamp, _ := strconv.ParseInt(exec.ExecTime, 10, 64)
+	return FundingPayment{
+		Symbol:    exec.Symbol,
+		Amount:    -parseFloatOrZero(exec.ExecFee),
+		Rate:      parseFloatOrZero(exec.ExecFeeRate),
+		Timestamp: timestamp,
+	}
+}
@@ -0,0 +1,316 @@
+package trader
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestBybitTrader 创建连接到模拟Bybit服务器的交易器
+func newTestBybitTrader(t *testing.T, ex *fakeExchange) (*BybitTrader, *fakeBybitAPI) {
+	t.Helper()
+	srv, api := newFakeBybitServer(t, ex)
+	tr, err := NewBybitTrader("test-key", fakeBybitSecret, false)
+	if err != nil {
+		t.Fatalf("创建Bybit交易器失败: %v", err)
+	}
+	tr.rest.baseURL = srv.URL
+	return tr, api
+}
+
+func TestNewBybitTrader(t *testing.T) {
+	if _, err := NewBybitTrader("", "secret", false); err == nil {
+		t.Error("API密钥为空时应返回错误")
+	}
+	if _, err := NewBybitTrader("key", "", false); err == nil {
+		t.Error("Secret密钥为空时应返回错误")
+	}
+
+	tr, err := NewBybitTrader("key", "secret", true)
+	if err != nil {
+		t.Fatalf("创建Bybit交易器失败: %v", err)
+	}
+	if tr.rest.baseURL != "https://api-testnet.bybit.com" {
+		t.Errorf("测试网地址错误: %s", tr.rest.baseURL)
+	}
+}
+
+func TestBybitRejectsInvalidSignature(t *testing.T) {
+	srv, _ := newFakeBybitServer(t, newFakeExchange())
+	tr, err := NewBybitTrader("test-key", "wrong-secret", false)
+	if err != nil {
+		t.Fatalf("创建Bybit交易器失败: %v", err)
+	}
+	tr.rest.baseURL = srv.URL
+
+	_, err = tr.GetBalance()
+	if exchangeErrorCode(err) != strconv.Itoa(bybitErrSign) || !IsExchangeErrorKind(err, ExchangeErrAuth) {
+		t.Fatalf("签名错误时应返回retCode=%d的鉴权错误, got: %v", bybitErrSign, err)
+	}
+}
+
+func TestBybitGetBalance(t *testing.T) {
+	ex := newFakeExchange()
+	tr, _ := newTestBybitTrader(t, ex)
+
+	if _, err := tr.OpenLong("BTCUSDT", 0.1, 10); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	ex.mu.Lock()
+	ex.specs["BTCUSDT"] = fakeSymbolSpec{Price: 51000, QtyStep: 0.001, TickSize: 0.1}
+	ex.mu.Unlock()
+
+	balance, err := tr.GetBalance()
+	if err != nil {
+		t.Fatalf("获取余额失败: %v", err)
+	}
+	// 0.1 BTC 从50000涨到51000，未实现盈亏100；10倍杠杆占用保证金500
+	if !almostEqual(balance.WalletBalance, 10000) || !almostEqual(balance.UnrealizedProfit, 100) {
+		t.Errorf("余额转换错误: %+v", balance)
+	}
+	if !almostEqual(balance.AvailableBalance, 9600) || !almostEqual(balance.UsedMargin(), 500) {
+		t.Errorf("可用保证金应为9600、占用500: %+v", balance)
+	}
+}
+
+func TestBybitSwitchesToHedgeMode(t *testing.T) {
+	tr, api := newTestBybitTrader(t, newFakeExchange())
+	if api.isHedgeMode() {
+		t.Fatal("模拟账户初始应为单向持仓模式")
+	}
+
+	if _, err := tr.OpenShort("BTCUSDT", 0.01, 5); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	if !api.isHedgeMode() {
+		t.Error("开仓前应切换到双向持仓模式")
+	}
+
+	// 已是双向持仓时，Bybit返回"未改变"，不能影响后续开仓
+	tr.hedgeModeReady = false
+	if _, err := tr.OpenLong("BTCUSDT", 0.01, 5); err != nil {
+		t.Fatalf("双向持仓下开反向仓失败: %v", err)
+	}
+	requirePosition(t, tr, "BTCUSDT", "long")
+	requirePosition(t, tr, "BTCUSDT", "short")
+}
+
+func TestBybitLimitOrderLifecycle(t *testing.T) {
+	tr, _ := newTestBybitTrader(t, newFakeExchange())
+
+	// 低于市价的买单挂单等待，价格按tickSize取整
+	order, err := tr.PlaceLimitOrder("BTCUSDT", "LONG", 0.01, 49000.04, 5, TimeInForceGTC)
+	if err != nil {
+		t.Fatalf("下限价单失败: %v", err)
+	}
+	if order.OrderID == "" || order.Status != OrderStatusNew || !almostEqual(order.Price, 49000) {
+		t.Errorf("限价单结果错误: %+v", order)
+	}
+
+	open, err := tr.GetOpenOrders("BTCUSDT")
+	if err != nil {
+		t.Fatalf("获取挂单失败: %v", err)
+	}
+	if len(open) != 1 || open[0].OrderID != order.OrderID || open[0].PositionSide != "LONG" {
+		t.Fatalf("挂单列表错误: %+v", open)
+	}
+
+	amended, err := tr.AmendOrder("BTCUSDT", order.OrderID, 0.02, 49500)
+	if err != nil {
+		t.Fatalf("修改订单失败: %v", err)
+	}
+	if amended.OrderID != order.OrderID || !almostEqual(amended.Price, 49500) || !almostEqual(amended.Quantity, 0.02) {
+		t.Errorf("修改后的订单错误: %+v", amended)
+	}
+
+	if err := tr.CancelOrder("BTCUSDT", order.OrderID); err != nil {
+		t.Fatalf("取消订单失败: %v", err)
+	}
+	cancelled, err := tr.GetOrder("BTCUSDT", order.OrderID)
+	if err != nil {
+		t.Fatalf("查询订单失败: %v", err)
+	}
+	if cancelled.Status != OrderStatusCanceled {
+		t.Errorf("取消后订单状态应为 %s, got %s", OrderStatusCanceled, cancelled.Status)
+	}
+	if err := tr.CancelOrder("BTCUSDT", order.OrderID); err == nil || !strings.Contains(err.Error(), bybitErrorMessages[bybitErrOrderNotExists]) {
+		t.Errorf("重复取消应返回交易所原生错误, got: %v", err)
+	}
+
+	// 无法立即成交的IOC单视为过期
+	ioc, err := tr.PlaceLimitOrder("BTCUSDT", "SHORT", 0.01, 51000, 5, TimeInForceIOC)
+	if err != nil {
+		t.Fatalf("下IOC单失败: %v", err)
+	}
+	if got, _ := tr.GetOrder("BTCUSDT", ioc.OrderID); got == nil || got.Status != OrderStatusExpired {
+		t.Errorf("未成交的IOC单状态应为 %s, got %+v", OrderStatusExpired, got)
+	}
+}
+
+func TestBybitOrderAndFillQueries(t *testing.T) {
+	tr, _ := newTestBybitTrader(t, newFakeExchange())
+	since := time.Now().Add(-time.Minute)
+
+	order, err := tr.OpenLong("BTCUSDT", 0.02, 5)
+	if err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+
+	details, err := tr.GetOrder("BTCUSDT", order.OrderID)
+	if err != nil {
+		t.Fatalf("查询订单失败: %v", err)
+	}
+	if details.Status != OrderStatusFilled || !almostEqual(details.AvgPrice, 50000) || !almostEqual(details.ExecutedQty, 0.02) {
+		t.Errorf("订单查询结果错误: %+v", details)
+	}
+	if !almostEqual(details.Fee, 50000*0.02*fakeBybitTakerFee) || details.Side != "BUY" || details.Type != "MARKET" {
+		t.Errorf("订单手续费或方向错误: %+v", details)
+	}
+
+	fills, err := tr.GetFills("BTCUSDT", since)
+	if err != nil {
+		t.Fatalf("获取成交记录失败: %v", err)
+	}
+	if len(fills) != 1 {
+		t.Fatalf("应有1条成交记录, got %d", len(fills))
+	}
+	fill := fills[0]
+	if fill.OrderID != order.OrderID || fill.Side != "buy" || fill.Role != "taker" || fill.FeeCurrency != "USDT" {
+		t.Errorf("成交记录转换错误: %+v", fill)
+	}
+	if !almostEqual(fill.Price, 50000) || !almostEqual(fill.Quantity, 0.02) || !almostEqual(fill.Fee, details.Fee) {
+		t.Errorf("成交价格、数量或手续费错误: %+v", fill)
+	}
+
+	if fills, _ := tr.GetFills("BTCUSDT", time.Now().Add(time.Minute)); len(fills) != 0 {
+		t.Errorf("since之后没有成交, got %d", len(fills))
+	}
+}
+
+func TestBybitCancelAllOrders(t *testing.T) {
+	ex := newFakeExchange()
+	tr, _ := newTestBybitTrader(t, ex)
+
+	if _, err := tr.OpenLong("BTCUSDT", 0.01, 5); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	if err := tr.SetStopLoss("BTCUSDT", "LONG", 0.01, 48000); err != nil {
+		t.Fatalf("设置止损失败: %v", err)
+	}
+	if _, err := tr.PlaceLimitOrder("BTCUSDT", "LONG", 0.01, 49000, 5, TimeInForceGTC); err != nil {
+		t.Fatalf("下限价单失败: %v", err)
+	}
+
+	// 部分平仓保留止损单
+	if _, err := tr.CloseLong("BTCUSDT", 0.005); err != nil {
+		t.Fatalf("部分平仓失败: %v", err)
+	}
+	if _, ok := ex.trigger("BTCUSDT", "long", "sl"); !ok {
+		t.Error("部分平仓后止损单不应被取消")
+	}
+
+	if err := tr.CancelAllOrders("BTCUSDT"); err != nil {
+		t.Fatalf("取消所有挂单失败: %v", err)
+	}
+	if _, ok := ex.trigger("BTCUSDT", "long", "sl"); ok {
+		t.Error("止损单应被取消")
+	}
+	open, err := tr.GetOpenOrders("")
+	if err != nil {
+		t.Fatalf("获取挂单失败: %v", err)
+	}
+	if len(open) != 0 {
+		t.Errorf("取消后不应有挂单: %+v", open)
+	}
+}
+
+func TestBybitPartialTrailingStopIsEmulated(t *testing.T) {
+	ex := newFakeExchange()
+	tr, _ := newTestBybitTrader(t, ex)
+	tr.trailing.interval = time.Hour
+
+	if _, err := tr.OpenLong("BTCUSDT", 0.02, 5); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	// 原生跟踪止损作用于整个仓位，只保护一半仓位时改为本地模拟
+	if err := tr.SetTrailingStop("BTCUSDT", "LONG", 0.01, 0.01, 0); err != nil {
+		t.Fatalf("设置跟踪止损失败: %v", err)
+	}
+	if _, ok := ex.trigger("BTCUSDT", "long", "trailing"); ok {
+		t.Error("部分仓位不应使用原生跟踪止损")
+	}
+	if _, ok := tr.trailing.Get("BTCUSDT", "LONG"); !ok {
+		t.Fatal("部分仓位应使用本地跟踪止损")
+	}
+
+	if err := tr.CancelAllOrders("BTCUSDT"); err != nil {
+		t.Fatalf("取消所有挂单失败: %v", err)
+	}
+	if _, ok := tr.trailing.Get("BTCUSDT", "LONG"); ok {
+		t.Error("取消所有挂单后本地跟踪止损应被移除")
+	}
+}
+
+func TestBybitPosition(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		pos  bybitPositionInfo
+		want Position
+		ok   bool
+	}{
+		{
+			name: "hedge_short_isolated",
+			pos: bybitPositionInfo{PositionIdx: 2, Symbol: "ETHUSDT", Side: "Sell", Size: "1.5", AvgPrice: "3000",
+				MarkPrice: "2900", UnrealisedPnl: "150", Leverage: "10", LiqPrice: "3250.5", TradeMode: 1},
+			want: Position{Symbol: "ETHUSDT", Side: "short", Quantity: 1.5, EntryPrice: 3000, MarkPrice: 2900,
+				UnrealizedProfit: 150, Leverage: 10, LiquidationPrice: 3250.5, MarginMode: "isolated"},
+			ok: true,
+		},
+		{
+			name: "one_way_sell",
+			pos:  bybitPositionInfo{PositionIdx: 0, Symbol: "BTCUSDT", Side: "Sell", Size: "0.01", Leverage: "5"},
+			want: Position{Symbol: "BTCUSDT", Side: "short", Quantity: 0.01, Leverage: 5, MarginMode: "cross"},
+			ok:   true,
+		},
+		{
+			name: "empty",
+			pos:  bybitPositionInfo{PositionIdx: 1, Symbol: "BTCUSDT", Size: "0", Leverage: "5"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := bybitPosition(tc.pos)
+			if ok != tc.ok || got != tc.want {
+				t.Errorf("bybitPosition() = %+v, %v; want %+v, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+func TestBybitOrderStatus(t *testing.T) {
+	for _, tc := range []struct {
+		status, timeInForce, want string
+	}{
+		{"New", "GTC", OrderStatusNew},
+		{"Untriggered", "IOC", OrderStatusNew},
+		{"PartiallyFilled", "GTC", OrderStatusPartiallyFilled},
+		{"Filled", "IOC", OrderStatusFilled},
+		{"Cancelled", "GTC", OrderStatusCanceled},
+		{"Cancelled", "IOC", OrderStatusExpired},
+		{"PartiallyFilledCanceled", "FOK", OrderStatusExpired},
+		{"Deactivated", "GTC", OrderStatusCanceled},
+		{"Rejected", "PostOnly", OrderStatusRejected},
+	} {
+		if got := bybitOrderStatus(tc.status, tc.timeInForce); got != tc.want {
+			t.Errorf("bybitOrderStatus(%s, %s) = %s, want %s", tc.status, tc.timeInForce, got, tc.want)
+		}
+	}
+}
+
+func TestBybitFundingPayment(t *testing.T) {
+	// execFee为正表示支付资金费
+	payment := bybitFundingPayment(bybitExecution{Symbol: "BTCUSDT", ExecType: "Funding", ExecFee: "0.25", ExecFeeRate: "0.0001", ExecTime: "1700006400000"})
+	if payment.Symbol != "BTCUSDT" || !almostEqual(payment.Amount, -0.25) || !almostEqual(payment.Rate, 0.0001) || payment.Timestamp != 1700006400000 {
+		t.Errorf("资金费转换错误: %+v", payment)
+	}
+}
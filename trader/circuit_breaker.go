@@ -0,0 +1,327 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"nofx/logger"
+	"sync"
+	"time"
+)
+
+// defaultCircuitBreakerHalt 未配置暂停时长时熔断后的默认暂停时间
+const defaultCircuitBreakerHalt = time.Hour
+
+// CircuitBreakerStore 熔断状态持久化接口（由config.Database实现），重启后恢复暂停状态
+type CircuitBreakerStore interface {
+	GetCircuitBreakerState(traderID string) (string, error)
+	SaveCircuitBreakerState(traderID string, state string) error
+}
+
+// transferHistory 可查询账户划转记录的交易所
+// 未实现时熔断按净值直接计算，充值或提现后需通过ResetCircuitBreaker人工调整基准
+type transferHistory interface {
+	GetTransfers(since time.Time) ([]Transfer, error)
+}
+
+// CircuitBreakerState 账户级熔断状态
+// 日盈亏 = 当前净值 - 当日首次检查时的净值（包含已实现和未实现盈亏）；回撤 = 净值相对历史峰值的跌幅
+// 充值和提现不计入盈亏：划转时当日起点和峰值同步调整
+type CircuitBreakerState struct {
+	DayStart       time.Time `json:"day_start"`        // 当日开始时间（UTC零点）
+	DayStartEquity float64   `json:"day_start_equity"` // 当日起始净值
+	PeakEquity     float64   `json:"peak_equity"`      // 净值峰值
+	Equity         float64   `json:"equity"`           // 最近一次检查时的净值
+	DailyPnL       float64   `json:"daily_pnl"`        // 当日盈亏（USDT）
+	DailyPnLPct    float64   `json:"daily_pnl_pct"`    // 当日盈亏百分比
+	DrawdownPct    float64   `json:"drawdown_pct"`     // 当前回撤百分比
+	HaltedUntil    time.Time `json:"halted_until"`     // 暂停交易截止时间（零值表示未熔断）
+	TrippedAt      time.Time `json:"tripped_at"`       // 最近一次熔断时间
+	Reason         string    `json:"reason"`           // 最近一次熔断原因
+	NetTransfers   float64   `json:"net_transfers"`    // 开始统计以来的净划转金额（转入为正）
+	TransfersSince time.Time `json:"transfers_since"`  // 划转记录已统计到的时间（零值表示尚未开始统计）
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// circuitBreaker 账户级熔断：日亏损或回撤超过阈值时暂停交易
+// 暂停结束后以当时的净值重新作为当日起点（同一笔日亏损不再重复触发）；峰值始终为历史最高净值，
+// 回撤超过阈值时暂停结束后仍会再次熔断，直到净值回到阈值内或人工重置
+type circuitBreaker struct {
+	traderID        string
+	maxDailyLossPct float64 // 最大日亏损百分比（0表示不检查）
+	maxDrawdownPct  float64 // 最大回撤百分比（0表示不检查）
+	haltDuration    time.Duration
+	flatten         bool // 熔断时是否平掉所有持仓
+	store           CircuitBreakerStore
+
+	mu    sync.Mutex
+	state CircuitBreakerState
+}
+
+// newCircuitBreaker 根据配置创建熔断器并恢复持久化的状态，两项阈值都未设置时返回nil
+func newCircuitBreaker(config AutoTraderConfig, store CircuitBreakerStore) *circuitBreaker {
+	if config.MaxDailyLoss <= 0 && config.MaxDrawdown <= 0 {
+		return nil
+	}
+	haltDuration := config.StopTradingTime
+	if haltDuration <= 0 {
+		haltDuration = defaultCircuitBreakerHalt
+	}
+	cb := &circuitBreaker{
+		traderID:        config.ID,
+		maxDailyLossPct: config.MaxDailyLoss,
+		maxDrawdownPct:  config.MaxDrawdown,
+		haltDuration:    haltDuration,
+		flatten:         config.FlattenOnCircuitBreak,
+		store:           store,
+	}
+	if store == nil {
+		return cb
+	}
+	data, err := store.GetCircuitBreakerState(config.ID)
+	if err != nil {
+		log.Printf("⚠️ [%s] 读取熔断状态失败: %v", config.Name, err)
+		return cb
+	}
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &cb.state); err != nil {
+			log.Printf("⚠️ [%s] 解析熔断状态失败，重新开始统计: %v", config.Name, err)
+			cb.state = CircuitBreakerState{}
+		}
+	}
+	return cb
+}
+
+// State 返回熔断状态副本
+func (cb *circuitBreaker) State() CircuitBreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+// update 用当前净值更新日盈亏和回撤，超过阈值时熔断；返回本次是否新触发熔断
+func (cb *circuitBreaker) update(equity float64, now time.Time) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	defer cb.save()
+
+	s := &cb.state
+	day := now.UTC().Truncate(24 * time.Hour)
+	if s.DayStart.IsZero() || day.After(s.DayStart) {
+		s.DayStart = day
+		s.DayStartEquity = equity
+	}
+	// 暂停结束：以当前净值重新计算日盈亏（峰值保持不变，回撤按历史峰值计算）
+	if !s.HaltedUntil.IsZero() && !now.Before(s.HaltedUntil) {
+		log.Printf("▶️ 熔断暂停结束，以当前净值 %.2f 重新计算日盈亏", equity)
+		s.HaltedUntil = time.Time{}
+		s.DayStartEquity = equity
+	}
+	if equity > s.PeakEquity {
+		s.PeakEquity = equity
+	}
+
+	s.Equity = equity
+	s.UpdatedAt = now
+	s.DailyPnL = equity - s.DayStartEquity
+	s.DailyPnLPct = 0
+	if s.DayStartEquity > 0 {
+		s.DailyPnLPct = s.DailyPnL / s.DayStartEquity * 100
+	}
+	s.DrawdownPct = 0
+	if s.PeakEquity > 0 {
+		s.DrawdownPct = (s.PeakEquity - equity) / s.PeakEquity * 100
+	}
+
+	if now.Before(s.HaltedUntil) {
+		return false
+	}
+	reason := ""
+	switch {
+	case cb.maxDailyLossPct > 0 && -s.DailyPnLPct >= cb.maxDailyLossPct:
+		reason = fmt.Sprintf("日亏损 %.2f%% 达到上限 %.2f%%", -s.DailyPnLPct, cb.maxDailyLossPct)
+	case cb.maxDrawdownPct > 0 && s.DrawdownPct >= cb.maxDrawdownPct:
+		reason = fmt.Sprintf("回撤 %.2f%% 达到上限 %.2f%% (峰值 %.2f)", s.DrawdownPct, cb.maxDrawdownPct, s.PeakEquity)
+	default:
+		return false
+	}
+
+	s.HaltedUntil = now.Add(cb.haltDuration)
+	s.TrippedAt = now
+	s.Reason = reason
+	return true
+}
+
+// applyTransfers 记录上次统计以来的净划转金额：当日起点和峰值同步调整，充值提现不计为盈亏或回撤
+func (cb *circuitBreaker) applyTransfers(amount float64, syncedAt time.Time) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	defer cb.save()
+
+	s := &cb.state
+	s.TransfersSince = syncedAt
+	if amount == 0 || s.DayStart.IsZero() {
+		return
+	}
+	s.DayStartEquity += amount
+	s.PeakEquity = math.Max(0, s.PeakEquity+amount)
+	s.NetTransfers += amount
+	log.Printf("💸 检测到账户划转 %+.2f，当日起点调整为 %.2f，峰值调整为 %.2f", amount, s.DayStartEquity, s.PeakEquity)
+}
+
+// reset 人工重置：以当前净值作为新的峰值和当日起点，并解除暂停
+func (cb *circuitBreaker) reset(equity float64, now time.Time) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	defer cb.save()
+
+	s := &cb.state
+	s.DayStart = now.UTC().Truncate(24 * time.Hour)
+	s.DayStartEquity = equity
+	s.PeakEquity = equity
+	s.Equity = equity
+	s.DailyPnL = 0
+	s.DailyPnLPct = 0
+	s.DrawdownPct = 0
+	s.HaltedUntil = time.Time{}
+	s.UpdatedAt = now
+}
+
+// save 持久化当前状态（调用方需持有锁）
+func (cb *circuitBreaker) save() {
+	if cb.store == nil {
+		return
+	}
+	data, err := json.Marshal(cb.state)
+	if err != nil {
+		log.Printf("⚠️ 序列化熔断状态失败: %v", err)
+		return
+	}
+	if err := cb.store.SaveCircuitBreakerState(cb.traderID, string(data)); err != nil {
+		log.Printf("⚠️ 保存熔断状态失败: %v", err)
+	}
+}
+
+// checkCircuitBreaker 每个周期按账户净值检查熔断，触发时设置暂停时间并按配置平掉所有持仓
+// 执行日志和平仓记录写入record
+func (at *AutoTrader) checkCircuitBreaker(record *logger.DecisionRecord) {
+	cb := at.breaker
+	if cb == nil {
+		return
+	}
+	at.syncCircuitBreakerTransfers(cb)
+	balance, err := at.trader.GetBalance()
+	if err != nil {
+		log.Printf("⚠️ 熔断检查获取账户余额失败: %v", err)
+		return
+	}
+	equity := balance.TotalEquity()
+	tripped := cb.update(equity, at.now())
+	state := cb.State()
+	at.dailyPnL = state.DailyPnL
+	at.lastResetTime = state.DayStart
+	// 暂停时间只由熔断设置，人工重置后下个周期即恢复交易
+	at.stopUntil = state.HaltedUntil
+	if !tripped {
+		return
+	}
+
+	line := fmt.Sprintf("🚨 账户熔断: %s，暂停交易至 %s", state.Reason, state.HaltedUntil.Format("2006-01-02 15:04:05"))
+	log.Println(line)
+	record.ExecutionLog = append(record.ExecutionLog, line)
+	if !cb.flatten {
+		return
+	}
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ 熔断平仓获取持仓失败: %v", err))
+		return
+	}
+	at.execMu.Lock()
+	defer at.execMu.Unlock()
+	for _, pos := range positions {
+		if pos.Quantity <= 0 {
+			continue
+		}
+		actionRecord := at.guardianClose(guardianBreach{Position: pos, Price: pos.MarkPrice, Reason: state.Reason})
+		if actionRecord.Success {
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🛡 %s %s 熔断平仓 (%s)", pos.Symbol, pos.Side, guardianTradeType))
+		} else {
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 熔断平仓失败: %s", pos.Symbol, pos.Side, actionRecord.Error))
+		}
+		record.Decisions = append(record.Decisions, actionRecord)
+	}
+	if at.guardian != nil {
+		at.guardian.invalidate()
+	}
+}
+
+// syncCircuitBreakerTransfers 查询上次统计以来的划转记录并调整熔断基准（交易所不支持时跳过）
+func (at *AutoTrader) syncCircuitBreakerTransfers(cb *circuitBreaker) {
+	source, ok := at.trader.(transferHistory)
+	if !ok {
+		return
+	}
+	now := at.now()
+	since := cb.State().TransfersSince
+	if since.IsZero() {
+		cb.applyTransfers(0, now) // 从现在开始统计，之前的划转已反映在净值中
+		return
+	}
+	transfers, err := source.GetTransfers(since)
+	if err != nil {
+		log.Printf("⚠️ 熔断检查获取划转记录失败: %v", err)
+		return
+	}
+	net := 0.0
+	for _, transfer := range transfers {
+		if transfer.Timestamp >= since.UnixMilli() {
+			net += transfer.Amount
+		}
+	}
+	cb.applyTransfers(net, now)
+}
+
+// ResetCircuitBreaker 人工重置账户熔断：解除暂停，以当前账户净值作为新的峰值和当日起点
+// 用于回撤熔断后确认恢复交易，或在交易所不支持查询划转记录时于充值提现后调整基准
+func (at *AutoTrader) ResetCircuitBreaker() error {
+	if at.breaker == nil {
+		return fmt.Errorf("未启用账户熔断")
+	}
+	balance, err := at.trader.GetBalance()
+	if err != nil {
+		return fmt.Errorf("获取账户余额失败: %w", err)
+	}
+	equity := balance.TotalEquity()
+	at.breaker.reset(equity, at.now())
+	log.Printf("▶️ [%s] 账户熔断已人工重置，峰值和当日起点调整为 %.2f", at.name, equity)
+	return nil
+}
+
+// circuitBreakerStatus 熔断状态（用于API），未启用时enabled为false
+func (at *AutoTrader) circuitBreakerStatus() map[string]interface{} {
+	cb := at.breaker
+	if cb == nil {
+		return map[string]interface{}{"enabled": false}
+	}
+	state := cb.State()
+	return map[string]interface{}{
+		"enabled":          true,
+		"halted":           at.now().Before(state.HaltedUntil),
+		"halted_until":     state.HaltedUntil.Format(time.RFC3339),
+		"reason":           state.Reason,
+		"tripped_at":       state.TrippedAt.Format(time.RFC3339),
+		"max_daily_loss":   cb.maxDailyLossPct,
+		"max_drawdown":     cb.maxDrawdownPct,
+		"flatten":          cb.flatten,
+		"equity":           state.Equity,
+		"day_start_equity": state.DayStartEquity,
+		"peak_equity":      state.PeakEquity,
+		"daily_pnl":        state.DailyPnL,
+		"daily_pnl_pct":    state.DailyPnLPct,
+		"drawdown_pct":     state.DrawdownPct,
+		"net_transfers":    state.NetTransfers,
+	}
+}
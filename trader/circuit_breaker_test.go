@@ -0,0 +1,250 @@
+package trader
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nofx/logger"
+)
+
+// memoryBreakerStore 内存版熔断状态存储
+type memoryBreakerStore struct {
+	mu     sync.Mutex
+	states map[string]string
+}
+
+func (s *memoryBreakerStore) GetCircuitBreakerState(traderID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.states[traderID], nil
+}
+
+func (s *memoryBreakerStore) SaveCircuitBreakerState(traderID string, state string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.states == nil {
+		s.states = make(map[string]string)
+	}
+	s.states[traderID] = state
+	return nil
+}
+
+func TestCircuitBreakerDailyLossAndDrawdown(t *testing.T) {
+	store := &memoryBreakerStore{}
+	config := AutoTraderConfig{ID: "cb", MaxDailyLoss: 5, MaxDrawdown: 10, StopTradingTime: time.Hour}
+	cb := newCircuitBreaker(config, store)
+	day1 := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	if cb.update(1000, day1) || cb.update(1020, day1.Add(time.Hour)) {
+		t.Fatal("盈利时不应熔断")
+	}
+	// 当日起始1000，净值960：日亏损4%，未达到5%
+	if cb.update(960, day1.Add(2*time.Hour)) {
+		t.Fatalf("日亏损未达上限不应熔断: %+v", cb.State())
+	}
+	if !cb.update(949, day1.Add(3*time.Hour)) {
+		t.Fatalf("日亏损5.1%%应熔断: %+v", cb.State())
+	}
+	state := cb.State()
+	if !strings.Contains(state.Reason, "日亏损") || !state.HaltedUntil.Equal(day1.Add(4*time.Hour)) {
+		t.Errorf("熔断状态错误: %+v", state)
+	}
+	if cb.update(900, day1.Add(3*time.Hour+30*time.Minute)) {
+		t.Error("暂停期间不应重复触发")
+	}
+
+	// 重启后恢复暂停状态
+	restored := newCircuitBreaker(config, store)
+	if got := restored.State(); !got.HaltedUntil.Equal(state.HaltedUntil) || got.PeakEquity != 1020 {
+		t.Errorf("应从存储恢复熔断状态, got %+v", got)
+	}
+
+	// 暂停结束后以当前净值为当日新基准（峰值仍为1020，回撤2.9%）
+	if restored.update(990, day1.Add(5*time.Hour)) {
+		t.Fatalf("暂停结束后应重新计算日盈亏: %+v", restored.State())
+	}
+	if got := restored.State(); got.DayStartEquity != 990 || got.PeakEquity != 1020 {
+		t.Errorf("暂停结束后只应重置当日起点: %+v", got)
+	}
+	day2 := day1.Add(24 * time.Hour)
+	if restored.update(1000, day2) {
+		t.Fatal("新的一天盈利不应熔断")
+	}
+	if restored.update(960, day2.Add(time.Hour)) {
+		t.Fatal("日亏损4%不应熔断")
+	}
+	restored.update(1000, day2.Add(2*time.Hour))
+	if !restored.update(899, day2.Add(3*time.Hour)) {
+		t.Fatalf("日亏损和回撤超过上限应熔断: %+v", restored.State())
+	}
+}
+
+func TestAutoTraderCircuitBreakerFlatten(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, paper := newLimitTestAutoTrader(t, feed, &now)
+	store := &memoryBreakerStore{}
+	at.breaker = newCircuitBreaker(AutoTraderConfig{ID: "cb", MaxDailyLoss: 5, FlattenOnCircuitBreak: true}, store)
+	if _, err := paper.OpenLong("BTCUSDT", 5, 5); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+
+	record := &logger.DecisionRecord{}
+	at.checkCircuitBreaker(record)
+	if len(record.ExecutionLog) != 0 || at.now().Before(at.stopUntil) {
+		t.Fatalf("未亏损时不应熔断: %v", record.ExecutionLog)
+	}
+
+	// 5 × 12 = 60 亏损，超过当日起始净值的5%
+	feed.set("BTCUSDT", 88)
+	now = now.Add(3 * time.Minute)
+	record = &logger.DecisionRecord{}
+	at.checkCircuitBreaker(record)
+	if !at.stopUntil.Equal(now.Add(defaultCircuitBreakerHalt)) {
+		t.Errorf("应暂停交易至 %v, got %v", now.Add(defaultCircuitBreakerHalt), at.stopUntil)
+	}
+	if len(record.Decisions) != 1 || record.Decisions[0].Action != "close_long" || !record.Decisions[0].Success {
+		t.Fatalf("熔断时应平掉所有持仓: %+v %v", record.Decisions, record.ExecutionLog)
+	}
+	if pos, ok := findPosition(t, paper, "BTCUSDT", "long"); ok {
+		t.Errorf("熔断平仓后不应有持仓: %+v", pos)
+	}
+
+	status := at.GetStatus()["circuit_breaker"].(map[string]interface{})
+	if status["halted"] != true || !strings.Contains(status["reason"].(string), "日亏损") {
+		t.Errorf("状态接口应返回熔断信息: %+v", status)
+	}
+}
+
+func TestCircuitBreakerDrawdownAcrossHalts(t *testing.T) {
+	config := AutoTraderConfig{ID: "cb", MaxDailyLoss: 5, MaxDrawdown: 8, StopTradingTime: time.Hour}
+	cb := newCircuitBreaker(config, nil)
+	day := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	cb.update(1000, day)
+	// 日亏损6%触发第一次熔断
+	if !cb.update(940, day.Add(time.Hour)) || !strings.Contains(cb.State().Reason, "日亏损") {
+		t.Fatalf("日亏损6%%应熔断: %+v", cb.State())
+	}
+	// 暂停结束后继续下跌：相对新起点940只亏4.3%，但相对峰值1000回撤10%
+	if !cb.update(900, day.Add(2*time.Hour+time.Minute)) {
+		t.Fatalf("回撤应按历史峰值计算: %+v", cb.State())
+	}
+	state := cb.State()
+	if !strings.Contains(state.Reason, "回撤") || state.PeakEquity != 1000 || state.DrawdownPct != 10 {
+		t.Errorf("第二次熔断应为回撤: %+v", state)
+	}
+	// 回撤仍超过上限时，暂停结束后再次熔断
+	if !cb.update(905, day.Add(3*time.Hour+2*time.Minute)) {
+		t.Fatalf("回撤未恢复时应再次熔断: %+v", cb.State())
+	}
+	// 净值回到阈值内后恢复交易
+	if cb.update(930, day.Add(4*time.Hour+3*time.Minute)) {
+		t.Fatalf("回撤回到阈值内不应熔断: %+v", cb.State())
+	}
+}
+
+// transferPaperTrader 可模拟充值提现的模拟盘（划转直接记入钱包余额）
+type transferPaperTrader struct {
+	*PaperTrader
+	transfers []Transfer
+}
+
+func (p *transferPaperTrader) GetTransfers(since time.Time) ([]Transfer, error) {
+	var result []Transfer
+	for _, transfer := range p.transfers {
+		if transfer.Timestamp >= since.UnixMilli() {
+			result = append(result, transfer)
+		}
+	}
+	return result, nil
+}
+
+func (p *transferPaperTrader) transfer(amount float64, at time.Time) {
+	p.mu.Lock()
+	p.state.WalletBalance += amount
+	p.mu.Unlock()
+	p.transfers = append(p.transfers, Transfer{Amount: amount, Timestamp: at.UnixMilli()})
+}
+
+func TestCircuitBreakerIgnoresTransfers(t *testing.T) {
+	now := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, paper := newLimitTestAutoTrader(t, feed, &now)
+	tr := &transferPaperTrader{PaperTrader: paper}
+	at.trader = tr
+	at.breaker = newCircuitBreaker(AutoTraderConfig{ID: "cb", MaxDailyLoss: 5, MaxDrawdown: 10}, nil)
+
+	at.checkCircuitBreaker(&logger.DecisionRecord{})
+
+	// 提现一半：净值从1000降到500，不应计为亏损或回撤
+	now = now.Add(time.Minute)
+	tr.transfer(-500, now)
+	now = now.Add(time.Minute)
+	record := &logger.DecisionRecord{}
+	at.checkCircuitBreaker(record)
+	state := at.breaker.State()
+	if len(record.ExecutionLog) != 0 || at.now().Before(at.stopUntil) {
+		t.Fatalf("提现不应触发熔断: %v %+v", record.ExecutionLog, state)
+	}
+	if !almostEqual(state.DailyPnL, 0) || !almostEqual(state.DrawdownPct, 0) || !almostEqual(state.PeakEquity, 500) || !almostEqual(state.NetTransfers, -500) {
+		t.Errorf("提现后应同步调整当日起点和峰值: %+v", state)
+	}
+
+	// 充值：净值上升不计为盈利
+	now = now.Add(time.Minute)
+	tr.transfer(300, now)
+	now = now.Add(time.Minute)
+	at.checkCircuitBreaker(&logger.DecisionRecord{})
+	if state := at.breaker.State(); !almostEqual(state.DailyPnL, 0) || !almostEqual(state.PeakEquity, 800) {
+		t.Errorf("充值不应计为盈利: %+v", state)
+	}
+}
+
+func TestCircuitBreakerManualReset(t *testing.T) {
+	now := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, paper := newLimitTestAutoTrader(t, feed, &now)
+	store := &memoryBreakerStore{}
+	at.breaker = newCircuitBreaker(AutoTraderConfig{ID: "cb", MaxDrawdown: 5, StopTradingTime: time.Hour}, store)
+	if _, err := paper.OpenLong("BTCUSDT", 10, 5); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	at.checkCircuitBreaker(&logger.DecisionRecord{})
+
+	// 10 × 8 = 80 亏损，回撤约8%：熔断，暂停结束后回撤仍超过上限会再次熔断
+	feed.set("BTCUSDT", 92)
+	now = now.Add(time.Minute)
+	at.checkCircuitBreaker(&logger.DecisionRecord{})
+	now = now.Add(time.Hour + time.Minute)
+	at.checkCircuitBreaker(&logger.DecisionRecord{})
+	if !at.now().Before(at.stopUntil) {
+		t.Fatalf("回撤未恢复时应再次熔断: %+v", at.breaker.State())
+	}
+
+	if err := at.ResetCircuitBreaker(); err != nil {
+		t.Fatalf("重置失败: %v", err)
+	}
+	balance, _ := paper.GetBalance()
+	state := at.breaker.State()
+	if !state.HaltedUntil.IsZero() || !almostEqual(state.PeakEquity, balance.TotalEquity()) || !almostEqual(state.DayStartEquity, balance.TotalEquity()) {
+		t.Errorf("重置后应解除暂停并以当前净值为基准: %+v", state)
+	}
+	if restored := newCircuitBreaker(AutoTraderConfig{ID: "cb", MaxDrawdown: 5}, store).State(); !restored.HaltedUntil.IsZero() || restored.PeakEquity != state.PeakEquity {
+		t.Errorf("重置应持久化: %+v", restored)
+	}
+
+	now = now.Add(time.Minute)
+	record := &logger.DecisionRecord{}
+	at.checkCircuitBreaker(record)
+	if len(record.ExecutionLog) != 0 || at.now().Before(at.stopUntil) {
+		t.Errorf("重置后应恢复交易: %v %v", record.ExecutionLog, at.stopUntil)
+	}
+
+	at.breaker = nil
+	if err := at.ResetCircuitBreaker(); err == nil {
+		t.Error("未启用熔断时重置应返回错误")
+	}
+}
@@ -3,6 +3,7 @@ package trader
 import (
 	"fmt"
 	"log"
+	"math"
 	"sync"
 	"time"
 )
@@ -39,6 +40,19 @@ type ConstraintsManager struct {
 	currentPositions     int
 	decisionRejections   int
 	lastDecisionTime     time.Time
+	clock                func() time.Time // 时钟（回测时注入模拟时间）
+}
+
+// ConstraintsState 约束管理器状态快照（按交易员持久化，重启后恢复学习阶段）
+type ConstraintsState struct {
+	Stage              LearningStage `json:"stage"`
+	TotalTrades        int           `json:"total_trades"`
+	ConsecutiveLosses  int           `json:"consecutive_losses"`
+	DailyTrades        []TradeResult `json:"daily_trades"`
+	DailyResetTime     time.Time     `json:"daily_reset_time"`
+	DailyLossAmount    float64       `json:"daily_loss_amount"` // 当日累计亏损（占账户百分比）
+	CurrentPositions   int           `json:"current_positions"`
+	DecisionRejections int           `json:"decision_rejections"`
 }
 
 // TradeResult 交易结果记录 (用于统计)
@@ -56,15 +70,70 @@ func NewConstraintsManager() *ConstraintsManager {
 		totalTrades:    0,
 		dailyResetTime: time.Now(),
 		dailyTrades:    make([]TradeResult, 0),
+		clock:          time.Now,
+	}
+}
+
+// SetClock 设置时钟（回测时使用模拟时间）
+func (cm *ConstraintsManager) SetClock(clock func() time.Time) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.clock = clock
+	cm.dailyResetTime = clock()
+}
+
+// State 获取状态快照
+func (cm *ConstraintsManager) State() ConstraintsState {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	return ConstraintsState{
+		Stage:              cm.currentStage,
+		TotalTrades:        cm.totalTrades,
+		ConsecutiveLosses:  cm.consecutiveLosses,
+		DailyTrades:        append([]TradeResult(nil), cm.dailyTrades...),
+		DailyResetTime:     cm.dailyResetTime,
+		DailyLossAmount:    cm.dailyLossAmount,
+		CurrentPositions:   cm.currentPositions,
+		DecisionRejections: cm.decisionRejections,
 	}
 }
 
+// RestoreState 从快照恢复状态（阶段按交易数重新计算）
+func (cm *ConstraintsManager) RestoreState(state ConstraintsState) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.totalTrades = state.TotalTrades
+	cm.consecutiveLosses = state.ConsecutiveLosses
+	cm.dailyTrades = append(make([]TradeResult, 0, len(state.DailyTrades)), state.DailyTrades...)
+	if !state.DailyResetTime.IsZero() {
+		cm.dailyResetTime = state.DailyResetTime
+	}
+	cm.dailyLossAmount = state.DailyLossAmount
+	cm.currentPositions = state.CurrentPositions
+	cm.decisionRejections = state.DecisionRejections
+	cm.updateStage()
+}
+
+// SetPositionCount 设置当前仓位数（用于并发仓位限制）
+func (cm *ConstraintsManager) SetPositionCount(count int) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.currentPositions = count
+}
+
 // GetCurrentConstraints 获取当前阶段的约束条件
 func (cm *ConstraintsManager) GetCurrentConstraints() Constraints {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
 
-	switch cm.currentStage {
+	return constraintsForStage(cm.currentStage)
+}
+
+// constraintsForStage 指定阶段的约束条件（未知阶段按婴儿期处理）
+func constraintsForStage(stage LearningStage) Constraints {
+	switch stage {
 	case StageInfant:
 		return Constraints{
 			Stage:             StageInfant,
@@ -96,21 +165,23 @@ func (cm *ConstraintsManager) GetCurrentConstraints() Constraints {
 			AllowExceptionForAI: true,   // 允许AI例外放权
 		}
 	default:
-		return cm.GetCurrentConstraints()
+		return constraintsForStage(StageInfant)
 	}
 }
 
 // ValidateDecision 验证AI决策是否符合约束
+// estimatedLoss: 止损触发时的预估亏损（占账户百分比，3.0表示3%）
+// position: 加仓时为已有持仓（不占用新的并发仓位），开新仓时为nil
 // 返回 (是否通过, 拒绝原因)
 func (cm *ConstraintsManager) ValidateDecision(
 	leverage int,
 	estimatedLoss float64,
-	position *Position,
+	position *TrackedPosition,
 ) (bool, string) {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
 
-	constraints := cm.GetCurrentConstraints()
+	constraints := constraintsForStage(cm.currentStage)
 
 	// 约束1: 杠杆上限
 	if leverage > constraints.MaxLeverage {
@@ -139,7 +210,7 @@ func (cm *ConstraintsManager) ValidateDecision(
 	}
 
 	// 约束4: 并发仓位限制
-	if cm.currentPositions >= constraints.MaxConcurrentPos {
+	if position == nil && cm.currentPositions >= constraints.MaxConcurrentPos {
 		return false, fmt.Sprintf(
 			"❌ 约束拦截: 当前仓位%d已达上限%d",
 			cm.currentPositions, constraints.MaxConcurrentPos,
@@ -152,14 +223,35 @@ func (cm *ConstraintsManager) ValidateDecision(
 	return true, ""
 }
 
+// ValidateClose 验证平仓/减仓是否满足最小持仓时间
+// position为nil（未跟踪到开仓时间）时不限制
+func (cm *ConstraintsManager) ValidateClose(position *TrackedPosition) (bool, string) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	constraints := constraintsForStage(cm.currentStage)
+	if position == nil || constraints.MinHoldingMinutes <= 0 || position.OpenTime.IsZero() {
+		return true, ""
+	}
+	held := cm.clock().Sub(position.OpenTime)
+	if held < time.Duration(constraints.MinHoldingMinutes)*time.Minute {
+		return false, fmt.Sprintf(
+			"❌ 约束拦截: %s 持仓%.0f分钟，未达到阶段%d最小持仓时间%d分钟",
+			position.Symbol, held.Minutes(), constraints.Stage, constraints.MinHoldingMinutes,
+		)
+	}
+	return true, ""
+}
+
 // RecordTradeResult 记录交易结果并更新阶段
+// pnl: 盈亏金额，pnlPct: 盈亏占账户比例（小数，-0.02表示亏损2%）
 func (cm *ConstraintsManager) RecordTradeResult(isWin bool, pnl, pnlPct float64) {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
 	// 更新日结果
 	cm.dailyTrades = append(cm.dailyTrades, TradeResult{
-		Timestamp: time.Now(),
+		Timestamp: cm.clock(),
 		IsWin:     isWin,
 		PnL:       pnl,
 		PnLPct:    pnlPct,
@@ -169,7 +261,7 @@ func (cm *ConstraintsManager) RecordTradeResult(isWin bool, pnl, pnlPct float64)
 	cm.totalTrades++
 	if !isWin {
 		cm.consecutiveLosses++
-		cm.dailyLossAmount += pnl
+		cm.dailyLossAmount += math.Abs(pnlPct) * 100
 		// 触发警告: 连续5笔亏损
 		if cm.consecutiveLosses >= 5 {
 			log.Printf("🚨 连续%d笔亏损,建议暂停交易检查策略", cm.consecutiveLosses)
@@ -211,7 +303,7 @@ func (cm *ConstraintsManager) CheckDailyReset() {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	now := time.Now()
+	now := cm.clock()
 	if now.Sub(cm.dailyResetTime) >= 24*time.Hour {
 		log.Printf("📅 日度重置: 清除日交易数据, 日亏损=%.2f%%", cm.dailyLossAmount/100.0*100)
 		cm.dailyTrades = make([]TradeResult, 0)
@@ -263,15 +355,16 @@ func (cm *ConstraintsManager) RejectDecision(reason string) {
 	}
 }
 
-// PositionTracker 持仓跟踪
+// PositionTracker 持仓跟踪（按symbol_side跟踪，双向持仓时同币种的多空仓位互不覆盖）
 type PositionTracker struct {
 	mu        sync.RWMutex
-	positions map[string]*Position
+	positions map[string]*TrackedPosition
 }
 
-// Position 持仓信息
-type Position struct {
+// TrackedPosition 跟踪中的持仓信息
+type TrackedPosition struct {
 	Symbol          string
+	Side            string // long/short
 	OpenPrice       float64
 	OpenTime        time.Time
 	Leverage        int
@@ -279,31 +372,59 @@ type Position struct {
 	UnrealizedPnL   float64
 }
 
+// Key 跟踪键：symbol_side（与Position.Key一致），未指定方向时为币种
+func (p *TrackedPosition) Key() string {
+	return trackedPositionKey(p.Symbol, p.Side)
+}
+
+func trackedPositionKey(symbol, side string) string {
+	if side == "" {
+		return symbol
+	}
+	return symbol + "_" + side
+}
+
 // NewPositionTracker 创建持仓跟踪器
 func NewPositionTracker() *PositionTracker {
 	return &PositionTracker{
-		positions: make(map[string]*Position),
+		positions: make(map[string]*TrackedPosition),
 	}
 }
 
 // OpenPosition 打开持仓
-func (pt *PositionTracker) OpenPosition(pos *Position) {
+func (pt *PositionTracker) OpenPosition(pos *TrackedPosition) {
 	pt.mu.Lock()
 	defer pt.mu.Unlock()
 
-	pt.positions[pos.Symbol] = pos
-	log.Printf("📈 开仓: %s @ %.6f, 杠杆=%dx, 仓位=%.2f", pos.Symbol, pos.OpenPrice, pos.Leverage, pos.PositionSize)
+	pt.positions[pos.Key()] = pos
+	log.Printf("📈 开仓: %s %s @ %.6f, 杠杆=%dx, 仓位=%.2f", pos.Symbol, pos.Side, pos.OpenPrice, pos.Leverage, pos.PositionSize)
 }
 
 // ClosePosition 平仓
-func (pt *PositionTracker) ClosePosition(symbol string) {
+func (pt *PositionTracker) ClosePosition(symbol, side string) {
 	pt.mu.Lock()
 	defer pt.mu.Unlock()
 
-	if pos, ok := pt.positions[symbol]; ok {
-		log.Printf("📉 平仓: %s, 未实现盈亏=%.2f", symbol, pos.UnrealizedPnL)
-		delete(pt.positions, symbol)
+	key := trackedPositionKey(symbol, side)
+	if pos, ok := pt.positions[key]; ok {
+		log.Printf("📉 平仓: %s %s, 未实现盈亏=%.2f", symbol, side, pos.UnrealizedPnL)
+		delete(pt.positions, key)
+	}
+}
+
+// Sync 用交易所持仓替换跟踪中的持仓（已跟踪的持仓保留开仓时间）
+func (pt *PositionTracker) Sync(positions []*TrackedPosition) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	synced := make(map[string]*TrackedPosition, len(positions))
+	for _, pos := range positions {
+		if old, ok := pt.positions[pos.Key()]; ok && !old.OpenTime.IsZero() && (pos.OpenTime.IsZero() || old.OpenTime.Before(pos.OpenTime)) {
+			pos.OpenTime = old.OpenTime
+		}
+		synced[pos.Key()] = pos
 	}
+	pt.positions = synced
 }
 
 // GetPositionCount 获取当前仓位数
@@ -314,20 +435,20 @@ func (pt *PositionTracker) GetPositionCount() int {
 	return len(pt.positions)
 }
 
-// GetPosition 获取指定币种的持仓信息
-func (pt *PositionTracker) GetPosition(symbol string) *Position {
+// GetPosition 获取指定币种和方向的持仓信息
+func (pt *PositionTracker) GetPosition(symbol, side string) *TrackedPosition {
 	pt.mu.RLock()
 	defer pt.mu.RUnlock()
 
-	return pt.positions[symbol]
+	return pt.positions[trackedPositionKey(symbol, side)]
 }
 
 // GetAllPositions 获取所有持仓
-func (pt *PositionTracker) GetAllPositions() []*Position {
+func (pt *PositionTracker) GetAllPositions() []*TrackedPosition {
 	pt.mu.RLock()
 	defer pt.mu.RUnlock()
 
-	positions := make([]*Position, 0, len(pt.positions))
+	positions := make([]*TrackedPosition, 0, len(pt.positions))
 	for _, pos := range pt.positions {
 		positions = append(positions, pos)
 	}
@@ -0,0 +1,77 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/mcp"
+)
+
+// newEnsemble 创建集成决策（未配置其他模型时返回nil，使用单模型决策）
+func newEnsemble(primaryName string, primary mcp.AIClient, models []ModelEndpoint, cfg decision.EnsembleConfig) (*decision.Ensemble, error) {
+	if len(models) == 0 {
+		return nil, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	ensemble := &decision.Ensemble{
+		Members: []decision.EnsembleMember{{Name: primaryName, Client: primary}},
+		Config:  cfg,
+	}
+	seen := map[string]bool{primaryName: true}
+	for _, model := range models {
+		if seen[model.Name] {
+			return nil, fmt.Errorf("集成决策模型重复: %s", model.Name)
+		}
+		seen[model.Name] = true
+		client, err := newModelClient(model)
+		if err != nil {
+			return nil, fmt.Errorf("创建集成决策模型 %s 失败: %w", model.Name, err)
+		}
+		// 集成决策成员同样记录提供商健康状态
+		if client, err = newFailoverClient(model.Name, client, nil); err != nil {
+			return nil, err
+		}
+		ensemble.Members = append(ensemble.Members, decision.EnsembleMember{Name: model.Name, Client: client})
+	}
+	return ensemble, nil
+}
+
+// buildEnsembleRecord 转换为决策日志中的集成决策记录
+func buildEnsembleRecord(result *decision.EnsembleResult) *logger.EnsembleRecord {
+	if result == nil {
+		return nil
+	}
+	record := &logger.EnsembleRecord{
+		Members: make([]logger.EnsembleMemberRecord, 0, len(result.Members)),
+		Votes:   make([]logger.EnsembleVoteRecord, 0, len(result.Votes)),
+	}
+	for _, member := range result.Members {
+		memberRecord := logger.EnsembleMemberRecord{
+			Model:        member.Model,
+			RawResponse:  member.RawResponse,
+			ParseOutcome: member.ParseOutcome,
+			Error:        member.Error,
+		}
+		if len(member.Decisions) > 0 {
+			decisionJSON, _ := json.Marshal(member.Decisions)
+			memberRecord.DecisionJSON = string(decisionJSON)
+		}
+		record.Members = append(record.Members, memberRecord)
+	}
+	for _, vote := range result.Votes {
+		record.Votes = append(record.Votes, logger.EnsembleVoteRecord{
+			Symbol:   vote.Symbol,
+			Action:   vote.Action,
+			Voters:   vote.Voters,
+			Required: vote.Required,
+			Accepted: vote.Accepted,
+			Reason:   vote.Reason,
+		})
+	}
+	return record
+}
@@ -0,0 +1,77 @@
+package trader
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"nofx/decision"
+	"nofx/mcp"
+)
+
+// staticAIClient 固定返回同一响应
+type staticAIClient struct {
+	response string
+}
+
+func (c staticAIClient) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	return c.response, nil
+}
+
+func TestNewEnsemble(t *testing.T) {
+	primary := staticAIClient{response: "[]"}
+	if ensemble, err := newEnsemble("deepseek", primary, nil, decision.EnsembleConfig{}); ensemble != nil || err != nil {
+		t.Fatalf("未配置其他模型时应使用单模型: %+v %v", ensemble, err)
+	}
+
+	temperature := 0.0
+	ensemble, err := newEnsemble("deepseek", primary, []ModelEndpoint{
+		{Name: "user_openai", Provider: "openai", APIKey: "key", Temperature: &temperature, MaxTokens: 1000},
+		{Name: "local", Client: staticAIClient{response: "[]"}},
+	}, decision.EnsembleConfig{OpenRule: decision.EnsembleRuleMajority})
+	if err != nil {
+		t.Fatalf("创建集成决策失败: %v", err)
+	}
+	var names []string
+	for _, member := range ensemble.Members {
+		names = append(names, member.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"deepseek", "user_openai", "local"}) || ensemble.Members[0].Client != primary {
+		t.Fatalf("主模型应作为第一个成员: %v", names)
+	}
+	// 集成决策成员放入单成员故障转移链（记录提供商健康状态）
+	chain, ok := ensemble.Members[1].Client.(*mcp.FailoverClient)
+	if !ok || len(chain.Members) != 1 {
+		t.Fatalf("集成决策成员应记录健康状态: %+v", ensemble.Members[1].Client)
+	}
+	client, ok := chain.Members[0].Client.(*mcp.Client)
+	if !ok || client.Provider != mcp.ProviderOpenAI || client.Temperature != 0 || client.MaxTokens != 1000 {
+		t.Errorf("应按模型配置创建客户端: %+v", ensemble.Members[1].Client)
+	}
+
+	if _, err := newEnsemble("deepseek", primary, []ModelEndpoint{{Name: "deepseek", Provider: "deepseek"}}, decision.EnsembleConfig{}); err == nil || !strings.Contains(err.Error(), "重复") {
+		t.Errorf("模型重复时应返回错误: %v", err)
+	}
+	if _, err := newEnsemble("deepseek", primary, []ModelEndpoint{{Name: "x", Provider: "unknown"}}, decision.EnsembleConfig{}); err == nil {
+		t.Error("未知提供商应返回错误")
+	}
+}
+
+func TestBuildEnsembleRecord(t *testing.T) {
+	record := buildEnsembleRecord(&decision.EnsembleResult{
+		Members: []decision.EnsembleMemberResult{
+			{Model: "a", RawResponse: "raw", Decisions: []decision.Decision{{Symbol: "BTCUSDT", Action: "close_long"}}, ParseOutcome: decision.ParseOutcomeOK},
+			{Model: "b", Error: "timeout"},
+		},
+		Votes: []decision.EnsembleVote{{Symbol: "BTCUSDT", Action: "close_long", Voters: []string{"a"}, Required: 1, Accepted: true}},
+	})
+	if len(record.Members) != 2 || !strings.Contains(record.Members[0].DecisionJSON, "close_long") || record.Members[1].Error != "timeout" {
+		t.Errorf("模型输出记录错误: %+v", record.Members)
+	}
+	if len(record.Votes) != 1 || !record.Votes[0].Accepted || record.Votes[0].Voters[0] != "a" {
+		t.Errorf("投票记录错误: %+v", record.Votes)
+	}
+	if buildEnsembleRecord(nil) != nil {
+		t.Error("单模型决策不应记录集成结果")
+	}
+}
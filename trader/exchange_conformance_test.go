@@ -0,0 +1,486 @@
+package trader
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// conformanceTarget 待测的交易所适配器
+type conformanceTarget struct {
+	// newTrader 创建连接到模拟交易所的适配器（每个场景使用全新的模拟交易所）
+	newTrader func(t *testing.T, ex *fakeExchange) Trader
+	// rejectMessage 下单被拒时错误中应保留的交易所原生错误信息
+	rejectMessage string
+}
+
+// runTraderConformance 对适配器运行统一的交易场景，检查交易所侧的状态和适配器返回的统一模型是否一致
+func runTraderConformance(t *testing.T, target conformanceTarget) {
+	const symbol = "BTCUSDT"
+
+	setup := func(t *testing.T) (*fakeExchange, Trader) {
+		ex := newFakeExchange()
+		return ex, target.newTrader(t, ex)
+	}
+
+	for _, side := range []string{"long", "short"} {
+		side := side
+
+		t.Run("open_close_"+side, func(t *testing.T) {
+			ex, tr := setup(t)
+			if _, err := openPosition(tr, side, symbol, 0.01, 5); err != nil {
+				t.Fatalf("开仓失败: %v", err)
+			}
+
+			pos := requirePosition(t, tr, symbol, side)
+			if !almostEqual(pos.Quantity, 0.01) || !almostEqual(pos.EntryPrice, 50000) || pos.Leverage != 5 {
+				t.Errorf("持仓转换错误: %+v", pos)
+			}
+			if got, ok := ex.position(symbol, side); !ok || !almostEqual(got.Quantity, 0.01) || got.Leverage != 5 {
+				t.Errorf("交易所持仓错误: %+v (存在=%v)", got, ok)
+			}
+
+			// 数量为0表示全部平仓
+			if _, err := closePosition(tr, side, symbol, 0); err != nil {
+				t.Fatalf("平仓失败: %v", err)
+			}
+			if _, ok := ex.position(symbol, side); ok {
+				t.Error("平仓后交易所仍有持仓")
+			}
+			if pos, ok := findPosition(t, tr, symbol, side); ok {
+				t.Errorf("平仓后适配器仍返回持仓: %+v", pos)
+			}
+		})
+
+		t.Run("stop_loss_take_profit_"+side, func(t *testing.T) {
+			ex, tr := setup(t)
+			if _, err := openPosition(tr, side, symbol, 0.01, 5); err != nil {
+				t.Fatalf("开仓失败: %v", err)
+			}
+
+			stopPrice, takeProfitPrice := 48000.0, 55000.0
+			if side == "short" {
+				stopPrice, takeProfitPrice = 52000, 45000
+			}
+			positionSide := strings.ToUpper(side)
+			if err := tr.SetStopLoss(symbol, positionSide, 0.01, stopPrice); err != nil {
+				t.Fatalf("设置止损失败: %v", err)
+			}
+			if err := tr.SetTakeProfit(symbol, positionSide, 0.01, takeProfitPrice); err != nil {
+				t.Fatalf("设置止盈失败: %v", err)
+			}
+
+			for kind, price := range map[string]float64{"sl": stopPrice, "tp": takeProfitPrice} {
+				trigger, ok := ex.trigger(symbol, side, kind)
+				if !ok {
+					t.Errorf("交易所没有收到 %s 条件单", kind)
+					continue
+				}
+				if !almostEqual(trigger.TriggerPrice, price) {
+					t.Errorf("%s 触发价应为 %.1f, got %.4f", kind, price, trigger.TriggerPrice)
+				}
+				// 条件单触发后只能平仓，不能反向开仓
+				if !trigger.ReduceOnly && !trigger.ClosePosition {
+					t.Errorf("%s 条件单必须是只减仓或全部平仓: %+v", kind, trigger)
+				}
+				if !trigger.ClosePosition && !almostEqual(trigger.Quantity, 0.01) {
+					t.Errorf("%s 条件单数量应为 0.01, got %.6f", kind, trigger.Quantity)
+				}
+			}
+		})
+
+		t.Run("protective_orders_"+side, func(t *testing.T) {
+			ex, tr := setup(t)
+			if _, err := openPosition(tr, side, symbol, 0.01, 5); err != nil {
+				t.Fatalf("开仓失败: %v", err)
+			}
+
+			stopPrice, takeProfitPrice := 48000.0, 55000.0
+			if side == "short" {
+				stopPrice, takeProfitPrice = 52000, 45000
+			}
+			positionSide := strings.ToUpper(side)
+			if err := tr.SetStopLoss(symbol, positionSide, 0.01, stopPrice); err != nil {
+				t.Fatalf("设置止损失败: %v", err)
+			}
+			if err := tr.SetTakeProfit(symbol, positionSide, 0.01, takeProfitPrice); err != nil {
+				t.Fatalf("设置止盈失败: %v", err)
+			}
+
+			orders, err := tr.GetProtectiveOrders(symbol)
+			if err != nil {
+				t.Fatalf("查询止盈止损单失败: %v", err)
+			}
+			if len(orders) != 2 {
+				t.Fatalf("应有2个止盈止损单, got %+v", orders)
+			}
+			var stopOrder ProtectiveOrder
+			for _, order := range orders {
+				price := takeProfitPrice
+				if order.Kind == ProtectiveKindStopLoss {
+					price, stopOrder = stopPrice, order
+				}
+				if order.Symbol != symbol || order.PositionSide != positionSide || !almostEqual(order.TriggerPrice, price) {
+					t.Errorf("止盈止损单转换错误: %+v", order)
+				}
+				if !order.ClosePosition && !almostEqual(order.Quantity, 0.01) {
+					t.Errorf("止盈止损单数量应为 0.01: %+v", order)
+				}
+			}
+			if stopOrder.OrderID == "" {
+				t.Fatalf("没有识别出止损单: %+v", orders)
+			}
+
+			if err := tr.CancelProtectiveOrder(symbol, stopOrder.OrderID); err != nil {
+				t.Fatalf("取消止损单失败: %v", err)
+			}
+			if _, ok := ex.trigger(symbol, side, "sl"); ok {
+				t.Error("取消后交易所仍有止损单")
+			}
+			if _, ok := ex.trigger(symbol, side, "tp"); !ok {
+				t.Error("取消止损单不应影响止盈单")
+			}
+		})
+
+		t.Run("reconcile_protective_orders_"+side, func(t *testing.T) {
+			ex, tr := setup(t)
+			if _, err := openPosition(tr, side, symbol, 0.01, 5); err != nil {
+				t.Fatalf("开仓失败: %v", err)
+			}
+
+			target := protectiveTarget{Symbol: symbol, PositionSide: strings.ToUpper(side), Quantity: 0.01, StopLoss: 48000, TakeProfit: 55000}
+			if side == "short" {
+				target.StopLoss, target.TakeProfit = 52000, 45000
+			}
+			reconcile := func() {
+				t.Helper()
+				if _, err := reconcileProtectiveOrders(tr, []protectiveTarget{target}); err != nil {
+					t.Fatalf("核对止盈止损单失败: %v", err)
+				}
+			}
+
+			// 价格不变时重复核对不会堆积条件单
+			reconcile()
+			reconcile()
+			if triggers := ex.triggersOf(symbol); len(triggers) != 2 {
+				t.Fatalf("重复核对后应只有2个条件单, got %+v", triggers)
+			}
+
+			// 止损价变化时替换止损单，止盈单保持不变
+			tp, _ := ex.trigger(symbol, side, "tp")
+			target.StopLoss = 49000
+			if side == "short" {
+				target.StopLoss = 51000
+			}
+			reconcile()
+			if triggers := ex.triggersOf(symbol); len(triggers) != 2 {
+				t.Fatalf("更新止损后应只有2个条件单, got %+v", triggers)
+			}
+			if sl, _ := ex.trigger(symbol, side, "sl"); !almostEqual(sl.TriggerPrice, target.StopLoss) {
+				t.Errorf("止损价应更新为 %.0f, got %+v", target.StopLoss, sl)
+			}
+			if got, _ := ex.trigger(symbol, side, "tp"); got.ID != tp.ID {
+				t.Errorf("止盈单不应被替换: %+v -> %+v", tp, got)
+			}
+
+			// 持仓不存在时撤销孤立条件单
+			if _, err := closePosition(tr, side, symbol, 0); err != nil {
+				t.Fatalf("平仓失败: %v", err)
+			}
+			if _, err := reconcileProtectiveOrders(tr, nil); err != nil {
+				t.Fatalf("核对止盈止损单失败: %v", err)
+			}
+			if triggers := ex.triggersOf(symbol); len(triggers) != 0 {
+				t.Errorf("孤立条件单应被撤销, got %+v", triggers)
+			}
+		})
+
+		t.Run("trailing_stop_"+side, func(t *testing.T) {
+			ex, tr := setup(t)
+			// 本地模拟时由测试手动驱动检查，不依赖后台定时器
+			if em := trailingEmulatorOf(tr); em != nil {
+				em.interval = time.Hour
+			}
+			if _, err := openPosition(tr, side, symbol, 0.01, 5); err != nil {
+				t.Fatalf("开仓失败: %v", err)
+			}
+
+			activationPrice := 51000.0
+			prices := []float64{50500, 52000, 51600, 51400} // 未激活 -> 激活并创新高 -> 回撤0.77% -> 回撤1.15%触发
+			if side == "short" {
+				activationPrice = 49000
+				prices = []float64{49500, 48000, 48400, 48600}
+			}
+			positionSide := strings.ToUpper(side)
+			if err := tr.SetTrailingStop(symbol, positionSide, 0.01, 0.01, activationPrice); err != nil {
+				t.Fatalf("设置跟踪止损失败: %v", err)
+			}
+
+			// 原生跟踪委托：检查交易所收到的参数
+			if trigger, ok := ex.trigger(symbol, side, "trailing"); ok {
+				if !almostEqual(trigger.CallbackRate, 0.01) || !almostEqual(trigger.ActivationPrice, activationPrice) {
+					t.Errorf("跟踪止损参数错误: %+v", trigger)
+				}
+				if !trigger.ReduceOnly && !trigger.ClosePosition {
+					t.Errorf("跟踪止损必须是只减仓或全部平仓: %+v", trigger)
+				}
+				if !trigger.ClosePosition && !almostEqual(trigger.Quantity, 0.01) {
+					t.Errorf("跟踪止损数量应为 0.01, got %.6f", trigger.Quantity)
+				}
+				return
+			}
+
+			// 本地模拟：按价格路径驱动，只有回撤超过回调比例时才平仓
+			em := trailingEmulatorOf(tr)
+			if em == nil {
+				t.Fatal("交易所没有收到跟踪止损委托，也没有本地模拟")
+			}
+			for i, price := range prices {
+				ex.setPrice(symbol, price)
+				em.check()
+				_, open := ex.position(symbol, side)
+				if last := i == len(prices)-1; open == last {
+					t.Fatalf("价格 %.0f 时持仓状态错误 (仍持仓=%v)", price, open)
+				}
+			}
+			if _, ok := em.Get(symbol, positionSide); ok {
+				t.Error("触发后本地跟踪止损应被移除")
+			}
+		})
+	}
+
+	t.Run("partial_close", func(t *testing.T) {
+		ex, tr := setup(t)
+		if _, err := tr.OpenLong(symbol, 0.02, 5); err != nil {
+			t.Fatalf("开仓失败: %v", err)
+		}
+		// 开仓后立即查询，不能读到缓存中的旧持仓
+		requirePosition(t, tr, symbol, "long")
+
+		if _, err := tr.CloseLong(symbol, 0.005); err != nil {
+			t.Fatalf("部分平仓失败: %v", err)
+		}
+		pos := requirePosition(t, tr, symbol, "long")
+		if !almostEqual(pos.Quantity, 0.015) {
+			t.Errorf("部分平仓后适配器持仓应为 0.015, got %.6f", pos.Quantity)
+		}
+		if got, _ := ex.position(symbol, "long"); !almostEqual(got.Quantity, 0.015) {
+			t.Errorf("部分平仓后交易所持仓应为 0.015, got %.6f", got.Quantity)
+		}
+	})
+
+	t.Run("precision_rounding", func(t *testing.T) {
+		ex, tr := setup(t)
+		// 数量向下取整到步长，避免超出持仓或可用保证金
+		for _, tc := range []struct {
+			symbol   string
+			quantity float64
+			want     string
+		}{
+			{symbol, 0.0126789, "0.012"},
+			{symbol, 0.0129999, "0.012"},
+			{"ETHUSDT", 1.239, "1.23"},
+		} {
+			got, err := tr.FormatQuantity(tc.symbol, tc.quantity)
+			if err != nil {
+				t.Fatalf("FormatQuantity(%s, %v) 失败: %v", tc.symbol, tc.quantity, err)
+			}
+			if got != tc.want {
+				t.Errorf("FormatQuantity(%s, %v) = %s, want %s", tc.symbol, tc.quantity, got, tc.want)
+			}
+		}
+
+		// 不合步长的数量下单时也必须被取整，否则交易所会拒单
+		if _, err := tr.OpenLong(symbol, 0.0126789, 5); err != nil {
+			t.Fatalf("开仓失败: %v", err)
+		}
+		if got, _ := ex.position(symbol, "long"); !almostEqual(got.Quantity, 0.012) {
+			t.Errorf("交易所持仓应为 0.012, got %.6f", got.Quantity)
+		}
+	})
+
+	t.Run("leverage_margin_mode", func(t *testing.T) {
+		ex, tr := setup(t)
+		if err := tr.SetMarginMode(symbol, false); err != nil {
+			t.Fatalf("设置逐仓失败: %v", err)
+		}
+		if _, err := tr.OpenLong(symbol, 0.01, 7); err != nil {
+			t.Fatalf("开仓失败: %v", err)
+		}
+
+		if ex.currentLeverage(symbol) != 7 {
+			t.Errorf("交易所杠杆应为 7, got %d", ex.currentLeverage(symbol))
+		}
+		if got, _ := ex.position(symbol, "long"); got.MarginMode != "isolated" || got.Leverage != 7 {
+			t.Errorf("交易所持仓应为7倍逐仓: %+v", got)
+		}
+		pos := requirePosition(t, tr, symbol, "long")
+		if pos.Leverage != 7 || pos.MarginMode != "isolated" {
+			t.Errorf("适配器持仓应为7倍逐仓: %+v", pos)
+		}
+	})
+
+	t.Run("error_payload", func(t *testing.T) {
+		ex, tr := setup(t)
+		ex.setRejectOrders(true)
+
+		_, err := tr.OpenLong(symbol, 0.01, 5)
+		if err == nil {
+			t.Fatal("交易所拒单时应返回错误")
+		}
+		if !strings.Contains(err.Error(), target.rejectMessage) {
+			t.Errorf("错误中应包含交易所原生信息 %q, got: %v", target.rejectMessage, err)
+		}
+		if _, ok := ex.position(symbol, "long"); ok {
+			t.Error("拒单后不应有持仓")
+		}
+	})
+}
+
+// trailingEmulatorOf 返回适配器的本地跟踪止损模拟器（使用原生跟踪委托的适配器返回nil）
+func trailingEmulatorOf(tr Trader) *trailingStopEmulator {
+	if emulated, ok := tr.(trailingStopEmulated); ok {
+		return emulated.trailingStops()
+	}
+	return nil
+}
+
+func openPosition(tr Trader, side, symbol string, quantity float64, leverage int) (*OrderResult, error) {
+	if side == "long" {
+		return tr.OpenLong(symbol, quantity, leverage)
+	}
+	return tr.OpenShort(symbol, quantity, leverage)
+}
+
+func closePosition(tr Trader, side, symbol string, quantity float64) (*OrderResult, error) {
+	if side == "long" {
+		return tr.CloseLong(symbol, quantity)
+	}
+	return tr.CloseShort(symbol, quantity)
+}
+
+func findPosition(t *testing.T, tr Trader, symbol, side string) (Position, bool) {
+	t.Helper()
+	positions, err := tr.GetPositions()
+	if err != nil {
+		t.Fatalf("获取持仓失败: %v", err)
+	}
+	for _, pos := range positions {
+		if pos.Symbol == symbol && pos.Side == side {
+			return pos, true
+		}
+	}
+	return Position{}, false
+}
+
+func requirePosition(t *testing.T, tr Trader, symbol, side string) Position {
+	t.Helper()
+	pos, ok := findPosition(t, tr, symbol, side)
+	if !ok {
+		t.Fatalf("没有找到 %s %s 持仓", symbol, side)
+	}
+	return pos
+}
+
+// newTestWalletKey 生成测试用的钱包私钥和地址
+func newTestWalletKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("生成私钥失败: %v", err)
+	}
+	return key, crypto.PubkeyToAddress(key.PublicKey).Hex()
+}
+
+func TestBinanceExchangeConformance(t *testing.T) {
+	runTraderConformance(t, conformanceTarget{
+		newTrader: func(t *testing.T, ex *fakeExchange) Trader {
+			srv := newFakeBinanceServer(t, ex)
+			tr := NewFuturesTrader("test-key", "test-secret")
+			tr.client.BaseURL = srv.URL
+			tr.leverageCooldown = 0
+			return tr
+		},
+		rejectMessage: binanceErrorMessages[binanceErrMarginInsufficient],
+	})
+}
+
+func TestAsterExchangeConformance(t *testing.T) {
+	runTraderConformance(t, conformanceTarget{
+		newTrader: func(t *testing.T, ex *fakeExchange) Trader {
+			srv := newFakeAsterServer(t, ex)
+			key, addr := newTestWalletKey(t)
+			tr, err := NewAsterTrader(addr, addr, hex.EncodeToString(crypto.FromECDSA(key)))
+			if err != nil {
+				t.Fatalf("创建Aster交易器失败: %v", err)
+			}
+			tr.baseURL = srv.URL
+			return tr
+		},
+		rejectMessage: binanceErrorMessages[binanceErrMarginInsufficient],
+	})
+}
+
+func TestOKXExchangeConformance(t *testing.T) {
+	runTraderConformance(t, conformanceTarget{
+		newTrader: func(t *testing.T, ex *fakeExchange) Trader {
+			srv := newFakeOKXServer(t, ex)
+			tr, err := NewOKXTrader("test-key", "test-secret", "test-passphrase", false)
+			if err != nil {
+				t.Fatalf("创建OKX交易器失败: %v", err)
+			}
+			tr.baseURL = srv.URL
+			return tr
+		},
+		rejectMessage: "sCode=51010, sMsg=" + GetErrorMessage("51010"),
+	})
+}
+
+func TestBybitExchangeConformance(t *testing.T) {
+	runTraderConformance(t, conformanceTarget{
+		newTrader: func(t *testing.T, ex *fakeExchange) Trader {
+			tr, _ := newTestBybitTrader(t, ex)
+			return tr
+		},
+		rejectMessage: bybitErrorMessages[bybitErrInsufficientBalance],
+	})
+}
+
+func TestGateExchangeConformance(t *testing.T) {
+	runTraderConformance(t, conformanceTarget{
+		newTrader: func(t *testing.T, ex *fakeExchange) Trader {
+			tr, _ := newTestGateTrader(t, ex)
+			return tr
+		},
+		rejectMessage: gateErrorMessages[gateErrInsufficient],
+	})
+}
+
+func TestBitgetExchangeConformance(t *testing.T) {
+	runTraderConformance(t, conformanceTarget{
+		newTrader: func(t *testing.T, ex *fakeExchange) Trader {
+			tr, _ := newTestBitgetTrader(t, ex)
+			return tr
+		},
+		rejectMessage: bitgetErrorMessages[bitgetErrInsufficient],
+	})
+}
+
+func TestHyperliquidExchangeConformance(t *testing.T) {
+	runTraderConformance(t, conformanceTarget{
+		newTrader: func(t *testing.T, ex *fakeExchange) Trader {
+			srv := newFakeHyperliquidServer(t, ex)
+			key, addr := newTestWalletKey(t)
+			tr, err := newHyperliquidTrader(key, addr, srv.URL)
+			if err != nil {
+				t.Fatalf("创建Hyperliquid交易器失败: %v", err)
+			}
+			return tr
+		},
+		rejectMessage: "Insufficient margin to place order.",
+	})
+}
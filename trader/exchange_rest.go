@@ -0,0 +1,389 @@
+package trader
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 本文件提供REST交易所适配器共用的基础组件：
+//   - HMAC签名工具
+//   - restClient: 请求构造、签名、发送、GET请求重试和业务错误映射
+//   - instrumentCache: 交易对下单规格缓存与数量/价格格式化
+//   - accountCache: 余额和持仓的短期缓存
+// 新接入的交易所只需要提供签名函数、响应解析函数和错误码表（参考 bybit_trader.go、gate_trader.go、bitget_trader.go）
+
+// ExchangeErrorKind 交易所错误的统一分类（各交易所的原生错误码映射到同一类别）
+type ExchangeErrorKind string
+
+const (
+	ExchangeErrUnknown            ExchangeErrorKind = ""
+	ExchangeErrAuth               ExchangeErrorKind = "auth"                // 密钥或签名错误
+	ExchangeErrRateLimit          ExchangeErrorKind = "rate_limit"          // 请求过于频繁（可重试）
+	ExchangeErrInsufficientMargin ExchangeErrorKind = "insufficient_margin" // 保证金不足
+	ExchangeErrInvalidQuantity    ExchangeErrorKind = "invalid_quantity"    // 下单数量不合规
+	ExchangeErrOrderNotFound      ExchangeErrorKind = "order_not_found"     // 订单不存在或已结束
+	ExchangeErrReduceOnly         ExchangeErrorKind = "reduce_only"         // 只减仓订单超出持仓
+	ExchangeErrNotModified        ExchangeErrorKind = "not_modified"        // 杠杆、保证金模式或持仓模式未改变
+)
+
+// ExchangeAPIError 交易所返回的业务错误（保留原生错误码和错误信息）
+type ExchangeAPIError struct {
+	Exchange   string
+	HTTPStatus int
+	Code       string
+	Message    string
+	Kind       ExchangeErrorKind
+}
+
+// Error 实现error接口
+func (e *ExchangeAPIError) Error() string {
+	return fmt.Sprintf("%s API错误 [%s]: %s", e.Exchange, e.Code, e.Message)
+}
+
+// IsExchangeErrorKind 判断错误是否为指定类别的交易所业务错误
+func IsExchangeErrorKind(err error, kind ExchangeErrorKind) bool {
+	var apiErr *ExchangeAPIError
+	return errors.As(err, &apiErr) && apiErr.Kind == kind
+}
+
+// exchangeErrorCode 返回交易所业务错误的原生错误码（不是业务错误时返回空字符串）
+func exchangeErrorCode(err error) string {
+	var apiErr *ExchangeAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
+
+// hmacSHA256Hex HMAC-SHA256签名，十六进制小写（Bybit、Binance系）
+func hmacSHA256Hex(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// hmacSHA256Base64 HMAC-SHA256签名，Base64编码（OKX、Bitget）
+func hmacSHA256Base64(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// hmacSHA512Hex HMAC-SHA512签名，十六进制小写（Gate.io）
+func hmacSHA512Hex(secret, message string) string {
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sha512Hex SHA512摘要，十六进制小写
+func sha512Hex(message string) string {
+	sum := sha512.Sum512([]byte(message))
+	return hex.EncodeToString(sum[:])
+}
+
+// restRequest 待签名的请求，签名和发送使用完全相同的Query和Body字符串
+type restRequest struct {
+	Method string
+	Path   string // 接口路径（不含域名和querystring）
+	Query  string // 已编码的querystring（不含"?"）
+	Body   string // JSON请求体
+	Header http.Header
+}
+
+// restDecoder 解析交易所响应：成功时把数据写入out（out可能为nil）；
+// 业务失败时返回交易所原生错误码和错误信息；响应格式无法识别时返回err
+type restDecoder func(status int, body []byte, out interface{}) (code, message string, err error)
+
+// restClient 交易所REST客户端
+type restClient struct {
+	exchange   string
+	baseURL    string
+	httpClient *http.Client
+
+	// sign 为请求添加鉴权请求头（每次发送前调用，重试时会重新签名）
+	sign func(req *restRequest)
+	// decode 解析响应
+	decode restDecoder
+	// errorKinds 原生错误码到统一分类的映射
+	errorKinds map[string]ExchangeErrorKind
+
+	// GET请求在网络错误或限频时的重试次数和间隔（下单等写请求不重试，避免重复下单）
+	maxRetries int
+	retryDelay time.Duration
+}
+
+// newRESTClient 创建交易所REST客户端
+func newRESTClient(exchange, baseURL string, sign func(req *restRequest), decode restDecoder, errorKinds map[string]ExchangeErrorKind) *restClient {
+	return &restClient{
+		exchange:   exchange,
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		sign:       sign,
+		decode:     decode,
+		errorKinds: errorKinds,
+		maxRetries: 2,
+		retryDelay: 500 * time.Millisecond,
+	}
+}
+
+// apiError 按错误码表创建业务错误，错误码表中没有的按HTTP状态码归类
+func (c *restClient) apiError(status int, code, message string) *ExchangeAPIError {
+	kind, ok := c.errorKinds[code]
+	if !ok {
+		switch status {
+		case http.StatusTooManyRequests:
+			kind = ExchangeErrRateLimit
+		case http.StatusUnauthorized, http.StatusForbidden:
+			kind = ExchangeErrAuth
+		}
+	}
+	return &ExchangeAPIError{
+		Exchange:   c.exchange,
+		HTTPStatus: status,
+		Code:       code,
+		Message:    message,
+		Kind:       kind,
+	}
+}
+
+// get 发送GET请求
+func (c *restClient) get(path string, query url.Values, out interface{}) error {
+	return c.do(http.MethodGet, path, query, nil, out)
+}
+
+// post 发送POST请求，body以JSON编码
+func (c *restClient) post(path string, body interface{}, out interface{}) error {
+	return c.do(http.MethodPost, path, nil, body, out)
+}
+
+// do 发送请求，业务失败时返回*ExchangeAPIError
+// GET请求遇到网络错误或限频时按递增间隔重试
+func (c *restClient) do(method, path string, query url.Values, body interface{}, out interface{}) error {
+	req := restRequest{Method: method, Path: path, Query: query.Encode()}
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("序列化请求参数失败: %w", err)
+		}
+		req.Body = string(jsonBody)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := c.send(req, out)
+		if err == nil || method != http.MethodGet || attempt >= c.maxRetries || !isRetryableRESTError(err) {
+			return err
+		}
+		delay := c.retryDelay * time.Duration(attempt+1)
+		log.Printf("⚠️ %s请求 %s 失败，%v后重试(%d/%d): %v", c.exchange, path, delay, attempt+1, c.maxRetries, err)
+		time.Sleep(delay)
+	}
+}
+
+// send 签名并发送一次请求
+func (c *restClient) send(req restRequest, out interface{}) error {
+	req.Header = http.Header{}
+	req.Header.Set("Content-Type", "application/json")
+	if c.sign != nil {
+		c.sign(&req)
+	}
+
+	fullURL := c.baseURL + req.Path
+	if req.Query != "" {
+		fullURL += "?" + req.Query
+	}
+	var body io.Reader
+	if req.Body != "" {
+		body = strings.NewReader(req.Body)
+	}
+
+	httpReq, err := http.NewRequest(req.Method, fullURL, body)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	httpReq.Header = req.Header
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("HTTP请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	code, message, err := c.decode(resp.StatusCode, respBody, out)
+	if err != nil {
+		return err
+	}
+	if code != "" {
+		return c.apiError(resp.StatusCode, code, message)
+	}
+	return nil
+}
+
+// isRetryableRESTError 网络错误和限频错误可以重试
+func isRetryableRESTError(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) || IsExchangeErrorKind(err, ExchangeErrRateLimit)
+}
+
+// instrumentSpec 交易对下单规格（数量均为币的数量）
+type instrumentSpec struct {
+	QtyStep  float64 // 数量步长
+	MinQty   float64 // 最小下单数量
+	TickSize float64 // 价格步长
+}
+
+// stepPrecision 步长对应的小数位数（如0.001为3）
+func stepPrecision(step float64) int {
+	if step <= 0 {
+		return 0
+	}
+	return calculatePrecision(strconv.FormatFloat(step, 'f', -1, 64))
+}
+
+// FormatQuantity 数量向下取整到步长（向上取整可能超出持仓或可用保证金）
+func (s instrumentSpec) FormatQuantity(quantity float64) string {
+	return strconv.FormatFloat(floorToStep(quantity, s.QtyStep), 'f', stepPrecision(s.QtyStep), 64)
+}
+
+// FormatOrderQuantity 格式化下单数量，取整后不足最小下单量时返回错误
+func (s instrumentSpec) FormatOrderQuantity(symbol string, quantity float64) (string, error) {
+	floored := floorToStep(quantity, s.QtyStep)
+	if floored <= 0 || floored < s.MinQty {
+		return "", fmt.Errorf("下单数量过小: %s 最小下单量为 %v，当前 %v", symbol, s.MinQty, quantity)
+	}
+	return strconv.FormatFloat(floored, 'f', stepPrecision(s.QtyStep), 64), nil
+}
+
+// FormatPrice 价格四舍五入到价格步长的整数倍
+func (s instrumentSpec) FormatPrice(price float64) string {
+	if s.TickSize > 0 {
+		price = math.Round(price/s.TickSize) * s.TickSize
+	}
+	return strconv.FormatFloat(price, 'f', stepPrecision(s.TickSize), 64)
+}
+
+// instrumentCache 交易对下单规格缓存（规格很少变化，首次使用时加载后不再过期）
+type instrumentCache struct {
+	mu    sync.RWMutex
+	specs map[string]instrumentSpec
+	load  func(symbol string) (instrumentSpec, error)
+}
+
+// newInstrumentCache 创建规格缓存，load在缓存未命中时从交易所查询
+func newInstrumentCache(load func(symbol string) (instrumentSpec, error)) *instrumentCache {
+	return &instrumentCache{
+		specs: make(map[string]instrumentSpec),
+		load:  load,
+	}
+}
+
+// Get 获取交易对规格（带缓存）
+func (c *instrumentCache) Get(symbol string) (instrumentSpec, error) {
+	c.mu.RLock()
+	spec, ok := c.specs[symbol]
+	c.mu.RUnlock()
+	if ok {
+		return spec, nil
+	}
+
+	spec, err := c.load(symbol)
+	if err != nil {
+		return instrumentSpec{}, err
+	}
+
+	c.mu.Lock()
+	c.specs[symbol] = spec
+	c.mu.Unlock()
+	return spec, nil
+}
+
+// accountCache 余额和持仓的短期缓存，下单或调整杠杆后调用Invalidate
+type accountCache struct {
+	exchange string
+	ttl      time.Duration
+
+	mu            sync.RWMutex
+	balance       *Balance
+	balanceTime   time.Time
+	positions     []Position
+	positionsTime time.Time
+}
+
+// newAccountCache 创建账户缓存
+func newAccountCache(exchange string, ttl time.Duration) *accountCache {
+	return &accountCache{exchange: exchange, ttl: ttl}
+}
+
+// Balance 返回缓存的余额，过期时调用fetch重新获取
+func (c *accountCache) Balance(fetch func() (*Balance, error)) (*Balance, error) {
+	c.mu.RLock()
+	if c.balance != nil && time.Since(c.balanceTime) < c.ttl {
+		balance, cacheAge := c.balance, time.Since(c.balanceTime)
+		c.mu.RUnlock()
+		log.Printf("✓ 使用缓存的%s账户余额（缓存时间: %.1f秒前）", c.exchange, cacheAge.Seconds())
+		return balance, nil
+	}
+	c.mu.RUnlock()
+
+	balance, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.balance = balance
+	c.balanceTime = time.Now()
+	c.mu.Unlock()
+	return balance, nil
+}
+
+// Positions 返回缓存的持仓，过期时调用fetch重新获取
+func (c *accountCache) Positions(fetch func() ([]Position, error)) ([]Position, error) {
+	c.mu.RLock()
+	if c.positions != nil && time.Since(c.positionsTime) < c.ttl {
+		positions, cacheAge := c.positions, time.Since(c.positionsTime)
+		c.mu.RUnlock()
+		log.Printf("✓ 使用缓存的%s持仓数据（缓存时间: %.1f秒前）", c.exchange, cacheAge.Seconds())
+		return positions, nil
+	}
+	c.mu.RUnlock()
+
+	positions, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.positions = positions
+	c.positionsTime = time.Now()
+	c.mu.Unlock()
+	return positions, nil
+}
+
+// Invalidate 清除余额和持仓缓存，避免下单后的查询读到旧数据
+func (c *accountCache) Invalidate() {
+	c.mu.Lock()
+	c.balance = nil
+	c.positions = nil
+	c.mu.Unlock()
+}
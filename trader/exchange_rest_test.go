@@ -0,0 +1,229 @@
+package trader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// decodeTestResponse 测试用响应格式：{"code":"", "msg":"", "data":{}}，code为空表示成功
+func decodeTestResponse(status int, body []byte, out interface{}) (string, string, error) {
+	var result struct {
+		Code string          `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", "", fmt.Errorf("解析响应失败 (HTTP %d): %s", status, string(body))
+	}
+	if result.Code != "" {
+		return result.Code, result.Msg, nil
+	}
+	if out != nil && len(result.Data) > 0 {
+		return "", "", json.Unmarshal(result.Data, out)
+	}
+	return "", "", nil
+}
+
+// newTestRESTClient 启动按调用次数返回响应的本地服务器，responses用完后重复最后一个
+func newTestRESTClient(t *testing.T, responses ...func(w http.ResponseWriter, r *http.Request)) (*restClient, *int) {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := calls
+		if i >= len(responses) {
+			i = len(responses) - 1
+		}
+		calls++
+		responses[i](w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	sign := func(req *restRequest) {
+		req.Header.Set("X-Test-Sign", hmacSHA256Hex("secret", req.Method+req.Path+req.Query+req.Body))
+	}
+	client := newRESTClient("Test", srv.URL, sign, decodeTestResponse, map[string]ExchangeErrorKind{
+		"1001": ExchangeErrAuth,
+		"2001": ExchangeErrInsufficientMargin,
+		"3001": ExchangeErrRateLimit,
+	})
+	client.retryDelay = 0
+	return client, &calls
+}
+
+func respondTest(status int, code, msg string, data interface{}) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeFakeJSON(w, status, map[string]interface{}{"code": code, "msg": msg, "data": data})
+	}
+}
+
+func TestRESTClientSignsRequest(t *testing.T) {
+	var gotSign, gotQuery, gotBody string
+	client, _ := newTestRESTClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		json.NewDecoder(r.Body).Decode(&body)
+		gotSign, gotQuery, gotBody = r.Header.Get("X-Test-Sign"), r.URL.RawQuery, string(body)
+		respondTest(http.StatusOK, "", "", map[string]string{"id": "42"})(w, r)
+	})
+
+	var out struct {
+		ID string `json:"id"`
+	}
+	if err := client.get("/v1/order", url.Values{"symbol": {"BTCUSDT"}}, &out); err != nil {
+		t.Fatalf("GET请求失败: %v", err)
+	}
+	if out.ID != "42" || gotQuery != "symbol=BTCUSDT" {
+		t.Errorf("响应解析或查询参数错误: %+v %s", out, gotQuery)
+	}
+	if gotSign != hmacSHA256Hex("secret", "GET/v1/ordersymbol=BTCUSDT") {
+		t.Errorf("GET签名错误: %s", gotSign)
+	}
+
+	if err := client.post("/v1/order", map[string]string{"symbol": "BTCUSDT"}, nil); err != nil {
+		t.Fatalf("POST请求失败: %v", err)
+	}
+	// 签名的请求体与实际发送的完全一致
+	if gotBody != `{"symbol":"BTCUSDT"}` || gotSign != hmacSHA256Hex("secret", "POST/v1/order"+gotBody) {
+		t.Errorf("POST签名或请求体错误: %s %s", gotSign, gotBody)
+	}
+}
+
+func TestRESTClientErrorKinds(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+		code   string
+		want   ExchangeErrorKind
+	}{
+		{"code_table", http.StatusOK, "2001", ExchangeErrInsufficientMargin},
+		{"http_429", http.StatusTooManyRequests, "9999", ExchangeErrRateLimit},
+		{"http_401", http.StatusUnauthorized, "9999", ExchangeErrAuth},
+		{"unknown", http.StatusBadRequest, "9999", ExchangeErrUnknown},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client, _ := newTestRESTClient(t, respondTest(tc.status, tc.code, "native message", nil))
+			client.maxRetries = 0
+
+			err := client.post("/v1/order", map[string]string{}, nil)
+			var apiErr *ExchangeAPIError
+			if !errors.As(fmt.Errorf("下单失败: %w", err), &apiErr) {
+				t.Fatalf("应返回*ExchangeAPIError, got: %v", err)
+			}
+			if apiErr.Kind != tc.want || apiErr.Code != tc.code || apiErr.HTTPStatus != tc.status {
+				t.Errorf("错误分类错误: %+v", apiErr)
+			}
+			if err.Error() != "Test API错误 ["+tc.code+"]: native message" {
+				t.Errorf("错误信息应保留原生错误码和信息: %v", err)
+			}
+		})
+	}
+}
+
+func TestRESTClientRetriesOnlyGET(t *testing.T) {
+	rateLimited := respondTest(http.StatusOK, "3001", "too many requests", nil)
+	ok := respondTest(http.StatusOK, "", "", nil)
+
+	client, calls := newTestRESTClient(t, rateLimited, rateLimited, ok)
+	if err := client.get("/v1/ticker", nil, nil); err != nil {
+		t.Fatalf("限频后重试应成功: %v", err)
+	}
+	if *calls != 3 {
+		t.Errorf("GET应重试2次, 共请求 %d 次", *calls)
+	}
+
+	// 写请求不重试，避免重复下单
+	client, calls = newTestRESTClient(t, rateLimited, ok)
+	if err := client.post("/v1/order", map[string]string{}, nil); !IsExchangeErrorKind(err, ExchangeErrRateLimit) {
+		t.Fatalf("POST限频应直接返回错误, got: %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("POST不应重试, 共请求 %d 次", *calls)
+	}
+
+	// 其他业务错误不重试
+	client, calls = newTestRESTClient(t, respondTest(http.StatusOK, "1001", "invalid key", nil))
+	if err := client.get("/v1/account", nil, nil); !IsExchangeErrorKind(err, ExchangeErrAuth) {
+		t.Fatalf("应返回鉴权错误, got: %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("鉴权错误不应重试, 共请求 %d 次", *calls)
+	}
+}
+
+func TestInstrumentSpecFormatting(t *testing.T) {
+	spec := instrumentSpec{QtyStep: 0.001, MinQty: 0.005, TickSize: 0.5}
+
+	if got := spec.FormatQuantity(0.0126789); got != "0.012" {
+		t.Errorf("数量应向下取整为 0.012, got %s", got)
+	}
+	if got, err := spec.FormatOrderQuantity("BTCUSDT", 0.0059); err != nil || got != "0.005" {
+		t.Errorf("FormatOrderQuantity() = %s, %v; want 0.005", got, err)
+	}
+	if _, err := spec.FormatOrderQuantity("BTCUSDT", 0.0049); err == nil {
+		t.Error("不足最小下单量时应返回错误")
+	}
+	if got := spec.FormatPrice(50000.26); got != "50000.5" {
+		t.Errorf("价格应取整到0.5的整数倍, got %s", got)
+	}
+}
+
+func TestInstrumentCacheLoadsOnce(t *testing.T) {
+	loads := 0
+	cache := newInstrumentCache(func(symbol string) (instrumentSpec, error) {
+		loads++
+		if symbol == "UNKNOWN" {
+			return instrumentSpec{}, fmt.Errorf("未找到交易对 %s", symbol)
+		}
+		return instrumentSpec{QtyStep: 0.01}, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		if spec, err := cache.Get("ETHUSDT"); err != nil || spec.QtyStep != 0.01 {
+			t.Fatalf("Get() = %+v, %v", spec, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("规格应只加载一次, got %d", loads)
+	}
+
+	// 加载失败不缓存
+	cache.Get("UNKNOWN")
+	if _, err := cache.Get("UNKNOWN"); err == nil || loads != 3 {
+		t.Errorf("加载失败后应重新加载, loads=%d err=%v", loads, err)
+	}
+}
+
+func TestAccountCache(t *testing.T) {
+	cache := newAccountCache("Test", time.Minute)
+	fetches := 0
+	fetch := func() (*Balance, error) {
+		fetches++
+		return &Balance{WalletBalance: float64(fetches)}, nil
+	}
+
+	cache.Balance(fetch)
+	balance, _ := cache.Balance(fetch)
+	if fetches != 1 || balance.WalletBalance != 1 {
+		t.Errorf("缓存有效期内不应重新获取: fetches=%d", fetches)
+	}
+
+	cache.Invalidate()
+	balance, _ = cache.Balance(fetch)
+	if fetches != 2 || balance.WalletBalance != 2 {
+		t.Errorf("Invalidate后应重新获取: fetches=%d", fetches)
+	}
+
+	// 获取失败时不缓存
+	if _, err := cache.Positions(func() ([]Position, error) { return nil, errors.New("网络错误") }); err == nil {
+		t.Fatal("获取失败时应返回错误")
+	}
+	positions, err := cache.Positions(func() ([]Position, error) { return []Position{}, nil })
+	if err != nil || positions == nil {
+		t.Errorf("失败后应重新获取持仓: %v %v", positions, err)
+	}
+}
@@ -0,0 +1,110 @@
+package trader
+
+import (
+	"log"
+	"strings"
+)
+
+// ExposureRequest 开仓前的组合风险检查请求
+type ExposureRequest struct {
+	TraderID    string
+	UserID      string
+	Symbol      string
+	Side        string  // long/short
+	NotionalUSD float64 // 计划开仓的名义价值（USDT）
+	Leverage    int
+}
+
+// ExposureChecker 组合风险检查（由manager中的用户级风控服务实现）
+// 汇总同一用户所有交易员的持仓，返回本次允许开仓的最大名义价值（可能小于请求值）和对应的预留额度；
+// 已达到任一上限时返回错误
+type ExposureChecker interface {
+	CheckExposure(req ExposureRequest) (float64, ExposureReservation, error)
+}
+
+// ExposureReservation 组合风险检查批准的开仓额度：下单成功后调用Commit，未下单或下单失败时调用Release
+// （Commit后Release无效，调用方可以defer Release）
+type ExposureReservation interface {
+	Commit()
+	Release()
+}
+
+// noExposureReservation 未设置组合风险检查时的空预留
+type noExposureReservation struct{}
+
+func (noExposureReservation) Commit()  {}
+func (noExposureReservation) Release() {}
+
+// SetExposureChecker 设置组合风险检查（nil表示只按本交易员的保证金检查）
+func (at *AutoTrader) SetExposureChecker(checker ExposureChecker) {
+	at.exposure = checker
+}
+
+// GetUserID 获取交易员所属用户ID
+func (at *AutoTrader) GetUserID() string {
+	return at.userID
+}
+
+// GetExposureSnapshot 获取账户净值和持仓（供组合风险汇总）
+func (at *AutoTrader) GetExposureSnapshot() (float64, []Position, error) {
+	balance, err := at.trader.GetBalance()
+	if err != nil {
+		return 0, nil, err
+	}
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return 0, nil, err
+	}
+	return balance.TotalEquity(), positions, nil
+}
+
+// PendingEntryExposure 未成交的限价入场单（按限价计算名义价值，供组合风险汇总）
+// 挂单成交前不会出现在持仓中，需单独计入，否则挂单期间其他交易员的开仓会绕过上限
+func (at *AutoTrader) PendingEntryExposure() []Position {
+	at.pendingEntryMu.Lock()
+	defer at.pendingEntryMu.Unlock()
+	return append([]Position(nil), at.pendingEntries...)
+}
+
+// publishPendingEntries 更新供组合风险汇总读取的挂单快照（调用方需持有execMu）
+// 汇总时不能获取execMu：其他交易员的检查可能发生在本交易员执行期间
+func (at *AutoTrader) publishPendingEntries() {
+	entries := make([]Position, 0, len(at.pendingLimitOrders))
+	for _, order := range at.pendingLimitOrders {
+		entries = append(entries, Position{
+			Symbol:     order.Symbol,
+			Side:       strings.ToLower(order.PositionSide),
+			Quantity:   order.Quantity,
+			EntryPrice: order.LimitPrice,
+			MarkPrice:  order.LimitPrice,
+		})
+	}
+	at.pendingEntryMu.Lock()
+	at.pendingEntries = entries
+	at.pendingEntryMu.Unlock()
+}
+
+// applyExposureLimit 按用户级组合风险上限调整开仓金额，超过上限时返回错误
+// 返回的预留额度需在下单后Commit，未下单时Release
+func (at *AutoTrader) applyExposureLimit(symbol, side string, sizeUSD float64, leverage int) (float64, ExposureReservation, error) {
+	if at.exposure == nil {
+		return sizeUSD, noExposureReservation{}, nil
+	}
+	allowed, reservation, err := at.exposure.CheckExposure(ExposureRequest{
+		TraderID:    at.id,
+		UserID:      at.userID,
+		Symbol:      symbol,
+		Side:        side,
+		NotionalUSD: sizeUSD,
+		Leverage:    leverage,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	if allowed < sizeUSD {
+		log.Printf("  ⚠️ 组合风险检查: %s 请求 $%.2f，按用户合计敞口上限调整为 $%.2f", symbol, sizeUSD, allowed)
+		return allowed, reservation, nil
+	}
+	log.Printf("  ✅ 组合风险检查通过: %s $%.2f", symbol, sizeUSD)
+	return sizeUSD, reservation, nil
+}
@@ -0,0 +1,93 @@
+package trader
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// fakeExposureChecker 记录请求并按固定额度放行
+type fakeExposureChecker struct {
+	limit        float64
+	err          error
+	requests     []ExposureRequest
+	reservations []*fakeReservation
+}
+
+// fakeReservation 记录预留额度的确认和释放
+type fakeReservation struct {
+	committed, released bool
+}
+
+func (r *fakeReservation) Commit() { r.committed = true }
+
+func (r *fakeReservation) Release() {
+	if !r.committed {
+		r.released = true
+	}
+}
+
+func (f *fakeExposureChecker) CheckExposure(req ExposureRequest) (float64, ExposureReservation, error) {
+	f.requests = append(f.requests, req)
+	if f.err != nil {
+		return 0, nil, f.err
+	}
+	reservation := &fakeReservation{}
+	f.reservations = append(f.reservations, reservation)
+	if req.NotionalUSD > f.limit {
+		return f.limit, reservation, nil
+	}
+	return req.NotionalUSD, reservation, nil
+}
+
+func TestApplyExposureLimit(t *testing.T) {
+	at := &AutoTrader{id: "t1", userID: "u1"}
+	if size, _, err := at.applyExposureLimit("BTCUSDT", "long", 500, 5); err != nil || size != 500 {
+		t.Fatalf("未设置组合风险检查时应原样返回, got %.2f %v", size, err)
+	}
+
+	checker := &fakeExposureChecker{limit: 200}
+	at.SetExposureChecker(checker)
+	size, _, err := at.applyExposureLimit("BTCUSDT", "long", 500, 5)
+	if err != nil || size != 200 {
+		t.Fatalf("应按组合上限调整为200, got %.2f %v", size, err)
+	}
+	want := ExposureRequest{TraderID: "t1", UserID: "u1", Symbol: "BTCUSDT", Side: "long", NotionalUSD: 500, Leverage: 5}
+	if len(checker.requests) != 1 || checker.requests[0] != want {
+		t.Errorf("检查请求错误: %+v", checker.requests)
+	}
+
+	limitErr := errors.New("超过组合风险上限")
+	checker.err = limitErr
+	if _, _, err := at.applyExposureLimit("ETHUSDT", "short", 100, 3); !errors.Is(err, limitErr) {
+		t.Errorf("超过上限应返回错误, got %v", err)
+	}
+}
+
+func TestOpenCommitsOrReleasesExposureReservation(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	at, _ := newLimitTestAutoTrader(t, newFakePriceFeed(map[string]float64{"BTCUSDT": 100}), &now)
+	checker := &fakeExposureChecker{limit: 5}
+	at.SetExposureChecker(checker)
+	open := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 500, StopLoss: 90, TakeProfit: 110}
+
+	// 按组合上限调整后低于最小开仓金额，未下单时释放预留
+	if err := at.executeOpenLongWithRecord(open, &logger.DecisionAction{}); err == nil {
+		t.Fatal("开仓金额过小应拒绝")
+	}
+	if r := checker.reservations[0]; !r.released || r.committed {
+		t.Errorf("未下单时应释放预留: %+v", r)
+	}
+
+	// 下单成功后确认预留
+	checker.limit = 1000
+	if err := at.executeOpenLongWithRecord(open, &logger.DecisionAction{}); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	if r := checker.reservations[1]; !r.committed || r.released {
+		t.Errorf("下单成功后应确认预留: %+v", r)
+	}
+}
@@ -0,0 +1,465 @@
+package trader
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 币安U本位合约API的原生错误（Aster接口与币安一致）
+const (
+	binanceErrInvalidSymbol       = -1121
+	binanceErrPrecision           = -1111
+	binanceErrParamNotRequired    = -1106
+	binanceErrInvalidOrderType    = -1116
+	binanceErrMarginInsufficient  = -2019
+	binanceErrUnknownOrder        = -2011
+	binanceErrReduceOnlyRejected  = -2022
+	binanceErrNoNeedChangeMargin  = -4046
+	binanceErrMarginTypeLocked    = -4048
+	binanceErrPositionSideInvalid = -4061
+)
+
+var binanceErrorMessages = map[int]string{
+	binanceErrInvalidSymbol:       "Invalid symbol.",
+	binanceErrPrecision:           "Precision is over the maximum defined for this asset.",
+	binanceErrParamNotRequired:    "Parameter 'reduceOnly' sent when not required.",
+	binanceErrInvalidOrderType:    "Invalid orderType.",
+	binanceErrMarginInsufficient:  "Margin is insufficient.",
+	binanceErrUnknownOrder:        "Unknown order sent.",
+	binanceErrReduceOnlyRejected:  "ReduceOnly Order is rejected.",
+	binanceErrNoNeedChangeMargin:  "No need to change margin type.",
+	binanceErrMarginTypeLocked:    "Margin type cannot be changed if there exists position.",
+	binanceErrPositionSideInvalid: "Order's position side does not match user's setting.",
+}
+
+// fakeBinanceAPI 模拟币安风格的合约接口
+// hedgeMode为true时模拟币安双向持仓，为false时模拟Aster的单向持仓（positionSide=BOTH）
+type fakeBinanceAPI struct {
+	ex        *fakeExchange
+	hedgeMode bool
+}
+
+// newFakeBinanceServer 启动模拟币安合约API的本地服务器
+func newFakeBinanceServer(t *testing.T, ex *fakeExchange) *httptest.Server {
+	api := &fakeBinanceAPI{ex: ex, hedgeMode: true}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fapi/v1/exchangeInfo", api.exchangeInfo)
+	mux.HandleFunc("/fapi/v2/ticker/price", api.tickerPrice)
+	mux.HandleFunc("/fapi/v2/account", api.account)
+	mux.HandleFunc("/fapi/v2/positionRisk", api.positionRisk)
+	mux.HandleFunc("/fapi/v1/order", api.order)
+	mux.HandleFunc("/fapi/v1/allOpenOrders", api.cancelAllOrders)
+	mux.HandleFunc("/fapi/v1/openOrders", api.openOrders)
+	mux.HandleFunc("/fapi/v1/leverage", api.leverage)
+	mux.HandleFunc("/fapi/v1/marginType", api.marginType)
+
+	srv := httptest.NewServer(ex.locked(mux))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newFakeAsterServer 启动模拟Aster合约API的本地服务器（接口与币安一致，路径为/fapi/v3）
+func newFakeAsterServer(t *testing.T, ex *fakeExchange) *httptest.Server {
+	api := &fakeBinanceAPI{ex: ex, hedgeMode: false}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fapi/v3/exchangeInfo", api.exchangeInfo)
+	mux.HandleFunc("/fapi/v3/ticker/price", api.tickerPrice)
+	mux.HandleFunc("/fapi/v3/balance", api.balance)
+	mux.HandleFunc("/fapi/v3/positionRisk", api.positionRisk)
+	mux.HandleFunc("/fapi/v3/order", api.order)
+	mux.HandleFunc("/fapi/v3/allOpenOrders", api.cancelAllOrders)
+	mux.HandleFunc("/fapi/v3/openOrders", api.openOrders)
+	mux.HandleFunc("/fapi/v3/leverage", api.leverage)
+	mux.HandleFunc("/fapi/v3/marginType", api.marginType)
+
+	srv := httptest.NewServer(ex.locked(mux))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// fakeRequestParams 合并querystring和表单body中的参数（币安DELETE请求的参数也在body中）
+func fakeRequestParams(r *http.Request) url.Values {
+	params := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+	form, _ := url.ParseQuery(string(body))
+	for key, values := range form {
+		params[key] = values
+	}
+	return params
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeBinanceError(w http.ResponseWriter, code int) {
+	writeFakeJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"code": code,
+		"msg":  binanceErrorMessages[code],
+	})
+}
+
+func (api *fakeBinanceAPI) sortedSymbols() []string {
+	symbols := make([]string, 0, len(api.ex.specs))
+	for symbol := range api.ex.specs {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+func (api *fakeBinanceAPI) exchangeInfo(w http.ResponseWriter, r *http.Request) {
+	symbols := []map[string]interface{}{}
+	for _, symbol := range api.sortedSymbols() {
+		spec := api.ex.specs[symbol]
+		symbols = append(symbols, map[string]interface{}{
+			"symbol":            symbol,
+			"pricePrecision":    calculatePrecision(fakeNum(spec.TickSize)),
+			"quantityPrecision": calculatePrecision(fakeNum(spec.QtyStep)),
+			"filters": []map[string]interface{}{
+				{"filterType": "PRICE_FILTER", "tickSize": fakeNum(spec.TickSize)},
+				{"filterType": "LOT_SIZE", "stepSize": fakeNum(spec.QtyStep), "minQty": fakeNum(spec.QtyStep)},
+			},
+		})
+	}
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{"symbols": symbols})
+}
+
+func (api *fakeBinanceAPI) tickerPrice(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	spec, ok := api.ex.specs[symbol]
+	if !ok {
+		writeBinanceError(w, binanceErrInvalidSymbol)
+		return
+	}
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"symbol": symbol,
+		"price":  fakeNum(spec.Price),
+		"time":   time.Now().UnixMilli(),
+	})
+}
+
+func (api *fakeBinanceAPI) account(w http.ResponseWriter, r *http.Request) {
+	upl := api.ex.totalUnrealizedProfit()
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"totalWalletBalance":    fakeNum(api.ex.balance),
+		"totalUnrealizedProfit": fakeNum(upl),
+		"availableBalance":      fakeNum(api.ex.balance + upl - api.ex.usedMargin()),
+		"assets":                []interface{}{},
+		"positions":             []interface{}{},
+	})
+}
+
+func (api *fakeBinanceAPI) balance(w http.ResponseWriter, r *http.Request) {
+	upl := api.ex.totalUnrealizedProfit()
+	writeFakeJSON(w, http.StatusOK, []map[string]interface{}{{
+		"asset":            "USDT",
+		"balance":          fakeNum(api.ex.balance),
+		"availableBalance": fakeNum(api.ex.balance + upl - api.ex.usedMargin()),
+		"crossUnPnl":       fakeNum(upl),
+	}})
+}
+
+// positionRisk 与交易所一致，无持仓的交易对也返回数量为0的记录
+func (api *fakeBinanceAPI) positionRisk(w http.ResponseWriter, r *http.Request) {
+	entry := func(symbol, positionSide string, pos *fakePosition) map[string]interface{} {
+		item := map[string]interface{}{
+			"symbol":           symbol,
+			"positionSide":     positionSide,
+			"positionAmt":      "0",
+			"entryPrice":       "0",
+			"markPrice":        fakeNum(api.ex.specs[symbol].Price),
+			"unRealizedProfit": "0",
+			"liquidationPrice": "0",
+			"leverage":         strconv.Itoa(api.ex.leverageOf(symbol)),
+			"marginType":       api.ex.marginModeOf(symbol),
+		}
+		if pos != nil {
+			amount := pos.Quantity
+			if pos.Side == "short" {
+				amount = -amount
+			}
+			item["positionAmt"] = fakeNum(amount)
+			item["entryPrice"] = fakeNum(pos.EntryPrice)
+			item["unRealizedProfit"] = fakeNum(api.ex.unrealizedProfit(pos))
+			item["leverage"] = strconv.Itoa(pos.Leverage)
+			item["marginType"] = pos.MarginMode
+		}
+		return item
+	}
+
+	result := []map[string]interface{}{}
+	for _, symbol := range api.sortedSymbols() {
+		if api.hedgeMode {
+			result = append(result,
+				entry(symbol, "LONG", api.ex.positions[symbol+"_long"]),
+				entry(symbol, "SHORT", api.ex.positions[symbol+"_short"]))
+			continue
+		}
+		result = append(result, entry(symbol, "BOTH", api.ex.netPosition(symbol)))
+	}
+	writeFakeJSON(w, http.StatusOK, result)
+}
+
+func (api *fakeBinanceAPI) order(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		api.cancelOrder(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	params := fakeRequestParams(r)
+	symbol := params.Get("symbol")
+	spec, ok := api.ex.specs[symbol]
+	if !ok {
+		writeBinanceError(w, binanceErrInvalidSymbol)
+		return
+	}
+	if api.ex.rejectOrders {
+		writeBinanceError(w, binanceErrMarginInsufficient)
+		return
+	}
+
+	side := params.Get("side")
+	isBuy := side == "BUY"
+	positionSide := params.Get("positionSide")
+	if positionSide == "" {
+		positionSide = "BOTH"
+	}
+	// 双向持仓必须指定LONG/SHORT，单向持仓只能是BOTH
+	if api.hedgeMode == (positionSide == "BOTH") {
+		writeBinanceError(w, binanceErrPositionSideInvalid)
+		return
+	}
+	reduceOnly := params.Get("reduceOnly") == "true"
+	if api.hedgeMode && reduceOnly {
+		writeBinanceError(w, binanceErrParamNotRequired)
+		return
+	}
+	closePosition := params.Get("closePosition") == "true"
+
+	quantity, _ := strconv.ParseFloat(params.Get("quantity"), 64)
+	if !(closePosition && quantity == 0) && !api.ex.validQuantity(symbol, quantity) {
+		writeBinanceError(w, binanceErrPrecision)
+		return
+	}
+
+	// 该订单会减少哪个方向的持仓（双向持仓下卖出LONG/买入SHORT为平仓）
+	targetSide := "long"
+	if isBuy {
+		targetSide = "short"
+	}
+	closing := true
+	if api.hedgeMode {
+		closing = strings.ToLower(positionSide) == targetSide
+		targetSide = strings.ToLower(positionSide)
+	}
+
+	orderType := params.Get("type")
+	resp := map[string]interface{}{
+		"orderId":       api.ex.newID(),
+		"symbol":        symbol,
+		"status":        "NEW",
+		"type":          orderType,
+		"side":          side,
+		"positionSide":  positionSide,
+		"origQty":       params.Get("quantity"),
+		"executedQty":   "0",
+		"avgPrice":      "0",
+		"price":         "0",
+		"stopPrice":     "0",
+		"reduceOnly":    reduceOnly,
+		"closePosition": closePosition,
+		"updateTime":    time.Now().UnixMilli(),
+	}
+	if price := params.Get("price"); price != "" {
+		resp["price"] = price
+	}
+	if stopPrice := params.Get("stopPrice"); stopPrice != "" {
+		resp["stopPrice"] = stopPrice
+	}
+
+	switch orderType {
+	case "STOP_MARKET", "TAKE_PROFIT_MARKET":
+		kind := "sl"
+		if orderType == "TAKE_PROFIT_MARKET" {
+			kind = "tp"
+		}
+		triggerPrice, _ := strconv.ParseFloat(params.Get("stopPrice"), 64)
+		api.ex.addTrigger(fakeTrigger{
+			Symbol:        symbol,
+			PositionSide:  targetSide,
+			Kind:          kind,
+			TriggerPrice:  triggerPrice,
+			Quantity:      quantity,
+			ReduceOnly:    closing && (api.hedgeMode || reduceOnly || closePosition),
+			ClosePosition: closePosition,
+		})
+
+	case "TRAILING_STOP_MARKET":
+		callbackRate, _ := strconv.ParseFloat(params.Get("callbackRate"), 64)
+		activationPrice, _ := strconv.ParseFloat(params.Get("activationPrice"), 64)
+		api.ex.addTrigger(fakeTrigger{
+			Symbol:          symbol,
+			PositionSide:    targetSide,
+			Kind:            "trailing",
+			Quantity:        quantity,
+			ReduceOnly:      closing && (api.hedgeMode || reduceOnly),
+			CallbackRate:    callbackRate / 100,
+			ActivationPrice: activationPrice,
+		})
+
+	case "MARKET", "LIMIT":
+		if orderType == "LIMIT" {
+			// 未穿过盘口的限价单挂单等待（本模拟不撮合挂单）
+			limitPrice, _ := strconv.ParseFloat(params.Get("price"), 64)
+			if (isBuy && limitPrice < spec.Price) || (!isBuy && limitPrice > spec.Price) {
+				writeFakeJSON(w, http.StatusOK, resp)
+				return
+			}
+		}
+
+		var err error
+		switch {
+		case !api.hedgeMode:
+			err = api.ex.trade(symbol, isBuy, quantity, reduceOnly)
+		case closing:
+			err = api.ex.reduce(symbol, targetSide, quantity)
+		default:
+			api.ex.open(symbol, targetSide, quantity)
+		}
+		if err != nil {
+			writeBinanceError(w, binanceErrReduceOnlyRejected)
+			return
+		}
+		resp["status"] = "FILLED"
+		resp["executedQty"] = params.Get("quantity")
+		resp["avgPrice"] = fakeNum(spec.Price)
+
+	default:
+		writeBinanceError(w, binanceErrInvalidOrderType)
+		return
+	}
+
+	writeFakeJSON(w, http.StatusOK, resp)
+}
+
+// triggerOrder 将条件单转换为币安订单格式
+func (api *fakeBinanceAPI) triggerOrder(trigger fakeTrigger) map[string]interface{} {
+	orderType := map[string]string{"sl": "STOP_MARKET", "tp": "TAKE_PROFIT_MARKET", "trailing": "TRAILING_STOP_MARKET"}[trigger.Kind]
+	side := "SELL"
+	if trigger.PositionSide == "short" {
+		side = "BUY"
+	}
+	positionSide := "BOTH"
+	if api.hedgeMode {
+		positionSide = strings.ToUpper(trigger.PositionSide)
+	}
+	quantity := fakeNum(trigger.Quantity)
+	if trigger.ClosePosition {
+		quantity = "0"
+	}
+	return map[string]interface{}{
+		"orderId":       trigger.ID,
+		"symbol":        trigger.Symbol,
+		"status":        "NEW",
+		"type":          orderType,
+		"side":          side,
+		"positionSide":  positionSide,
+		"origQty":       quantity,
+		"executedQty":   "0",
+		"avgPrice":      "0",
+		"price":         "0",
+		"stopPrice":     fakeNum(trigger.TriggerPrice),
+		"reduceOnly":    trigger.ReduceOnly,
+		"closePosition": trigger.ClosePosition,
+		"updateTime":    time.Now().UnixMilli(),
+	}
+}
+
+// openOrders 返回未触发的条件单（本模拟不保存挂单中的限价单）
+func (api *fakeBinanceAPI) openOrders(w http.ResponseWriter, r *http.Request) {
+	symbol := fakeRequestParams(r).Get("symbol")
+	orders := []map[string]interface{}{}
+	for _, trigger := range api.ex.triggers {
+		if symbol == "" || trigger.Symbol == symbol {
+			orders = append(orders, api.triggerOrder(trigger))
+		}
+	}
+	writeFakeJSON(w, http.StatusOK, orders)
+}
+
+func (api *fakeBinanceAPI) cancelOrder(w http.ResponseWriter, r *http.Request) {
+	params := fakeRequestParams(r)
+	id, _ := strconv.ParseInt(params.Get("orderId"), 10, 64)
+	for _, trigger := range api.ex.triggers {
+		if trigger.ID == id && trigger.Symbol == params.Get("symbol") {
+			api.ex.cancelTrigger(id)
+			order := api.triggerOrder(trigger)
+			order["status"] = "CANCELED"
+			writeFakeJSON(w, http.StatusOK, order)
+			return
+		}
+	}
+	writeBinanceError(w, binanceErrUnknownOrder)
+}
+
+func (api *fakeBinanceAPI) cancelAllOrders(w http.ResponseWriter, r *http.Request) {
+	api.ex.cancelAll(fakeRequestParams(r).Get("symbol"))
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"code": 200,
+		"msg":  "The operation of cancel all open order is done.",
+	})
+}
+
+func (api *fakeBinanceAPI) leverage(w http.ResponseWriter, r *http.Request) {
+	params := fakeRequestParams(r)
+	symbol := params.Get("symbol")
+	if _, ok := api.ex.specs[symbol]; !ok {
+		writeBinanceError(w, binanceErrInvalidSymbol)
+		return
+	}
+	leverage, _ := strconv.Atoi(params.Get("leverage"))
+	api.ex.setLeverage(symbol, leverage)
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"symbol":           symbol,
+		"leverage":         leverage,
+		"maxNotionalValue": "1000000",
+	})
+}
+
+func (api *fakeBinanceAPI) marginType(w http.ResponseWriter, r *http.Request) {
+	params := fakeRequestParams(r)
+	symbol := params.Get("symbol")
+	if _, ok := api.ex.specs[symbol]; !ok {
+		writeBinanceError(w, binanceErrInvalidSymbol)
+		return
+	}
+
+	mode := "cross"
+	if strings.EqualFold(params.Get("marginType"), "ISOLATED") {
+		mode = "isolated"
+	}
+	if api.ex.marginModeOf(symbol) == mode {
+		writeBinanceError(w, binanceErrNoNeedChangeMargin)
+		return
+	}
+	if err := api.ex.setMarginMode(symbol, mode); err != nil {
+		writeBinanceError(w, binanceErrMarginTypeLocked)
+		return
+	}
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{"code": 200, "msg": "success"})
+}
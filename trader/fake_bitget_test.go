@@ -0,0 +1,702 @@
+package trader
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// 模拟服务器校验签名使用的Secret和Passphrase
+const (
+	fakeBitgetSecret     = "test-secret"
+	fakeBitgetPassphrase = "test-passphrase"
+)
+
+// Bitget V2接口的原生错误码
+const (
+	bitgetErrParam           = "40017"
+	bitgetErrSymbolNotFound  = "40034"
+	bitgetErrSign            = "40009"
+	bitgetErrPassphrase      = "40012"
+	bitgetErrOrderNotFound   = "40768"
+	bitgetErrInsufficient    = "43012"
+	bitgetErrNoPosition      = "22002"
+	bitgetErrNoOrder         = "22001"
+	bitgetErrMinQuantity     = "45110"
+	bitgetErrPositionHolding = "40920"
+)
+
+var bitgetErrorMessages = map[string]string{
+	bitgetErrParam:           "Parameter verification failed",
+	bitgetErrSymbolNotFound:  "Parameter symbol does not exist",
+	bitgetErrSign:            "sign signature error",
+	bitgetErrPassphrase:      "apikey/password is incorrect",
+	bitgetErrOrderNotFound:   "Order does not exist",
+	bitgetErrInsufficient:    "Insufficient balance",
+	bitgetErrNoPosition:      "No position to close",
+	bitgetErrNoOrder:         "No order to cancel",
+	bitgetErrMinQuantity:     "less than the minimum order quantity",
+	bitgetErrPositionHolding: "Position or order exists, the position mode cannot be switched",
+}
+
+// fakeBitgetTakerFee 模拟的taker手续费率
+const fakeBitgetTakerFee = 0.0006
+
+// fakeBitgetAPI 模拟Bitget V2 USDT永续合约接口（账户初始为单向持仓模式）
+type fakeBitgetAPI struct {
+	ex        *fakeExchange
+	hedgeMode bool
+
+	// 订单和成交记录（字段名与Bitget接口一致）
+	orders map[string]map[string]interface{}
+	fills  []map[string]interface{}
+}
+
+// newFakeBitgetServer 启动模拟Bitget API的本地服务器，所有私有接口都会校验签名
+func newFakeBitgetServer(t *testing.T, ex *fakeExchange) (*httptest.Server, *fakeBitgetAPI) {
+	api := &fakeBitgetAPI{ex: ex, orders: make(map[string]map[string]interface{})}
+	const prefix = "/api/v2/mix"
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+prefix+"/market/contracts", api.contracts)
+	mux.HandleFunc("GET "+prefix+"/market/ticker", api.ticker)
+	mux.HandleFunc("GET "+prefix+"/account/accounts", api.signed(api.accounts))
+	mux.HandleFunc("GET "+prefix+"/account/account", api.signed(api.account))
+	mux.HandleFunc("POST "+prefix+"/account/set-position-mode", api.signed(api.setPositionMode))
+	mux.HandleFunc("POST "+prefix+"/account/set-leverage", api.signed(api.setLeverage))
+	mux.HandleFunc("POST "+prefix+"/account/set-margin-mode", api.signed(api.setMarginMode))
+	mux.HandleFunc("GET "+prefix+"/position/all-position", api.signed(api.positions))
+	mux.HandleFunc("POST "+prefix+"/order/place-order", api.signed(api.placeOrder))
+	mux.HandleFunc("POST "+prefix+"/order/modify-order", api.signed(api.modifyOrder))
+	mux.HandleFunc("POST "+prefix+"/order/cancel-order", api.signed(api.cancelOrder))
+	mux.HandleFunc("POST "+prefix+"/order/batch-cancel-orders", api.signed(api.batchCancelOrders))
+	mux.HandleFunc("GET "+prefix+"/order/detail", api.signed(api.orderDetail))
+	mux.HandleFunc("GET "+prefix+"/order/orders-pending", api.signed(api.ordersPending))
+	mux.HandleFunc("GET "+prefix+"/order/fills", api.signed(api.orderFills))
+	mux.HandleFunc("POST "+prefix+"/order/place-tpsl-order", api.signed(api.placeTPSLOrder))
+	mux.HandleFunc("GET "+prefix+"/order/orders-plan-pending", api.signed(api.plansPending))
+	mux.HandleFunc("POST "+prefix+"/order/cancel-plan-order", api.signed(api.cancelPlanOrder))
+
+	srv := httptest.NewServer(ex.locked(mux))
+	t.Cleanup(srv.Close)
+	return srv, api
+}
+
+// isHedgeMode 供测试断言使用，会自行加锁
+func (api *fakeBitgetAPI) isHedgeMode() bool {
+	api.ex.mu.Lock()
+	defer api.ex.mu.Unlock()
+	return api.hedgeMode
+}
+
+func writeBitgetResult(w http.ResponseWriter, data interface{}) {
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"code":        "00000",
+		"msg":         "success",
+		"requestTime": time.Now().UnixMilli(),
+		"data":        data,
+	})
+}
+
+func writeBitgetError(w http.ResponseWriter, code string) {
+	status := http.StatusBadRequest
+	if code == bitgetErrSign || code == bitgetErrPassphrase {
+		status = http.StatusUnauthorized
+	}
+	writeFakeJSON(w, status, map[string]interface{}{
+		"code":        code,
+		"msg":         bitgetErrorMessages[code],
+		"requestTime": time.Now().UnixMilli(),
+		"data":        nil,
+	})
+}
+
+// fakeBitgetParams 签名校验通过后的请求参数（GET取查询参数，POST取JSON请求体）
+type fakeBitgetParams map[string]interface{}
+
+func (p fakeBitgetParams) str(key string) string {
+	switch v := p[key].(type) {
+	case string:
+		return v
+	case float64:
+		return fakeNum(v)
+	}
+	return ""
+}
+
+func (p fakeBitgetParams) num(key string) float64 {
+	value, _ := strconv.ParseFloat(p.str(key), 64)
+	return value
+}
+
+// signed 校验ACCESS-*请求头，签名原文为 timestamp + method + path + ["?" + query] + body
+// 同时要求请求带上productType=USDT-FUTURES，与实盘接口一致
+func (api *fakeBitgetAPI) signed(handler func(http.ResponseWriter, *http.Request, fakeBitgetParams)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		payload := r.Header.Get("ACCESS-TIMESTAMP") + r.Method + r.URL.Path
+		if r.URL.RawQuery != "" {
+			payload += "?" + r.URL.RawQuery
+		}
+		payload += string(body)
+		mac := hmac.New(sha256.New, []byte(fakeBitgetSecret))
+		mac.Write([]byte(payload))
+		if r.Header.Get("ACCESS-KEY") == "" || r.Header.Get("ACCESS-TIMESTAMP") == "" ||
+			r.Header.Get("ACCESS-SIGN") != base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+			writeBitgetError(w, bitgetErrSign)
+			return
+		}
+		if r.Header.Get("ACCESS-PASSPHRASE") != fakeBitgetPassphrase {
+			writeBitgetError(w, bitgetErrPassphrase)
+			return
+		}
+
+		params := fakeBitgetParams{}
+		for key := range r.URL.Query() {
+			params[key] = r.URL.Query().Get(key)
+		}
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, &params); err != nil {
+				writeBitgetError(w, bitgetErrParam)
+				return
+			}
+		}
+		if params.str("productType") != "USDT-FUTURES" {
+			writeBitgetError(w, bitgetErrParam)
+			return
+		}
+		handler(w, r, params)
+	}
+}
+
+// symbolOf 请求中的交易对及其规格
+func (api *fakeBitgetAPI) symbolOf(params fakeBitgetParams) (string, fakeSymbolSpec, bool) {
+	symbol := params.str("symbol")
+	spec, ok := api.ex.specs[symbol]
+	return symbol, spec, ok
+}
+
+// sortedSymbols 按名称排序的交易对（symbol非空时只返回该交易对）
+func (api *fakeBitgetAPI) sortedSymbols(symbol string) []string {
+	symbols := []string{}
+	for s := range api.ex.specs {
+		if symbol == "" || s == symbol {
+			symbols = append(symbols, s)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// contracts pricePlace/priceEndStep 表示价格步长，这里固定用 priceEndStep=1
+func (api *fakeBitgetAPI) contracts(w http.ResponseWriter, r *http.Request) {
+	list := []map[string]interface{}{}
+	for _, symbol := range api.sortedSymbols(r.URL.Query().Get("symbol")) {
+		spec := api.ex.specs[symbol]
+		list = append(list, map[string]interface{}{
+			"symbol":         symbol,
+			"minTradeNum":    fakeNum(spec.QtyStep),
+			"sizeMultiplier": fakeNum(spec.QtyStep),
+			"volumePlace":    strconv.Itoa(stepPrecision(spec.QtyStep)),
+			"pricePlace":     strconv.Itoa(stepPrecision(spec.TickSize)),
+			"priceEndStep":   "1",
+		})
+	}
+	writeBitgetResult(w, list)
+}
+
+func (api *fakeBitgetAPI) ticker(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	spec, ok := api.ex.specs[symbol]
+	if !ok {
+		writeBitgetError(w, bitgetErrSymbolNotFound)
+		return
+	}
+	writeBitgetResult(w, []map[string]interface{}{{
+		"symbol":    symbol,
+		"lastPr":    fakeNum(spec.Price),
+		"markPrice": fakeNum(spec.Price),
+	}})
+}
+
+func (api *fakeBitgetAPI) accounts(w http.ResponseWriter, r *http.Request, _ fakeBitgetParams) {
+	upl := api.ex.totalUnrealizedProfit()
+	writeBitgetResult(w, []map[string]interface{}{{
+		"marginCoin":    "USDT",
+		"accountEquity": fakeNum(api.ex.balance + upl),
+		"usdtEquity":    fakeNum(api.ex.balance + upl),
+		"available":     fakeNum(api.ex.balance + upl - api.ex.usedMargin()),
+		"unrealizedPL":  fakeNum(upl),
+	}})
+}
+
+// posMode 当前持仓模式
+func (api *fakeBitgetAPI) posMode() string {
+	if api.hedgeMode {
+		return "hedge_mode"
+	}
+	return "one_way_mode"
+}
+
+// marginModeOf 交易对的保证金模式（Bitget格式：crossed/isolated）
+func (api *fakeBitgetAPI) marginModeOf(symbol string) string {
+	if api.ex.marginModeOf(symbol) == "isolated" {
+		return "isolated"
+	}
+	return "crossed"
+}
+
+func (api *fakeBitgetAPI) account(w http.ResponseWriter, r *http.Request, params fakeBitgetParams) {
+	symbol, _, ok := api.symbolOf(params)
+	if !ok {
+		writeBitgetError(w, bitgetErrSymbolNotFound)
+		return
+	}
+	leverage := strconv.Itoa(api.ex.leverageOf(symbol))
+	writeBitgetResult(w, map[string]interface{}{
+		"marginCoin":            "USDT",
+		"marginMode":            api.marginModeOf(symbol),
+		"posMode":               api.posMode(),
+		"crossedMarginLeverage": leverage,
+		"isolatedLongLever":     leverage,
+		"isolatedShortLever":    leverage,
+	})
+}
+
+func (api *fakeBitgetAPI) setPositionMode(w http.ResponseWriter, r *http.Request, params fakeBitgetParams) {
+	posMode := params.str("posMode")
+	if posMode != "hedge_mode" && posMode != "one_way_mode" {
+		writeBitgetError(w, bitgetErrParam)
+		return
+	}
+	hedge := posMode == "hedge_mode"
+	if hedge != api.hedgeMode && len(api.ex.positions) > 0 {
+		writeBitgetError(w, bitgetErrPositionHolding)
+		return
+	}
+	api.hedgeMode = hedge
+	writeBitgetResult(w, map[string]string{"posMode": posMode})
+}
+
+// setLeverage 逐仓模式下必须指定holdSide（本模拟多空共用同一杠杆）
+func (api *fakeBitgetAPI) setLeverage(w http.ResponseWriter, r *http.Request, params fakeBitgetParams) {
+	symbol, _, ok := api.symbolOf(params)
+	if !ok {
+		writeBitgetError(w, bitgetErrSymbolNotFound)
+		return
+	}
+	leverage, err := strconv.Atoi(params.str("leverage"))
+	if err != nil || leverage <= 0 {
+		writeBitgetError(w, bitgetErrParam)
+		return
+	}
+	holdSide := params.str("holdSide")
+	if api.hedgeMode && api.ex.marginModeOf(symbol) == "isolated" && holdSide != "long" && holdSide != "short" {
+		writeBitgetError(w, bitgetErrParam)
+		return
+	}
+	api.ex.setLeverage(symbol, leverage)
+	writeBitgetResult(w, map[string]interface{}{
+		"symbol":                symbol,
+		"marginCoin":            "USDT",
+		"marginMode":            api.marginModeOf(symbol),
+		"crossedMarginLeverage": strconv.Itoa(leverage),
+	})
+}
+
+func (api *fakeBitgetAPI) setMarginMode(w http.ResponseWriter, r *http.Request, params fakeBitgetParams) {
+	symbol, _, ok := api.symbolOf(params)
+	if !ok {
+		writeBitgetError(w, bitgetErrSymbolNotFound)
+		return
+	}
+	mode := "cross"
+	switch params.str("marginMode") {
+	case "crossed":
+	case "isolated":
+		mode = "isolated"
+	default:
+		writeBitgetError(w, bitgetErrParam)
+		return
+	}
+	if err := api.ex.setMarginMode(symbol, mode); err != nil {
+		writeBitgetError(w, bitgetErrPositionHolding)
+		return
+	}
+	writeBitgetResult(w, map[string]interface{}{"symbol": symbol, "marginMode": params.str("marginMode")})
+}
+
+func (api *fakeBitgetAPI) positionEntry(pos *fakePosition) map[string]interface{} {
+	return map[string]interface{}{
+		"symbol":           pos.Symbol,
+		"marginCoin":       "USDT",
+		"holdSide":         pos.Side,
+		"total":            fakeNum(pos.Quantity),
+		"available":        fakeNum(pos.Quantity),
+		"openPriceAvg":     fakeNum(pos.EntryPrice),
+		"markPrice":        fakeNum(api.ex.specs[pos.Symbol].Price),
+		"unrealizedPL":     fakeNum(api.ex.unrealizedProfit(pos)),
+		"leverage":         strconv.Itoa(pos.Leverage),
+		"liquidationPrice": "0",
+		"marginMode":       api.marginModeOf(pos.Symbol),
+		"posMode":          api.posMode(),
+	}
+}
+
+func (api *fakeBitgetAPI) positions(w http.ResponseWriter, r *http.Request, _ fakeBitgetParams) {
+	list := []map[string]interface{}{}
+	for _, symbol := range api.sortedSymbols("") {
+		for _, side := range []string{"long", "short"} {
+			if pos, ok := api.ex.positions[symbol+"_"+side]; ok {
+				list = append(list, api.positionEntry(pos))
+			}
+		}
+	}
+	writeBitgetResult(w, list)
+}
+
+// execute 市价成交：双向持仓模式下side表示持仓方向、tradeSide区分开平；单向持仓模式按side净额成交
+func (api *fakeBitgetAPI) execute(symbol, side, tradeSide string, quantity float64, reduceOnly bool) string {
+	if !api.hedgeMode {
+		if err := api.ex.trade(symbol, side == "buy", quantity, reduceOnly); err != nil {
+			return bitgetErrNoPosition
+		}
+		return ""
+	}
+
+	holdSide := "long"
+	if side == "sell" {
+		holdSide = "short"
+	}
+	if tradeSide == "close" {
+		if err := api.ex.reduce(symbol, holdSide, quantity); err != nil {
+			return bitgetErrNoPosition
+		}
+		return ""
+	}
+	api.ex.open(symbol, holdSide, quantity)
+	return ""
+}
+
+// fill 按当前价格成交订单，记录成交并更新订单状态（手续费为负数）
+func (api *fakeBitgetAPI) fill(order map[string]interface{}, symbol string) {
+	price := api.ex.specs[symbol].Price
+	quantity, _ := strconv.ParseFloat(order["size"].(string), 64)
+	fee := -price * quantity * fakeBitgetTakerFee
+	order["state"] = "filled"
+	order["baseVolume"] = order["size"]
+	order["priceAvg"] = fakeNum(price)
+	order["fee"] = fakeNum(fee)
+	api.fills = append(api.fills, map[string]interface{}{
+		"tradeId":    strconv.FormatInt(api.ex.newID(), 10),
+		"orderId":    order["orderId"],
+		"symbol":     symbol,
+		"side":       order["side"],
+		"tradeSide":  order["tradeSide"],
+		"price":      fakeNum(price),
+		"baseVolume": order["size"],
+		"feeDetail":  []map[string]string{{"feeCoin": "USDT", "totalFee": fakeNum(fee)}},
+		"tradeScope": "taker",
+		"cTime":      order["cTime"],
+	})
+}
+
+func (api *fakeBitgetAPI) placeOrder(w http.ResponseWriter, r *http.Request, params fakeBitgetParams) {
+	symbol, spec, ok := api.symbolOf(params)
+	if !ok {
+		writeBitgetError(w, bitgetErrSymbolNotFound)
+		return
+	}
+	if api.ex.rejectOrders {
+		writeBitgetError(w, bitgetErrInsufficient)
+		return
+	}
+	quantity := params.num("size")
+	if !api.ex.validQuantity(symbol, quantity) {
+		writeBitgetError(w, bitgetErrMinQuantity)
+		return
+	}
+	side, tradeSide, orderType := params.str("side"), params.str("tradeSide"), params.str("orderType")
+	marginMode := params.str("marginMode")
+	if (side != "buy" && side != "sell") || (orderType != "market" && orderType != "limit") ||
+		(marginMode != "crossed" && marginMode != "isolated") {
+		writeBitgetError(w, bitgetErrParam)
+		return
+	}
+	if api.hedgeMode && tradeSide != "open" && tradeSide != "close" {
+		writeBitgetError(w, bitgetErrParam)
+		return
+	}
+	price := params.num("price")
+	force := params.str("force")
+	if orderType == "limit" && (price <= 0 || force == "") {
+		writeBitgetError(w, bitgetErrParam)
+		return
+	}
+	if force == "" {
+		force = "gtc"
+	}
+
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	id := strconv.FormatInt(api.ex.newID(), 10)
+	posSide := "net"
+	if api.hedgeMode {
+		posSide = "long"
+		if side == "sell" {
+			posSide = "short"
+		}
+	}
+	order := map[string]interface{}{
+		"orderId":    id,
+		"symbol":     symbol,
+		"size":       fakeNum(quantity),
+		"price":      fakeNum(price),
+		"state":      "live",
+		"side":       side,
+		"tradeSide":  tradeSide,
+		"posSide":    posSide,
+		"force":      force,
+		"orderType":  orderType,
+		"marginMode": marginMode,
+		"baseVolume": "0",
+		"priceAvg":   "",
+		"fee":        "0",
+		"cTime":      now,
+		"uTime":      now,
+	}
+
+	// 开空、平多为卖出，其余为买入
+	isBuy := (side == "buy") != (api.hedgeMode && tradeSide == "close")
+	crosses := orderType == "market" || (isBuy && price >= spec.Price) || (!isBuy && price <= spec.Price)
+	switch {
+	case !crosses && (force == "ioc" || force == "fok"):
+		order["state"] = "canceled"
+	case !crosses:
+		// 未穿过盘口的限价单挂单等待（本模拟不撮合挂单）
+	case force == "post_only":
+		order["state"] = "canceled"
+	default:
+		if code := api.execute(symbol, side, tradeSide, quantity, params.str("reduceOnly") == "YES"); code != "" {
+			writeBitgetError(w, code)
+			return
+		}
+		api.fill(order, symbol)
+	}
+
+	api.orders[id] = order
+	writeBitgetResult(w, map[string]string{"orderId": id, "clientOid": params.str("clientOid")})
+}
+
+// orderOf 按orderId查找订单
+func (api *fakeBitgetAPI) orderOf(params fakeBitgetParams) (map[string]interface{}, bool) {
+	order, ok := api.orders[params.str("orderId")]
+	return order, ok
+}
+
+func (api *fakeBitgetAPI) orderDetail(w http.ResponseWriter, r *http.Request, params fakeBitgetParams) {
+	order, ok := api.orderOf(params)
+	if !ok {
+		writeBitgetError(w, bitgetErrOrderNotFound)
+		return
+	}
+	writeBitgetResult(w, order)
+}
+
+// modifyOrder newSize和newPrice必须同时提交，修改后订单ID不变
+func (api *fakeBitgetAPI) modifyOrder(w http.ResponseWriter, r *http.Request, params fakeBitgetParams) {
+	order, ok := api.orderOf(params)
+	if !ok || order["state"] != "live" {
+		writeBitgetError(w, bitgetErrOrderNotFound)
+		return
+	}
+	size, price := params.num("newSize"), params.num("newPrice")
+	if params.str("newClientOid") == "" || price <= 0 || !api.ex.validQuantity(order["symbol"].(string), size) {
+		writeBitgetError(w, bitgetErrParam)
+		return
+	}
+	order["size"], order["price"] = fakeNum(size), fakeNum(price)
+	order["uTime"] = strconv.FormatInt(time.Now().UnixMilli(), 10)
+	writeBitgetResult(w, map[string]string{"orderId": order["orderId"].(string), "clientOid": params.str("newClientOid")})
+}
+
+func (api *fakeBitgetAPI) cancel(order map[string]interface{}) {
+	order["state"] = "canceled"
+	order["uTime"] = strconv.FormatInt(time.Now().UnixMilli(), 10)
+}
+
+func (api *fakeBitgetAPI) cancelOrder(w http.ResponseWriter, r *http.Request, params fakeBitgetParams) {
+	order, ok := api.orderOf(params)
+	if !ok || order["state"] != "live" {
+		writeBitgetError(w, bitgetErrOrderNotFound)
+		return
+	}
+	api.cancel(order)
+	writeBitgetResult(w, map[string]string{"orderId": order["orderId"].(string)})
+}
+
+// openOrders 未成交订单（symbol为空表示所有交易对），按订单ID排序
+func (api *fakeBitgetAPI) openOrders(symbol string) []map[string]interface{} {
+	list := []map[string]interface{}{}
+	for _, order := range api.orders {
+		if order["state"] == "live" && (symbol == "" || order["symbol"] == symbol) {
+			list = append(list, order)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, _ := strconv.ParseInt(list[i]["orderId"].(string), 10, 64)
+		b, _ := strconv.ParseInt(list[j]["orderId"].(string), 10, 64)
+		return a < b
+	})
+	return list
+}
+
+// batchCancelOrders 不传orderIdList时撤销该交易对的所有挂单，没有挂单时返回22001
+func (api *fakeBitgetAPI) batchCancelOrders(w http.ResponseWriter, r *http.Request, params fakeBitgetParams) {
+	symbol, _, ok := api.symbolOf(params)
+	if !ok {
+		writeBitgetError(w, bitgetErrSymbolNotFound)
+		return
+	}
+	cancelled := api.openOrders(symbol)
+	if len(cancelled) == 0 {
+		writeBitgetError(w, bitgetErrNoOrder)
+		return
+	}
+	successList := []map[string]interface{}{}
+	for _, order := range cancelled {
+		api.cancel(order)
+		successList = append(successList, map[string]interface{}{"orderId": order["orderId"]})
+	}
+	writeBitgetResult(w, map[string]interface{}{"successList": successList, "failureList": []interface{}{}})
+}
+
+// ordersPending 没有挂单时entrustedList为null
+func (api *fakeBitgetAPI) ordersPending(w http.ResponseWriter, r *http.Request, params fakeBitgetParams) {
+	var list []map[string]interface{}
+	if open := api.openOrders(params.str("symbol")); len(open) > 0 {
+		list = open
+	}
+	writeBitgetResult(w, map[string]interface{}{"entrustedList": list, "endId": nil})
+}
+
+// orderFills 成交记录，支持按交易对、订单ID和开始时间(毫秒)过滤
+func (api *fakeBitgetAPI) orderFills(w http.ResponseWriter, r *http.Request, params fakeBitgetParams) {
+	symbol, orderID := params.str("symbol"), params.str("orderId")
+	startTime, _ := strconv.ParseInt(params.str("startTime"), 10, 64)
+
+	list := []map[string]interface{}{}
+	for _, fill := range api.fills {
+		cTime, _ := strconv.ParseInt(fill["cTime"].(string), 10, 64)
+		if symbol != "" && fill["symbol"] != symbol {
+			continue
+		}
+		if orderID != "" && fill["orderId"] != orderID {
+			continue
+		}
+		if cTime < startTime {
+			continue
+		}
+		list = append(list, fill)
+	}
+	writeBitgetResult(w, map[string]interface{}{"fillList": list, "endId": nil})
+}
+
+// placeTPSLOrder 止盈止损单：planType决定类型，holdSide为要平掉的持仓方向
+func (api *fakeBitgetAPI) placeTPSLOrder(w http.ResponseWriter, r *http.Request, params fakeBitgetParams) {
+	symbol, _, ok := api.symbolOf(params)
+	if !ok {
+		writeBitgetError(w, bitgetErrSymbolNotFound)
+		return
+	}
+	kind := map[string]string{"loss_plan": "sl", "profit_plan": "tp"}[params.str("planType")]
+	holdSide := params.str("holdSide")
+	triggerPrice := params.num("triggerPrice")
+	quantity := params.num("size")
+	if kind == "" || (holdSide != "long" && holdSide != "short") || triggerPrice <= 0 ||
+		params.str("triggerType") != "fill_price" || !api.ex.validQuantity(symbol, quantity) {
+		writeBitgetError(w, bitgetErrParam)
+		return
+	}
+
+	id := api.ex.addTrigger(fakeTrigger{
+		Symbol:       symbol,
+		PositionSide: holdSide,
+		Kind:         kind,
+		TriggerPrice: triggerPrice,
+		Quantity:     roundFakeQuantity(math.Abs(quantity)),
+		ReduceOnly:   true,
+	})
+	writeBitgetResult(w, map[string]string{"orderId": strconv.FormatInt(id, 10)})
+}
+
+// plansPending 未触发的止盈止损单，没有时entrustedList为null
+func (api *fakeBitgetAPI) plansPending(w http.ResponseWriter, r *http.Request, params fakeBitgetParams) {
+	if params.str("planType") != "profit_loss" {
+		writeBitgetError(w, bitgetErrParam)
+		return
+	}
+	symbol := params.str("symbol")
+
+	var list []map[string]interface{}
+	for _, trigger := range api.ex.triggers {
+		planType := map[string]string{"sl": "loss_plan", "tp": "profit_plan"}[trigger.Kind]
+		if planType == "" || (symbol != "" && trigger.Symbol != symbol) {
+			continue
+		}
+		list = append(list, map[string]interface{}{
+			"orderId":      strconv.FormatInt(trigger.ID, 10),
+			"symbol":       trigger.Symbol,
+			"planType":     planType,
+			"triggerPrice": fakeNum(trigger.TriggerPrice),
+			"size":         fakeNum(trigger.Quantity),
+			"posSide":      trigger.PositionSide,
+			"planStatus":   "live",
+		})
+	}
+	writeBitgetResult(w, map[string]interface{}{"entrustedList": list, "endId": nil})
+}
+
+// cancelPlanOrder planType=profit_loss撤销该交易对的所有止盈止损单，没有条件单时返回22001
+// 传入orderIdList时只撤销指定的条件单，撤销失败的放在failureList中
+func (api *fakeBitgetAPI) cancelPlanOrder(w http.ResponseWriter, r *http.Request, params fakeBitgetParams) {
+	symbol, _, ok := api.symbolOf(params)
+	if !ok || params.str("planType") != "profit_loss" {
+		writeBitgetError(w, bitgetErrParam)
+		return
+	}
+	if idList, ok := params["orderIdList"].([]interface{}); ok {
+		success, failure := []interface{}{}, []interface{}{}
+		for _, item := range idList {
+			orderID, _ := item.(map[string]interface{})["orderId"].(string)
+			id, err := strconv.ParseInt(orderID, 10, 64)
+			if err != nil || !api.ex.cancelTrigger(id) {
+				failure = append(failure, map[string]string{"orderId": orderID, "errorCode": bitgetErrOrderNotFound, "errorMsg": "order does not exist"})
+				continue
+			}
+			success = append(success, map[string]string{"orderId": orderID})
+		}
+		writeBitgetResult(w, map[string]interface{}{"successList": success, "failureList": failure})
+		return
+	}
+	found := false
+	for _, trigger := range api.ex.triggers {
+		if trigger.Symbol == symbol {
+			found = true
+			break
+		}
+	}
+	if !found {
+		writeBitgetError(w, bitgetErrNoOrder)
+		return
+	}
+	api.ex.cancelAll(symbol)
+	writeBitgetResult(w, map[string]interface{}{"successList": []interface{}{}, "failureList": []interface{}{}})
+}
@@ -0,0 +1,661 @@
+package trader
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeBybitSecret 模拟服务器校验签名使用的Secret
+const fakeBybitSecret = "test-secret"
+
+// Bybit V5接口的原生错误
+const (
+	bybitErrParams                  = 10001
+	bybitErrSign                    = 10004
+	bybitErrOrderNotExists          = 110001
+	bybitErrInsufficientBalance     = 110007
+	bybitErrReduceOnly              = 110017
+	bybitErrPositionExists          = 110024
+	bybitErrPositionModeNotModified = 110025
+	bybitErrMarginModeNotModified   = 110026
+	bybitErrMarginModeLocked        = 110027
+	bybitErrLeverageNotModified     = 110043
+)
+
+var bybitErrorMessages = map[int]string{
+	bybitErrParams:                  "params error",
+	bybitErrSign:                    "error sign! origin_string[...]",
+	bybitErrOrderNotExists:          "order not exists or too late to cancel",
+	bybitErrInsufficientBalance:     "ab not enough for new order",
+	bybitErrReduceOnly:              "current position is zero, cannot fix reduce-only order qty",
+	bybitErrPositionExists:          "You have an existing position, so position mode cannot be switched",
+	bybitErrPositionModeNotModified: "Position mode is not modified",
+	bybitErrMarginModeNotModified:   "Cross/isolated margin mode is not modified",
+	bybitErrMarginModeLocked:        "Margin mode cannot be switched with an existing position",
+	bybitErrLeverageNotModified:     "leverage not modified",
+}
+
+// fakeBybitTakerFee 模拟的taker手续费率
+const fakeBybitTakerFee = 0.00055
+
+// fakeBybitAPI 模拟Bybit V5 USDT永续合约接口（账户初始为单向持仓模式）
+type fakeBybitAPI struct {
+	ex        *fakeExchange
+	hedgeMode bool
+
+	// 订单和成交记录（字段名与Bybit接口一致）
+	orders     map[string]map[string]interface{}
+	executions []map[string]interface{}
+}
+
+// newFakeBybitServer 启动模拟Bybit API的本地服务器，所有私有接口都会校验V5签名
+func newFakeBybitServer(t *testing.T, ex *fakeExchange) (*httptest.Server, *fakeBybitAPI) {
+	api := &fakeBybitAPI{ex: ex, orders: make(map[string]map[string]interface{})}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v5/market/instruments-info", api.instruments)
+	mux.HandleFunc("/v5/market/tickers", api.tickers)
+	mux.HandleFunc("/v5/account/wallet-balance", api.signed(api.walletBalance))
+	mux.HandleFunc("/v5/position/list", api.signed(api.positionList))
+	mux.HandleFunc("/v5/position/switch-mode", api.signed(api.switchMode))
+	mux.HandleFunc("/v5/position/set-leverage", api.signed(api.setLeverage))
+	mux.HandleFunc("/v5/position/switch-isolated", api.signed(api.switchIsolated))
+	mux.HandleFunc("/v5/position/trading-stop", api.signed(api.tradingStop))
+	mux.HandleFunc("/v5/order/create", api.signed(api.createOrder))
+	mux.HandleFunc("/v5/order/amend", api.signed(api.amendOrder))
+	mux.HandleFunc("/v5/order/cancel", api.signed(api.cancelOrder))
+	mux.HandleFunc("/v5/order/cancel-all", api.signed(api.cancelAllOrders))
+	mux.HandleFunc("/v5/order/realtime", api.signed(api.realtimeOrders))
+	mux.HandleFunc("/v5/order/history", api.signed(api.historyOrders))
+	mux.HandleFunc("/v5/execution/list", api.signed(api.executionList))
+
+	srv := httptest.NewServer(ex.locked(mux))
+	t.Cleanup(srv.Close)
+	return srv, api
+}
+
+// isHedgeMode 供测试断言使用，会自行加锁
+func (api *fakeBybitAPI) isHedgeMode() bool {
+	api.ex.mu.Lock()
+	defer api.ex.mu.Unlock()
+	return api.hedgeMode
+}
+
+func writeBybitResult(w http.ResponseWriter, result interface{}) {
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"retCode":    0,
+		"retMsg":     "OK",
+		"result":     result,
+		"retExtInfo": map[string]interface{}{},
+		"time":       time.Now().UnixMilli(),
+	})
+}
+
+func writeBybitError(w http.ResponseWriter, retCode int) {
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"retCode":    retCode,
+		"retMsg":     bybitErrorMessages[retCode],
+		"result":     map[string]interface{}{},
+		"retExtInfo": map[string]interface{}{},
+		"time":       time.Now().UnixMilli(),
+	})
+}
+
+// fakeBybitRequest 签名校验通过后的请求参数（GET为querystring，POST为JSON body）
+type fakeBybitRequest map[string]interface{}
+
+func (r fakeBybitRequest) str(key string) string {
+	switch v := r[key].(type) {
+	case string:
+		return v
+	case float64:
+		return fakeNum(v)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+func (r fakeBybitRequest) num(key string) float64 {
+	value, _ := strconv.ParseFloat(r.str(key), 64)
+	return value
+}
+
+func (r fakeBybitRequest) flag(key string) bool {
+	return r.str(key) == "true"
+}
+
+// signed 校验X-BAPI-*请求头和签名，签名原文为 timestamp + apiKey + recvWindow + querystring/body
+func (api *fakeBybitAPI) signed(handler func(http.ResponseWriter, fakeBybitRequest)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payload := r.URL.RawQuery
+		params := fakeBybitRequest{}
+		if r.Method == http.MethodPost {
+			body, _ := io.ReadAll(r.Body)
+			payload = string(body)
+			if err := json.Unmarshal(body, &params); err != nil {
+				writeBybitError(w, bybitErrParams)
+				return
+			}
+		} else {
+			for key := range r.URL.Query() {
+				params[key] = r.URL.Query().Get(key)
+			}
+		}
+
+		mac := hmac.New(sha256.New, []byte(fakeBybitSecret))
+		mac.Write([]byte(r.Header.Get("X-BAPI-TIMESTAMP") + r.Header.Get("X-BAPI-API-KEY") + r.Header.Get("X-BAPI-RECV-WINDOW") + payload))
+		if r.Header.Get("X-BAPI-TIMESTAMP") == "" || r.Header.Get("X-BAPI-SIGN") != hex.EncodeToString(mac.Sum(nil)) {
+			writeBybitError(w, bybitErrSign)
+			return
+		}
+		if params.str("category") != "" && params.str("category") != "linear" {
+			writeBybitError(w, bybitErrParams)
+			return
+		}
+		handler(w, params)
+	}
+}
+
+func (api *fakeBybitAPI) sortedSymbols() []string {
+	symbols := make([]string, 0, len(api.ex.specs))
+	for symbol := range api.ex.specs {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+func (api *fakeBybitAPI) instruments(w http.ResponseWriter, r *http.Request) {
+	list := []map[string]interface{}{}
+	symbol := r.URL.Query().Get("symbol")
+	if spec, ok := api.ex.specs[symbol]; ok {
+		list = append(list, map[string]interface{}{
+			"symbol": symbol,
+			"status": "Trading",
+			"lotSizeFilter": map[string]interface{}{
+				"qtyStep":     fakeNum(spec.QtyStep),
+				"minOrderQty": fakeNum(spec.QtyStep),
+				"maxOrderQty": "1000",
+			},
+			"priceFilter": map[string]interface{}{
+				"tickSize": fakeNum(spec.TickSize),
+			},
+		})
+	}
+	writeBybitResult(w, map[string]interface{}{"category": "linear", "list": list})
+}
+
+func (api *fakeBybitAPI) tickers(w http.ResponseWriter, r *http.Request) {
+	list := []map[string]interface{}{}
+	symbol := r.URL.Query().Get("symbol")
+	if spec, ok := api.ex.specs[symbol]; ok {
+		list = append(list, map[string]interface{}{
+			"symbol":    symbol,
+			"lastPrice": fakeNum(spec.Price),
+			"markPrice": fakeNum(spec.Price),
+		})
+	}
+	writeBybitResult(w, map[string]interface{}{"category": "linear", "list": list})
+}
+
+func (api *fakeBybitAPI) walletBalance(w http.ResponseWriter, req fakeBybitRequest) {
+	if req.str("accountType") != "UNIFIED" {
+		writeBybitError(w, bybitErrParams)
+		return
+	}
+	upl := api.ex.totalUnrealizedProfit()
+	writeBybitResult(w, map[string]interface{}{"list": []map[string]interface{}{{
+		"accountType":           "UNIFIED",
+		"totalEquity":           fakeNum(api.ex.balance + upl),
+		"totalWalletBalance":    fakeNum(api.ex.balance),
+		"totalAvailableBalance": fakeNum(api.ex.balance + upl - api.ex.usedMargin()),
+		"totalPerpUPL":          fakeNum(upl),
+	}}})
+}
+
+// positionEntry 构造持仓记录（pos为nil时返回数量为0的记录，Bybit按symbol查询时也会返回）
+func (api *fakeBybitAPI) positionEntry(symbol string, positionIdx int, pos *fakePosition) map[string]interface{} {
+	entry := map[string]interface{}{
+		"positionIdx":   positionIdx,
+		"symbol":        symbol,
+		"side":          "",
+		"size":          "0",
+		"avgPrice":      "0",
+		"markPrice":     fakeNum(api.ex.specs[symbol].Price),
+		"unrealisedPnl": "0",
+		"leverage":      strconv.Itoa(api.ex.leverageOf(symbol)),
+		"liqPrice":      "",
+		"tradeMode":     0,
+	}
+	if api.ex.marginModeOf(symbol) == "isolated" {
+		entry["tradeMode"] = 1
+	}
+	if pos != nil {
+		entry["side"] = "Buy"
+		if pos.Side == "short" {
+			entry["side"] = "Sell"
+		}
+		entry["size"] = fakeNum(pos.Quantity)
+		entry["avgPrice"] = fakeNum(pos.EntryPrice)
+		entry["unrealisedPnl"] = fakeNum(api.ex.unrealizedProfit(pos))
+		entry["leverage"] = strconv.Itoa(pos.Leverage)
+	}
+	return entry
+}
+
+func (api *fakeBybitAPI) positionList(w http.ResponseWriter, req fakeBybitRequest) {
+	symbols := api.sortedSymbols()
+	symbol := req.str("symbol")
+	if symbol != "" {
+		if _, ok := api.ex.specs[symbol]; !ok {
+			writeBybitError(w, bybitErrParams)
+			return
+		}
+		symbols = []string{symbol}
+	} else if req.str("settleCoin") != "USDT" {
+		writeBybitError(w, bybitErrParams)
+		return
+	}
+
+	list := []map[string]interface{}{}
+	for _, s := range symbols {
+		var entries []map[string]interface{}
+		if api.hedgeMode {
+			entries = append(entries,
+				api.positionEntry(s, 1, api.ex.positions[s+"_long"]),
+				api.positionEntry(s, 2, api.ex.positions[s+"_short"]))
+		} else {
+			entries = append(entries, api.positionEntry(s, 0, api.ex.netPosition(s)))
+		}
+		for _, entry := range entries {
+			// 按结算币种查询时只返回有持仓的记录
+			if symbol == "" && entry["size"] == "0" {
+				continue
+			}
+			list = append(list, entry)
+		}
+	}
+	writeBybitResult(w, map[string]interface{}{"category": "linear", "list": list, "nextPageCursor": ""})
+}
+
+func (api *fakeBybitAPI) switchMode(w http.ResponseWriter, req fakeBybitRequest) {
+	hedge := req.num("mode") == 3
+	if hedge == api.hedgeMode {
+		writeBybitError(w, bybitErrPositionModeNotModified)
+		return
+	}
+	if len(api.ex.positions) > 0 {
+		writeBybitError(w, bybitErrPositionExists)
+		return
+	}
+	api.hedgeMode = hedge
+	writeBybitResult(w, map[string]interface{}{})
+}
+
+func (api *fakeBybitAPI) setLeverage(w http.ResponseWriter, req fakeBybitRequest) {
+	symbol := req.str("symbol")
+	leverage, err := strconv.Atoi(req.str("buyLeverage"))
+	if _, ok := api.ex.specs[symbol]; !ok || err != nil || req.str("sellLeverage") != req.str("buyLeverage") {
+		writeBybitError(w, bybitErrParams)
+		return
+	}
+	if api.ex.leverageOf(symbol) == leverage {
+		writeBybitError(w, bybitErrLeverageNotModified)
+		return
+	}
+	api.ex.setLeverage(symbol, leverage)
+	writeBybitResult(w, map[string]interface{}{})
+}
+
+func (api *fakeBybitAPI) switchIsolated(w http.ResponseWriter, req fakeBybitRequest) {
+	symbol := req.str("symbol")
+	leverage, err := strconv.Atoi(req.str("buyLeverage"))
+	if _, ok := api.ex.specs[symbol]; !ok || err != nil {
+		writeBybitError(w, bybitErrParams)
+		return
+	}
+	mode := "cross"
+	if req.num("tradeMode") == 1 {
+		mode = "isolated"
+	}
+	if api.ex.marginModeOf(symbol) == mode {
+		writeBybitError(w, bybitErrMarginModeNotModified)
+		return
+	}
+	if err := api.ex.setMarginMode(symbol, mode); err != nil {
+		writeBybitError(w, bybitErrMarginModeLocked)
+		return
+	}
+	api.ex.setLeverage(symbol, leverage)
+	writeBybitResult(w, map[string]interface{}{})
+}
+
+// tradingStop 设置仓位级别的跟踪止损（trailingStop为价格距离，"0"表示取消）
+func (api *fakeBybitAPI) tradingStop(w http.ResponseWriter, req fakeBybitRequest) {
+	symbol := req.str("symbol")
+	side := "long"
+	if int(req.num("positionIdx")) == bybitPositionIdxShort {
+		side = "short"
+	}
+	pos, ok := api.ex.positions[symbol+"_"+side]
+	if !ok || req.str("trailingStop") == "" {
+		writeBybitError(w, bybitErrParams)
+		return
+	}
+
+	for _, trigger := range api.ex.triggers {
+		if trigger.Symbol == symbol && trigger.PositionSide == side && trigger.Kind == "trailing" {
+			api.ex.cancelTrigger(trigger.ID)
+			break
+		}
+	}
+	if distance := req.num("trailingStop"); distance > 0 {
+		reference := req.num("activePrice")
+		if reference == 0 {
+			reference = api.ex.specs[symbol].Price
+		}
+		api.ex.addTrigger(fakeTrigger{
+			Symbol:          symbol,
+			PositionSide:    side,
+			Kind:            "trailing",
+			Quantity:        pos.Quantity,
+			ClosePosition:   true,
+			CallbackRate:    distance / reference,
+			ActivationPrice: req.num("activePrice"),
+		})
+	}
+	writeBybitResult(w, map[string]interface{}{})
+}
+
+// fill 按当前价格成交订单，记录成交并更新订单状态
+func (api *fakeBybitAPI) fill(order map[string]interface{}, quantity float64) {
+	price := api.ex.specs[order["symbol"].(string)].Price
+	fee := price * quantity * fakeBybitTakerFee
+	order["orderStatus"] = "Filled"
+	order["cumExecQty"] = fakeNum(quantity)
+	order["avgPrice"] = fakeNum(price)
+	order["cumExecFee"] = fakeNum(fee)
+	api.executions = append(api.executions, map[string]interface{}{
+		"execId":      strconv.FormatInt(api.ex.newID(), 10),
+		"orderId":     order["orderId"],
+		"symbol":      order["symbol"],
+		"side":        order["side"],
+		"execPrice":   fakeNum(price),
+		"execQty":     fakeNum(quantity),
+		"execFee":     fakeNum(fee),
+		"feeCurrency": "USDT",
+		"execType":    "Trade",
+		"isMaker":     false,
+		"execTime":    order["updatedTime"],
+	})
+}
+
+// execute 市价成交：双向持仓按positionIdx开平仓，单向持仓按买卖方向净额成交
+func (api *fakeBybitAPI) execute(symbol string, isBuy bool, positionIdx int, quantity float64, reduceOnly bool) int {
+	if !api.hedgeMode {
+		if err := api.ex.trade(symbol, isBuy, quantity, reduceOnly); err != nil {
+			return bybitErrReduceOnly
+		}
+		return 0
+	}
+
+	side := "long"
+	if positionIdx == 2 {
+		side = "short"
+	}
+	closing := isBuy == (side == "short")
+	if closing {
+		if err := api.ex.reduce(symbol, side, quantity); err != nil {
+			return bybitErrReduceOnly
+		}
+		return 0
+	}
+	if reduceOnly {
+		return bybitErrReduceOnly
+	}
+	api.ex.open(symbol, side, quantity)
+	return 0
+}
+
+func (api *fakeBybitAPI) createOrder(w http.ResponseWriter, req fakeBybitRequest) {
+	symbol := req.str("symbol")
+	spec, ok := api.ex.specs[symbol]
+	if !ok {
+		writeBybitError(w, bybitErrParams)
+		return
+	}
+	if api.ex.rejectOrders {
+		writeBybitError(w, bybitErrInsufficientBalance)
+		return
+	}
+
+	// 双向持仓必须指定positionIdx=1/2，单向持仓只能是0
+	positionIdx := int(req.num("positionIdx"))
+	if api.hedgeMode == (positionIdx == 0) || positionIdx < 0 || positionIdx > 2 {
+		writeBybitError(w, bybitErrParams)
+		return
+	}
+	quantity := req.num("qty")
+	if !api.ex.validQuantity(symbol, quantity) {
+		writeBybitError(w, bybitErrParams)
+		return
+	}
+	side := req.str("side")
+	isBuy := side == "Buy"
+	orderType := req.str("orderType")
+	if (side != "Buy" && side != "Sell") || (orderType != "Market" && orderType != "Limit") {
+		writeBybitError(w, bybitErrParams)
+		return
+	}
+
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	order := map[string]interface{}{
+		"symbol":       symbol,
+		"orderStatus":  "New",
+		"orderType":    orderType,
+		"side":         side,
+		"positionIdx":  positionIdx,
+		"timeInForce":  req.str("timeInForce"),
+		"price":        "0",
+		"triggerPrice": "",
+		"qty":          req.str("qty"),
+		"cumExecQty":   "0",
+		"avgPrice":     "",
+		"cumExecFee":   "0",
+		"reduceOnly":   req.flag("reduceOnly"),
+		"createdTime":  now,
+		"updatedTime":  now,
+	}
+	if orderType == "Limit" {
+		order["price"] = req.str("price")
+	}
+
+	if triggerPrice := req.num("triggerPrice"); triggerPrice > 0 {
+		// 条件单：由触发方向和持仓方向判断是止损还是止盈
+		positionSide := "long"
+		if positionIdx == 2 || (positionIdx == 0 && isBuy) {
+			positionSide = "short"
+		}
+		direction := int(req.num("triggerDirection"))
+		if direction != 1 && direction != 2 {
+			writeBybitError(w, bybitErrParams)
+			return
+		}
+		kind := "tp"
+		if (positionSide == "long") == (direction == 2) {
+			kind = "sl"
+		}
+		id := api.ex.addTrigger(fakeTrigger{
+			Symbol:       symbol,
+			PositionSide: positionSide,
+			Kind:         kind,
+			TriggerPrice: triggerPrice,
+			Quantity:     quantity,
+			ReduceOnly:   req.flag("reduceOnly"),
+		})
+		order["orderId"] = strconv.FormatInt(id, 10)
+		order["orderStatus"] = "Untriggered"
+		order["triggerPrice"] = req.str("triggerPrice")
+		order["triggerDirection"] = direction
+		api.orders[order["orderId"].(string)] = order
+		writeBybitResult(w, map[string]interface{}{"orderId": order["orderId"], "orderLinkId": ""})
+		return
+	}
+
+	order["orderId"] = strconv.FormatInt(api.ex.newID(), 10)
+	api.orders[order["orderId"].(string)] = order
+
+	crosses := true
+	if orderType == "Limit" {
+		limitPrice := req.num("price")
+		crosses = (isBuy && limitPrice >= spec.Price) || (!isBuy && limitPrice <= spec.Price)
+	}
+	switch tif := req.str("timeInForce"); {
+	case !crosses && (tif == "IOC" || tif == "FOK"):
+		order["orderStatus"] = "Cancelled"
+	case !crosses:
+		// 未穿过盘口的限价单挂单等待（本模拟不撮合挂单）
+	case orderType == "Limit" && tif == "PostOnly":
+		order["orderStatus"] = "Cancelled"
+	default:
+		if code := api.execute(symbol, isBuy, positionIdx, quantity, req.flag("reduceOnly")); code != 0 {
+			delete(api.orders, order["orderId"].(string))
+			writeBybitError(w, code)
+			return
+		}
+		api.fill(order, quantity)
+	}
+
+	writeBybitResult(w, map[string]interface{}{"orderId": order["orderId"], "orderLinkId": ""})
+}
+
+// activeOrder 查找仍可修改或撤销的订单
+func (api *fakeBybitAPI) activeOrder(req fakeBybitRequest) (map[string]interface{}, bool) {
+	order, ok := api.orders[req.str("orderId")]
+	if !ok || order["symbol"] != req.str("symbol") {
+		return nil, false
+	}
+	status := order["orderStatus"]
+	return order, status == "New" || status == "PartiallyFilled" || status == "Untriggered"
+}
+
+func (api *fakeBybitAPI) amendOrder(w http.ResponseWriter, req fakeBybitRequest) {
+	order, ok := api.activeOrder(req)
+	if !ok {
+		writeBybitError(w, bybitErrOrderNotExists)
+		return
+	}
+	if qty := req.str("qty"); qty != "" {
+		if !api.ex.validQuantity(req.str("symbol"), req.num("qty")) {
+			writeBybitError(w, bybitErrParams)
+			return
+		}
+		order["qty"] = qty
+	}
+	if price := req.str("price"); price != "" {
+		order["price"] = price
+	}
+	order["updatedTime"] = strconv.FormatInt(time.Now().UnixMilli(), 10)
+	writeBybitResult(w, map[string]interface{}{"orderId": order["orderId"], "orderLinkId": ""})
+}
+
+// cancel 撤销订单（条件单撤销后状态为Deactivated）
+func (api *fakeBybitAPI) cancel(order map[string]interface{}) {
+	if order["orderStatus"] == "Untriggered" {
+		id, _ := strconv.ParseInt(order["orderId"].(string), 10, 64)
+		api.ex.cancelTrigger(id)
+		order["orderStatus"] = "Deactivated"
+		return
+	}
+	order["orderStatus"] = "Cancelled"
+}
+
+func (api *fakeBybitAPI) cancelOrder(w http.ResponseWriter, req fakeBybitRequest) {
+	order, ok := api.activeOrder(req)
+	if !ok {
+		writeBybitError(w, bybitErrOrderNotExists)
+		return
+	}
+	api.cancel(order)
+	writeBybitResult(w, map[string]interface{}{"orderId": order["orderId"], "orderLinkId": ""})
+}
+
+func (api *fakeBybitAPI) cancelAllOrders(w http.ResponseWriter, req fakeBybitRequest) {
+	symbol := req.str("symbol")
+	filter := req.str("orderFilter")
+	cancelled := []map[string]interface{}{}
+	for _, order := range api.orders {
+		status := order["orderStatus"]
+		if order["symbol"] != symbol {
+			continue
+		}
+		conditional := status == "Untriggered"
+		if (conditional && filter == "Order") || (!conditional && filter == "StopOrder") {
+			continue
+		}
+		if conditional || status == "New" || status == "PartiallyFilled" {
+			api.cancel(order)
+			cancelled = append(cancelled, map[string]interface{}{"orderId": order["orderId"], "orderLinkId": ""})
+		}
+	}
+	writeBybitResult(w, map[string]interface{}{"list": cancelled, "success": "1"})
+}
+
+func (api *fakeBybitAPI) realtimeOrders(w http.ResponseWriter, req fakeBybitRequest) {
+	list := []map[string]interface{}{}
+	if orderID := req.str("orderId"); orderID != "" {
+		// 按订单ID查询时也返回已结束的订单
+		if order, ok := api.orders[orderID]; ok && order["symbol"] == req.str("symbol") {
+			list = append(list, order)
+		}
+	} else {
+		symbol := req.str("symbol")
+		if symbol == "" && req.str("settleCoin") != "USDT" {
+			writeBybitError(w, bybitErrParams)
+			return
+		}
+		filter := req.str("orderFilter")
+		for _, order := range api.orders {
+			if _, active := api.activeOrder(fakeBybitRequest{"orderId": order["orderId"], "symbol": order["symbol"]}); !active {
+				continue
+			}
+			if conditional := order["orderStatus"] == "Untriggered"; (conditional && filter == "Order") || (!conditional && filter == "StopOrder") {
+				continue
+			}
+			if symbol == "" || order["symbol"] == symbol {
+				list = append(list, order)
+			}
+		}
+		sort.Slice(list, func(i, j int) bool {
+			return list[i]["orderId"].(string) < list[j]["orderId"].(string)
+		})
+	}
+	writeBybitResult(w, map[string]interface{}{"category": "linear", "list": list, "nextPageCursor": ""})
+}
+
+// historyOrders 本模拟的实时订单接口已包含所有订单，历史订单总是为空
+func (api *fakeBybitAPI) historyOrders(w http.ResponseWriter, req fakeBybitRequest) {
+	writeBybitResult(w, map[string]interface{}{"category": "linear", "list": []interface{}{}, "nextPageCursor": ""})
+}
+
+func (api *fakeBybitAPI) executionList(w http.ResponseWriter, req fakeBybitRequest) {
+	symbol := req.str("symbol")
+	startTime := int64(req.num("startTime"))
+	list := []map[string]interface{}{}
+	for _, exec := range api.executions {
+		execTime, _ := strconv.ParseInt(exec["execTime"].(string), 10, 64)
+		if (symbol == "" || exec["symbol"] == symbol) && execTime >= startTime {
+			list = append(list, exec)
+		}
+	}
+	writeBybitResult(w, map[string]interface{}{"category": "linear", "list": list, "nextPageCursor": ""})
+}
@@ -0,0 +1,345 @@
+package trader
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// 本文件提供各交易所模拟服务器共用的撮合状态，具体的接口格式见 fake_*_test.go
+
+var (
+	errFakeInsufficientPosition = errors.New("平仓数量超过持仓")
+	errFakeMarginModeLocked     = errors.New("有持仓时不能切换保证金模式")
+)
+
+// fakeSymbolSpec 模拟交易对规格
+type fakeSymbolSpec struct {
+	Price    float64
+	QtyStep  float64
+	TickSize float64
+}
+
+// fakePosition 模拟交易所中的持仓
+type fakePosition struct {
+	Symbol     string
+	Side       string // long/short
+	Quantity   float64
+	EntryPrice float64
+	Leverage   int
+	MarginMode string // cross/isolated
+}
+
+// fakeTrigger 模拟交易所中的止盈止损条件单
+type fakeTrigger struct {
+	ID            int64
+	Symbol        string
+	PositionSide  string // long/short（该条件单要平掉的持仓方向）
+	Kind          string // sl/tp/trailing
+	TriggerPrice  float64
+	Quantity      float64
+	ReduceOnly    bool
+	ClosePosition bool
+
+	// 跟踪止损参数（Kind为trailing时有效）
+	CallbackRate    float64
+	ActivationPrice float64
+}
+
+// fakeExchange 模拟交易所的账户与撮合状态（所有HTTP请求在同一把锁内处理）
+type fakeExchange struct {
+	mu sync.Mutex
+
+	specs      map[string]fakeSymbolSpec
+	positions  map[string]*fakePosition // key: symbol_side
+	leverage   map[string]int
+	marginMode map[string]string
+	triggers   []fakeTrigger
+	balance    float64
+	nextID     int64
+
+	// rejectOrders 为true时所有下单请求返回交易所原生的保证金不足错误
+	rejectOrders bool
+}
+
+// newFakeExchange 创建模拟交易所（BTC价格50000，数量步长0.001）
+func newFakeExchange() *fakeExchange {
+	return &fakeExchange{
+		specs: map[string]fakeSymbolSpec{
+			"BTCUSDT": {Price: 50000, QtyStep: 0.001, TickSize: 0.1},
+			"ETHUSDT": {Price: 3000, QtyStep: 0.01, TickSize: 0.01},
+		},
+		positions:  make(map[string]*fakePosition),
+		leverage:   make(map[string]int),
+		marginMode: make(map[string]string),
+		balance:    10000,
+		nextID:     1000,
+	}
+}
+
+// locked 串行处理请求，保证撮合状态一致
+func (ex *fakeExchange) locked(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ex.mu.Lock()
+		defer ex.mu.Unlock()
+		h.ServeHTTP(w, r)
+	})
+}
+
+// 以下方法供HTTP处理函数使用，调用方需持有锁
+
+func (ex *fakeExchange) newID() int64 {
+	ex.nextID++
+	return ex.nextID
+}
+
+func (ex *fakeExchange) leverageOf(symbol string) int {
+	if lev, ok := ex.leverage[symbol]; ok {
+		return lev
+	}
+	return 20
+}
+
+func (ex *fakeExchange) marginModeOf(symbol string) string {
+	if mode, ok := ex.marginMode[symbol]; ok {
+		return mode
+	}
+	return "cross"
+}
+
+// setLeverage 调整杠杆，已有持仓同步生效
+func (ex *fakeExchange) setLeverage(symbol string, leverage int) {
+	ex.leverage[symbol] = leverage
+	for _, pos := range ex.positions {
+		if pos.Symbol == symbol {
+			pos.Leverage = leverage
+		}
+	}
+}
+
+// setMarginMode 切换保证金模式（与主流交易所一致，有持仓时不允许切换）
+func (ex *fakeExchange) setMarginMode(symbol, mode string) error {
+	if ex.marginModeOf(symbol) == mode {
+		return nil
+	}
+	for _, pos := range ex.positions {
+		if pos.Symbol == symbol {
+			return errFakeMarginModeLocked
+		}
+	}
+	ex.marginMode[symbol] = mode
+	return nil
+}
+
+// validQuantity 数量必须为正且是步长的整数倍
+func (ex *fakeExchange) validQuantity(symbol string, quantity float64) bool {
+	spec, ok := ex.specs[symbol]
+	if !ok || quantity <= 0 {
+		return false
+	}
+	steps := quantity / spec.QtyStep
+	return math.Abs(steps-math.Round(steps)) < 1e-6
+}
+
+// open 按市价开仓或加仓（双向持仓模式）
+func (ex *fakeExchange) open(symbol, side string, quantity float64) {
+	price := ex.specs[symbol].Price
+	key := symbol + "_" + side
+	pos, ok := ex.positions[key]
+	if !ok {
+		ex.positions[key] = &fakePosition{
+			Symbol:     symbol,
+			Side:       side,
+			Quantity:   quantity,
+			EntryPrice: price,
+			Leverage:   ex.leverageOf(symbol),
+			MarginMode: ex.marginModeOf(symbol),
+		}
+		return
+	}
+	total := roundFakeQuantity(pos.Quantity + quantity)
+	pos.EntryPrice = (pos.EntryPrice*pos.Quantity + price*quantity) / total
+	pos.Quantity = total
+}
+
+// reduce 按市价减仓（双向持仓模式），数量超过持仓时拒绝
+func (ex *fakeExchange) reduce(symbol, side string, quantity float64) error {
+	key := symbol + "_" + side
+	pos, ok := ex.positions[key]
+	if !ok || quantity > pos.Quantity+1e-9 {
+		return errFakeInsufficientPosition
+	}
+	pos.Quantity = roundFakeQuantity(pos.Quantity - quantity)
+	if pos.Quantity <= 0 {
+		delete(ex.positions, key)
+	}
+	return nil
+}
+
+// trade 单向持仓模式下成交一笔买卖：先抵消反向持仓，剩余部分开新仓
+// reduceOnly订单超出反向持仓时拒绝，避免反向开仓
+func (ex *fakeExchange) trade(symbol string, isBuy bool, quantity float64, reduceOnly bool) error {
+	openSide, closeSide := "long", "short"
+	if !isBuy {
+		openSide, closeSide = "short", "long"
+	}
+
+	var existing float64
+	if pos, ok := ex.positions[symbol+"_"+closeSide]; ok {
+		existing = pos.Quantity
+	}
+	if reduceOnly && quantity > existing+1e-9 {
+		return errFakeInsufficientPosition
+	}
+
+	closing := math.Min(quantity, existing)
+	if closing > 0 {
+		if err := ex.reduce(symbol, closeSide, closing); err != nil {
+			return err
+		}
+	}
+	if remaining := quantity - closing; remaining > 1e-9 {
+		ex.open(symbol, openSide, remaining)
+	}
+	return nil
+}
+
+// netPosition 单向持仓模式下的当前持仓（同一时间只有一个方向）
+func (ex *fakeExchange) netPosition(symbol string) *fakePosition {
+	if pos, ok := ex.positions[symbol+"_long"]; ok {
+		return pos
+	}
+	if pos, ok := ex.positions[symbol+"_short"]; ok {
+		return pos
+	}
+	return nil
+}
+
+func (ex *fakeExchange) addTrigger(trigger fakeTrigger) int64 {
+	trigger.ID = ex.newID()
+	ex.triggers = append(ex.triggers, trigger)
+	return trigger.ID
+}
+
+func (ex *fakeExchange) cancelTrigger(id int64) bool {
+	for i, trigger := range ex.triggers {
+		if trigger.ID == id {
+			ex.triggers = append(ex.triggers[:i], ex.triggers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (ex *fakeExchange) cancelAll(symbol string) {
+	kept := ex.triggers[:0]
+	for _, trigger := range ex.triggers {
+		if trigger.Symbol != symbol {
+			kept = append(kept, trigger)
+		}
+	}
+	ex.triggers = kept
+}
+
+// unrealizedProfit 按当前价格计算的未实现盈亏
+func (ex *fakeExchange) unrealizedProfit(pos *fakePosition) float64 {
+	pnl := (ex.specs[pos.Symbol].Price - pos.EntryPrice) * pos.Quantity
+	if pos.Side == "short" {
+		pnl = -pnl
+	}
+	return pnl
+}
+
+// usedMargin 所有持仓占用的保证金
+func (ex *fakeExchange) usedMargin() float64 {
+	var used float64
+	for _, pos := range ex.positions {
+		used += pos.EntryPrice * pos.Quantity / float64(pos.Leverage)
+	}
+	return used
+}
+
+func (ex *fakeExchange) totalUnrealizedProfit() float64 {
+	var pnl float64
+	for _, pos := range ex.positions {
+		pnl += ex.unrealizedProfit(pos)
+	}
+	return pnl
+}
+
+// 以下方法供测试断言使用，会自行加锁
+
+func (ex *fakeExchange) setRejectOrders(reject bool) {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	ex.rejectOrders = reject
+}
+
+// setPrice 修改币种的最新价格（用于模拟行情变化）
+func (ex *fakeExchange) setPrice(symbol string, price float64) {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	spec := ex.specs[symbol]
+	spec.Price = price
+	ex.specs[symbol] = spec
+}
+
+// position 返回某方向持仓的副本
+func (ex *fakeExchange) position(symbol, side string) (fakePosition, bool) {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	pos, ok := ex.positions[symbol+"_"+side]
+	if !ok {
+		return fakePosition{}, false
+	}
+	return *pos, true
+}
+
+// trigger 返回某持仓方向上指定类型（sl/tp/trailing）的条件单
+func (ex *fakeExchange) trigger(symbol, positionSide, kind string) (fakeTrigger, bool) {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	for _, trigger := range ex.triggers {
+		if trigger.Symbol == symbol && trigger.PositionSide == positionSide && trigger.Kind == kind {
+			return trigger, true
+		}
+	}
+	return fakeTrigger{}, false
+}
+
+// triggersOf 返回某币种的所有条件单副本
+func (ex *fakeExchange) triggersOf(symbol string) []fakeTrigger {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	triggers := []fakeTrigger{}
+	for _, trigger := range ex.triggers {
+		if trigger.Symbol == symbol {
+			triggers = append(triggers, trigger)
+		}
+	}
+	return triggers
+}
+
+func (ex *fakeExchange) currentMarginMode(symbol string) string {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	return ex.marginModeOf(symbol)
+}
+
+func (ex *fakeExchange) currentLeverage(symbol string) int {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	return ex.leverageOf(symbol)
+}
+
+// roundFakeQuantity 去掉加减产生的浮点误差（如0.015000000000000001）
+func roundFakeQuantity(quantity float64) float64 {
+	return math.Round(quantity*1e9) / 1e9
+}
+
+// fakeNum 按交易所习惯把数字格式化为字符串
+func fakeNum(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,672 @@
+package trader
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGateSecret 模拟服务器校验签名使用的Secret
+const fakeGateSecret = "test-secret"
+
+// Gate.io API v4的原生错误label
+const (
+	gateErrInvalidParam       = "INVALID_PARAM_VALUE"
+	gateErrInvalidSignature   = "INVALID_SIGNATURE"
+	gateErrContractNotFound   = "CONTRACT_NOT_FOUND"
+	gateErrOrderNotFound      = "ORDER_NOT_FOUND"
+	gateErrInsufficient       = "INSUFFICIENT_AVAILABLE"
+	gateErrReduceExceeded     = "REDUCE_EXCEEDED"
+	gateErrPositionHolding    = "POSITION_HOLDING"
+	gateErrSizeTooSmall       = "SIZE_TOO_SMALL"
+	gateErrDualModeNotEnabled = "POSITION_DUAL_MODE"
+)
+
+var gateErrorMessages = map[string]string{
+	gateErrInvalidParam:       "invalid argument",
+	gateErrInvalidSignature:   "Signature mismatch",
+	gateErrContractNotFound:   "contract not found",
+	gateErrOrderNotFound:      "order not found",
+	gateErrInsufficient:       "Insufficient available balance",
+	gateErrReduceExceeded:     "reduce order size exceeds position size",
+	gateErrPositionHolding:    "position holding, cannot change mode",
+	gateErrSizeTooSmall:       "order size too small",
+	gateErrDualModeNotEnabled: "dual mode is not enabled",
+}
+
+// fakeGateTakerFee 模拟的taker手续费率
+const fakeGateTakerFee = 0.0005
+
+// fakeGateAPI 模拟Gate.io API v4 USDT永续合约接口（账户初始为单仓模式，1张合约对应QtyStep个币）
+type fakeGateAPI struct {
+	ex       *fakeExchange
+	dualMode bool
+
+	// 订单和成交记录（字段名与Gate.io接口一致）
+	orders map[int64]map[string]interface{}
+	trades []map[string]interface{}
+}
+
+// newFakeGateServer 启动模拟Gate.io API的本地服务器，所有私有接口都会校验签名
+func newFakeGateServer(t *testing.T, ex *fakeExchange) (*httptest.Server, *fakeGateAPI) {
+	api := &fakeGateAPI{ex: ex, orders: make(map[int64]map[string]interface{})}
+	const prefix = "/api/v4/futures/usdt"
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+prefix+"/contracts/{contract}", api.contract)
+	mux.HandleFunc("GET "+prefix+"/tickers", api.tickers)
+	mux.HandleFunc("GET "+prefix+"/accounts", api.signed(api.account))
+	mux.HandleFunc("POST "+prefix+"/dual_mode", api.signed(api.setDualMode))
+	mux.HandleFunc("GET "+prefix+"/positions", api.signed(api.positions))
+	mux.HandleFunc("GET "+prefix+"/positions/{contract}", api.signed(api.singlePosition))
+	mux.HandleFunc("POST "+prefix+"/positions/{contract}/leverage", api.signed(api.updateLeverage))
+	mux.HandleFunc("GET "+prefix+"/dual_comp/positions/{contract}", api.signed(api.dualPositions))
+	mux.HandleFunc("POST "+prefix+"/dual_comp/positions/{contract}/leverage", api.signed(api.updateLeverage))
+	mux.HandleFunc("POST "+prefix+"/orders", api.signed(api.createOrder))
+	mux.HandleFunc("GET "+prefix+"/orders", api.signed(api.listOrders))
+	mux.HandleFunc("DELETE "+prefix+"/orders", api.signed(api.cancelOrders))
+	mux.HandleFunc("GET "+prefix+"/orders/{id}", api.signed(api.getOrder))
+	mux.HandleFunc("PUT "+prefix+"/orders/{id}", api.signed(api.amendOrder))
+	mux.HandleFunc("DELETE "+prefix+"/orders/{id}", api.signed(api.cancelOrder))
+	mux.HandleFunc("POST "+prefix+"/price_orders", api.signed(api.createPriceOrder))
+	mux.HandleFunc("GET "+prefix+"/price_orders", api.signed(api.listPriceOrders))
+	mux.HandleFunc("DELETE "+prefix+"/price_orders", api.signed(api.cancelPriceOrders))
+	mux.HandleFunc("DELETE "+prefix+"/price_orders/{id}", api.signed(api.cancelPriceOrder))
+	mux.HandleFunc("GET "+prefix+"/my_trades", api.signed(api.myTrades))
+	mux.HandleFunc("GET "+prefix+"/my_trades_timerange", api.signed(api.myTrades))
+
+	srv := httptest.NewServer(ex.locked(mux))
+	t.Cleanup(srv.Close)
+	return srv, api
+}
+
+// isDualMode 供测试断言使用，会自行加锁
+func (api *fakeGateAPI) isDualMode() bool {
+	api.ex.mu.Lock()
+	defer api.ex.mu.Unlock()
+	return api.dualMode
+}
+
+func writeGateError(w http.ResponseWriter, label string) {
+	status := http.StatusBadRequest
+	switch label {
+	case gateErrInvalidSignature:
+		status = http.StatusUnauthorized
+	case gateErrOrderNotFound, gateErrContractNotFound:
+		status = http.StatusNotFound
+	}
+	writeFakeJSON(w, status, map[string]string{"label": label, "message": gateErrorMessages[label]})
+}
+
+// fakeGateBody 签名校验通过后的JSON请求体
+type fakeGateBody map[string]interface{}
+
+func (b fakeGateBody) str(key string) string {
+	switch v := b[key].(type) {
+	case string:
+		return v
+	case float64:
+		return fakeNum(v)
+	}
+	return ""
+}
+
+func (b fakeGateBody) num(key string) float64 {
+	value, _ := strconv.ParseFloat(b.str(key), 64)
+	return value
+}
+
+func (b fakeGateBody) obj(key string) fakeGateBody {
+	v, _ := b[key].(map[string]interface{})
+	return fakeGateBody(v)
+}
+
+// signed 校验KEY/Timestamp/SIGN请求头，签名原文为 method\npath\nquery\nhex(sha512(body))\ntimestamp
+func (api *fakeGateAPI) signed(handler func(http.ResponseWriter, *http.Request, fakeGateBody)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodyHash := sha512.Sum512(body)
+		payload := strings.Join([]string{r.Method, r.URL.Path, r.URL.RawQuery, hex.EncodeToString(bodyHash[:]), r.Header.Get("Timestamp")}, "\n")
+		mac := hmac.New(sha512.New, []byte(fakeGateSecret))
+		mac.Write([]byte(payload))
+		if r.Header.Get("KEY") == "" || r.Header.Get("Timestamp") == "" || r.Header.Get("SIGN") != hex.EncodeToString(mac.Sum(nil)) {
+			writeGateError(w, gateErrInvalidSignature)
+			return
+		}
+
+		params := fakeGateBody{}
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, &params); err != nil {
+				writeGateError(w, gateErrInvalidParam)
+				return
+			}
+		}
+		handler(w, r, params)
+	}
+}
+
+// symbolOf Gate.io合约名对应的交易对和规格（BTC_USDT -> BTCUSDT）
+func (api *fakeGateAPI) symbolOf(contract string) (string, fakeSymbolSpec, bool) {
+	if !strings.HasSuffix(contract, "_USDT") {
+		return "", fakeSymbolSpec{}, false
+	}
+	symbol := strings.ReplaceAll(contract, "_", "")
+	spec, ok := api.ex.specs[symbol]
+	return symbol, spec, ok
+}
+
+func (api *fakeGateAPI) contract(w http.ResponseWriter, r *http.Request) {
+	contract := r.PathValue("contract")
+	_, spec, ok := api.symbolOf(contract)
+	if !ok {
+		writeGateError(w, gateErrContractNotFound)
+		return
+	}
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"name":              contract,
+		"type":              "direct",
+		"quanto_multiplier": fakeNum(spec.QtyStep),
+		"order_size_min":    1,
+		"order_size_max":    1000000,
+		"order_price_round": fakeNum(spec.TickSize),
+		"mark_price":        fakeNum(spec.Price),
+		"last_price":        fakeNum(spec.Price),
+	})
+}
+
+func (api *fakeGateAPI) tickers(w http.ResponseWriter, r *http.Request) {
+	list := []map[string]interface{}{}
+	contract := r.URL.Query().Get("contract")
+	if _, spec, ok := api.symbolOf(contract); ok {
+		list = append(list, map[string]interface{}{
+			"contract":   contract,
+			"last":       fakeNum(spec.Price),
+			"mark_price": fakeNum(spec.Price),
+		})
+	}
+	writeFakeJSON(w, http.StatusOK, list)
+}
+
+func (api *fakeGateAPI) accountEntry() map[string]interface{} {
+	upl := api.ex.totalUnrealizedProfit()
+	return map[string]interface{}{
+		"total":          fakeNum(api.ex.balance),
+		"unrealised_pnl": fakeNum(upl),
+		"available":      fakeNum(api.ex.balance + upl - api.ex.usedMargin()),
+		"currency":       "USDT",
+		"in_dual_mode":   api.dualMode,
+	}
+}
+
+func (api *fakeGateAPI) account(w http.ResponseWriter, r *http.Request, _ fakeGateBody) {
+	writeFakeJSON(w, http.StatusOK, api.accountEntry())
+}
+
+func (api *fakeGateAPI) setDualMode(w http.ResponseWriter, r *http.Request, _ fakeGateBody) {
+	dual := r.URL.Query().Get("dual_mode") == "true"
+	if dual != api.dualMode && len(api.ex.positions) > 0 {
+		writeGateError(w, gateErrPositionHolding)
+		return
+	}
+	api.dualMode = dual
+	writeFakeJSON(w, http.StatusOK, api.accountEntry())
+}
+
+// positionEntry 构造持仓记录（pos为nil时返回数量为0的记录）
+// 全仓时leverage为0、杠杆在cross_leverage_limit中；逐仓时leverage即杠杆
+func (api *fakeGateAPI) positionEntry(symbol, mode string, pos *fakePosition) map[string]interface{} {
+	spec := api.ex.specs[symbol]
+	leverage := api.ex.leverageOf(symbol)
+	entry := map[string]interface{}{
+		"contract":             strings.TrimSuffix(symbol, "USDT") + "_USDT",
+		"size":                 0,
+		"leverage":             "0",
+		"cross_leverage_limit": strconv.Itoa(leverage),
+		"entry_price":          "0",
+		"mark_price":           fakeNum(spec.Price),
+		"unrealised_pnl":       "0",
+		"liq_price":            "0",
+		"mode":                 mode,
+	}
+	if api.ex.marginModeOf(symbol) == "isolated" {
+		entry["leverage"] = strconv.Itoa(leverage)
+		entry["cross_leverage_limit"] = "0"
+	}
+	if pos != nil {
+		size := int64(math.Round(pos.Quantity / spec.QtyStep))
+		if pos.Side == "short" {
+			size = -size
+		}
+		entry["size"] = size
+		entry["entry_price"] = fakeNum(pos.EntryPrice)
+		entry["unrealised_pnl"] = fakeNum(api.ex.unrealizedProfit(pos))
+	}
+	return entry
+}
+
+// entriesOf 合约的持仓记录：双仓模式为多空两条，单仓模式为一条净持仓
+func (api *fakeGateAPI) entriesOf(symbol string) []map[string]interface{} {
+	if api.dualMode {
+		return []map[string]interface{}{
+			api.positionEntry(symbol, "dual_long", api.ex.positions[symbol+"_long"]),
+			api.positionEntry(symbol, "dual_short", api.ex.positions[symbol+"_short"]),
+		}
+	}
+	return []map[string]interface{}{api.positionEntry(symbol, "single", api.ex.netPosition(symbol))}
+}
+
+func (api *fakeGateAPI) positions(w http.ResponseWriter, r *http.Request, _ fakeGateBody) {
+	symbols := make([]string, 0, len(api.ex.specs))
+	for symbol := range api.ex.specs {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	holding := r.URL.Query().Get("holding") == "true"
+	list := []map[string]interface{}{}
+	for _, symbol := range symbols {
+		for _, entry := range api.entriesOf(symbol) {
+			if holding && entry["size"] == 0 {
+				continue
+			}
+			list = append(list, entry)
+		}
+	}
+	writeFakeJSON(w, http.StatusOK, list)
+}
+
+func (api *fakeGateAPI) singlePosition(w http.ResponseWriter, r *http.Request, _ fakeGateBody) {
+	symbol, _, ok := api.symbolOf(r.PathValue("contract"))
+	if !ok {
+		writeGateError(w, gateErrContractNotFound)
+		return
+	}
+	if api.dualMode {
+		writeGateError(w, gateErrInvalidParam)
+		return
+	}
+	writeFakeJSON(w, http.StatusOK, api.entriesOf(symbol)[0])
+}
+
+func (api *fakeGateAPI) dualPositions(w http.ResponseWriter, r *http.Request, _ fakeGateBody) {
+	symbol, _, ok := api.symbolOf(r.PathValue("contract"))
+	if !ok {
+		writeGateError(w, gateErrContractNotFound)
+		return
+	}
+	if !api.dualMode {
+		writeGateError(w, gateErrDualModeNotEnabled)
+		return
+	}
+	writeFakeJSON(w, http.StatusOK, api.entriesOf(symbol))
+}
+
+// updateLeverage leverage=0时为全仓（杠杆取cross_leverage_limit），大于0时为逐仓
+func (api *fakeGateAPI) updateLeverage(w http.ResponseWriter, r *http.Request, _ fakeGateBody) {
+	symbol, _, ok := api.symbolOf(r.PathValue("contract"))
+	if !ok {
+		writeGateError(w, gateErrContractNotFound)
+		return
+	}
+	if api.dualMode != strings.Contains(r.URL.Path, "/dual_comp/") {
+		writeGateError(w, gateErrInvalidParam)
+		return
+	}
+
+	query := r.URL.Query()
+	leverage, err := strconv.Atoi(query.Get("leverage"))
+	mode := "isolated"
+	if err == nil && leverage == 0 {
+		mode = "cross"
+		leverage, err = strconv.Atoi(query.Get("cross_leverage_limit"))
+	}
+	if err != nil || leverage <= 0 {
+		writeGateError(w, gateErrInvalidParam)
+		return
+	}
+	if err := api.ex.setMarginMode(symbol, mode); err != nil {
+		writeGateError(w, gateErrPositionHolding)
+		return
+	}
+	api.ex.setLeverage(symbol, leverage)
+	writeFakeJSON(w, http.StatusOK, api.entriesOf(symbol))
+}
+
+// execute 市价成交（size为合约张数）：双仓模式下只减仓订单平掉反方向持仓，其余开仓；单仓模式按净额成交
+func (api *fakeGateAPI) execute(symbol string, size int64, reduceOnly bool) string {
+	quantity := roundFakeQuantity(math.Abs(float64(size)) * api.ex.specs[symbol].QtyStep)
+	isBuy := size > 0
+	if !api.dualMode {
+		if err := api.ex.trade(symbol, isBuy, quantity, reduceOnly); err != nil {
+			return gateErrReduceExceeded
+		}
+		return ""
+	}
+
+	if reduceOnly {
+		side := "long"
+		if isBuy {
+			side = "short"
+		}
+		if err := api.ex.reduce(symbol, side, quantity); err != nil {
+			return gateErrReduceExceeded
+		}
+		return ""
+	}
+	side := "long"
+	if !isBuy {
+		side = "short"
+	}
+	api.ex.open(symbol, side, quantity)
+	return ""
+}
+
+// fill 按当前价格成交订单，记录成交并更新订单状态
+func (api *fakeGateAPI) fill(order map[string]interface{}, symbol string) {
+	spec := api.ex.specs[symbol]
+	size := order["size"].(int64)
+	fee := spec.Price * math.Abs(float64(size)) * spec.QtyStep * fakeGateTakerFee
+	order["status"] = "finished"
+	order["finish_as"] = "filled"
+	order["left"] = int64(0)
+	order["fill_price"] = fakeNum(spec.Price)
+	order["finish_time"] = order["create_time"]
+	api.trades = append(api.trades, map[string]interface{}{
+		"id":          api.ex.newID(),
+		"create_time": order["create_time"],
+		"contract":    order["contract"],
+		"order_id":    strconv.FormatInt(order["id"].(int64), 10),
+		"size":        size,
+		"price":       fakeNum(spec.Price),
+		"role":        "taker",
+		"fee":         fakeNum(fee),
+	})
+}
+
+// intSize 解析合约张数（必须为非0整数）
+func intSize(v float64) (int64, bool) {
+	if v == 0 || v != math.Trunc(v) {
+		return 0, false
+	}
+	return int64(v), true
+}
+
+func (api *fakeGateAPI) createOrder(w http.ResponseWriter, r *http.Request, body fakeGateBody) {
+	contract := body.str("contract")
+	symbol, spec, ok := api.symbolOf(contract)
+	if !ok {
+		writeGateError(w, gateErrContractNotFound)
+		return
+	}
+	if api.ex.rejectOrders {
+		writeGateError(w, gateErrInsufficient)
+		return
+	}
+	size, ok := intSize(body.num("size"))
+	if !ok {
+		writeGateError(w, gateErrSizeTooSmall)
+		return
+	}
+	tif := body.str("tif")
+	price := body.num("price")
+	if body.str("price") == "" || (price == 0 && tif != "ioc" && tif != "fok") {
+		writeGateError(w, gateErrInvalidParam)
+		return
+	}
+	reduceOnly := body["reduce_only"] == true
+
+	now := float64(time.Now().UnixMilli()) / 1000
+	order := map[string]interface{}{
+		"id":             api.ex.newID(),
+		"contract":       contract,
+		"size":           size,
+		"left":           size,
+		"price":          body.str("price"),
+		"fill_price":     "0",
+		"status":         "open",
+		"finish_as":      "",
+		"tif":            tif,
+		"is_reduce_only": reduceOnly,
+		"create_time":    now,
+		"finish_time":    0,
+	}
+
+	isBuy := size > 0
+	crosses := price == 0 || (isBuy && price >= spec.Price) || (!isBuy && price <= spec.Price)
+	switch {
+	case !crosses && (tif == "ioc" || tif == "fok"):
+		order["status"], order["finish_as"], order["finish_time"] = "finished", "ioc", now
+	case !crosses:
+		// 未穿过盘口的限价单挂单等待（本模拟不撮合挂单）
+	case tif == "poc":
+		order["status"], order["finish_as"], order["finish_time"] = "finished", "poc", now
+	default:
+		if label := api.execute(symbol, size, reduceOnly); label != "" {
+			writeGateError(w, label)
+			return
+		}
+		api.fill(order, symbol)
+	}
+
+	api.orders[order["id"].(int64)] = order
+	writeFakeJSON(w, http.StatusCreated, order)
+}
+
+// orderOf 按路径中的订单ID查找订单
+func (api *fakeGateAPI) orderOf(r *http.Request) (map[string]interface{}, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	order, ok := api.orders[id]
+	return order, ok
+}
+
+func (api *fakeGateAPI) getOrder(w http.ResponseWriter, r *http.Request, _ fakeGateBody) {
+	order, ok := api.orderOf(r)
+	if !ok {
+		writeGateError(w, gateErrOrderNotFound)
+		return
+	}
+	writeFakeJSON(w, http.StatusOK, order)
+}
+
+// amendOrder size为包含已成交部分的新张数，方向必须与原订单一致
+func (api *fakeGateAPI) amendOrder(w http.ResponseWriter, r *http.Request, body fakeGateBody) {
+	order, ok := api.orderOf(r)
+	if !ok || order["status"] != "open" {
+		writeGateError(w, gateErrOrderNotFound)
+		return
+	}
+	if _, present := body["size"]; present {
+		size, ok := intSize(body.num("size"))
+		if !ok || (size > 0) != (order["size"].(int64) > 0) {
+			writeGateError(w, gateErrInvalidParam)
+			return
+		}
+		order["size"], order["left"] = size, size
+	}
+	if price := body.str("price"); price != "" {
+		order["price"] = price
+	}
+	writeFakeJSON(w, http.StatusOK, order)
+}
+
+func (api *fakeGateAPI) cancel(order map[string]interface{}) {
+	order["status"] = "finished"
+	order["finish_as"] = "cancelled"
+	order["finish_time"] = float64(time.Now().UnixMilli()) / 1000
+}
+
+func (api *fakeGateAPI) cancelOrder(w http.ResponseWriter, r *http.Request, _ fakeGateBody) {
+	order, ok := api.orderOf(r)
+	if !ok || order["status"] != "open" {
+		writeGateError(w, gateErrOrderNotFound)
+		return
+	}
+	api.cancel(order)
+	writeFakeJSON(w, http.StatusOK, order)
+}
+
+// openOrders 未成交订单（contract为空表示所有合约），按订单ID排序
+func (api *fakeGateAPI) openOrders(contract string) []map[string]interface{} {
+	list := []map[string]interface{}{}
+	for _, order := range api.orders {
+		if order["status"] == "open" && (contract == "" || order["contract"] == contract) {
+			list = append(list, order)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i]["id"].(int64) < list[j]["id"].(int64)
+	})
+	return list
+}
+
+func (api *fakeGateAPI) listOrders(w http.ResponseWriter, r *http.Request, _ fakeGateBody) {
+	if r.URL.Query().Get("status") != "open" {
+		writeGateError(w, gateErrInvalidParam)
+		return
+	}
+	writeFakeJSON(w, http.StatusOK, api.openOrders(r.URL.Query().Get("contract")))
+}
+
+func (api *fakeGateAPI) cancelOrders(w http.ResponseWriter, r *http.Request, _ fakeGateBody) {
+	contract := r.URL.Query().Get("contract")
+	if contract == "" {
+		writeGateError(w, gateErrInvalidParam)
+		return
+	}
+	cancelled := api.openOrders(contract)
+	for _, order := range cancelled {
+		api.cancel(order)
+	}
+	writeFakeJSON(w, http.StatusOK, cancelled)
+}
+
+// createPriceOrder 条件单：由触发规则和平仓方向判断是止损还是止盈
+func (api *fakeGateAPI) createPriceOrder(w http.ResponseWriter, r *http.Request, body fakeGateBody) {
+	initial, trigger := body.obj("initial"), body.obj("trigger")
+	symbol, spec, ok := api.symbolOf(initial.str("contract"))
+	if !ok {
+		writeGateError(w, gateErrContractNotFound)
+		return
+	}
+	size, ok := intSize(initial.num("size"))
+	rule := int(trigger.num("rule"))
+	triggerPrice := trigger.num("price")
+	if !ok || (rule != 1 && rule != 2) || triggerPrice <= 0 || initial.num("price") != 0 || initial.str("tif") != "ioc" {
+		writeGateError(w, gateErrInvalidParam)
+		return
+	}
+
+	// 卖出平多、买入平空
+	positionSide := "long"
+	if size > 0 {
+		positionSide = "short"
+	}
+	kind := "tp"
+	if (positionSide == "long") == (rule == 2) {
+		kind = "sl"
+	}
+	id := api.ex.addTrigger(fakeTrigger{
+		Symbol:       symbol,
+		PositionSide: positionSide,
+		Kind:         kind,
+		TriggerPrice: triggerPrice,
+		Quantity:     roundFakeQuantity(math.Abs(float64(size)) * spec.QtyStep),
+		ReduceOnly:   initial["reduce_only"] == true,
+	})
+	writeFakeJSON(w, http.StatusCreated, map[string]interface{}{"id": id})
+}
+
+// listPriceOrders 未触发的条件单（跟踪止损由本地模拟，不在交易所挂单）
+func (api *fakeGateAPI) listPriceOrders(w http.ResponseWriter, r *http.Request, _ fakeGateBody) {
+	query := r.URL.Query()
+	if query.Get("status") != "open" {
+		writeGateError(w, gateErrInvalidParam)
+		return
+	}
+	contract := query.Get("contract")
+
+	list := []map[string]interface{}{}
+	for _, trigger := range api.ex.triggers {
+		symbolContract := strings.TrimSuffix(trigger.Symbol, "USDT") + "_USDT"
+		if contract != "" && contract != symbolContract {
+			continue
+		}
+		// 卖出平多、买入平空；多仓止损和空仓止盈为价格下跌触发
+		size := int64(math.Round(trigger.Quantity / api.ex.specs[trigger.Symbol].QtyStep))
+		orderType, rule := "plan-close-short-position", 1
+		if trigger.PositionSide == "long" {
+			size = -size
+			orderType = "plan-close-long-position"
+		}
+		if (trigger.PositionSide == "long") == (trigger.Kind == "sl") {
+			rule = 2
+		}
+		list = append(list, map[string]interface{}{
+			"id":         trigger.ID,
+			"status":     "open",
+			"order_type": orderType,
+			"initial":    map[string]interface{}{"contract": symbolContract, "size": size, "price": "0", "reduce_only": trigger.ReduceOnly},
+			"trigger":    map[string]interface{}{"price": fakeNum(trigger.TriggerPrice), "rule": rule},
+		})
+	}
+	writeFakeJSON(w, http.StatusOK, list)
+}
+
+func (api *fakeGateAPI) cancelPriceOrder(w http.ResponseWriter, r *http.Request, _ fakeGateBody) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || !api.ex.cancelTrigger(id) {
+		writeGateError(w, gateErrOrderNotFound)
+		return
+	}
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{"id": id, "status": "finished"})
+}
+
+func (api *fakeGateAPI) cancelPriceOrders(w http.ResponseWriter, r *http.Request, _ fakeGateBody) {
+	symbol, _, ok := api.symbolOf(r.URL.Query().Get("contract"))
+	if !ok {
+		writeGateError(w, gateErrContractNotFound)
+		return
+	}
+	api.ex.cancelAll(symbol)
+	writeFakeJSON(w, http.StatusOK, []interface{}{})
+}
+
+// myTrades 成交记录，支持按合约、订单ID和时间范围(秒)过滤
+func (api *fakeGateAPI) myTrades(w http.ResponseWriter, r *http.Request, _ fakeGateBody) {
+	query := r.URL.Query()
+	contract, orderID := query.Get("contract"), query.Get("order")
+	from, _ := strconv.ParseFloat(query.Get("from"), 64)
+	to, _ := strconv.ParseFloat(query.Get("to"), 64)
+	if strings.HasSuffix(r.URL.Path, "_timerange") && (from == 0 || to == 0) {
+		writeGateError(w, gateErrInvalidParam)
+		return
+	}
+
+	list := []map[string]interface{}{}
+	for _, trade := range api.trades {
+		createTime := trade["create_time"].(float64)
+		if contract != "" && trade["contract"] != contract {
+			continue
+		}
+		if orderID != "" && trade["order_id"] != orderID {
+			continue
+		}
+		if (from > 0 && createTime < from) || (to > 0 && createTime > to) {
+			continue
+		}
+		list = append(list, trade)
+	}
+	writeFakeJSON(w, http.StatusOK, list)
+}
@@ -0,0 +1,320 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Hyperliquid下单失败时在statuses中返回的原生错误
+const (
+	hyperliquidErrInsufficientMargin = "Insufficient margin to place order. asset=%d"
+	hyperliquidErrInvalidSize        = "Order has invalid size."
+	hyperliquidErrReduceOnly         = "Reduce only order would increase position. asset=%d"
+	hyperliquidErrIocNoMatch         = "Order could not immediately match against any resting orders. asset=%d"
+	hyperliquidErrCancelMissing      = "Order was never placed, already canceled, or filled. asset=%d"
+)
+
+// fakeHyperliquidAPI 模拟Hyperliquid的/info和/exchange接口（单向持仓）
+type fakeHyperliquidAPI struct {
+	ex *fakeExchange
+}
+
+// newFakeHyperliquidServer 启动模拟Hyperliquid API的本地服务器
+func newFakeHyperliquidServer(t *testing.T, ex *fakeExchange) *httptest.Server {
+	api := &fakeHyperliquidAPI{ex: ex}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/info", api.info)
+	mux.HandleFunc("/exchange", api.exchange)
+
+	srv := httptest.NewServer(ex.locked(mux))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// coins 按资产编号排列的币种（资产编号即在meta.universe中的下标）
+func (api *fakeHyperliquidAPI) coins() []string {
+	coins := make([]string, 0, len(api.ex.specs))
+	for symbol := range api.ex.specs {
+		coins = append(coins, strings.TrimSuffix(symbol, "USDT"))
+	}
+	sort.Strings(coins)
+	return coins
+}
+
+func (api *fakeHyperliquidAPI) assetSymbol(asset int) (string, bool) {
+	coins := api.coins()
+	if asset < 0 || asset >= len(coins) {
+		return "", false
+	}
+	return coins[asset] + "USDT", true
+}
+
+func (api *fakeHyperliquidAPI) info(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Type string `json:"type"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	switch req.Type {
+	case "meta":
+		universe := []map[string]interface{}{}
+		for _, coin := range api.coins() {
+			universe = append(universe, map[string]interface{}{
+				"name":        coin,
+				"szDecimals":  calculatePrecision(fakeNum(api.ex.specs[coin+"USDT"].QtyStep)),
+				"maxLeverage": 50,
+			})
+		}
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"universe": universe, "marginTables": []interface{}{}})
+	case "spotMeta":
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"universe": []interface{}{}, "tokens": []interface{}{}})
+	case "allMids":
+		mids := map[string]string{}
+		for _, coin := range api.coins() {
+			mids[coin] = fakeNum(api.ex.specs[coin+"USDT"].Price)
+		}
+		writeFakeJSON(w, http.StatusOK, mids)
+	case "clearinghouseState":
+		api.clearinghouseState(w)
+	case "openOrders":
+		orders := []map[string]interface{}{}
+		for _, trigger := range api.ex.triggers {
+			side := "A"
+			if trigger.PositionSide == "short" {
+				side = "B"
+			}
+			orders = append(orders, map[string]interface{}{
+				"coin":      strings.TrimSuffix(trigger.Symbol, "USDT"),
+				"limitPx":   fakeNum(trigger.TriggerPrice),
+				"oid":       trigger.ID,
+				"side":      side,
+				"sz":        fakeNum(trigger.Quantity),
+				"timestamp": time.Now().UnixMilli(),
+			})
+		}
+		writeFakeJSON(w, http.StatusOK, orders)
+	case "frontendOpenOrders":
+		// 带触发信息的挂单列表，止损为Stop Market，止盈为Take Profit Market
+		orders := []map[string]interface{}{}
+		for _, trigger := range api.ex.triggers {
+			side, orderType := "A", "Stop Market"
+			if trigger.PositionSide == "short" {
+				side = "B"
+			}
+			if trigger.Kind == "tp" {
+				orderType = "Take Profit Market"
+			}
+			orders = append(orders, map[string]interface{}{
+				"coin":             strings.TrimSuffix(trigger.Symbol, "USDT"),
+				"isPositionTpsl":   false,
+				"isTrigger":        true,
+				"limitPx":          fakeNum(trigger.TriggerPrice),
+				"oid":              trigger.ID,
+				"orderType":        orderType,
+				"origSz":           fakeNum(trigger.Quantity),
+				"reduceOnly":       trigger.ReduceOnly,
+				"side":             side,
+				"sz":               fakeNum(trigger.Quantity),
+				"timestamp":        time.Now().UnixMilli(),
+				"triggerCondition": "Triggered when price crosses " + fakeNum(trigger.TriggerPrice),
+				"triggerPx":        fakeNum(trigger.TriggerPrice),
+			})
+		}
+		writeFakeJSON(w, http.StatusOK, orders)
+	default:
+		http.Error(w, "Failed to deserialize the JSON body", http.StatusUnprocessableEntity)
+	}
+}
+
+func (api *fakeHyperliquidAPI) clearinghouseState(w http.ResponseWriter) {
+	positions := []map[string]interface{}{}
+	var notional float64
+	for _, coin := range api.coins() {
+		symbol := coin + "USDT"
+		pos := api.ex.netPosition(symbol)
+		if pos == nil {
+			continue
+		}
+		szi := pos.Quantity
+		if pos.Side == "short" {
+			szi = -szi
+		}
+		value := pos.Quantity * api.ex.specs[symbol].Price
+		notional += value
+		positions = append(positions, map[string]interface{}{
+			"type": "oneWay",
+			"position": map[string]interface{}{
+				"coin":           coin,
+				"szi":            fakeNum(szi),
+				"entryPx":        fakeNum(pos.EntryPrice),
+				"positionValue":  fakeNum(value),
+				"unrealizedPnl":  fakeNum(api.ex.unrealizedProfit(pos)),
+				"returnOnEquity": "0",
+				"liquidationPx":  nil,
+				"marginUsed":     fakeNum(value / float64(pos.Leverage)),
+				"leverage":       map[string]interface{}{"type": pos.MarginMode, "value": pos.Leverage},
+			},
+		})
+	}
+
+	summary := map[string]interface{}{
+		"accountValue":    fakeNum(api.ex.balance + api.ex.totalUnrealizedProfit()),
+		"totalMarginUsed": fakeNum(api.ex.usedMargin()),
+		"totalNtlPos":     fakeNum(notional),
+		"totalRawUsd":     fakeNum(api.ex.balance),
+	}
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"assetPositions":     positions,
+		"marginSummary":      summary,
+		"crossMarginSummary": summary,
+		"withdrawable":       fakeNum(api.ex.balance - api.ex.usedMargin()),
+	})
+}
+
+// hyperliquidOrderWire 与SDK提交的订单格式一致
+type hyperliquidOrderWire struct {
+	Asset      int    `json:"a"`
+	IsBuy      bool   `json:"b"`
+	LimitPx    string `json:"p"`
+	Size       string `json:"s"`
+	ReduceOnly bool   `json:"r"`
+	OrderType  struct {
+		Limit *struct {
+			Tif string `json:"tif"`
+		} `json:"limit"`
+		Trigger *struct {
+			IsMarket  bool   `json:"isMarket"`
+			TriggerPx string `json:"triggerPx"`
+			Tpsl      string `json:"tpsl"`
+		} `json:"trigger"`
+	} `json:"t"`
+}
+
+func (api *fakeHyperliquidAPI) exchange(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Action struct {
+			Type    string                 `json:"type"`
+			Orders  []hyperliquidOrderWire `json:"orders"`
+			Cancels []struct {
+				Asset int   `json:"a"`
+				Oid   int64 `json:"o"`
+			} `json:"cancels"`
+			Asset    int  `json:"asset"`
+			IsCross  bool `json:"isCross"`
+			Leverage int  `json:"leverage"`
+		} `json:"action"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Failed to deserialize the JSON body", http.StatusUnprocessableEntity)
+		return
+	}
+
+	action := req.Action
+	switch action.Type {
+	case "order":
+		statuses := []interface{}{}
+		for _, order := range action.Orders {
+			statuses = append(statuses, api.placeOrder(order))
+		}
+		writeHyperliquidStatuses(w, "order", statuses)
+
+	case "cancel":
+		statuses := []interface{}{}
+		for _, cancel := range action.Cancels {
+			if api.ex.cancelTrigger(cancel.Oid) {
+				statuses = append(statuses, "success")
+			} else {
+				statuses = append(statuses, map[string]interface{}{"error": fmt.Sprintf(hyperliquidErrCancelMissing, cancel.Asset)})
+			}
+		}
+		writeHyperliquidStatuses(w, "cancel", statuses)
+
+	case "updateLeverage":
+		symbol, ok := api.assetSymbol(action.Asset)
+		if !ok {
+			writeFakeJSON(w, http.StatusOK, map[string]interface{}{"status": "err", "response": "Invalid asset."})
+			return
+		}
+		mode := "isolated"
+		if action.IsCross {
+			mode = "cross"
+		}
+		if err := api.ex.setMarginMode(symbol, mode); err != nil {
+			writeFakeJSON(w, http.StatusOK, map[string]interface{}{"status": "err", "response": "Cannot switch leverage type with open position."})
+			return
+		}
+		api.ex.setLeverage(symbol, action.Leverage)
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok", "response": map[string]interface{}{"type": "default"}})
+
+	default:
+		http.Error(w, "Failed to deserialize the JSON body", http.StatusUnprocessableEntity)
+	}
+}
+
+// placeOrder 处理单个订单：IOC限价单按市价成交，触发单挂起等待触发
+func (api *fakeHyperliquidAPI) placeOrder(order hyperliquidOrderWire) interface{} {
+	symbol, ok := api.assetSymbol(order.Asset)
+	if !ok {
+		return map[string]interface{}{"error": "Invalid asset."}
+	}
+	if api.ex.rejectOrders {
+		return map[string]interface{}{"error": fmt.Sprintf(hyperliquidErrInsufficientMargin, order.Asset)}
+	}
+	quantity, _ := strconv.ParseFloat(order.Size, 64)
+	if !api.ex.validQuantity(symbol, quantity) {
+		return map[string]interface{}{"error": hyperliquidErrInvalidSize}
+	}
+
+	if trigger := order.OrderType.Trigger; trigger != nil {
+		triggerPrice, _ := strconv.ParseFloat(trigger.TriggerPx, 64)
+		positionSide := "long"
+		if order.IsBuy {
+			positionSide = "short"
+		}
+		id := api.ex.addTrigger(fakeTrigger{
+			Symbol:       symbol,
+			PositionSide: positionSide,
+			Kind:         trigger.Tpsl,
+			TriggerPrice: triggerPrice,
+			Quantity:     quantity,
+			ReduceOnly:   order.ReduceOnly,
+		})
+		return map[string]interface{}{"resting": map[string]interface{}{"oid": id}}
+	}
+
+	price := api.ex.specs[symbol].Price
+	limitPrice, _ := strconv.ParseFloat(order.LimitPx, 64)
+	crosses := (order.IsBuy && limitPrice >= price) || (!order.IsBuy && limitPrice <= price)
+	if !crosses {
+		if order.OrderType.Limit != nil && order.OrderType.Limit.Tif == "Ioc" {
+			return map[string]interface{}{"error": fmt.Sprintf(hyperliquidErrIocNoMatch, order.Asset)}
+		}
+		return map[string]interface{}{"resting": map[string]interface{}{"oid": api.ex.newID()}}
+	}
+
+	if err := api.ex.trade(symbol, order.IsBuy, quantity, order.ReduceOnly); err != nil {
+		return map[string]interface{}{"error": fmt.Sprintf(hyperliquidErrReduceOnly, order.Asset)}
+	}
+	return map[string]interface{}{"filled": map[string]interface{}{
+		"totalSz": order.Size,
+		"avgPx":   fakeNum(price),
+		"oid":     api.ex.newID(),
+	}}
+}
+
+func writeHyperliquidStatuses(w http.ResponseWriter, responseType string, statuses []interface{}) {
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "ok",
+		"response": map[string]interface{}{
+			"type": responseType,
+			"data": map[string]interface{}{"statuses": statuses},
+		},
+	})
+}
@@ -0,0 +1,378 @@
+package trader
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// okxFakeCtVal 模拟的合约面值（1张合约对应的币数量）
+var okxFakeCtVal = map[string]float64{
+	"BTCUSDT": 0.01,
+	"ETHUSDT": 0.1,
+}
+
+// fakeOKXAPI 模拟OKX V5永续合约接口（按张数下单，多空持仓模式）
+type fakeOKXAPI struct {
+	ex      *fakeExchange
+	posMode string
+}
+
+// newFakeOKXServer 启动模拟OKX API的本地服务器，账户初始为单向持仓模式
+func newFakeOKXServer(t *testing.T, ex *fakeExchange) *httptest.Server {
+	api := &fakeOKXAPI{ex: ex, posMode: "net_mode"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v5/account/config", api.accountConfig)
+	mux.HandleFunc("/api/v5/account/set-position-mode", api.setPositionMode)
+	mux.HandleFunc("/api/v5/account/set-leverage", api.setLeverage)
+	mux.HandleFunc("/api/v5/account/balance", api.balance)
+	mux.HandleFunc("/api/v5/account/positions", api.positions)
+	mux.HandleFunc("/api/v5/public/instruments", api.instruments)
+	mux.HandleFunc("/api/v5/market/ticker", api.ticker)
+	mux.HandleFunc("/api/v5/trade/order", api.order)
+	mux.HandleFunc("/api/v5/trade/order-algo", api.algoOrder)
+	mux.HandleFunc("/api/v5/trade/orders-algo-pending", api.algoPending)
+	mux.HandleFunc("/api/v5/trade/cancel-algos", api.cancelAlgos)
+
+	srv := httptest.NewServer(ex.locked(mux))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeOKXData(w http.ResponseWriter, data []map[string]interface{}) {
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{"code": "0", "msg": "", "data": data})
+}
+
+// writeOKXError 接口级错误，错误信息取自okx_errors.go
+func writeOKXError(w http.ResponseWriter, code string) {
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"code": code,
+		"msg":  GetErrorMessage(code),
+		"data": []interface{}{},
+	})
+}
+
+// writeOKXOrderError 下单类接口的错误：外层code=1，具体原因在data[0].sCode/sMsg中
+func writeOKXOrderError(w http.ResponseWriter, sCode string) {
+	writeFakeJSON(w, http.StatusOK, map[string]interface{}{
+		"code": "1",
+		"msg":  "All operations failed",
+		"data": []map[string]interface{}{{
+			"ordId": "",
+			"sCode": sCode,
+			"sMsg":  GetErrorMessage(sCode),
+		}},
+	})
+}
+
+func readOKXBody(r *http.Request) map[string]string {
+	params := map[string]string{}
+	json.NewDecoder(r.Body).Decode(&params)
+	return params
+}
+
+// instrument 将instId转换为内部交易对，返回合约面值和下单精度
+func (api *fakeOKXAPI) instrument(instID string) (symbol string, ctVal, lotSz float64, ok bool) {
+	symbol = convertFromOKXSymbol(instID)
+	spec, ok := api.ex.specs[symbol]
+	if !ok {
+		return "", 0, 0, false
+	}
+	ctVal = okxFakeCtVal[symbol]
+	return symbol, ctVal, spec.QtyStep / ctVal, true
+}
+
+// parseContracts 校验张数是lotSz的整数倍且不小于最小下单量，返回对应的币数量
+func (api *fakeOKXAPI) parseContracts(sz string, ctVal, lotSz float64) (float64, bool) {
+	contracts, err := strconv.ParseFloat(sz, 64)
+	if err != nil || contracts < lotSz-1e-9 {
+		return 0, false
+	}
+	lots := contracts / lotSz
+	if math.Abs(lots-math.Round(lots)) > 1e-6 {
+		return 0, false
+	}
+	return roundFakeQuantity(contracts * ctVal), true
+}
+
+func (api *fakeOKXAPI) accountConfig(w http.ResponseWriter, r *http.Request) {
+	writeOKXData(w, []map[string]interface{}{{"posMode": api.posMode, "acctLv": "2"}})
+}
+
+func (api *fakeOKXAPI) setPositionMode(w http.ResponseWriter, r *http.Request) {
+	params := readOKXBody(r)
+	if params["posMode"] != "long_short_mode" && params["posMode"] != "net_mode" {
+		writeOKXError(w, "50064")
+		return
+	}
+	api.posMode = params["posMode"]
+	writeOKXData(w, []map[string]interface{}{{"posMode": api.posMode}})
+}
+
+func (api *fakeOKXAPI) setLeverage(w http.ResponseWriter, r *http.Request) {
+	params := readOKXBody(r)
+	symbol, _, _, ok := api.instrument(params["instId"])
+	if !ok {
+		writeOKXError(w, "50035")
+		return
+	}
+	leverage, err := strconv.Atoi(params["lever"])
+	if err != nil || leverage < 1 || leverage > 125 {
+		writeOKXError(w, "50062")
+		return
+	}
+	api.ex.setLeverage(symbol, leverage)
+	writeOKXData(w, []map[string]interface{}{{
+		"instId":  params["instId"],
+		"lever":   params["lever"],
+		"mgnMode": params["mgnMode"],
+		"posSide": params["posSide"],
+	}})
+}
+
+func (api *fakeOKXAPI) balance(w http.ResponseWriter, r *http.Request) {
+	upl := api.ex.totalUnrealizedProfit()
+	writeOKXData(w, []map[string]interface{}{{
+		"totalEq": fakeNum(api.ex.balance + upl),
+		"upl":     fakeNum(upl),
+		"adjEq":   fakeNum(api.ex.balance + upl - api.ex.usedMargin()),
+	}})
+}
+
+func (api *fakeOKXAPI) positions(w http.ResponseWriter, r *http.Request) {
+	keys := make([]string, 0, len(api.ex.positions))
+	for key := range api.ex.positions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	data := []map[string]interface{}{}
+	for _, key := range keys {
+		pos := api.ex.positions[key]
+		instID := convertToOKXSymbol(pos.Symbol)
+		data = append(data, map[string]interface{}{
+			"instId":  instID,
+			"posSide": pos.Side,
+			"pos":     fakeNum(roundFakeQuantity(pos.Quantity / okxFakeCtVal[pos.Symbol])),
+			"avgPx":   fakeNum(pos.EntryPrice),
+			"markPx":  fakeNum(api.ex.specs[pos.Symbol].Price),
+			"upl":     fakeNum(api.ex.unrealizedProfit(pos)),
+			"lever":   strconv.Itoa(pos.Leverage),
+			"liqPx":   "",
+			"mgnMode": pos.MarginMode,
+		})
+	}
+	writeOKXData(w, data)
+}
+
+func (api *fakeOKXAPI) instruments(w http.ResponseWriter, r *http.Request) {
+	instID := r.URL.Query().Get("instId")
+	_, ctVal, lotSz, ok := api.instrument(instID)
+	if !ok {
+		writeOKXError(w, "50035")
+		return
+	}
+	writeOKXData(w, []map[string]interface{}{{
+		"instId": instID,
+		"ctVal":  fakeNum(ctVal),
+		"lotSz":  fakeNum(lotSz),
+		"minSz":  fakeNum(lotSz),
+	}})
+}
+
+func (api *fakeOKXAPI) ticker(w http.ResponseWriter, r *http.Request) {
+	instID := r.URL.Query().Get("instId")
+	symbol, _, _, ok := api.instrument(instID)
+	if !ok {
+		writeOKXError(w, "50035")
+		return
+	}
+	writeOKXData(w, []map[string]interface{}{{
+		"instId": instID,
+		"last":   fakeNum(api.ex.specs[symbol].Price),
+	}})
+}
+
+// checkOrder 校验下单类请求的通用参数，返回交易对、币数量和该订单是否为平仓方向
+func (api *fakeOKXAPI) checkOrder(w http.ResponseWriter, params map[string]string) (symbol string, quantity float64, closing, ok bool) {
+	symbol, ctVal, lotSz, ok := api.instrument(params["instId"])
+	if !ok {
+		writeOKXOrderError(w, "50035")
+		return "", 0, false, false
+	}
+	if api.ex.rejectOrders {
+		writeOKXOrderError(w, "51010")
+		return "", 0, false, false
+	}
+	// posSide只能在多空持仓模式下使用
+	posSide := params["posSide"]
+	if api.posMode != "long_short_mode" || (posSide != "long" && posSide != "short") {
+		writeOKXOrderError(w, "50058")
+		return "", 0, false, false
+	}
+	tdMode := params["tdMode"]
+	if tdMode != "cross" && tdMode != "isolated" {
+		writeOKXOrderError(w, "50063")
+		return "", 0, false, false
+	}
+	quantity, ok = api.parseContracts(params["sz"], ctVal, lotSz)
+	if !ok {
+		writeOKXOrderError(w, "50055")
+		return "", 0, false, false
+	}
+	closing = (params["side"] == "sell") == (posSide == "long")
+	return symbol, quantity, closing, true
+}
+
+func (api *fakeOKXAPI) order(w http.ResponseWriter, r *http.Request) {
+	params := readOKXBody(r)
+	symbol, quantity, closing, ok := api.checkOrder(w, params)
+	if !ok {
+		return
+	}
+
+	// 限价类订单只挂单（本模拟不撮合挂单）
+	if params["ordType"] == "market" {
+		posSide := params["posSide"]
+		if closing {
+			pos, exists := api.ex.positions[symbol+"_"+posSide]
+			if !exists {
+				writeOKXOrderError(w, "58113")
+				return
+			}
+			if pos.MarginMode != params["tdMode"] {
+				writeOKXOrderError(w, "50063")
+				return
+			}
+			if err := api.ex.reduce(symbol, posSide, quantity); err != nil {
+				writeOKXOrderError(w, "58104")
+				return
+			}
+		} else {
+			if err := api.ex.setMarginMode(symbol, params["tdMode"]); err != nil {
+				writeOKXOrderError(w, "50063")
+				return
+			}
+			api.ex.open(symbol, posSide, quantity)
+		}
+	}
+
+	writeOKXData(w, []map[string]interface{}{{
+		"ordId":   strconv.FormatInt(api.ex.newID(), 10),
+		"clOrdId": "",
+		"sCode":   "0",
+		"sMsg":    "Order placed",
+	}})
+}
+
+func (api *fakeOKXAPI) algoOrder(w http.ResponseWriter, r *http.Request) {
+	params := readOKXBody(r)
+	symbol, quantity, closing, ok := api.checkOrder(w, params)
+	if !ok {
+		return
+	}
+	trigger := fakeTrigger{
+		Symbol:       symbol,
+		PositionSide: params["posSide"],
+		Quantity:     quantity,
+		ReduceOnly:   closing,
+	}
+	switch params["ordType"] {
+	case "conditional":
+		trigger.Kind = "tp"
+		triggerPx := params["tpTriggerPx"]
+		if params["slTriggerPx"] != "" {
+			trigger.Kind = "sl"
+			triggerPx = params["slTriggerPx"]
+		}
+		triggerPrice, err := strconv.ParseFloat(triggerPx, 64)
+		if err != nil {
+			writeOKXOrderError(w, "50056")
+			return
+		}
+		trigger.TriggerPrice = triggerPrice
+
+	case "move_order_stop":
+		callbackRatio, err := strconv.ParseFloat(params["callbackRatio"], 64)
+		if err != nil {
+			writeOKXOrderError(w, "50056")
+			return
+		}
+		trigger.Kind = "trailing"
+		trigger.CallbackRate = callbackRatio
+		if activePx := params["activePx"]; activePx != "" {
+			trigger.ActivationPrice, _ = strconv.ParseFloat(activePx, 64)
+		}
+
+	default:
+		writeOKXOrderError(w, "50054")
+		return
+	}
+
+	id := api.ex.addTrigger(trigger)
+	writeOKXData(w, []map[string]interface{}{{
+		"algoId": strconv.FormatInt(id, 10),
+		"sCode":  "0",
+		"sMsg":   "",
+	}})
+}
+
+// algoPending 返回未触发的止盈止损策略委托（sz为合约张数）
+func (api *fakeOKXAPI) algoPending(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("ordType") != "conditional" {
+		writeOKXError(w, "50054")
+		return
+	}
+	instID := query.Get("instId")
+	data := []map[string]interface{}{}
+	for _, trigger := range api.ex.triggers {
+		if trigger.Kind != "sl" && trigger.Kind != "tp" {
+			continue
+		}
+		if instID != "" && convertToOKXSymbol(trigger.Symbol) != instID {
+			continue
+		}
+		side := "sell"
+		if trigger.PositionSide == "short" {
+			side = "buy"
+		}
+		item := map[string]interface{}{
+			"algoId":      strconv.FormatInt(trigger.ID, 10),
+			"instId":      convertToOKXSymbol(trigger.Symbol),
+			"ordType":     "conditional",
+			"side":        side,
+			"posSide":     trigger.PositionSide,
+			"sz":          fakeNum(trigger.Quantity / okxFakeCtVal[trigger.Symbol]),
+			"state":       "live",
+			"slTriggerPx": "",
+			"tpTriggerPx": "",
+		}
+		item[trigger.Kind+"TriggerPx"] = fakeNum(trigger.TriggerPrice)
+		data = append(data, item)
+	}
+	writeOKXData(w, data)
+}
+
+// cancelAlgos 批量撤销策略委托（body为数组）
+func (api *fakeOKXAPI) cancelAlgos(w http.ResponseWriter, r *http.Request) {
+	var items []map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil || len(items) == 0 {
+		writeOKXError(w, "50017")
+		return
+	}
+	data := []map[string]interface{}{}
+	for _, item := range items {
+		id, _ := strconv.ParseInt(item["algoId"], 10, 64)
+		if !api.ex.cancelTrigger(id) {
+			writeOKXOrderError(w, "50051")
+			return
+		}
+		data = append(data, map[string]interface{}{"algoId": item["algoId"], "sCode": "0", "sMsg": ""})
+	}
+	writeOKXData(w, data)
+}
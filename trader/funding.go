@@ -0,0 +1,297 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	fundingIntervalHours    = 8.0  // 资金费结算周期（小时），market.Data.FundingRate为单个周期的费率
+	defaultFundingHoldHours = 24.0 // 未配置预计持有时长时按24小时估算
+)
+
+// FundingGuardConfig 资金费成本控制（MaxTPFraction为0表示不启用）
+type FundingGuardConfig struct {
+	MaxTPFraction float64 `json:"max_tp_fraction"` // 预计持有期资金费占止盈距离的比例上限（如0.3表示30%）
+	HoldHours     float64 `json:"hold_hours"`      // 预计持有时长（小时，0表示默认24小时）
+	AutoClose     bool    `json:"auto_close"`      // 持仓的剩余止盈空间不足以覆盖预计资金费时自动平仓
+}
+
+// holdHours 预计持有时长
+func (c FundingGuardConfig) holdHours() float64 {
+	if c.HoldHours > 0 {
+		return c.HoldHours
+	}
+	return defaultFundingHoldHours
+}
+
+// projectedFundingCost 按当前资金费率估算持有hours小时的资金费（占名义价值的比例，正数为支付，负数为收到）
+// 费率为正时多头支付空头
+func projectedFundingCost(side string, rate, hours float64) float64 {
+	cost := rate * hours / fundingIntervalHours
+	if side == "short" {
+		return -cost
+	}
+	return cost
+}
+
+// FundingStore 持仓资金费统计持久化接口（由config.Database实现）
+// 统计起点只在本地记录，重启后需要恢复，否则重启前结算的资金费不会计入交易结果
+type FundingStore interface {
+	GetFundingState(traderID string) (string, error)
+	SaveFundingState(traderID string, state string) error
+}
+
+// positionFunding 单个持仓已结算的资金费
+type positionFunding struct {
+	Since  time.Time `json:"since"`  // 开仓时间（从此时开始统计）
+	Total  float64   `json:"total"`  // 开仓以来累计（正数为收到，负数为支付）
+	Booked float64   `json:"booked"` // 已计入减仓交易结果的部分
+}
+
+// fundingTracker 按持仓跟踪已结算的资金费（AI周期与实时风控都会读写）
+type fundingTracker struct {
+	traderID string
+	store    FundingStore
+
+	mu        sync.Mutex
+	positions map[string]*positionFunding // symbol_side -> 资金费
+}
+
+// newFundingTracker 创建资金费跟踪并恢复重启前的统计（读取失败时从空表开始）
+func newFundingTracker(store FundingStore, traderID, name string) *fundingTracker {
+	f := &fundingTracker{traderID: traderID, store: store, positions: make(map[string]*positionFunding)}
+	if store == nil {
+		return f
+	}
+	data, err := store.GetFundingState(traderID)
+	if err != nil {
+		log.Printf("⚠️ [%s] 读取持仓资金费统计失败: %v", name, err)
+		return f
+	}
+	if data == "" {
+		return f
+	}
+	if err := json.Unmarshal([]byte(data), &f.positions); err != nil {
+		log.Printf("⚠️ [%s] 解析持仓资金费统计失败: %v", name, err)
+		f.positions = make(map[string]*positionFunding)
+	}
+	return f
+}
+
+// save 持久化统计起点和已计入部分（调用方需持有锁）
+func (f *fundingTracker) save() {
+	if f.store == nil {
+		return
+	}
+	data, err := json.Marshal(f.positions)
+	if err != nil {
+		log.Printf("⚠️ 序列化持仓资金费统计失败: %v", err)
+		return
+	}
+	if err := f.store.SaveFundingState(f.traderID, string(data)); err != nil {
+		log.Printf("⚠️ 保存持仓资金费统计失败: %v", err)
+	}
+}
+
+// open 新开仓：从开仓时间重新统计
+func (f *fundingTracker) open(key string, since time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.positions[key] = &positionFunding{Since: since}
+	f.save()
+}
+
+// track 跟踪已有持仓（已跟踪时保持不变，包括重启前恢复的统计）
+func (f *fundingTracker) track(key string, since time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.positions[key]; !ok {
+		f.positions[key] = &positionFunding{Since: since}
+		f.save()
+	}
+}
+
+// since 持仓的统计起点（未跟踪时返回零值）
+func (f *fundingTracker) since(key string) time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p, ok := f.positions[key]; ok {
+		return p.Since
+	}
+	return time.Time{}
+}
+
+// update 更新累计资金费，返回尚未计入交易结果的部分
+func (f *fundingTracker) update(key string, total float64) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.positions[key]
+	if !ok {
+		return 0
+	}
+	p.Total = total
+	return p.Total - p.Booked
+}
+
+// accrued 尚未计入交易结果的资金费
+func (f *fundingTracker) accrued(key string) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p, ok := f.positions[key]; ok {
+		return p.Total - p.Booked
+	}
+	return 0
+}
+
+// book 减仓时把部分资金费计入交易结果
+func (f *fundingTracker) book(key string, amount float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p, ok := f.positions[key]; ok {
+		p.Booked += amount
+		f.save()
+	}
+}
+
+// prune 清理已不存在的持仓
+func (f *fundingTracker) prune(current map[string]bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	pruned := false
+	for key := range f.positions {
+		if !current[key] {
+			delete(f.positions, key)
+			pruned = true
+		}
+	}
+	if pruned {
+		f.save()
+	}
+}
+
+// syncPositionFunding 从交易所流水汇总持仓开仓以来的资金费，返回尚未计入交易结果的部分
+// 查询失败时沿用上次的结果；双向持仓同时持有多空时按币种合计（交易所流水不区分持仓方向）
+func (at *AutoTrader) syncPositionFunding(symbol, key string) float64 {
+	since := at.funding.since(key)
+	if since.IsZero() {
+		return 0
+	}
+	payments, err := at.trader.GetFundingPayments(symbol, since)
+	if err != nil {
+		log.Printf("⚠️ 获取 %s 资金费记录失败: %v", symbol, err)
+		return at.funding.accrued(key)
+	}
+	total := 0.0
+	for _, p := range payments {
+		if p.Symbol == symbol {
+			total += p.Amount
+		}
+	}
+	return at.funding.update(key, total)
+}
+
+// bookReduceFunding 减仓时按减仓比例把已结算资金费计入该笔交易
+func (at *AutoTrader) bookReduceFunding(pos *Position, reduced float64, actionRecord *logger.DecisionAction) {
+	if pos.Quantity <= 0 {
+		return
+	}
+	key := pos.Key()
+	share := at.syncPositionFunding(pos.Symbol, key) * math.Min(reduced/pos.Quantity, 1)
+	at.funding.book(key, share)
+	actionRecord.Funding = share
+}
+
+// checkFundingCost 开仓前检查资金费成本：按当前费率估算预计持有期的资金费，超过止盈距离的设定比例时拒绝开仓
+func (at *AutoTrader) checkFundingCost(d *decision.Decision, data *market.Data, actionRecord *logger.DecisionAction) error {
+	cfg := at.config.FundingGuard
+	if cfg.MaxTPFraction <= 0 || data == nil || d.TakeProfit <= 0 {
+		return nil
+	}
+	entry := d.LimitPrice
+	if entry <= 0 {
+		entry = data.CurrentPrice
+	}
+	if entry <= 0 {
+		return nil
+	}
+	side := "long"
+	if strings.HasSuffix(d.Action, "_short") {
+		side = "short"
+	}
+
+	cost := projectedFundingCost(side, data.FundingRate, cfg.holdHours())
+	tpDistance := math.Abs(d.TakeProfit-entry) / entry
+	actionRecord.FundingNote = fmt.Sprintf("资金费率 %.4f%%，预计持有%.0fh资金费 %+.4f%%，止盈距离 %.2f%%",
+		data.FundingRate*100, cfg.holdHours(), cost*100, tpDistance*100)
+	if cost > 0 && cost > cfg.MaxTPFraction*tpDistance {
+		return fmt.Errorf("资金费成本过高: %s，超过止盈距离的%.0f%%", actionRecord.FundingNote, cfg.MaxTPFraction*100)
+	}
+	return nil
+}
+
+// checkFundingAutoClose 持仓预计持有期的资金费超过剩余止盈空间的设定比例时市价平仓（需启用AutoClose）
+func (at *AutoTrader) checkFundingAutoClose(record *logger.DecisionRecord) {
+	cfg := at.config.FundingGuard
+	if cfg.MaxTPFraction <= 0 || !cfg.AutoClose {
+		return
+	}
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠️ 资金费检查: 获取持仓失败: %v", err)
+		return
+	}
+
+	closed := false
+	for _, pos := range positions {
+		if pos.Quantity <= 0 || pos.MarkPrice <= 0 {
+			continue
+		}
+		orders, err := at.trader.GetProtectiveOrders(pos.Symbol)
+		if err != nil {
+			log.Printf("⚠️ 资金费检查: 查询 %s 止盈止损单失败: %v", pos.Symbol, err)
+			continue
+		}
+		_, takeProfit := protectivePrices(orders, pos.Symbol, pos.PositionSide())
+		if takeProfit <= 0 {
+			continue
+		}
+		remaining := (takeProfit - pos.MarkPrice) / pos.MarkPrice
+		if pos.Side == "short" {
+			remaining = -remaining
+		}
+		if remaining <= 0 {
+			continue // 已到止盈价，由止盈单处理
+		}
+		data, err := at.marketData(pos.Symbol)
+		if err != nil {
+			continue
+		}
+		cost := projectedFundingCost(pos.Side, data.FundingRate, cfg.holdHours())
+		if cost <= cfg.MaxTPFraction*remaining {
+			continue
+		}
+
+		reason := fmt.Sprintf("预计%.0fh资金费 %.4f%% 超过剩余止盈空间 %.2f%% 的%.0f%%",
+			cfg.holdHours(), cost*100, remaining*100, cfg.MaxTPFraction*100)
+		actionRecord := at.guardianClose(guardianBreach{Position: pos, Price: pos.MarkPrice, Reason: reason})
+		if actionRecord.Success {
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("💸 %s %s 资金费平仓: %s", pos.Symbol, pos.Side, reason))
+		} else {
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 资金费平仓失败: %s", pos.Symbol, pos.Side, actionRecord.Error))
+		}
+		record.Decisions = append(record.Decisions, actionRecord)
+		closed = true
+	}
+	if closed && at.guardian != nil {
+		at.guardian.invalidate()
+	}
+}
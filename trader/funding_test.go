@@ -0,0 +1,198 @@
+package trader
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+)
+
+// fundingPaperTrader 返回预设资金费流水的模拟盘
+type fundingPaperTrader struct {
+	*PaperTrader
+	payments []FundingPayment
+}
+
+func (f *fundingPaperTrader) GetFundingPayments(symbol string, since time.Time) ([]FundingPayment, error) {
+	var result []FundingPayment
+	for _, p := range f.payments {
+		if p.Symbol == symbol && p.Timestamp >= since.UnixMilli() {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+func TestCheckFundingCost(t *testing.T) {
+	at := &AutoTrader{config: AutoTraderConfig{FundingGuard: FundingGuardConfig{MaxTPFraction: 0.3}}}
+	data := &market.Data{CurrentPrice: 100, FundingRate: 0.001} // 每8小时0.1%，24小时0.3%
+
+	if got := projectedFundingCost("long", 0.001, 24); math.Abs(got-0.003) > 1e-12 {
+		t.Errorf("多仓24小时资金费应为0.3%%: %v", got)
+	}
+	if got := projectedFundingCost("short", 0.001, 24); math.Abs(got+0.003) > 1e-12 {
+		t.Errorf("费率为正时空仓收到资金费: %v", got)
+	}
+
+	// 止盈距离2%，30% = 0.6% > 0.3%
+	record := logger.DecisionAction{}
+	if err := at.checkFundingCost(&decision.Decision{Action: "open_long", TakeProfit: 102}, data, &record); err != nil {
+		t.Fatalf("资金费低于止盈距离的30%%应通过: %v", err)
+	}
+	if !strings.Contains(record.FundingNote, "止盈距离 2.00%") {
+		t.Errorf("应记录资金费估算: %s", record.FundingNote)
+	}
+
+	// 止盈距离0.5%，30% = 0.15% < 0.3%
+	err := at.checkFundingCost(&decision.Decision{Action: "open_long", TakeProfit: 100.5}, data, &logger.DecisionAction{})
+	if err == nil || !strings.Contains(err.Error(), "资金费成本过高") {
+		t.Fatalf("资金费超过止盈距离的30%%应拒绝: %v", err)
+	}
+
+	// 收取资金费的方向不限制
+	if err := at.checkFundingCost(&decision.Decision{Action: "open_short", TakeProfit: 99.5}, data, &logger.DecisionAction{}); err != nil {
+		t.Errorf("收取资金费时不应拒绝: %v", err)
+	}
+}
+
+func TestFundingBookedIntoTradeResults(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, paper := newLimitTestAutoTrader(t, feed, &now)
+	tr := &fundingPaperTrader{PaperTrader: paper}
+	at.trader = tr
+
+	d := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 500, StopLoss: 90, TakeProfit: 110}
+	if err := at.executeOpenLongWithRecord(d, &logger.DecisionAction{}); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+
+	// 开仓前的流水不计入，开仓后两次结算共支付1.0
+	tr.payments = []FundingPayment{
+		{Symbol: "BTCUSDT", Amount: -3, Timestamp: now.Add(-time.Hour).UnixMilli()},
+		{Symbol: "BTCUSDT", Amount: -0.4, Timestamp: now.Add(8 * time.Hour).UnixMilli()},
+		{Symbol: "BTCUSDT", Amount: -0.6, Timestamp: now.Add(16 * time.Hour).UnixMilli()},
+		{Symbol: "ETHUSDT", Amount: 5, Timestamp: now.Add(8 * time.Hour).UnixMilli()},
+	}
+	now = now.Add(17 * time.Hour)
+	if got := at.syncPositionFunding("BTCUSDT", "BTCUSDT_long"); math.Abs(got+1) > 1e-9 {
+		t.Fatalf("持仓累计资金费应为-1.0, got %.4f", got)
+	}
+
+	// 减仓一半：计入一半资金费
+	reduce := logger.DecisionAction{}
+	if err := at.executeAdjustWithRecord(&decision.Decision{Symbol: "BTCUSDT", Action: "reduce_long", SizePercent: 50}, &reduce); err != nil {
+		t.Fatalf("减仓失败: %v", err)
+	}
+	if math.Abs(reduce.Funding+0.5) > 1e-9 {
+		t.Errorf("减仓应计入一半资金费, got %.4f", reduce.Funding)
+	}
+
+	// 平仓：计入剩余资金费
+	closeRecord := logger.DecisionAction{}
+	if err := at.executeCloseLongWithRecord(&decision.Decision{Symbol: "BTCUSDT", Action: "close_long"}, &closeRecord); err != nil {
+		t.Fatalf("平仓失败: %v", err)
+	}
+	if math.Abs(closeRecord.Funding+0.5) > 1e-9 {
+		t.Errorf("平仓应计入剩余资金费, got %.4f", closeRecord.Funding)
+	}
+}
+
+func TestFundingAutoClose(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, paper := newLimitTestAutoTrader(t, feed, &now)
+	at.config.FundingGuard = FundingGuardConfig{MaxTPFraction: 0.3, AutoClose: true}
+	rate := 0.0
+	at.marketData = func(symbol string) (*market.Data, error) {
+		price, err := feed.get(symbol)
+		if err != nil {
+			return nil, err
+		}
+		return &market.Data{Symbol: symbol, CurrentPrice: price, FundingRate: rate}, nil
+	}
+
+	d := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 500, StopLoss: 90, TakeProfit: 102}
+	if err := at.executeOpenLongWithRecord(d, &logger.DecisionAction{}); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+
+	// 价格接近止盈，剩余约0.99%；费率0.09%时24小时资金费0.27% < 剩余空间的30%，不平仓
+	feed.set("BTCUSDT", 101)
+	rate = 0.0009
+	record := &logger.DecisionRecord{}
+	at.checkFundingAutoClose(record)
+	if len(record.Decisions) != 0 {
+		t.Fatalf("资金费未超过设定比例时不应平仓: %+v", record.Decisions)
+	}
+
+	// 费率升到0.2%：资金费0.6%超过剩余空间的30%
+	rate = 0.002
+	at.checkFundingAutoClose(record)
+	if len(record.Decisions) != 1 || !record.Decisions[0].Success || record.Decisions[0].Action != "close_long" {
+		t.Fatalf("应自动平仓: %+v", record.Decisions)
+	}
+	if !strings.Contains(record.ExecutionLog[0], "资金费平仓") {
+		t.Errorf("应记录资金费平仓原因: %v", record.ExecutionLog)
+	}
+	if _, ok := findPosition(t, paper, "BTCUSDT", "long"); ok {
+		t.Error("持仓应已平掉")
+	}
+}
+
+// memoryFundingStore 内存中的持仓资金费统计存储
+type memoryFundingStore map[string]string
+
+func (m memoryFundingStore) GetFundingState(traderID string) (string, error) {
+	return m[traderID], nil
+}
+
+func (m memoryFundingStore) SaveFundingState(traderID string, state string) error {
+	m[traderID] = state
+	return nil
+}
+
+func TestFundingWindowSurvivesRestart(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, paper := newLimitTestAutoTrader(t, feed, &now)
+	tr := &fundingPaperTrader{PaperTrader: paper}
+	at.trader = tr
+	store := memoryFundingStore{}
+	at.funding = newFundingTracker(store, at.id, at.name)
+
+	d := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 500, StopLoss: 90, TakeProfit: 110}
+	if err := at.executeOpenLongWithRecord(d, &logger.DecisionAction{}); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	tr.payments = []FundingPayment{
+		{Symbol: "BTCUSDT", Amount: -0.4, Timestamp: now.Add(8 * time.Hour).UnixMilli()},
+		{Symbol: "BTCUSDT", Amount: -0.6, Timestamp: now.Add(16 * time.Hour).UnixMilli()},
+	}
+
+	// 重启：内存中的持仓首次出现时间丢失，资金费统计起点从存储恢复
+	now = now.Add(17 * time.Hour)
+	at.funding = newFundingTracker(store, at.id, at.name)
+	at.positionFirstSeenTime = make(map[string]int64)
+	if since := at.funding.since("BTCUSDT_long"); !since.Equal(now.Add(-17 * time.Hour)) {
+		t.Fatalf("重启后应恢复开仓时间, got %v", since)
+	}
+	if _, err := at.buildTradingContext(); err != nil {
+		t.Fatalf("构建上下文失败: %v", err)
+	}
+	if got := at.positionFirstSeenTime["BTCUSDT_long"]; got != now.Add(-17*time.Hour).UnixMilli() {
+		t.Errorf("持仓首次出现时间应沿用恢复的开仓时间, got %d", got)
+	}
+
+	closeRecord := logger.DecisionAction{}
+	if err := at.executeCloseLongWithRecord(&decision.Decision{Symbol: "BTCUSDT", Action: "close_long"}, &closeRecord); err != nil {
+		t.Fatalf("平仓失败: %v", err)
+	}
+	if math.Abs(closeRecord.Funding+1) > 1e-9 {
+		t.Errorf("重启前结算的资金费应计入平仓结果, got %.4f", closeRecord.Funding)
+	}
+}
@@ -0,0 +1,1138 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gateFuturesPath Gate.io USDT结算永续合约接口前缀
+const gateFuturesPath = "/api/v4/futures/usdt"
+
+// gateErrorKinds Gate.io错误label到统一错误分类的映射
+var gateErrorKinds = map[string]ExchangeErrorKind{
+	"INVALID_KEY":             ExchangeErrAuth,
+	"INVALID_SIGNATURE":       ExchangeErrAuth,
+	"MISSING_REQUIRED_HEADER": ExchangeErrAuth,
+	"REQUEST_EXPIRED":         ExchangeErrAuth,
+	"TOO_MANY_REQUESTS":       ExchangeErrRateLimit,
+	"INSUFFICIENT_AVAILABLE":  ExchangeErrInsufficientMargin,
+	"BALANCE_NOT_ENOUGH":      ExchangeErrInsufficientMargin,
+	"ORDER_NOT_FOUND":         ExchangeErrOrderNotFound,
+	"ORDER_FINISHED":          ExchangeErrOrderNotFound,
+	"REDUCE_EXCEEDED":         ExchangeErrReduceOnly,
+	"POSITION_EMPTY":          ExchangeErrReduceOnly,
+	"SIZE_TOO_SMALL":          ExchangeErrInvalidQuantity,
+}
+
+// GateTrader Gate.io USDT永续合约交易器（API v4，双仓模式）
+// Gate.io按合约张数下单，1张对应quanto_multiplier个币，对外统一使用币的数量
+type GateTrader struct {
+	apiKey    string
+	secretKey string
+	rest      *restClient
+
+	// 余额和持仓缓存（15秒）
+	cache *accountCache
+
+	// 合约规格缓存（QtyStep即每张合约对应的币数量）
+	instruments *instrumentCache
+
+	// 账户是否已确认处于双仓模式
+	dualModeReady bool
+	dualModeMutex sync.Mutex
+
+	// 各合约的保证金模式（cross/isolated）
+	// Gate.io没有单独的保证金模式接口，通过调整杠杆切换：leverage=0为全仓，大于0为逐仓
+	marginModes      map[string]string
+	marginModesMutex sync.RWMutex
+
+	// 交易所不支持原生跟踪止损，在本地模拟
+	trailing *trailingStopEmulator
+}
+
+// decodeGateResponse 解析Gate.io响应：2xx时响应体即数据，否则为{label, message}
+func decodeGateResponse(status int, body []byte, out interface{}) (string, string, error) {
+	if status >= 200 && status < 300 {
+		if out != nil && len(body) > 0 {
+			if err := json.Unmarshal(body, out); err != nil {
+				return "", "", fmt.Errorf("解析响应数据失败: %w", err)
+			}
+		}
+		return "", "", nil
+	}
+
+	var apiErr struct {
+		Label   string `json:"label"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Label == "" {
+		return "", "", fmt.Errorf("解析响应失败 (HTTP %d): %s", status, string(body))
+	}
+	return apiErr.Label, apiErr.Message, nil
+}
+
+// NewGateTrader 创建Gate.io交易器
+func NewGateTrader(apiKey, secretKey string, testnet bool) (*GateTrader, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("API密钥不能为空")
+	}
+	if secretKey == "" {
+		return nil, fmt.Errorf("Secret密钥不能为空")
+	}
+
+	baseURL := "https://api.gateio.ws"
+	if testnet {
+		baseURL = "https://fx-api-testnet.gateio.ws"
+		log.Println("✅ Gate.io测试网模式已启用")
+	}
+
+	t := &GateTrader{
+		apiKey:      apiKey,
+		secretKey:   secretKey,
+		cache:       newAccountCache("Gate.io", 15*time.Second),
+		marginModes: make(map[string]string),
+	}
+	t.rest = newRESTClient("Gate.io", baseURL, t.sign, decodeGateResponse, gateErrorKinds)
+	t.instruments = newInstrumentCache(t.loadInstrument)
+	t.trailing = newTrailingStopEmulator("Gate.io", t)
+	return t, nil
+}
+
+// sign 添加API v4签名请求头
+// 签名原文: method\npath\nquerystring\nhex(SHA512(body))\ntimestamp，HMAC_SHA512后十六进制小写
+func (t *GateTrader) sign(req *restRequest) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	payload := strings.Join([]string{req.Method, req.Path, req.Query, sha512Hex(req.Body), timestamp}, "\n")
+	req.Header.Set("KEY", t.apiKey)
+	req.Header.Set("Timestamp", timestamp)
+	req.Header.Set("SIGN", hmacSHA512Hex(t.secretKey, payload))
+	req.Header.Set("Accept", "application/json")
+}
+
+// invalidateCache 下单或调整杠杆后清除余额和持仓缓存
+func (t *GateTrader) invalidateCache() {
+	t.cache.Invalidate()
+}
+
+// gateContract 交易对转换为Gate.io合约名（BTCUSDT -> BTC_USDT）
+func gateContract(symbol string) string {
+	if strings.Contains(symbol, "_") || !strings.HasSuffix(symbol, "USDT") {
+		return symbol
+	}
+	return strings.TrimSuffix(symbol, "USDT") + "_USDT"
+}
+
+// gateSymbol Gate.io合约名转换为交易对（BTC_USDT -> BTCUSDT）
+func gateSymbol(contract string) string {
+	return strings.ReplaceAll(contract, "_", "")
+}
+
+// gateContracts 币数量换算为合约张数（向下取整）
+func gateContracts(spec instrumentSpec, quantity float64) int64 {
+	if spec.QtyStep <= 0 {
+		return 0
+	}
+	return int64(math.Floor(quantity/spec.QtyStep + 1e-9))
+}
+
+// loadInstrument 从交易所查询合约规格（由instrumentCache缓存）
+func (t *GateTrader) loadInstrument(symbol string) (instrumentSpec, error) {
+	var info struct {
+		Name             string `json:"name"`
+		QuantoMultiplier string `json:"quanto_multiplier"`
+		OrderSizeMin     int64  `json:"order_size_min"`
+		OrderPriceRound  string `json:"order_price_round"`
+	}
+	if err := t.rest.get(gateFuturesPath+"/contracts/"+gateContract(symbol), nil, &info); err != nil {
+		return instrumentSpec{}, fmt.Errorf("获取Gate.io合约规格失败: %w", err)
+	}
+
+	multiplier := parseFloatOrZero(info.QuantoMultiplier)
+	if multiplier <= 0 {
+		return instrumentSpec{}, fmt.Errorf("Gate.io合约 %s 的quanto_multiplier无效: %s", info.Name, info.QuantoMultiplier)
+	}
+	log.Printf("📋 Gate.io合约规格 %s: quanto_multiplier=%s, order_size_min=%d, order_price_round=%s",
+		info.Name, info.QuantoMultiplier, info.OrderSizeMin, info.OrderPriceRound)
+	return instrumentSpec{
+		QtyStep:  multiplier,
+		MinQty:   float64(info.OrderSizeMin) * multiplier,
+		TickSize: parseFloatOrZero(info.OrderPriceRound),
+	}, nil
+}
+
+// FormatQuantity 格式化数量（向下取整到整张合约对应的币数量）
+func (t *GateTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	spec, err := t.instruments.Get(symbol)
+	if err != nil {
+		return "", err
+	}
+	return spec.FormatQuantity(quantity), nil
+}
+
+// orderContracts 下单数量换算为合约张数，不足最小下单量时返回错误
+func (t *GateTrader) orderContracts(symbol string, quantity float64) (int64, error) {
+	spec, err := t.instruments.Get(symbol)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := spec.FormatOrderQuantity(symbol, quantity); err != nil {
+		return 0, err
+	}
+	return gateContracts(spec, quantity), nil
+}
+
+// formatPrice 格式化价格到order_price_round的整数倍
+func (t *GateTrader) formatPrice(symbol string, price float64) (string, error) {
+	spec, err := t.instruments.Get(symbol)
+	if err != nil {
+		return "", err
+	}
+	return spec.FormatPrice(price), nil
+}
+
+// gateAccount 合约账户（total为不含未实现盈亏的钱包余额）
+type gateAccount struct {
+	Total         string `json:"total"`
+	UnrealisedPnl string `json:"unrealised_pnl"`
+	Available     string `json:"available"`
+	Currency      string `json:"currency"`
+	InDualMode    bool   `json:"in_dual_mode"`
+}
+
+// GetBalance 获取账户余额（带缓存）
+func (t *GateTrader) GetBalance() (*Balance, error) {
+	return t.cache.Balance(func() (*Balance, error) {
+		var account gateAccount
+		if err := t.rest.get(gateFuturesPath+"/accounts", nil, &account); err != nil {
+			return nil, fmt.Errorf("获取Gate.io余额失败: %w", err)
+		}
+
+		balance := &Balance{
+			WalletBalance:    parseFloatOrZero(account.Total),
+			UnrealizedProfit: parseFloatOrZero(account.UnrealisedPnl),
+			AvailableBalance: parseFloatOrZero(account.Available),
+		}
+		log.Printf("✅ Gate.io余额获取成功: total=%.2f, used=%.2f, free=%.2f",
+			balance.TotalEquity(), balance.UsedMargin(), balance.AvailableBalance)
+		return balance, nil
+	})
+}
+
+// gatePositionInfo 持仓信息（size为合约张数，双仓模式下空仓为负数）
+type gatePositionInfo struct {
+	Contract           string `json:"contract"`
+	Size               int64  `json:"size"`
+	Leverage           string `json:"leverage"`
+	CrossLeverageLimit string `json:"cross_leverage_limit"`
+	EntryPrice         string `json:"entry_price"`
+	MarkPrice          string `json:"mark_price"`
+	UnrealisedPnl      string `json:"unrealised_pnl"`
+	LiqPrice           string `json:"liq_price"`
+	Mode               string `json:"mode"` // single/dual_long/dual_short
+}
+
+// marginSettings 持仓记录中的保证金模式和杠杆（leverage=0表示全仓，杠杆为cross_leverage_limit）
+func (p gatePositionInfo) marginSettings() (string, int) {
+	if leverage := int(parseFloatOrZero(p.Leverage)); leverage > 0 {
+		return "isolated", leverage
+	}
+	return "cross", int(parseFloatOrZero(p.CrossLeverageLimit))
+}
+
+// gatePosition 将Gate.io持仓转换为统一格式（multiplier为每张合约对应的币数量，无持仓时返回false）
+func gatePosition(pos gatePositionInfo, multiplier float64) (Position, bool) {
+	if pos.Size == 0 {
+		return Position{}, false
+	}
+
+	side := "long"
+	switch pos.Mode {
+	case "dual_short":
+		side = "short"
+	case "dual_long":
+	default:
+		if pos.Size < 0 {
+			side = "short"
+		}
+	}
+	marginMode, leverage := pos.marginSettings()
+
+	return Position{
+		Symbol:           gateSymbol(pos.Contract),
+		Side:             side,
+		Quantity:         math.Abs(float64(pos.Size)) * multiplier,
+		EntryPrice:       parseFloatOrZero(pos.EntryPrice),
+		MarkPrice:        parseFloatOrZero(pos.MarkPrice),
+		UnrealizedProfit: parseFloatOrZero(pos.UnrealisedPnl),
+		Leverage:         leverage,
+		LiquidationPrice: parseFloatOrZero(pos.LiqPrice),
+		MarginMode:       marginMode,
+	}, true
+}
+
+// GetPositions 获取所有持仓（带缓存）
+func (t *GateTrader) GetPositions() ([]Position, error) {
+	return t.cache.Positions(func() ([]Position, error) {
+		var list []gatePositionInfo
+		if err := t.rest.get(gateFuturesPath+"/positions", url.Values{"holding": {"true"}}, &list); err != nil {
+			return nil, fmt.Errorf("获取Gate.io持仓失败: %w", err)
+		}
+
+		positions := []Position{}
+		for _, item := range list {
+			spec, err := t.instruments.Get(gateSymbol(item.Contract))
+			if err != nil {
+				return nil, err
+			}
+			if position, ok := gatePosition(item, spec.QtyStep); ok {
+				positions = append(positions, position)
+			}
+		}
+
+		log.Printf("✅ Gate.io持仓获取成功: %d个持仓", len(positions))
+		return positions, nil
+	})
+}
+
+// ensureDualMode 确保账户处于双仓模式（同一合约同时持有多仓和空仓）
+// 有持仓时Gate.io不允许切换，此时只记录警告，由随后的下单返回具体错误
+func (t *GateTrader) ensureDualMode() {
+	t.dualModeMutex.Lock()
+	defer t.dualModeMutex.Unlock()
+	if t.dualModeReady {
+		return
+	}
+
+	var account gateAccount
+	if err := t.rest.get(gateFuturesPath+"/accounts", nil, &account); err == nil && account.InDualMode {
+		t.dualModeReady = true
+		return
+	}
+
+	err := t.rest.do(http.MethodPost, gateFuturesPath+"/dual_mode", url.Values{"dual_mode": {"true"}}, nil, nil)
+	if err != nil {
+		log.Printf("⚠️ 设置Gate.io双仓模式失败: %v，继续尝试下单", err)
+		return
+	}
+	t.dualModeReady = true
+	log.Printf("✓ Gate.io账户已切换为双仓模式")
+}
+
+// isDualMode 账户是否已确认处于双仓模式
+func (t *GateTrader) isDualMode() bool {
+	t.dualModeMutex.Lock()
+	defer t.dualModeMutex.Unlock()
+	return t.dualModeReady
+}
+
+// positionPath 单个合约的持仓接口（双仓和单仓模式的路径不同）
+func (t *GateTrader) positionPath(contract string) string {
+	if t.isDualMode() {
+		return gateFuturesPath + "/dual_comp/positions/" + contract
+	}
+	return gateFuturesPath + "/positions/" + contract
+}
+
+// currentMarginSettings 查询合约当前的保证金模式和杠杆（没有持仓时也会返回持仓记录）
+func (t *GateTrader) currentMarginSettings(symbol string) (string, int, error) {
+	path := t.positionPath(gateContract(symbol))
+	var list []gatePositionInfo
+	if t.isDualMode() {
+		if err := t.rest.get(path, nil, &list); err != nil {
+			return "", 0, err
+		}
+	} else {
+		var pos gatePositionInfo
+		if err := t.rest.get(path, nil, &pos); err != nil {
+			return "", 0, err
+		}
+		list = append(list, pos)
+	}
+	if len(list) == 0 {
+		return "", 0, fmt.Errorf("未找到 %s 的持仓设置", symbol)
+	}
+
+	mode, leverage := list[0].marginSettings()
+	return mode, leverage, nil
+}
+
+// marginModeOf 合约使用的保证金模式（未设置过时以交易所当前设置为准）
+func (t *GateTrader) marginModeOf(symbol string) string {
+	t.marginModesMutex.RLock()
+	mode, ok := t.marginModes[symbol]
+	t.marginModesMutex.RUnlock()
+	if ok {
+		return mode
+	}
+
+	mode, _, err := t.currentMarginSettings(symbol)
+	if err != nil {
+		log.Printf("⚠️ 查询Gate.io保证金模式失败: %v，按全仓处理", err)
+		return "cross"
+	}
+	return mode
+}
+
+// applyLeverage 按保证金模式提交杠杆（全仓: leverage=0 + cross_leverage_limit，逐仓: leverage）
+func (t *GateTrader) applyLeverage(symbol, marginMode string, leverage int) error {
+	query := url.Values{}
+	if marginMode == "cross" {
+		query.Set("leverage", "0")
+		query.Set("cross_leverage_limit", strconv.Itoa(leverage))
+	} else {
+		query.Set("leverage", strconv.Itoa(leverage))
+	}
+	if err := t.rest.do(http.MethodPost, t.positionPath(gateContract(symbol))+"/leverage", query, nil, nil); err != nil {
+		return err
+	}
+	t.invalidateCache()
+	return nil
+}
+
+// SetLeverage 设置杠杆（沿用该合约的保证金模式）
+func (t *GateTrader) SetLeverage(symbol string, leverage int) error {
+	t.ensureDualMode()
+	if err := t.applyLeverage(symbol, t.marginModeOf(symbol), leverage); err != nil {
+		return fmt.Errorf("设置Gate.io杠杆失败: %w", err)
+	}
+
+	log.Printf("  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
+	return nil
+}
+
+// SetMarginMode 设置仓位模式
+// 与当前模式不同时立即以当前杠杆切换，之后的SetLeverage沿用该模式
+func (t *GateTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+	mode := "cross"
+	marginModeStr := "全仓"
+	if !isCrossMargin {
+		mode = "isolated"
+		marginModeStr = "逐仓"
+	}
+
+	t.marginModesMutex.Lock()
+	t.marginModes[symbol] = mode
+	t.marginModesMutex.Unlock()
+
+	t.ensureDualMode()
+	current, leverage, err := t.currentMarginSettings(symbol)
+	if err != nil {
+		return fmt.Errorf("设置Gate.io仓位模式失败: %w", err)
+	}
+	if current == mode {
+		log.Printf("  ✓ %s 仓位模式已是 %s", symbol, marginModeStr)
+		return nil
+	}
+	if leverage <= 0 {
+		// 当前杠杆未知，等下一次SetLeverage时按新模式提交
+		return nil
+	}
+
+	if err := t.applyLeverage(symbol, mode, leverage); err != nil {
+		return fmt.Errorf("设置Gate.io仓位模式失败: %w", err)
+	}
+	log.Printf("  ✓ %s 仓位模式已设置为 %s", symbol, marginModeStr)
+	return nil
+}
+
+// GetMarketPrice 获取市场价格
+func (t *GateTrader) GetMarketPrice(symbol string) (float64, error) {
+	var tickers []struct {
+		Contract  string `json:"contract"`
+		Last      string `json:"last"`
+		MarkPrice string `json:"mark_price"`
+	}
+	if err := t.rest.get(gateFuturesPath+"/tickers", url.Values{"contract": {gateContract(symbol)}}, &tickers); err != nil {
+		return 0, fmt.Errorf("获取Gate.io市场价格失败: %w", err)
+	}
+	if len(tickers) == 0 {
+		return 0, fmt.Errorf("未找到 %s 的价格", symbol)
+	}
+
+	price, err := strconv.ParseFloat(tickers[0].Last, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析价格失败: %w", err)
+	}
+	return price, nil
+}
+
+// gateOrder 订单信息（size为合约张数，正数买入、负数卖出；left为未成交张数）
+type gateOrder struct {
+	ID           int64   `json:"id"`
+	Contract     string  `json:"contract"`
+	Size         int64   `json:"size"`
+	Left         int64   `json:"left"`
+	Price        string  `json:"price"`
+	FillPrice    string  `json:"fill_price"`
+	Status       string  `json:"status"`    // open/finished
+	FinishAs     string  `json:"finish_as"` // filled/cancelled/ioc/poc/reduce_only/...
+	Tif          string  `json:"tif"`
+	IsReduceOnly bool    `json:"is_reduce_only"`
+	CreateTime   float64 `json:"create_time"`
+	FinishTime   float64 `json:"finish_time"`
+}
+
+// absInt64 合约张数的绝对值
+func absInt64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// gateOrderStatus 将Gate.io订单状态转换为统一格式
+// IOC/FOK订单未能成交(finish_as=ioc)和只做Maker订单会立即成交(finish_as=poc)时视为过期，与币安的EXPIRED一致
+func gateOrderStatus(o *gateOrder) string {
+	if o.Status == "open" {
+		if absInt64(o.Left) != absInt64(o.Size) {
+			return OrderStatusPartiallyFilled
+		}
+		return OrderStatusNew
+	}
+
+	switch o.FinishAs {
+	case "filled":
+		return OrderStatusFilled
+	case "ioc", "poc":
+		return OrderStatusExpired
+	default:
+		// cancelled、reduce_only、position_closed、stp等
+		return OrderStatusCanceled
+	}
+}
+
+// toOrderResult 将Gate.io订单转换为统一格式（multiplier为每张合约对应的币数量）
+// 双仓模式下买入开多、卖出开空，只减仓订单则相反
+func (o *gateOrder) toOrderResult(multiplier float64) *OrderResult {
+	side := "BUY"
+	if o.Size < 0 {
+		side = "SELL"
+	}
+	positionSide := "LONG"
+	if (o.Size < 0) != o.IsReduceOnly {
+		positionSide = "SHORT"
+	}
+	orderType := "LIMIT"
+	if parseFloatOrZero(o.Price) == 0 {
+		orderType = "MARKET"
+	}
+	executed := float64(absInt64(o.Size) - absInt64(o.Left))
+	updateTime := o.FinishTime
+	if updateTime == 0 {
+		updateTime = o.CreateTime
+	}
+
+	return &OrderResult{
+		OrderID:      strconv.FormatInt(o.ID, 10),
+		Symbol:       gateSymbol(o.Contract),
+		Status:       gateOrderStatus(o),
+		Type:         orderType,
+		Side:         side,
+		PositionSide: positionSide,
+		Price:        parseFloatOrZero(o.Price),
+		Quantity:     math.Abs(float64(o.Size)) * multiplier,
+		ExecutedQty:  executed * multiplier,
+		AvgPrice:     parseFloatOrZero(o.FillPrice),
+		Time:         int64(o.CreateTime * 1000),
+		UpdateTime:   int64(updateTime * 1000),
+	}
+}
+
+// placeOrder 提交订单（price为"0"、tif为ioc表示市价单）
+func (t *GateTrader) placeOrder(symbol string, size int64, price, tif string, reduceOnly bool) (*OrderResult, error) {
+	spec, err := t.instruments.Get(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"contract": gateContract(symbol),
+		"size":     size,
+		"price":    price,
+		"tif":      tif,
+	}
+	if reduceOnly {
+		body["reduce_only"] = true
+	}
+	log.Printf("📤 Gate.io下单请求: %v", body)
+
+	var order gateOrder
+	if err := t.rest.post(gateFuturesPath+"/orders", body, &order); err != nil {
+		return nil, err
+	}
+	t.invalidateCache()
+	return order.toOrderResult(spec.QtyStep), nil
+}
+
+// openPosition 市价开仓
+func (t *GateTrader) openPosition(symbol, positionSide string, quantity float64, leverage int) (*OrderResult, error) {
+	// 先取消该合约的条件单（清理旧的止损止盈单，保留未成交的限价单）
+	if err := t.cancelPriceOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消旧条件单失败（可能没有条件单）: %v", err)
+	}
+
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	contracts, err := t.orderContracts(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(positionSide, "short") {
+		contracts = -contracts
+	}
+
+	result, err := t.placeOrder(symbol, contracts, "0", "ioc", false)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Gate.io开仓成功: %s %s 数量: %.6f 订单ID: %s", symbol, positionSide, result.Quantity, result.OrderID)
+	return result, nil
+}
+
+// OpenLong 开多仓
+func (t *GateTrader) OpenLong(symbol string, quantity float64, leverage int) (*OrderResult, error) {
+	result, err := t.openPosition(symbol, "LONG", quantity, leverage)
+	if err != nil {
+		return nil, fmt.Errorf("开多仓失败: %w", err)
+	}
+	return result, nil
+}
+
+// OpenShort 开空仓
+func (t *GateTrader) OpenShort(symbol string, quantity float64, leverage int) (*OrderResult, error) {
+	result, err := t.openPosition(symbol, "SHORT", quantity, leverage)
+	if err != nil {
+		return nil, fmt.Errorf("开空仓失败: %w", err)
+	}
+	return result, nil
+}
+
+// closePosition 市价平仓（quantity=0或超过持仓时全部平仓），全部平仓后取消该合约的条件单
+func (t *GateTrader) closePosition(symbol, side string, quantity float64) (*OrderResult, error) {
+	positions, err := t.GetPositions()
+	if err != nil {
+		return nil, err
+	}
+
+	var positionSize float64
+	for _, pos := range positions {
+		if pos.Symbol == symbol && pos.Side == side {
+			positionSize = pos.Quantity
+			break
+		}
+	}
+	if positionSize <= 0 {
+		return nil, fmt.Errorf("没有找到 %s 的%s持仓", symbol, side)
+	}
+
+	closeAll := quantity <= 0 || quantity >= positionSize
+	if closeAll {
+		quantity = positionSize
+	}
+
+	spec, err := t.instruments.Get(symbol)
+	if err != nil {
+		return nil, err
+	}
+	contracts := gateContracts(spec, quantity)
+	if contracts <= 0 {
+		return nil, fmt.Errorf("平仓数量过小: %s 每张合约 %v，当前 %v", symbol, spec.QtyStep, quantity)
+	}
+	// 平多卖出、平空买入
+	if side == "long" {
+		contracts = -contracts
+	}
+
+	result, err := t.placeOrder(symbol, contracts, "0", "ioc", true)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ Gate.io平仓成功: %s %s 数量: %.6f", symbol, side, result.Quantity)
+
+	if closeAll {
+		if err := t.cancelPriceOrders(symbol); err != nil {
+			log.Printf("  ⚠ 取消条件单失败: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// CloseLong 平多仓
+func (t *GateTrader) CloseLong(symbol string, quantity float64) (*OrderResult, error) {
+	result, err := t.closePosition(symbol, "long", quantity)
+	if err != nil {
+		return nil, fmt.Errorf("平多仓失败: %w", err)
+	}
+	return result, nil
+}
+
+// CloseShort 平空仓
+func (t *GateTrader) CloseShort(symbol string, quantity float64) (*OrderResult, error) {
+	result, err := t.closePosition(symbol, "short", quantity)
+	if err != nil {
+		return nil, fmt.Errorf("平空仓失败: %w", err)
+	}
+	return result, nil
+}
+
+// placePriceOrder 提交止盈止损条件单（kind: sl/tp），按最新价触发后市价只减仓
+// rule: 1=最新价大于等于触发价时触发，2=最新价小于等于触发价时触发
+func (t *GateTrader) placePriceOrder(symbol, positionSide string, quantity, triggerPrice float64, kind string) error {
+	isLong := !strings.EqualFold(positionSide, "short")
+
+	// 多仓止损和空仓止盈在价格下跌时触发
+	rule := 1
+	if isLong == (kind == "sl") {
+		rule = 2
+	}
+
+	spec, err := t.instruments.Get(symbol)
+	if err != nil {
+		return err
+	}
+	contracts := gateContracts(spec, quantity)
+	if contracts <= 0 {
+		return fmt.Errorf("条件单数量过小: %s 每张合约 %v，当前 %v", symbol, spec.QtyStep, quantity)
+	}
+	orderType := "plan-close-short-position"
+	if isLong {
+		contracts = -contracts
+		orderType = "plan-close-long-position"
+	}
+
+	body := map[string]interface{}{
+		"initial": map[string]interface{}{
+			"contract":    gateContract(symbol),
+			"size":        contracts,
+			"price":       "0",
+			"tif":         "ioc",
+			"reduce_only": true,
+		},
+		"trigger": map[string]interface{}{
+			"strategy_type": 0, // 按价格触发
+			"price_type":    0, // 最新成交价
+			"price":         spec.FormatPrice(triggerPrice),
+			"rule":          rule,
+		},
+		"order_type": orderType,
+	}
+	if err := t.rest.post(gateFuturesPath+"/price_orders", body, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetStopLoss 设置止损单
+func (t *GateTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	if err := t.placePriceOrder(symbol, positionSide, quantity, stopPrice, "sl"); err != nil {
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
+
+	log.Printf("  止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// SetTakeProfit 设置止盈单
+func (t *GateTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	if err := t.placePriceOrder(symbol, positionSide, quantity, takeProfitPrice, "tp"); err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
+	}
+
+	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
+
+// trailingStops 本地跟踪止损模拟器（由AutoTrader接管触发平仓和持久化）
+func (t *GateTrader) trailingStops() *trailingStopEmulator {
+	return t.trailing
+}
+
+// SetTrailingStop 设置跟踪止损（本地根据实时行情模拟，触发后市价平仓）
+func (t *GateTrader) SetTrailingStop(symbol string, positionSide string, quantity, callbackRate, activationPrice float64) error {
+	if err := t.trailing.Set(symbol, positionSide, quantity, callbackRate, activationPrice); err != nil {
+		return fmt.Errorf("设置跟踪止损失败: %w", err)
+	}
+	return nil
+}
+
+// gatePriceOrder 价格触发单
+type gatePriceOrder struct {
+	ID      int64 `json:"id"`
+	Initial struct {
+		Contract string `json:"contract"`
+		Size     int64  `json:"size"` // 负数为卖出（平多）
+	} `json:"initial"`
+	Trigger struct {
+		Price string `json:"price"`
+		Rule  int    `json:"rule"`
+	} `json:"trigger"`
+	OrderType string `json:"order_type"`
+}
+
+// GetProtectiveOrders 获取止盈止损条件单（由平仓方向和触发规则区分止损和止盈）
+func (t *GateTrader) GetProtectiveOrders(symbol string) ([]ProtectiveOrder, error) {
+	query := url.Values{"status": {"open"}}
+	if symbol != "" {
+		query.Set("contract", gateContract(symbol))
+	}
+
+	var orders []gatePriceOrder
+	if err := t.rest.get(gateFuturesPath+"/price_orders", query, &orders); err != nil {
+		return nil, fmt.Errorf("获取条件单失败: %w", err)
+	}
+
+	result := []ProtectiveOrder{}
+	for _, order := range orders {
+		if order.Trigger.Rule != 1 && order.Trigger.Rule != 2 {
+			continue
+		}
+		orderSymbol := gateSymbol(order.Initial.Contract)
+		spec, err := t.instruments.Get(orderSymbol)
+		if err != nil {
+			return nil, err
+		}
+
+		positionSide := "SHORT"
+		if order.Initial.Size < 0 || order.OrderType == "plan-close-long-position" {
+			positionSide = "LONG"
+		}
+		// 多仓止损和空仓止盈在价格下跌时触发
+		kind := ProtectiveKindTakeProfit
+		if (positionSide == "LONG") == (order.Trigger.Rule == 2) {
+			kind = ProtectiveKindStopLoss
+		}
+
+		size := order.Initial.Size
+		if size < 0 {
+			size = -size
+		}
+		result = append(result, ProtectiveOrder{
+			OrderID:       strconv.FormatInt(order.ID, 10),
+			Symbol:        orderSymbol,
+			PositionSide:  positionSide,
+			Kind:          kind,
+			TriggerPrice:  parseFloatOrZero(order.Trigger.Price),
+			Quantity:      float64(size) * spec.QtyStep,
+			ClosePosition: size == 0,
+		})
+	}
+	return result, nil
+}
+
+// CancelProtectiveOrder 取消单个止盈止损条件单
+func (t *GateTrader) CancelProtectiveOrder(symbol string, orderID string) error {
+	if err := t.rest.do(http.MethodDelete, gateFuturesPath+"/price_orders/"+orderID, nil, nil, nil); err != nil {
+		return fmt.Errorf("取消条件单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已取消 %s 条件单 %s", symbol, orderID)
+	return nil
+}
+
+// cancelPriceOrders 取消该合约的所有条件单（止盈止损）
+func (t *GateTrader) cancelPriceOrders(symbol string) error {
+	query := url.Values{"contract": {gateContract(symbol)}}
+	return t.rest.do(http.MethodDelete, gateFuturesPath+"/price_orders", query, nil, nil)
+}
+
+// CancelAllOrders 取消该合约的所有挂单（包括限价单和条件单）
+func (t *GateTrader) CancelAllOrders(symbol string) error {
+	t.trailing.Cancel(symbol)
+	query := url.Values{"contract": {gateContract(symbol)}}
+	if err := t.rest.do(http.MethodDelete, gateFuturesPath+"/orders", query, nil, nil); err != nil {
+		return fmt.Errorf("取消挂单失败: %w", err)
+	}
+	if err := t.cancelPriceOrders(symbol); err != nil {
+		return fmt.Errorf("取消条件单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	return nil
+}
+
+// gateTimeInForce 转换为Gate.io的有效方式（poc即只做Maker）
+func gateTimeInForce(tif TimeInForce) (string, error) {
+	switch tif {
+	case TimeInForceGTC, "":
+		return "gtc", nil
+	case TimeInForceIOC:
+		return "ioc", nil
+	case TimeInForceFOK:
+		return "fok", nil
+	case TimeInForcePostOnly:
+		return "poc", nil
+	default:
+		return "", fmt.Errorf("不支持的限价单有效方式: %s", tif)
+	}
+}
+
+// PlaceLimitOrder 下限价开仓单
+func (t *GateTrader) PlaceLimitOrder(symbol string, positionSide string, quantity, price float64, leverage int, tif TimeInForce) (*OrderResult, error) {
+	timeInForce, err := gateTimeInForce(tif)
+	if err != nil {
+		return nil, err
+	}
+
+	// 设置杠杆（限价单不取消旧委托单，避免撤掉其他挂单）
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	contracts, err := t.orderContracts(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(positionSide, "short") {
+		contracts = -contracts
+	}
+	priceStr, err := t.formatPrice(symbol, price)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := t.placeOrder(symbol, contracts, priceStr, timeInForce, false)
+	if err != nil {
+		return nil, fmt.Errorf("下限价单失败: %w", err)
+	}
+
+	log.Printf("✓ 限价单已提交: %s %s 数量: %.6f 价格: %s (%s) 订单ID: %s", symbol, positionSide, result.Quantity, priceStr, tif, result.OrderID)
+	return result, nil
+}
+
+// getOrder 查询交易所原始订单
+func (t *GateTrader) getOrder(orderID string) (*gateOrder, error) {
+	var order gateOrder
+	if err := t.rest.get(gateFuturesPath+"/orders/"+orderID, nil, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// AmendOrder 修改未成交限价单的价格和数量（Gate.io的size包含已成交部分，方向必须与原订单一致）
+func (t *GateTrader) AmendOrder(symbol string, orderID string, quantity, price float64) (*OrderResult, error) {
+	original, err := t.getOrder(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("修改订单失败: %w", err)
+	}
+
+	body := map[string]interface{}{}
+	if quantity > 0 {
+		contracts, err := t.orderContracts(symbol, quantity)
+		if err != nil {
+			return nil, err
+		}
+		if original.Size < 0 {
+			contracts = -contracts
+		}
+		body["size"] = contracts
+	}
+	if price > 0 {
+		priceStr, err := t.formatPrice(symbol, price)
+		if err != nil {
+			return nil, err
+		}
+		body["price"] = priceStr
+	}
+
+	var order gateOrder
+	if err := t.rest.do(http.MethodPut, gateFuturesPath+"/orders/"+orderID, nil, body, &order); err != nil {
+		return nil, fmt.Errorf("修改订单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已修改订单 %s: 张数 %v 价格 %v", orderID, body["size"], body["price"])
+
+	spec, err := t.instruments.Get(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return order.toOrderResult(spec.QtyStep), nil
+}
+
+// CancelOrder 取消单个订单
+func (t *GateTrader) CancelOrder(symbol string, orderID string) error {
+	if err := t.rest.do(http.MethodDelete, gateFuturesPath+"/orders/"+orderID, nil, nil, nil); err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已取消 %s 订单 %s", symbol, orderID)
+	return nil
+}
+
+// GetOrder 查询单个订单（Gate.io订单不包含手续费，有成交时从成交记录中汇总）
+func (t *GateTrader) GetOrder(symbol string, orderID string) (*OrderResult, error) {
+	order, err := t.getOrder(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	spec, err := t.instruments.Get(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	result := order.toOrderResult(spec.QtyStep)
+	if result.ExecutedQty > 0 {
+		trades, err := t.listTrades("/my_trades", url.Values{
+			"contract": {gateContract(symbol)},
+			"order":    {orderID},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("查询订单成交失败: %w", err)
+		}
+		for _, trade := range trades {
+			result.Fee += parseFloatOrZero(trade.Fee)
+		}
+	}
+	return result, nil
+}
+
+// GetOpenOrders 获取未成交挂单（symbol为空表示所有合约）
+func (t *GateTrader) GetOpenOrders(symbol string) ([]OrderResult, error) {
+	query := url.Values{"status": {"open"}}
+	if symbol != "" {
+		query.Set("contract", gateContract(symbol))
+	}
+
+	var orders []gateOrder
+	if err := t.rest.get(gateFuturesPath+"/orders", query, &orders); err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	result := make([]OrderResult, 0, len(orders))
+	for i := range orders {
+		spec, err := t.instruments.Get(gateSymbol(orders[i].Contract))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *orders[i].toOrderResult(spec.QtyStep))
+	}
+	return result, nil
+}
+
+// gateTrade 成交记录（size为合约张数，正数买入、负数卖出）
+type gateTrade struct {
+	ID         int64   `json:"id"`
+	CreateTime float64 `json:"create_time"`
+	Contract   string  `json:"contract"`
+	OrderID    string  `json:"order_id"`
+	Size       int64   `json:"size"`
+	Price      string  `json:"price"`
+	Role       string  `json:"role"` // taker/maker
+	Fee        string  `json:"fee"`
+}
+
+// gateFill 将Gate.io成交记录转换为统一格式（fee为正表示支付的手续费）
+func gateFill(trade gateTrade, multiplier float64) Fill {
+	side := "buy"
+	if trade.Size < 0 {
+		side = "sell"
+	}
+
+	return Fill{
+		FillID:      strconv.FormatInt(trade.ID, 10),
+		OrderID:     trade.OrderID,
+		Symbol:      gateSymbol(trade.Contract),
+		Side:        side,
+		Price:       parseFloatOrZero(trade.Price),
+		Quantity:    math.Abs(float64(trade.Size)) * multiplier,
+		Fee:         parseFloatOrZero(trade.Fee),
+		FeeCurrency: "USDT",
+		Role:        trade.Role,
+		Timestamp:   int64(trade.CreateTime * 1000),
+	}
+}
+
+// listTrades 查询成交记录（endpoint: /my_trades 或 /my_trades_timerange）
+func (t *GateTrader) listTrades(endpoint string, query url.Values) ([]gateTrade, error) {
+	var trades []gateTrade
+	if err := t.rest.get(gateFuturesPath+endpoint, query, &trades); err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+// GetFills 获取自since以来的成交记录（symbol为空表示所有合约）
+func (t *GateTrader) GetFills(symbol string, since time.Time) ([]Fill, error) {
+	query := url.Values{
+		"from":  {strconv.FormatInt(since.Unix(), 10)},
+		"to":    {strconv.FormatInt(time.Now().Unix()+1, 10)},
+		"limit": {"1000"},
+	}
+	if symbol != "" {
+		query.Set("contract", gateContract(symbol))
+	}
+
+	trades, err := t.listTrades("/my_trades_timerange", query)
+	if err != nil {
+		return nil, fmt.Errorf("获取成交记录失败: %w", err)
+	}
+
+	fills := make([]Fill, 0, len(trades))
+	for _, trade := range trades {
+		// from参数精确到秒，按毫秒时间再过滤一次
+		if int64(trade.CreateTime*1000) < since.UnixMilli() {
+			continue
+		}
+		spec, err := t.instruments.Get(gateSymbol(trade.Contract))
+		if err != nil {
+			return nil, err
+		}
+		fills = append(fills, gateFill(trade, spec.QtyStep))
+	}
+	return fills, nil
+}
+
+// gateAccountBook 账户流水（type=fund为资金费，change为正表示收到）
+type gateAccountBook struct {
+	Time     float64 `json:"time"`
+	Change   string  `json:"change"`
+	Type     string  `json:"type"`
+	Contract string  `json:"contract"`
+}
+
+// GetFundingPayments 获取自since以来的资金费结算记录（symbol为空表示所有合约）
+func (t *GateTrader) GetFundingPayments(symbol string, since time.Time) ([]FundingPayment, error) {
+	query := url.Values{
+		"type":  {"fund"},
+		"from":  {strconv.FormatInt(since.Unix(), 10)},
+		"limit": {"1000"},
+	}
+	if symbol != "" {
+		query.Set("contract", gateContract(symbol))
+	}
+
+	var books []gateAccountBook
+	if err := t.rest.get(gateFuturesPath+"/account_book", query, &books); err != nil {
+		return nil, fmt.Errorf("获取资金费记录失败: %w", err)
+	}
+
+	payments := make([]FundingPayment, 0, len(books))
+	for _, book := range books {
+		// from参数精确到秒，按毫秒时间再过滤一次
+		if book.Type != "fund" || int64(book.Time*1000) < since.UnixMilli() {
+			continue
+		}
+		payments = append(payments, gateFundingPayment(book))
+	}
+	return payments, nil
+}
+
+// gateFundingPayment 将资金费流水转换为统一格式
+func gateFundingPayment(book gateAccountBook) FundingPayment {
+	return FundingPayment{
+		Symbol:    gateSymbol(book.Contract),
+		Amount:    parseFloatOrZero(book.Change),
+		Timestamp: int64(book.Time * 1000),
+	}
+}
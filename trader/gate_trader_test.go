@@ -0,0 +1,305 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestGateTrader 创建连接到模拟Gate.io服务器的交易器
+func newTestGateTrader(t *testing.T, ex *fakeExchange) (*GateTrader, *fakeGateAPI) {
+	t.Helper()
+	srv, api := newFakeGateServer(t, ex)
+	tr, err := NewGateTrader("test-key", fakeGateSecret, false)
+	if err != nil {
+		t.Fatalf("创建Gate.io交易器失败: %v", err)
+	}
+	tr.rest.baseURL = srv.URL
+	return tr, api
+}
+
+func TestNewGateTrader(t *testing.T) {
+	if _, err := NewGateTrader("", "secret", false); err == nil {
+		t.Error("API密钥为空时应返回错误")
+	}
+	if _, err := NewGateTrader("key", "", false); err == nil {
+		t.Error("Secret密钥为空时应返回错误")
+	}
+
+	tr, err := NewGateTrader("key", "secret", true)
+	if err != nil {
+		t.Fatalf("创建Gate.io交易器失败: %v", err)
+	}
+	if tr.rest.baseURL != "https://fx-api-testnet.gateio.ws" {
+		t.Errorf("测试网地址错误: %s", tr.rest.baseURL)
+	}
+}
+
+func TestGateRejectsInvalidSignature(t *testing.T) {
+	srv, _ := newFakeGateServer(t, newFakeExchange())
+	tr, err := NewGateTrader("test-key", "wrong-secret", false)
+	if err != nil {
+		t.Fatalf("创建Gate.io交易器失败: %v", err)
+	}
+	tr.rest.baseURL = srv.URL
+
+	_, err = tr.GetBalance()
+	if exchangeErrorCode(err) != gateErrInvalidSignature || !IsExchangeErrorKind(err, ExchangeErrAuth) {
+		t.Fatalf("签名错误时应返回%s鉴权错误, got: %v", gateErrInvalidSignature, err)
+	}
+}
+
+func TestGateGetBalance(t *testing.T) {
+	ex := newFakeExchange()
+	tr, _ := newTestGateTrader(t, ex)
+
+	if _, err := tr.OpenLong("BTCUSDT", 0.1, 10); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	ex.mu.Lock()
+	ex.specs["BTCUSDT"] = fakeSymbolSpec{Price: 51000, QtyStep: 0.001, TickSize: 0.1}
+	ex.mu.Unlock()
+
+	balance, err := tr.GetBalance()
+	if err != nil {
+		t.Fatalf("获取余额失败: %v", err)
+	}
+	// 0.1 BTC 从50000涨到51000，未实现盈亏100；10倍杠杆占用保证金500
+	if !almostEqual(balance.WalletBalance, 10000) || !almostEqual(balance.UnrealizedProfit, 100) {
+		t.Errorf("余额转换错误: %+v", balance)
+	}
+	if !almostEqual(balance.AvailableBalance, 9600) || !almostEqual(balance.UsedMargin(), 500) {
+		t.Errorf("可用保证金应为9600、占用500: %+v", balance)
+	}
+}
+
+func TestGateSwitchesToDualMode(t *testing.T) {
+	tr, api := newTestGateTrader(t, newFakeExchange())
+	if api.isDualMode() {
+		t.Fatal("模拟账户初始应为单仓模式")
+	}
+
+	if _, err := tr.OpenShort("BTCUSDT", 0.01, 5); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	if !api.isDualMode() {
+		t.Error("开仓前应切换到双仓模式")
+	}
+
+	// 重新确认时账户已是双仓模式，不再切换
+	tr.dualModeReady = false
+	if _, err := tr.OpenLong("BTCUSDT", 0.01, 5); err != nil {
+		t.Fatalf("双仓模式下开反向仓失败: %v", err)
+	}
+	requirePosition(t, tr, "BTCUSDT", "long")
+	requirePosition(t, tr, "BTCUSDT", "short")
+}
+
+func TestGateLimitOrderLifecycle(t *testing.T) {
+	tr, _ := newTestGateTrader(t, newFakeExchange())
+
+	// 低于市价的买单挂单等待，价格按order_price_round取整
+	order, err := tr.PlaceLimitOrder("BTCUSDT", "LONG", 0.01, 49000.04, 5, TimeInForceGTC)
+	if err != nil {
+		t.Fatalf("下限价单失败: %v", err)
+	}
+	if order.OrderID == "" || order.Status != OrderStatusNew || !almostEqual(order.Price, 49000) || !almostEqual(order.Quantity, 0.01) {
+		t.Errorf("限价单结果错误: %+v", order)
+	}
+
+	open, err := tr.GetOpenOrders("BTCUSDT")
+	if err != nil {
+		t.Fatalf("获取挂单失败: %v", err)
+	}
+	if len(open) != 1 || open[0].OrderID != order.OrderID || open[0].PositionSide != "LONG" {
+		t.Fatalf("挂单列表错误: %+v", open)
+	}
+
+	amended, err := tr.AmendOrder("BTCUSDT", order.OrderID, 0.02, 49500)
+	if err != nil {
+		t.Fatalf("修改订单失败: %v", err)
+	}
+	if amended.OrderID != order.OrderID || !almostEqual(amended.Price, 49500) || !almostEqual(amended.Quantity, 0.02) {
+		t.Errorf("修改后的订单错误: %+v", amended)
+	}
+
+	if err := tr.CancelOrder("BTCUSDT", order.OrderID); err != nil {
+		t.Fatalf("取消订单失败: %v", err)
+	}
+	cancelled, err := tr.GetOrder("BTCUSDT", order.OrderID)
+	if err != nil {
+		t.Fatalf("查询订单失败: %v", err)
+	}
+	if cancelled.Status != OrderStatusCanceled {
+		t.Errorf("取消后订单状态应为 %s, got %s", OrderStatusCanceled, cancelled.Status)
+	}
+	err = tr.CancelOrder("BTCUSDT", order.OrderID)
+	if !IsExchangeErrorKind(err, ExchangeErrOrderNotFound) || !strings.Contains(err.Error(), gateErrorMessages[gateErrOrderNotFound]) {
+		t.Errorf("重复取消应返回交易所原生错误, got: %v", err)
+	}
+
+	// 空单在高于市价处挂单：张数为负，持仓方向为SHORT
+	short, err := tr.PlaceLimitOrder("BTCUSDT", "SHORT", 0.01, 51000, 5, TimeInForceGTC)
+	if err != nil {
+		t.Fatalf("下空单失败: %v", err)
+	}
+	if short.Side != "SELL" || short.PositionSide != "SHORT" || short.Status != OrderStatusNew {
+		t.Errorf("空单结果错误: %+v", short)
+	}
+
+	// 无法立即成交的IOC单视为过期
+	ioc, err := tr.PlaceLimitOrder("BTCUSDT", "LONG", 0.01, 49000, 5, TimeInForceIOC)
+	if err != nil {
+		t.Fatalf("下IOC单失败: %v", err)
+	}
+	if ioc.Status != OrderStatusExpired {
+		t.Errorf("未成交的IOC单状态应为 %s, got %+v", OrderStatusExpired, ioc)
+	}
+}
+
+func TestGateOrderAndFillQueries(t *testing.T) {
+	tr, _ := newTestGateTrader(t, newFakeExchange())
+	since := time.Now().Add(-time.Minute)
+
+	order, err := tr.OpenLong("BTCUSDT", 0.02, 5)
+	if err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+
+	details, err := tr.GetOrder("BTCUSDT", order.OrderID)
+	if err != nil {
+		t.Fatalf("查询订单失败: %v", err)
+	}
+	if details.Status != OrderStatusFilled || !almostEqual(details.AvgPrice, 50000) || !almostEqual(details.ExecutedQty, 0.02) {
+		t.Errorf("订单查询结果错误: %+v", details)
+	}
+	// 订单本身不含手续费，从该订单的成交记录汇总
+	if !almostEqual(details.Fee, 50000*0.02*fakeGateTakerFee) || details.Side != "BUY" || details.Type != "MARKET" {
+		t.Errorf("订单手续费或方向错误: %+v", details)
+	}
+
+	fills, err := tr.GetFills("BTCUSDT", since)
+	if err != nil {
+		t.Fatalf("获取成交记录失败: %v", err)
+	}
+	if len(fills) != 1 {
+		t.Fatalf("应有1条成交记录, got %d", len(fills))
+	}
+	fill := fills[0]
+	if fill.OrderID != order.OrderID || fill.Side != "buy" || fill.Role != "taker" || fill.Symbol != "BTCUSDT" {
+		t.Errorf("成交记录转换错误: %+v", fill)
+	}
+	if !almostEqual(fill.Price, 50000) || !almostEqual(fill.Quantity, 0.02) || !almostEqual(fill.Fee, details.Fee) {
+		t.Errorf("成交价格、数量或手续费错误: %+v", fill)
+	}
+
+	if fills, _ := tr.GetFills("BTCUSDT", time.Now().Add(time.Minute)); len(fills) != 0 {
+		t.Errorf("since之后没有成交, got %d", len(fills))
+	}
+}
+
+func TestGateCancelAllOrders(t *testing.T) {
+	ex := newFakeExchange()
+	tr, _ := newTestGateTrader(t, ex)
+
+	if _, err := tr.OpenLong("BTCUSDT", 0.01, 5); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	if err := tr.SetStopLoss("BTCUSDT", "LONG", 0.01, 48000); err != nil {
+		t.Fatalf("设置止损失败: %v", err)
+	}
+	if _, err := tr.PlaceLimitOrder("BTCUSDT", "LONG", 0.01, 49000, 5, TimeInForceGTC); err != nil {
+		t.Fatalf("下限价单失败: %v", err)
+	}
+
+	// 部分平仓保留止损单
+	if _, err := tr.CloseLong("BTCUSDT", 0.005); err != nil {
+		t.Fatalf("部分平仓失败: %v", err)
+	}
+	if _, ok := ex.trigger("BTCUSDT", "long", "sl"); !ok {
+		t.Error("部分平仓后止损单不应被取消")
+	}
+
+	if err := tr.CancelAllOrders("BTCUSDT"); err != nil {
+		t.Fatalf("取消所有挂单失败: %v", err)
+	}
+	if _, ok := ex.trigger("BTCUSDT", "long", "sl"); ok {
+		t.Error("止损单应被取消")
+	}
+	open, err := tr.GetOpenOrders("")
+	if err != nil {
+		t.Fatalf("获取挂单失败: %v", err)
+	}
+	if len(open) != 0 {
+		t.Errorf("取消后不应有挂单: %+v", open)
+	}
+}
+
+func TestGatePosition(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		pos        gatePositionInfo
+		multiplier float64
+		want       Position
+		ok         bool
+	}{
+		{
+			name: "dual_short_isolated",
+			pos: gatePositionInfo{Contract: "ETH_USDT", Size: -150, Leverage: "10", CrossLeverageLimit: "0", EntryPrice: "3000",
+				MarkPrice: "2900", UnrealisedPnl: "150", LiqPrice: "3250.5", Mode: "dual_short"},
+			multiplier: 0.01,
+			want: Position{Symbol: "ETHUSDT", Side: "short", Quantity: 1.5, EntryPrice: 3000, MarkPrice: 2900,
+				UnrealizedProfit: 150, Leverage: 10, LiquidationPrice: 3250.5, MarginMode: "isolated"},
+			ok: true,
+		},
+		{
+			name:       "single_cross",
+			pos:        gatePositionInfo{Contract: "BTC_USDT", Size: 100, Leverage: "0", CrossLeverageLimit: "5", Mode: "single"},
+			multiplier: 0.0001,
+			want:       Position{Symbol: "BTCUSDT", Side: "long", Quantity: 0.01, Leverage: 5, MarginMode: "cross"},
+			ok:         true,
+		},
+		{
+			name:       "empty",
+			pos:        gatePositionInfo{Contract: "BTC_USDT", Size: 0, Mode: "dual_long"},
+			multiplier: 0.0001,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := gatePosition(tc.pos, tc.multiplier)
+			if ok != tc.ok || !almostEqual(got.Quantity, tc.want.Quantity) {
+				t.Fatalf("gatePosition() = %+v, %v; want %+v, %v", got, ok, tc.want, tc.ok)
+			}
+			got.Quantity = tc.want.Quantity
+			if got != tc.want {
+				t.Errorf("gatePosition() = %+v; want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGateOrderStatus(t *testing.T) {
+	for _, tc := range []struct {
+		order gateOrder
+		want  string
+	}{
+		{gateOrder{Status: "open", Size: 10, Left: 10}, OrderStatusNew},
+		{gateOrder{Status: "open", Size: -10, Left: -4}, OrderStatusPartiallyFilled},
+		{gateOrder{Status: "finished", FinishAs: "filled"}, OrderStatusFilled},
+		{gateOrder{Status: "finished", FinishAs: "cancelled"}, OrderStatusCanceled},
+		{gateOrder{Status: "finished", FinishAs: "ioc"}, OrderStatusExpired},
+		{gateOrder{Status: "finished", FinishAs: "poc"}, OrderStatusExpired},
+		{gateOrder{Status: "finished", FinishAs: "position_closed"}, OrderStatusCanceled},
+	} {
+		if got := gateOrderStatus(&tc.order); got != tc.want {
+			t.Errorf("gateOrderStatus(%+v) = %s, want %s", tc.order, got, tc.want)
+		}
+	}
+}
+
+func TestGateFundingPayment(t *testing.T) {
+	payment := gateFundingPayment(gateAccountBook{Time: 1700006400.5, Change: "-0.3", Type: "fund", Contract: "BTC_USDT"})
+	if payment.Symbol != "BTCUSDT" || !almostEqual(payment.Amount, -0.3) || payment.Timestamp != 1700006400500 {
+		t.Errorf("资金费转换错误: %+v", payment)
+	}
+}
@@ -1,11 +1,17 @@
 package trader
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
+	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sonirico/go-hyperliquid"
@@ -16,8 +22,12 @@ type HyperliquidTrader struct {
 	exchange      *hyperliquid.Exchange
 	ctx           context.Context
 	walletAddr    string
+	apiURL        string            // API地址（SDK未提供的查询直接请求/info）
 	meta          *hyperliquid.Meta // 缓存meta信息（包含精度等）
 	isCrossMargin bool              // 是否为全仓模式
+
+	// Hyperliquid没有原生跟踪止损，在本地模拟
+	trailing *trailingStopEmulator
 }
 
 // NewHyperliquidTrader 创建Hyperliquid交易器
@@ -34,6 +44,16 @@ func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool)
 		apiURL = hyperliquid.TestnetAPIURL
 	}
 
+	trader, err := newHyperliquidTrader(privateKey, walletAddr, apiURL)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("✓ Hyperliquid交易器初始化成功 (testnet=%v, wallet=%s)", testnet, walletAddr)
+	return trader, nil
+}
+
+// newHyperliquidTrader 使用指定API地址创建交易器（测试时指向本地模拟服务器）
+func newHyperliquidTrader(privateKey *ecdsa.PrivateKey, walletAddr string, apiURL string) (*HyperliquidTrader, error) {
 	// // 从私钥生成钱包地址
 	// pubKey := privateKey.Public()
 	// publicKeyECDSA, ok := pubKey.(*ecdsa.PublicKey)
@@ -55,25 +75,26 @@ func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool)
 		nil,        // SpotMeta will be fetched automatically
 	)
 
-	log.Printf("✓ Hyperliquid交易器初始化成功 (testnet=%v, wallet=%s)", testnet, walletAddr)
-
 	// 获取meta信息（包含精度等配置）
 	meta, err := exchange.Info().Meta(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("获取meta信息失败: %w", err)
 	}
 
-	return &HyperliquidTrader{
+	t := &HyperliquidTrader{
 		exchange:      exchange,
 		ctx:           ctx,
 		walletAddr:    walletAddr,
+		apiURL:        apiURL,
 		meta:          meta,
 		isCrossMargin: true, // 默认使用全仓模式
-	}, nil
+	}
+	t.trailing = newTrailingStopEmulator("Hyperliquid", t)
+	return t, nil
 }
 
 // GetBalance 获取账户余额
-func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
+func (t *HyperliquidTrader) GetBalance() (*Balance, error) {
 	log.Printf("🔄 正在调用Hyperliquid API获取账户余额...")
 
 	// 获取账户状态
@@ -83,14 +104,25 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 		return nil, fmt.Errorf("获取账户信息失败: %w", err)
 	}
 
-	// 解析余额信息（MarginSummary字段都是string）
-	result := make(map[string]interface{})
-
 	// 🔍 调试：打印API返回的完整CrossMarginSummary结构
 	summaryJSON, _ := json.MarshalIndent(accountState.MarginSummary, "  ", "  ")
 	log.Printf("🔍 [DEBUG] Hyperliquid API CrossMarginSummary完整数据:")
 	log.Printf("%s", string(summaryJSON))
 
+	balance := hyperliquidBalance(accountState)
+
+	log.Printf("✓ Hyperliquid 账户: 总净值=%.2f (钱包%.2f+未实现%.2f), 可用=%.2f, 保证金占用=%.2f",
+		balance.TotalEquity(),
+		balance.WalletBalance,
+		balance.UnrealizedProfit,
+		balance.AvailableBalance,
+		balance.UsedMargin())
+
+	return balance, nil
+}
+
+// hyperliquidBalance 将账户状态转换为统一余额（MarginSummary字段都是string）
+func hyperliquidBalance(accountState *hyperliquid.UserState) *Balance {
 	accountValue, _ := strconv.ParseFloat(accountState.MarginSummary.AccountValue, 64)
 	totalMarginUsed, _ := strconv.ParseFloat(accountState.MarginSummary.TotalMarginUsed, 64)
 
@@ -105,88 +137,69 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 	// AccountValue = 总账户净值（已包含空闲资金+持仓价值+未实现盈亏）
 	// TotalMarginUsed = 持仓占用的保证金（已包含在AccountValue中，仅用于显示）
 	//
-	// 为了兼容auto_trader.go的计算逻辑（totalEquity = totalWalletBalance + totalUnrealizedProfit）
-	// 需要返回"不包含未实现盈亏的钱包余额"
-	walletBalanceWithoutUnrealized := accountValue - totalUnrealizedPnl
-
-	result["totalWalletBalance"] = walletBalanceWithoutUnrealized // 钱包余额（不含未实现盈亏）
-	result["availableBalance"] = accountValue - totalMarginUsed   // 可用余额（总净值 - 占用保证金）
-	result["totalUnrealizedProfit"] = totalUnrealizedPnl          // 未实现盈亏
-
-	log.Printf("✓ Hyperliquid 账户: 总净值=%.2f (钱包%.2f+未实现%.2f), 可用=%.2f, 保证金占用=%.2f",
-		accountValue,
-		walletBalanceWithoutUnrealized,
-		totalUnrealizedPnl,
-		result["availableBalance"],
-		totalMarginUsed)
-
-	return result, nil
+	// Balance.WalletBalance 是"不包含未实现盈亏的钱包余额"（TotalEquity = 钱包余额 + 未实现盈亏）
+	return &Balance{
+		WalletBalance:    accountValue - totalUnrealizedPnl, // 钱包余额（不含未实现盈亏）
+		UnrealizedProfit: totalUnrealizedPnl,                // 未实现盈亏
+		AvailableBalance: accountValue - totalMarginUsed,    // 可用余额（总净值 - 占用保证金）
+	}
 }
 
 // GetPositions 获取所有持仓
-func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
+func (t *HyperliquidTrader) GetPositions() ([]Position, error) {
 	// 获取账户状态
 	accountState, err := t.exchange.Info().UserState(t.ctx, t.walletAddr)
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
-	var result []map[string]interface{}
+	result := []Position{}
 
 	// 遍历所有持仓
 	for _, assetPos := range accountState.AssetPositions {
-		position := assetPos.Position
-
-		// 持仓数量（string类型）
-		posAmt, _ := strconv.ParseFloat(position.Szi, 64)
-
-		if posAmt == 0 {
-			continue // 跳过无持仓的
-		}
-
-		posMap := make(map[string]interface{})
-
-		// 标准化symbol格式（Hyperliquid使用如"BTC"，我们转换为"BTCUSDT"）
-		symbol := position.Coin + "USDT"
-		posMap["symbol"] = symbol
-
-		// 持仓数量和方向
-		if posAmt > 0 {
-			posMap["side"] = "long"
-			posMap["positionAmt"] = posAmt
-		} else {
-			posMap["side"] = "short"
-			posMap["positionAmt"] = -posAmt // 转为正数
-		}
-
-		// 价格信息（EntryPx和LiquidationPx是指针类型）
-		var entryPrice, liquidationPx float64
-		if position.EntryPx != nil {
-			entryPrice, _ = strconv.ParseFloat(*position.EntryPx, 64)
-		}
-		if position.LiquidationPx != nil {
-			liquidationPx, _ = strconv.ParseFloat(*position.LiquidationPx, 64)
+		if pos, ok := hyperliquidPosition(assetPos.Position); ok {
+			result = append(result, pos)
 		}
+	}
 
-		positionValue, _ := strconv.ParseFloat(position.PositionValue, 64)
-		unrealizedPnl, _ := strconv.ParseFloat(position.UnrealizedPnl, 64)
+	return result, nil
+}
 
-		// 计算mark price（positionValue / abs(posAmt)）
-		var markPrice float64
-		if posAmt != 0 {
-			markPrice = positionValue / absFloat(posAmt)
-		}
+// hyperliquidPosition 将持仓转换为统一格式（无持仓时返回false）
+func hyperliquidPosition(position hyperliquid.Position) (Position, bool) {
+	// 持仓数量（string类型）
+	posAmt, _ := strconv.ParseFloat(position.Szi, 64)
+	if posAmt == 0 {
+		return Position{}, false // 跳过无持仓的
+	}
 
-		posMap["entryPrice"] = entryPrice
-		posMap["markPrice"] = markPrice
-		posMap["unRealizedProfit"] = unrealizedPnl
-		posMap["leverage"] = float64(position.Leverage.Value)
-		posMap["liquidationPrice"] = liquidationPx
+	// 标准化symbol格式（Hyperliquid使用如"BTC"，我们转换为"BTCUSDT"）
+	pos := Position{
+		Symbol:     position.Coin + "USDT",
+		Side:       "long",
+		Quantity:   posAmt,
+		Leverage:   position.Leverage.Value,
+		MarginMode: position.Leverage.Type,
+	}
+	if posAmt < 0 {
+		pos.Side = "short"
+		pos.Quantity = -posAmt // 转为正数
+	}
 
-		result = append(result, posMap)
+	// 价格信息（EntryPx和LiquidationPx是指针类型）
+	if position.EntryPx != nil {
+		pos.EntryPrice, _ = strconv.ParseFloat(*position.EntryPx, 64)
+	}
+	if position.LiquidationPx != nil {
+		pos.LiquidationPrice, _ = strconv.ParseFloat(*position.LiquidationPx, 64)
 	}
 
-	return result, nil
+	// 计算mark price（positionValue / abs(posAmt)）
+	positionValue, _ := strconv.ParseFloat(position.PositionValue, 64)
+	pos.MarkPrice = positionValue / pos.Quantity
+	pos.UnrealizedProfit, _ = strconv.ParseFloat(position.UnrealizedPnl, 64)
+
+	return pos, true
 }
 
 // SetMarginMode 设置仓位模式 (在SetLeverage时一并设置)
@@ -218,7 +231,7 @@ func (t *HyperliquidTrader) SetLeverage(symbol string, leverage int) error {
 }
 
 // OpenLong 开多仓
-func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage int) (*OrderResult, error) {
 	// 先取消该币种的所有委托单
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
@@ -260,23 +273,20 @@ func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage i
 		ReduceOnly: false,
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	status, err := t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
 		return nil, fmt.Errorf("开多仓失败: %w", err)
 	}
 
 	log.Printf("✓ 开多仓成功: %s 数量: %.4f", symbol, roundedQuantity)
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0 // Hyperliquid没有返回order ID
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
+	result := hyperliquidMarketOrderResult(symbol, status)
 
 	return result, nil
 }
 
 // OpenShort 开空仓
-func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage int) (*OrderResult, error) {
 	// 先取消该币种的所有委托单
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
@@ -318,23 +328,20 @@ func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage
 		ReduceOnly: false,
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	status, err := t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
 		return nil, fmt.Errorf("开空仓失败: %w", err)
 	}
 
 	log.Printf("✓ 开空仓成功: %s 数量: %.4f", symbol, roundedQuantity)
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
+	result := hyperliquidMarketOrderResult(symbol, status)
 
 	return result, nil
 }
 
 // CloseLong 平多仓
-func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -343,8 +350,8 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 		}
 
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
-				quantity = pos["positionAmt"].(float64)
+			if pos.Symbol == symbol && pos.Side == "long" {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -385,7 +392,7 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 		ReduceOnly: true, // 只平仓，不开新仓
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	status, err := t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
 		return nil, fmt.Errorf("平多仓失败: %w", err)
 	}
@@ -397,16 +404,13 @@ func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[stri
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
+	result := hyperliquidMarketOrderResult(symbol, status)
 
 	return result, nil
 }
 
 // CloseShort 平空仓
-func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (*OrderResult, error) {
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
@@ -415,8 +419,8 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 		}
 
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				quantity = pos["positionAmt"].(float64)
+			if pos.Symbol == symbol && pos.Side == "short" {
+				quantity = pos.Quantity
 				break
 			}
 		}
@@ -457,7 +461,7 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 		ReduceOnly: true,
 	}
 
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	status, err := t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
 		return nil, fmt.Errorf("平空仓失败: %w", err)
 	}
@@ -469,16 +473,14 @@ func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[str
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
+	result := hyperliquidMarketOrderResult(symbol, status)
 
 	return result, nil
 }
 
 // CancelAllOrders 取消该币种的所有挂单
 func (t *HyperliquidTrader) CancelAllOrders(symbol string) error {
+	t.trailing.Cancel(symbol)
 	coin := convertSymbolToHyperliquid(symbol)
 
 	// 获取所有挂单
@@ -597,14 +599,438 @@ func (t *HyperliquidTrader) SetTakeProfit(symbol string, positionSide string, qu
 	return nil
 }
 
+// trailingStops 本地跟踪止损模拟器（由AutoTrader接管触发平仓和持久化）
+func (t *HyperliquidTrader) trailingStops() *trailingStopEmulator {
+	return t.trailing
+}
+
+// SetTrailingStop 设置跟踪止损（本地根据实时行情模拟，触发后市价平仓）
+func (t *HyperliquidTrader) SetTrailingStop(symbol string, positionSide string, quantity, callbackRate, activationPrice float64) error {
+	if err := t.trailing.Set(symbol, positionSide, quantity, callbackRate, activationPrice); err != nil {
+		return fmt.Errorf("设置跟踪止损失败: %w", err)
+	}
+	return nil
+}
+
+// hyperliquidTif 转换为Hyperliquid的有效方式（post-only对应Alo，不支持FOK）
+func hyperliquidTif(tif TimeInForce) (hyperliquid.Tif, error) {
+	switch tif {
+	case TimeInForceGTC, "":
+		return hyperliquid.TifGtc, nil
+	case TimeInForceIOC:
+		return hyperliquid.TifIoc, nil
+	case TimeInForcePostOnly:
+		return hyperliquid.TifAlo, nil
+	default:
+		return "", fmt.Errorf("Hyperliquid不支持的限价单有效方式: %s", tif)
+	}
+}
+
+// hyperliquidOrderResult 转换下单/改单返回的订单状态
+func hyperliquidOrderResult(symbol string, status hyperliquid.OrderStatus) (*OrderResult, error) {
+	if status.Error != nil {
+		return nil, fmt.Errorf("%s", *status.Error)
+	}
+
+	result := &OrderResult{Symbol: symbol, Type: "LIMIT"}
+	switch {
+	case status.Filled != nil:
+		result.OrderID = strconv.Itoa(status.Filled.Oid)
+		result.Status = OrderStatusFilled
+		result.AvgPrice = parseFloatOrZero(status.Filled.AvgPx)
+		result.ExecutedQty = parseFloatOrZero(status.Filled.TotalSz)
+	case status.Resting != nil:
+		result.OrderID = strconv.FormatInt(status.Resting.Oid, 10)
+		result.Status = OrderStatusNew
+	default:
+		// IOC未成交时既没有resting也没有filled
+		result.Status = OrderStatusExpired
+	}
+	return result, nil
+}
+
+// hyperliquidMarketOrderResult 市价单（IOC）结果，订单ID和成交均价取自交易所响应
+func hyperliquidMarketOrderResult(symbol string, status hyperliquid.OrderStatus) *OrderResult {
+	result := &OrderResult{Symbol: symbol, Status: OrderStatusFilled, Type: "MARKET"}
+	if status.Filled != nil {
+		result.OrderID = strconv.Itoa(status.Filled.Oid)
+		result.AvgPrice = parseFloatOrZero(status.Filled.AvgPx)
+		result.ExecutedQty = parseFloatOrZero(status.Filled.TotalSz)
+	}
+	return result
+}
+
+// PlaceLimitOrder 下限价开仓单
+func (t *HyperliquidTrader) PlaceLimitOrder(symbol string, positionSide string, quantity, price float64, leverage int, tif TimeInForce) (*OrderResult, error) {
+	hlTif, err := hyperliquidTif(tif)
+	if err != nil {
+		return nil, err
+	}
+
+	// 设置杠杆（限价单不取消旧委托单，避免撤掉其他挂单）
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	coin := convertSymbolToHyperliquid(symbol)
+	roundedQuantity := t.roundToSzDecimals(coin, quantity)
+	roundedPrice := t.roundPriceToSigfigs(price)
+
+	order := hyperliquid.CreateOrderRequest{
+		Coin:  coin,
+		IsBuy: positionSide != "SHORT",
+		Size:  roundedQuantity,
+		Price: roundedPrice,
+		OrderType: hyperliquid.OrderType{
+			Limit: &hyperliquid.LimitOrderType{
+				Tif: hlTif,
+			},
+		},
+		ReduceOnly: false,
+	}
+
+	status, err := t.exchange.Order(t.ctx, order, nil)
+	if err != nil {
+		return nil, fmt.Errorf("下限价单失败: %w", err)
+	}
+	result, err := hyperliquidOrderResult(symbol, status)
+	if err != nil {
+		return nil, fmt.Errorf("下限价单失败: %w", err)
+	}
+
+	log.Printf("✓ 限价单已提交: %s %s 数量: %.4f 价格: %.4f (%s) 状态: %s", symbol, positionSide, roundedQuantity, roundedPrice, tif, result.Status)
+	return result, nil
+}
+
+// AmendOrder 修改未成交限价单的价格和数量
+func (t *HyperliquidTrader) AmendOrder(symbol string, orderID string, quantity, price float64) (*OrderResult, error) {
+	oid, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+	coin := convertSymbolToHyperliquid(symbol)
+
+	// Hyperliquid改单需要提交完整订单，先查出原订单的方向和数量
+	openOrders, err := t.exchange.Info().FrontendOpenOrders(t.ctx, t.walletAddr)
+	if err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+	var existing *hyperliquid.FrontendOpenOrder
+	for i := range openOrders {
+		if openOrders[i].Oid == oid && openOrders[i].Coin == coin {
+			existing = &openOrders[i]
+			break
+		}
+	}
+	if existing == nil {
+		return nil, fmt.Errorf("未找到挂单 %s（可能已成交或已取消）", orderID)
+	}
+
+	if quantity <= 0 {
+		quantity = existing.Sz
+	}
+	if price <= 0 {
+		price = existing.LimitPx
+	}
+
+	status, err := t.exchange.ModifyOrder(t.ctx, hyperliquid.ModifyOrderRequest{
+		Oid: oid,
+		Order: hyperliquid.CreateOrderRequest{
+			Coin:  coin,
+			IsBuy: existing.Side == hyperliquid.OrderSideBid,
+			Size:  t.roundToSzDecimals(coin, quantity),
+			Price: t.roundPriceToSigfigs(price),
+			OrderType: hyperliquid.OrderType{
+				Limit: &hyperliquid.LimitOrderType{
+					Tif: hyperliquid.TifGtc,
+				},
+			},
+			ReduceOnly: existing.ReduceOnly,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("修改订单失败: %w", err)
+	}
+	result, err := hyperliquidOrderResult(symbol, status)
+	if err != nil {
+		return nil, fmt.Errorf("修改订单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已修改订单 %s: 数量 %.4f 价格 %.4f", orderID, quantity, price)
+	return result, nil
+}
+
+// CancelOrder 取消单个订单
+func (t *HyperliquidTrader) CancelOrder(symbol string, orderID string) error {
+	oid, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+
+	coin := convertSymbolToHyperliquid(symbol)
+	if _, err := t.exchange.Cancel(t.ctx, coin, oid); err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已取消 %s 订单 %s", symbol, orderID)
+	return nil
+}
+
+// hyperliquidOrderStatus 将Hyperliquid订单状态转换为统一格式
+func hyperliquidOrderStatus(status hyperliquid.OrderStatusValue, origSz, sz float64) string {
+	switch status {
+	case hyperliquid.OrderStatusValueOpen:
+		if sz < origSz {
+			return OrderStatusPartiallyFilled
+		}
+		return OrderStatusNew
+	case hyperliquid.OrderStatusValueFilled, hyperliquid.OrderStatusValueTriggered:
+		return OrderStatusFilled
+	case hyperliquid.OrderStatusValueRejected:
+		return OrderStatusRejected
+	default:
+		// canceled、marginCanceled等各种撤单原因
+		return OrderStatusCanceled
+	}
+}
+
+// hyperliquidSide 将Hyperliquid方向（B/A）转换为BUY/SELL
+func hyperliquidSide(side string) string {
+	if side == string(hyperliquid.OrderSideBid) {
+		return "BUY"
+	}
+	return "SELL"
+}
+
+// GetOrder 查询单个订单（成交均价由该订单的成交记录计算）
+func (t *HyperliquidTrader) GetOrder(symbol string, orderID string) (*OrderResult, error) {
+	oid, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+
+	queried, err := t.exchange.Info().QueryOrderByOid(t.ctx, t.walletAddr, oid)
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	if queried.Status != hyperliquid.OrderQueryStatusSuccess {
+		return nil, fmt.Errorf("未找到订单 %s", orderID)
+	}
+
+	order := queried.Order.Order
+	origSz, _ := strconv.ParseFloat(order.OrigSz, 64)
+	sz, _ := strconv.ParseFloat(order.Sz, 64)
+	price, _ := strconv.ParseFloat(order.LimitPx, 64)
+	stopPrice, _ := strconv.ParseFloat(order.TriggerPx, 64)
+	status := hyperliquidOrderStatus(queried.Order.Status, origSz, sz)
+
+	// 订单已成交数量 = 原始数量 - 剩余数量（已成交订单的剩余数量为0）
+	executedQty := origSz - sz
+	if status == OrderStatusFilled {
+		executedQty = origSz
+	}
+
+	avgPrice := 0.0
+	if executedQty > 0 {
+		fills, err := t.exchange.Info().UserFillsByTime(t.ctx, t.walletAddr, order.Timestamp, nil)
+		if err != nil {
+			log.Printf("  ⚠ 获取订单 %s 成交记录失败: %v", orderID, err)
+		}
+		notional, filledQty := 0.0, 0.0
+		for _, fill := range fills {
+			if fill.Oid != oid {
+				continue
+			}
+			px, _ := strconv.ParseFloat(fill.Price, 64)
+			qty, _ := strconv.ParseFloat(fill.Size, 64)
+			notional += px * qty
+			filledQty += qty
+		}
+		if filledQty > 0 {
+			avgPrice = notional / filledQty
+		}
+	}
+
+	return &OrderResult{
+		OrderID:     orderID,
+		Symbol:      symbol,
+		Status:      status,
+		Type:        order.OrderType,
+		Side:        hyperliquidSide(string(order.Side)),
+		Price:       price,
+		StopPrice:   stopPrice,
+		Quantity:    origSz,
+		ExecutedQty: executedQty,
+		AvgPrice:    avgPrice,
+		Time:        order.Timestamp,
+		UpdateTime:  queried.Order.StatusTimestamp,
+	}, nil
+}
+
+// GetOpenOrders 获取未成交挂单（symbol为空表示所有币种）
+func (t *HyperliquidTrader) GetOpenOrders(symbol string) ([]OrderResult, error) {
+	openOrders, err := t.exchange.Info().FrontendOpenOrders(t.ctx, t.walletAddr)
+	if err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	coin := convertSymbolToHyperliquid(symbol)
+	result := make([]OrderResult, 0, len(openOrders))
+	for _, order := range openOrders {
+		if symbol != "" && order.Coin != coin {
+			continue
+		}
+
+		status := OrderStatusNew
+		if order.Sz < order.OrigSz {
+			status = OrderStatusPartiallyFilled
+		}
+		result = append(result, OrderResult{
+			OrderID:     strconv.FormatInt(order.Oid, 10),
+			Symbol:      order.Coin + "USDT",
+			Status:      status,
+			Type:        order.OrderType,
+			Side:        hyperliquidSide(string(order.Side)),
+			Price:       order.LimitPx,
+			StopPrice:   order.TriggerPx,
+			Quantity:    order.OrigSz,
+			ExecutedQty: order.OrigSz - order.Sz,
+			Time:        order.Timestamp,
+			UpdateTime:  order.Timestamp,
+		})
+	}
+	return result, nil
+}
+
+// hyperliquidProtectiveKind Hyperliquid触发单类型（Stop Market/Take Profit Market等）
+func hyperliquidProtectiveKind(order *OrderResult) string {
+	switch {
+	case strings.HasPrefix(order.Type, "Stop"):
+		return ProtectiveKindStopLoss
+	case strings.HasPrefix(order.Type, "Take Profit"):
+		return ProtectiveKindTakeProfit
+	}
+	return ""
+}
+
+// GetProtectiveOrders 获取止盈止损触发单（触发单与普通委托在同一挂单列表中返回）
+func (t *HyperliquidTrader) GetProtectiveOrders(symbol string) ([]ProtectiveOrder, error) {
+	orders, err := t.GetOpenOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return filterProtectiveOrders(orders, hyperliquidProtectiveKind), nil
+}
+
+// CancelProtectiveOrder 取消单个止盈止损触发单
+func (t *HyperliquidTrader) CancelProtectiveOrder(symbol string, orderID string) error {
+	return t.CancelOrder(symbol, orderID)
+}
+
+// GetFills 获取自since以来的成交记录（symbol为空表示所有币种）
+func (t *HyperliquidTrader) GetFills(symbol string, since time.Time) ([]Fill, error) {
+	userFills, err := t.exchange.Info().UserFillsByTime(t.ctx, t.walletAddr, since.UnixMilli(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取成交记录失败: %w", err)
+	}
+
+	coin := convertSymbolToHyperliquid(symbol)
+	fills := make([]Fill, 0, len(userFills))
+	for _, fill := range userFills {
+		if symbol != "" && fill.Coin != coin {
+			continue
+		}
+		fills = append(fills, hyperliquidFill(fill))
+	}
+	return fills, nil
+}
+
+// hyperliquidFill 将成交记录转换为统一格式（crossed表示吃单）
+func hyperliquidFill(fill hyperliquid.Fill) Fill {
+	role := "maker"
+	if fill.Crossed {
+		role = "taker"
+	}
+	return Fill{
+		FillID:      strconv.FormatInt(fill.Tid, 10),
+		OrderID:     strconv.FormatInt(fill.Oid, 10),
+		Symbol:      fill.Coin + "USDT",
+		Side:        strings.ToLower(hyperliquidSide(fill.Side)),
+		Price:       parseFloatOrZero(fill.Price),
+		Quantity:    parseFloatOrZero(fill.Size),
+		Fee:         parseFloatOrZero(fill.Fee),
+		FeeCurrency: fill.FeeToken,
+		Role:        role,
+		Timestamp:   fill.Time,
+	}
+}
+
+// hyperliquidFunding 资金费结算记录（SDK的UserFundingHistory缺少delta字段，这里自行解析）
+type hyperliquidFunding struct {
+	Time  int64 `json:"time"`
+	Delta struct {
+		Type        string `json:"type"`
+		Coin        string `json:"coin"`
+		USDC        string `json:"usdc"`
+		FundingRate string `json:"fundingRate"`
+	} `json:"delta"`
+}
+
+// GetFundingPayments 获取自since以来的资金费结算记录（symbol为空表示所有币种）
+func (t *HyperliquidTrader) GetFundingPayments(symbol string, since time.Time) ([]FundingPayment, error) {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"type":      "userFunding",
+		"user":      t.walletAddr,
+		"startTime": since.UnixMilli(),
+	})
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodPost, strings.TrimSuffix(t.apiURL, "/")+"/info", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费记录失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费记录失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取资金费记录失败: HTTP %d", resp.StatusCode)
+	}
+
+	var records []hyperliquidFunding
+	if err := json.NewDecoder(resp.Body).Decode(&records); err != nil {
+		return nil, fmt.Errorf("解析资金费记录失败: %w", err)
+	}
+
+	coin := convertSymbolToHyperliquid(symbol)
+	payments := make([]FundingPayment, 0, len(records))
+	for _, record := range records {
+		if record.Delta.Type != "funding" || (symbol != "" && record.Delta.Coin != coin) {
+			continue
+		}
+		payments = append(payments, hyperliquidFundingPayment(record))
+	}
+	return payments, nil
+}
+
+// hyperliquidFundingPayment 将资金费结算记录转换为统一格式（usdc为正表示收到）
+func hyperliquidFundingPayment(record hyperliquidFunding) FundingPayment {
+	return FundingPayment{
+		Symbol:    record.Delta.Coin + "USDT",
+		Amount:    parseFloatOrZero(record.Delta.USDC),
+		Rate:      parseFloatOrZero(record.Delta.FundingRate),
+		Timestamp: record.Time,
+	}
+}
+
 // FormatQuantity 格式化数量到正确的精度
 func (t *HyperliquidTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
 	coin := convertSymbolToHyperliquid(symbol)
 	szDecimals := t.getSzDecimals(coin)
 
-	// 使用szDecimals格式化数量
+	// 使用szDecimals格式化数量（向下取整）
 	formatStr := fmt.Sprintf("%%.%df", szDecimals)
-	return fmt.Sprintf(formatStr, quantity), nil
+	return fmt.Sprintf(formatStr, t.roundToSzDecimals(coin, quantity)), nil
 }
 
 // getSzDecimals 获取币种的数量精度
@@ -625,7 +1051,8 @@ func (t *HyperliquidTrader) getSzDecimals(coin string) int {
 	return 4 // 默认精度
 }
 
-// roundToSzDecimals 将数量四舍五入到正确的精度
+// roundToSzDecimals 将数量向下取整到正确的精度
+// 向上取整会导致只减仓订单超出持仓、开仓超出可用保证金
 func (t *HyperliquidTrader) roundToSzDecimals(coin string, quantity float64) float64 {
 	szDecimals := t.getSzDecimals(coin)
 
@@ -635,8 +1062,8 @@ func (t *HyperliquidTrader) roundToSzDecimals(coin string, quantity float64) flo
 		multiplier *= 10.0
 	}
 
-	// 四舍五入
-	return float64(int(quantity*multiplier+0.5)) / multiplier
+	// 向下取整（加极小值避免0.012被表示为0.0119999时少一档）
+	return math.Floor(quantity*multiplier+1e-9) / multiplier
 }
 
 // roundPriceToSigfigs 将价格四舍五入到5位有效数字
@@ -686,11 +1113,3 @@ func convertSymbolToHyperliquid(symbol string) string {
 	}
 	return symbol
 }
-
-// absFloat 返回浮点数的绝对值
-func absFloat(x float64) float64 {
-	if x < 0 {
-		return -x
-	}
-	return x
-}
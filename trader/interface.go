@@ -1,6 +1,9 @@
 package trader
 
-import "database/sql"
+import (
+        "database/sql"
+        "time"
+)
 
 // CreditReservation 积分预留凭证
 // 用于两阶段提交：先预留积分，交易成功后确认消费，失败则释放
@@ -62,24 +65,25 @@ type CreditConsumer interface {
 
 // Trader 交易器统一接口
 // 支持多个交易平台（币安、Hyperliquid等）
+// 所有交易器返回统一的类型化结果：数量为币数量，价格和金额为float64，symbol为BTCUSDT格式
 type Trader interface {
         // GetBalance 获取账户余额
-        GetBalance() (map[string]interface{}, error)
+        GetBalance() (*Balance, error)
 
-        // GetPositions 获取所有持仓
-        GetPositions() ([]map[string]interface{}, error)
+        // GetPositions 获取所有持仓（不包含数量为0的持仓）
+        GetPositions() ([]Position, error)
 
         // OpenLong 开多仓
-        OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error)
+        OpenLong(symbol string, quantity float64, leverage int) (*OrderResult, error)
 
         // OpenShort 开空仓
-        OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error)
+        OpenShort(symbol string, quantity float64, leverage int) (*OrderResult, error)
 
         // CloseLong 平多仓（quantity=0表示全部平仓）
-        CloseLong(symbol string, quantity float64) (map[string]interface{}, error)
+        CloseLong(symbol string, quantity float64) (*OrderResult, error)
 
         // CloseShort 平空仓（quantity=0表示全部平仓）
-        CloseShort(symbol string, quantity float64) (map[string]interface{}, error)
+        CloseShort(symbol string, quantity float64) (*OrderResult, error)
 
         // SetLeverage 设置杠杆
         SetLeverage(symbol string, leverage int) error
@@ -96,9 +100,41 @@ type Trader interface {
         // SetTakeProfit 设置止盈单
         SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error
 
+        // SetTrailingStop 设置跟踪止损单（callbackRate为回调比例，如0.01表示1%；activationPrice为激活价，0表示立即激活）
+        // 交易所支持时使用原生跟踪委托，否则由本地根据实时行情模拟
+        SetTrailingStop(symbol string, positionSide string, quantity, callbackRate, activationPrice float64) error
+
         // CancelAllOrders 取消该币种的所有挂单
         CancelAllOrders(symbol string) error
 
+        // GetProtectiveOrders 获取止盈止损条件单（symbol为空表示所有币种）
+        GetProtectiveOrders(symbol string) ([]ProtectiveOrder, error)
+
+        // CancelProtectiveOrder 取消单个止盈止损条件单
+        CancelProtectiveOrder(symbol string, orderID string) error
+
         // FormatQuantity 格式化数量到正确的精度
         FormatQuantity(symbol string, quantity float64) (string, error)
+
+        // PlaceLimitOrder 下限价开仓单（positionSide: LONG/SHORT）
+        // 返回状态为NEW（挂单中）、FILLED（已成交）或EXPIRED（IOC/FOK未成交）
+        PlaceLimitOrder(symbol string, positionSide string, quantity, price float64, leverage int, tif TimeInForce) (*OrderResult, error)
+
+        // AmendOrder 修改未成交限价单的价格和数量（quantity=0表示保持原数量）
+        AmendOrder(symbol string, orderID string, quantity, price float64) (*OrderResult, error)
+
+        // CancelOrder 取消单个订单
+        CancelOrder(symbol string, orderID string) error
+
+        // GetOrder 查询单个订单
+        GetOrder(symbol string, orderID string) (*OrderResult, error)
+
+        // GetOpenOrders 获取未成交挂单（symbol为空表示所有币种）
+        GetOpenOrders(symbol string) ([]OrderResult, error)
+
+        // GetFills 获取自since以来的成交记录（部分交易所要求指定symbol）
+        GetFills(symbol string, since time.Time) ([]Fill, error)
+
+        // GetFundingPayments 获取自since以来的资金费结算记录（部分交易所要求指定symbol）
+        GetFundingPayments(symbol string, since time.Time) ([]FundingPayment, error)
 }
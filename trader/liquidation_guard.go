@@ -0,0 +1,241 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"strings"
+)
+
+// defaultLiquidationATRMultiple 未配置时强平价距离入场价至少为ATR14的倍数
+const defaultLiquidationATRMultiple = 2.0
+
+// maintenanceTier 维持保证金阶梯：仓位名义价值不超过MaxNotional时使用Rate
+type maintenanceTier struct {
+	MaxNotional float64
+	Rate        float64
+}
+
+// exchangeMaintenanceTiers 各交易所维持保证金阶梯（按交易所公开的USDT永续阶梯取整，majors为BTC/ETH）
+// 实际阶梯随交易所调整，这里只用于开仓前估算强平价，估算偏保守
+var exchangeMaintenanceTiers = map[string]struct {
+	Majors []maintenanceTier
+	Alts   []maintenanceTier
+}{
+	"binance": {
+		Majors: []maintenanceTier{{50000, 0.004}, {600000, 0.005}, {3000000, 0.0065}, {12000000, 0.01}, {math.Inf(1), 0.025}},
+		Alts:   []maintenanceTier{{10000, 0.01}, {50000, 0.015}, {250000, 0.02}, {1000000, 0.05}, {math.Inf(1), 0.1}},
+	},
+	"aster": {
+		Majors: []maintenanceTier{{50000, 0.004}, {600000, 0.005}, {3000000, 0.0065}, {math.Inf(1), 0.025}},
+		Alts:   []maintenanceTier{{10000, 0.01}, {50000, 0.015}, {250000, 0.02}, {math.Inf(1), 0.1}},
+	},
+	"okx": {
+		Majors: []maintenanceTier{{500000, 0.004}, {2000000, 0.006}, {10000000, 0.01}, {math.Inf(1), 0.02}},
+		Alts:   []maintenanceTier{{20000, 0.01}, {100000, 0.015}, {500000, 0.025}, {math.Inf(1), 0.05}},
+	},
+	"bybit": {
+		Majors: []maintenanceTier{{2000000, 0.005}, {10000000, 0.01}, {math.Inf(1), 0.02}},
+		Alts:   []maintenanceTier{{200000, 0.01}, {1000000, 0.02}, {math.Inf(1), 0.05}},
+	},
+	"gate": {
+		Majors: []maintenanceTier{{1000000, 0.005}, {5000000, 0.01}, {math.Inf(1), 0.02}},
+		Alts:   []maintenanceTier{{50000, 0.01}, {500000, 0.02}, {math.Inf(1), 0.05}},
+	},
+	"bitget": {
+		Majors: []maintenanceTier{{150000, 0.004}, {1000000, 0.005}, {5000000, 0.01}, {math.Inf(1), 0.02}},
+		Alts:   []maintenanceTier{{10000, 0.01}, {100000, 0.015}, {500000, 0.025}, {math.Inf(1), 0.05}},
+	},
+	// Hyperliquid维持保证金率 = 最大杠杆下初始保证金率的一半（BTC 40x, 其他约20x）
+	"hyperliquid": {
+		Majors: []maintenanceTier{{math.Inf(1), 0.0125}},
+		Alts:   []maintenanceTier{{math.Inf(1), 0.025}},
+	},
+}
+
+// maintenanceMarginRate 估算维持保证金率（未知交易所按1%/2%保守估算）
+func (at *AutoTrader) maintenanceMarginRate(symbol string, notional float64) float64 {
+	if paper, ok := at.trader.(*PaperTrader); ok {
+		return paper.maintenanceMarginRate
+	}
+	tiers, ok := exchangeMaintenanceTiers[at.exchange]
+	if !ok {
+		if symbol == "BTCUSDT" || symbol == "ETHUSDT" {
+			return 0.01
+		}
+		return 0.02
+	}
+	table := tiers.Alts
+	if symbol == "BTCUSDT" || symbol == "ETHUSDT" {
+		table = tiers.Majors
+	}
+	for _, tier := range table {
+		if notional <= tier.MaxNotional {
+			return tier.Rate
+		}
+	}
+	return table[len(table)-1].Rate
+}
+
+// estimateLiquidationPrice 按抵押品估算强平价（与PaperTrader口径一致），无强平风险时返回0
+// 多仓：C + Q×(P-E) = mmr×Q×P  =>  P = (Q×E - C) / (Q×(1-mmr))
+// 空仓：C + Q×(E-P) = mmr×Q×P  =>  P = (C + Q×E) / (Q×(1+mmr))
+func estimateLiquidationPrice(side string, entry, quantity, collateral, mmr float64) float64 {
+	if quantity <= 0 || entry <= 0 {
+		return 0
+	}
+	var liq float64
+	if side == "long" {
+		liq = (quantity*entry - collateral) / (quantity * (1 - mmr))
+	} else {
+		liq = (collateral + quantity*entry) / (quantity * (1 + mmr))
+	}
+	return math.Max(liq, 0)
+}
+
+// liquidationCheck 单个杠杆下的强平距离检查结果
+type liquidationCheck struct {
+	Leverage    int
+	Price       float64 // 强平价（0表示无强平风险）
+	DistancePct float64 // 强平价距离入场价的百分比
+	Problem     string  // 不满足条件的原因（空表示通过）
+}
+
+// checkLiquidationAt 计算指定杠杆下的强平价，检查止损是否在强平前触发、强平价是否距离入场价足够远
+// existing不为nil时（加仓）按合并后的持仓计算：数量相加、入场价取均价，杠杆作用于整个持仓
+// 逐仓的抵押品为仓位保证金（名义价值/杠杆）；全仓（balance不为nil）的抵押品为账户可用余额加上该持仓已占用的保证金，与杠杆无关
+func (at *AutoTrader) checkLiquidationAt(d *decision.Decision, side string, entry, sizeUSD float64, leverage int, balance *Balance, minDistance float64, existing *Position) liquidationCheck {
+	quantity := sizeUSD / entry
+	notional := sizeUSD
+	existingMargin := 0.0
+	if existing != nil && existing.Quantity > 0 && existing.EntryPrice > 0 {
+		quantity += existing.Quantity
+		notional += existing.Quantity * existing.EntryPrice
+		entry = notional / quantity
+		if existing.Leverage > 0 {
+			existingMargin = existing.Quantity * existing.EntryPrice / float64(existing.Leverage)
+		}
+	}
+	collateral := notional / float64(leverage)
+	if at.config.IsCrossMargin && balance != nil {
+		collateral = balance.AvailableBalance + existingMargin
+	}
+	mmr := at.maintenanceMarginRate(d.Symbol, notional)
+	liq := estimateLiquidationPrice(side, entry, quantity, collateral, mmr)
+	check := liquidationCheck{Leverage: leverage, Price: liq}
+	if liq <= 0 {
+		return check
+	}
+	check.DistancePct = math.Abs(entry-liq) / entry * 100
+
+	if d.StopLoss > 0 && ((side == "long" && d.StopLoss <= liq) || (side == "short" && d.StopLoss >= liq)) {
+		check.Problem = fmt.Sprintf("%dx $%.2f 时止损 %.4f 在强平价 %.4f 之外", leverage, sizeUSD, d.StopLoss, liq)
+	} else if minDistance > 0 && math.Abs(entry-liq) < minDistance {
+		check.Problem = fmt.Sprintf("%dx $%.2f 时强平价 %.4f 距入场价 %.2f%%，小于 %.4f (N×ATR14)", leverage, sizeUSD, liq, check.DistancePct, minDistance)
+	}
+	return check
+}
+
+// maxCrossSize 全仓模式下满足强平距离的最大开仓金额（不超过sizeUSD）
+// 抵押品固定时开仓金额越小强平价越远，二分查找；没有满足条件的金额时返回0和最后一次检查结果
+func (at *AutoTrader) maxCrossSize(d *decision.Decision, side string, entry, sizeUSD float64, balance *Balance, minDistance float64, existing *Position) (float64, liquidationCheck) {
+	lo, hi := 0.0, sizeUSD
+	var best, last liquidationCheck
+	found := false
+	for i := 0; i < 40; i++ {
+		mid := (lo + hi) / 2
+		last = at.checkLiquidationAt(d, side, entry, mid, d.Leverage, balance, minDistance, existing)
+		if last.Problem == "" {
+			lo, best, found = mid, last, true
+		} else {
+			hi = mid
+		}
+	}
+	if !found {
+		return 0, last
+	}
+	return lo, best
+}
+
+// checkLiquidationDistance 开仓/加仓前的强平距离检查，sizeUSD为保证金和组合风险调整后的最终金额（existing为加仓前的持仓，开仓时为nil）
+// 止损在强平价之外、或强平价距入场价不足N×ATR14时：
+//   - 逐仓：逐步降低杠杆直到满足条件（降低杠杆后按可用保证金重新限制金额），降到1x仍不满足时拒绝开仓
+//   - 全仓：抵押品为账户可用余额，降低杠杆不改变强平价，改为减小开仓金额，没有满足条件的金额时拒绝开仓
+//
+// 返回调整后的开仓金额，计算结果记录到actionRecord（决策日志）
+func (at *AutoTrader) checkLiquidationDistance(d *decision.Decision, data *market.Data, balance *Balance, sizeUSD float64, existing *Position, actionRecord *logger.DecisionAction) (float64, error) {
+	entry := d.LimitPrice
+	if entry <= 0 && data != nil {
+		entry = data.CurrentPrice
+	}
+	if entry <= 0 || sizeUSD <= 0 || d.Leverage <= 0 {
+		return sizeUSD, nil
+	}
+	side := "long"
+	if strings.HasSuffix(d.Action, "_short") {
+		side = "short"
+	}
+
+	multiple := at.config.LiquidationATRMultiple
+	if multiple <= 0 {
+		multiple = defaultLiquidationATRMultiple
+	}
+	minDistance := 0.0
+	if data != nil && data.LongerTermContext != nil && data.LongerTermContext.ATR14 > 0 {
+		minDistance = data.LongerTermContext.ATR14 * multiple
+	}
+
+	cross := at.config.IsCrossMargin && balance != nil
+	requested := at.checkLiquidationAt(d, side, entry, sizeUSD, d.Leverage, balance, minDistance, existing)
+	check := requested
+	adjustedSize := sizeUSD
+	if cross {
+		if check.Problem != "" {
+			adjustedSize, check = at.maxCrossSize(d, side, entry, sizeUSD, balance, minDistance, existing)
+		}
+	} else {
+		for check.Problem != "" && check.Leverage > 1 {
+			check = at.checkLiquidationAt(d, side, entry, sizeUSD, check.Leverage-1, balance, minDistance, existing)
+		}
+	}
+
+	actionRecord.LiquidationPrice = check.Price
+	actionRecord.LiquidationDistancePct = check.DistancePct
+	if check.Problem != "" {
+		if cross {
+			actionRecord.LiquidationNote = fmt.Sprintf("%s，全仓减小仓位仍不满足", requested.Problem)
+		} else {
+			actionRecord.LiquidationNote = fmt.Sprintf("%s，降至1x仍不满足: %s", requested.Problem, check.Problem)
+		}
+		return 0, fmt.Errorf("强平距离检查未通过: %s", actionRecord.LiquidationNote)
+	}
+
+	if check.Price <= 0 {
+		actionRecord.LiquidationNote = fmt.Sprintf("%dx无强平风险", check.Leverage)
+	} else {
+		actionRecord.LiquidationNote = fmt.Sprintf("%dx强平价 %.4f，距入场价 %.2f%%", check.Leverage, check.Price, check.DistancePct)
+	}
+	switch {
+	case adjustedSize < sizeUSD:
+		actionRecord.LiquidationNote = fmt.Sprintf("全仓金额 $%.2f → $%.2f (%s)；%s", sizeUSD, adjustedSize, requested.Problem, actionRecord.LiquidationNote)
+		log.Printf("  ⚠️ 强平距离检查: %s", actionRecord.LiquidationNote)
+	case check.Leverage < d.Leverage:
+		actionRecord.LiquidationNote = fmt.Sprintf("杠杆 %dx → %dx (%s)；%s", d.Leverage, check.Leverage, requested.Problem, actionRecord.LiquidationNote)
+		log.Printf("  ⚠️ 强平距离检查: %s", actionRecord.LiquidationNote)
+		d.Leverage = check.Leverage
+		actionRecord.Leverage = check.Leverage
+		// 降低杠杆后可用保证金能支持的金额随之减少
+		if balance != nil {
+			if maxPositionValue := balance.AvailableBalance * 0.80 * float64(check.Leverage); adjustedSize > maxPositionValue {
+				log.Printf("  ⚠️ 保证金检查: 杠杆降至 %dx 后最大可开仓 $%.2f，金额 $%.2f -> $%.2f", check.Leverage, maxPositionValue, adjustedSize, maxPositionValue)
+				adjustedSize = maxPositionValue
+			}
+		}
+	default:
+		log.Printf("  ✅ 强平距离检查通过: %s", actionRecord.LiquidationNote)
+	}
+	return adjustedSize, nil
+}
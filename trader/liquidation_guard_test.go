@@ -0,0 +1,105 @@
+package trader
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+)
+
+func TestEstimateLiquidationPrice(t *testing.T) {
+	// 逐仓10x多仓：抵押品10，(100-10)/(1-0.005)
+	if got := estimateLiquidationPrice("long", 100, 1, 10, 0.005); math.Abs(got-90/0.995) > 1e-9 {
+		t.Errorf("多仓强平价错误: %.4f", got)
+	}
+	if got := estimateLiquidationPrice("short", 100, 1, 10, 0.005); math.Abs(got-110/1.005) > 1e-9 {
+		t.Errorf("空仓强平价错误: %.4f", got)
+	}
+	if got := estimateLiquidationPrice("long", 100, 1, 100, 0.005); got != 0 {
+		t.Errorf("1x多仓不应有强平价: %.4f", got)
+	}
+
+	at := &AutoTrader{exchange: "binance"}
+	if got := at.maintenanceMarginRate("BTCUSDT", 10000); got != 0.004 {
+		t.Errorf("币安BTC第一档维持保证金率应为0.4%%: %v", got)
+	}
+	if got := at.maintenanceMarginRate("SOLUSDT", 100000); got != 0.02 {
+		t.Errorf("币安山寨币10万档维持保证金率应为2%%: %v", got)
+	}
+	at.exchange = "unknown"
+	if got := at.maintenanceMarginRate("SOLUSDT", 100); got != 0.02 {
+		t.Errorf("未知交易所应按2%%保守估算: %v", got)
+	}
+}
+
+func TestCheckLiquidationDistance(t *testing.T) {
+	at := &AutoTrader{exchange: "binance"}
+	data := &market.Data{CurrentPrice: 100, LongerTermContext: &market.LongerTermData{ATR14: 2}}
+
+	// 山寨币维持保证金率1%：10x强平价约90.91，止损95在强平前，距离9.09% > 2×ATR(4)
+	d := &decision.Decision{Symbol: "SOLUSDT", Action: "open_long", Leverage: 10, StopLoss: 95}
+	record := logger.DecisionAction{Leverage: 10}
+	if size, err := at.checkLiquidationDistance(d, data, nil, 1000, nil, &record); err != nil || size != 1000 {
+		t.Fatalf("满足条件应通过且不调整金额: %.2f %v", size, err)
+	}
+	if d.Leverage != 10 || math.Abs(record.LiquidationPrice-90/0.99) > 1e-9 || record.LiquidationDistancePct <= 9 {
+		t.Errorf("应记录强平价和距离: %+v", record)
+	}
+
+	// 止损88在10x强平价之外（8x强平价约88.38），降到7x（强平价约86.58）
+	d = &decision.Decision{Symbol: "SOLUSDT", Action: "open_long", Leverage: 10, StopLoss: 88}
+	record = logger.DecisionAction{Leverage: 10}
+	if _, err := at.checkLiquidationDistance(d, data, nil, 1000, nil, &record); err != nil {
+		t.Fatalf("应降低杠杆而不是拒绝: %v", err)
+	}
+	if d.Leverage != 7 || record.Leverage != 7 || !strings.Contains(record.LiquidationNote, "10x → 7x") {
+		t.Errorf("应降杠杆到止损位于强平前的最高杠杆: lev=%d %+v", d.Leverage, record)
+	}
+
+	// 逐仓降杠杆后按可用保证金重新限制金额：7x × 可用100 × 80% = 560
+	d = &decision.Decision{Symbol: "SOLUSDT", Action: "open_long", Leverage: 10, StopLoss: 88}
+	record = logger.DecisionAction{Leverage: 10}
+	if size, err := at.checkLiquidationDistance(d, data, &Balance{AvailableBalance: 100}, 800, nil, &record); err != nil || d.Leverage != 7 || math.Abs(size-560) > 1e-9 {
+		t.Fatalf("降杠杆后应按保证金限制金额: lev=%d size=%.2f %v", d.Leverage, size, err)
+	}
+
+	// 全仓：可用余额作为抵押品，强平价更远
+	d = &decision.Decision{Symbol: "SOLUSDT", Action: "open_long", Leverage: 10, StopLoss: 88}
+	at.config.IsCrossMargin = true
+	record = logger.DecisionAction{Leverage: 10}
+	if size, err := at.checkLiquidationDistance(d, data, &Balance{AvailableBalance: 500}, 1000, nil, &record); err != nil || d.Leverage != 10 || size != 1000 {
+		t.Fatalf("全仓可用余额充足时不应调整: lev=%d size=%.2f %v", d.Leverage, size, err)
+	}
+
+	// 全仓可用余额50：$1000时强平价约95.96在止损之上；抵押品与杠杆无关，应减小金额而不是降杠杆
+	// (S-50)/(S/100×0.99) < 88  =>  S < 388.2
+	record = logger.DecisionAction{Leverage: 10}
+	size, err := at.checkLiquidationDistance(d, data, &Balance{AvailableBalance: 50}, 1000, nil, &record)
+	if err != nil || d.Leverage != 10 || size > 388.2 || size < 387 || !strings.Contains(record.LiquidationNote, "全仓金额") {
+		t.Fatalf("全仓应减小金额: lev=%d size=%.2f %v %+v", d.Leverage, size, err, record)
+	}
+	if record.LiquidationPrice >= 88 {
+		t.Errorf("减小金额后强平价应在止损之下: %+v", record)
+	}
+
+	// 全仓加仓：已有持仓的强平价已在止损之上时，减小加仓金额也无法满足，拒绝
+	existing := &Position{Symbol: "SOLUSDT", Side: "long", Quantity: 10, EntryPrice: 100, Leverage: 10}
+	d = &decision.Decision{Symbol: "SOLUSDT", Action: "add_long", Leverage: 10, StopLoss: 90}
+	record = logger.DecisionAction{Leverage: 10}
+	if _, err := at.checkLiquidationDistance(d, data, &Balance{}, 200, existing, &record); err == nil || !strings.Contains(err.Error(), "全仓减小仓位仍不满足") {
+		t.Fatalf("全仓无法满足时应拒绝: %v", err)
+	}
+	at.config.IsCrossMargin = false
+
+	// 空仓1x时强平价约197，ATR过大（2×60=120）时拒绝
+	wild := &market.Data{CurrentPrice: 100, LongerTermContext: &market.LongerTermData{ATR14: 60}}
+	d = &decision.Decision{Symbol: "SOLUSDT", Action: "open_short", Leverage: 3, StopLoss: 130}
+	record = logger.DecisionAction{Leverage: 3}
+	_, err = at.checkLiquidationDistance(d, wild, nil, 1000, nil, &record)
+	if err == nil || !strings.Contains(err.Error(), "降至1x仍不满足") || d.Leverage != 3 {
+		t.Fatalf("降到1x仍不满足时应拒绝且不修改杠杆: lev=%d %v", d.Leverage, err)
+	}
+}
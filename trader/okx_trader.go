@@ -25,11 +25,11 @@ type OKXTrader struct {
         client     *http.Client
 
         // 缓存机制（遵循现有模式）
-        cachedBalance     map[string]interface{}
+        cachedBalance     *Balance
         balanceCacheTime  time.Time
         balanceCacheMutex sync.RWMutex
 
-        cachedPositions     []map[string]interface{}
+        cachedPositions     []Position
         positionsCacheTime  time.Time
         positionsCacheMutex sync.RWMutex
 
@@ -38,6 +38,29 @@ type OKXTrader struct {
 
         // 速率限制器
         rateLimiter *RateLimiter
+
+        // 保证金模式（cross/isolated，为空表示全仓）
+        // OKX没有单独设置保证金模式的接口，通过下单时的tdMode指定
+        marginMode string
+}
+
+// tdMode 下单使用的保证金模式
+func (t *OKXTrader) tdMode() string {
+        if t.marginMode == "" {
+                return "cross"
+        }
+        return t.marginMode
+}
+
+// invalidateCache 下单后清除余额和持仓缓存，避免随后的查询读到旧数据
+func (t *OKXTrader) invalidateCache() {
+        t.balanceCacheMutex.Lock()
+        t.cachedBalance = nil
+        t.balanceCacheMutex.Unlock()
+
+        t.positionsCacheMutex.Lock()
+        t.cachedPositions = nil
+        t.positionsCacheMutex.Unlock()
 }
 
 // NewOKXTrader 创建OKX交易器
@@ -71,7 +94,7 @@ func NewOKXTrader(apiKey, secretKey, passphrase string, testnet bool) (*OKXTrade
 }
 
 // GetBalance 获取账户余额（带缓存）
-func (t *OKXTrader) GetBalance() (map[string]interface{}, error) {
+func (t *OKXTrader) GetBalance() (*Balance, error) {
         // 先检查缓存是否有效
         t.balanceCacheMutex.RLock()
         if t.cachedBalance != nil && time.Since(t.balanceCacheTime) < t.cacheDuration {
@@ -98,40 +121,25 @@ func (t *OKXTrader) GetBalance() (map[string]interface{}, error) {
         t.balanceCacheTime = time.Now()
         t.balanceCacheMutex.Unlock()
 
-        log.Printf("✅ OKX余额获取成功: total=%v, used=%v, free=%v",
-                balance["total"], balance["used"], balance["free"])
+        log.Printf("✅ OKX余额获取成功: total=%.2f, used=%.2f, free=%.2f",
+                balance.TotalEquity(), balance.UsedMargin(), balance.AvailableBalance)
 
         return balance, nil
 }
 
 // parseBalance 解析OKX余额响应
-func (t *OKXTrader) parseBalance(resp map[string]interface{}) map[string]interface{} {
-        result := map[string]interface{}{
-                "total": float64(0),
-                "used":  float64(0),
-                "free":  float64(0),
-        }
+// totalEq为账户总权益（已包含未实现盈亏），adjEq为可用于开仓的有效保证金
+func (t *OKXTrader) parseBalance(resp map[string]interface{}) *Balance {
+        result := &Balance{}
 
         if data, ok := resp["data"].([]interface{}); ok && len(data) > 0 {
                 if balance, ok := data[0].(map[string]interface{}); ok {
-                        // 总资产
-                        if totalEq, ok := balance["totalEq"].(string); ok {
-                                if total, err := strconv.ParseFloat(totalEq, 64); err == nil {
-                                        result["total"] = total
-                                }
-                        }
-                        // 已用资产（isoEq）
-                        if isoEq, ok := balance["isoEq"].(string); ok {
-                                if used, err := strconv.ParseFloat(isoEq, 64); err == nil {
-                                        result["used"] = used
-                                }
-                        }
-                        // 可用资产（adjEq）
-                        if adjEq, ok := balance["adjEq"].(string); ok {
-                                if free, err := strconv.ParseFloat(adjEq, 64); err == nil {
-                                        result["free"] = free
-                                }
-                        }
+                        totalEq := parseOKXFloat(parseOKXString(balance["totalEq"]))
+                        upl := parseOKXFloat(parseOKXString(balance["upl"]))
+
+                        result.WalletBalance = totalEq - upl
+                        result.UnrealizedProfit = upl
+                        result.AvailableBalance = parseOKXFloat(parseOKXString(balance["adjEq"]))
                 }
         }
 
@@ -139,7 +147,7 @@ func (t *OKXTrader) parseBalance(resp map[string]interface{}) map[string]interfa
 }
 
 // GetPositions 获取所有持仓
-func (t *OKXTrader) GetPositions() ([]map[string]interface{}, error) {
+func (t *OKXTrader) GetPositions() ([]Position, error) {
         // 检查缓存
         t.positionsCacheMutex.RLock()
         if t.cachedPositions != nil && time.Since(t.positionsCacheTime) < t.cacheDuration {
@@ -171,54 +179,16 @@ func (t *OKXTrader) GetPositions() ([]map[string]interface{}, error) {
 }
 
 // parsePositions 解析OKX持仓响应
-func (t *OKXTrader) parsePositions(resp map[string]interface{}) []map[string]interface{} {
-        var positions []map[string]interface{}
+func (t *OKXTrader) parsePositions(resp map[string]interface{}) []Position {
+        positions := []Position{}
 
         if data, ok := resp["data"].([]interface{}); ok {
                 for _, item := range data {
                         if pos, ok := item.(map[string]interface{}); ok {
-                                // 辅助函数：安全解析float字符串
-                                parseFloat := func(key string) float64 {
-                                        if valStr, ok := pos[key].(string); ok && valStr != "" {
-                                                if val, err := strconv.ParseFloat(valStr, 64); err == nil {
-                                                        return val
-                                                }
-                                        }
-                                        return 0.0
+                                instId := parseOKXString(pos["instId"])
+                                if position, ok := okxPosition(pos, t.getContractValue(instId)); ok {
+                                        positions = append(positions, position)
                                 }
-
-                                // 解析关键数值字段
-                                markPrice := parseFloat("markPx")
-                                entryPrice := parseFloat("avgPx")
-                                quantity := parseFloat("pos")
-                                upl := parseFloat("upl")
-                                liqPx := parseFloat("liqPx")
-                                leverage := parseFloat("lever")
-
-                                // 将OKX格式的symbol (如 BTC-USDT-SWAP) 转换为内部格式 (如 BTCUSDT)
-                                // 这是关键修复：确保返回的symbol格式与market.Get()期望的格式一致
-                                okxInstId, _ := pos["instId"].(string)
-                                internalSymbol := convertFromOKXSymbol(okxInstId)
-
-                                // 标准化持仓数据格式 (适配 AutoTrader 要求)
-                                standardizedPos := map[string]interface{}{
-                                        // 核心字段 (AutoTrader 必需)
-                                        "symbol":           internalSymbol,     // 使用内部格式 (BTCUSDT) 而非OKX格式 (BTC-USDT-SWAP)
-                                        "side":             pos["posSide"],     // AutoTrader期望 key="side"
-                                        "markPrice":        markPrice,          // AutoTrader期望 key="markPrice" (float64)
-                                        "entryPrice":       entryPrice,         // AutoTrader期望 key="entryPrice" (float64)
-                                        "positionAmt":      quantity,           // AutoTrader期望 key="positionAmt" (float64)
-                                        "unRealizedProfit": upl,                // AutoTrader期望 key="unRealizedProfit" (float64)
-                                        "leverage":         leverage,           // AutoTrader期望 key="leverage" (float64)
-                                        "liquidationPrice": liqPx,              // AutoTrader期望 key="liquidationPrice" (float64)
-
-                                        // 兼容性/原始字段
-                                        "posSide":          pos["posSide"],
-                                        "marginMode":       pos["mgnMode"],
-                                        "uplRatio":         pos["uplRatio"],
-                                        "okxInstId":        okxInstId,          // 保留原始OKX格式供调试用
-                                }
-                                positions = append(positions, standardizedPos)
                         }
                 }
         }
@@ -226,6 +196,40 @@ func (t *OKXTrader) parsePositions(resp map[string]interface{}) []map[string]int
         return positions
 }
 
+// okxPosition 将OKX持仓转换为统一格式（pos为合约张数，按面值ctVal换算为币数量）
+func okxPosition(pos map[string]interface{}, ctVal float64) (Position, bool) {
+        contracts := parseOKXFloat(parseOKXString(pos["pos"]))
+        if contracts == 0 {
+                return Position{}, false
+        }
+
+        // 多空模式下posSide为long/short，单向持仓模式(net)下由张数正负判断方向
+        side := parseOKXString(pos["posSide"])
+        if side != "long" && side != "short" {
+                side = "long"
+                if contracts < 0 {
+                        side = "short"
+                }
+        }
+        if contracts < 0 {
+                contracts = -contracts
+        }
+
+        // 将OKX格式的symbol (如 BTC-USDT-SWAP) 转换为内部格式 (如 BTCUSDT)
+        // 这是关键修复：确保返回的symbol格式与market.Get()期望的格式一致
+        return Position{
+                Symbol:           convertFromOKXSymbol(parseOKXString(pos["instId"])),
+                Side:             side,
+                Quantity:         contracts * ctVal,
+                EntryPrice:       parseOKXFloat(parseOKXString(pos["avgPx"])),
+                MarkPrice:        parseOKXFloat(parseOKXString(pos["markPx"])),
+                UnrealizedProfit: parseOKXFloat(parseOKXString(pos["upl"])),
+                Leverage:         int(parseOKXFloat(parseOKXString(pos["lever"]))),
+                LiquidationPrice: parseOKXFloat(parseOKXString(pos["liqPx"])),
+                MarginMode:       parseOKXString(pos["mgnMode"]),
+        }, true
+}
+
 // ContractSpec 合约规格
 type ContractSpec struct {
         CtVal float64 // 合约面值（1张合约对应多少币）
@@ -331,7 +335,8 @@ func (t *OKXTrader) convertToContractSize(instId string, coinAmount float64) (st
         // 例如: lotSz=1 时，3.7 -> 3; lotSz=0.1 时，3.75 -> 3.7; lotSz=0.01 时，3.756 -> 3.75
         contractSize := rawContractSize
         if spec.LotSz > 0 {
-                contractSize = math.Floor(rawContractSize/spec.LotSz) * spec.LotSz
+                // 加上极小值避免浮点误差（如持仓币数量换算回张数得到2.9999999）导致少平一档
+                contractSize = math.Floor(rawContractSize/spec.LotSz+1e-9) * spec.LotSz
         }
         
         // 检查取整后是否为0或小于最小下单量
@@ -368,7 +373,7 @@ func (t *OKXTrader) convertToContractSize(instId string, coinAmount float64) (st
 }
 
 // OpenLong 开多仓
-func (t *OKXTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *OKXTrader) OpenLong(symbol string, quantity float64, leverage int) (*OrderResult, error) {
         if quantity <= 0 {
                 return nil, fmt.Errorf("开仓数量必须大于0")
         }
@@ -395,7 +400,7 @@ func (t *OKXTrader) OpenLong(symbol string, quantity float64, leverage int) (map
 
         order := map[string]string{
                 "instId":  okxSymbol,        // 产品ID，如 "BTC-USDT-SWAP"
-                "tdMode":  "cross",          // 保证金模式：cross(全仓) / isolated(逐仓)
+                "tdMode":  t.tdMode(),       // 保证金模式：cross(全仓) / isolated(逐仓)
                 "side":    "buy",            // 订单方向：buy(买入开多)
                 "posSide": "long",           // 仓位方向：long(多头) - OKX多空模式必须
                 "ordType": "market",         // 订单类型：market(市价)
@@ -406,7 +411,7 @@ func (t *OKXTrader) OpenLong(symbol string, quantity float64, leverage int) (map
 }
 
 // OpenShort 开空仓
-func (t *OKXTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+func (t *OKXTrader) OpenShort(symbol string, quantity float64, leverage int) (*OrderResult, error) {
         if quantity <= 0 {
                 return nil, fmt.Errorf("开仓数量必须大于0")
         }
@@ -433,7 +438,7 @@ func (t *OKXTrader) OpenShort(symbol string, quantity float64, leverage int) (ma
 
         order := map[string]string{
                 "instId":  okxSymbol,
-                "tdMode":  "cross",
+                "tdMode":  t.tdMode(),
                 "side":    "sell",           // 卖出开空
                 "posSide": "short",          // 仓位方向：short(空头) - OKX多空模式必须
                 "ordType": "market",
@@ -444,7 +449,7 @@ func (t *OKXTrader) OpenShort(symbol string, quantity float64, leverage int) (ma
 }
 
 // CloseLong 平多仓
-func (t *OKXTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *OKXTrader) CloseLong(symbol string, quantity float64) (*OrderResult, error) {
         // 转换交易对格式
         okxSymbol := convertToOKXSymbol(symbol)
         log.Printf("📊 OKX平多: 原始交易对=%s, OKX格式=%s", symbol, okxSymbol)
@@ -457,14 +462,16 @@ func (t *OKXTrader) CloseLong(symbol string, quantity float64) (map[string]inter
         }
 
         var positionSize float64
+        tdMode := t.tdMode()
         for _, pos := range positions {
-                posSymbol := pos["symbol"].(string)
                 // 比较时也需要转换格式
-                if (posSymbol == okxSymbol || convertToOKXSymbol(posSymbol) == okxSymbol) && pos["posSide"] == "long" {
-                        if size, ok := pos["positionAmt"].(float64); ok {
-                                positionSize = size
-                                break
+                if (pos.Symbol == okxSymbol || convertToOKXSymbol(pos.Symbol) == okxSymbol) && pos.Side == "long" {
+                        positionSize = pos.Quantity
+                        // 平仓的tdMode必须与持仓的保证金模式一致
+                        if pos.MarginMode != "" {
+                                tdMode = pos.MarginMode
                         }
+                        break
                 }
         }
 
@@ -482,20 +489,26 @@ func (t *OKXTrader) CloseLong(symbol string, quantity float64) (map[string]inter
                 quantity = positionSize
         }
 
+        // 将币数量转换为合约张数
+        contractSize, err := t.convertToContractSize(okxSymbol, quantity)
+        if err != nil {
+                return nil, fmt.Errorf("转换合约张数失败: %w", err)
+        }
+
         order := map[string]string{
                 "instId":  okxSymbol,
-                "tdMode":  "cross",
+                "tdMode":  tdMode,
                 "side":    "sell",           // 卖出平仓
                 "posSide": "long",           // 仓位方向：平多仓 - OKX多空模式必须
                 "ordType": "market",
-                "sz":      strconv.FormatFloat(quantity, 'f', -1, 64),
+                "sz":      contractSize,     // 合约张数（不是币数量）
         }
 
         return t.placeOrder(order)
 }
 
 // CloseShort 平空仓
-func (t *OKXTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+func (t *OKXTrader) CloseShort(symbol string, quantity float64) (*OrderResult, error) {
         // 转换交易对格式
         okxSymbol := convertToOKXSymbol(symbol)
         log.Printf("📊 OKX平空: 原始交易对=%s, OKX格式=%s", symbol, okxSymbol)
@@ -506,14 +519,16 @@ func (t *OKXTrader) CloseShort(symbol string, quantity float64) (map[string]inte
         }
 
         var positionSize float64
+        tdMode := t.tdMode()
         for _, pos := range positions {
-                posSymbol := pos["symbol"].(string)
                 // 比较时也需要转换格式
-                if (posSymbol == okxSymbol || convertToOKXSymbol(posSymbol) == okxSymbol) && pos["posSide"] == "short" {
-                        if size, ok := pos["positionAmt"].(float64); ok {
-                                positionSize = size
-                                break
+                if (pos.Symbol == okxSymbol || convertToOKXSymbol(pos.Symbol) == okxSymbol) && pos.Side == "short" {
+                        positionSize = pos.Quantity
+                        // 平仓的tdMode必须与持仓的保证金模式一致
+                        if pos.MarginMode != "" {
+                                tdMode = pos.MarginMode
                         }
+                        break
                 }
         }
 
@@ -529,20 +544,26 @@ func (t *OKXTrader) CloseShort(symbol string, quantity float64) (map[string]inte
                 quantity = positionSize
         }
 
+        // 将币数量转换为合约张数
+        contractSize, err := t.convertToContractSize(okxSymbol, quantity)
+        if err != nil {
+                return nil, fmt.Errorf("转换合约张数失败: %w", err)
+        }
+
         order := map[string]string{
                 "instId":  okxSymbol,
-                "tdMode":  "cross",
+                "tdMode":  tdMode,
                 "side":    "buy",            // 买入平仓
                 "posSide": "short",          // 仓位方向：平空仓 - OKX多空模式必须
                 "ordType": "market",
-                "sz":      strconv.FormatFloat(quantity, 'f', -1, 64),
+                "sz":      contractSize,     // 合约张数（不是币数量）
         }
 
         return t.placeOrder(order)
 }
 
 // placeOrder 下单统一方法
-func (t *OKXTrader) placeOrder(order map[string]string) (map[string]interface{}, error) {
+func (t *OKXTrader) placeOrder(order map[string]string) (*OrderResult, error) {
         // ========== 保证金预检查 ==========
         // 只对开仓订单进行保证金检查（side=buy/sell 且 ordType=market）
         // 平仓订单不需要额外保证金
@@ -553,13 +574,13 @@ func (t *OKXTrader) placeOrder(order map[string]string) (map[string]interface{},
         isOpenPosition := (order["side"] == "buy" && posSide == "long") || 
                           (order["side"] == "sell" && posSide == "short")
         
-        if isOpenPosition && (ordType == "market" || ordType == "limit") {
+        if isOpenPosition && ordType != "" {
                 // 获取可用保证金
                 balance, err := t.GetBalance()
                 if err != nil {
                         log.Printf("⚠️ 获取余额失败，跳过保证金检查: %v", err)
                 } else {
-                        availableMargin, _ := balance["free"].(float64)
+                        availableMargin := balance.AvailableBalance
                         
                         // 获取订单参数
                         instId := order["instId"]
@@ -591,7 +612,7 @@ func (t *OKXTrader) placeOrder(order map[string]string) (map[string]interface{},
                                         errMsg := fmt.Sprintf("INSUFFICIENT_MARGIN: 保证金不足，无法下单。可用保证金: %.2f USDT, 所需保证金: %.2f USDT (交易对: %s, 数量: %s, 价格: %.2f, 名义价值: %.2f USDT)。请减少下单数量或增加账户资金。",
                                                 availableMargin, requiredMarginWithBuffer, instId, szStr, price, notionalValue)
                                         log.Printf("❌ %s", errMsg)
-                                        return nil, fmt.Errorf(errMsg)
+                                        return nil, fmt.Errorf("%s", errMsg)
                                 }
                                 
                                 log.Printf("✅ 保证金充足，继续下单...")
@@ -610,6 +631,15 @@ func (t *OKXTrader) placeOrder(order map[string]string) (map[string]interface{},
                 return nil, fmt.Errorf("OKX下单失败: %w", err)
         }
 
+        result := &OrderResult{
+                Symbol:       convertFromOKXSymbol(order["instId"]),
+                Status:       OrderStatusNew, // 下单响应不包含订单状态，成交情况需要通过订单或成交记录查询确认
+                Type:         order["ordType"],
+                Side:         strings.ToUpper(order["side"]),
+                PositionSide: strings.ToUpper(order["posSide"]),
+                Price:        parseOKXFloat(order["px"]),
+        }
+
         // 检查data数组中的详细错误信息
         if data, ok := resp["data"].([]interface{}); ok && len(data) > 0 {
                 if orderResp, ok := data[0].(map[string]interface{}); ok {
@@ -623,11 +653,13 @@ func (t *OKXTrader) placeOrder(order map[string]string) (map[string]interface{},
                         if ordId, ok := orderResp["ordId"].(string); ok && ordId != "" {
                                 log.Printf("✅ OKX下单成功: ordId=%s, side=%s, symbol=%s, quantity=%s",
                                         ordId, order["side"], order["instId"], order["sz"])
+                                result.OrderID = ordId
                         }
                 }
         }
+        t.invalidateCache()
 
-        return resp, nil
+        return result, nil
 }
 
 // SetLeverage 设置杠杆（多空模式下需要分别设置多头和空头杠杆）
@@ -649,7 +681,7 @@ func (t *OKXTrader) SetLeverage(symbol string, leverage int) error {
         paramsLong := map[string]string{
                 "instId":  okxSymbol,
                 "lever":   strconv.Itoa(leverage),
-                "mgnMode": "cross",
+                "mgnMode": t.tdMode(),
                 "posSide": "long",
         }
         _, err := t.makeRequest("POST", endpoint, paramsLong)
@@ -661,7 +693,7 @@ func (t *OKXTrader) SetLeverage(symbol string, leverage int) error {
         paramsShort := map[string]string{
                 "instId":  okxSymbol,
                 "lever":   strconv.Itoa(leverage),
-                "mgnMode": "cross",
+                "mgnMode": t.tdMode(),
                 "posSide": "short",
         }
         _, err = t.makeRequest("POST", endpoint, paramsShort)
@@ -674,28 +706,15 @@ func (t *OKXTrader) SetLeverage(symbol string, leverage int) error {
 }
 
 // SetMarginMode 设置仓位模式
+// OKX的保证金模式由每笔订单的tdMode决定，这里只记录，之后的开仓、杠杆设置和止盈止损使用该模式
 func (t *OKXTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
         mgnMode := "isolated"
         if isCrossMargin {
                 mgnMode = "cross"
         }
+        t.marginMode = mgnMode
 
-        // 转换交易对格式
-        okxSymbol := convertToOKXSymbol(symbol)
-
-        params := map[string]string{
-                "instId":  okxSymbol,
-                "mgnMode": mgnMode,
-        }
-
-        // OKX API: POST /api/v5/account/set-margin-mode
-        endpoint := "/api/v5/account/set-margin-mode"
-        _, err := t.makeRequest("POST", endpoint, params)
-        if err != nil {
-                return fmt.Errorf("设置OKX保证金模式失败: %w", err)
-        }
-
-        log.Printf("✅ OKX保证金模式设置成功: symbol=%s, mode=%s", okxSymbol, mgnMode)
+        log.Printf("✅ OKX保证金模式已设置: symbol=%s, mode=%s", convertToOKXSymbol(symbol), mgnMode)
         return nil
 }
 
@@ -793,65 +812,178 @@ func (t *OKXTrader) GetMarketPrice(symbol string) (float64, error) {
 
 // SetStopLoss 设置止损单
 func (t *OKXTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
-        side := "buy"
-        posSide := "short"
-        if positionSide == "long" {
-                side = "sell"
-                posSide = "long"
+        if err := t.placeAlgoOrder(symbol, positionSide, quantity, "sl", stopPrice); err != nil {
+                return fmt.Errorf("设置OKX止损失败: %w", err)
         }
 
-        // 转换交易对格式
-        okxSymbol := convertToOKXSymbol(symbol)
+        log.Printf("✅ OKX止损设置成功: symbol=%s, posSide=%s, stopPrice=%f", symbol, positionSide, stopPrice)
+        return nil
+}
 
-        order := map[string]string{
-                "instId":  okxSymbol,
-                "tdMode":  "cross",
-                "side":    side,
-                "posSide": posSide,          // 仓位方向 - OKX多空模式必须
-                "ordType": "conditional",    // 条件单
-                "sz":      strconv.FormatFloat(quantity, 'f', -1, 64),
-                "tpTriggerPx": strconv.FormatFloat(stopPrice, 'f', -1, 64), // 触发价格
-                "tpOrdPx": "-1", // 市价触发
+// SetTakeProfit 设置止盈单
+func (t *OKXTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+        if err := t.placeAlgoOrder(symbol, positionSide, quantity, "tp", takeProfitPrice); err != nil {
+                return fmt.Errorf("设置OKX止盈失败: %w", err)
+        }
+
+        log.Printf("✅ OKX止盈设置成功: symbol=%s, posSide=%s, takeProfitPrice=%f", symbol, positionSide, takeProfitPrice)
+        return nil
+}
+
+// SetTrailingStop 设置跟踪止损单（OKX原生移动止盈止损委托move_order_stop）
+func (t *OKXTrader) SetTrailingStop(symbol string, positionSide string, quantity, callbackRate, activationPrice float64) error {
+        if err := validateTrailingStop(positionSide, quantity, callbackRate, activationPrice); err != nil {
+                return err
         }
 
-        _, err := t.placeOrder(order)
+        params, err := t.algoOrderParams(symbol, positionSide, quantity, "move_order_stop")
         if err != nil {
-                return fmt.Errorf("设置OKX止损失败: %w", err)
+                return fmt.Errorf("设置OKX跟踪止损失败: %w", err)
+        }
+        params["callbackRatio"] = strconv.FormatFloat(callbackRate, 'f', -1, 64)
+        if activationPrice > 0 {
+                params["activePx"] = strconv.FormatFloat(activationPrice, 'f', -1, 64)
+        }
+
+        if err := t.submitAlgoOrder(params); err != nil {
+                return fmt.Errorf("设置OKX跟踪止损失败: %w", err)
         }
 
-        log.Printf("✅ OKX止损设置成功: symbol=%s, posSide=%s, stopPrice=%f", okxSymbol, posSide, stopPrice)
+        log.Printf("✅ OKX跟踪止损设置成功: symbol=%s, posSide=%s, callbackRatio=%f, activePx=%f", symbol, positionSide, callbackRate, activationPrice)
         return nil
 }
 
-// SetTakeProfit 设置止盈单
-func (t *OKXTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+// placeAlgoOrder 提交止盈止损条件单（kind: sl/tp），触发后市价平仓
+// OKX条件单需要通过策略委托接口提交，止损使用slTriggerPx、止盈使用tpTriggerPx
+func (t *OKXTrader) placeAlgoOrder(symbol, positionSide string, quantity float64, kind string, triggerPrice float64) error {
+        params, err := t.algoOrderParams(symbol, positionSide, quantity, "conditional") // 单向止盈止损
+        if err != nil {
+                return err
+        }
+        params[kind+"TriggerPx"] = strconv.FormatFloat(triggerPrice, 'f', -1, 64)
+        params[kind+"OrdPx"] = "-1" // 市价触发
+
+        return t.submitAlgoOrder(params)
+}
+
+// algoOrderParams 构造平仓方向的策略委托公共参数
+func (t *OKXTrader) algoOrderParams(symbol, positionSide string, quantity float64, ordType string) (map[string]string, error) {
+        // positionSide为LONG/SHORT，平多=卖出，平空=买入
         side := "buy"
         posSide := "short"
-        if positionSide == "long" {
+        if strings.EqualFold(positionSide, "long") {
                 side = "sell"
                 posSide = "long"
         }
 
-        // 转换交易对格式
         okxSymbol := convertToOKXSymbol(symbol)
 
-        order := map[string]string{
+        // 条件单数量同样是合约张数
+        contractSize, err := t.convertToContractSize(okxSymbol, quantity)
+        if err != nil {
+                return nil, fmt.Errorf("转换合约张数失败: %w", err)
+        }
+
+        return map[string]string{
                 "instId":  okxSymbol,
-                "tdMode":  "cross",
+                "tdMode":  t.tdMode(),
                 "side":    side,
-                "posSide": posSide,          // 仓位方向 - OKX多空模式必须
-                "ordType": "conditional",
-                "sz":      strconv.FormatFloat(quantity, 'f', -1, 64),
-                "tpTriggerPx": strconv.FormatFloat(takeProfitPrice, 'f', -1, 64),
-                "tpOrdPx": "-1",
+                "posSide": posSide, // 仓位方向 - OKX多空模式必须
+                "ordType": ordType,
+                "sz":      contractSize,
+        }, nil
+}
+
+// submitAlgoOrder 提交策略委托并检查单条结果的sCode
+func (t *OKXTrader) submitAlgoOrder(params map[string]string) error {
+        // OKX API: POST /api/v5/trade/order-algo
+        resp, err := t.makeRequest("POST", "/api/v5/trade/order-algo", params)
+        if err != nil {
+                return err
+        }
+        if data, ok := resp["data"].([]interface{}); ok && len(data) > 0 {
+                if item, ok := data[0].(map[string]interface{}); ok {
+                        sCode, _ := item["sCode"].(string)
+                        sMsg, _ := item["sMsg"].(string)
+                        if sCode != "" && sCode != "0" {
+                                return fmt.Errorf("OKX条件单失败 [%s]: %s", sCode, sMsg)
+                        }
+                }
+        }
+        return nil
+}
+
+// GetProtectiveOrders 获取止盈止损条件单（OKX条件单为策略委托，需要单独查询）
+func (t *OKXTrader) GetProtectiveOrders(symbol string) ([]ProtectiveOrder, error) {
+        params := map[string]string{
+                "instType": "SWAP",
+                "ordType":  "conditional",
+        }
+        if symbol != "" {
+                params["instId"] = convertToOKXSymbol(symbol)
         }
 
-        _, err := t.placeOrder(order)
+        // OKX API: GET /api/v5/trade/orders-algo-pending
+        resp, err := t.makeRequest("GET", "/api/v5/trade/orders-algo-pending", params)
         if err != nil {
-                return fmt.Errorf("设置OKX止盈失败: %w", err)
+                return nil, fmt.Errorf("获取OKX条件单失败: %w", err)
         }
 
-        log.Printf("✅ OKX止盈设置成功: symbol=%s, posSide=%s, takeProfitPrice=%f", okxSymbol, posSide, takeProfitPrice)
+        orders := []ProtectiveOrder{}
+        data, _ := resp["data"].([]interface{})
+        for _, entry := range data {
+                item, ok := entry.(map[string]interface{})
+                if !ok {
+                        continue
+                }
+                instId, _ := item["instId"].(string)
+                algoId, _ := item["algoId"].(string)
+                posSide, _ := item["posSide"].(string)
+                side, _ := item["side"].(string)
+                sz, _ := item["sz"].(string)
+
+                kind := ProtectiveKindTakeProfit
+                triggerPx, _ := item["tpTriggerPx"].(string)
+                if slTriggerPx, _ := item["slTriggerPx"].(string); slTriggerPx != "" {
+                        kind = ProtectiveKindStopLoss
+                        triggerPx = slTriggerPx
+                }
+
+                orders = append(orders, ProtectiveOrder{
+                        OrderID:      algoId,
+                        Symbol:       convertFromOKXSymbol(instId),
+                        PositionSide: protectiveSide(posSide, side),
+                        Kind:         kind,
+                        TriggerPrice: parseFloatOrZero(triggerPx),
+                        Quantity:     parseFloatOrZero(sz) * t.getContractValue(instId),
+                })
+        }
+        return orders, nil
+}
+
+// CancelProtectiveOrder 取消单个止盈止损条件单（撤销策略委托）
+func (t *OKXTrader) CancelProtectiveOrder(symbol string, orderID string) error {
+        okxSymbol := convertToOKXSymbol(symbol)
+
+        // OKX API: POST /api/v5/trade/cancel-algos（body为数组）
+        resp, err := t.makeBatchRequest("/api/v5/trade/cancel-algos", []map[string]string{{
+                "instId": okxSymbol,
+                "algoId": orderID,
+        }})
+        if err != nil {
+                return fmt.Errorf("取消OKX条件单失败: %w", err)
+        }
+        if data, ok := resp["data"].([]interface{}); ok && len(data) > 0 {
+                if item, ok := data[0].(map[string]interface{}); ok {
+                        sCode, _ := item["sCode"].(string)
+                        sMsg, _ := item["sMsg"].(string)
+                        if sCode != "" && sCode != "0" {
+                                return fmt.Errorf("取消OKX条件单失败 [%s]: %s", sCode, sMsg)
+                        }
+                }
+        }
+
+        log.Printf("✅ OKX取消条件单成功: symbol=%s, algoId=%s", okxSymbol, orderID)
         return nil
 }
 
@@ -875,8 +1007,140 @@ func (t *OKXTrader) CancelAllOrders(symbol string) error {
         return nil
 }
 
+// okxOrdType 转换为OKX的订单类型（OKX用ordType表达有效方式）
+func okxOrdType(tif TimeInForce) (string, error) {
+        switch tif {
+        case TimeInForceGTC, "":
+                return "limit", nil
+        case TimeInForceIOC:
+                return "ioc", nil
+        case TimeInForceFOK:
+                return "fok", nil
+        case TimeInForcePostOnly:
+                return "post_only", nil
+        default:
+                return "", fmt.Errorf("不支持的限价单有效方式: %s", tif)
+        }
+}
+
+// okxOrderID 从下单/改单响应中提取订单ID
+func okxOrderID(resp map[string]interface{}) string {
+        if data, ok := resp["data"].([]interface{}); ok && len(data) > 0 {
+                if item, ok := data[0].(map[string]interface{}); ok {
+                        ordId, _ := item["ordId"].(string)
+                        return ordId
+                }
+        }
+        return ""
+}
+
+// PlaceLimitOrder 下限价开仓单
+func (t *OKXTrader) PlaceLimitOrder(symbol string, positionSide string, quantity, price float64, leverage int, tif TimeInForce) (*OrderResult, error) {
+        if quantity <= 0 || price <= 0 {
+                return nil, fmt.Errorf("限价单数量和价格必须大于0")
+        }
+        ordType, err := okxOrdType(tif)
+        if err != nil {
+                return nil, err
+        }
+
+        okxSymbol := convertToOKXSymbol(symbol)
+        side, posSide := "buy", "long"
+        if positionSide == "SHORT" {
+                side, posSide = "sell", "short"
+        }
+        log.Printf("📊 OKX限价单: %s %s 币数量=%f 价格=%f 杠杆=%d 类型=%s", okxSymbol, posSide, quantity, price, leverage, ordType)
+
+        if err := t.EnsureLongShortMode(); err != nil {
+                log.Printf("⚠️ 设置持仓模式失败: %v，继续尝试下单", err)
+        }
+        if err := t.SetLeverage(okxSymbol, leverage); err != nil {
+                log.Printf("⚠️ 设置杠杆失败: %v", err)
+        }
+
+        contractSize, err := t.convertToContractSize(okxSymbol, quantity)
+        if err != nil {
+                return nil, fmt.Errorf("转换合约张数失败: %w", err)
+        }
+
+        order := map[string]string{
+                "instId":  okxSymbol,
+                "tdMode":  t.tdMode(),
+                "side":    side,
+                "posSide": posSide,
+                "ordType": ordType,
+                "sz":      contractSize,
+                "px":      strconv.FormatFloat(price, 'f', -1, 64),
+        }
+
+        result, err := t.placeOrder(order)
+        if err != nil {
+                return nil, err
+        }
+
+        // 下单响应不包含订单状态，成交情况需要通过持仓或订单查询确认
+        result.Symbol = symbol
+        result.Quantity = quantity
+        return result, nil
+}
+
+// AmendOrder 修改未成交限价单的价格和数量
+func (t *OKXTrader) AmendOrder(symbol string, orderID string, quantity, price float64) (*OrderResult, error) {
+        okxSymbol := convertToOKXSymbol(symbol)
+        params := map[string]string{
+                "instId": okxSymbol,
+                "ordId":  orderID,
+        }
+        if price > 0 {
+                params["newPx"] = strconv.FormatFloat(price, 'f', -1, 64)
+        }
+        if quantity > 0 {
+                contractSize, err := t.convertToContractSize(okxSymbol, quantity)
+                if err != nil {
+                        return nil, fmt.Errorf("转换合约张数失败: %w", err)
+                }
+                params["newSz"] = contractSize
+        }
+
+        // OKX API: POST /api/v5/trade/amend-order
+        resp, err := t.makeRequest("POST", "/api/v5/trade/amend-order", params)
+        if err != nil {
+                return nil, fmt.Errorf("修改OKX订单失败: %w", err)
+        }
+
+        log.Printf("✅ OKX修改订单成功: symbol=%s, ordId=%s", okxSymbol, orderID)
+        if amendedID := okxOrderID(resp); amendedID != "" {
+                orderID = amendedID
+        }
+        return &OrderResult{
+                OrderID:  orderID,
+                Symbol:   symbol,
+                Status:   OrderStatusNew,
+                Type:     "limit",
+                Price:    price,
+                Quantity: quantity,
+        }, nil
+}
+
+// CancelOrder 取消单个订单
+func (t *OKXTrader) CancelOrder(symbol string, orderID string) error {
+        okxSymbol := convertToOKXSymbol(symbol)
+        params := map[string]string{
+                "instId": okxSymbol,
+                "ordId":  orderID,
+        }
+
+        // OKX API: POST /api/v5/trade/cancel-order
+        if _, err := t.makeRequest("POST", "/api/v5/trade/cancel-order", params); err != nil {
+                return fmt.Errorf("取消OKX订单失败: %w", err)
+        }
+
+        log.Printf("✅ OKX取消订单成功: symbol=%s, ordId=%s", okxSymbol, orderID)
+        return nil
+}
+
 // ClosePosition 关闭指定持仓
-func (t *OKXTrader) ClosePosition(symbol string, side string) (map[string]interface{}, error) {
+func (t *OKXTrader) ClosePosition(symbol string, side string) (*OrderResult, error) {
         // 转换交易对格式
         okxSymbol := convertToOKXSymbol(symbol)
 
@@ -887,10 +1151,10 @@ func (t *OKXTrader) ClosePosition(symbol string, side string) (map[string]interf
         }
 
         // 查找匹配的持仓
-        var position map[string]interface{}
-        for _, pos := range positions {
-                posSymbol := pos["symbol"].(string)
-                if (posSymbol == okxSymbol || convertToOKXSymbol(posSymbol) == okxSymbol) && pos["side"] == side {
+        var position *Position
+        for i := range positions {
+                pos := &positions[i]
+                if (pos.Symbol == okxSymbol || convertToOKXSymbol(pos.Symbol) == okxSymbol) && pos.Side == side {
                         position = pos
                         break
                 }
@@ -900,7 +1164,11 @@ func (t *OKXTrader) ClosePosition(symbol string, side string) (map[string]interf
                 return nil, fmt.Errorf("未找到持仓: symbol=%s, side=%s", symbol, side)
         }
 
-        quantity := position["positionAmt"].(float64)
+        quantity := position.Quantity
+        contractSize, err := t.convertToContractSize(okxSymbol, quantity)
+        if err != nil {
+                return nil, fmt.Errorf("转换合约张数失败: %w", err)
+        }
 
         // 根据持仓方向决定平仓方向
         var closeSide string
@@ -910,12 +1178,18 @@ func (t *OKXTrader) ClosePosition(symbol string, side string) (map[string]interf
                 closeSide = "buy"  // 空头平仓需要买入
         }
 
+        tdMode := position.MarginMode
+        if tdMode == "" {
+                tdMode = t.tdMode()
+        }
+
         order := map[string]string{
-                "instId":  symbol,
-                "tdMode":  "cross", // 默认全仓模式
+                "instId":  okxSymbol,
+                "tdMode":  tdMode, // 与持仓的保证金模式一致
                 "side":    closeSide,
+                "posSide": side,
                 "ordType": "market", // 市价平仓
-                "sz":      fmt.Sprintf("%.4f", quantity),
+                "sz":      contractSize,
         }
 
         result, err := t.placeOrder(order)
@@ -927,15 +1201,104 @@ func (t *OKXTrader) ClosePosition(symbol string, side string) (map[string]interf
         return result, nil
 }
 
-// GetFills 获取成交记录
-func (t *OKXTrader) GetFills(symbol string, limit int) ([]map[string]interface{}, error) {
-        if limit <= 0 || limit > 100 {
-                limit = 20 // 默认获取最近20条记录
+// okxOrderStatus 将OKX订单状态转换为统一格式
+func okxOrderStatus(state string) string {
+        switch state {
+        case "live":
+                return OrderStatusNew
+        case "partially_filled":
+                return OrderStatusPartiallyFilled
+        case "filled":
+                return OrderStatusFilled
+        default:
+                // canceled、mmp_canceled
+                return OrderStatusCanceled
+        }
+}
+
+// parseOKXOrder 将OKX订单转换为统一格式
+func (t *OKXTrader) parseOKXOrder(item map[string]interface{}) *OrderResult {
+        return okxOrderResult(item, t.getContractValue(parseOKXString(item["instId"])))
+}
+
+// okxOrderResult 将OKX订单转换为统一格式（张数按面值ctVal换算为币数量）
+func okxOrderResult(item map[string]interface{}, ctVal float64) *OrderResult {
+        return &OrderResult{
+                OrderID:      parseOKXString(item["ordId"]),
+                Symbol:       convertFromOKXSymbol(parseOKXString(item["instId"])),
+                Status:       okxOrderStatus(parseOKXString(item["state"])),
+                Type:         parseOKXString(item["ordType"]),
+                Side:         strings.ToUpper(parseOKXString(item["side"])),
+                PositionSide: strings.ToUpper(parseOKXString(item["posSide"])),
+                Price:        parseOKXFloat(parseOKXString(item["px"])),
+                Quantity:     parseOKXFloat(parseOKXString(item["sz"])) * ctVal,
+                ExecutedQty:  parseOKXFloat(parseOKXString(item["accFillSz"])) * ctVal,
+                AvgPrice:     parseOKXFloat(parseOKXString(item["avgPx"])),
+                // OKX的手续费为负数表示支出，统一为正数表示支付的手续费
+                Fee:        -parseOKXFloat(parseOKXString(item["fee"])),
+                Time:       parseOKXTimestamp(parseOKXString(item["cTime"])),
+                UpdateTime: parseOKXTimestamp(parseOKXString(item["uTime"])),
+        }
+}
+
+// GetOrder 查询单个订单
+func (t *OKXTrader) GetOrder(symbol string, orderID string) (*OrderResult, error) {
+        params := map[string]string{
+                "instId": convertToOKXSymbol(symbol),
+                "ordId":  orderID,
+        }
+
+        // OKX API: GET /api/v5/trade/order
+        resp, err := t.makeRequest("GET", "/api/v5/trade/order", params)
+        if err != nil {
+                return nil, fmt.Errorf("查询OKX订单失败: %w", err)
+        }
+
+        data, ok := resp["data"].([]interface{})
+        if !ok || len(data) == 0 {
+                return nil, fmt.Errorf("未找到OKX订单 %s", orderID)
+        }
+        item, ok := data[0].(map[string]interface{})
+        if !ok {
+                return nil, fmt.Errorf("OKX订单数据格式错误")
+        }
+        return t.parseOKXOrder(item), nil
+}
+
+// GetOpenOrders 获取未成交挂单（symbol为空表示所有币种）
+func (t *OKXTrader) GetOpenOrders(symbol string) ([]OrderResult, error) {
+        params := map[string]string{
+                "instType": "SWAP",
+        }
+        if symbol != "" {
+                params["instId"] = convertToOKXSymbol(symbol)
         }
 
+        // OKX API: GET /api/v5/trade/orders-pending
+        resp, err := t.makeRequest("GET", "/api/v5/trade/orders-pending", params)
+        if err != nil {
+                return nil, fmt.Errorf("获取OKX挂单失败: %w", err)
+        }
+
+        orders := []OrderResult{}
+        data, _ := resp["data"].([]interface{})
+        for _, entry := range data {
+                if item, ok := entry.(map[string]interface{}); ok {
+                        orders = append(orders, *t.parseOKXOrder(item))
+                }
+        }
+        return orders, nil
+}
+
+// GetFills 获取自since以来的成交记录（symbol为空表示所有币种）
+func (t *OKXTrader) GetFills(symbol string, since time.Time) ([]Fill, error) {
         params := map[string]string{
-                "instId": symbol,
-                "limit":  fmt.Sprintf("%d", limit),
+                "instType": "SWAP",
+                "begin":    strconv.FormatInt(since.UnixMilli(), 10),
+                "limit":    "100",
+        }
+        if symbol != "" {
+                params["instId"] = convertToOKXSymbol(symbol)
         }
 
         // OKX API: GET /api/v5/trade/fills
@@ -948,39 +1311,48 @@ func (t *OKXTrader) GetFills(symbol string, limit int) ([]map[string]interface{}
         // 解析成交记录
         fillsData, ok := resp["data"].([]interface{})
         if !ok {
-                return []map[string]interface{}{}, nil
+                return []Fill{}, nil
         }
 
-        var fills []map[string]interface{}
+        fills := []Fill{}
         for _, fillItem := range fillsData {
                 fill, ok := fillItem.(map[string]interface{})
                 if !ok {
                         continue
                 }
 
-                // 标准化成交记录格式
-                standardizedFill := map[string]interface{}{
-                        "symbol":      symbol,
-                        "orderId":     fill["ordId"],
-                        "fillId":      fill["tradeId"],
-                        "side":        t.standardizeSide(fill["side"].(string)),
-                        "quantity":    parseOKXFloat(fill["sz"].(string)),
-                        "price":       parseOKXFloat(fill["px"].(string)),
-                        "timestamp":   parseOKXTimestamp(fill["ts"].(string)),
-                        "fee":         parseOKXFloat(fill["fee"].(string)),
-                        "feeCurrency": fill["feeCcy"],
-                        "role":        fill["side"], // maker or taker
-                }
-
-                fills = append(fills, standardizedFill)
+                instId := parseOKXString(fill["instId"])
+                fills = append(fills, okxFill(fill, t.getContractValue(instId)))
         }
 
         log.Printf("✅ OKX获取成交记录成功: symbol=%s, count=%d", symbol, len(fills))
         return fills, nil
 }
 
-// standardizeSide 标准化交易方向
-func (t *OKXTrader) standardizeSide(side string) string {
+// okxFill 将OKX成交记录转换为统一格式（张数按面值ctVal换算为币数量，手续费统一为正数表示支出）
+func okxFill(fill map[string]interface{}, ctVal float64) Fill {
+        role := "taker"
+        if parseOKXString(fill["execType"]) == "M" {
+                role = "maker"
+        }
+
+        return Fill{
+                FillID:       parseOKXString(fill["tradeId"]),
+                OrderID:      parseOKXString(fill["ordId"]),
+                Symbol:       convertFromOKXSymbol(parseOKXString(fill["instId"])),
+                Side:         standardizeOKXSide(parseOKXString(fill["side"])),
+                PositionSide: strings.ToUpper(parseOKXString(fill["posSide"])),
+                Price:        parseOKXFloat(parseOKXString(fill["fillPx"])),
+                Quantity:     parseOKXFloat(parseOKXString(fill["fillSz"])) * ctVal,
+                Fee:          -parseOKXFloat(parseOKXString(fill["fee"])),
+                FeeCurrency:  parseOKXString(fill["feeCcy"]),
+                Role:         role,
+                Timestamp:    parseOKXTimestamp(parseOKXString(fill["ts"])),
+        }
+}
+
+// standardizeOKXSide 标准化交易方向
+func standardizeOKXSide(side string) string {
         switch strings.ToLower(side) {
         case "buy":
                 return "buy"
@@ -991,28 +1363,60 @@ func (t *OKXTrader) standardizeSide(side string) string {
         }
 }
 
+// GetFundingPayments 获取自since以来的资金费结算记录（账单流水type=8，symbol为空表示所有币种）
+func (t *OKXTrader) GetFundingPayments(symbol string, since time.Time) ([]FundingPayment, error) {
+        params := map[string]string{
+                "instType": "SWAP",
+                "type":     "8",
+                "begin":    strconv.FormatInt(since.UnixMilli(), 10),
+                "limit":    "100",
+        }
+        if symbol != "" {
+                params["instId"] = convertToOKXSymbol(symbol)
+        }
+
+        // OKX API: GET /api/v5/account/bills
+        resp, err := t.makeRequest("GET", "/api/v5/account/bills", params)
+        if err != nil {
+                return nil, fmt.Errorf("获取资金费记录失败: %w", err)
+        }
+
+        payments := []FundingPayment{}
+        bills, _ := resp["data"].([]interface{})
+        for _, entry := range bills {
+                if bill, ok := entry.(map[string]interface{}); ok {
+                        payments = append(payments, okxFundingPayment(bill))
+                }
+        }
+        return payments, nil
+}
+
+// okxFundingPayment 将资金费账单转换为统一格式（balChg为正表示收到）
+func okxFundingPayment(bill map[string]interface{}) FundingPayment {
+        return FundingPayment{
+                Symbol:    convertFromOKXSymbol(parseOKXString(bill["instId"])),
+                Amount:    parseOKXFloat(parseOKXString(bill["balChg"])),
+                Timestamp: parseOKXTimestamp(parseOKXString(bill["ts"])),
+        }
+}
+
 // FormatQuantity 格式化数量到正确的精度
+// OKX按合约张数下单，币数量的精度 = 下单精度lotSz × 合约面值ctVal
 func (t *OKXTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
-        // OKX的数量精度规则：
-        // BTC-USDT-SWAP: 0.001
-        // ETH-USDT-SWAP: 0.001
-        // 其他币种根据合约规定
-
-        // 基本实现：根据symbol判断精度
-        var precision int
-        switch {
-        case strings.HasPrefix(symbol, "BTC-"):
-                precision = 3
-        case strings.HasPrefix(symbol, "ETH-"):
-                precision = 3
-        case strings.HasPrefix(symbol, "SOL-"):
-                precision = 3
-        default:
-                precision = 4 // 默认精度
+        spec, err := t.getContractSpec(convertToOKXSymbol(symbol))
+        if err != nil {
+                return "", err
+        }
+
+        step := spec.LotSz * spec.CtVal
+        precision := 0
+        if step > 0 && step < 1 {
+                precision = int(math.Ceil(-math.Log10(step) - 1e-9))
         }
 
+        // 与下单时的张数换算一致，向下取整
         format := fmt.Sprintf("%%.%df", precision)
-        return fmt.Sprintf(format, quantity), nil
+        return fmt.Sprintf(format, floorToStep(quantity, step)), nil
 }
 
 // generateSignature 生成OKX API签名
@@ -1052,6 +1456,23 @@ func (t *OKXTrader) makeRequest(method, endpoint string, params map[string]strin
                 log.Printf("📡 OKX POST请求: %s, body: %s", endpoint, body)
         }
 
+        return t.send(timestamp, method, requestPath, fullURL, body)
+}
+
+// makeBatchRequest 发送body为JSON数组的POST请求（如批量撤销策略委托）
+func (t *OKXTrader) makeBatchRequest(endpoint string, items []map[string]string) (map[string]interface{}, error) {
+        jsonBody, err := json.Marshal(items)
+        if err != nil {
+                return nil, fmt.Errorf("序列化请求参数失败: %w", err)
+        }
+        log.Printf("📡 OKX POST请求: %s, body: %s", endpoint, string(jsonBody))
+
+        timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+        return t.send(timestamp, "POST", endpoint, t.baseURL+endpoint, string(jsonBody))
+}
+
+// send 签名并发送请求，解析响应并检查OKX错误码
+func (t *OKXTrader) send(timestamp, method, requestPath, fullURL, body string) (map[string]interface{}, error) {
         // 生成签名（使用完整的请求路径）
         signature := t.generateSignature(timestamp, method, requestPath, body)
 
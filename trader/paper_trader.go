@@ -0,0 +1,1264 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"nofx/market"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 模拟盘默认参数
+	defaultPaperFeeRate               = 0.0004           // 默认taker手续费 0.04%
+	defaultPaperSlippageRate          = 0.0005           // 默认滑点 0.05%
+	defaultPaperMaintenanceMarginRate = 0.005            // 默认维持保证金率 0.5%
+	maxPaperFillHistory               = 500              // 保留的成交记录上限
+	paperMonitorInterval              = 10 * time.Second // 价格监控间隔
+
+	// 模拟成交原因
+	PaperFillReasonManual      = "manual"
+	PaperFillReasonStopLoss    = "stop_loss"
+	PaperFillReasonTakeProfit  = "take_profit"
+	PaperFillReasonLiquidation = "liquidation"
+	PaperFillReasonLimit       = "limit"
+
+	// 模拟条件单类型（与币安订单类型保持一致）
+	paperOrderTypeStop       = "STOP_MARKET"
+	paperOrderTypeTakeProfit = "TAKE_PROFIT_MARKET"
+	paperOrderTypeLimit      = "LIMIT"
+)
+
+// PaperAccountStore 模拟盘账户持久化接口（由config.Database实现）
+type PaperAccountStore interface {
+	GetPaperAccountState(traderID string) (string, error)
+	SavePaperAccountState(traderID string, state string) error
+}
+
+// PaperTraderConfig 模拟盘配置
+type PaperTraderConfig struct {
+	TraderID              string                               // 交易员ID（持久化主键）
+	InitialBalance        float64                              // 初始资金（USDT）
+	FeeRate               *float64                             // 手续费率（如0.0004表示0.04%，nil使用默认值，0表示免手续费）
+	SlippageRate          *float64                             // 滑点比例（如0.0005表示0.05%，nil使用默认值，0表示无滑点）
+	MaintenanceMarginRate float64                              // 维持保证金率（用于计算强平价）
+	Store                 PaperAccountStore                    // 持久化存储（可选，nil表示仅内存）
+	PriceSource           func(symbol string) (float64, error) // 价格来源（可选，默认market.Get）
+	Clock                 func() time.Time                     // 时钟（可选，回测时使用模拟时间）
+	FillHandler           func(fill PaperFill)                 // 成交回调（可选，持锁调用，不可回调交易器）
+}
+
+// PaperFill 模拟成交记录
+type PaperFill struct {
+	OrderID     int64     `json:"order_id"`
+	Symbol      string    `json:"symbol"`
+	Action      string    `json:"action"` // open_long/open_short/close_long/close_short
+	Price       float64   `json:"price"`
+	Quantity    float64   `json:"quantity"`
+	Leverage    int       `json:"leverage"`
+	EntryPrice  float64   `json:"entry_price"` // 平仓时为持仓开仓均价
+	Fee         float64   `json:"fee"`
+	RealizedPnL float64   `json:"realized_pnl"`
+	Reason      string    `json:"reason"` // manual/stop_loss/take_profit/liquidation/limit
+	Time        time.Time `json:"time"`
+}
+
+// paperPosition 模拟持仓
+type paperPosition struct {
+	Symbol     string    `json:"symbol"`
+	Side       string    `json:"side"` // long/short
+	Quantity   float64   `json:"quantity"`
+	EntryPrice float64   `json:"entry_price"`
+	MarkPrice  float64   `json:"mark_price"`
+	Leverage   int       `json:"leverage"`
+	IsCross    bool      `json:"is_cross"`
+	OpenTime   time.Time `json:"open_time"`
+}
+
+// paperOrder 模拟委托单（止损/止盈触发后平掉整个仓位，限价单到价后开仓）
+type paperOrder struct {
+	ID           int64       `json:"id"`
+	Symbol       string      `json:"symbol"`
+	PositionSide string      `json:"position_side"` // LONG/SHORT
+	Type         string      `json:"type"`          // STOP_MARKET/TAKE_PROFIT_MARKET/LIMIT
+	Quantity     float64     `json:"quantity"`
+	TriggerPrice float64     `json:"trigger_price"` // 限价单为限价
+	Leverage     int         `json:"leverage,omitempty"`
+	TimeInForce  TimeInForce `json:"time_in_force,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// paperAccountState 模拟账户状态（整体序列化后持久化）
+type paperAccountState struct {
+	WalletBalance float64                   `json:"wallet_balance"` // 钱包余额 = 初始资金 + 已实现盈亏 - 手续费
+	RealizedPnL   float64                   `json:"realized_pnl"`
+	TotalFees     float64                   `json:"total_fees"`
+	Positions     map[string]*paperPosition `json:"positions"` // key: symbol_side
+	Orders        []*paperOrder             `json:"orders"`
+	Leverage      map[string]int            `json:"leverage"`
+	CrossMargin   map[string]bool           `json:"cross_margin"`
+	NextOrderID   int64                     `json:"next_order_id"`
+	Fills         []PaperFill               `json:"fills"`
+}
+
+// PaperTrader 模拟盘交易器
+// 使用实时行情价格模拟成交，账户保存在内存并持久化到数据库
+type PaperTrader struct {
+	traderID              string
+	feeRate               float64
+	slippageRate          float64
+	maintenanceMarginRate float64
+	store                 PaperAccountStore
+	priceSource           func(symbol string) (float64, error)
+	clock                 func() time.Time
+	fillHandler           func(fill PaperFill)
+
+	mu    sync.Mutex
+	state *paperAccountState
+
+	monitorMu   sync.Mutex
+	monitorStop chan struct{}
+
+	// 跟踪止损在本地模拟（由AutoTrader持久化，重启后恢复）
+	trailing *trailingStopEmulator
+}
+
+// NewPaperTrader 创建模拟盘交易器
+func NewPaperTrader(cfg PaperTraderConfig) (*PaperTrader, error) {
+	if cfg.InitialBalance <= 0 {
+		return nil, fmt.Errorf("模拟盘初始资金必须大于0")
+	}
+	// 只有未设置时使用默认值，显式设置为0表示免手续费/无滑点
+	feeRate, slippageRate := defaultPaperFeeRate, defaultPaperSlippageRate
+	if cfg.FeeRate != nil {
+		feeRate = *cfg.FeeRate
+	}
+	if cfg.SlippageRate != nil {
+		slippageRate = *cfg.SlippageRate
+	}
+	if feeRate < 0 || slippageRate < 0 {
+		return nil, fmt.Errorf("模拟盘手续费率和滑点不能为负数")
+	}
+	if cfg.MaintenanceMarginRate <= 0 {
+		cfg.MaintenanceMarginRate = defaultPaperMaintenanceMarginRate
+	}
+	if cfg.PriceSource == nil {
+		cfg.PriceSource = marketPrice
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+
+	t := &PaperTrader{
+		traderID:              cfg.TraderID,
+		feeRate:               feeRate,
+		slippageRate:          slippageRate,
+		maintenanceMarginRate: cfg.MaintenanceMarginRate,
+		store:                 cfg.Store,
+		priceSource:           cfg.PriceSource,
+		clock:                 cfg.Clock,
+		fillHandler:           cfg.FillHandler,
+	}
+	t.trailing = newTrailingStopEmulator("Paper", t)
+
+	// 优先从数据库恢复账户状态
+	if t.store != nil && t.traderID != "" {
+		raw, err := t.store.GetPaperAccountState(t.traderID)
+		if err != nil {
+			return nil, fmt.Errorf("读取模拟盘账户失败: %w", err)
+		}
+		if raw != "" {
+			var state paperAccountState
+			if err := json.Unmarshal([]byte(raw), &state); err != nil {
+				return nil, fmt.Errorf("解析模拟盘账户失败: %w", err)
+			}
+			t.state = &state
+			t.state.ensureMaps()
+			log.Printf("📄 [Paper] 恢复模拟盘账户: %s 钱包余额 %.2f USDT, 持仓 %d 个", t.traderID, state.WalletBalance, len(state.Positions))
+		}
+	}
+
+	if t.state == nil {
+		t.state = &paperAccountState{WalletBalance: cfg.InitialBalance, NextOrderID: 1}
+		t.state.ensureMaps()
+		t.persistLocked()
+		log.Printf("📄 [Paper] 创建模拟盘账户: %s 初始资金 %.2f USDT", t.traderID, cfg.InitialBalance)
+	}
+
+	return t, nil
+}
+
+// marketPrice 默认价格来源：使用market.Get的最新价格
+func marketPrice(symbol string) (float64, error) {
+	data, err := market.Get(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return data.CurrentPrice, nil
+}
+
+func (s *paperAccountState) ensureMaps() {
+	if s.Positions == nil {
+		s.Positions = make(map[string]*paperPosition)
+	}
+	if s.Leverage == nil {
+		s.Leverage = make(map[string]int)
+	}
+	if s.CrossMargin == nil {
+		s.CrossMargin = make(map[string]bool)
+	}
+	if s.NextOrderID <= 0 {
+		s.NextOrderID = 1
+	}
+}
+
+func paperPositionKey(symbol, side string) string {
+	return symbol + "_" + side
+}
+
+// persistLocked 保存账户状态（调用方需持有锁）
+func (t *PaperTrader) persistLocked() {
+	if t.store == nil || t.traderID == "" {
+		return
+	}
+	data, err := json.Marshal(t.state)
+	if err != nil {
+		log.Printf("⚠️ [Paper] 序列化模拟盘账户失败: %v", err)
+		return
+	}
+	if err := t.store.SavePaperAccountState(t.traderID, string(data)); err != nil {
+		log.Printf("⚠️ [Paper] 保存模拟盘账户失败: %v", err)
+	}
+}
+
+// nextOrderIDLocked 分配订单ID（调用方需持有锁）
+func (t *PaperTrader) nextOrderIDLocked() int64 {
+	id := t.state.NextOrderID
+	t.state.NextOrderID++
+	return id
+}
+
+// recordFillLocked 记录成交（调用方需持有锁）
+func (t *PaperTrader) recordFillLocked(fill PaperFill) {
+	t.state.Fills = append(t.state.Fills, fill)
+	if len(t.state.Fills) > maxPaperFillHistory {
+		t.state.Fills = t.state.Fills[len(t.state.Fills)-maxPaperFillHistory:]
+	}
+	if t.fillHandler != nil {
+		t.fillHandler(fill)
+	}
+}
+
+// fetchPrice 获取价格并驱动条件单/强平检查
+func (t *PaperTrader) fetchPrice(symbol string) (float64, error) {
+	price, err := t.priceSource(symbol)
+	if err != nil {
+		return 0, fmt.Errorf("获取 %s 价格失败: %w", symbol, err)
+	}
+	if price <= 0 {
+		return 0, fmt.Errorf("%s 价格无效: %.8f", symbol, price)
+	}
+	t.UpdateMarkPrice(symbol, price)
+	return price, nil
+}
+
+// refreshMarks 刷新所有持仓和挂单币种的标记价格
+func (t *PaperTrader) refreshMarks() {
+	t.mu.Lock()
+	symbols := make(map[string]bool)
+	for _, pos := range t.state.Positions {
+		symbols[pos.Symbol] = true
+	}
+	for _, order := range t.state.Orders {
+		symbols[order.Symbol] = true
+	}
+	t.mu.Unlock()
+
+	for symbol := range symbols {
+		if _, err := t.fetchPrice(symbol); err != nil {
+			log.Printf("⚠️ [Paper] %v", err)
+		}
+	}
+}
+
+// UpdateMarkPrice 推送最新标记价格，检查强平和止盈止损触发
+// 返回本次触发的成交记录
+func (t *PaperTrader) UpdateMarkPrice(symbol string, price float64) []PaperFill {
+	if price <= 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, pos := range t.state.Positions {
+		if pos.Symbol == symbol {
+			pos.MarkPrice = price
+		}
+	}
+
+	var fills []PaperFill
+
+	// 1. 强平检查（优先于止损）
+	for _, side := range []string{"long", "short"} {
+		pos, ok := t.state.Positions[paperPositionKey(symbol, side)]
+		if !ok {
+			continue
+		}
+		liqPrice := t.liquidationPriceLocked(pos)
+		if liqPrice <= 0 {
+			continue
+		}
+		if (side == "long" && price <= liqPrice) || (side == "short" && price >= liqPrice) {
+			log.Printf("💥 [Paper] %s %s 触发强平: 标记价 %.4f, 强平价 %.4f", symbol, side, price, liqPrice)
+			fills = append(fills, t.closePositionLocked(pos, pos.Quantity, liqPrice, PaperFillReasonLiquidation))
+		}
+	}
+
+	// 2. 止损/止盈检查（条件单触发后以触发价加滑点成交）
+	for _, order := range append([]*paperOrder(nil), t.state.Orders...) {
+		if order.Symbol != symbol || order.Type == paperOrderTypeLimit {
+			continue
+		}
+		side := strings.ToLower(order.PositionSide)
+		pos, ok := t.state.Positions[paperPositionKey(symbol, side)]
+		if !ok {
+			continue
+		}
+
+		triggered := false
+		reason := PaperFillReasonStopLoss
+		switch order.Type {
+		case paperOrderTypeStop:
+			triggered = (side == "long" && price <= order.TriggerPrice) || (side == "short" && price >= order.TriggerPrice)
+		case paperOrderTypeTakeProfit:
+			reason = PaperFillReasonTakeProfit
+			triggered = (side == "long" && price >= order.TriggerPrice) || (side == "short" && price <= order.TriggerPrice)
+		}
+		if !triggered {
+			continue
+		}
+
+		log.Printf("🎯 [Paper] %s %s 触发%s: 标记价 %.4f, 触发价 %.4f", symbol, side, order.Type, price, order.TriggerPrice)
+		fill := t.closePositionLocked(pos, pos.Quantity, t.applySlippage(order.TriggerPrice, side, false), reason)
+		fill.OrderID = order.ID
+		fills = append(fills, fill)
+	}
+
+	// 3. 限价开仓单检查（到价后以限价成交）
+	for _, order := range append([]*paperOrder(nil), t.state.Orders...) {
+		if order.Symbol != symbol || order.Type != paperOrderTypeLimit {
+			continue
+		}
+		side := strings.ToLower(order.PositionSide)
+		if !limitReached(side, price, order.TriggerPrice) {
+			continue
+		}
+
+		t.removeOrderLocked(order.ID)
+		fill, err := t.openPositionLocked(order.ID, symbol, side, order.Quantity, order.Leverage, order.TriggerPrice, price, PaperFillReasonLimit)
+		if err != nil {
+			log.Printf("⚠️ [Paper] %s 限价单 #%d 无法成交，已取消: %v", symbol, order.ID, err)
+			t.persistLocked()
+			continue
+		}
+		log.Printf("🎯 [Paper] %s %s 限价单 #%d 成交: 标记价 %.4f, 限价 %.4f", symbol, side, order.ID, price, order.TriggerPrice)
+		fills = append(fills, fill)
+	}
+
+	// 标记价格只在内存中更新，有成交时才持久化
+	if len(fills) > 0 {
+		t.persistLocked()
+	}
+	return fills
+}
+
+// limitReached 判断限价开仓单在当前价格下是否可以成交（买入价格≤限价，卖出价格≥限价）
+func limitReached(side string, price, limitPrice float64) bool {
+	if side == "long" {
+		return price <= limitPrice
+	}
+	return price >= limitPrice
+}
+
+// applySlippage 计算含滑点的成交价（开多/平空向上滑，开空/平多向下滑）
+func (t *PaperTrader) applySlippage(price float64, side string, isOpen bool) float64 {
+	buy := (side == "long") == isOpen
+	if buy {
+		return price * (1 + t.slippageRate)
+	}
+	return price * (1 - t.slippageRate)
+}
+
+// unrealizedPnL 计算持仓未实现盈亏
+func (p *paperPosition) unrealizedPnL() float64 {
+	mark := p.MarkPrice
+	if mark <= 0 {
+		mark = p.EntryPrice
+	}
+	if p.Side == "long" {
+		return p.Quantity * (mark - p.EntryPrice)
+	}
+	return p.Quantity * (p.EntryPrice - mark)
+}
+
+// margin 计算持仓占用保证金（按开仓均价）
+func (p *paperPosition) margin() float64 {
+	leverage := p.Leverage
+	if leverage <= 0 {
+		leverage = 1
+	}
+	return p.Quantity * p.EntryPrice / float64(leverage)
+}
+
+// liquidationPriceLocked 计算强平价（调用方需持有锁）
+// 逐仓：抵押品 = 仓位保证金；全仓：抵押品 = 钱包余额 + 其他仓位未实现盈亏 - 其他仓位维持保证金
+// 多仓：C + Q×(P-E) = mmr×Q×P  =>  P = (Q×E - C) / (Q×(1-mmr))
+// 空仓：C + Q×(E-P) = mmr×Q×P  =>  P = (C + Q×E) / (Q×(1+mmr))
+func (t *PaperTrader) liquidationPriceLocked(pos *paperPosition) float64 {
+	if pos.Quantity <= 0 {
+		return 0
+	}
+
+	collateral := pos.margin()
+	if pos.IsCross {
+		collateral = t.state.WalletBalance
+		for _, other := range t.state.Positions {
+			if other == pos || !other.IsCross {
+				continue
+			}
+			mark := other.MarkPrice
+			if mark <= 0 {
+				mark = other.EntryPrice
+			}
+			collateral += other.unrealizedPnL() - t.maintenanceMarginRate*other.Quantity*mark
+		}
+		// 逐仓仓位的保证金不能用于全仓
+		for _, other := range t.state.Positions {
+			if !other.IsCross {
+				collateral -= other.margin()
+			}
+		}
+	}
+
+	mmr := t.maintenanceMarginRate
+	var liq float64
+	if pos.Side == "long" {
+		liq = (pos.Quantity*pos.EntryPrice - collateral) / (pos.Quantity * (1 - mmr))
+	} else {
+		liq = (collateral + pos.Quantity*pos.EntryPrice) / (pos.Quantity * (1 + mmr))
+	}
+	if liq < 0 {
+		return 0
+	}
+	return liq
+}
+
+// closePositionLocked 以指定价格平仓（调用方需持有锁）
+func (t *PaperTrader) closePositionLocked(pos *paperPosition, quantity, fillPrice float64, reason string) PaperFill {
+	if quantity > pos.Quantity {
+		quantity = pos.Quantity
+	}
+
+	var pnl float64
+	if pos.Side == "long" {
+		pnl = quantity * (fillPrice - pos.EntryPrice)
+	} else {
+		pnl = quantity * (pos.EntryPrice - fillPrice)
+	}
+	fee := quantity * fillPrice * t.feeRate
+
+	t.state.WalletBalance += pnl - fee
+	t.state.RealizedPnL += pnl
+	t.state.TotalFees += fee
+
+	pos.Quantity -= quantity
+	if pos.Quantity <= 1e-12 {
+		delete(t.state.Positions, paperPositionKey(pos.Symbol, pos.Side))
+		t.removeOrdersLocked(pos.Symbol, strings.ToUpper(pos.Side), false)
+	}
+
+	fill := PaperFill{
+		OrderID:     t.nextOrderIDLocked(),
+		Symbol:      pos.Symbol,
+		Action:      "close_" + pos.Side,
+		Price:       fillPrice,
+		Quantity:    quantity,
+		Leverage:    pos.Leverage,
+		EntryPrice:  pos.EntryPrice,
+		Fee:         fee,
+		RealizedPnL: pnl,
+		Reason:      reason,
+		Time:        t.clock(),
+	}
+	t.recordFillLocked(fill)
+
+	log.Printf("📄 [Paper] 平%s仓 %s: 数量 %.6f @ %.4f, 盈亏 %.4f, 手续费 %.4f (%s)",
+		sideCN(pos.Side), pos.Symbol, quantity, fillPrice, pnl, fee, reason)
+	return fill
+}
+
+// removeOrdersLocked 删除某币种某方向的委托单，positionSide为空表示全部方向，
+// includeLimit为false时保留限价开仓单（仓位平掉后不影响等待入场的挂单）（调用方需持有锁）
+func (t *PaperTrader) removeOrdersLocked(symbol, positionSide string, includeLimit bool) int {
+	kept := t.state.Orders[:0]
+	removed := 0
+	for _, order := range t.state.Orders {
+		if !includeLimit && order.Type == paperOrderTypeLimit {
+			kept = append(kept, order)
+			continue
+		}
+		if order.Symbol == symbol && (positionSide == "" || order.PositionSide == positionSide) {
+			removed++
+			continue
+		}
+		kept = append(kept, order)
+	}
+	t.state.Orders = kept
+	return removed
+}
+
+// removeOrderLocked 按ID删除委托单，返回被删除的订单（调用方需持有锁）
+func (t *PaperTrader) removeOrderLocked(id int64) *paperOrder {
+	for i, order := range t.state.Orders {
+		if order.ID == id {
+			t.state.Orders = append(t.state.Orders[:i], t.state.Orders[i+1:]...)
+			return order
+		}
+	}
+	return nil
+}
+
+// accountSummaryLocked 计算账户汇总（调用方需持有锁）
+func (t *PaperTrader) accountSummaryLocked() (equity, used, unrealized float64) {
+	for _, pos := range t.state.Positions {
+		unrealized += pos.unrealizedPnL()
+		used += pos.margin()
+	}
+	equity = t.state.WalletBalance + unrealized
+	return equity, used, unrealized
+}
+
+func sideCN(side string) string {
+	if side == "long" {
+		return "多"
+	}
+	return "空"
+}
+
+// GetBalance 获取账户余额
+func (t *PaperTrader) GetBalance() (*Balance, error) {
+	t.refreshMarks()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	equity, used, unrealized := t.accountSummaryLocked()
+	free := equity - used
+	if free < 0 {
+		free = 0
+	}
+
+	return &Balance{
+		WalletBalance:    t.state.WalletBalance,
+		UnrealizedProfit: unrealized,
+		AvailableBalance: free,
+	}, nil
+}
+
+// RealizedPnL 累计已实现盈亏（不含手续费）
+func (t *PaperTrader) RealizedPnL() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state.RealizedPnL
+}
+
+// TotalFees 累计支付的手续费
+func (t *PaperTrader) TotalFees() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state.TotalFees
+}
+
+// GetPositions 获取所有持仓
+func (t *PaperTrader) GetPositions() ([]Position, error) {
+	t.refreshMarks()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make([]Position, 0, len(t.state.Positions))
+	for _, pos := range t.state.Positions {
+		mark := pos.MarkPrice
+		if mark <= 0 {
+			mark = pos.EntryPrice
+		}
+		marginMode := "cross"
+		if !pos.IsCross {
+			marginMode = "isolated"
+		}
+		result = append(result, Position{
+			Symbol:           pos.Symbol,
+			Side:             pos.Side,
+			Quantity:         pos.Quantity,
+			EntryPrice:       pos.EntryPrice,
+			MarkPrice:        mark,
+			UnrealizedProfit: pos.unrealizedPnL(),
+			Leverage:         pos.Leverage,
+			LiquidationPrice: t.liquidationPriceLocked(pos),
+			MarginMode:       marginMode,
+		})
+	}
+	return result, nil
+}
+
+// OpenLong 开多仓
+func (t *PaperTrader) OpenLong(symbol string, quantity float64, leverage int) (*OrderResult, error) {
+	return t.open(symbol, "long", quantity, leverage)
+}
+
+// OpenShort 开空仓
+func (t *PaperTrader) OpenShort(symbol string, quantity float64, leverage int) (*OrderResult, error) {
+	return t.open(symbol, "short", quantity, leverage)
+}
+
+// open 按市价（含滑点）模拟开仓
+func (t *PaperTrader) open(symbol, side string, quantity float64, leverage int) (*OrderResult, error) {
+	if quantity <= 0 {
+		return nil, fmt.Errorf("开仓数量必须大于0")
+	}
+
+	// 与币安行为一致：开仓前取消该币种的旧委托单
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+
+	price, err := t.fetchPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	leverage = t.resolveLeverageLocked(symbol, leverage)
+	fill, err := t.openPositionLocked(0, symbol, side, quantity, leverage, t.applySlippage(price, side, true), price, PaperFillReasonManual)
+	if err != nil {
+		return nil, err
+	}
+	t.persistLocked()
+
+	return paperFillOrderResult(fill, "MARKET"), nil
+}
+
+// resolveLeverageLocked 确定开仓杠杆（未指定时使用该币种已设置的杠杆）并记录（调用方需持有锁）
+func (t *PaperTrader) resolveLeverageLocked(symbol string, leverage int) int {
+	if leverage <= 0 {
+		leverage = t.state.Leverage[symbol]
+	}
+	if leverage <= 0 {
+		leverage = 1
+	}
+	t.state.Leverage[symbol] = leverage
+	return leverage
+}
+
+// openPositionLocked 以指定价格开仓或加仓，orderID为0时分配新ID（调用方需持有锁并负责持久化）
+func (t *PaperTrader) openPositionLocked(orderID int64, symbol, side string, quantity float64, leverage int, fillPrice, markPrice float64, reason string) (PaperFill, error) {
+	isCross, ok := t.state.CrossMargin[symbol]
+	if !ok {
+		isCross = true
+	}
+
+	notional := quantity * fillPrice
+	fee := notional * t.feeRate
+	requiredMargin := notional / float64(leverage)
+
+	equity, used, _ := t.accountSummaryLocked()
+	available := equity - used
+	if requiredMargin+fee > available {
+		return PaperFill{}, fmt.Errorf("模拟盘可用保证金不足: 需要 %.2f USDT, 可用 %.2f USDT", requiredMargin+fee, available)
+	}
+
+	key := paperPositionKey(symbol, side)
+	pos, exists := t.state.Positions[key]
+	if exists {
+		// 加仓：按数量加权计算新的开仓均价
+		totalQty := pos.Quantity + quantity
+		pos.EntryPrice = (pos.EntryPrice*pos.Quantity + fillPrice*quantity) / totalQty
+		pos.Quantity = totalQty
+		pos.Leverage = leverage
+		pos.IsCross = isCross
+	} else {
+		pos = &paperPosition{
+			Symbol:     symbol,
+			Side:       side,
+			Quantity:   quantity,
+			EntryPrice: fillPrice,
+			Leverage:   leverage,
+			IsCross:    isCross,
+			OpenTime:   t.clock(),
+		}
+		t.state.Positions[key] = pos
+	}
+	pos.MarkPrice = markPrice
+
+	t.state.WalletBalance -= fee
+	t.state.TotalFees += fee
+
+	if orderID == 0 {
+		orderID = t.nextOrderIDLocked()
+	}
+	fill := PaperFill{
+		OrderID:  orderID,
+		Symbol:   symbol,
+		Action:   "open_" + side,
+		Price:    fillPrice,
+		Quantity: quantity,
+		Leverage: leverage,
+		Fee:      fee,
+		Reason:   reason,
+		Time:     t.clock(),
+	}
+	t.recordFillLocked(fill)
+
+	log.Printf("✓ [Paper] 开%s仓成功: %s 数量: %.6f @ %.4f (%dx), 手续费 %.4f", sideCN(side), symbol, quantity, fillPrice, leverage, fee)
+	return fill, nil
+}
+
+// CloseLong 平多仓（quantity=0表示全部平仓）
+func (t *PaperTrader) CloseLong(symbol string, quantity float64) (*OrderResult, error) {
+	return t.close(symbol, "long", quantity)
+}
+
+// CloseShort 平空仓（quantity=0表示全部平仓）
+func (t *PaperTrader) CloseShort(symbol string, quantity float64) (*OrderResult, error) {
+	return t.close(symbol, "short", quantity)
+}
+
+// close 按市价（含滑点）模拟平仓
+func (t *PaperTrader) close(symbol, side string, quantity float64) (*OrderResult, error) {
+	price, err := t.fetchPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pos, ok := t.state.Positions[paperPositionKey(symbol, side)]
+	if !ok {
+		return nil, fmt.Errorf("没有找到 %s 的%s仓", symbol, sideCN(side))
+	}
+	if quantity <= 0 || quantity > pos.Quantity {
+		quantity = pos.Quantity
+	}
+
+	fill := t.closePositionLocked(pos, quantity, t.applySlippage(price, side, false), PaperFillReasonManual)
+	t.persistLocked()
+
+	return paperFillOrderResult(fill, "MARKET"), nil
+}
+
+// SetLeverage 设置杠杆
+func (t *PaperTrader) SetLeverage(symbol string, leverage int) error {
+	if leverage <= 0 {
+		return fmt.Errorf("杠杆必须大于0")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.Leverage[symbol] = leverage
+	t.persistLocked()
+	return nil
+}
+
+// SetMarginMode 设置仓位模式 (true=全仓, false=逐仓)
+func (t *PaperTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// 与交易所一致：有持仓时不允许切换仓位模式
+	for _, pos := range t.state.Positions {
+		if pos.Symbol == symbol && pos.IsCross != isCrossMargin {
+			log.Printf("  ⚠️ [Paper] %s 有持仓，无法更改仓位模式，继续使用当前模式", symbol)
+			return nil
+		}
+	}
+	t.state.CrossMargin[symbol] = isCrossMargin
+	t.persistLocked()
+	return nil
+}
+
+// GetMarketPrice 获取市场价格
+func (t *PaperTrader) GetMarketPrice(symbol string) (float64, error) {
+	return t.fetchPrice(symbol)
+}
+
+// SetStopLoss 设置止损单
+func (t *PaperTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	if err := t.addOrder(symbol, positionSide, paperOrderTypeStop, quantity, stopPrice); err != nil {
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
+	log.Printf("  止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// SetTakeProfit 设置止盈单
+func (t *PaperTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	if err := t.addOrder(symbol, positionSide, paperOrderTypeTakeProfit, quantity, takeProfitPrice); err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
+	}
+	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
+
+// trailingStops 本地跟踪止损模拟器（由AutoTrader接管触发平仓和持久化）
+func (t *PaperTrader) trailingStops() *trailingStopEmulator {
+	return t.trailing
+}
+
+// SetTrailingStop 设置跟踪止损（本地根据实时行情模拟，触发后市价平仓）
+func (t *PaperTrader) SetTrailingStop(symbol string, positionSide string, quantity, callbackRate, activationPrice float64) error {
+	if err := t.trailing.Set(symbol, positionSide, quantity, callbackRate, activationPrice); err != nil {
+		return fmt.Errorf("设置跟踪止损失败: %w", err)
+	}
+	return nil
+}
+
+// addOrder 添加条件单
+func (t *PaperTrader) addOrder(symbol, positionSide, orderType string, quantity, triggerPrice float64) error {
+	positionSide = strings.ToUpper(positionSide)
+	if positionSide != "LONG" && positionSide != "SHORT" {
+		return fmt.Errorf("无效的持仓方向: %s", positionSide)
+	}
+	if triggerPrice <= 0 {
+		return fmt.Errorf("触发价格必须大于0")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.state.Orders = append(t.state.Orders, &paperOrder{
+		ID:           t.nextOrderIDLocked(),
+		Symbol:       symbol,
+		PositionSide: positionSide,
+		Type:         orderType,
+		Quantity:     quantity,
+		TriggerPrice: triggerPrice,
+		CreatedAt:    t.clock(),
+	})
+	t.persistLocked()
+	return nil
+}
+
+// CancelAllOrders 取消该币种的所有挂单
+func (t *PaperTrader) CancelAllOrders(symbol string) error {
+	t.trailing.Cancel(symbol)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if removed := t.removeOrdersLocked(symbol, "", true); removed > 0 {
+		log.Printf("  ✓ [Paper] 已取消 %s 的 %d 个挂单", symbol, removed)
+		t.persistLocked()
+	}
+	return nil
+}
+
+// PlaceLimitOrder 下限价开仓单
+// 可立即成交时按市价（含滑点，不劣于限价）成交；否则GTC/post-only挂单等待价格到达，IOC/FOK直接过期
+func (t *PaperTrader) PlaceLimitOrder(symbol string, positionSide string, quantity, price float64, leverage int, tif TimeInForce) (*OrderResult, error) {
+	positionSide = strings.ToUpper(positionSide)
+	if positionSide != "LONG" && positionSide != "SHORT" {
+		return nil, fmt.Errorf("无效的持仓方向: %s", positionSide)
+	}
+	if quantity <= 0 || price <= 0 {
+		return nil, fmt.Errorf("限价单数量和价格必须大于0")
+	}
+	if tif == "" {
+		tif = TimeInForceGTC
+	}
+	if _, err := ParseTimeInForce(string(tif)); err != nil {
+		return nil, err
+	}
+
+	markPrice, err := t.fetchPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	side := strings.ToLower(positionSide)
+	leverage = t.resolveLeverageLocked(symbol, leverage)
+
+	if limitReached(side, markPrice, price) {
+		if tif == TimeInForcePostOnly {
+			return nil, fmt.Errorf("post-only限价单会立即成交，已拒绝: %s 限价 %.4f, 当前价 %.4f", symbol, price, markPrice)
+		}
+		fillPrice := t.applySlippage(markPrice, side, true)
+		if side == "long" {
+			fillPrice = math.Min(fillPrice, price)
+		} else {
+			fillPrice = math.Max(fillPrice, price)
+		}
+		fill, err := t.openPositionLocked(0, symbol, side, quantity, leverage, fillPrice, markPrice, PaperFillReasonLimit)
+		if err != nil {
+			return nil, err
+		}
+		t.persistLocked()
+		return paperFillOrderResult(fill, paperOrderTypeLimit), nil
+	}
+
+	orderID := t.nextOrderIDLocked()
+	if tif == TimeInForceIOC || tif == TimeInForceFOK {
+		log.Printf("  [Paper] %s %s限价单未能立即成交，已过期: 限价 %.4f, 当前价 %.4f", symbol, tif, price, markPrice)
+		return &OrderResult{
+			OrderID:      strconv.FormatInt(orderID, 10),
+			Symbol:       symbol,
+			Status:       OrderStatusExpired,
+			Type:         paperOrderTypeLimit,
+			Side:         paperOrderSide(positionSide),
+			PositionSide: positionSide,
+			Price:        price,
+			Quantity:     quantity,
+		}, nil
+	}
+
+	// 挂单时按限价检查保证金（已挂限价单占用的保证金一并计算）
+	required := quantity * price * (1/float64(leverage) + t.feeRate)
+	equity, used, _ := t.accountSummaryLocked()
+	available := equity - used - t.pendingOrderMarginLocked()
+	if required > available {
+		return nil, fmt.Errorf("模拟盘可用保证金不足: 需要 %.2f USDT, 可用 %.2f USDT", required, available)
+	}
+
+	order := &paperOrder{
+		ID:           orderID,
+		Symbol:       symbol,
+		PositionSide: positionSide,
+		Type:         paperOrderTypeLimit,
+		Quantity:     quantity,
+		TriggerPrice: price,
+		Leverage:     leverage,
+		TimeInForce:  tif,
+		CreatedAt:    t.clock(),
+	}
+	t.state.Orders = append(t.state.Orders, order)
+	t.persistLocked()
+
+	log.Printf("📄 [Paper] 限价单已挂出 #%d: %s %s 数量 %.6f @ %.4f (%s)", orderID, symbol, positionSide, quantity, price, tif)
+	return paperOrderResult(order), nil
+}
+
+// pendingOrderMarginLocked 计算挂单中的限价单预占的保证金和手续费（调用方需持有锁）
+func (t *PaperTrader) pendingOrderMarginLocked() float64 {
+	var reserved float64
+	for _, order := range t.state.Orders {
+		if order.Type != paperOrderTypeLimit {
+			continue
+		}
+		leverage := order.Leverage
+		if leverage <= 0 {
+			leverage = 1
+		}
+		reserved += order.Quantity * order.TriggerPrice * (1/float64(leverage) + t.feeRate)
+	}
+	return reserved
+}
+
+// AmendOrder 修改未成交限价单的价格和数量（改价后可立即成交时直接成交，post-only则拒绝修改）
+func (t *PaperTrader) AmendOrder(symbol string, orderID string, quantity, price float64) (*OrderResult, error) {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+
+	markPrice, err := t.fetchPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var order *paperOrder
+	for _, o := range t.state.Orders {
+		if o.ID == id && o.Symbol == symbol && o.Type == paperOrderTypeLimit {
+			order = o
+			break
+		}
+	}
+	if order == nil {
+		return nil, fmt.Errorf("未找到挂单 %s（可能已成交或已取消）", orderID)
+	}
+
+	if price <= 0 {
+		price = order.TriggerPrice
+	}
+	if quantity <= 0 {
+		quantity = order.Quantity
+	}
+
+	side := strings.ToLower(order.PositionSide)
+	if limitReached(side, markPrice, price) {
+		if order.TimeInForce == TimeInForcePostOnly {
+			return nil, fmt.Errorf("post-only限价单改价后会立即成交，已拒绝: 限价 %.4f, 当前价 %.4f", price, markPrice)
+		}
+		t.removeOrderLocked(order.ID)
+		fill, err := t.openPositionLocked(order.ID, symbol, side, quantity, order.Leverage, price, markPrice, PaperFillReasonLimit)
+		t.persistLocked()
+		if err != nil {
+			return nil, fmt.Errorf("改价后成交失败，订单已取消: %w", err)
+		}
+		return paperFillOrderResult(fill, paperOrderTypeLimit), nil
+	}
+
+	order.TriggerPrice = price
+	order.Quantity = quantity
+	t.persistLocked()
+
+	log.Printf("  ✓ [Paper] 已修改限价单 #%d: 数量 %.6f @ %.4f", order.ID, quantity, price)
+	return paperOrderResult(order), nil
+}
+
+// CancelOrder 取消单个订单
+func (t *PaperTrader) CancelOrder(symbol string, orderID string) error {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, order := range t.state.Orders {
+		if order.ID == id && order.Symbol == symbol {
+			t.removeOrderLocked(id)
+			t.persistLocked()
+			log.Printf("  ✓ [Paper] 已取消 %s 订单 #%d", symbol, id)
+			return nil
+		}
+	}
+	return fmt.Errorf("未找到订单 %s（可能已成交或已取消）", orderID)
+}
+
+// paperOrderSide 开仓方向对应的买卖方向
+func paperOrderSide(positionSide string) string {
+	if positionSide == "SHORT" {
+		return "SELL"
+	}
+	return "BUY"
+}
+
+// paperOrderResult 将挂单中的模拟委托单转换为统一格式
+func paperOrderResult(order *paperOrder) *OrderResult {
+	side := paperOrderSide(order.PositionSide)
+	price, stopPrice := 0.0, order.TriggerPrice
+	if order.Type == paperOrderTypeLimit {
+		price, stopPrice = order.TriggerPrice, 0
+	} else if side == "BUY" {
+		// 止损/止盈单是平仓方向
+		side = "SELL"
+	} else {
+		side = "BUY"
+	}
+
+	return &OrderResult{
+		OrderID:      strconv.FormatInt(order.ID, 10),
+		Symbol:       order.Symbol,
+		Status:       OrderStatusNew,
+		Type:         order.Type,
+		Side:         side,
+		PositionSide: order.PositionSide,
+		Price:        price,
+		StopPrice:    stopPrice,
+		Quantity:     order.Quantity,
+		Time:         order.CreatedAt.UnixMilli(),
+		UpdateTime:   order.CreatedAt.UnixMilli(),
+	}
+}
+
+// paperFillOrderResult 将模拟成交转换为已成交订单（模拟盘每个订单一次性全部成交）
+func paperFillOrderResult(fill PaperFill, orderType string) *OrderResult {
+	side, positionSide := paperFillSides(fill.Action)
+	price := 0.0
+	if orderType == paperOrderTypeLimit {
+		price = fill.Price
+	}
+	return &OrderResult{
+		OrderID:      strconv.FormatInt(fill.OrderID, 10),
+		Symbol:       fill.Symbol,
+		Status:       OrderStatusFilled,
+		Type:         orderType,
+		Side:         strings.ToUpper(side),
+		PositionSide: positionSide,
+		Price:        price,
+		Quantity:     fill.Quantity,
+		ExecutedQty:  fill.Quantity,
+		AvgPrice:     fill.Price,
+		Fee:          fill.Fee,
+		Time:         fill.Time.UnixMilli(),
+		UpdateTime:   fill.Time.UnixMilli(),
+	}
+}
+
+// paperFillSides 根据成交动作确定买卖方向和持仓方向
+func paperFillSides(action string) (side, positionSide string) {
+	switch action {
+	case "open_long":
+		return "buy", "LONG"
+	case "close_long":
+		return "sell", "LONG"
+	case "open_short":
+		return "sell", "SHORT"
+	default:
+		return "buy", "SHORT"
+	}
+}
+
+// GetOrder 查询单个订单（挂单中的委托单或已成交订单，已撤销的订单不保留）
+func (t *PaperTrader) GetOrder(symbol string, orderID string) (*OrderResult, error) {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, order := range t.state.Orders {
+		if order.ID == id && order.Symbol == symbol {
+			return paperOrderResult(order), nil
+		}
+	}
+
+	for _, fill := range t.state.Fills {
+		if fill.OrderID != id || fill.Symbol != symbol {
+			continue
+		}
+		orderType := "MARKET"
+		if fill.Reason == PaperFillReasonLimit {
+			orderType = paperOrderTypeLimit
+		}
+		return paperFillOrderResult(fill, orderType), nil
+	}
+	return nil, fmt.Errorf("未找到订单 %s", orderID)
+}
+
+// GetOpenOrders 获取未成交挂单（symbol为空表示所有币种）
+func (t *PaperTrader) GetOpenOrders(symbol string) ([]OrderResult, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	orders := make([]OrderResult, 0, len(t.state.Orders))
+	for _, order := range t.state.Orders {
+		if symbol == "" || order.Symbol == symbol {
+			orders = append(orders, *paperOrderResult(order))
+		}
+	}
+	return orders, nil
+}
+
+// GetProtectiveOrders 获取止盈止损条件单（与币安相同的订单类型）
+func (t *PaperTrader) GetProtectiveOrders(symbol string) ([]ProtectiveOrder, error) {
+	orders, err := t.GetOpenOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return filterProtectiveOrders(orders, binanceProtectiveKind), nil
+}
+
+// CancelProtectiveOrder 取消单个止盈止损条件单
+func (t *PaperTrader) CancelProtectiveOrder(symbol string, orderID string) error {
+	return t.CancelOrder(symbol, orderID)
+}
+
+// GetFundingPayments 模拟盘不模拟资金费结算，始终返回空列表
+func (t *PaperTrader) GetFundingPayments(symbol string, since time.Time) ([]FundingPayment, error) {
+	return []FundingPayment{}, nil
+}
+
+// GetFills 获取自since以来的成交记录（symbol为空表示所有币种）
+func (t *PaperTrader) GetFills(symbol string, since time.Time) ([]Fill, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fills := []Fill{}
+	for _, fill := range t.state.Fills {
+		if fill.Time.Before(since) || (symbol != "" && fill.Symbol != symbol) {
+			continue
+		}
+		side, positionSide := paperFillSides(fill.Action)
+		role := "taker"
+		if fill.Reason == PaperFillReasonLimit {
+			role = "maker"
+		}
+		fills = append(fills, Fill{
+			FillID:       strconv.FormatInt(fill.OrderID, 10), // 模拟盘每个订单一次性全部成交
+			OrderID:      strconv.FormatInt(fill.OrderID, 10),
+			Symbol:       fill.Symbol,
+			Side:         side,
+			PositionSide: positionSide,
+			Price:        fill.Price,
+			Quantity:     fill.Quantity,
+			Fee:          fill.Fee,
+			FeeCurrency:  "USDT",
+			Role:         role,
+			Timestamp:    fill.Time.UnixMilli(),
+		})
+	}
+	return fills, nil
+}
+
+// FormatQuantity 格式化数量到正确的精度（模拟盘不限制精度，保留6位小数）
+func (t *PaperTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	return strconv.FormatFloat(math.Floor(quantity*1e6)/1e6, 'f', -1, 64), nil
+}
+
+// GetFillHistory 获取模拟成交记录（按时间顺序）
+func (t *PaperTrader) GetFillHistory() []PaperFill {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]PaperFill(nil), t.state.Fills...)
+}
+
+// StartMonitor 启动标记价格监控，定期检查止盈止损和强平
+func (t *PaperTrader) StartMonitor(interval time.Duration) {
+	t.monitorMu.Lock()
+	defer t.monitorMu.Unlock()
+	if t.monitorStop != nil {
+		return
+	}
+
+	stop := make(chan struct{})
+	t.monitorStop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.refreshMarks()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	log.Printf("👁 [Paper] 模拟盘价格监控已启动 (间隔 %v)", interval)
+}
+
+// StopMonitor 停止标记价格监控
+func (t *PaperTrader) StopMonitor() {
+	t.monitorMu.Lock()
+	defer t.monitorMu.Unlock()
+	if t.monitorStop != nil {
+		close(t.monitorStop)
+		t.monitorStop = nil
+	}
+}
@@ -0,0 +1,426 @@
+package trader
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePriceFeed 可控的价格源
+type fakePriceFeed struct {
+	mu     sync.Mutex
+	prices map[string]float64
+}
+
+func newFakePriceFeed(prices map[string]float64) *fakePriceFeed {
+	return &fakePriceFeed{prices: prices}
+}
+
+func (f *fakePriceFeed) set(symbol string, price float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prices[symbol] = price
+}
+
+func (f *fakePriceFeed) get(symbol string) (float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.prices[symbol], nil
+}
+
+// memoryPaperStore 内存版持久化存储
+type memoryPaperStore struct {
+	states map[string]string
+}
+
+func (s *memoryPaperStore) GetPaperAccountState(traderID string) (string, error) {
+	return s.states[traderID], nil
+}
+
+func (s *memoryPaperStore) SavePaperAccountState(traderID string, state string) error {
+	s.states[traderID] = state
+	return nil
+}
+
+func newTestPaperTrader(t *testing.T, feed *fakePriceFeed, store PaperAccountStore) *PaperTrader {
+	t.Helper()
+	feeRate, slippageRate := 0.001, 0.001
+	pt, err := NewPaperTrader(PaperTraderConfig{
+		TraderID:       "paper_test",
+		InitialBalance: 1000,
+		FeeRate:        &feeRate,
+		SlippageRate:   &slippageRate,
+		Store:          store,
+		PriceSource:    feed.get,
+	})
+	if err != nil {
+		t.Fatalf("创建模拟盘失败: %v", err)
+	}
+	return pt
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestPaperTraderZeroFeeAndDefaultRates(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+
+	// 显式设置为0：免手续费、无滑点
+	zero := 0.0
+	pt, err := NewPaperTrader(PaperTraderConfig{InitialBalance: 1000, FeeRate: &zero, SlippageRate: &zero, PriceSource: feed.get})
+	if err != nil {
+		t.Fatalf("创建模拟盘失败: %v", err)
+	}
+	order, err := pt.OpenLong("BTCUSDT", 2, 5)
+	if err != nil {
+		t.Fatalf("开多失败: %v", err)
+	}
+	if !almostEqual(order.AvgPrice, 100) || pt.TotalFees() != 0 {
+		t.Errorf("手续费率和滑点为0时应按原价成交且不收手续费: 成交价 %.4f, 手续费 %.6f", order.AvgPrice, pt.TotalFees())
+	}
+
+	// 未设置：使用默认值
+	pt, err = NewPaperTrader(PaperTraderConfig{InitialBalance: 1000, PriceSource: feed.get})
+	if err != nil {
+		t.Fatalf("创建模拟盘失败: %v", err)
+	}
+	if pt.feeRate != defaultPaperFeeRate || pt.slippageRate != defaultPaperSlippageRate {
+		t.Errorf("未设置时应使用默认费率: %v %v", pt.feeRate, pt.slippageRate)
+	}
+
+	negative := -0.001
+	if _, err := NewPaperTrader(PaperTraderConfig{InitialBalance: 1000, FeeRate: &negative, PriceSource: feed.get}); err == nil {
+		t.Error("负的手续费率应报错")
+	}
+}
+
+func TestPaperTraderOpenAndCloseWithFeesAndSlippage(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	pt := newTestPaperTrader(t, feed, nil)
+
+	order, err := pt.OpenLong("BTCUSDT", 2, 5)
+	if err != nil {
+		t.Fatalf("开多失败: %v", err)
+	}
+	if order.OrderID == "" || order.Status != OrderStatusFilled {
+		t.Errorf("市价单应立即成交并返回订单ID: %+v", order)
+	}
+	// 开多向上滑点: 100 * 1.001
+	if !almostEqual(order.AvgPrice, 100.1) {
+		t.Errorf("开仓价错误: %v", order.AvgPrice)
+	}
+
+	feed.set("BTCUSDT", 110)
+	if _, err := pt.CloseLong("BTCUSDT", 0); err != nil {
+		t.Fatalf("平多失败: %v", err)
+	}
+
+	// 平多向下滑点: 110 * 0.999 = 109.89; 盈亏 = 2 * (109.89 - 100.1) = 19.58
+	openFee := 2 * 100.1 * 0.001
+	closeFee := 2 * 109.89 * 0.001
+	expected := 1000 + 19.58 - openFee - closeFee
+
+	balance, err := pt.GetBalance()
+	if err != nil {
+		t.Fatalf("获取余额失败: %v", err)
+	}
+	if !almostEqual(balance.TotalEquity(), expected) {
+		t.Errorf("期望权益 %.6f, got %.6f", expected, balance.TotalEquity())
+	}
+	if !almostEqual(balance.WalletBalance, expected) {
+		t.Errorf("钱包余额应等于权益（无持仓）, got %.6f", balance.WalletBalance)
+	}
+	if !almostEqual(pt.RealizedPnL(), 19.58) || !almostEqual(pt.TotalFees(), openFee+closeFee) {
+		t.Errorf("已实现盈亏或手续费错误: %.6f %.6f", pt.RealizedPnL(), pt.TotalFees())
+	}
+
+	positions, _ := pt.GetPositions()
+	if len(positions) != 0 {
+		t.Errorf("平仓后不应有持仓, got %d", len(positions))
+	}
+}
+
+func TestPaperTraderRejectsInsufficientMargin(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"ETHUSDT": 1000})
+	pt := newTestPaperTrader(t, feed, nil)
+
+	// 名义价值 20000，5倍杠杆需要 4000 保证金，超过 1000
+	if _, err := pt.OpenShort("ETHUSDT", 20, 5); err == nil {
+		t.Fatal("保证金不足时应拒绝开仓")
+	}
+}
+
+func TestPaperTraderStopLossAndTakeProfitTrigger(t *testing.T) {
+	tests := []struct {
+		name       string
+		side       string
+		stop       float64
+		takeProfit float64
+		movePrice  float64
+		wantReason string
+	}{
+		{"多仓止损", "long", 95, 120, 94, PaperFillReasonStopLoss},
+		{"多仓止盈", "long", 95, 120, 121, PaperFillReasonTakeProfit},
+		{"空仓止损", "short", 105, 80, 106, PaperFillReasonStopLoss},
+		{"空仓止盈", "short", 105, 80, 79, PaperFillReasonTakeProfit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := newFakePriceFeed(map[string]float64{"SOLUSDT": 100})
+			pt := newTestPaperTrader(t, feed, nil)
+
+			var err error
+			if tt.side == "long" {
+				_, err = pt.OpenLong("SOLUSDT", 1, 3)
+			} else {
+				_, err = pt.OpenShort("SOLUSDT", 1, 3)
+			}
+			if err != nil {
+				t.Fatalf("开仓失败: %v", err)
+			}
+			positionSide := "LONG"
+			if tt.side == "short" {
+				positionSide = "SHORT"
+			}
+			if err := pt.SetStopLoss("SOLUSDT", positionSide, 1, tt.stop); err != nil {
+				t.Fatalf("设置止损失败: %v", err)
+			}
+			if err := pt.SetTakeProfit("SOLUSDT", positionSide, 1, tt.takeProfit); err != nil {
+				t.Fatalf("设置止盈失败: %v", err)
+			}
+
+			fills := pt.UpdateMarkPrice("SOLUSDT", tt.movePrice)
+			if len(fills) != 1 {
+				t.Fatalf("期望触发1笔成交, got %d", len(fills))
+			}
+			if fills[0].Reason != tt.wantReason {
+				t.Errorf("期望触发原因 %s, got %s", tt.wantReason, fills[0].Reason)
+			}
+
+			positions, _ := pt.GetPositions()
+			if len(positions) != 0 {
+				t.Errorf("触发后仓位应被平掉, got %d", len(positions))
+			}
+			pt.mu.Lock()
+			remaining := len(pt.state.Orders)
+			pt.mu.Unlock()
+			if remaining != 0 {
+				t.Errorf("平仓后条件单应被清理, got %d", remaining)
+			}
+		})
+	}
+}
+
+func TestPaperTraderLiquidation(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	pt := newTestPaperTrader(t, feed, nil)
+
+	if err := pt.SetMarginMode("BTCUSDT", false); err != nil {
+		t.Fatalf("设置逐仓失败: %v", err)
+	}
+	if _, err := pt.OpenLong("BTCUSDT", 10, 10); err != nil {
+		t.Fatalf("开多失败: %v", err)
+	}
+
+	positions, _ := pt.GetPositions()
+	if len(positions) != 1 {
+		t.Fatalf("期望1个持仓, got %d", len(positions))
+	}
+	liqPrice := positions[0].LiquidationPrice
+	// 10倍逐仓多仓，强平价约为开仓价的90%多一点
+	if liqPrice < 90 || liqPrice > 92 {
+		t.Fatalf("强平价不合理: %.4f", liqPrice)
+	}
+
+	fills := pt.UpdateMarkPrice("BTCUSDT", liqPrice-0.01)
+	if len(fills) != 1 || fills[0].Reason != PaperFillReasonLiquidation {
+		t.Fatalf("期望触发强平, got %+v", fills)
+	}
+
+	balance, _ := pt.GetBalance()
+	// 逐仓强平最多损失该仓位保证金（约100）和手续费
+	if total := balance.TotalEquity(); total < 880 || total > 910 {
+		t.Errorf("强平后权益不合理: %.4f", total)
+	}
+}
+
+func TestPaperTraderPersistence(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	store := &memoryPaperStore{states: make(map[string]string)}
+
+	pt := newTestPaperTrader(t, feed, store)
+	if _, err := pt.OpenShort("BTCUSDT", 1, 2); err != nil {
+		t.Fatalf("开空失败: %v", err)
+	}
+	if err := pt.SetStopLoss("BTCUSDT", "SHORT", 1, 110); err != nil {
+		t.Fatalf("设置止损失败: %v", err)
+	}
+
+	// 重新创建，应从存储中恢复持仓和条件单
+	restored := newTestPaperTrader(t, feed, store)
+	positions, _ := restored.GetPositions()
+	if len(positions) != 1 || positions[0].Side != "short" {
+		t.Fatalf("未正确恢复持仓: %+v", positions)
+	}
+
+	fills := restored.UpdateMarkPrice("BTCUSDT", 111)
+	if len(fills) != 1 || fills[0].Reason != PaperFillReasonStopLoss {
+		t.Fatalf("恢复后的止损单未触发: %+v", fills)
+	}
+}
+
+func TestPaperTraderLimitOrderRestsAndFills(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	pt := newTestPaperTrader(t, feed, nil)
+
+	order, err := pt.PlaceLimitOrder("BTCUSDT", "LONG", 2, 95, 5, TimeInForceGTC)
+	if err != nil {
+		t.Fatalf("下限价单失败: %v", err)
+	}
+	if order.Status != OrderStatusNew {
+		t.Fatalf("未到价的限价单应挂单, got %v", order.Status)
+	}
+	if order.OrderID == "" {
+		t.Errorf("限价单应返回订单ID: %+v", order)
+	}
+
+	// 价格未到限价不成交
+	if fills := pt.UpdateMarkPrice("BTCUSDT", 96); len(fills) != 0 {
+		t.Fatalf("未到价不应成交: %+v", fills)
+	}
+
+	// 到价后以限价成交（无滑点），并且仓位平掉后不应影响其他限价单
+	fills := pt.UpdateMarkPrice("BTCUSDT", 94)
+	if len(fills) != 1 || fills[0].Reason != PaperFillReasonLimit || fills[0].Action != "open_long" {
+		t.Fatalf("期望限价单成交, got %+v", fills)
+	}
+	if !almostEqual(fills[0].Price, 95) || fills[0].Leverage != 5 {
+		t.Errorf("限价单成交价或杠杆错误: %+v", fills[0])
+	}
+
+	positions, _ := pt.GetPositions()
+	if len(positions) != 1 || !almostEqual(positions[0].Quantity, 2) {
+		t.Fatalf("限价单成交后应有持仓: %+v", positions)
+	}
+}
+
+func TestPaperTraderLimitOrderTimeInForce(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	pt := newTestPaperTrader(t, feed, nil)
+
+	// post-only会立即成交时拒绝
+	if _, err := pt.PlaceLimitOrder("BTCUSDT", "SHORT", 1, 99, 2, TimeInForcePostOnly); err == nil {
+		t.Error("会立即成交的post-only限价单应被拒绝")
+	}
+
+	// IOC未到价直接过期，不留挂单
+	order, err := pt.PlaceLimitOrder("BTCUSDT", "SHORT", 1, 105, 2, TimeInForceIOC)
+	if err != nil || order.Status != OrderStatusExpired {
+		t.Fatalf("未到价的IOC应过期, got %v %v", order, err)
+	}
+	if fills := pt.UpdateMarkPrice("BTCUSDT", 106); len(fills) != 0 {
+		t.Fatalf("过期的IOC不应成交: %+v", fills)
+	}
+
+	// 可立即成交的GTC按市价含滑点成交，但不劣于限价
+	feed.set("BTCUSDT", 106)
+	order, err = pt.PlaceLimitOrder("BTCUSDT", "LONG", 1, 110, 2, TimeInForceGTC)
+	if err != nil || order.Status != OrderStatusFilled {
+		t.Fatalf("可立即成交的限价单应成交, got %v %v", order, err)
+	}
+	if !almostEqual(order.AvgPrice, 106*1.001) {
+		t.Errorf("成交价错误: %v", order.AvgPrice)
+	}
+	order, err = pt.PlaceLimitOrder("BTCUSDT", "SHORT", 1, 105.95, 2, TimeInForceFOK)
+	if err != nil || order.Status != OrderStatusFilled {
+		t.Fatalf("可立即成交的FOK应成交, got %v %v", order, err)
+	}
+	if !almostEqual(order.AvgPrice, 105.95) {
+		t.Errorf("滑点后劣于限价时应按限价成交: %v", order.AvgPrice)
+	}
+}
+
+func TestPaperTraderAmendAndCancelLimitOrder(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	pt := newTestPaperTrader(t, feed, nil)
+
+	order, err := pt.PlaceLimitOrder("BTCUSDT", "LONG", 1, 90, 2, TimeInForcePostOnly)
+	if err != nil {
+		t.Fatalf("下限价单失败: %v", err)
+	}
+	orderID := order.OrderID
+
+	// post-only改价到会立即成交的价格应被拒绝，原挂单保留
+	if _, err := pt.AmendOrder("BTCUSDT", orderID, 0, 101); err == nil {
+		t.Error("post-only改价后会立即成交应被拒绝")
+	}
+	amended, err := pt.AmendOrder("BTCUSDT", orderID, 2, 92)
+	if err != nil || amended.Status != OrderStatusNew {
+		t.Fatalf("改单失败: %v %v", amended, err)
+	}
+
+	fills := pt.UpdateMarkPrice("BTCUSDT", 92)
+	if len(fills) != 1 || !almostEqual(fills[0].Quantity, 2) || !almostEqual(fills[0].Price, 92) {
+		t.Fatalf("改单后应按新价格和数量成交, got %+v", fills)
+	}
+
+	order, _ = pt.PlaceLimitOrder("BTCUSDT", "SHORT", 1, 120, 2, TimeInForceGTC)
+	if err := pt.CancelOrder("BTCUSDT", order.OrderID); err != nil {
+		t.Fatalf("撤单失败: %v", err)
+	}
+	if err := pt.CancelOrder("BTCUSDT", order.OrderID); err == nil {
+		t.Error("重复撤单应返回错误")
+	}
+	if fills := pt.UpdateMarkPrice("BTCUSDT", 121); len(fills) != 0 {
+		t.Fatalf("已撤销的限价单不应成交: %+v", fills)
+	}
+}
+
+func TestPaperTraderOrderAndFillQueries(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100, "ETHUSDT": 10})
+	pt := newTestPaperTrader(t, feed, nil)
+	start := pt.clock()
+
+	opened, err := pt.OpenLong("BTCUSDT", 1, 5)
+	if err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	if err := pt.SetStopLoss("BTCUSDT", "LONG", 1, 90); err != nil {
+		t.Fatalf("设置止损失败: %v", err)
+	}
+	if _, err := pt.PlaceLimitOrder("ETHUSDT", "SHORT", 2, 12, 2, TimeInForceGTC); err != nil {
+		t.Fatalf("下限价单失败: %v", err)
+	}
+
+	openID := opened.OrderID
+	order, err := pt.GetOrder("BTCUSDT", openID)
+	if err != nil || order.Status != OrderStatusFilled || !almostEqual(order.AvgPrice, 100.1) {
+		t.Fatalf("已成交订单查询错误: %v %v", order, err)
+	}
+	if _, err := pt.GetOrder("ETHUSDT", openID); err == nil {
+		t.Error("币种不匹配时应查询失败")
+	}
+
+	openOrders, _ := pt.GetOpenOrders("")
+	if len(openOrders) != 2 {
+		t.Fatalf("应有止损单和限价单2个挂单, got %v", openOrders)
+	}
+	ethOrders, _ := pt.GetOpenOrders("ETHUSDT")
+	if len(ethOrders) != 1 || ethOrders[0].Side != "SELL" || !almostEqual(ethOrders[0].Price, 12) {
+		t.Fatalf("限价挂单查询错误: %v", ethOrders)
+	}
+
+	fills, _ := pt.GetFills("BTCUSDT", start)
+	if len(fills) != 1 || fills[0].OrderID != openID || fills[0].Side != "buy" {
+		t.Fatalf("成交记录查询错误: %v", fills)
+	}
+	if !almostEqual(fills[0].Fee, 100.1*0.001) {
+		t.Errorf("手续费错误: %v", fills[0].Fee)
+	}
+	if later, _ := pt.GetFills("", start.Add(time.Hour)); len(later) != 0 {
+		t.Errorf("since之后没有成交, got %v", later)
+	}
+}
@@ -0,0 +1,503 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/logger"
+	"nofx/market"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultGuardianInterval 实时风控按最新价检查持仓的间隔
+	defaultGuardianInterval = 2 * time.Second
+	// guardianRefreshInterval 持仓和钱包余额快照的刷新间隔（价格来自WebSocket，快照只需跟上持仓变化；
+	// 本交易器的交易会使快照立即失效，交易所侧的止盈止损成交在下次刷新时体现）
+	guardianRefreshInterval = 30 * time.Second
+
+	// guardianTradeType 风控平仓属于系统保护机制，按止损处理（不消耗积分）
+	guardianTradeType = TradeTypeStopLoss
+)
+
+// GuardianStore 实时风控状态持久化接口（由config.Database实现），重启后恢复净值下限触发的暂停
+type GuardianStore interface {
+	GetGuardianState(traderID string) (string, error)
+	SaveGuardianState(traderID string, state string) error
+}
+
+// GuardianState 净值下限触发的暂停状态，触发后停止交易直到人工重置
+type GuardianState struct {
+	Halted    bool      `json:"halted"`
+	HaltedAt  time.Time `json:"halted_at"`
+	Reason    string    `json:"reason"`
+	FloorBase float64   `json:"floor_base"` // 净值下限的基准资金（0表示初始资金，人工重置后为重置时的净值）
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// positionGuardian 独立于AI决策周期的实时风控
+// 交易所止损单设置失败时持仓在两个周期之间没有保护，守护协程按WebSocket实时价格（行情中断时使用快照中的标记价格）检查：
+//  1. 单个持仓亏损超过保证金的 maxLossPct% 时平掉该持仓
+//  2. 账户净值低于初始资金的 equityFloorPct% 时平掉所有持仓并暂停交易，直到人工重置
+type positionGuardian struct {
+	traderID       string
+	maxLossPct     float64 // 单个持仓最大亏损（占保证金百分比，0表示不检查）
+	equityFloorPct float64 // 账户净值下限（占初始资金百分比，0表示不检查）
+	interval       time.Duration
+	price          func(symbol string) (float64, error) // 实时价格（WebSocket）
+	subscribe      func(symbol string) error            // 订阅实时价格
+	store          GuardianStore
+
+	mu          sync.Mutex
+	positions   []Position
+	wallet      float64 // 钱包余额（不含未实现盈亏）
+	refreshedAt time.Time
+	version     int // 每次快照失效时递增，用于判断检查期间持仓是否已被AI周期修改
+	subscribed  map[string]bool
+	stop        chan struct{}
+	state       GuardianState
+}
+
+// guardianBreach 触发风控的持仓
+type guardianBreach struct {
+	Position Position
+	Price    float64
+	Reason   string
+	Quantity float64 // 平仓数量（0或不小于持仓数量时全部平仓）
+}
+
+// newPositionGuardian 根据配置创建实时风控并恢复持久化的暂停状态，两项检查都未启用时返回nil
+func newPositionGuardian(config AutoTraderConfig, store GuardianStore) *positionGuardian {
+	if config.GuardianMaxLossPct <= 0 && config.GuardianEquityFloorPct <= 0 {
+		return nil
+	}
+	interval := config.GuardianInterval
+	if interval <= 0 {
+		interval = defaultGuardianInterval
+	}
+	g := &positionGuardian{
+		traderID:       config.ID,
+		maxLossPct:     config.GuardianMaxLossPct,
+		equityFloorPct: config.GuardianEquityFloorPct,
+		interval:       interval,
+		price:          market.LatestPrice,
+		subscribe:      market.SubscribePrice,
+		store:          store,
+		subscribed:     make(map[string]bool),
+	}
+	if store == nil {
+		return g
+	}
+	data, err := store.GetGuardianState(config.ID)
+	if err != nil {
+		log.Printf("⚠️ [%s] 读取实时风控状态失败: %v", config.Name, err)
+		return g
+	}
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &g.state); err != nil {
+			log.Printf("⚠️ [%s] 解析实时风控状态失败: %v", config.Name, err)
+			g.state = GuardianState{}
+		}
+	}
+	return g
+}
+
+// State 返回暂停状态副本
+func (g *positionGuardian) State() GuardianState {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.state
+}
+
+// floorBase 净值下限的基准资金
+func (g *positionGuardian) floorBase(initialBalance float64) float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.state.FloorBase > 0 {
+		return g.state.FloorBase
+	}
+	return initialBalance
+}
+
+// halt 触发净值下限后暂停交易；返回是否为新触发（已暂停时不重复记录）
+func (g *positionGuardian) halt(reason string, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.state.Halted {
+		return false
+	}
+	g.state.Halted = true
+	g.state.HaltedAt = now
+	g.state.Reason = reason
+	g.save()
+	return true
+}
+
+// reset 人工解除暂停，以当前净值作为新的净值下限基准（否则净值仍低于下限，恢复交易后会立即再次触发）
+func (g *positionGuardian) reset(equity float64, now time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.state.Halted {
+		return fmt.Errorf("实时风控未处于暂停状态")
+	}
+	g.state.Halted = false
+	g.state.FloorBase = equity
+	g.state.ResetAt = now
+	g.save()
+	return nil
+}
+
+// save 持久化暂停状态（调用方需持有锁）
+func (g *positionGuardian) save() {
+	if g.store == nil {
+		return
+	}
+	data, err := json.Marshal(g.state)
+	if err != nil {
+		log.Printf("⚠️ 序列化实时风控状态失败: %v", err)
+		return
+	}
+	if err := g.store.SaveGuardianState(g.traderID, string(data)); err != nil {
+		log.Printf("⚠️ 保存实时风控状态失败: %v", err)
+	}
+}
+
+// invalidate 持仓可能已变化（AI执行了交易），下次检查时重新获取快照
+func (g *positionGuardian) invalidate() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refreshedAt = time.Time{}
+	g.version++
+}
+
+// isCurrent 快照版本是否仍然有效
+func (g *positionGuardian) isCurrent(version int) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.version == version
+}
+
+// snapshot 返回持仓、钱包余额快照及其版本，过期时从交易所刷新并订阅新持仓的实时价格
+// 查询交易所期间不持有锁，AI周期的invalidate和状态查询不会被慢请求阻塞
+func (g *positionGuardian) snapshot(tr Trader, now time.Time) ([]Position, float64, int, error) {
+	g.mu.Lock()
+	if !g.refreshedAt.IsZero() && now.Sub(g.refreshedAt) < guardianRefreshInterval {
+		defer g.mu.Unlock()
+		return g.positions, g.wallet, g.version, nil
+	}
+	version := g.version
+	g.mu.Unlock()
+
+	balance, err := tr.GetBalance()
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("获取账户余额失败: %w", err)
+	}
+	positions, err := tr.GetPositions()
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	var newSymbols []string
+	g.mu.Lock()
+	for _, pos := range positions {
+		if !g.subscribed[pos.Symbol] {
+			g.subscribed[pos.Symbol] = true
+			newSymbols = append(newSymbols, pos.Symbol)
+		}
+	}
+	// 查询期间快照已失效（AI执行了交易）时不缓存，返回的旧版本号使本次检查不会平仓
+	if g.version == version {
+		g.positions = positions
+		g.wallet = balance.WalletBalance
+		g.refreshedAt = now
+	}
+	g.mu.Unlock()
+
+	for _, symbol := range newSymbols {
+		if err := g.subscribe(symbol); err != nil {
+			log.Printf("⚠️ [风控] %s 订阅实时价格失败，使用交易所标记价格: %v", symbol, err)
+		}
+	}
+	return positions, balance.WalletBalance, version, nil
+}
+
+// positionPnL 按指定价格计算持仓的未实现盈亏（USDT）
+func positionPnL(pos Position, price float64) float64 {
+	if pos.Side == "short" {
+		return pos.Quantity * (pos.EntryPrice - price)
+	}
+	return pos.Quantity * (price - pos.EntryPrice)
+}
+
+// evaluate 按实时价格检查持仓，返回需要平仓的持仓（触发净值下限时返回所有持仓）和净值下限的触发原因（未触发时为空）
+// 快照最长30秒刷新一次，其中的标记价格可能已过时，只在WebSocket行情中断或过期时使用
+func (g *positionGuardian) evaluate(positions []Position, wallet, floorBase float64) ([]guardianBreach, string) {
+	var breaches []guardianBreach
+	equity := wallet
+	for _, pos := range positions {
+		if pos.Quantity <= 0 || pos.EntryPrice <= 0 {
+			continue
+		}
+		price := pos.MarkPrice
+		if live, err := g.price(pos.Symbol); err == nil && live > 0 {
+			price = live
+		}
+		if price <= 0 {
+			continue
+		}
+		pnl := positionPnL(pos, price)
+		equity += pnl
+
+		leverage := pos.Leverage
+		if leverage <= 0 {
+			leverage = 1
+		}
+		margin := pos.Quantity * pos.EntryPrice / float64(leverage)
+		if lossPct := -pnl / margin * 100; g.maxLossPct > 0 && lossPct >= g.maxLossPct {
+			breaches = append(breaches, guardianBreach{
+				Position: pos,
+				Price:    price,
+				Reason:   fmt.Sprintf("亏损 %.2f%% 超过单仓上限 %.2f%%", lossPct, g.maxLossPct),
+			})
+		} else {
+			breaches = append(breaches, guardianBreach{Position: pos, Price: price})
+		}
+	}
+
+	floor := floorBase * g.equityFloorPct / 100
+	if g.equityFloorPct > 0 && equity < floor {
+		reason := fmt.Sprintf("账户净值 %.2f 低于下限 %.2f (基准资金 %.2f 的 %.0f%%)", equity, floor, floorBase, g.equityFloorPct)
+		for i := range breaches {
+			if breaches[i].Reason == "" {
+				breaches[i].Reason = reason
+			}
+		}
+		return breaches, reason
+	}
+
+	// 未触发净值下限时只平掉超过单仓亏损上限的持仓
+	triggered := breaches[:0]
+	for _, breach := range breaches {
+		if breach.Reason != "" {
+			triggered = append(triggered, breach)
+		}
+	}
+	return triggered, ""
+}
+
+// startGuardian 启动实时风控协程（未启用或已启动时不做任何事）
+func (at *AutoTrader) startGuardian() {
+	g := at.guardian
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stop != nil {
+		return
+	}
+
+	stop := make(chan struct{})
+	g.stop = stop
+	go func() {
+		ticker := time.NewTicker(g.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				at.checkGuardian()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	log.Printf("🛡 [%s] 实时风控已启动 (单仓亏损上限 %.0f%%, 净值下限 %.0f%%, 间隔 %v)",
+		at.name, g.maxLossPct, g.equityFloorPct, g.interval)
+}
+
+// stopGuardian 停止实时风控协程
+func (at *AutoTrader) stopGuardian() {
+	g := at.guardian
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stop != nil {
+		close(g.stop)
+		g.stop = nil
+	}
+}
+
+// checkGuardian 执行一次实时风控检查，触发时平仓并写入决策日志；返回执行日志
+// 触发净值下限时同时暂停交易（持久化，重启后仍然有效），需通过ResetGuardianHalt人工解除
+func (at *AutoTrader) checkGuardian() []string {
+	g := at.guardian
+	if g == nil {
+		return nil
+	}
+	positions, wallet, version, err := g.snapshot(at.trader, at.now())
+	if err != nil {
+		log.Printf("⚠️ [风控] %v", err)
+		return nil
+	}
+	breaches, floorReason := g.evaluate(positions, wallet, g.floorBase(at.initialBalance))
+	var haltLine string
+	if floorReason != "" && g.halt(floorReason, at.now()) {
+		haltLine = fmt.Sprintf("🚨 实时风控: %s，暂停交易直到人工重置", floorReason)
+		log.Printf("🚨 [%s] %s", at.name, haltLine)
+	}
+	if len(breaches) == 0 && haltLine == "" {
+		return nil
+	}
+	sort.SliceStable(breaches, func(i, j int) bool { return breaches[i].Position.Key() < breaches[j].Position.Key() })
+
+	// 与AI周期的交易执行互斥，避免重复平仓；等待期间AI修改了持仓时放弃本次检查，下次按新持仓重新判断
+	at.execMu.Lock()
+	defer at.execMu.Unlock()
+	if !g.isCurrent(version) {
+		return nil // 暂停状态已保存，持仓下次检查时平掉
+	}
+
+	record := &logger.DecisionRecord{
+		ExecutionLog: []string{},
+		Success:      true,
+	}
+	if haltLine != "" {
+		record.ExecutionLog = append(record.ExecutionLog, haltLine)
+	}
+	for _, breach := range breaches {
+		actionRecord := at.guardianClose(breach)
+		if actionRecord.Success {
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🛡 %s %s 风控平仓 (%s): %s",
+				breach.Position.Symbol, breach.Position.Side, guardianTradeType, breach.Reason))
+		} else {
+			record.Success = false
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 风控平仓失败: %s",
+				breach.Position.Symbol, breach.Position.Side, actionRecord.Error))
+		}
+		record.Decisions = append(record.Decisions, actionRecord)
+	}
+	g.invalidate()
+
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存风控记录失败: %v", err)
+	}
+	return record.ExecutionLog
+}
+
+// guardianClose 市价平掉触发风控的持仓（系统保护平仓，不消耗积分）
+// 指定了小于持仓的平仓数量时只平掉该数量（如跟踪止损），交易结果和资金费按平仓部分记录
+func (at *AutoTrader) guardianClose(breach guardianBreach) logger.DecisionAction {
+	pos := breach.Position
+	positionSide := pos.PositionSide()
+	partial := breach.Quantity > 0 && breach.Quantity < pos.Quantity
+	closeQty := 0.0 // 0 = 全部平仓
+	action := "close_" + pos.Side
+	if partial {
+		closeQty = breach.Quantity
+		action = "reduce_" + pos.Side
+	}
+	actionRecord := logger.DecisionAction{
+		Action:    action,
+		Symbol:    pos.Symbol,
+		Quantity:  pos.Quantity,
+		Leverage:  pos.Leverage,
+		Price:     breach.Price,
+		Timestamp: at.now(),
+	}
+	if partial {
+		actionRecord.Quantity = closeQty
+	}
+	log.Printf("🛡 [风控] %s %s 触发: %s，市价平仓 %.6f (%s，不消耗积分)", pos.Symbol, positionSide, breach.Reason, actionRecord.Quantity, guardianTradeType)
+
+	if !partial {
+		at.cancelPendingLimitOrder(pos.Symbol, positionSide, "风控平仓")
+	}
+
+	submittedAt := at.now()
+	var order *OrderResult
+	var err error
+	if positionSide == "LONG" {
+		order, err = at.trader.CloseLong(pos.Symbol, closeQty)
+	} else {
+		order, err = at.trader.CloseShort(pos.Symbol, closeQty)
+	}
+	if err != nil {
+		log.Printf("❌ [风控] %s %s 平仓失败: %v", pos.Symbol, positionSide, err)
+		actionRecord.Error = err.Error()
+		return actionRecord
+	}
+	at.recordOrderExecution(pos.Symbol, order, submittedAt, &actionRecord)
+	actionRecord.Success = true
+	closed := pos
+	if partial {
+		closed.Quantity = closeQty
+		at.bookReduceFunding(&pos, closeQty, &actionRecord)
+	} else {
+		actionRecord.Funding = at.syncPositionFunding(pos.Symbol, pos.Key())
+	}
+
+	// 按实际成交均价计算盈亏（滑点、跳空导致的成交价偏差计入交易结果），交易所未返回成交价时使用触发价
+	exitPrice := actionRecord.Price
+	if exitPrice <= 0 {
+		exitPrice = breach.Price
+	}
+	pnl := positionPnL(closed, exitPrice)
+	profitPct := 0.0
+	if pos.EntryPrice > 0 {
+		profitPct = pnl / (closed.Quantity * pos.EntryPrice) * 100
+	}
+	at.recordTradeResult(pos.Symbol, profitPct >= 0, profitPct, pnl+actionRecord.Funding)
+	log.Printf("  ✓ [风控] %s %s 已平仓，订单ID: %s", pos.Symbol, strings.ToLower(positionSide), actionRecord.OrderID)
+	return actionRecord
+}
+
+// guardianHaltReason 净值下限触发的暂停原因（未暂停或未启用实时风控时为空）
+func (at *AutoTrader) guardianHaltReason() string {
+	if at.guardian == nil {
+		return ""
+	}
+	if state := at.guardian.State(); state.Halted {
+		return state.Reason
+	}
+	return ""
+}
+
+// ResetGuardianHalt 人工解除净值下限触发的暂停，以当前账户净值作为新的净值下限基准
+func (at *AutoTrader) ResetGuardianHalt() error {
+	if at.guardian == nil {
+		return fmt.Errorf("未启用实时风控")
+	}
+	balance, err := at.trader.GetBalance()
+	if err != nil {
+		return fmt.Errorf("获取账户余额失败: %w", err)
+	}
+	equity := balance.TotalEquity()
+	if err := at.guardian.reset(equity, at.now()); err != nil {
+		return err
+	}
+	log.Printf("▶️ [%s] 实时风控暂停已人工解除，净值下限基准调整为 %.2f", at.name, equity)
+	return nil
+}
+
+// guardianStatus 实时风控状态（用于API），未启用时enabled为false
+func (at *AutoTrader) guardianStatus() map[string]interface{} {
+	g := at.guardian
+	if g == nil {
+		return map[string]interface{}{"enabled": false}
+	}
+	state := g.State()
+	return map[string]interface{}{
+		"enabled":          true,
+		"max_loss_pct":     g.maxLossPct,
+		"equity_floor_pct": g.equityFloorPct,
+		"floor_base":       g.floorBase(at.initialBalance),
+		"halted":           state.Halted,
+		"halted_at":        state.HaltedAt.Format(time.RFC3339),
+		"reason":           state.Reason,
+	}
+}
@@ -0,0 +1,275 @@
+package trader
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// newGuardianTestAutoTrader 创建带实时风控的AutoTrader，实时价格取自live（模拟WebSocket行情）
+func newGuardianTestAutoTrader(t *testing.T, feed, live *fakePriceFeed, maxLossPct, equityFloorPct float64) (*AutoTrader, *PaperTrader, *[]string) {
+	t.Helper()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	at, paper := newLimitTestAutoTrader(t, feed, &now)
+	at.guardian = newPositionGuardian(AutoTraderConfig{ID: "t1", GuardianMaxLossPct: maxLossPct, GuardianEquityFloorPct: equityFloorPct}, nil)
+	at.guardian.price = live.get
+	var subscribed []string
+	at.guardian.subscribe = func(symbol string) error {
+		subscribed = append(subscribed, symbol)
+		return nil
+	}
+	return at, paper, &subscribed
+}
+
+func guardianOpenLong(t *testing.T, at *AutoTrader, symbol string) {
+	t.Helper()
+	d := &decision.Decision{Symbol: symbol, Action: "open_long", Leverage: 5, PositionSizeUSD: 500, StopLoss: 82, TakeProfit: 200}
+	if err := at.executeDecisionWithRecord(d, &logger.DecisionAction{}); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	at.guardian.invalidate()
+}
+
+func TestPositionGuardianMaxLossPerPosition(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	live := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, paper, subscribed := newGuardianTestAutoTrader(t, feed, live, 50, 0)
+	guardianOpenLong(t, at, "BTCUSDT")
+
+	// 5倍杠杆下跌5%：亏损保证金的25%，未超过上限
+	live.set("BTCUSDT", 95)
+	if lines := at.checkGuardian(); len(lines) != 0 {
+		t.Fatalf("未超过亏损上限不应平仓: %v", lines)
+	}
+	if len(*subscribed) != 1 || (*subscribed)[0] != "BTCUSDT" {
+		t.Errorf("应订阅持仓币种的实时价格, got %v", *subscribed)
+	}
+
+	// 实时价格跌破上限：不需要重新查询持仓快照即可触发
+	live.set("BTCUSDT", 89)
+	feed.set("BTCUSDT", 89)
+	lines := at.checkGuardian()
+	if len(lines) != 1 || !strings.Contains(lines[0], TradeTypeStopLoss.String()) {
+		t.Fatalf("应按止损类型风控平仓: %v", lines)
+	}
+	if pos, ok := findPosition(t, paper, "BTCUSDT", "long"); ok {
+		t.Errorf("风控平仓后不应有持仓: %+v", pos)
+	}
+
+	records, err := at.decisionLogger.GetLatestRecords(1)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("应写入风控记录: %v %v", records, err)
+	}
+	if actions := records[0].Decisions; len(actions) != 1 || actions[0].Action != "close_long" || !actions[0].Success || actions[0].OrderID == "" {
+		t.Errorf("风控记录错误: %+v", actions)
+	}
+
+	// 持仓已平，不再重复触发
+	if lines := at.checkGuardian(); len(lines) != 0 {
+		t.Errorf("没有持仓时不应再触发: %v", lines)
+	}
+}
+
+func TestPositionGuardianEquityFloor(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100, "SOLUSDT": 100})
+	live := newFakePriceFeed(map[string]float64{"BTCUSDT": 100, "SOLUSDT": 100})
+	at, paper, _ := newGuardianTestAutoTrader(t, feed, live, 0, 90)
+	guardianOpenLong(t, at, "BTCUSDT")
+	guardianOpenLong(t, at, "SOLUSDT")
+
+	// 各亏损 5×10 = 50，净值约900，高于下限
+	for _, symbol := range []string{"BTCUSDT", "SOLUSDT"} {
+		live.set(symbol, 92)
+	}
+	if lines := at.checkGuardian(); len(lines) != 0 {
+		t.Fatalf("净值高于下限不应平仓: %v", lines)
+	}
+
+	// 各亏损 5×15 = 75，净值约850，低于初始资金的90%：平掉所有持仓并暂停交易
+	for _, symbol := range []string{"BTCUSDT", "SOLUSDT"} {
+		live.set(symbol, 85)
+		feed.set(symbol, 85)
+	}
+	at.guardian.invalidate()
+	lines := at.checkGuardian()
+	if len(lines) != 3 || !strings.Contains(lines[0], "暂停交易") || !strings.Contains(lines[1], "低于下限") {
+		t.Fatalf("应暂停交易并平掉所有持仓: %v", lines)
+	}
+	positions, err := paper.GetPositions()
+	if err != nil || len(positions) != 0 {
+		t.Errorf("触发净值下限后不应有持仓: %+v %v", positions, err)
+	}
+	if reason := at.guardianHaltReason(); !strings.Contains(reason, "低于下限") {
+		t.Errorf("触发净值下限后应暂停交易, got %q", reason)
+	}
+}
+
+// memoryGuardianStore 内存中的实时风控状态存储
+type memoryGuardianStore map[string]string
+
+func (m memoryGuardianStore) GetGuardianState(traderID string) (string, error) {
+	return m[traderID], nil
+}
+
+func (m memoryGuardianStore) SaveGuardianState(traderID string, state string) error {
+	m[traderID] = state
+	return nil
+}
+
+func TestPositionGuardianEquityFloorHaltUntilReset(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	live := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, paper, _ := newGuardianTestAutoTrader(t, feed, live, 0, 95)
+	store := memoryGuardianStore{}
+	config := AutoTraderConfig{ID: "t1", GuardianEquityFloorPct: 95}
+	at.guardian = newPositionGuardian(config, store)
+	at.guardian.price = live.get
+	at.guardian.subscribe = func(string) error { return nil }
+	guardianOpenLong(t, at, "BTCUSDT")
+
+	// 亏损 5×12 = 60，净值约940，低于950
+	live.set("BTCUSDT", 88)
+	feed.set("BTCUSDT", 88)
+	if lines := at.checkGuardian(); len(lines) != 2 {
+		t.Fatalf("应暂停交易并平仓: %v", lines)
+	}
+
+	// 已暂停时不重复记录
+	at.guardian.invalidate()
+	if lines := at.checkGuardian(); len(lines) != 0 {
+		t.Errorf("已暂停且没有持仓时不应重复触发: %v", lines)
+	}
+
+	// 重启后恢复暂停状态，AI周期不执行
+	at.guardian = newPositionGuardian(config, store)
+	at.guardian.price = live.get
+	if at.guardianHaltReason() == "" {
+		t.Fatal("重启后应恢复净值下限触发的暂停")
+	}
+	if err := at.runCycle(); err != nil {
+		t.Fatalf("暂停期间周期不应返回错误: %v", err)
+	}
+	if records, _ := at.decisionLogger.GetLatestRecords(1); len(records) != 1 || !strings.Contains(records[0].ErrorMessage, "需人工重置") {
+		t.Errorf("暂停期间应跳过AI决策: %+v", records)
+	}
+
+	// 人工重置：以当前净值作为新的下限基准，不会立即再次触发
+	if err := at.ResetGuardianHalt(); err != nil {
+		t.Fatalf("重置失败: %v", err)
+	}
+	balance, _ := paper.GetBalance()
+	if state := at.guardian.State(); state.Halted || !almostEqual(state.FloorBase, balance.TotalEquity()) {
+		t.Errorf("重置后应解除暂停并调整基准: %+v", state)
+	}
+	at.guardian.invalidate()
+	if lines := at.checkGuardian(); len(lines) != 0 || at.guardianHaltReason() != "" {
+		t.Errorf("重置后净值高于新下限不应触发: %v", lines)
+	}
+	if err := at.ResetGuardianHalt(); err == nil {
+		t.Error("未暂停时重置应返回错误")
+	}
+}
+
+func TestPositionGuardianPrefersLivePrice(t *testing.T) {
+	live := newFakePriceFeed(map[string]float64{"BTCUSDT": 80})
+	g := newPositionGuardian(AutoTraderConfig{GuardianMaxLossPct: 50}, nil)
+	g.price = live.get
+	positions := []Position{{Symbol: "BTCUSDT", Side: "long", Quantity: 1, EntryPrice: 100, MarkPrice: 95, Leverage: 5}}
+
+	// 快照中的标记价格可能已过时，以WebSocket实时价格为准
+	if breaches, _ := g.evaluate(positions, 1000, 1000); len(breaches) != 1 || breaches[0].Price != 80 {
+		t.Fatalf("应按实时价格触发: %+v", breaches)
+	}
+	// 实时行情中断或过期时使用标记价格
+	g.price = func(string) (float64, error) { return 0, errors.New("实时行情已过期") }
+	if breaches, _ := g.evaluate(positions, 1000, 1000); len(breaches) != 0 {
+		t.Errorf("标记价格未超过上限不应触发: %+v", breaches)
+	}
+	positions[0].MarkPrice = 85
+	if breaches, _ := g.evaluate(positions, 1000, 1000); len(breaches) != 1 || breaches[0].Price != 85 {
+		t.Errorf("行情中断时应按标记价格触发: %+v", breaches)
+	}
+}
+
+// countingTrader 统计持仓查询次数，during在查询期间执行（模拟AI周期同时交易）
+type countingTrader struct {
+	*PaperTrader
+	calls  int
+	during func()
+}
+
+func (c *countingTrader) GetPositions() ([]Position, error) {
+	c.calls++
+	if c.during != nil {
+		c.during()
+	}
+	return c.PaperTrader.GetPositions()
+}
+
+func TestPositionGuardianSnapshotRefresh(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	_, paper := newLimitTestAutoTrader(t, feed, &now)
+	tr := &countingTrader{PaperTrader: paper}
+	g := newPositionGuardian(AutoTraderConfig{GuardianMaxLossPct: 50}, nil)
+	g.subscribe = func(string) error { return nil }
+
+	// 刷新间隔内复用快照，只按实时价格重新计算
+	for i := 0; i < 5; i++ {
+		if _, _, _, err := g.snapshot(tr, now.Add(time.Duration(i)*defaultGuardianInterval)); err != nil {
+			t.Fatalf("获取快照失败: %v", err)
+		}
+	}
+	if tr.calls != 1 {
+		t.Errorf("刷新间隔内不应重复查询交易所, got %d", tr.calls)
+	}
+	if _, _, _, err := g.snapshot(tr, now.Add(guardianRefreshInterval)); err != nil || tr.calls != 2 {
+		t.Errorf("超过刷新间隔后应重新查询, got %d (%v)", tr.calls, err)
+	}
+
+	// 查询期间快照失效：不缓存查询结果，返回的版本号已过期
+	tr.calls = 0
+	g.invalidate()
+	tr.during = g.invalidate
+	_, _, version, _ := g.snapshot(tr, now)
+	if g.isCurrent(version) {
+		t.Error("查询期间快照已失效时返回的版本号不应有效")
+	}
+	tr.during = nil
+	g.snapshot(tr, now)
+	if tr.calls != 2 {
+		t.Errorf("失效的查询结果不应缓存, got %d", tr.calls)
+	}
+}
+
+func TestGuardianCloseRecordsFillPrice(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	live := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, paper, _ := newGuardianTestAutoTrader(t, feed, live, 50, 0)
+	guardianOpenLong(t, at, "BTCUSDT")
+	pos := requirePosition(t, paper, "BTCUSDT", "long")
+
+	// 触发时价格为99，实际成交时已跳空到95（再扣0.1%滑点）：交易结果按成交均价记录
+	feed.set("BTCUSDT", 95)
+	actionRecord := at.guardianClose(guardianBreach{Position: pos, Price: 99, Reason: "测试"})
+	if !actionRecord.Success || !almostEqual(actionRecord.Price, 95*0.999) {
+		t.Fatalf("应记录实际成交均价: %+v", actionRecord)
+	}
+	wantLossPct := (pos.EntryPrice - 95*0.999) / pos.EntryPrice * 100
+	if stats := at.kellyManager.GetHistoricalStats("BTCUSDT"); stats == nil || !almostEqual(stats.TotalLossPct, wantLossPct) {
+		t.Errorf("亏损应按成交价计算为 %.4f%%, got %+v", wantLossPct, stats)
+	}
+}
+
+func TestNewPositionGuardianDisabled(t *testing.T) {
+	if g := newPositionGuardian(AutoTraderConfig{}, nil); g != nil {
+		t.Errorf("未配置风控时不应创建: %+v", g)
+	}
+	if g := newPositionGuardian(AutoTraderConfig{GuardianMaxLossPct: 30}, nil); g == nil || g.interval != defaultGuardianInterval {
+		t.Errorf("应使用默认检查间隔: %+v", g)
+	}
+}
@@ -0,0 +1,121 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+)
+
+// SizingMode 开仓金额计算方式
+type SizingMode string
+
+const (
+	// SizingModeAI 使用AI给出的position_size_usd（默认）
+	SizingModeAI SizingMode = "ai"
+	// SizingModeVolatility 按每笔风险预算、止损距离和ATR计算开仓金额
+	SizingModeVolatility SizingMode = "volatility"
+)
+
+const (
+	defaultRiskPerTradePct = 1.0  // 未配置时每笔交易风险预算（占净值百分比）
+	defaultConfidenceScale = 0.5  // AI未给出信心度时使用一半风险预算
+	minConfidenceScale     = 0.25 // 信心度再低也至少使用1/4风险预算（低于此值AI应选择wait）
+	minStopATRMultiple     = 1.0  // 计算仓位时止损距离至少按1倍ATR（防止过近的止损放大仓位）
+	defaultStopATRMultiple = 2.0  // 未给出止损时按2倍ATR估算止损距离
+	minVolatilityScale     = 0.25 // 高波动币种最多缩小到1/4
+)
+
+// volatilitySizing 波动率仓位计算结果
+type volatilitySizing struct {
+	SizeUSD float64
+	Note    string // 计算说明（记录到DecisionAction）
+}
+
+// sizeByVolatility 按风险预算计算开仓金额
+// 风险金额 = 净值 × 每笔风险预算 × 信心度系数（AI给出的risk_usd更小时以其为准）
+// 数量 = 风险金额 / 止损距离（止损距离不小于1倍ATR14，未给止损时按2倍ATR14）
+// 币种ATR占价格的比例高于目标波动率时按比例缩小仓位
+func sizeByVolatility(d *decision.Decision, entryPrice, equity float64, data *market.Data, riskPct, targetVolPct float64) (volatilitySizing, error) {
+	if equity <= 0 || entryPrice <= 0 {
+		return volatilitySizing{}, fmt.Errorf("净值或入场价无效")
+	}
+	if riskPct <= 0 {
+		riskPct = defaultRiskPerTradePct
+	}
+
+	atr := 0.0
+	if data != nil && data.LongerTermContext != nil {
+		atr = data.LongerTermContext.ATR14
+	}
+
+	confidenceScale := defaultConfidenceScale
+	if d.Confidence > 0 {
+		confidenceScale = math.Min(math.Max(float64(d.Confidence)/100, minConfidenceScale), 1)
+	}
+	riskUSD := equity * riskPct / 100 * confidenceScale
+	riskNote := fmt.Sprintf("风险预算 $%.2f (净值 $%.2f × %.2f%% × 信心 %.2f)", riskUSD, equity, riskPct, confidenceScale)
+	if d.RiskUSD > 0 && d.RiskUSD < riskUSD {
+		riskUSD = d.RiskUSD
+		riskNote += fmt.Sprintf("，按AI风险 $%.2f", d.RiskUSD)
+	}
+
+	stopDistance := 0.0
+	stopNote := ""
+	if d.StopLoss > 0 {
+		stopDistance = math.Abs(entryPrice - d.StopLoss)
+		stopNote = fmt.Sprintf("止损距离 %.2f%%", stopDistance/entryPrice*100)
+		if atr > 0 && stopDistance < atr*minStopATRMultiple {
+			stopDistance = atr * minStopATRMultiple
+			stopNote += fmt.Sprintf(" (小于ATR14，按 %.2f%%)", stopDistance/entryPrice*100)
+		}
+	} else if atr > 0 {
+		stopDistance = atr * defaultStopATRMultiple
+		stopNote = fmt.Sprintf("未给止损，按2×ATR14 %.2f%%", stopDistance/entryPrice*100)
+	}
+	if stopDistance <= 0 {
+		return volatilitySizing{}, fmt.Errorf("缺少止损价和ATR数据")
+	}
+
+	sizeUSD := riskUSD / stopDistance * entryPrice
+	note := fmt.Sprintf("%s，%s", riskNote, stopNote)
+
+	if targetVolPct > 0 && atr > 0 {
+		atrPct := atr / entryPrice * 100
+		if atrPct > targetVolPct {
+			scale := math.Max(targetVolPct/atrPct, minVolatilityScale)
+			sizeUSD *= scale
+			note += fmt.Sprintf("，ATR14 %.2f%% 高于目标 %.2f%%，缩放 %.2f", atrPct, targetVolPct, scale)
+		}
+	}
+
+	note += fmt.Sprintf(" → $%.2f (AI建议 $%.2f)", sizeUSD, d.PositionSizeUSD)
+	return volatilitySizing{SizeUSD: sizeUSD, Note: note}, nil
+}
+
+// targetPositionSize 计算开仓金额：波动率模式按风险预算和ATR计算，否则（或无法计算时）使用AI给出的金额
+func (at *AutoTrader) targetPositionSize(d *decision.Decision, data *market.Data, balance *Balance, actionRecord *logger.DecisionAction) float64 {
+	if at.config.SizingMode != SizingModeVolatility {
+		return d.PositionSizeUSD
+	}
+
+	entryPrice := d.LimitPrice
+	if entryPrice <= 0 && data != nil {
+		entryPrice = data.CurrentPrice
+	}
+	equity := 0.0
+	if balance != nil {
+		equity = balance.TotalEquity()
+	}
+	sizing, err := sizeByVolatility(d, entryPrice, equity, data, at.config.RiskPerTradePct, at.config.TargetVolatilityPct)
+	if err != nil {
+		actionRecord.SizingNote = fmt.Sprintf("波动率仓位无法计算 (%v)，使用AI建议 $%.2f", err, d.PositionSizeUSD)
+		log.Printf("  ⚠️ %s", actionRecord.SizingNote)
+		return d.PositionSizeUSD
+	}
+	actionRecord.SizingNote = sizing.Note
+	log.Printf("  📐 波动率仓位: %s", sizing.Note)
+	return sizing.SizeUSD
+}
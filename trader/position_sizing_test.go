@@ -0,0 +1,73 @@
+package trader
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+)
+
+func TestSizeByVolatility(t *testing.T) {
+	data := &market.Data{CurrentPrice: 100, LongerTermContext: &market.LongerTermData{ATR14: 2}}
+
+	// 风险 = 1000 × 1% × 0.8 = 8，止损距离5 → 数量1.6 → $160
+	d := &decision.Decision{Symbol: "SOLUSDT", Action: "open_long", PositionSizeUSD: 500, StopLoss: 95, Confidence: 80}
+	sizing, err := sizeByVolatility(d, 100, 1000, data, 1, 0)
+	if err != nil || math.Abs(sizing.SizeUSD-160) > 1e-9 {
+		t.Fatalf("按止损距离计算仓位错误: %+v %v", sizing, err)
+	}
+	if !strings.Contains(sizing.Note, "风险预算 $8.00") || !strings.Contains(sizing.Note, "AI建议 $500.00") {
+		t.Errorf("计算说明不完整: %s", sizing.Note)
+	}
+
+	// AI给出的风险更小时以其为准；止损距离小于ATR时按ATR计算
+	d = &decision.Decision{PositionSizeUSD: 500, StopLoss: 99, Confidence: 100, RiskUSD: 4}
+	sizing, err = sizeByVolatility(d, 100, 1000, data, 1, 0)
+	if err != nil || math.Abs(sizing.SizeUSD-200) > 1e-9 {
+		t.Fatalf("应按AI风险4和ATR距离2计算为$200: %+v %v", sizing, err)
+	}
+
+	// 未给止损按2×ATR；ATR占比2%高于目标1%，缩放0.5；未给信心度按一半预算
+	d = &decision.Decision{PositionSizeUSD: 500}
+	sizing, err = sizeByVolatility(d, 100, 1000, data, 2, 1)
+	if err != nil || math.Abs(sizing.SizeUSD-125) > 1e-9 {
+		t.Fatalf("风险10/距离4=$250，缩放0.5后应为$125: %+v %v", sizing, err)
+	}
+
+	if _, err := sizeByVolatility(&decision.Decision{}, 100, 1000, &market.Data{CurrentPrice: 100}, 1, 0); err == nil {
+		t.Error("缺少止损和ATR时应无法计算")
+	}
+}
+
+func TestOpenWithVolatilitySizing(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, paper := newLimitTestAutoTrader(t, feed, &now)
+	at.config.SizingMode = SizingModeVolatility
+	at.config.RiskPerTradePct = 2
+	at.marketData = func(symbol string) (*market.Data, error) {
+		price, err := feed.get(symbol)
+		if err != nil {
+			return nil, err
+		}
+		return &market.Data{Symbol: symbol, CurrentPrice: price, LongerTermContext: &market.LongerTermData{ATR14: 1}}, nil
+	}
+
+	// 风险 = 1000 × 2% × 1.0 = 20，止损距离10 → 数量2 → $200（AI建议$500）
+	d := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 500, StopLoss: 90, TakeProfit: 130, Confidence: 100}
+	record := logger.DecisionAction{Action: d.Action, Symbol: d.Symbol}
+	if err := at.executeOpenLongWithRecord(d, &record); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	if math.Abs(record.SizeUSD-200) > 1e-9 || !strings.Contains(record.SizingNote, "风险预算 $20.00") {
+		t.Errorf("应记录波动率仓位: size=%.2f note=%s", record.SizeUSD, record.SizingNote)
+	}
+	pos, ok := findPosition(t, paper, "BTCUSDT", "long")
+	if !ok || math.Abs(pos.Quantity-2) > 1e-6 {
+		t.Errorf("应按波动率仓位开仓2个, got %+v", pos)
+	}
+}
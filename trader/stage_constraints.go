@@ -0,0 +1,227 @@
+package trader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"strings"
+	"time"
+)
+
+// ConstraintsStore 学习阶段约束状态持久化接口（由config.Database实现）
+type ConstraintsStore interface {
+	GetConstraintsState(traderID string) (string, error)
+	SaveConstraintsState(traderID string, state string) error
+}
+
+// newStageConstraints 创建学习阶段约束并恢复持久化的状态，未启用时返回nil
+func newStageConstraints(config AutoTraderConfig, store ConstraintsStore, clock func() time.Time) *ConstraintsManager {
+	if !config.EnableStageConstraints {
+		return nil
+	}
+	cm := NewConstraintsManager()
+	cm.SetClock(clock)
+	if store == nil {
+		return cm
+	}
+	data, err := store.GetConstraintsState(config.ID)
+	if err != nil {
+		log.Printf("⚠️ [%s] 读取学习阶段约束状态失败: %v", config.Name, err)
+		return cm
+	}
+	if data == "" {
+		return cm
+	}
+	var state ConstraintsState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		log.Printf("⚠️ [%s] 解析学习阶段约束状态失败，从婴儿期开始: %v", config.Name, err)
+		return cm
+	}
+	cm.RestoreState(state)
+	log.Printf("🎓 [%s] 恢复学习阶段: 阶段%d (交易数 %d)", config.Name, state.Stage, state.TotalTrades)
+	return cm
+}
+
+// saveConstraintsState 持久化学习阶段约束状态
+func (at *AutoTrader) saveConstraintsState() {
+	if at.constraints == nil || at.constraintsStore == nil {
+		return
+	}
+	data, err := json.Marshal(at.constraints.State())
+	if err != nil {
+		log.Printf("⚠️ 序列化学习阶段约束状态失败: %v", err)
+		return
+	}
+	if err := at.constraintsStore.SaveConstraintsState(at.id, string(data)); err != nil {
+		log.Printf("⚠️ 保存学习阶段约束状态失败: %v", err)
+	}
+}
+
+// syncStageConstraints 每个周期用当前持仓刷新持仓跟踪和并发仓位数，并检查日度重置
+func (at *AutoTrader) syncStageConstraints(ctx *decision.Context) {
+	if at.constraints == nil {
+		return
+	}
+	at.constraints.CheckDailyReset()
+
+	tracked := make([]*TrackedPosition, 0, len(ctx.Positions))
+	for _, pos := range ctx.Positions {
+		tracked = append(tracked, &TrackedPosition{
+			Symbol:        pos.Symbol,
+			Side:          pos.Side,
+			OpenPrice:     pos.EntryPrice,
+			OpenTime:      time.UnixMilli(pos.UpdateTime),
+			Leverage:      pos.Leverage,
+			PositionSize:  pos.Quantity * pos.MarkPrice,
+			UnrealizedPnL: pos.UnrealizedPnL,
+		})
+	}
+	at.positionTracker.Sync(tracked)
+	at.constraints.SetPositionCount(at.positionTracker.GetPositionCount())
+
+	// 提示词和决策校验使用阶段杠杆上限，避免AI给出必然被拦截的杠杆
+	maxLeverage := at.constraints.GetCurrentConstraints().MaxLeverage
+	if ctx.BTCETHLeverage > maxLeverage {
+		ctx.BTCETHLeverage = maxLeverage
+	}
+	if ctx.AltcoinLeverage > maxLeverage {
+		ctx.AltcoinLeverage = maxLeverage
+	}
+}
+
+// checkStageConstraints 执行前按当前学习阶段验证决策，不通过时返回拒绝原因
+// 开仓/加仓检查杠杆、预估亏损和并发仓位，平仓/减仓检查最小持仓时间
+func (at *AutoTrader) checkStageConstraints(d *decision.Decision, equity float64) error {
+	if at.constraints == nil {
+		return nil
+	}
+
+	tracked := at.positionTracker.GetPosition(d.Symbol, decisionSide(d))
+	ok, reason := true, ""
+	switch {
+	case strings.HasPrefix(d.Action, "close_") || strings.HasPrefix(d.Action, "reduce_"):
+		ok, reason = at.constraints.ValidateClose(tracked)
+	case strings.HasPrefix(d.Action, "open_") || strings.HasPrefix(d.Action, "add_"):
+		leverage := d.Leverage
+		if leverage <= 0 && tracked != nil {
+			leverage = tracked.Leverage // 加仓未指定杠杆时沿用持仓杠杆
+		}
+		var position *TrackedPosition
+		if strings.HasPrefix(d.Action, "add_") {
+			position = tracked
+		}
+		ok, reason = at.constraints.ValidateDecision(leverage, at.estimateStopLoss(d, tracked, equity), position)
+	}
+	if ok {
+		return nil
+	}
+	at.constraints.RejectDecision(reason)
+	return errors.New(reason)
+}
+
+// estimateStopLoss 止损触发时的预估亏损（占账户净值百分比），缺少止损价或价格时返回0
+func (at *AutoTrader) estimateStopLoss(d *decision.Decision, tracked *TrackedPosition, equity float64) float64 {
+	if d.StopLoss <= 0 || equity <= 0 {
+		return 0
+	}
+	sizeUSD := d.PositionSizeUSD
+	if sizeUSD <= 0 && tracked != nil {
+		sizeUSD = tracked.PositionSize * d.SizePercent / 100
+	}
+	entryPrice := d.LimitPrice
+	if entryPrice <= 0 {
+		data, err := at.marketData(d.Symbol)
+		if err != nil {
+			return 0
+		}
+		entryPrice = data.CurrentPrice
+	}
+	if sizeUSD <= 0 || entryPrice <= 0 {
+		return 0
+	}
+	return math.Abs(entryPrice-d.StopLoss) / entryPrice * sizeUSD / equity * 100
+}
+
+// decisionSide 决策操作的持仓方向（long/short）
+func decisionSide(d *decision.Decision) string {
+	if strings.HasSuffix(d.Action, "_short") {
+		return "short"
+	}
+	return "long"
+}
+
+// trackExecutedDecision 成功执行后更新持仓跟踪
+func (at *AutoTrader) trackExecutedDecision(d *decision.Decision, price, quantity float64, leverage int) {
+	if at.constraints == nil {
+		return
+	}
+	switch d.Action {
+	case "open_long", "open_short":
+		if d.LimitPrice > 0 {
+			return // 限价单成交后由下一周期的持仓同步跟踪
+		}
+		at.positionTracker.OpenPosition(&TrackedPosition{
+			Symbol:       d.Symbol,
+			Side:         decisionSide(d),
+			OpenPrice:    price,
+			OpenTime:     at.now(),
+			Leverage:     leverage,
+			PositionSize: quantity * price,
+		})
+	case "close_long", "close_short":
+		at.positionTracker.ClosePosition(d.Symbol, decisionSide(d))
+	default:
+		return
+	}
+	at.constraints.SetPositionCount(at.positionTracker.GetPositionCount())
+}
+
+// stageConstraintsStatus 学习阶段约束状态（用于API），未启用时enabled为false
+func (at *AutoTrader) stageConstraintsStatus() map[string]interface{} {
+	if at.constraints == nil {
+		return map[string]interface{}{"enabled": false}
+	}
+	state := at.constraints.State()
+	limits := constraintsForStage(state.Stage)
+	return map[string]interface{}{
+		"enabled":            true,
+		"stage":              int(state.Stage),
+		"stage_name":         stageName(state.Stage),
+		"total_trades":       state.TotalTrades,
+		"consecutive_losses": state.ConsecutiveLosses,
+		"daily_loss_pct":     state.DailyLossAmount,
+		"current_positions":  state.CurrentPositions,
+		"rejections":         state.DecisionRejections,
+		"limits": map[string]interface{}{
+			"max_leverage":        limits.MaxLeverage,
+			"max_daily_loss_pct":  limits.MaxDailyLoss * 100,
+			"max_single_loss_pct": limits.MaxSingleLoss * 100,
+			"min_holding_minutes": limits.MinHoldingMinutes,
+			"max_positions":       limits.MaxConcurrentPos,
+		},
+	}
+}
+
+// stageName 学习阶段名称
+func stageName(stage LearningStage) string {
+	switch stage {
+	case StageChild:
+		return "学童期"
+	case StageMature:
+		return "成熟期"
+	default:
+		return "婴儿期"
+	}
+}
+
+// stageConstraintsLine 决策日志中的阶段摘要
+func (at *AutoTrader) stageConstraintsLine() string {
+	state := at.constraints.State()
+	limits := constraintsForStage(state.Stage)
+	return fmt.Sprintf("🎓 学习阶段: %s (交易数 %d) | 杠杆≤%dx | 仓位≤%d | 当日亏损 %.2f%%/%.0f%%",
+		stageName(state.Stage), state.TotalTrades, limits.MaxLeverage, limits.MaxConcurrentPos,
+		state.DailyLossAmount, limits.MaxDailyLoss*100)
+}
@@ -0,0 +1,132 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/decision"
+)
+
+// memoryConstraintsStore 内存版学习阶段约束状态存储
+type memoryConstraintsStore struct {
+	states map[string]string
+}
+
+func (s *memoryConstraintsStore) GetConstraintsState(traderID string) (string, error) {
+	return s.states[traderID], nil
+}
+
+func (s *memoryConstraintsStore) SaveConstraintsState(traderID string, state string) error {
+	if s.states == nil {
+		s.states = make(map[string]string)
+	}
+	s.states[traderID] = state
+	return nil
+}
+
+func TestStageConstraintsValidateAndPersist(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100, "ETHUSDT": 100})
+	at, _ := newLimitTestAutoTrader(t, feed, &now)
+	store := &memoryConstraintsStore{}
+	config := AutoTraderConfig{ID: at.id, EnableStageConstraints: true}
+	at.constraints = newStageConstraints(config, store, at.now)
+	at.constraintsStore = store
+
+	ctx := &decision.Context{BTCETHLeverage: 5, AltcoinLeverage: 5}
+	at.syncStageConstraints(ctx)
+	if ctx.BTCETHLeverage != 1 || ctx.AltcoinLeverage != 1 {
+		t.Fatalf("婴儿期应把杠杆上限调整为1x, got %d/%d", ctx.BTCETHLeverage, ctx.AltcoinLeverage)
+	}
+
+	// 婴儿期：杠杆≤1，单笔亏损≤3%
+	open := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 2, PositionSizeUSD: 200, StopLoss: 95, TakeProfit: 120}
+	if err := at.checkStageConstraints(open, 1000); err == nil || !strings.Contains(err.Error(), "杠杆") {
+		t.Fatalf("杠杆超过阶段上限应拒绝, got %v", err)
+	}
+	open.Leverage = 1
+	open.PositionSizeUSD = 800 // 止损5% × 800 = 40，占净值4%
+	if err := at.checkStageConstraints(open, 1000); err == nil || !strings.Contains(err.Error(), "单笔") {
+		t.Fatalf("预估亏损超过单笔上限应拒绝, got %v", err)
+	}
+	open.PositionSizeUSD = 400
+	if err := at.checkStageConstraints(open, 1000); err != nil {
+		t.Fatalf("满足约束的开仓应通过: %v", err)
+	}
+	at.trackExecutedDecision(open, 100, 4, 1)
+
+	// 并发仓位已满，加仓不占用新仓位
+	second := &decision.Decision{Symbol: "ETHUSDT", Action: "open_short", Leverage: 1, PositionSizeUSD: 100, StopLoss: 105, TakeProfit: 80}
+	if err := at.checkStageConstraints(second, 1000); err == nil || !strings.Contains(err.Error(), "仓位") {
+		t.Fatalf("并发仓位已满应拒绝, got %v", err)
+	}
+	add := &decision.Decision{Symbol: "BTCUSDT", Action: "add_long", SizePercent: 50, StopLoss: 95}
+	if err := at.checkStageConstraints(add, 1000); err != nil {
+		t.Fatalf("加仓不应受并发仓位限制: %v", err)
+	}
+
+	// 最小持仓30分钟
+	closeLong := &decision.Decision{Symbol: "BTCUSDT", Action: "close_long"}
+	now = now.Add(10 * time.Minute)
+	if err := at.checkStageConstraints(closeLong, 1000); err == nil || !strings.Contains(err.Error(), "最小持仓") {
+		t.Fatalf("未达到最小持仓时间应拒绝平仓, got %v", err)
+	}
+	now = now.Add(25 * time.Minute)
+	if err := at.checkStageConstraints(closeLong, 1000); err != nil {
+		t.Fatalf("达到最小持仓时间后应允许平仓: %v", err)
+	}
+	at.trackExecutedDecision(closeLong, 98, 4, 1)
+	at.recordTradeResult("BTCUSDT", false, -2, -20)
+
+	state := at.constraints.State()
+	if state.TotalTrades != 1 || state.CurrentPositions != 0 || state.DecisionRejections != 4 || state.DailyLossAmount != 2 {
+		t.Fatalf("约束状态错误: %+v", state)
+	}
+
+	// 重启后从存储恢复
+	restored := newStageConstraints(config, store, at.now)
+	if got := restored.State(); got.TotalTrades != 1 || got.DailyLossAmount != 2 || got.DecisionRejections != 4 {
+		t.Errorf("应从存储恢复约束状态, got %+v", got)
+	}
+
+	status := at.GetStatus()["stage_constraints"].(map[string]interface{})
+	if status["enabled"] != true || status["stage"] != int(StageInfant) || status["total_trades"] != 1 {
+		t.Errorf("状态接口应返回学习阶段信息: %+v", status)
+	}
+}
+
+func TestPositionTrackerKeepsHedgeSidesApart(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := newFakePriceFeed(map[string]float64{"BTCUSDT": 100})
+	at, _ := newLimitTestAutoTrader(t, feed, &now)
+	config := AutoTraderConfig{ID: at.id, EnableStageConstraints: true}
+	at.constraints = newStageConstraints(config, &memoryConstraintsStore{}, at.now)
+
+	// 双向持仓：同币种的多空仓位分别跟踪
+	at.syncStageConstraints(&decision.Context{Positions: []decision.PositionInfo{
+		{Symbol: "BTCUSDT", Side: "long", EntryPrice: 100, MarkPrice: 100, Quantity: 2, Leverage: 1, UpdateTime: now.UnixMilli()},
+		{Symbol: "BTCUSDT", Side: "short", EntryPrice: 110, MarkPrice: 100, Quantity: 1, Leverage: 1, UpdateTime: now.Add(-time.Hour).UnixMilli()},
+	}})
+	if count := at.positionTracker.GetPositionCount(); count != 2 {
+		t.Fatalf("多空仓位不应互相覆盖, got %d", count)
+	}
+	long, short := at.positionTracker.GetPosition("BTCUSDT", "long"), at.positionTracker.GetPosition("BTCUSDT", "short")
+	if long == nil || long.OpenPrice != 100 || short == nil || short.OpenPrice != 110 {
+		t.Fatalf("应按方向返回持仓: long=%+v short=%+v", long, short)
+	}
+
+	// 最小持仓时间按各自方向的开仓时间判断
+	now = now.Add(10 * time.Minute)
+	if err := at.checkStageConstraints(&decision.Decision{Symbol: "BTCUSDT", Action: "close_short"}, 1000); err != nil {
+		t.Errorf("空仓已持有超过最小持仓时间，应允许平仓: %v", err)
+	}
+	if err := at.checkStageConstraints(&decision.Decision{Symbol: "BTCUSDT", Action: "close_long"}, 1000); err == nil {
+		t.Error("多仓未达到最小持仓时间，应拒绝平仓")
+	}
+
+	at.trackExecutedDecision(&decision.Decision{Symbol: "BTCUSDT", Action: "close_short"}, 100, 1, 1)
+	if at.positionTracker.GetPosition("BTCUSDT", "short") != nil || at.positionTracker.GetPosition("BTCUSDT", "long") == nil {
+		t.Error("平空仓只应移除空仓的跟踪")
+	}
+}
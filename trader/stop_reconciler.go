@@ -0,0 +1,254 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+)
+
+const (
+	// protectivePriceTolerance 触发价相对误差在此范围内视为未变化（交易所会按精度取整）
+	protectivePriceTolerance = 1e-4
+	// protectiveQuantityTolerance 数量相对误差在此范围内视为未变化（合约张数换算会产生浮点误差）
+	protectiveQuantityTolerance = 1e-6
+)
+
+// protectiveTarget 某个持仓期望的止盈止损
+// 价格为0表示本周期不管理该类型（如跟踪止损模式下的止损、计算失败的持仓），已有条件单保持不变
+type protectiveTarget struct {
+	Symbol       string
+	PositionSide string // LONG/SHORT
+	Quantity     float64
+	StopLoss     float64
+	TakeProfit   float64
+}
+
+// protectiveTargetOf 为持仓创建不修改任何条件单的目标，调用方再填入止盈止损价
+func protectiveTargetOf(pos Position) protectiveTarget {
+	return protectiveTarget{
+		Symbol:       pos.Symbol,
+		PositionSide: pos.PositionSide(),
+		Quantity:     pos.Quantity,
+	}
+}
+
+func protectiveKey(symbol, positionSide string) string {
+	return symbol + "_" + positionSide
+}
+
+// protectiveKindName 条件单类型的中文名称
+func protectiveKindName(kind string) string {
+	if kind == ProtectiveKindStopLoss {
+		return "止损"
+	}
+	return "止盈"
+}
+
+// matches 条件单的触发价和数量是否与期望一致（按仓位平仓的条件单不比较数量）
+func (o ProtectiveOrder) matches(price, quantity float64) bool {
+	if math.Abs(o.TriggerPrice-price) > price*protectivePriceTolerance {
+		return false
+	}
+	return o.ClosePosition || math.Abs(o.Quantity-quantity) <= quantity*protectiveQuantityTolerance
+}
+
+// tighterProtectivePrice 返回两个触发价中先被触发的一个
+// 多仓止损取较高者、止盈取较低者；空仓相反
+func tighterProtectivePrice(kind, positionSide string, a, b float64) float64 {
+	if (kind == ProtectiveKindStopLoss) == (positionSide == "LONG") {
+		return math.Max(a, b)
+	}
+	return math.Min(a, b)
+}
+
+// protectiveReconciler 核对止盈止损单并记录执行日志
+type protectiveReconciler struct {
+	tr Trader
+	// tighten 为true时止损只向保护利润的方向移动、止盈只向靠近当前价的方向移动
+	tighten bool
+
+	lines                           []string
+	kept, placed, cancelled, failed int
+}
+
+// reconcileProtectiveOrders 对比交易所现有的止盈止损单与期望值，只修改有变化的部分：
+//  1. 止损只向保护利润的方向移动，止盈只向靠近当前价的方向移动（与多个条件单并存时最先触发的价格一致）
+//  2. 已有一致的条件单时保持不变，多余的重复单撤销
+//  3. 价格或数量变化时先下新单再撤旧单，避免出现没有保护的空档；
+//     交易所不允许同方向存在两个条件单时（如币安的closePosition单）改为先撤后下
+//  4. 持仓已不存在的条件单视为孤立单撤销
+//
+// targets需包含所有当前持仓（不管理的类型价格为0），否则其条件单会被当作孤立单撤销。
+// 返回写入决策记录的执行日志；只有查询条件单失败时返回错误（此时不做任何修改，避免重复下单）
+func reconcileProtectiveOrders(tr Trader, targets []protectiveTarget) ([]string, error) {
+	existing, err := tr.GetProtectiveOrders("")
+	if err != nil {
+		return nil, fmt.Errorf("查询止盈止损单失败: %w", err)
+	}
+
+	r := &protectiveReconciler{tr: tr, tighten: true}
+	grouped := groupProtectiveOrders(existing)
+	managed := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		managed[protectiveKey(target.Symbol, target.PositionSide)] = true
+		r.reconcile(target, grouped)
+	}
+
+	// 持仓已不存在的条件单（按币种排序，保证日志顺序稳定）
+	var orphans []ProtectiveOrder
+	for _, order := range existing {
+		if !managed[protectiveKey(order.Symbol, order.PositionSide)] {
+			orphans = append(orphans, order)
+		}
+	}
+	sort.SliceStable(orphans, func(i, j int) bool { return orphans[i].Symbol < orphans[j].Symbol })
+	for _, order := range orphans {
+		r.cancel(order, "孤立")
+	}
+
+	return r.result(), nil
+}
+
+// resizeProtectiveOrders 减仓/加仓后按新的持仓数量调整单个持仓的止盈止损单
+// 目标价格直接生效（不做只收紧的限制），价格为0的类型保持不变；不处理其他持仓的条件单
+func resizeProtectiveOrders(tr Trader, target protectiveTarget) ([]string, error) {
+	existing, err := tr.GetProtectiveOrders(target.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("查询止盈止损单失败: %w", err)
+	}
+
+	r := &protectiveReconciler{tr: tr}
+	r.reconcile(target, groupProtectiveOrders(existing))
+	return r.result(), nil
+}
+
+// protectivePrices 持仓当前最先触发的止损价和止盈价（没有对应条件单时为0）
+func protectivePrices(orders []ProtectiveOrder, symbol, positionSide string) (stopLoss, takeProfit float64) {
+	for _, order := range orders {
+		if order.Symbol != symbol || order.PositionSide != positionSide {
+			continue
+		}
+		if order.Kind == ProtectiveKindStopLoss {
+			if stopLoss == 0 {
+				stopLoss = order.TriggerPrice
+			}
+			stopLoss = tighterProtectivePrice(order.Kind, positionSide, stopLoss, order.TriggerPrice)
+		} else {
+			if takeProfit == 0 {
+				takeProfit = order.TriggerPrice
+			}
+			takeProfit = tighterProtectivePrice(order.Kind, positionSide, takeProfit, order.TriggerPrice)
+		}
+	}
+	return stopLoss, takeProfit
+}
+
+// groupProtectiveOrders 按 symbol_positionSide_kind 分组
+func groupProtectiveOrders(orders []ProtectiveOrder) map[string][]ProtectiveOrder {
+	grouped := make(map[string][]ProtectiveOrder)
+	for _, order := range orders {
+		key := protectiveKey(order.Symbol, order.PositionSide) + "_" + order.Kind
+		grouped[key] = append(grouped[key], order)
+	}
+	return grouped
+}
+
+func (r *protectiveReconciler) cancel(order ProtectiveOrder, reason string) bool {
+	if err := r.tr.CancelProtectiveOrder(order.Symbol, order.OrderID); err != nil {
+		r.failed++
+		r.lines = append(r.lines, fmt.Sprintf("❌ %s %s 撤销%s%s单 #%s 失败: %v",
+			order.Symbol, order.PositionSide, reason, protectiveKindName(order.Kind), order.OrderID, err))
+		return false
+	}
+	r.cancelled++
+	r.lines = append(r.lines, fmt.Sprintf("✓ %s %s 撤销%s%s单 #%s @ %.6f",
+		order.Symbol, order.PositionSide, reason, protectiveKindName(order.Kind), order.OrderID, order.TriggerPrice))
+	return true
+}
+
+// reconcile 核对单个持仓的止损和止盈
+func (r *protectiveReconciler) reconcile(target protectiveTarget, grouped map[string][]ProtectiveOrder) {
+	key := protectiveKey(target.Symbol, target.PositionSide)
+	for _, kind := range []string{ProtectiveKindStopLoss, ProtectiveKindTakeProfit} {
+		price := target.StopLoss
+		if kind == ProtectiveKindTakeProfit {
+			price = target.TakeProfit
+		}
+		if price <= 0 {
+			continue
+		}
+		orders := grouped[key+"_"+kind]
+		if r.tighten {
+			for _, order := range orders {
+				price = tighterProtectivePrice(kind, target.PositionSide, price, order.TriggerPrice)
+			}
+		}
+
+		// 保留第一个一致的条件单，其余为重复单或过期单
+		var stale []ProtectiveOrder
+		found := false
+		for _, order := range orders {
+			if !found && order.matches(price, target.Quantity) {
+				found = true
+				continue
+			}
+			stale = append(stale, order)
+		}
+		if found {
+			r.kept++
+			for _, order := range stale {
+				r.cancel(order, "重复")
+			}
+			continue
+		}
+
+		err := placeProtectiveOrder(r.tr, target, kind, price)
+		if err != nil && len(stale) > 0 {
+			log.Printf("⚠️ %s %s 新%s单下单失败，撤销旧单后重试: %v", target.Symbol, target.PositionSide, protectiveKindName(kind), err)
+			remaining := stale[:0]
+			for _, order := range stale {
+				if !r.cancel(order, "过期") {
+					remaining = append(remaining, order)
+				}
+			}
+			stale = remaining
+			err = placeProtectiveOrder(r.tr, target, kind, price)
+		}
+		if err != nil {
+			r.failed++
+			r.lines = append(r.lines, fmt.Sprintf("❌ %s %s 设置%s @ %.6f 失败: %v",
+				target.Symbol, target.PositionSide, protectiveKindName(kind), price, err))
+			continue
+		}
+
+		r.placed++
+		if len(orders) == 0 {
+			r.lines = append(r.lines, fmt.Sprintf("✓ %s %s 新建%s @ %.6f 数量 %.6f",
+				target.Symbol, target.PositionSide, protectiveKindName(kind), price, target.Quantity))
+		} else {
+			r.lines = append(r.lines, fmt.Sprintf("✓ %s %s 更新%s %.6f → %.6f 数量 %.6f",
+				target.Symbol, target.PositionSide, protectiveKindName(kind), orders[0].TriggerPrice, price, target.Quantity))
+		}
+		for _, order := range stale {
+			r.cancel(order, "过期")
+		}
+	}
+}
+
+// result 执行日志（最后一行为汇总）
+func (r *protectiveReconciler) result() []string {
+	summary := fmt.Sprintf("✅ 止盈止损核对完成: 保持 %d, 新下 %d, 撤销 %d", r.kept, r.placed, r.cancelled)
+	if r.failed > 0 {
+		summary = fmt.Sprintf("⚠ 止盈止损核对完成: 保持 %d, 新下 %d, 撤销 %d, 失败 %d", r.kept, r.placed, r.cancelled, r.failed)
+	}
+	return append(r.lines, summary)
+}
+
+// placeProtectiveOrder 按类型下止损或止盈单
+func placeProtectiveOrder(tr Trader, target protectiveTarget, kind string, price float64) error {
+	if kind == ProtectiveKindStopLoss {
+		return tr.SetStopLoss(target.Symbol, target.PositionSide, target.Quantity, price)
+	}
+	return tr.SetTakeProfit(target.Symbol, target.PositionSide, target.Quantity, price)
+}
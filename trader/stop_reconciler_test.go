@@ -0,0 +1,108 @@
+package trader
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// singleStopTrader 模拟同一持仓方向只允许一个止损单的交易所（如币安的closePosition条件单）
+type singleStopTrader struct {
+	Trader
+}
+
+func (t singleStopTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	orders, err := t.GetProtectiveOrders(symbol)
+	if err != nil {
+		return err
+	}
+	for _, order := range orders {
+		if order.PositionSide == positionSide && order.Kind == ProtectiveKindStopLoss {
+			return errors.New("stop order already exists")
+		}
+	}
+	return t.Trader.SetStopLoss(symbol, positionSide, quantity, stopPrice)
+}
+
+func protectiveOrdersOf(t *testing.T, tr Trader, symbol, kind string) []ProtectiveOrder {
+	t.Helper()
+	orders, err := tr.GetProtectiveOrders(symbol)
+	if err != nil {
+		t.Fatalf("查询止盈止损单失败: %v", err)
+	}
+	var result []ProtectiveOrder
+	for _, order := range orders {
+		if order.Kind == kind {
+			result = append(result, order)
+		}
+	}
+	return result
+}
+
+func TestReconcileProtectiveOrders(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"SOLUSDT": 100})
+	pt := newTestPaperTrader(t, feed, nil)
+	if _, err := pt.OpenLong("SOLUSDT", 2, 5); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	// 旧逻辑每个周期重复下单留下的重复止损单
+	for i := 0; i < 3; i++ {
+		if err := pt.SetStopLoss("SOLUSDT", "LONG", 2, 95); err != nil {
+			t.Fatalf("设置止损失败: %v", err)
+		}
+	}
+	if err := pt.SetTakeProfit("SOLUSDT", "LONG", 2, 120); err != nil {
+		t.Fatalf("设置止盈失败: %v", err)
+	}
+
+	// 止损一致时只保留一个；止盈价为0表示不管理，保持原样
+	target := protectiveTarget{Symbol: "SOLUSDT", PositionSide: "LONG", Quantity: 2, StopLoss: 95}
+	lines, err := reconcileProtectiveOrders(pt, []protectiveTarget{target})
+	if err != nil {
+		t.Fatalf("核对失败: %v", err)
+	}
+	if stops := protectiveOrdersOf(t, pt, "SOLUSDT", ProtectiveKindStopLoss); len(stops) != 1 {
+		t.Errorf("重复止损单应只保留一个, got %+v", stops)
+	}
+	if tps := protectiveOrdersOf(t, pt, "SOLUSDT", ProtectiveKindTakeProfit); len(tps) != 1 || !almostEqual(tps[0].TriggerPrice, 120) {
+		t.Errorf("不管理的止盈单应保持不变, got %+v", tps)
+	}
+	if summary := lines[len(lines)-1]; !strings.Contains(summary, "保持 1, 新下 0, 撤销 2") {
+		t.Errorf("执行日志汇总错误: %v", lines)
+	}
+
+	// 数量变化（加仓后）时替换止损单
+	if _, err := pt.OpenLong("SOLUSDT", 1, 5); err != nil {
+		t.Fatalf("加仓失败: %v", err)
+	}
+	target.Quantity = 3
+	if _, err := reconcileProtectiveOrders(pt, []protectiveTarget{target}); err != nil {
+		t.Fatalf("核对失败: %v", err)
+	}
+	if stops := protectiveOrdersOf(t, pt, "SOLUSDT", ProtectiveKindStopLoss); len(stops) != 1 || !almostEqual(stops[0].Quantity, 3) {
+		t.Errorf("止损单数量应更新为3, got %+v", stops)
+	}
+}
+
+func TestReconcileProtectiveOrdersCancelsBeforeRetry(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"SOLUSDT": 100})
+	pt := newTestPaperTrader(t, feed, nil)
+	if _, err := pt.OpenLong("SOLUSDT", 2, 5); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	if err := pt.SetStopLoss("SOLUSDT", "LONG", 2, 95); err != nil {
+		t.Fatalf("设置止损失败: %v", err)
+	}
+
+	// 交易所拒绝第二个止损单时先撤销旧单再重试
+	tr := singleStopTrader{pt}
+	target := protectiveTarget{Symbol: "SOLUSDT", PositionSide: "LONG", Quantity: 2, StopLoss: 97}
+	lines, err := reconcileProtectiveOrders(tr, []protectiveTarget{target})
+	if err != nil {
+		t.Fatalf("核对失败: %v", err)
+	}
+	stops := protectiveOrdersOf(t, pt, "SOLUSDT", ProtectiveKindStopLoss)
+	if len(stops) != 1 || !almostEqual(stops[0].TriggerPrice, 97) {
+		t.Errorf("止损单应替换为97, got %+v (日志: %v)", stops, lines)
+	}
+}
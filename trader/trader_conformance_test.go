@@ -0,0 +1,301 @@
+package trader
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/sonirico/go-hyperliquid"
+)
+
+// 所有适配器都必须实现Trader接口
+var (
+	_ Trader = (*FuturesTrader)(nil)
+	_ Trader = (*AsterTrader)(nil)
+	_ Trader = (*HyperliquidTrader)(nil)
+	_ Trader = (*OKXTrader)(nil)
+	_ Trader = (*PaperTrader)(nil)
+)
+
+// mustUnmarshal 解析测试用的交易所原始响应
+func mustUnmarshal(t *testing.T, data string, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		t.Fatalf("解析测试数据失败: %v", err)
+	}
+}
+
+// checkShortPosition 检查各交易所的空仓都被转换为统一格式（数量为正、方向为short）
+func checkShortPosition(t *testing.T, exchange string, pos Position, wantQty, wantEntry, wantMark float64, wantLeverage int) {
+	t.Helper()
+	if pos.Symbol != "ETHUSDT" || pos.Side != "short" || pos.Key() != "ETHUSDT_short" || pos.PositionSide() != "SHORT" {
+		t.Errorf("%s: 持仓标识错误: %+v", exchange, pos)
+	}
+	if !almostEqual(pos.Quantity, wantQty) {
+		t.Errorf("%s: 空仓数量应为正数 %.4f, got %.4f", exchange, wantQty, pos.Quantity)
+	}
+	if !almostEqual(pos.EntryPrice, wantEntry) || !almostEqual(pos.MarkPrice, wantMark) {
+		t.Errorf("%s: 价格错误: %+v", exchange, pos)
+	}
+	if pos.Leverage != wantLeverage {
+		t.Errorf("%s: 杠杆应为 %d, got %d", exchange, wantLeverage, pos.Leverage)
+	}
+}
+
+func TestBinanceConversions(t *testing.T) {
+	var account futures.Account
+	mustUnmarshal(t, `{"totalWalletBalance":"1000.5","totalUnrealizedProfit":"-20.5","availableBalance":"700"}`, &account)
+	balance := binanceBalance(&account)
+	if !almostEqual(balance.TotalEquity(), 980) || !almostEqual(balance.UsedMargin(), 280) {
+		t.Errorf("余额转换错误: %+v", balance)
+	}
+
+	var risks []*futures.PositionRisk
+	mustUnmarshal(t, `[
+		{"symbol":"BTCUSDT","positionAmt":"0.000","entryPrice":"0","markPrice":"100000","leverage":"10"},
+		{"symbol":"ETHUSDT","positionAmt":"-1.5","entryPrice":"3000","markPrice":"2900","unRealizedProfit":"150","leverage":"5","liquidationPrice":"3500","marginType":"isolated"}
+	]`, &risks)
+	if _, ok := binancePosition(risks[0]); ok {
+		t.Error("空仓位应被跳过")
+	}
+	pos, ok := binancePosition(risks[1])
+	if !ok {
+		t.Fatal("应解析出空仓")
+	}
+	checkShortPosition(t, "binance", pos, 1.5, 3000, 2900, 5)
+	if pos.MarginMode != "isolated" || !almostEqual(pos.LiquidationPrice, 3500) || !almostEqual(pos.UnrealizedProfit, 150) {
+		t.Errorf("持仓附加字段错误: %+v", pos)
+	}
+
+	var order futures.Order
+	mustUnmarshal(t, `{"orderId":123456789012,"symbol":"ETHUSDT","status":"PARTIALLY_FILLED","type":"LIMIT","side":"SELL","positionSide":"SHORT","price":"3000","origQty":"2","executedQty":"0.5","avgPrice":"3000.5"}`, &order)
+	result := binanceOrderResult(&order)
+	if result.OrderID != "123456789012" || result.Status != OrderStatusPartiallyFilled || !almostEqual(result.ExecutedQty, 0.5) || !almostEqual(result.AvgPrice, 3000.5) {
+		t.Errorf("订单转换错误: %+v", result)
+	}
+
+	var trade futures.AccountTrade
+	mustUnmarshal(t, `{"id":7,"orderId":123456789012,"symbol":"ETHUSDT","side":"SELL","positionSide":"SHORT","price":"3000","qty":"0.5","commission":"0.6","commissionAsset":"USDT","maker":true,"time":1700000000000}`, &trade)
+	fill := binanceFill(&trade)
+	if fill.OrderID != "123456789012" || fill.Side != "sell" || fill.Role != "maker" || !almostEqual(fill.Fee, 0.6) {
+		t.Errorf("成交转换错误: %+v", fill)
+	}
+
+	var income futures.IncomeHistory
+	mustUnmarshal(t, `{"symbol":"ETHUSDT","incomeType":"FUNDING_FEE","income":"-0.4512","asset":"USDT","time":1700006400000,"tranId":99}`, &income)
+	payment := binanceFundingPayment(&income)
+	if payment.Symbol != "ETHUSDT" || !almostEqual(payment.Amount, -0.4512) || payment.Timestamp != 1700006400000 {
+		t.Errorf("资金费转换错误: %+v", payment)
+	}
+}
+
+func TestAsterConversions(t *testing.T) {
+	balance, err := parseAsterBalance([]byte(`[
+		{"asset":"BNB","balance":"5","availableBalance":"5","crossUnPnl":"0"},
+		{"asset":"USDT","balance":"500","availableBalance":"320.5","crossUnPnl":"-10"}
+	]`))
+	if err != nil {
+		t.Fatalf("解析余额失败: %v", err)
+	}
+	if !almostEqual(balance.WalletBalance, 500) || !almostEqual(balance.TotalEquity(), 490) || !almostEqual(balance.AvailableBalance, 320.5) {
+		t.Errorf("余额转换错误: %+v", balance)
+	}
+
+	positions, err := parseAsterPositions([]byte(`[
+		{"symbol":"BTCUSDT","positionAmt":"0","entryPrice":"0","markPrice":"0","leverage":"20"},
+		{"symbol":"ETHUSDT","positionAmt":"-2.25","entryPrice":"3000","markPrice":"3100","unRealizedProfit":"-225","leverage":"3","liquidationPrice":"","marginType":"cross"}
+	]`))
+	if err != nil {
+		t.Fatalf("解析持仓失败: %v", err)
+	}
+	if len(positions) != 1 {
+		t.Fatalf("应只解析出1个持仓, got %d", len(positions))
+	}
+	checkShortPosition(t, "aster", positions[0], 2.25, 3000, 3100, 3)
+	if positions[0].LiquidationPrice != 0 || positions[0].MarginMode != "cross" {
+		t.Errorf("缺失的强平价应为0: %+v", positions[0])
+	}
+
+	order, err := asterOrderResult("ETHUSDT", []byte(`{"orderId":9007199254740993,"status":"FILLED","type":"MARKET","side":"SELL","origQty":"2.25","executedQty":"2.25","avgPrice":"2999.5"}`))
+	if err != nil {
+		t.Fatalf("解析订单失败: %v", err)
+	}
+	// 大整数订单ID不能因为float64精度丢失而改变
+	if order.OrderID != "9007199254740993" || order.Symbol != "ETHUSDT" || order.Status != OrderStatusFilled || !almostEqual(order.AvgPrice, 2999.5) {
+		t.Errorf("订单转换错误: %+v", order)
+	}
+
+	fills, err := parseAsterFills([]byte(`[{"id":1,"orderId":9007199254740993,"symbol":"ETHUSDT","side":"SELL","price":"2999.5","qty":"2.25","commission":"2.7","commissionAsset":"USDT","maker":false,"time":1700000000000}]`))
+	if err != nil || len(fills) != 1 {
+		t.Fatalf("解析成交记录失败: %v %v", fills, err)
+	}
+	if fills[0].OrderID != "9007199254740993" || fills[0].Side != "sell" || fills[0].Role != "taker" || !almostEqual(fills[0].Quantity, 2.25) {
+		t.Errorf("成交转换错误: %+v", fills[0])
+	}
+
+	payments, err := parseAsterFundingPayments([]byte(`[
+		{"symbol":"ETHUSDT","incomeType":"FUNDING_FEE","income":"0.75","asset":"USDT","time":1700006400000},
+		{"symbol":"ETHUSDT","incomeType":"COMMISSION","income":"-2.7","asset":"USDT","time":1700000000000}
+	]`))
+	if err != nil || len(payments) != 1 {
+		t.Fatalf("应只解析出资金费记录: %v %v", payments, err)
+	}
+	if payments[0].Symbol != "ETHUSDT" || !almostEqual(payments[0].Amount, 0.75) || payments[0].Timestamp != 1700006400000 {
+		t.Errorf("资金费转换错误: %+v", payments[0])
+	}
+
+	transfers, err := parseAsterTransfers([]byte(`[
+		{"symbol":"","incomeType":"TRANSFER","income":"-200","asset":"USDT","time":1700010000000},
+		{"symbol":"","incomeType":"TRANSFER","income":"1","asset":"BNB","time":1700010000000},
+		{"symbol":"ETHUSDT","incomeType":"REALIZED_PNL","income":"30","asset":"USDT","time":1700000000000}
+	]`))
+	if err != nil || len(transfers) != 1 {
+		t.Fatalf("应只解析出USDT划转记录: %v %v", transfers, err)
+	}
+	if !almostEqual(transfers[0].Amount, -200) || transfers[0].Timestamp != 1700010000000 {
+		t.Errorf("划转记录转换错误: %+v", transfers[0])
+	}
+}
+
+func TestHyperliquidConversions(t *testing.T) {
+	var state hyperliquid.UserState
+	mustUnmarshal(t, `{
+		"marginSummary":{"accountValue":"1050","totalMarginUsed":"300"},
+		"assetPositions":[
+			{"position":{"coin":"BTC","szi":"0","positionValue":"0","unrealizedPnl":"0","leverage":{"type":"cross","value":10}}},
+			{"position":{"coin":"ETH","szi":"-0.5","entryPx":"3000","positionValue":"1450","unrealizedPnl":"50","liquidationPx":"3600","leverage":{"type":"isolated","value":5}}}
+		]
+	}`, &state)
+
+	balance := hyperliquidBalance(&state)
+	// 钱包余额不含未实现盈亏，净值与accountValue一致
+	if !almostEqual(balance.WalletBalance, 1000) || !almostEqual(balance.TotalEquity(), 1050) || !almostEqual(balance.AvailableBalance, 750) {
+		t.Errorf("余额转换错误: %+v", balance)
+	}
+
+	if _, ok := hyperliquidPosition(state.AssetPositions[0].Position); ok {
+		t.Error("空仓位应被跳过")
+	}
+	pos, ok := hyperliquidPosition(state.AssetPositions[1].Position)
+	if !ok {
+		t.Fatal("应解析出空仓")
+	}
+	// 标记价格 = positionValue / |szi|
+	checkShortPosition(t, "hyperliquid", pos, 0.5, 3000, 2900, 5)
+	if pos.MarginMode != "isolated" || !almostEqual(pos.LiquidationPrice, 3600) {
+		t.Errorf("持仓附加字段错误: %+v", pos)
+	}
+
+	var status hyperliquid.OrderStatus
+	mustUnmarshal(t, `{"filled":{"oid":42,"totalSz":"0.5","avgPx":"2999.9"}}`, &status)
+	order, err := hyperliquidOrderResult("ETHUSDT", status)
+	if err != nil {
+		t.Fatalf("转换订单失败: %v", err)
+	}
+	// 成交均价和数量是字符串，需要转换为数值
+	if order.OrderID != "42" || order.Status != OrderStatusFilled || !almostEqual(order.AvgPrice, 2999.9) || !almostEqual(order.ExecutedQty, 0.5) {
+		t.Errorf("订单转换错误: %+v", order)
+	}
+
+	fill := hyperliquidFill(hyperliquid.Fill{Coin: "ETH", Oid: 42, Tid: 7, Side: "A", Price: "2999.9", Size: "0.5", Fee: "0.3", FeeToken: "USDC", Crossed: true})
+	if fill.Symbol != "ETHUSDT" || fill.OrderID != "42" || fill.Side != "sell" || fill.Role != "taker" || !almostEqual(fill.Fee, 0.3) {
+		t.Errorf("成交转换错误: %+v", fill)
+	}
+
+	var funding hyperliquidFunding
+	mustUnmarshal(t, `{"time":1700006400000,"hash":"0x0","delta":{"type":"funding","coin":"ETH","usdc":"-0.1234","szi":"0.5","fundingRate":"0.0000125"}}`, &funding)
+	payment := hyperliquidFundingPayment(funding)
+	if payment.Symbol != "ETHUSDT" || !almostEqual(payment.Amount, -0.1234) || !almostEqual(payment.Rate, 0.0000125) || payment.Timestamp != 1700006400000 {
+		t.Errorf("资金费转换错误: %+v", payment)
+	}
+}
+
+func TestOKXConversions(t *testing.T) {
+	okx := &OKXTrader{}
+	balance := okx.parseBalance(map[string]interface{}{
+		"data": []interface{}{
+			map[string]interface{}{"totalEq": "1020", "upl": "20", "adjEq": "800", "isoEq": "0"},
+		},
+	})
+	if !almostEqual(balance.WalletBalance, 1000) || !almostEqual(balance.TotalEquity(), 1020) || !almostEqual(balance.AvailableBalance, 800) {
+		t.Errorf("余额转换错误: %+v", balance)
+	}
+
+	// OKX持仓数量是合约张数，ETH合约面值0.1，15张 = 1.5 ETH
+	item := map[string]interface{}{
+		"instId": "ETH-USDT-SWAP", "posSide": "short", "pos": "15", "avgPx": "3000", "markPx": "2900",
+		"upl": "150", "lever": "5", "liqPx": "3500", "mgnMode": "cross",
+	}
+	pos, ok := okxPosition(item, 0.1)
+	if !ok {
+		t.Fatal("应解析出空仓")
+	}
+	checkShortPosition(t, "okx", pos, 1.5, 3000, 2900, 5)
+
+	// 单向持仓模式下由张数正负判断方向
+	netItem := map[string]interface{}{"instId": "ETH-USDT-SWAP", "posSide": "net", "pos": "-15", "avgPx": "3000", "markPx": "2900", "lever": "5"}
+	netPos, ok := okxPosition(netItem, 0.1)
+	if !ok {
+		t.Fatal("应解析出单向持仓模式的空仓")
+	}
+	checkShortPosition(t, "okx net", netPos, 1.5, 3000, 2900, 5)
+
+	if _, ok := okxPosition(map[string]interface{}{"instId": "BTC-USDT-SWAP", "pos": "0"}, 0.01); ok {
+		t.Error("空仓位应被跳过")
+	}
+
+	order := okxOrderResult(map[string]interface{}{
+		"ordId": "612", "instId": "ETH-USDT-SWAP", "state": "partially_filled", "ordType": "limit",
+		"side": "sell", "posSide": "short", "px": "3000", "sz": "20", "accFillSz": "5", "avgPx": "3000", "fee": "-0.15",
+	}, 0.1)
+	if order.OrderID != "612" || order.Symbol != "ETHUSDT" || order.Status != OrderStatusPartiallyFilled {
+		t.Errorf("订单标识错误: %+v", order)
+	}
+	if !almostEqual(order.Quantity, 2) || !almostEqual(order.ExecutedQty, 0.5) || !almostEqual(order.Fee, 0.15) {
+		t.Errorf("订单数量应换算为币数量、手续费为正数: %+v", order)
+	}
+
+	fill := okxFill(map[string]interface{}{
+		"instId": "ETH-USDT-SWAP", "ordId": "612", "tradeId": "88", "side": "sell", "posSide": "short",
+		"fillPx": "3000", "fillSz": "5", "fee": "-0.15", "feeCcy": "USDT", "execType": "M", "ts": "1700000000000",
+	}, 0.1)
+	if fill.OrderID != "612" || fill.Side != "sell" || fill.Role != "maker" || !almostEqual(fill.Quantity, 0.5) || !almostEqual(fill.Fee, 0.15) || fill.Timestamp != 1700000000000 {
+		t.Errorf("成交转换错误: %+v", fill)
+	}
+
+	payment := okxFundingPayment(map[string]interface{}{
+		"billId": "1", "instId": "ETH-USDT-SWAP", "type": "8", "subType": "174", "balChg": "0.32", "ts": "1700006400000",
+	})
+	if payment.Symbol != "ETHUSDT" || !almostEqual(payment.Amount, 0.32) || payment.Timestamp != 1700006400000 {
+		t.Errorf("资金费转换错误: %+v", payment)
+	}
+}
+
+func TestPaperTraderConformance(t *testing.T) {
+	feed := newFakePriceFeed(map[string]float64{"ETHUSDT": 3000})
+	pt := newTestPaperTrader(t, feed, nil)
+
+	order, err := pt.OpenShort("ETHUSDT", 0.1, 5)
+	if err != nil {
+		t.Fatalf("开空失败: %v", err)
+	}
+	if order.OrderID == "" || order.Status != OrderStatusFilled || order.Side != "SELL" || order.PositionSide != "SHORT" || !almostEqual(order.ExecutedQty, 0.1) {
+		t.Errorf("订单转换错误: %+v", order)
+	}
+
+	positions, err := pt.GetPositions()
+	if err != nil || len(positions) != 1 {
+		t.Fatalf("获取持仓失败: %v %v", positions, err)
+	}
+	checkShortPosition(t, "paper", positions[0], 0.1, 3000*0.999, 3000, 5)
+
+	balance, err := pt.GetBalance()
+	if err != nil {
+		t.Fatalf("获取余额失败: %v", err)
+	}
+	// 模拟盘按开仓价锁定保证金
+	wantMargin := positions[0].Quantity * positions[0].EntryPrice / float64(positions[0].Leverage)
+	if !almostEqual(balance.UsedMargin(), wantMargin) {
+		t.Errorf("已用保证金应等于持仓保证金: %.4f vs %.4f", balance.UsedMargin(), wantMargin)
+	}
+}
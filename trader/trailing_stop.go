@@ -0,0 +1,345 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/market"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 回调比例范围按币安的限制（0.1%~10%）统一校验，保证各交易所行为一致
+	minTrailingCallbackRate = 0.001
+	maxTrailingCallbackRate = 0.1
+
+	// defaultTrailingCallbackRate 无法根据止损价推算时使用的回调比例
+	defaultTrailingCallbackRate = 0.01
+
+	// trailingStopCheckInterval 本地模拟跟踪止损的检查间隔
+	trailingStopCheckInterval = time.Second
+
+	// trailingStopSaveInterval 仅极值价变化时的最短保存间隔（设置、取消和触发立即保存）
+	trailingStopSaveInterval = 10 * time.Second
+)
+
+// TrailingStopStore 本地模拟跟踪止损持久化接口（由config.Database实现）
+// 跟踪止损只在本地监控，重启后需要恢复（含激活状态和极值价）才能继续保护持仓
+type TrailingStopStore interface {
+	GetTrailingStopState(traderID string) (string, error)
+	SaveTrailingStopState(traderID string, state string) error
+}
+
+// trailingStopEmulated 在本地模拟跟踪止损的交易器
+type trailingStopEmulated interface {
+	trailingStops() *trailingStopEmulator
+}
+
+// validateTrailingStop 校验跟踪止损参数
+// callbackRate为回调比例（0.01表示1%），activationPrice为激活价（0表示立即激活）
+func validateTrailingStop(positionSide string, quantity, callbackRate, activationPrice float64) error {
+	if !strings.EqualFold(positionSide, "LONG") && !strings.EqualFold(positionSide, "SHORT") {
+		return fmt.Errorf("无效的持仓方向: %s", positionSide)
+	}
+	if quantity <= 0 {
+		return fmt.Errorf("跟踪止损数量必须大于0")
+	}
+	if callbackRate < minTrailingCallbackRate || callbackRate > maxTrailingCallbackRate {
+		return fmt.Errorf("跟踪止损回调比例必须在 %.1f%% ~ %.0f%% 之间: %.4f%%",
+			minTrailingCallbackRate*100, maxTrailingCallbackRate*100, callbackRate*100)
+	}
+	if activationPrice < 0 {
+		return fmt.Errorf("跟踪止损激活价不能为负数")
+	}
+	return nil
+}
+
+// trailingStopOrder 本地监控的跟踪止损
+type trailingStopOrder struct {
+	Symbol          string  `json:"symbol"`
+	PositionSide    string  `json:"position_side"` // LONG/SHORT
+	Quantity        float64 `json:"quantity"`
+	CallbackRate    float64 `json:"callback_rate"`
+	ActivationPrice float64 `json:"activation_price"` // 0表示立即激活
+	Activated       bool    `json:"activated"`
+	ExtremePrice    float64 `json:"extreme_price"` // 激活后多仓的最高价 / 空仓的最低价
+}
+
+// update 用最新价格更新跟踪状态，返回是否触发平仓
+// 多仓：价格达到激活价后记录最高价，从最高价回撤超过回调比例时触发；空仓反之
+func (o *trailingStopOrder) update(price float64) bool {
+	long := o.PositionSide == "LONG"
+	if !o.Activated {
+		if o.ActivationPrice > 0 && ((long && price < o.ActivationPrice) || (!long && price > o.ActivationPrice)) {
+			return false
+		}
+		o.Activated = true
+		o.ExtremePrice = price
+	}
+
+	if long {
+		o.ExtremePrice = math.Max(o.ExtremePrice, price)
+		return price <= o.ExtremePrice*(1-o.CallbackRate)
+	}
+	o.ExtremePrice = math.Min(o.ExtremePrice, price)
+	return price >= o.ExtremePrice*(1+o.CallbackRate)
+}
+
+// trailingStopEmulator 交易所不支持跟踪止损时在本地模拟
+// 价格优先取自WebSocket实时行情，不可用时回退到交易所REST接口；
+// 触发后交给AutoTrader在交易执行锁内平仓并记录交易结果（未接管时直接通过交易器市价平仓）
+type trailingStopEmulator struct {
+	exchange string
+	trader   Trader
+	price    func(symbol string) (float64, error)
+	interval time.Duration
+
+	mu        sync.Mutex
+	orders    map[string]*trailingStopOrder // key: symbol_positionSide
+	running   bool
+	onTrigger func(order trailingStopOrder, price float64) error // 触发处理，返回错误时保留跟踪止损下次重试
+	persist   func(orders []trailingStopOrder)                   // 保存全部跟踪止损
+	lastSaved time.Time
+
+	saveMu sync.Mutex // 串行保存，保证最后写入的是最新快照
+}
+
+func newTrailingStopEmulator(exchange string, tr Trader) *trailingStopEmulator {
+	return &trailingStopEmulator{
+		exchange: exchange,
+		trader:   tr,
+		price: func(symbol string) (float64, error) {
+			if price, err := market.LatestPrice(symbol); err == nil {
+				return price, nil
+			}
+			return tr.GetMarketPrice(symbol)
+		},
+		interval: trailingStopCheckInterval,
+		orders:   make(map[string]*trailingStopOrder),
+	}
+}
+
+func trailingStopKey(symbol, positionSide string) string {
+	return symbol + "_" + positionSide
+}
+
+// attach 由AutoTrader接管触发平仓和持久化
+func (e *trailingStopEmulator) attach(onTrigger func(order trailingStopOrder, price float64) error, persist func(orders []trailingStopOrder)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onTrigger = onTrigger
+	e.persist = persist
+}
+
+// restore 恢复重启前保存的跟踪止损并启动监控（已存在的同方向跟踪止损不覆盖）
+func (e *trailingStopEmulator) restore(orders []trailingStopOrder) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	restored := 0
+	for _, order := range orders {
+		if validateTrailingStop(order.PositionSide, order.Quantity, order.CallbackRate, order.ActivationPrice) != nil {
+			continue
+		}
+		order.PositionSide = strings.ToUpper(order.PositionSide)
+		key := trailingStopKey(order.Symbol, order.PositionSide)
+		if _, exists := e.orders[key]; exists {
+			continue
+		}
+		e.orders[key] = &order
+		restored++
+	}
+	if restored > 0 && !e.running {
+		e.running = true
+		go e.run()
+	}
+	return restored
+}
+
+// snapshot 返回全部跟踪止损的副本（按key排序）
+func (e *trailingStopEmulator) snapshot() []trailingStopOrder {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	keys := make([]string, 0, len(e.orders))
+	for key := range e.orders {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	orders := make([]trailingStopOrder, 0, len(keys))
+	for _, key := range keys {
+		orders = append(orders, *e.orders[key])
+	}
+	return orders
+}
+
+// save 保存全部跟踪止损（未接管持久化时忽略）
+func (e *trailingStopEmulator) save() {
+	e.mu.Lock()
+	persist := e.persist
+	e.lastSaved = time.Now()
+	e.mu.Unlock()
+	if persist == nil {
+		return
+	}
+	e.saveMu.Lock()
+	defer e.saveMu.Unlock()
+	persist(e.snapshot())
+}
+
+// Set 设置（或替换）某个持仓方向的跟踪止损，并在需要时启动监控
+func (e *trailingStopEmulator) Set(symbol, positionSide string, quantity, callbackRate, activationPrice float64) error {
+	if err := validateTrailingStop(positionSide, quantity, callbackRate, activationPrice); err != nil {
+		return err
+	}
+	positionSide = strings.ToUpper(positionSide)
+	defer e.save()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.orders[trailingStopKey(symbol, positionSide)] = &trailingStopOrder{
+		Symbol:          symbol,
+		PositionSide:    positionSide,
+		Quantity:        quantity,
+		CallbackRate:    callbackRate,
+		ActivationPrice: activationPrice,
+	}
+	if !e.running {
+		e.running = true
+		go e.run()
+	}
+
+	log.Printf("  [%s] 本地跟踪止损设置: %s %s 回调%.2f%%, 激活价 %.4f", e.exchange, symbol, positionSide, callbackRate*100, activationPrice)
+	return nil
+}
+
+// Cancel 取消该币种的所有本地跟踪止损
+func (e *trailingStopEmulator) Cancel(symbol string) {
+	e.mu.Lock()
+	canceled := false
+	for key, order := range e.orders {
+		if order.Symbol == symbol {
+			delete(e.orders, key)
+			canceled = true
+		}
+	}
+	e.mu.Unlock()
+	if canceled {
+		e.save()
+	}
+}
+
+// Get 返回某持仓方向的跟踪止损副本
+func (e *trailingStopEmulator) Get(symbol, positionSide string) (trailingStopOrder, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	order, ok := e.orders[trailingStopKey(symbol, strings.ToUpper(positionSide))]
+	if !ok {
+		return trailingStopOrder{}, false
+	}
+	return *order, true
+}
+
+// run 定时检查，没有剩余跟踪止损时退出
+func (e *trailingStopEmulator) run() {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !e.check() {
+			return
+		}
+	}
+}
+
+// check 用最新价格检查所有跟踪止损，触发的按市价平仓；返回是否还有待监控的跟踪止损
+func (e *trailingStopEmulator) check() bool {
+	e.mu.Lock()
+	orders := make([]*trailingStopOrder, 0, len(e.orders))
+	for _, order := range e.orders {
+		orders = append(orders, order)
+	}
+	e.mu.Unlock()
+
+	changed, moved := false, false
+	for _, order := range orders {
+		price, err := e.price(order.Symbol)
+		if err != nil {
+			log.Printf("⚠️ [%s] 跟踪止损获取 %s 价格失败: %v", e.exchange, order.Symbol, err)
+			continue
+		}
+
+		// 检查期间可能被取消或替换，只处理仍然有效的跟踪止损
+		key := trailingStopKey(order.Symbol, order.PositionSide)
+		e.mu.Lock()
+		current := e.orders[key] == order
+		before := *order
+		triggered := current && order.update(price)
+		if current && (order.Activated != before.Activated || order.ExtremePrice != before.ExtremePrice) {
+			moved = true
+		}
+		if triggered {
+			delete(e.orders, key)
+		}
+		e.mu.Unlock()
+
+		if triggered {
+			changed = true
+			if err := e.trigger(order, price); err != nil {
+				e.rearm(key, order)
+			}
+		}
+	}
+
+	e.mu.Lock()
+	due := time.Since(e.lastSaved) >= trailingStopSaveInterval
+	e.mu.Unlock()
+	if changed || (moved && due) {
+		e.save()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.orders) == 0 {
+		e.running = false
+		return false
+	}
+	return true
+}
+
+// rearm 平仓失败时恢复跟踪止损，下次检查重试（期间已被替换的不恢复）
+func (e *trailingStopEmulator) rearm(key string, order *trailingStopOrder) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, exists := e.orders[key]; !exists {
+		e.orders[key] = order
+	}
+}
+
+// trigger 跟踪止损触发，市价平仓（由AutoTrader接管时交给其处理），返回错误时保留跟踪止损
+func (e *trailingStopEmulator) trigger(order *trailingStopOrder, price float64) error {
+	log.Printf("🎯 [%s] 跟踪止损触发: %s %s 价格 %.4f 自极值 %.4f 回撤超过 %.2f%%",
+		e.exchange, order.Symbol, order.PositionSide, price, order.ExtremePrice, order.CallbackRate*100)
+
+	e.mu.Lock()
+	onTrigger := e.onTrigger
+	e.mu.Unlock()
+	if onTrigger != nil {
+		if err := onTrigger(*order, price); err != nil {
+			log.Printf("❌ [%s] 跟踪止损平仓失败 (%s %s)，下次检查重试: %v", e.exchange, order.Symbol, order.PositionSide, err)
+			return err
+		}
+		return nil
+	}
+
+	var err error
+	if order.PositionSide == "LONG" {
+		_, err = e.trader.CloseLong(order.Symbol, order.Quantity)
+	} else {
+		_, err = e.trader.CloseShort(order.Symbol, order.Quantity)
+	}
+	if err != nil {
+		log.Printf("❌ [%s] 跟踪止损平仓失败 (%s %s): %v", e.exchange, order.Symbol, order.PositionSide, err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package trader
+
+import "testing"
+
+func TestTrailingStopOrderUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  trailingStopOrder
+		prices []float64
+		want   int // 第几个价格触发，-1表示不触发
+	}{
+		{
+			name:   "多仓立即激活",
+			order:  trailingStopOrder{PositionSide: "LONG", CallbackRate: 0.02},
+			prices: []float64{100, 105, 103, 102.8},
+			want:   3,
+		},
+		{
+			name:   "多仓未到激活价不触发",
+			order:  trailingStopOrder{PositionSide: "LONG", CallbackRate: 0.02, ActivationPrice: 110},
+			prices: []float64{100, 90, 80},
+			want:   -1,
+		},
+		{
+			name:   "空仓激活后反弹触发",
+			order:  trailingStopOrder{PositionSide: "SHORT", CallbackRate: 0.01, ActivationPrice: 95},
+			prices: []float64{100, 96, 94, 90, 90.5, 91},
+			want:   5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := tt.order
+			got := -1
+			for i, price := range tt.prices {
+				if order.update(price) {
+					got = i
+					break
+				}
+			}
+			if got != tt.want {
+				t.Errorf("触发位置 = %d, want %d (极值 %.4f)", got, tt.want, order.ExtremePrice)
+			}
+		})
+	}
+}
+
+func TestValidateTrailingStop(t *testing.T) {
+	if err := validateTrailingStop("LONG", 0.01, 0.01, 0); err != nil {
+		t.Errorf("合法参数不应报错: %v", err)
+	}
+	for _, tc := range []struct {
+		side                       string
+		quantity, rate, activation float64
+	}{
+		{"BOTH", 0.01, 0.01, 0},
+		{"SHORT", 0, 0.01, 0},
+		{"SHORT", 0.01, 0.0005, 0},
+		{"SHORT", 0.01, 0.2, 0},
+		{"LONG", 0.01, 0.01, -1},
+	} {
+		if err := validateTrailingStop(tc.side, tc.quantity, tc.rate, tc.activation); err == nil {
+			t.Errorf("非法参数应报错: %+v", tc)
+		}
+	}
+}
+
+func TestTrailingCallbackRate(t *testing.T) {
+	for _, tc := range []struct {
+		current, stop, want float64
+	}{
+		{50000, 49000, 0.02},
+		{50000, 49990, minTrailingCallbackRate},
+		{50000, 30000, maxTrailingCallbackRate},
+		{50000, 0, defaultTrailingCallbackRate},
+	} {
+		if got := trailingCallbackRate(tc.current, tc.stop); !almostEqual(got, tc.want) {
+			t.Errorf("trailingCallbackRate(%v, %v) = %v, want %v", tc.current, tc.stop, got, tc.want)
+		}
+	}
+}
@@ -1,5 +1,12 @@
 package trader
 
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
 // TradeType 交易类型枚举
 // 用于区分不同触发源的交易，决定是否消耗积分
 type TradeType int
@@ -48,3 +55,216 @@ func (t TradeType) ShouldConsumeCredit() bool {
 func (t TradeType) IsSystemTriggered() bool {
 	return t == TradeTypeStopLoss || t == TradeTypeTakeProfit || t == TradeTypeForceClose
 }
+
+// TimeInForce 限价单有效方式
+type TimeInForce string
+
+const (
+	// TimeInForceGTC 一直有效直到成交或撤销
+	TimeInForceGTC TimeInForce = "GTC"
+
+	// TimeInForceIOC 立即成交，未成交部分撤销
+	TimeInForceIOC TimeInForce = "IOC"
+
+	// TimeInForceFOK 全部立即成交，否则整单撤销
+	TimeInForceFOK TimeInForce = "FOK"
+
+	// TimeInForcePostOnly 只做maker，会立即成交时交易所拒绝该订单
+	TimeInForcePostOnly TimeInForce = "POST_ONLY"
+)
+
+// ParseTimeInForce 解析有效方式（不区分大小写，空字符串默认GTC）
+func ParseTimeInForce(value string) (TimeInForce, error) {
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "", "GTC":
+		return TimeInForceGTC, nil
+	case "IOC":
+		return TimeInForceIOC, nil
+	case "FOK":
+		return TimeInForceFOK, nil
+	case "POST_ONLY", "POSTONLY", "GTX", "ALO":
+		return TimeInForcePostOnly, nil
+	default:
+		return "", fmt.Errorf("不支持的限价单有效方式: %s", value)
+	}
+}
+
+// 订单状态（各交易所统一转换为以下取值）
+const (
+	OrderStatusNew             = "NEW"
+	OrderStatusPartiallyFilled = "PARTIALLY_FILLED"
+	OrderStatusFilled          = "FILLED"
+	OrderStatusCanceled        = "CANCELED"
+	OrderStatusExpired         = "EXPIRED"
+	OrderStatusRejected        = "REJECTED"
+)
+
+// 止盈止损条件单类型
+const (
+	ProtectiveKindStopLoss   = "sl"
+	ProtectiveKindTakeProfit = "tp"
+)
+
+// Balance 账户余额（USDT计价）
+type Balance struct {
+	WalletBalance    float64 `json:"wallet_balance"`    // 钱包余额（不含未实现盈亏）
+	UnrealizedProfit float64 `json:"unrealized_profit"` // 未实现盈亏
+	AvailableBalance float64 `json:"available_balance"` // 可用保证金
+}
+
+// TotalEquity 账户净值 = 钱包余额 + 未实现盈亏
+func (b *Balance) TotalEquity() float64 {
+	return b.WalletBalance + b.UnrealizedProfit
+}
+
+// UsedMargin 已占用保证金 = 账户净值 - 可用保证金
+func (b *Balance) UsedMargin() float64 {
+	used := b.TotalEquity() - b.AvailableBalance
+	if used < 0 {
+		return 0
+	}
+	return used
+}
+
+// Position 持仓
+type Position struct {
+	Symbol           string  `json:"symbol"`                // 统一格式，如BTCUSDT
+	Side             string  `json:"side"`                  // long/short
+	Quantity         float64 `json:"quantity"`              // 持仓数量（币数量，始终为正）
+	EntryPrice       float64 `json:"entry_price"`           // 开仓均价
+	MarkPrice        float64 `json:"mark_price"`            // 标记价格
+	UnrealizedProfit float64 `json:"unrealized_profit"`     // 未实现盈亏
+	Leverage         int     `json:"leverage"`              // 杠杆倍数
+	LiquidationPrice float64 `json:"liquidation_price"`     // 强平价（未知时为0）
+	MarginMode       string  `json:"margin_mode,omitempty"` // cross/isolated
+}
+
+// Key 持仓唯一标识（symbol_side）
+func (p *Position) Key() string {
+	return p.Symbol + "_" + p.Side
+}
+
+// PositionSide 下单使用的持仓方向（LONG/SHORT）
+func (p *Position) PositionSide() string {
+	return strings.ToUpper(p.Side)
+}
+
+// MarginUsed 估算占用保证金 = 持仓价值 / 杠杆
+func (p *Position) MarginUsed() float64 {
+	leverage := p.Leverage
+	if leverage <= 0 {
+		leverage = 1
+	}
+	return p.Quantity * p.MarkPrice / float64(leverage)
+}
+
+// OrderResult 下单结果或订单查询结果
+type OrderResult struct {
+	OrderID      string  `json:"order_id"`                // 交易所订单ID（没有返回时为空）
+	Symbol       string  `json:"symbol"`                  // 统一格式，如BTCUSDT
+	Status       string  `json:"status"`                  // 见OrderStatus*常量
+	Type         string  `json:"type,omitempty"`          // MARKET/LIMIT/STOP_MARKET等（各交易所原始类型）
+	Side         string  `json:"side,omitempty"`          // BUY/SELL
+	PositionSide string  `json:"position_side,omitempty"` // LONG/SHORT（单向持仓模式下可能为空）
+	Price        float64 `json:"price"`                   // 委托价（市价单为0）
+	StopPrice    float64 `json:"stop_price,omitempty"`    // 触发价（条件单）
+	Quantity     float64 `json:"quantity"`                // 委托数量（币数量）
+	ExecutedQty  float64 `json:"executed_qty"`            // 已成交数量（币数量）
+	AvgPrice     float64 `json:"avg_price"`               // 成交均价（未知时为0）
+	Fee          float64 `json:"fee"`                     // 已支付手续费（未知时为0）
+	Time         int64   `json:"time,omitempty"`          // 创建时间（毫秒）
+	UpdateTime   int64   `json:"update_time,omitempty"`   // 更新时间（毫秒）
+}
+
+// ProtectiveOrder 止盈止损条件单（不包含跟踪止损）
+type ProtectiveOrder struct {
+	OrderID       string  `json:"order_id"`
+	Symbol        string  `json:"symbol"`         // 统一格式，如BTCUSDT
+	PositionSide  string  `json:"position_side"`  // LONG/SHORT（该条件单要平掉的持仓方向）
+	Kind          string  `json:"kind"`           // sl/tp，见ProtectiveKind*常量
+	TriggerPrice  float64 `json:"trigger_price"`  // 触发价
+	Quantity      float64 `json:"quantity"`       // 币数量（ClosePosition为true时可能为0）
+	ClosePosition bool    `json:"close_position"` // 触发后平掉整个仓位
+}
+
+// protectiveSide 条件单要平掉的持仓方向：优先使用订单的持仓方向，单向持仓下卖出平多、买入平空
+func protectiveSide(positionSide, side string) string {
+	switch strings.ToUpper(positionSide) {
+	case "LONG", "SHORT":
+		return strings.ToUpper(positionSide)
+	}
+	if strings.EqualFold(side, "BUY") {
+		return "SHORT"
+	}
+	return "LONG"
+}
+
+// filterProtectiveOrders 从挂单列表中筛选止盈止损条件单（适用于条件单与普通委托在同一接口返回的交易所）
+// kindOf返回sl/tp，其他订单返回空字符串
+func filterProtectiveOrders(orders []OrderResult, kindOf func(order *OrderResult) string) []ProtectiveOrder {
+	result := []ProtectiveOrder{}
+	for i := range orders {
+		order := &orders[i]
+		kind := kindOf(order)
+		if kind == "" {
+			continue
+		}
+		result = append(result, ProtectiveOrder{
+			OrderID:       order.OrderID,
+			Symbol:        order.Symbol,
+			PositionSide:  protectiveSide(order.PositionSide, order.Side),
+			Kind:          kind,
+			TriggerPrice:  order.StopPrice,
+			Quantity:      order.Quantity,
+			ClosePosition: order.Quantity == 0,
+		})
+	}
+	return result
+}
+
+// Fill 成交记录
+type Fill struct {
+	FillID       string  `json:"fill_id"`
+	OrderID      string  `json:"order_id"`
+	Symbol       string  `json:"symbol"`                  // 统一格式，如BTCUSDT
+	Side         string  `json:"side"`                    // buy/sell
+	PositionSide string  `json:"position_side,omitempty"` // LONG/SHORT（单向持仓模式下可能为空）
+	Price        float64 `json:"price"`
+	Quantity     float64 `json:"quantity"`     // 币数量
+	Fee          float64 `json:"fee"`          // 正数表示支付的手续费
+	FeeCurrency  string  `json:"fee_currency"` // 手续费币种
+	Role         string  `json:"role"`         // maker/taker
+	Timestamp    int64   `json:"timestamp"`    // 成交时间（毫秒）
+}
+
+// FundingPayment 资金费结算记录
+type FundingPayment struct {
+	Symbol    string  `json:"symbol"`         // 统一格式，如BTCUSDT
+	Amount    float64 `json:"amount"`         // 结算金额（USDT），正数表示收到，负数表示支付
+	Rate      float64 `json:"rate,omitempty"` // 结算时的资金费率（交易所未返回时为0）
+	Timestamp int64   `json:"timestamp"`      // 结算时间（毫秒）
+}
+
+// Transfer 账户资金划转记录（充值、提现及与其他账户之间的划转）
+type Transfer struct {
+	Amount    float64 `json:"amount"`    // 划转金额（USDT），正数表示转入，负数表示转出
+	Timestamp int64   `json:"timestamp"` // 划转时间（毫秒）
+}
+
+// parseFloatOrZero 解析交易所返回的数值字符串（空字符串或格式错误时返回0）
+func parseFloatOrZero(s string) float64 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// floorToStep 将下单数量向下取整到步长的整数倍（向上取整可能超出持仓或可用保证金）
+// 加上极小值避免浮点误差（如0.012被表示为0.0119999）导致少一档
+func floorToStep(value, step float64) float64 {
+	if step <= 0 {
+		return value
+	}
+	return math.Floor(value/step+1e-9) * step
+}
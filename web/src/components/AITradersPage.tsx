@@ -1254,7 +1254,7 @@ function ExchangeConfigModal({
     shouldShowCEXFields: (selectedExchange?.id === 'binance' || selectedExchange?.type === 'cex') &&
       selectedExchange?.id !== 'hyperliquid' &&
       selectedExchange?.id !== 'aster',
-    shouldShowPassphrase: selectedExchange?.id === 'okx'
+    shouldShowPassphrase: selectedExchange?.id === 'okx' || selectedExchange?.id === 'bitget'
   });
 
   // 如果是编辑现有交易所，初始化表单数据
@@ -1289,7 +1289,8 @@ function ExchangeConfigModal({
     } else if (selectedExchange?.id === 'aster') {
       if (!asterUser.trim() || !asterSigner.trim() || !asterPrivateKey.trim()) return;
       await onSave(selectedExchangeId, '', '', testnet, undefined, asterUser.trim(), asterSigner.trim(), asterPrivateKey.trim());
-    } else if (selectedExchange?.id === 'okx') {
+    } else if (selectedExchange?.id === 'okx' || selectedExchange?.id === 'bitget') {
+      // Bitget 与 OKX 一样需要 Passphrase（复用 okxPassphrase 字段）
       if (!apiKey.trim() || !secretKey.trim() || !passphrase.trim()) return;
       await onSave(selectedExchangeId, apiKey.trim(), secretKey.trim(), testnet, undefined, undefined, undefined, undefined, passphrase.trim());
     } else {
@@ -1400,7 +1401,7 @@ function ExchangeConfigModal({
                     />
                   </div>
 
-                  {selectedExchange.id === 'okx' && (
+                  {(selectedExchange.id === 'okx' || selectedExchange.id === 'bitget') && (
                     <div>
                       <label className="block text-sm font-semibold mb-2" style={{ color: '#EAECEF' }}>
                         {t('passphrase', language)}
@@ -1553,10 +1554,10 @@ function ExchangeConfigModal({
               disabled={
                 !selectedExchange || 
                 (selectedExchange.id === 'binance' && (!apiKey.trim() || !secretKey.trim())) ||
-                (selectedExchange.id === 'okx' && (!apiKey.trim() || !secretKey.trim() || !passphrase.trim())) ||
+                ((selectedExchange.id === 'okx' || selectedExchange.id === 'bitget') && (!apiKey.trim() || !secretKey.trim() || !passphrase.trim())) ||
                 (selectedExchange.id === 'hyperliquid' && (!apiKey.trim() || !hyperliquidWalletAddr.trim())) ||
                 (selectedExchange.id === 'aster' && (!asterUser.trim() || !asterSigner.trim() || !asterPrivateKey.trim())) ||
-                (selectedExchange.type === 'cex' && selectedExchange.id !== 'hyperliquid' && selectedExchange.id !== 'aster' && selectedExchange.id !== 'binance' && selectedExchange.id !== 'okx' && (!apiKey.trim() || !secretKey.trim()))
+                (selectedExchange.type === 'cex' && selectedExchange.id !== 'hyperliquid' && selectedExchange.id !== 'aster' && selectedExchange.id !== 'binance' && selectedExchange.id !== 'okx' && selectedExchange.id !== 'bitget' && (!apiKey.trim() || !secretKey.trim()))
               }
               className="flex-1 px-4 py-2 rounded text-sm font-semibold disabled:opacity-50"
               style={{ background: '#F0B90B', color: '#000' }}
@@ -10,6 +10,8 @@ import type {
   CreateTraderRequest,
   UpdateModelConfigRequest,
   UpdateExchangeConfigRequest,
+  ExchangeAccount,
+  ExchangeAccountRequest,
   CompetitionData,
 } from '../types';
 
@@ -204,6 +206,75 @@ export const api = {
     if (!res.ok) throw new Error('更新交易所配置失败');
   },
 
+  // 交易所命名凭证接口（同一交易所多套凭证/子账户）
+  async getExchangeAccounts(): Promise<ExchangeAccount[]> {
+    const res = await fetch(`${API_BASE}/exchange-accounts`, {
+      headers: getAuthHeaders(),
+    });
+    if (!res.ok) throw new Error('获取交易所凭证失败');
+    return res.json();
+  },
+
+  async createExchangeAccount(request: ExchangeAccountRequest): Promise<ExchangeAccount> {
+    const res = await fetch(`${API_BASE}/exchange-accounts`, {
+      method: 'POST',
+      headers: getAuthHeaders(),
+      body: JSON.stringify(request),
+    });
+    if (!res.ok) {
+      let errorMsg = '添加交易所凭证失败';
+      try {
+        const errorData = await res.json();
+        if (errorData.error) {
+          errorMsg = errorData.error;
+        }
+      } catch (e) {
+        // 使用默认错误信息
+      }
+      throw new Error(errorMsg);
+    }
+    return res.json();
+  },
+
+  async updateExchangeAccount(id: string, request: ExchangeAccountRequest): Promise<void> {
+    const res = await fetch(`${API_BASE}/exchange-accounts/${id}`, {
+      method: 'PUT',
+      headers: getAuthHeaders(),
+      body: JSON.stringify(request),
+    });
+    if (!res.ok) {
+      let errorMsg = '更新交易所凭证失败';
+      try {
+        const errorData = await res.json();
+        if (errorData.error) {
+          errorMsg = errorData.error;
+        }
+      } catch (e) {
+        // 使用默认错误信息
+      }
+      throw new Error(errorMsg);
+    }
+  },
+
+  async deleteExchangeAccount(id: string): Promise<void> {
+    const res = await fetch(`${API_BASE}/exchange-accounts/${id}`, {
+      method: 'DELETE',
+      headers: getAuthHeaders(),
+    });
+    if (!res.ok) {
+      let errorMsg = '删除交易所凭证失败';
+      try {
+        const errorData = await res.json();
+        if (errorData.error) {
+          errorMsg = errorData.error;
+        }
+      } catch (e) {
+        // 使用默认错误信息
+      }
+      throw new Error(errorMsg);
+    }
+  },
+
   // 获取系统状态（支持trader_id）
   async getStatus(traderId?: string): Promise<SystemStatus> {
     const url = traderId
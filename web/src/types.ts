@@ -47,7 +47,8 @@ export interface DecisionAction {
   quantity: number;
   leverage: number;
   price: number;
-  order_id: number;
+  fee: number;
+  order_id: string;
   timestamp: string;
   success: boolean;
   error?: string;
@@ -137,6 +138,41 @@ export interface CreateTraderRequest {
   is_cross_margin?: boolean;
   use_coin_pool?: boolean;
   use_oi_top?: boolean;
+  // 交易所命名凭证ID，为空使用交易所默认配置
+  exchange_account_id?: string;
+  // 允许与其他交易员共用同一交易所账户（双方都开启才生效）
+  allow_shared_account?: boolean;
+}
+
+// 交易所命名凭证（同一交易所可有多套，如币安/OKX子账户），不包含密钥
+export interface ExchangeAccount {
+  id: string;
+  exchange_id: string;
+  name: string;
+  testnet: boolean;
+  sub_account: string;
+  hyperliquid_wallet_addr: string;
+  aster_user: string;
+  aster_signer: string;
+  has_api_key: boolean;
+  has_secret_key: boolean;
+  has_passphrase: boolean;
+  has_aster_private_key: boolean;
+}
+
+export interface ExchangeAccountRequest {
+  exchange_id?: string;
+  name: string;
+  // 更新时密钥留空表示保持原值
+  api_key?: string;
+  secret_key?: string;
+  passphrase?: string;
+  testnet?: boolean;
+  sub_account?: string;
+  hyperliquid_wallet_addr?: string;
+  aster_user?: string;
+  aster_signer?: string;
+  aster_private_key?: string;
 }
 
 export interface UpdateModelConfigRequest {
@@ -204,5 +240,7 @@ export interface TraderConfigData {
   use_oi_top: boolean;
   initial_balance: number;
   scan_interval_minutes: number;
+  exchange_account_id?: string;
+  allow_shared_account?: boolean;
   is_running: boolean;
 }
@@ -46,7 +46,8 @@ export interface DecisionAction {
   quantity: number;
   leverage: number;
   price: number;
-  order_id: number;
+  fee: number;
+  order_id: string;
   timestamp: string;
   success: boolean;
   error: string;